@@ -8,13 +8,11 @@ import (
 	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/utils"
 	"github.com/pkg/errors"
 	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
 	"sigs.k8s.io/kustomize/kyaml/yaml"
 )
 
-// ContainersPath Declare ContainersPath enum.
-type ContainersPath string
-
 const (
 	_errMsgJsonToYamlConversionFail                = "Failed to convert json to yaml node"
 	_imagePullSecretsConst                         = "imagePullSecrets"
@@ -27,13 +25,14 @@ const (
 	_nameConst                                     = "name"
 	_kindConst                                     = "kind"
 	_apiVersionConst                               = "apiVersion"
-	_containersPath                 ContainersPath = _containersConst
-	_initContainersPath             ContainersPath = _initContainersConst
+	_uidConst                                      = "uid"
+	_controllerConst                               = "controller"
 )
 
 var (
 	// conventional pod spec paths for all kubernetes workload resources.
 	//based on yaml.ConventionalContainersPaths var.
+	// Used for the kinds of SupportedKubernetesWorkloadResources that don't match any of WorkloadResourceKinds.
 	_conventionalPodSpecPaths = [][]string{
 		{"spec", "jobTemplate", "spec", "template", "spec"}, // CronJob
 		{"spec", "template", "spec"},                        // Deployment, ReplicaSet, StatefulSet, DaemonSet,Job, ReplicationController
@@ -41,12 +40,29 @@ var (
 	_errInvalidAdmission      = errors.New("Admission request was nil")
 	_errWorkloadResourceEmpty = errors.New("Request did not include workload resource")
 	_errTypeConversionFailed  = errors.New("Type conversion failed")
-	_errWrongContainersPath   = errors.New("Wrong containers path")
 )
 
 // ExtractorConfiguration configuration for extractor
 type ExtractorConfiguration struct {
+	// SupportedKubernetesWorkloadResources are the kinds (of any group and version) that are extracted from the conventional pod spec paths.
 	SupportedKubernetesWorkloadResources []string
+	// WorkloadResourceKinds are the kinds that are matched by group, version and kind and extracted from their own pod spec paths
+	// (e.g. Argo Rollout, Knative Service). They take precedence over SupportedKubernetesWorkloadResources.
+	WorkloadResourceKinds []*WorkloadResourceKind
+}
+
+// Validate returns InvalidConfiguration error in case that no kind is supported, or in case that one of the
+// WorkloadResourceKinds has no kind, group, version or pod spec paths.
+func (configuration *ExtractorConfiguration) Validate() error {
+	if len(configuration.SupportedKubernetesWorkloadResources) == 0 && len(configuration.WorkloadResourceKinds) == 0 {
+		return errors.Wrap(utils.InvalidConfiguration, "got empty SupportedKubernetesWorkloadResources and WorkloadResourceKinds")
+	}
+	for i, workloadResourceKind := range configuration.WorkloadResourceKinds {
+		if workloadResourceKind == nil || workloadResourceKind.Kind == "" || workloadResourceKind.Version == "" || len(workloadResourceKind.PodSpecPaths) == 0 {
+			return errors.Wrapf(utils.InvalidConfiguration, "WorkloadResourceKinds[%d] must have kind, version and podSpecPaths", i)
+		}
+	}
+	return nil
 }
 
 // IExtractor represents interface for admission request extractor.
@@ -54,6 +70,8 @@ type IExtractor interface {
 	// ExtractWorkloadResourceFromAdmissionRequest return WorkloadResource object according
 	// to the information in admission.Request.
 	ExtractWorkloadResourceFromAdmissionRequest(req *admission.Request) (*WorkloadResource, error)
+	// IsSupportedKind returns true if workload resources of the group, version and kind can be extracted.
+	IsSupportedKind(gvk metav1.GroupVersionKind) bool
 }
 
 // Extractor implements IExtractor interface
@@ -65,19 +83,26 @@ type Extractor struct {
 	tracerProvider trace.ITracerProvider
 	// MetricSubmitter
 	metricSubmitter metric.IMetricSubmitter
-	// Configurations extractor's config.
-	configuration *ExtractorConfiguration
+	// getConfiguration returns the current configuration of the extractor - the configuration that it was created with,
+	// or the reloaded configuration in case that a configuration provider is set.
+	getConfiguration func() *ExtractorConfiguration
 }
 
 // NewExtractor Constructor for Extractor
 func NewExtractor(instrumentationProvider instrumentation.IInstrumentationProvider, configuration *ExtractorConfiguration) *Extractor {
 	return &Extractor{
-		tracerProvider:  instrumentationProvider.GetTracerProvider("Extractor"),
-		metricSubmitter: instrumentationProvider.GetMetricSubmitter(),
-		configuration:   configuration,
+		tracerProvider:   instrumentationProvider.GetTracerProvider("Extractor"),
+		metricSubmitter:  instrumentationProvider.GetMetricSubmitter(),
+		getConfiguration: func() *ExtractorConfiguration { return configuration },
 	}
 }
 
+// SetConfigurationProvider makes the extractor read its configuration from the provider on each use (e.g. from the
+// reloaded configuration), so the extractions after a reload use the new configuration. Should be called before the extractor is used.
+func (extractor *Extractor) SetConfigurationProvider(getConfiguration func() *ExtractorConfiguration) {
+	extractor.getConfiguration = getConfiguration
+}
+
 // ExtractWorkloadResourceFromAdmissionRequest return WorkloadResource object according
 // to the information in admission.Request.
 func (extractor *Extractor) ExtractWorkloadResourceFromAdmissionRequest(req *admission.Request) (resource *WorkloadResource, err error) {
@@ -91,6 +116,13 @@ func (extractor *Extractor) ExtractWorkloadResourceFromAdmissionRequest(req *adm
 		return nil, err
 	}
 
+	workloadResourceKind, err := extractor.getWorkloadResourceKind(req.Kind)
+	if err != nil {
+		err = errors.Wrap(err, "request isn't valid")
+		tracer.Error(err, "")
+		return nil, err
+	}
+
 	objectRequest := string(req.Object.Raw)
 	tracer.Info("kubernetes resource in admission request: ", "object: ", objectRequest)
 	objectRequestYaml, err := yaml.ConvertJSONToYamlNode(objectRequest)
@@ -107,15 +139,28 @@ func (extractor *Extractor) ExtractWorkloadResourceFromAdmissionRequest(req *adm
 		return nil, err
 	}
 
-	spec, err := extractor.extractSpecFromAdmissionRequest(objectRequestYaml)
+	spec, err := extractor.extractSpecFromAdmissionRequest(objectRequestYaml, workloadResourceKind)
 	if err != nil {
 		return nil, err
 	}
 
-	workloadResource := newWorkLoadResource(metadata, spec)
+	podTemplate, err := extractor.extractPodTemplateFromAdmissionRequest(objectRequestYaml, workloadResourceKind)
+	if err != nil {
+		err = errors.Wrap(err, "failed to extract pod template from admission request")
+		tracer.Error(err, "")
+		return nil, err
+	}
+
+	workloadResource := newWorkLoadResource(metadata, spec, podTemplate)
 	return workloadResource, nil
 }
 
+// IsSupportedKind returns true if one of the WorkloadResourceKinds of the configuration matches the group, version and kind,
+// or if the kind is one of SupportedKubernetesWorkloadResources.
+func (extractor *Extractor) IsSupportedKind(gvk metav1.GroupVersionKind) bool {
+	return extractor.findWorkloadResourceKind(gvk) != nil
+}
+
 //isRequestValid return error is request isn't valid, else returns nil.
 func (extractor *Extractor) isRequestValid(req *admission.Request) (isValid bool, err error) {
 	tracer := extractor.tracerProvider.GetTracer("isRequestValid")
@@ -127,14 +172,37 @@ func (extractor *Extractor) isRequestValid(req *admission.Request) (isValid bool
 		tracer.Error(_errWorkloadResourceEmpty, "")
 		return false, _errWorkloadResourceEmpty
 	}
-	if !utils.StringInSlice(req.Kind.Kind, extractor.configuration.SupportedKubernetesWorkloadResources) {
-		err = errors.New(fmt.Sprintf("%s is unsupported kind of workload resource", req.Kind.Kind))
-		tracer.Error(err, "")
-		return false, err
-	}
 	return true, nil
 }
 
+// getWorkloadResourceKind returns the first WorkloadResourceKind of the configuration that matches the group, version and kind of the request.
+// In case that none of them matches, kinds of SupportedKubernetesWorkloadResources are extracted from the conventional pod spec paths.
+// Returns error if the kind is unsupported.
+func (extractor *Extractor) getWorkloadResourceKind(gvk metav1.GroupVersionKind) (*WorkloadResourceKind, error) {
+	tracer := extractor.tracerProvider.GetTracer("getWorkloadResourceKind")
+	if workloadResourceKind := extractor.findWorkloadResourceKind(gvk); workloadResourceKind != nil {
+		return workloadResourceKind, nil
+	}
+	err := errors.New(fmt.Sprintf("%s is unsupported kind of workload resource", gvk.Kind))
+	tracer.Error(err, "", "group", gvk.Group, "version", gvk.Version)
+	return nil, err
+}
+
+// findWorkloadResourceKind returns the first WorkloadResourceKind of the configuration that matches the group, version and kind,
+// or a conventional WorkloadResourceKind in case that the kind is one of SupportedKubernetesWorkloadResources. Returns nil if none matches.
+func (extractor *Extractor) findWorkloadResourceKind(gvk metav1.GroupVersionKind) *WorkloadResourceKind {
+	configuration := extractor.getConfiguration()
+	for _, workloadResourceKind := range configuration.WorkloadResourceKinds {
+		if workloadResourceKind != nil && workloadResourceKind.matches(gvk) {
+			return workloadResourceKind
+		}
+	}
+	if utils.StringInSlice(gvk.Kind, configuration.SupportedKubernetesWorkloadResources) {
+		return newConventionalWorkloadResourceKind(gvk.Kind)
+	}
+	return nil
+}
+
 // extractMetadataFromAdmissionRequest extracts *ObjectMetadata from admission request.
 func (extractor *Extractor) extractMetadataFromAdmissionRequest(root *yaml.RNode) (metadata *ObjectMetadata, err error) {
 	tracer := extractor.tracerProvider.GetTracer("extractMetadataFromAdmissionRequest")
@@ -144,12 +212,22 @@ func (extractor *Extractor) extractMetadataFromAdmissionRequest(root *yaml.RNode
 	if name == "" {
 		tracer.Info("name is empty")
 	}
+	// uid is missing on CREATE requests - the api server sets it after the admission.
+	uid, err := root.GetString(_metadataConst + "." + _uidConst)
+	if err != nil {
+		uid = ""
+	}
 	namespace := root.GetNamespace()
 	annotations := root.GetAnnotations()
 	// If annotations field missing from yaml, annotations is nil by default but GetAnnotations returns empty map.
 	if len(annotations) == 0 {
 		annotations = nil
 	}
+	labels := root.GetLabels()
+	// If labels field missing from yaml, labels is nil by default but GetLabels returns empty map.
+	if len(labels) == 0 {
+		labels = nil
+	}
 	ownerReferences, err := extractor.getOwnerReference(root)
 	if err != nil {
 		err = errors.Wrap(err, "Couldn't get owner references from metadata: error encountered")
@@ -157,15 +235,15 @@ func (extractor *Extractor) extractMetadataFromAdmissionRequest(root *yaml.RNode
 		return nil, err
 	}
 	tracer.Info("metadata: ", " name:", name, " namespace:", "annotations", annotations)
-	metadata = newObjectMetadata(name, namespace, annotations, ownerReferences)
+	metadata = newObjectMetadata(name, uid, namespace, annotations, labels, ownerReferences)
 	return metadata, nil
 }
 
-// extractSpecFromAdmissionRequest extracts *PodSpec from admission request.
-func (extractor *Extractor) extractSpecFromAdmissionRequest(root *yaml.RNode) (spec *PodSpec, err error) {
+// extractSpecFromAdmissionRequest extracts *PodSpec from admission request - from the first existing pod spec path of the workloadResourceKind.
+func (extractor *Extractor) extractSpecFromAdmissionRequest(root *yaml.RNode, workloadResourceKind *WorkloadResourceKind) (spec *PodSpec, err error) {
 	tracer := extractor.tracerProvider.GetTracer("extractSpecFromAdmissionRequest")
 	// Gets the matching pod spec path of the given root.
-	podSpecPathFilter := yaml.LookupFirstMatch(_conventionalPodSpecPaths)
+	podSpecPathFilter := yaml.LookupFirstMatch(workloadResourceKind.getPodSpecPaths())
 	// Go to podSpec Node according to given podSpecPathFilter.
 	specNode, err := podSpecPathFilter.Filter(root)
 	if err != nil {
@@ -179,7 +257,7 @@ func (extractor *Extractor) extractSpecFromAdmissionRequest(root *yaml.RNode) (s
 		tracer.Info("spec field is missing. Api server should have blocked the request")
 		return newEmptySpec(), err
 	}
-	containerList, initContainerList, err := extractor.getContainers(specNode)
+	containerList, initContainerList, err := extractor.getContainers(specNode, workloadResourceKind)
 	if err != nil {
 		err = errors.Wrap(err, "Couldn't get containers/init containers from spec: error encountered")
 		tracer.Error(err, "")
@@ -200,6 +278,46 @@ func (extractor *Extractor) extractSpecFromAdmissionRequest(root *yaml.RNode) (s
 	return spec, nil
 }
 
+// extractPodTemplateFromAdmissionRequest extracts *PodTemplate from admission request - the parent of the pod spec of the
+// first matching pod spec path of the workloadResourceKind.
+// Returns nil if the pod spec isn't in a pod template (e.g. Pod, or spec.taskSpec of a Tekton TaskRun) or if it's missing.
+func (extractor *Extractor) extractPodTemplateFromAdmissionRequest(root *yaml.RNode, workloadResourceKind *WorkloadResourceKind) (podTemplate *PodTemplate, err error) {
+	for _, podSpecPath := range workloadResourceKind.getPodSpecPaths() {
+		specNode, err := root.Pipe(yaml.Lookup(podSpecPath...))
+		if err != nil {
+			return nil, err
+		}
+		if specNode == nil {
+			continue
+		}
+		// Pod - the pod spec isn't in a pod template
+		if !isPodTemplateSpecPath(podSpecPath) {
+			return nil, nil
+		}
+
+		templatePath := make([]string, len(podSpecPath)-1)
+		copy(templatePath, podSpecPath)
+		templateNode, err := root.Pipe(yaml.Lookup(templatePath...))
+		if err != nil {
+			return nil, err
+		}
+		metadataNode, err := templateNode.Pipe(yaml.Lookup(_metadataConst))
+		if err != nil {
+			return nil, err
+		}
+		labels := templateNode.GetLabels()
+		if len(labels) == 0 {
+			labels = nil
+		}
+		annotations := templateNode.GetAnnotations()
+		if len(annotations) == 0 {
+			annotations = nil
+		}
+		return newPodTemplate(templatePath, metadataNode != nil, labels, annotations), nil
+	}
+	return nil, nil
+}
+
 // getImagePullSecrets returns workload kubernetes resource's image pull secrets.
 func (extractor *Extractor) getImagePullSecrets(specRoot *yaml.RNode) (secrets []*corev1.LocalObjectReference, err error) {
 	tracer := extractor.tracerProvider.GetTracer("getImagePullSecrets")
@@ -265,34 +383,51 @@ func (extractor *Extractor) getOwnerReference(root *yaml.RNode) (ownerReferences
 			tracer.Error(_errTypeConversionFailed, "Failed to convert owner reference name interface to string")
 			return nil, _errTypeConversionFailed
 		}
-		ownerReferences[i] = &OwnerReference{APIVersion: apiVersion, Kind: kind, Name: name}
+		// uid is optional - the reference is still valid without it
+		uid, _ := mapReference[_uidConst].(string)
+		// controller is optional - false if it's missing
+		controller, _ := mapReference[_controllerConst].(bool)
+		ownerReferences[i] = &OwnerReference{APIVersion: apiVersion, Kind: kind, Name: name, UID: uid, Controller: controller}
 		tracer.Info("ownerReference: ", "apiVersion", apiVersion, "kind", kind, "name", name)
 	}
 	return ownerReferences, nil
 }
 
-// getContainers returns workload kubernetes resource's containers and initContainers.
-func (extractor *Extractor) getContainers(specRoot *yaml.RNode) (containers []*Container, initContainers []*Container, err error) {
-	containers, err = extractor.getContainersFromPath(specRoot, _containersPath)
+// getContainers returns workload kubernetes resource's containers and initContainers - from the containers fields of the workloadResourceKind.
+func (extractor *Extractor) getContainers(specRoot *yaml.RNode, workloadResourceKind *WorkloadResourceKind) (containers []*Container, initContainers []*Container, err error) {
+	containers, err = extractor.getContainersFromFields(specRoot, workloadResourceKind.getContainersFields())
 	if err != nil {
 		return nil, nil, err
 	}
-	initContainers, err = extractor.getContainersFromPath(specRoot, _initContainersPath)
+	initContainers, err = extractor.getContainersFromFields(specRoot, workloadResourceKind.getInitContainersFields())
 	if err != nil {
 		return nil, nil, err
 	}
 	return containers, initContainers, nil
 }
 
-func (extractor *Extractor) getContainersFromPath(specRoot *yaml.RNode, path ContainersPath) (containers []*Container, err error) {
-	tracer := extractor.tracerProvider.GetTracer("getContainersFromPath")
-	if !(path == _containersPath || path == _initContainersPath) {
-		tracer.Error(_errWrongContainersPath, "")
-		return nil, _errWrongContainersPath
+// getContainersFromFields returns the containers of all the given container list fields of the spec (e.g. steps and sidecars of a Tekton TaskRun).
+func (extractor *Extractor) getContainersFromFields(specRoot *yaml.RNode, fields []string) (containers []*Container, err error) {
+	for _, field := range fields {
+		fieldContainers, err := extractor.getContainersFromPath(specRoot, field)
+		if err != nil {
+			return nil, err
+		}
+		if containers == nil {
+			containers = fieldContainers
+		} else {
+			containers = append(containers, fieldContainers...)
+		}
 	}
-	containersInterface, err := specRoot.GetSlice(string(path))
+	return containers, nil
+}
+
+// getContainersFromPath returns the containers of the container list field of the spec.
+func (extractor *Extractor) getContainersFromPath(specRoot *yaml.RNode, path string) (containers []*Container, err error) {
+	tracer := extractor.tracerProvider.GetTracer("getContainersFromPath")
+	containersInterface, err := specRoot.GetSlice(path)
 	if err != nil {
-		tracer.Info(fmt.Sprintf("%s field is missing", string(path)))
+		tracer.Info(fmt.Sprintf("%s field is missing", path))
 		return nil, nil
 	}
 	containers = make([]*Container, len(containersInterface))
@@ -307,10 +442,14 @@ func (extractor *Extractor) getContainersFromPath(specRoot *yaml.RNode, path Con
 			tracer.Error(_errTypeConversionFailed, "Failed to convert image name interface to string")
 			return nil, _errTypeConversionFailed
 		}
+		// name is mandatory in containers, but optional in some custom resources (e.g. steps of a Tekton TaskRun)
 		containerName, ok := (tempContainer[_nameConst]).(string)
 		if ok == false {
-			tracer.Error(_errTypeConversionFailed, "Failed to convert container name interface to string")
-			return nil, _errTypeConversionFailed
+			if _, exists := tempContainer[_nameConst]; exists {
+				tracer.Error(_errTypeConversionFailed, "Failed to convert container name interface to string")
+				return nil, _errTypeConversionFailed
+			}
+			tracer.Info("container name is missing", "field", path, "Image", imageName)
 		}
 		containers[i] = &Container{Image: imageName, Name: containerName}
 		tracer.Info("container:", " Name:", containerName, " Image:", imageName)
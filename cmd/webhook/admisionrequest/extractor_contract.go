@@ -16,12 +16,18 @@ type OwnerReference struct {
 	Kind string
 	// Name of the referent.
 	Name string
+	// UID of the referent - empty if it's missing from the reference.
+	UID string
+	// Controller is whether the referent is the managing controller of the object (e.g. the ReplicaSet of a Pod).
+	Controller bool
 }
 
 // ObjectMetadata represents the metadata of WorkloadResource object.
 type ObjectMetadata struct {
 	// Name is the resource's name.
 	Name string
+	// UID is the resource's unique identifier. It is empty on CREATE requests - the api server sets it after the admission.
+	UID string
 	// Namespace defines the space within which each name must be unique. An empty namespace is
 	// equivalent to the "default" namespace, but "default" is the canonical representation.
 	// Not all objects are required to be scoped to a namespace - the value of this field for
@@ -31,6 +37,9 @@ type ObjectMetadata struct {
 	// set by external tools to store and retrieve arbitrary metadata. They are not
 	// queryable and should be preserved when modifying objects.
 	Annotations map[string]string
+	// Labels is a map of string keys and values that can be used to organize and categorize
+	// (scope and select) objects. Nil if the resource has no labels.
+	Labels map[string]string
 	// List of objects depended by this object. If ALL objects in the list have
 	// been deleted, this object will be garbage collected. If this object is managed by a controller,
 	// then an entry in this list will point to this controller, with the controller field set to true.
@@ -38,9 +47,36 @@ type ObjectMetadata struct {
 	OwnerReferences []*OwnerReference
 }
 
+// GetControllerOwnerReference returns the owner reference of the managing controller of the object, or nil if it has no controller.
+func (metadata *ObjectMetadata) GetControllerOwnerReference() *OwnerReference {
+	for _, ownerReference := range metadata.OwnerReferences {
+		if ownerReference != nil && ownerReference.Controller {
+			return ownerReference
+		}
+	}
+	return nil
+}
+
 // newObjectMetadata initialize ObjectMetadata object.
-func newObjectMetadata(name string, namespace string, annotation map[string]string, ownerReferences []*OwnerReference) (metadata *ObjectMetadata) {
-	return &ObjectMetadata{Name: name, Namespace: namespace, Annotations: annotation, OwnerReferences: ownerReferences}
+func newObjectMetadata(name string, uid string, namespace string, annotation map[string]string, labels map[string]string, ownerReferences []*OwnerReference) (metadata *ObjectMetadata) {
+	return &ObjectMetadata{Name: name, UID: uid, Namespace: namespace, Annotations: annotation, Labels: labels, OwnerReferences: ownerReferences}
+}
+
+// PodTemplate represents the pod template of a WorkloadResource that creates pods (e.g. spec.template of Deployment).
+type PodTemplate struct {
+	// Path is the path of the pod template in the WorkloadResource (e.g. ["spec", "template"]).
+	Path []string
+	// HasMetadata is whether the pod template has metadata field.
+	HasMetadata bool
+	// Labels are the labels of the pod template. Nil if the pod template has no labels.
+	Labels map[string]string
+	// Annotations are the annotations of the pod template. Nil if the pod template has no annotations.
+	Annotations map[string]string
+}
+
+// newPodTemplate initialize PodTemplate object.
+func newPodTemplate(path []string, hasMetadata bool, labels map[string]string, annotations map[string]string) *PodTemplate {
+	return &PodTemplate{Path: path, HasMetadata: hasMetadata, Labels: labels, Annotations: annotations}
 }
 
 // Container represents container object.
@@ -103,9 +139,11 @@ type WorkloadResource struct {
 	Metadata *ObjectMetadata
 	// Spec defines the behavior of a WorkloadResource.
 	Spec *PodSpec
+	// PodTemplate is the pod template of the WorkloadResource. Nil for Pods.
+	PodTemplate *PodTemplate
 }
 
 // newWorkLoadResource initialize WorkloadResource object.
-func newWorkLoadResource(metadata *ObjectMetadata, spec *PodSpec) (workLoadResource *WorkloadResource) {
-	return &WorkloadResource{Metadata: metadata, Spec: spec}
+func newWorkLoadResource(metadata *ObjectMetadata, spec *PodSpec, podTemplate *PodTemplate) (workLoadResource *WorkloadResource) {
+	return &WorkloadResource{Metadata: metadata, Spec: spec, PodTemplate: podTemplate}
 }
@@ -3,6 +3,7 @@ package admisionrequest
 import (
 	"encoding/json"
 	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/instrumentation"
+	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/utils"
 	"github.com/pkg/errors"
 	log "github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/suite"
@@ -105,11 +106,38 @@ func (suite *TestSuite) Test_ExtractWorkloadResourceFromAdmissionRequest_PodAdmi
 	suite.True(reflect.DeepEqual(suite.workloadResource, workLoadResource))
 }
 
+func (suite *TestSuite) Test_ExtractWorkloadResourceFromAdmissionRequest_PodWithUIDs_UIDsExtracted() {
+	suite.pod.UID = "podUID"
+	suite.pod.OwnerReferences = []metav1.OwnerReference{{APIVersion: "apps/v1", Kind: "ReplicaSet", Name: "replicaSetName", UID: "replicaSetUID"}}
+	req := createReq(suite.pod, "Pod")
+
+	workLoadResource, err := suite.extractor.ExtractWorkloadResourceFromAdmissionRequest(req)
+
+	suite.Nil(err)
+	suite.Equal("podUID", workLoadResource.Metadata.UID)
+	suite.Equal([]*OwnerReference{{APIVersion: "apps/v1", Kind: "ReplicaSet", Name: "replicaSetName", UID: "replicaSetUID"}}, workLoadResource.Metadata.OwnerReferences)
+}
+
+func (suite *TestSuite) Test_ExtractWorkloadResourceFromAdmissionRequest_PodWithControllerOwner_ControllerExtracted() {
+	controller := true
+	suite.pod.OwnerReferences = []metav1.OwnerReference{
+		{APIVersion: "v1", Kind: "ConfigMap", Name: "configMapName", UID: "configMapUID"},
+		{APIVersion: "apps/v1", Kind: "ReplicaSet", Name: "replicaSetName", UID: "replicaSetUID", Controller: &controller},
+	}
+	req := createReq(suite.pod, "Pod")
+
+	workLoadResource, err := suite.extractor.ExtractWorkloadResourceFromAdmissionRequest(req)
+
+	suite.Nil(err)
+	suite.Equal(&OwnerReference{APIVersion: "apps/v1", Kind: "ReplicaSet", Name: "replicaSetName", UID: "replicaSetUID", Controller: true}, workLoadResource.Metadata.GetControllerOwnerReference())
+}
+
 func (suite *TestSuite) Test_ExtractWorkloadResourceFromAdmissionRequest_DeploymentAdmissionReqWithMatchingObject_AsExpected() {
 	deployment := createFullDeploymentForTests()
 	req := createReq(deployment, "Deployment")
 	workLoadResource, err := suite.extractor.ExtractWorkloadResourceFromAdmissionRequest(req)
 	suite.Nil(err)
+	suite.workloadResource.PodTemplate = newPodTemplate([]string{"spec", "template"}, true, nil, nil)
 	suite.True(reflect.DeepEqual(suite.workloadResource, workLoadResource))
 }
 
@@ -118,6 +146,7 @@ func (suite *TestSuite) Test_ExtractWorkloadResourceFromAdmissionRequest_Replica
 	req := createReq(replicaSet, "ReplicaSet")
 	workLoadResource, err := suite.extractor.ExtractWorkloadResourceFromAdmissionRequest(req)
 	suite.Nil(err)
+	suite.workloadResource.PodTemplate = newPodTemplate([]string{"spec", "template"}, true, nil, nil)
 	suite.True(reflect.DeepEqual(suite.workloadResource, workLoadResource))
 }
 
@@ -126,6 +155,7 @@ func (suite *TestSuite) Test_ExtractWorkloadResourceFromAdmissionRequest_Replica
 	req := createReq(replicationController, "ReplicationController")
 	workLoadResource, err := suite.extractor.ExtractWorkloadResourceFromAdmissionRequest(req)
 	suite.Nil(err)
+	suite.workloadResource.PodTemplate = newPodTemplate([]string{"spec", "template"}, true, nil, nil)
 	suite.True(reflect.DeepEqual(suite.workloadResource, workLoadResource))
 }
 
@@ -134,6 +164,7 @@ func (suite *TestSuite) Test_ExtractWorkloadResourceFromAdmissionRequest_Statefu
 	req := createReq(statefulSet, "StatefulSet")
 	workLoadResource, err := suite.extractor.ExtractWorkloadResourceFromAdmissionRequest(req)
 	suite.Nil(err)
+	suite.workloadResource.PodTemplate = newPodTemplate([]string{"spec", "template"}, true, nil, nil)
 	suite.True(reflect.DeepEqual(suite.workloadResource, workLoadResource))
 }
 
@@ -142,6 +173,7 @@ func (suite *TestSuite) Test_ExtractWorkloadResourceFromAdmissionRequest_DaemonS
 	req := createReq(daemonSet, "DaemonSet")
 	workLoadResource, err := suite.extractor.ExtractWorkloadResourceFromAdmissionRequest(req)
 	suite.Nil(err)
+	suite.workloadResource.PodTemplate = newPodTemplate([]string{"spec", "template"}, true, nil, nil)
 	suite.True(reflect.DeepEqual(suite.workloadResource, workLoadResource))
 }
 
@@ -150,6 +182,7 @@ func (suite *TestSuite) Test_ExtractWorkloadResourceFromAdmissionRequest_JobAdmi
 	req := createReq(job, "Job")
 	workLoadResource, err := suite.extractor.ExtractWorkloadResourceFromAdmissionRequest(req)
 	suite.Nil(err)
+	suite.workloadResource.PodTemplate = newPodTemplate([]string{"spec", "template"}, true, nil, nil)
 	suite.True(reflect.DeepEqual(suite.workloadResource, workLoadResource))
 }
 
@@ -158,9 +191,44 @@ func (suite *TestSuite) Test_ExtractWorkloadResourceFromAdmissionRequest_CronJob
 	req := createReq(cronJob, "CronJob")
 	workLoadResource, err := suite.extractor.ExtractWorkloadResourceFromAdmissionRequest(req)
 	suite.Nil(err)
+	suite.workloadResource.PodTemplate = newPodTemplate([]string{"spec", "jobTemplate", "spec", "template"}, true, nil, nil)
 	suite.True(reflect.DeepEqual(suite.workloadResource, workLoadResource))
 }
 
+func (suite *TestSuite) Test_ExtractWorkloadResourceFromAdmissionRequest_DeploymentWithLabels_LabelsExtracted() {
+	deployment := createFullDeploymentForTests()
+	deployment.Labels = map[string]string{"app": "deploymentLabel"}
+	deployment.Spec.Template.Labels = map[string]string{"app": "templateLabel"}
+	req := createReq(deployment, "Deployment")
+
+	workLoadResource, err := suite.extractor.ExtractWorkloadResourceFromAdmissionRequest(req)
+
+	suite.Nil(err)
+	suite.Equal(map[string]string{"app": "deploymentLabel"}, workLoadResource.Metadata.Labels)
+	suite.Equal(newPodTemplate([]string{"spec", "template"}, true, map[string]string{"app": "templateLabel"}, nil), workLoadResource.PodTemplate)
+}
+
+func (suite *TestSuite) Test_ExtractWorkloadResourceFromAdmissionRequest_DeploymentWithTemplateAnnotations_AnnotationsExtracted() {
+	deployment := createFullDeploymentForTests()
+	deployment.Spec.Template.Annotations = map[string]string{"annotation": "templateAnnotation"}
+	req := createReq(deployment, "Deployment")
+
+	workLoadResource, err := suite.extractor.ExtractWorkloadResourceFromAdmissionRequest(req)
+
+	suite.Nil(err)
+	suite.Equal(map[string]string{"annotation": "templateAnnotation"}, workLoadResource.PodTemplate.Annotations)
+}
+
+func (suite *TestSuite) Test_ExtractWorkloadResourceFromAdmissionRequest_TemplateWithoutMetadata_HasMetadataFalse() {
+	req := createReq(createFullDeploymentForTests(), "Deployment")
+	req.Object.Raw = []byte(`{"metadata":{"name":"deploymentTest"},"spec":{"template":{"spec":{"containers":[{"name":"containerTest","image":"image.com"}]}}}}`)
+
+	workLoadResource, err := suite.extractor.ExtractWorkloadResourceFromAdmissionRequest(req)
+
+	suite.Nil(err)
+	suite.Equal(newPodTemplate([]string{"spec", "template"}, false, nil, nil), workLoadResource.PodTemplate)
+}
+
 func (suite *TestSuite) Test_ExtractWorkloadResourceFromAdmissionRequest_EmptyPodAdmissionReqWithMatchingObject_AsExpected() {
 	emptyPod := createEmptyPodForTests()
 	req := createReq(emptyPod, "Pod")
@@ -211,6 +279,92 @@ func (suite *TestSuite) Test_GetWorkloadResourceFromAdmissionRequest_NotWorkload
 	suite.True(strings.Contains(err.Error(), "NotWorkloadResource is unsupported kind of workload resource"))
 }
 
+func (suite *TestSuite) Test_ExtractWorkloadResourceFromAdmissionRequest_CustomResourceMatchingKind_ExtractedFromPodSpecPath() {
+	suite.config.WorkloadResourceKinds = []*WorkloadResourceKind{
+		{Group: "argoproj.io", Version: "*", Kind: "Rollout", Resource: "rollouts", PodSpecPaths: []string{"spec.template.spec"}},
+	}
+	req := createReq(createFullDeploymentForTests(), "Rollout")
+	req.Kind = metav1.GroupVersionKind{Group: "argoproj.io", Version: "v1alpha1", Kind: "Rollout"}
+
+	workLoadResource, err := suite.extractor.ExtractWorkloadResourceFromAdmissionRequest(req)
+
+	suite.Nil(err)
+	suite.workloadResource.PodTemplate = newPodTemplate([]string{"spec", "template"}, true, nil, nil)
+	suite.Equal(suite.workloadResource, workLoadResource)
+}
+
+func (suite *TestSuite) Test_ExtractWorkloadResourceFromAdmissionRequest_CustomResourceWithContainersFields_ContainersExtractedWithoutPodTemplate() {
+	suite.config.WorkloadResourceKinds = []*WorkloadResourceKind{
+		{Group: "tekton.dev", Version: "v1beta1", Kind: "TaskRun", Resource: "taskruns", PodSpecPaths: []string{"spec.taskSpec"}, ContainersFields: []string{"steps", "sidecars"}},
+	}
+	req := createReq(createFullPodForTests(), "TaskRun")
+	req.Kind = metav1.GroupVersionKind{Group: "tekton.dev", Version: "v1beta1", Kind: "TaskRun"}
+	req.Object.Raw = []byte(`{"metadata":{"name":"taskRunTest"},"spec":{"taskSpec":{"steps":[{"name":"build","image":"build.com"},{"image":"test.com"}],"sidecars":[{"name":"sidecar","image":"sidecar.com"}]}}}`)
+
+	workLoadResource, err := suite.extractor.ExtractWorkloadResourceFromAdmissionRequest(req)
+
+	suite.Nil(err)
+	suite.Equal([]*Container{{Name: "build", Image: "build.com"}, {Name: "", Image: "test.com"}, {Name: "sidecar", Image: "sidecar.com"}}, workLoadResource.Spec.Containers)
+	suite.Nil(workLoadResource.Spec.InitContainers)
+	suite.Nil(workLoadResource.PodTemplate)
+}
+
+func (suite *TestSuite) Test_ExtractWorkloadResourceFromAdmissionRequest_CustomResourceGroupMismatch_Error() {
+	suite.config.WorkloadResourceKinds = []*WorkloadResourceKind{
+		{Group: "serving.knative.dev", Version: "v1", Kind: "Service", Resource: "services", PodSpecPaths: []string{"spec.template.spec"}},
+	}
+	req := createReq(createFullDeploymentForTests(), "Service")
+	req.Kind = metav1.GroupVersionKind{Group: "", Version: "v1", Kind: "Service"}
+
+	workLoadResource, err := suite.extractor.ExtractWorkloadResourceFromAdmissionRequest(req)
+
+	suite.Nil(workLoadResource)
+	suite.True(strings.Contains(err.Error(), "Service is unsupported kind of workload resource"))
+}
+
+func (suite *TestSuite) Test_IsSupportedKind_MatchedByGroupVersionAndKind() {
+	suite.config.WorkloadResourceKinds = []*WorkloadResourceKind{
+		{Group: "serving.knative.dev", Version: "v1", Kind: "Service", Resource: "services", PodSpecPaths: []string{"spec.template.spec"}},
+	}
+
+	suite.True(suite.extractor.IsSupportedKind(metav1.GroupVersionKind{Group: "serving.knative.dev", Version: "v1", Kind: "Service"}))
+	suite.False(suite.extractor.IsSupportedKind(metav1.GroupVersionKind{Group: "", Version: "v1", Kind: "Service"}))
+	suite.False(suite.extractor.IsSupportedKind(metav1.GroupVersionKind{Group: "serving.knative.dev", Version: "v2", Kind: "Service"}))
+	suite.True(suite.extractor.IsSupportedKind(metav1.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"}))
+}
+
+func (suite *TestSuite) Test_ExtractWorkloadResourceFromAdmissionRequest_MatchingKindOverridesSupportedKind_ExtractedFromPodSpecPath() {
+	suite.config.WorkloadResourceKinds = []*WorkloadResourceKind{
+		{Group: "*", Version: "*", Kind: "Deployment", Resource: "deployments", PodSpecPaths: []string{"spec.template.spec"}, InitContainersFields: []string{"notInitContainers"}},
+	}
+	req := createReq(createFullDeploymentForTests(), "Deployment")
+
+	workLoadResource, err := suite.extractor.ExtractWorkloadResourceFromAdmissionRequest(req)
+
+	suite.Nil(err)
+	suite.Equal(_expectedContainers, workLoadResource.Spec.Containers)
+	suite.Nil(workLoadResource.Spec.InitContainers)
+}
+
+func (suite *TestSuite) Test_Validate_ValidConfiguration_NoError() {
+	suite.config.WorkloadResourceKinds = []*WorkloadResourceKind{
+		{Group: "argoproj.io", Version: "v1alpha1", Kind: "Rollout", Resource: "rollouts", PodSpecPaths: []string{"spec.template.spec"}},
+	}
+	suite.Nil(suite.config.Validate())
+	suite.config.SupportedKubernetesWorkloadResources = nil
+	suite.Nil(suite.config.Validate())
+}
+
+func (suite *TestSuite) Test_Validate_NoKinds_Error() {
+	suite.config.SupportedKubernetesWorkloadResources = nil
+	suite.True(errors.Is(suite.config.Validate(), utils.InvalidConfiguration))
+}
+
+func (suite *TestSuite) Test_Validate_KindWithoutPodSpecPaths_Error() {
+	suite.config.WorkloadResourceKinds = []*WorkloadResourceKind{{Group: "argoproj.io", Version: "v1alpha1", Kind: "Rollout", Resource: "rollouts"}}
+	suite.True(errors.Is(suite.config.Validate(), utils.InvalidConfiguration))
+}
+
 func TestExtractWorkloadResourceFromAdmissionRequest(t *testing.T) {
 	suite.Run(t, new(TestSuite))
 }
@@ -322,8 +476,8 @@ func createFullReplicationControllerForTests() *corev1.ReplicationController {
 }
 
 func createFullWorkloadResourceForTests() *WorkloadResource {
-	return newWorkLoadResource(newObjectMetadata(_name, _namespace, _annotation, _expectedOwnerReferences),
-		newSpec(_expectedContainers, _expectedInitContainers, _expectedImagePullSecrets, _serviceAccountName))
+	return newWorkLoadResource(newObjectMetadata(_name, "", _namespace, _annotation, nil, _expectedOwnerReferences),
+		newSpec(_expectedContainers, _expectedInitContainers, _expectedImagePullSecrets, _serviceAccountName), nil)
 }
 func createEmptyPodForTests() *corev1.Pod {
 	return &corev1.Pod{}
@@ -337,8 +491,8 @@ func createPodWithEmptyPropertiesForTests() *corev1.Pod {
 }
 
 func createEmptyWorkloadResourceForTests() *WorkloadResource {
-	return newWorkLoadResource(newObjectMetadata("", "", nil, nil),
-		newEmptySpec())
+	return newWorkLoadResource(newObjectMetadata("", "", "", nil, nil, nil),
+		newEmptySpec(), nil)
 }
 
 func createReq(resource interface{}, kind string) *admission.Request {
@@ -0,0 +1,90 @@
+package admisionrequest
+
+import (
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// _anyGroupOrVersion matches all the groups/versions in WorkloadResourceKind
+	_anyGroupOrVersion = "*"
+	// _podSpecPathSeparator is the separator of the fields in the pod spec paths of WorkloadResourceKind (e.g. "spec.template.spec")
+	_podSpecPathSeparator = "."
+	// _podTemplateSpecField is the field of the pod spec in a pod template
+	_podTemplateSpecField = "spec"
+)
+
+var (
+	// _defaultContainersFields are the container list fields of a core pod spec
+	_defaultContainersFields = []string{_containersConst}
+	// _defaultInitContainersFields are the init container list fields of a core pod spec
+	_defaultInitContainersFields = []string{_initContainersConst}
+)
+
+// WorkloadResourceKind represents a kind of workload resource (built-in or custom resource, e.g. Argo Rollout) and the paths of its pod spec.
+// The same list generates the rules of the mutating webhook in the helm chart.
+type WorkloadResourceKind struct {
+	// Group is the api group of the kind ("" is the core group, "*" matches all the groups)
+	Group string
+	// Version is the api version of the kind ("*" matches all the versions)
+	Version string
+	// Kind is the kind of the workload resource (e.g. "Rollout")
+	Kind string
+	// Resource is the plural resource name of the kind (e.g. "rollouts") - used in the rules of the mutating webhook
+	Resource string
+	// PodSpecPaths are the dot separated paths of the pod spec (e.g. "spec.template.spec") - the first existing path is extracted.
+	// In case that the path ends with "spec" and has a parent, the parent is the pod template (its metadata is patched).
+	PodSpecPaths []string
+	// ContainersFields are the container list fields of the pod spec (e.g. "steps" of a Tekton TaskRun). Empty means "containers".
+	ContainersFields []string
+	// InitContainersFields are the init container list fields of the pod spec. Empty means "initContainers".
+	InitContainersFields []string
+}
+
+// matches returns true if the group, version and kind of the request match the WorkloadResourceKind.
+func (workloadResourceKind *WorkloadResourceKind) matches(gvk metav1.GroupVersionKind) bool {
+	return workloadResourceKind.Kind == gvk.Kind &&
+		(workloadResourceKind.Group == _anyGroupOrVersion || workloadResourceKind.Group == gvk.Group) &&
+		(workloadResourceKind.Version == _anyGroupOrVersion || workloadResourceKind.Version == gvk.Version)
+}
+
+// getPodSpecPaths returns the pod spec paths of the WorkloadResourceKind split to fields.
+func (workloadResourceKind *WorkloadResourceKind) getPodSpecPaths() [][]string {
+	podSpecPaths := make([][]string, 0, len(workloadResourceKind.PodSpecPaths))
+	for _, podSpecPath := range workloadResourceKind.PodSpecPaths {
+		podSpecPaths = append(podSpecPaths, strings.Split(podSpecPath, _podSpecPathSeparator))
+	}
+	return podSpecPaths
+}
+
+// getContainersFields returns the container list fields of the WorkloadResourceKind, or the fields of a core pod spec if not set.
+func (workloadResourceKind *WorkloadResourceKind) getContainersFields() []string {
+	if len(workloadResourceKind.ContainersFields) == 0 {
+		return _defaultContainersFields
+	}
+	return workloadResourceKind.ContainersFields
+}
+
+// getInitContainersFields returns the init container list fields of the WorkloadResourceKind, or the fields of a core pod spec if not set.
+func (workloadResourceKind *WorkloadResourceKind) getInitContainersFields() []string {
+	if len(workloadResourceKind.InitContainersFields) == 0 {
+		return _defaultInitContainersFields
+	}
+	return workloadResourceKind.InitContainersFields
+}
+
+// newConventionalWorkloadResourceKind returns a WorkloadResourceKind of a kind of SupportedKubernetesWorkloadResources -
+// the pod spec is in one of the conventional pod spec paths of the built-in workload resources.
+func newConventionalWorkloadResourceKind(kind string) *WorkloadResourceKind {
+	podSpecPaths := make([]string, 0, len(_conventionalPodSpecPaths))
+	for _, podSpecPath := range _conventionalPodSpecPaths {
+		podSpecPaths = append(podSpecPaths, strings.Join(podSpecPath, _podSpecPathSeparator))
+	}
+	return &WorkloadResourceKind{Group: _anyGroupOrVersion, Version: _anyGroupOrVersion, Kind: kind, PodSpecPaths: podSpecPaths}
+}
+
+// isPodTemplateSpecPath returns true if the parent of the pod spec path is a pod template (e.g. spec.template of spec.template.spec).
+func isPodTemplateSpecPath(podSpecPath []string) bool {
+	return len(podSpecPath) >= 2 && podSpecPath[len(podSpecPath)-1] == _podTemplateSpecField
+}
@@ -14,44 +14,57 @@ import (
 const (
 	// _addPatchOperation operation type in json patch to add annotations
 	_addPatchOperation = "add"
+	// _removePatchOperation operation type in json patch to remove annotations
+	_removePatchOperation = "remove"
+	// _metadataField is the metadata field of the object
+	_metadataField = "metadata"
+	// _annotationsField is the annotations field of the metadata of the object
+	_annotationsField = "annotations"
+)
+
+var (
 	// _annotationPatchPath operation path in json patch to add object annotations
-	_annotationPatchPath = "/metadata/annotations"
+	_annotationPatchPath = utils.JoinJSONPointer(_metadataField, _annotationsField)
+
+	// _scanInfoAnnotationNames are the scan info annotations of all the schema versions
+	_scanInfoAnnotationNames = []string{contracts.ContainersVulnerabilityScanInfoAnnotationName, contracts.ContainersVulnerabilityScanInfoV2AnnotationName}
 )
 
-// CreateContainersVulnerabilityScanAnnotationPatchAdd returns an add type json patch in order to add to annotations map a new key value of ContainersVulnerabilityScanInfoAnnotationName.
-// It does so by adding to the exiting map the new key value and setting the updated map as the json patch value.
+// CreateContainersVulnerabilityScanAnnotationPatchAdd returns json patches in order to set the ContainersVulnerabilityScanInfoAnnotationName key of the annotations.
+// The patches are key-level - only the scan info annotations are added (or removed), so annotations that other mutating webhooks
+// in the chain add aren't overridden, and the other annotations of the object aren't re-sent.
+// The key is escaped in the json patch path according to RFC 6901 (e.g. /metadata/annotations/azuredefender.io~1containers.vulnerability.scan.info).
 // The function creates a scanInfoList from the provided containers scan info  slice of type contracts.ContainerVulnerabilityScanInfoList serialize/marshal it and set it as a value string to the new key annotation
 // Contracts.ContainersVulnerabilityScanInfoAnnotationName (azuredefender.io/containers.vulnerability.scan.info)
-// If the annotations map doesn't exist, it creates a new map and add the key value before setting it as the json patch value.
-// As a result, the annotations are updated with no override of the existing values.
-func CreateContainersVulnerabilityScanAnnotationPatchAdd(containersScanInfoList []*contracts.ContainerVulnerabilityScanInfo, workloadResource *admisionrequest.WorkloadResource) (*jsonpatch.JsonPatchOperation, error) {
-	scanInfoList := &contracts.ContainerVulnerabilityScanInfoList{
-		GeneratedTimestamp: time.Now().UTC(),
-		Containers:         containersScanInfoList,
+// If the annotations map doesn't exist, an add patch of an empty map is created before the patches of the keys.
+// In case that the scan info exceeds the size budget of the configuration, it's encoded with the oversized encoding (summarized or gzip).
+// The annotation of each schema version of the configuration is set (v1 - ContainersVulnerabilityScanInfoAnnotationName,
+// v2 - ContainersVulnerabilityScanInfoV2AnnotationName), and the stale annotations of the other schema versions are removed.
+// The annotations of the WorkloadResource are updated accordingly.
+// vulnerabilityReportName is the name of the ContainerVulnerabilityReport of the workload that the annotations point to (empty if no report is written).
+func CreateContainersVulnerabilityScanAnnotationPatchAdd(containersScanInfoList []*contracts.ContainerVulnerabilityScanInfo, workloadResource *admisionrequest.WorkloadResource, configuration *ScanInfoAnnotationConfiguration, vulnerabilityReportName string) ([]jsonpatch.JsonPatchOperation, error) {
+	if workloadResource == nil || workloadResource.Metadata == nil || configuration == nil {
+		return nil, errors.Wrap(utils.NilArgumentError, "CreateContainersVulnerabilityScanAnnotationPatchAdd got nil WorkloadResource or configuration")
 	}
-
-	// Marshal the scan info list (annotations can only be strings)
-	serVulnerabilitySecInfo, err := marshalAnnotationInnerObject(scanInfoList)
-	if err != nil {
-		return nil, errors.Wrap(err, "AzdAnnotationsPatchGenerator failed marshaling scanInfoList during CreateContainersVulnerabilityScanAnnotationPatchAdd")
+	patches := []jsonpatch.JsonPatchOperation{}
+	// Create the annotations map only if it's missing - otherwise the existing annotations would be replaced
+	if workloadResource.Metadata.Annotations == nil {
+		workloadResource.Metadata.Annotations = make(map[string]string)
+		patches = append(patches, jsonpatch.NewOperation(_addPatchOperation, _annotationPatchPath, map[string]string{}))
 	}
 
-	// Create annotations map and add to the map serVulnerabilitySecInfo. If the WorkloadResource's annotations is nil create a new map
-	annotations, err := updateAnnotations(workloadResource, contracts.ContainersVulnerabilityScanInfoAnnotationName, serVulnerabilitySecInfo)
+	scanInfoPatches, err := createScanInfoAnnotationPatches(containersScanInfoList, workloadResource.Metadata.Annotations, []string{_metadataField}, configuration, vulnerabilityReportName)
 	if err != nil {
-		return nil, errors.Wrap(err, "AzdAnnotationsPatchGenerator failed updating annotations because WorkloadResource is nil during CreateContainersVulnerabilityScanAnnotationPatchAdd")
+		return nil, errors.Wrap(err, "AzdAnnotationsPatchGenerator failed to create the scan info annotations patches during CreateContainersVulnerabilityScanAnnotationPatchAdd")
 	}
-
-	// Create an add operation to annotations to add or create if annotations are empty
-	// **important note** any future changes to the WorkloadResource's annotation map will result in changing the json patch because annotations is a map reference.
-	patch := jsonpatch.NewOperation(_addPatchOperation, _annotationPatchPath, annotations)
-	return &patch, nil
+	return append(patches, scanInfoPatches...), nil
 }
 
-// CreateAnnotationPatchToDeleteContainersVulnerabilityScanAnnotationIfNeeded create a patch to delete ContainersVulnerabilityScanAnnotation (stale annotations) if the WorkloadResource's annotations contain ContainersVulnerabilityScanAnnotation.
+// CreateAnnotationPatchToDeleteContainersVulnerabilityScanAnnotationIfNeeded create remove patches of the ContainersVulnerabilityScanAnnotation
+// of each schema version (stale annotations) that the WorkloadResource's annotations contain.
 // Otherwise, no deletion is needed - return nil.
-func CreateAnnotationPatchToDeleteContainersVulnerabilityScanAnnotationIfNeeded(workloadResource *admisionrequest.WorkloadResource) (*jsonpatch.JsonPatchOperation, error) {
-	if workloadResource == nil {
+func CreateAnnotationPatchToDeleteContainersVulnerabilityScanAnnotationIfNeeded(workloadResource *admisionrequest.WorkloadResource) ([]jsonpatch.JsonPatchOperation, error) {
+	if workloadResource == nil || workloadResource.Metadata == nil {
 		return nil, errors.Wrap(utils.NilArgumentError, "CreateAnnotationPatchToDeleteContainersVulnerabilityScanAnnotationIfNeeded got nil WorkloadResource")
 	}
 
@@ -60,12 +73,54 @@ func CreateAnnotationPatchToDeleteContainersVulnerabilityScanAnnotationIfNeeded(
 		return nil, nil
 	}
 
-	// Create annotations map after deleting contracts.ContainersVulnerabilityScanInfoAnnotationName
-	annotations := deleteAzdAnnotations(workloadResource.Metadata.Annotations)
+	var patches []jsonpatch.JsonPatchOperation
+	for _, key := range _scanInfoAnnotationNames {
+		patches = append(patches, removeAnnotationIfExists(workloadResource.Metadata.Annotations, []string{_metadataField}, key)...)
+	}
+	return patches, nil
+}
+
+// createScanInfoAnnotationPatches returns the patches that set the scan info annotation of each schema version of the configuration
+// in the annotations of the metadata in metadataPath, and remove the stale annotations of the other schema versions.
+// The annotations map must exist - it's updated accordingly.
+func createScanInfoAnnotationPatches(containersScanInfoList []*contracts.ContainerVulnerabilityScanInfo, annotations map[string]string, metadataPath []string, configuration *ScanInfoAnnotationConfiguration, vulnerabilityReportName string) ([]jsonpatch.JsonPatchOperation, error) {
+	schemaVersions, err := configuration.GetSchemaVersions()
+	if err != nil {
+		return nil, errors.Wrap(err, "AzdAnnotationsPatchGenerator failed to get the schema versions during createScanInfoAnnotationPatches")
+	}
+	generatedTimestamp := time.Now().UTC()
+
+	patches := []jsonpatch.JsonPatchOperation{}
+	// The v2 annotation is set first, so its size is taken into account in the size budget of the v1 annotation
+	if schemaVersions[contracts.SchemaVersionV2] {
+		scanInfoListV2 := createScanInfoListV2(generatedTimestamp, containersScanInfoList)
+		scanInfoListV2.VulnerabilityReport = vulnerabilityReportName
+		serVulnerabilitySecInfoV2, err := encodeScanInfoListV2(scanInfoListV2, configuration, annotations)
+		if err != nil {
+			return nil, errors.Wrap(err, "AzdAnnotationsPatchGenerator failed marshaling v2 scanInfoList during createScanInfoAnnotationPatches")
+		}
+		patches = append(patches, setAnnotation(annotations, metadataPath, contracts.ContainersVulnerabilityScanInfoV2AnnotationName, serVulnerabilitySecInfoV2))
+	} else {
+		patches = append(patches, removeAnnotationIfExists(annotations, metadataPath, contracts.ContainersVulnerabilityScanInfoV2AnnotationName)...)
+	}
 
-	// Create an add operation to annotations to add
-	patch := jsonpatch.NewOperation(_addPatchOperation, _annotationPatchPath, annotations)
-	return &patch, nil
+	if schemaVersions[contracts.SchemaVersionV1] {
+		scanInfoList := &contracts.ContainerVulnerabilityScanInfoList{
+			GeneratedTimestamp:  generatedTimestamp,
+			Containers:          containersScanInfoList,
+			VulnerabilityReport: vulnerabilityReportName,
+		}
+
+		// Marshal the scan info list (annotations can only be strings)
+		serVulnerabilitySecInfo, err := encodeScanInfoList(scanInfoList, configuration, annotations)
+		if err != nil {
+			return nil, errors.Wrap(err, "AzdAnnotationsPatchGenerator failed marshaling scanInfoList during createScanInfoAnnotationPatches")
+		}
+		patches = append(patches, setAnnotation(annotations, metadataPath, contracts.ContainersVulnerabilityScanInfoAnnotationName, serVulnerabilitySecInfo))
+	} else {
+		patches = append(patches, removeAnnotationIfExists(annotations, metadataPath, contracts.ContainersVulnerabilityScanInfoAnnotationName)...)
+	}
+	return patches, nil
 }
 
 // marshalAnnotationInnerObject marshaling provided object needed to be set as string in annotations to json represented string
@@ -81,20 +136,26 @@ func marshalAnnotationInnerObject(object interface{}) (string, error) {
 	return ser, nil
 }
 
-// updateAnnotations update the annotations of a given WorkloadResource with the given key and value.
-// If annotations map not exist - create a new map and add the key.
-// Return the annotations map
-func updateAnnotations(workloadResource *admisionrequest.WorkloadResource, key string, value string) (map[string]string, error) {
-	if workloadResource == nil {
-		return nil, errors.Wrap(utils.NilArgumentError, "updateAnnotations got nil WorkloadResource")
-	}
-	annotations := workloadResource.Metadata.Annotations
-	if annotations == nil {
-		annotations = make(map[string]string)
-		workloadResource.Metadata.Annotations = annotations
-	}
+// setAnnotation sets the key of the annotations (the annotations map must exist) to the value,
+// and returns a key-level add patch of the annotation of the metadata in metadataPath.
+func setAnnotation(annotations map[string]string, metadataPath []string, key string, value string) jsonpatch.JsonPatchOperation {
 	annotations[key] = value
-	return annotations, nil
+	return jsonpatch.NewOperation(_addPatchOperation, getAnnotationPatchPath(metadataPath, key), value)
+}
+
+// removeAnnotationIfExists deletes the key from the annotations and returns a key-level remove patch of the annotation of the metadata in metadataPath.
+// A remove patch of a missing path fails, so in case that the key doesn't exist - no patch is returned.
+func removeAnnotationIfExists(annotations map[string]string, metadataPath []string, key string) []jsonpatch.JsonPatchOperation {
+	if _, ok := annotations[key]; !ok {
+		return nil
+	}
+	delete(annotations, key)
+	return []jsonpatch.JsonPatchOperation{jsonpatch.NewOperation(_removePatchOperation, getAnnotationPatchPath(metadataPath, key), nil)}
+}
+
+// getAnnotationPatchPath returns the json patch path of the annotation key of the metadata in metadataPath, escaped according to RFC 6901.
+func getAnnotationPatchPath(metadataPath []string, key string) string {
+	return utils.JoinJSONPointer(append(append([]string{}, metadataPath...), _annotationsField, key)...)
 }
 
 // isDeleteStaleAzdAnnotationsNeeded returns true if delete stale Azd annotations is needed, otherwise false.
@@ -103,16 +164,12 @@ func isDeleteStaleAzdAnnotationsNeeded(annotations map[string]string) bool {
 	if annotations == nil {
 		return false
 	}
-	// key don't exist - no need to delete
-	if _, ok := annotations[contracts.ContainersVulnerabilityScanInfoAnnotationName]; !ok {
-		return false
+	// key of one of the schema versions exist - need to delete
+	for _, key := range _scanInfoAnnotationNames {
+		if _, ok := annotations[key]; ok {
+			return true
+		}
 	}
-	// key exist - need to delete
-	return true
-}
-
-// deleteAzdAnnotations return the WorkloadResource's annotations after deleting contracts.ContainersVulnerabilityScanInfoAnnotationName.
-func deleteAzdAnnotations(annotations map[string]string) map[string]string {
-	delete(annotations, contracts.ContainersVulnerabilityScanInfoAnnotationName)
-	return annotations
+	// keys don't exist - no need to delete
+	return false
 }
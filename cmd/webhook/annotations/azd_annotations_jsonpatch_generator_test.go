@@ -4,18 +4,25 @@ import (
 	"encoding/json"
 	"github.com/Azure/AzureDefender-K8S-InClusterDefense/cmd/webhook/admisionrequest"
 	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/azdsecinfo/contracts"
+	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/utils"
+	jsonpatchapplier "github.com/evanphx/json-patch"
+	"github.com/pkg/errors"
 	"github.com/stretchr/testify/suite"
+	"gomodules.xyz/jsonpatch/v2"
 	"testing"
 	"time"
 )
 
 const (
-	_expectedTestAddPatchOperation   = "add"
-	_expectedTestAnnotationPatchPath = "/metadata/annotations"
-	_annotationTestKeyOne            = "cluster-autoscaler.kubernetes.io/safe-to-evict"
-	_annotationTestValueOne          = "true"
-	_annotationTestKeyTwo            = "container.seccomp.security.alpha.kubernetes.io/manager"
-	_annotationTestValueTwo          = "runtime/default"
+	_expectedTestAddPatchOperation    = "add"
+	_expectedTestRemovePatchOperation = "remove"
+	_expectedTestAnnotationPatchPath  = "/metadata/annotations"
+	_expectedTestScanInfoPatchPath    = "/metadata/annotations/azuredefender.io~1containers.vulnerability.scan.info"
+	_expectedTestScanInfoV2PatchPath  = "/metadata/annotations/azuredefender.io~1containers.vulnerability.scan.info.v2"
+	_annotationTestKeyOne             = "cluster-autoscaler.kubernetes.io/safe-to-evict"
+	_annotationTestValueOne           = "true"
+	_annotationTestKeyTwo             = "container.seccomp.security.alpha.kubernetes.io/manager"
+	_annotationTestValueTwo           = "runtime/default"
 )
 
 type TestSuite struct {
@@ -77,20 +84,109 @@ func (suite *TestSuite) Test_CreateContainersVulnerabilityScanAnnotationPatchAdd
 	suite.checkNoOverrideOfExistingAnnotations(mapAnnotations, _annotationTestKeyTwo, _annotationTestValueTwo)
 }
 
-func (suite *TestSuite) Test_DeleteContainersVulnerabilityScanAnnotationPatch_PodWithAzdAnnotations_AnnotationsGeneratedAsExpected() {
-	result, err := CreateAnnotationPatchToDeleteContainersVulnerabilityScanAnnotationIfNeeded(createWorkloadResourceWithAzdAnnotationsForTest())
+func (suite *TestSuite) Test_CreateContainersVulnerabilityScanAnnotationPatchAdd_PodWithoutAnnotations_MapCreatedBeforeKey() {
+	result, err := CreateContainersVulnerabilityScanAnnotationPatchAdd(suite.containersScanInfo, createWorkloadResourceWithoutAnnotationsForTest(), &ScanInfoAnnotationConfiguration{}, "")
+	suite.Nil(err)
+	suite.Equal(2, len(result))
+	suite.Equal(jsonpatch.NewOperation(_expectedTestAddPatchOperation, _expectedTestAnnotationPatchPath, map[string]string{}), result[0])
+	suite.Equal(_expectedTestAddPatchOperation, result[1].Operation)
+	suite.Equal(_expectedTestScanInfoPatchPath, result[1].Path)
+}
+
+func (suite *TestSuite) Test_CreateContainersVulnerabilityScanAnnotationPatchAdd_PodWithAnnotations_OnlyKeyPatched() {
+	result, err := CreateContainersVulnerabilityScanAnnotationPatchAdd(suite.containersScanInfo, createWorkloadResourceWithAnnotationsForTest(), &ScanInfoAnnotationConfiguration{}, "")
 	suite.Nil(err)
-	mapAnnotations, ok := result.Value.(map[string]string)
+	suite.Equal(1, len(result))
+	suite.Equal(_expectedTestAddPatchOperation, result[0].Operation)
+	suite.Equal(_expectedTestScanInfoPatchPath, result[0].Path)
+	_, ok := result[0].Value.(string)
 	suite.True(ok)
+}
+
+func (suite *TestSuite) Test_CreateContainersVulnerabilityScanAnnotationPatchAdd_AnnotationAddedByPreviousWebhook_NotOverridden() {
+	// The object of the request is the object that the previous mutating webhooks in the chain patched
+	previousWebhookPatches := []jsonpatch.JsonPatchOperation{
+		jsonpatch.NewOperation(_expectedTestAddPatchOperation, _expectedTestAnnotationPatchPath, map[string]string{}),
+		jsonpatch.NewOperation(_expectedTestAddPatchOperation, "/metadata/annotations/sidecar.istio.io~1status", "injected"),
+	}
+	object := suite.applyPatches(createObjectForTest(nil), previousWebhookPatches)
+	workloadResource := createWorkloadResourceWithoutAnnotationsForTest()
+	workloadResource.Metadata.Annotations = suite.getAnnotations(object)
+
+	result, err := CreateContainersVulnerabilityScanAnnotationPatchAdd(suite.containersScanInfo, workloadResource, &ScanInfoAnnotationConfiguration{}, "")
+	suite.Nil(err)
+
+	mapAnnotations := suite.getAnnotations(suite.applyPatches(object, result))
 	suite.Equal(2, len(mapAnnotations))
+	suite.checkNoOverrideOfExistingAnnotations(mapAnnotations, "sidecar.istio.io/status", "injected")
+}
+
+func (suite *TestSuite) Test_CreateContainersVulnerabilityScanAnnotationPatchAdd_AnnotationAddedByNextWebhook_NotOverridden() {
+	// The next mutating webhook in the chain (or a reinvocation of the webhooks) patches the object after the webhook
+	workloadResource := createWorkloadResourceWithAnnotationsForTest()
+	object := createObjectForTest(workloadResource.Metadata.Annotations)
+	result, err := CreateContainersVulnerabilityScanAnnotationPatchAdd(suite.containersScanInfo, workloadResource, &ScanInfoAnnotationConfiguration{}, "")
+	suite.Nil(err)
+	object = suite.applyPatches(object, result)
+	object = suite.applyPatches(object, []jsonpatch.JsonPatchOperation{jsonpatch.NewOperation(_expectedTestAddPatchOperation, "/metadata/annotations/sidecar.istio.io~1status", "injected")})
+
+	// Reinvocation of the webhook on the object that the next webhook patched
+	workloadResource.Metadata.Annotations = suite.getAnnotations(object)
+	result, err = CreateContainersVulnerabilityScanAnnotationPatchAdd(suite.containersScanInfo, workloadResource, &ScanInfoAnnotationConfiguration{}, "")
+	suite.Nil(err)
 
-	_, ok = mapAnnotations[contracts.ContainersVulnerabilityScanInfoAnnotationName]
+	mapAnnotations := suite.getAnnotations(suite.applyPatches(object, result))
+	suite.Equal(4, len(mapAnnotations))
+	suite.checkNoOverrideOfExistingAnnotations(mapAnnotations, "sidecar.istio.io/status", "injected")
+	suite.checkNoOverrideOfExistingAnnotations(mapAnnotations, _annotationTestKeyOne, _annotationTestValueOne)
+}
+
+func (suite *TestSuite) Test_CreateContainersVulnerabilityScanAnnotationPatchAdd_PatchesAppliedOnConcurrentlyChangedObject_OnlyScanInfoChanged() {
+	// The patches are applied on an object that has annotations that the workload resource didn't have
+	result, err := CreateContainersVulnerabilityScanAnnotationPatchAdd(suite.containersScanInfo, createWorkloadResourceWithAzdAnnotationsForTest(), &ScanInfoAnnotationConfiguration{}, "")
+	suite.Nil(err)
+	object := createObjectForTest(map[string]string{
+		_annotationTestKeyOne: "false",
+		"other.io/annotation": "value",
+		contracts.ContainersVulnerabilityScanInfoAnnotationName: "some value",
+	})
+
+	mapAnnotations := suite.getAnnotations(suite.applyPatches(object, result))
+	suite.Equal(3, len(mapAnnotations))
+	suite.checkNoOverrideOfExistingAnnotations(mapAnnotations, _annotationTestKeyOne, "false")
+	suite.checkNoOverrideOfExistingAnnotations(mapAnnotations, "other.io/annotation", "value")
+	suite.NotEqual("some value", mapAnnotations[contracts.ContainersVulnerabilityScanInfoAnnotationName])
+}
+
+func (suite *TestSuite) Test_DeleteContainersVulnerabilityScanAnnotationPatch_PodWithAzdAnnotations_AnnotationsGeneratedAsExpected() {
+	workloadResource := createWorkloadResourceWithAzdAnnotationsForTest()
+	object := createObjectForTest(workloadResource.Metadata.Annotations)
+	result, err := CreateAnnotationPatchToDeleteContainersVulnerabilityScanAnnotationIfNeeded(workloadResource)
+	suite.Nil(err)
+	suite.Equal([]jsonpatch.JsonPatchOperation{jsonpatch.NewOperation(_expectedTestRemovePatchOperation, _expectedTestScanInfoPatchPath, nil)}, result)
+
+	mapAnnotations := suite.getAnnotations(suite.applyPatches(object, result))
+	suite.Equal(2, len(mapAnnotations))
+	_, ok := mapAnnotations[contracts.ContainersVulnerabilityScanInfoAnnotationName]
 	suite.False(ok)
 	// check no override of existing annotations
 	suite.checkNoOverrideOfExistingAnnotations(mapAnnotations, _annotationTestKeyOne, _annotationTestValueOne)
 	suite.checkNoOverrideOfExistingAnnotations(mapAnnotations, _annotationTestKeyTwo, _annotationTestValueTwo)
 }
 
+func (suite *TestSuite) Test_DeleteContainersVulnerabilityScanAnnotationPatch_AnnotationAddedByPreviousWebhook_NotOverridden() {
+	workloadResource := createWorkloadResourceWithAzdAnnotationsForTest()
+	workloadResource.Metadata.Annotations["sidecar.istio.io/status"] = "injected"
+	object := createObjectForTest(workloadResource.Metadata.Annotations)
+
+	result, err := CreateAnnotationPatchToDeleteContainersVulnerabilityScanAnnotationIfNeeded(workloadResource)
+	suite.Nil(err)
+
+	mapAnnotations := suite.getAnnotations(suite.applyPatches(object, result))
+	suite.Equal(3, len(mapAnnotations))
+	suite.checkNoOverrideOfExistingAnnotations(mapAnnotations, "sidecar.istio.io/status", "injected")
+}
+
 func (suite *TestSuite) Test_DeleteContainersVulnerabilityScanAnnotationPatch_PodWithoutAnnotations_AnnotationsGeneratedAsExpected() {
 
 	result, err := CreateAnnotationPatchToDeleteContainersVulnerabilityScanAnnotationIfNeeded(createWorkloadResourceWithoutAnnotationsForTest())
@@ -105,14 +201,72 @@ func (suite *TestSuite) Test_DeleteContainersVulnerabilityScanAnnotationPatch_Po
 	suite.Nil(result)
 }
 
+func (suite *TestSuite) Test_CreateContainersVulnerabilityScanAnnotationPatchAdd_SchemaVersionV2_OnlyV2AnnotationGenerated() {
+	workloadResource := createWorkloadResourceWithAzdAnnotationsForTest()
+	object := createObjectForTest(workloadResource.Metadata.Annotations)
+	result, err := CreateContainersVulnerabilityScanAnnotationPatchAdd(suite.containersScanInfo, workloadResource, &ScanInfoAnnotationConfiguration{SchemaVersions: []string{"v2"}}, "")
+	suite.Nil(err)
+	suite.Equal(2, len(result))
+	suite.Equal(_expectedTestScanInfoV2PatchPath, result[0].Path)
+	// Stale v1 annotation is removed
+	suite.Equal(jsonpatch.NewOperation(_expectedTestRemovePatchOperation, _expectedTestScanInfoPatchPath, nil), result[1])
+
+	mapAnnotations := suite.getAnnotations(suite.applyPatches(object, result))
+	suite.Equal(3, len(mapAnnotations))
+	_, ok := mapAnnotations[contracts.ContainersVulnerabilityScanInfoAnnotationName]
+	suite.False(ok)
+	scanInfoList := new(contracts.ContainerVulnerabilityScanInfoListV2)
+	suite.Nil(json.Unmarshal([]byte(mapAnnotations[contracts.ContainersVulnerabilityScanInfoV2AnnotationName]), scanInfoList))
+	suite.Equal(contracts.SchemaVersionV2, scanInfoList.SchemaVersion)
+	suite.Equal(2, len(scanInfoList.Containers))
+	suite.Equal("High", scanInfoList.Containers[0].Summary.MaxSeverity)
+}
+
+func (suite *TestSuite) Test_CreateContainersVulnerabilityScanAnnotationPatchAdd_SchemaVersionsV1AndV2_BothAnnotationsGenerated() {
+	workloadResource := createWorkloadResourceWithoutAnnotationsForTest()
+	object := createObjectForTest(nil)
+	result, err := CreateContainersVulnerabilityScanAnnotationPatchAdd(suite.containersScanInfo, workloadResource, &ScanInfoAnnotationConfiguration{SchemaVersions: []string{"v1", "v2"}}, "pod-podtest")
+	suite.Nil(err)
+	mapAnnotations := suite.getAnnotations(suite.applyPatches(object, result))
+	suite.Equal(2, len(mapAnnotations))
+	suite.Equal(workloadResource.Metadata.Annotations, mapAnnotations)
+
+	scanInfoList := new(contracts.ContainerVulnerabilityScanInfoList)
+	suite.Nil(json.Unmarshal([]byte(mapAnnotations[contracts.ContainersVulnerabilityScanInfoAnnotationName]), scanInfoList))
+	suite.Equal(contracts.SchemaVersionV1, scanInfoList.SchemaVersion)
+	scanInfoListV2 := new(contracts.ContainerVulnerabilityScanInfoListV2)
+	suite.Nil(json.Unmarshal([]byte(mapAnnotations[contracts.ContainersVulnerabilityScanInfoV2AnnotationName]), scanInfoListV2))
+	suite.Equal(scanInfoList.GeneratedTimestamp, scanInfoListV2.GeneratedTimestamp)
+	suite.Equal("pod-podtest", scanInfoList.VulnerabilityReport)
+	suite.Equal("pod-podtest", scanInfoListV2.VulnerabilityReport)
+}
+
+func (suite *TestSuite) Test_CreateContainersVulnerabilityScanAnnotationPatchAdd_UnknownSchemaVersion_Error() {
+	result, err := CreateContainersVulnerabilityScanAnnotationPatchAdd(suite.containersScanInfo, createWorkloadResourceWithoutAnnotationsForTest(), &ScanInfoAnnotationConfiguration{SchemaVersions: []string{"v3"}}, "")
+	suite.Equal(utils.InvalidConfiguration, errors.Cause(err))
+	suite.Nil(result)
+}
+
+func (suite *TestSuite) Test_DeleteContainersVulnerabilityScanAnnotationPatch_PodWithV2Annotation_AnnotationDeleted() {
+	workloadResource := createWorkloadResourceWithAnnotationsForTest()
+	workloadResource.Metadata.Annotations[contracts.ContainersVulnerabilityScanInfoV2AnnotationName] = "some value"
+	object := createObjectForTest(workloadResource.Metadata.Annotations)
+
+	result, err := CreateAnnotationPatchToDeleteContainersVulnerabilityScanAnnotationIfNeeded(workloadResource)
+	suite.Nil(err)
+	suite.Equal([]jsonpatch.JsonPatchOperation{jsonpatch.NewOperation(_expectedTestRemovePatchOperation, _expectedTestScanInfoV2PatchPath, nil)}, result)
+	suite.Equal(2, len(suite.getAnnotations(suite.applyPatches(object, result))))
+}
+
 func (suite *TestSuite) checkContainersVulnerabilityScanAnnotation(patchLen int, pod *admisionrequest.WorkloadResource) map[string]string {
-	result, err := CreateContainersVulnerabilityScanAnnotationPatchAdd(suite.containersScanInfo, pod)
+	object := createObjectForTest(pod.Metadata.Annotations)
+	result, err := CreateContainersVulnerabilityScanAnnotationPatchAdd(suite.containersScanInfo, pod, &ScanInfoAnnotationConfiguration{}, "")
 	suite.Nil(err)
-	suite.Equal(_expectedTestAddPatchOperation, result.Operation)
-	suite.Equal(_expectedTestAnnotationPatchPath, result.Path)
+	scanInfoPatch := result[len(result)-1]
+	suite.Equal(_expectedTestAddPatchOperation, scanInfoPatch.Operation)
+	suite.Equal(_expectedTestScanInfoPatchPath, scanInfoPatch.Path)
 
-	mapAnnotations, ok := result.Value.(map[string]string)
-	suite.True(ok)
+	mapAnnotations := suite.getAnnotations(suite.applyPatches(object, result))
 
 	suite.Equal(patchLen, len(mapAnnotations))
 	strContainersVulnerabilityScanValue, ok := mapAnnotations[contracts.ContainersVulnerabilityScanInfoAnnotationName]
@@ -136,6 +290,28 @@ func (suite *TestSuite) checkNoOverrideOfExistingAnnotations(mapAnnotations map[
 	suite.Equal(expectedVal, strAnnotationField1)
 }
 
+// applyPatches applies the json patches on the object the way that the api server applies the patches of a mutating webhook
+func (suite *TestSuite) applyPatches(object []byte, patches []jsonpatch.JsonPatchOperation) []byte {
+	serPatches, err := json.Marshal(patches)
+	suite.Require().Nil(err)
+	patch, err := jsonpatchapplier.DecodePatch(serPatches)
+	suite.Require().Nil(err)
+	patchedObject, err := patch.Apply(object)
+	suite.Require().Nil(err)
+	return patchedObject
+}
+
+// getAnnotations returns the annotations of the object
+func (suite *TestSuite) getAnnotations(object []byte) map[string]string {
+	unmarshalledObject := struct {
+		Metadata struct {
+			Annotations map[string]string `json:"annotations"`
+		} `json:"metadata"`
+	}{}
+	suite.Require().Nil(json.Unmarshal(object, &unmarshalledObject))
+	return unmarshalledObject.Metadata.Annotations
+}
+
 func TestCreateContainersVulnerabilityScanAnnotationPatchAdd(t *testing.T) {
 	suite.Run(t, new(TestSuite))
 }
@@ -172,3 +348,13 @@ func createWorkloadResourceWithoutAnnotationsForTest() *admisionrequest.Workload
 	workloadResource := admisionrequest.WorkloadResource{Metadata: metadata}
 	return &workloadResource
 }
+
+// createObjectForTest returns a serialized pod with the given annotations (a copy of them, so later changes of the map don't affect it)
+func createObjectForTest(annotations map[string]string) []byte {
+	metadata := map[string]interface{}{"name": "podTest"}
+	if annotations != nil {
+		metadata["annotations"] = annotations
+	}
+	object, _ := json.Marshal(map[string]interface{}{"kind": "Pod", "metadata": metadata})
+	return object
+}
@@ -0,0 +1,100 @@
+package annotations
+
+import (
+	"context"
+	"github.com/Azure/AzureDefender-K8S-InClusterDefense/cmd/webhook/admisionrequest"
+	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/azdsecinfo/contracts"
+	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/instrumentation"
+	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/instrumentation/metric"
+	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/instrumentation/metric/util"
+	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/instrumentation/trace"
+	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/utils"
+	"github.com/pkg/errors"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+var (
+	// _ownerPodTemplateAnnotationsPath is the path of the annotations of the pod template of the controllers that create pods
+	// (ReplicaSet, StatefulSet, DaemonSet, Job, ReplicationController)
+	_ownerPodTemplateAnnotationsPath = []string{"spec", "template", _metadataField, _annotationsField}
+)
+
+// IOwnerPodTemplateScanInfoVerifier verifies that the scan info annotation of a Pod is the annotation of the pod template of its owner
+type IOwnerPodTemplateScanInfoVerifier interface {
+	// IsScanInfoOfOwnerPodTemplate returns true if the controller owner of the Pod has the same v1 scan info annotation in its pod template.
+	// namespace is the namespace of the admission request of the Pod.
+	IsScanInfoOfOwnerPodTemplate(ctx context.Context, namespace string, workloadResource *admisionrequest.WorkloadResource) (bool, error)
+}
+
+// OwnerPodTemplateScanInfoVerifier implements IOwnerPodTemplateScanInfoVerifier interface
+var _ IOwnerPodTemplateScanInfoVerifier = (*OwnerPodTemplateScanInfoVerifier)(nil)
+
+// OwnerPodTemplateScanInfoVerifier is IOwnerPodTemplateScanInfoVerifier that fetches the owner from the API server.
+// The annotations and the owner references of a Pod are set by its creator, so a Pod can claim any owner and any scan info -
+// the annotation is trusted only if the owner with the same UID has it in its pod template (it was set by the webhook on the owner
+// or on the controller of the owner, e.g. the Deployment of a ReplicaSet).
+type OwnerPodTemplateScanInfoVerifier struct {
+	//tracerProvider is tracer provider of OwnerPodTemplateScanInfoVerifier
+	tracerProvider trace.ITracerProvider
+	//metricSubmitter is metric submitter of OwnerPodTemplateScanInfoVerifier
+	metricSubmitter metric.IMetricSubmitter
+	// reader reads the owners from the API server (not from a cache, so a just created owner is found)
+	reader client.Reader
+}
+
+// NewOwnerPodTemplateScanInfoVerifier Ctor for OwnerPodTemplateScanInfoVerifier
+func NewOwnerPodTemplateScanInfoVerifier(instrumentationProvider instrumentation.IInstrumentationProvider, reader client.Reader) *OwnerPodTemplateScanInfoVerifier {
+	return &OwnerPodTemplateScanInfoVerifier{
+		tracerProvider:  instrumentationProvider.GetTracerProvider("OwnerPodTemplateScanInfoVerifier"),
+		metricSubmitter: instrumentationProvider.GetMetricSubmitter(),
+		reader:          reader,
+	}
+}
+
+// IsScanInfoOfOwnerPodTemplate returns true if the controller owner of the Pod, fetched from the API server, has the same UID as the
+// owner reference of the Pod and the same v1 scan info annotation in its pod template. Returns false if the owner isn't found.
+func (verifier *OwnerPodTemplateScanInfoVerifier) IsScanInfoOfOwnerPodTemplate(ctx context.Context, namespace string, workloadResource *admisionrequest.WorkloadResource) (bool, error) {
+	tracer := verifier.tracerProvider.GetTracer("IsScanInfoOfOwnerPodTemplate")
+	if workloadResource == nil || workloadResource.Metadata == nil {
+		err := errors.Wrap(utils.NilArgumentError, "OwnerPodTemplateScanInfoVerifier.IsScanInfoOfOwnerPodTemplate got nil WorkloadResource")
+		tracer.Error(err, "")
+		verifier.metricSubmitter.SendMetric(1, util.NewErrorEncounteredMetric(err, "OwnerPodTemplateScanInfoVerifier.IsScanInfoOfOwnerPodTemplate"))
+		return false, err
+	}
+	scanInfo, ok := workloadResource.Metadata.Annotations[contracts.ContainersVulnerabilityScanInfoAnnotationName]
+	owner := workloadResource.Metadata.GetControllerOwnerReference()
+	if !ok || owner == nil || owner.UID == "" {
+		return false, nil
+	}
+	if workloadResource.Metadata.Namespace != "" {
+		namespace = workloadResource.Metadata.Namespace
+	}
+
+	object := &unstructured.Unstructured{}
+	object.SetGroupVersionKind(schema.FromAPIVersionAndKind(owner.APIVersion, owner.Kind))
+	if err := verifier.reader.Get(ctx, types.NamespacedName{Namespace: namespace, Name: owner.Name}, object); err != nil {
+		if apierrors.IsNotFound(err) {
+			tracer.Info("Owner not found", "namespace", namespace, "kind", owner.Kind, "name", owner.Name)
+			return false, nil
+		}
+		err = errors.Wrap(err, "OwnerPodTemplateScanInfoVerifier.IsScanInfoOfOwnerPodTemplate failed to get the owner")
+		tracer.Error(err, "", "namespace", namespace, "kind", owner.Kind, "name", owner.Name)
+		verifier.metricSubmitter.SendMetric(1, util.NewErrorEncounteredMetric(err, "OwnerPodTemplateScanInfoVerifier.IsScanInfoOfOwnerPodTemplate"))
+		return false, err
+	}
+	if string(object.GetUID()) != owner.UID {
+		tracer.Info("Owner UID doesn't match the owner reference", "namespace", namespace, "kind", owner.Kind, "name", owner.Name)
+		return false, nil
+	}
+
+	ownerAnnotations, found, err := unstructured.NestedStringMap(object.Object, _ownerPodTemplateAnnotationsPath...)
+	if err != nil || !found {
+		tracer.Info("Owner has no pod template annotations", "namespace", namespace, "kind", owner.Kind, "name", owner.Name)
+		return false, nil
+	}
+	return ownerAnnotations[contracts.ContainersVulnerabilityScanInfoAnnotationName] == scanInfo, nil
+}
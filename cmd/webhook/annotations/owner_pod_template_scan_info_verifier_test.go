@@ -0,0 +1,89 @@
+package annotations
+
+import (
+	"context"
+	"github.com/Azure/AzureDefender-K8S-InClusterDefense/cmd/webhook/admisionrequest"
+	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/azdsecinfo/contracts"
+	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/instrumentation"
+	"github.com/stretchr/testify/suite"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"testing"
+)
+
+const (
+	_ownerTestScanInfo = `{"generatedTimestamp":"2021-01-01T00:00:00Z","containers":[]}`
+)
+
+type OwnerPodTemplateScanInfoVerifierTestSuite struct {
+	suite.Suite
+	verifier *OwnerPodTemplateScanInfoVerifier
+}
+
+// This will run before each test in the suite
+func (suite *OwnerPodTemplateScanInfoVerifierTestSuite) SetupTest() {
+	replicaSet := &appsv1.ReplicaSet{
+		ObjectMeta: metav1.ObjectMeta{Name: "replicaSetTest", Namespace: "default", UID: "replicaSetUID"},
+		Spec: appsv1.ReplicaSetSpec{Template: corev1.PodTemplateSpec{
+			ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{contracts.ContainersVulnerabilityScanInfoAnnotationName: _ownerTestScanInfo}},
+		}},
+	}
+	client := fake.NewClientBuilder().WithScheme(clientgoscheme.Scheme).WithObjects(replicaSet).Build()
+	suite.verifier = NewOwnerPodTemplateScanInfoVerifier(instrumentation.NewNoOpInstrumentationProvider(), client)
+}
+
+func (suite *OwnerPodTemplateScanInfoVerifierTestSuite) Test_IsScanInfoOfOwnerPodTemplate_SameAnnotation_True() {
+	isVerified, err := suite.verifier.IsScanInfoOfOwnerPodTemplate(context.Background(), "default", suite.createPod(_ownerTestScanInfo, "replicaSetTest", "replicaSetUID"))
+
+	suite.Nil(err)
+	suite.True(isVerified)
+}
+
+func (suite *OwnerPodTemplateScanInfoVerifierTestSuite) Test_IsScanInfoOfOwnerPodTemplate_ForgedAnnotation_False() {
+	forged := `{"generatedTimestamp":"2021-01-01T00:00:00Z","containers":[{"name":"app","scanStatus":"healthyScan"}]}`
+
+	isVerified, err := suite.verifier.IsScanInfoOfOwnerPodTemplate(context.Background(), "default", suite.createPod(forged, "replicaSetTest", "replicaSetUID"))
+
+	suite.Nil(err)
+	suite.False(isVerified)
+}
+
+func (suite *OwnerPodTemplateScanInfoVerifierTestSuite) Test_IsScanInfoOfOwnerPodTemplate_OtherOwnerUID_False() {
+	isVerified, err := suite.verifier.IsScanInfoOfOwnerPodTemplate(context.Background(), "default", suite.createPod(_ownerTestScanInfo, "replicaSetTest", "otherUID"))
+
+	suite.Nil(err)
+	suite.False(isVerified)
+}
+
+func (suite *OwnerPodTemplateScanInfoVerifierTestSuite) Test_IsScanInfoOfOwnerPodTemplate_OwnerNotFound_False() {
+	isVerified, err := suite.verifier.IsScanInfoOfOwnerPodTemplate(context.Background(), "default", suite.createPod(_ownerTestScanInfo, "otherReplicaSet", "replicaSetUID"))
+
+	suite.Nil(err)
+	suite.False(isVerified)
+}
+
+func (suite *OwnerPodTemplateScanInfoVerifierTestSuite) Test_IsScanInfoOfOwnerPodTemplate_OwnerNotController_False() {
+	pod := suite.createPod(_ownerTestScanInfo, "replicaSetTest", "replicaSetUID")
+	pod.Metadata.OwnerReferences[0].Controller = false
+
+	isVerified, err := suite.verifier.IsScanInfoOfOwnerPodTemplate(context.Background(), "default", pod)
+
+	suite.Nil(err)
+	suite.False(isVerified)
+}
+
+func (suite *OwnerPodTemplateScanInfoVerifierTestSuite) createPod(scanInfo string, ownerName string, ownerUID string) *admisionrequest.WorkloadResource {
+	return &admisionrequest.WorkloadResource{
+		Metadata: &admisionrequest.ObjectMetadata{
+			Annotations:     map[string]string{contracts.ContainersVulnerabilityScanInfoAnnotationName: scanInfo},
+			OwnerReferences: []*admisionrequest.OwnerReference{{APIVersion: "apps/v1", Kind: "ReplicaSet", Name: ownerName, UID: ownerUID, Controller: true}},
+		},
+	}
+}
+
+func TestOwnerPodTemplateScanInfoVerifierTestSuite(t *testing.T) {
+	suite.Run(t, new(OwnerPodTemplateScanInfoVerifierTestSuite))
+}
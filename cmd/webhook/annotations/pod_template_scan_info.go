@@ -0,0 +1,177 @@
+package annotations
+
+import (
+	"encoding/json"
+	"github.com/Azure/AzureDefender-K8S-InClusterDefense/cmd/webhook/admisionrequest"
+	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/azdsecinfo/contracts"
+	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/utils"
+	"github.com/pkg/errors"
+	"gomodules.xyz/jsonpatch/v2"
+	"strings"
+	"time"
+)
+
+const (
+	// _digestSeparator separates the digest of an image reference that is pinned by digest (e.g. image.com/repo@sha256:...)
+	_digestSeparator = "@"
+)
+
+// PodTemplateScanInfoConfiguration is configuration data for the scan info that pods inherit from the pod template of their owner
+type PodTemplateScanInfoConfiguration struct {
+	// Enabled is whether the scan info annotations are set on the pod template of the workload resources as well, and whether pods
+	// that inherit fresh scan info from the pod template of their owner are admitted without evaluating their containers again.
+	Enabled bool
+	// MaxAgeInSeconds is the max age of inherited scan info that is reused.
+	MaxAgeInSeconds int
+}
+
+// CreatePodTemplateScanInfoAnnotationPatchAddIfNeeded returns json patches that set the scan info annotations on the pod template
+// of the WorkloadResource, so the pods that are created from it inherit the scan info.
+// In case that the pod template already has scan info of its containers' images, no patches are returned and the pod template isn't changed -
+// a change of the pod template rolls out the pods of the workload (and the Deployment controller creates a new ReplicaSet in case that
+// the pod template of its ReplicaSet differs from its own). Returns nil for WorkloadResources without pod template (Pods), and for
+// WorkloadResources that are managed by a controller (e.g. a ReplicaSet of a Deployment, a Job of a CronJob) - their pod template
+// is copied from the pod template of the controller, and a change of it would make the controller replace them.
+func CreatePodTemplateScanInfoAnnotationPatchAddIfNeeded(containersScanInfoList []*contracts.ContainerVulnerabilityScanInfo, workloadResource *admisionrequest.WorkloadResource, configuration *ScanInfoAnnotationConfiguration, vulnerabilityReportName string) ([]jsonpatch.JsonPatchOperation, error) {
+	if workloadResource == nil || configuration == nil {
+		return nil, errors.Wrap(utils.NilArgumentError, "CreatePodTemplateScanInfoAnnotationPatchAddIfNeeded got nil WorkloadResource or configuration")
+	}
+	podTemplate := workloadResource.PodTemplate
+	if podTemplate == nil {
+		return nil, nil
+	}
+	if workloadResource.Metadata != nil && workloadResource.Metadata.GetControllerOwnerReference() != nil {
+		return nil, nil
+	}
+	if areScanInfoImagesMatching(GetScanInfoImages(podTemplate.Annotations), workloadResource.Spec, false) {
+		return nil, nil
+	}
+
+	metadataPath := append(append([]string{}, podTemplate.Path...), _metadataField)
+	patches := []jsonpatch.JsonPatchOperation{}
+	// Create the metadata or the annotations map of the pod template only if it's missing - otherwise the existing one would be replaced
+	if !podTemplate.HasMetadata {
+		podTemplate.HasMetadata = true
+		patches = append(patches, jsonpatch.NewOperation(_addPatchOperation, utils.JoinJSONPointer(metadataPath...), map[string]interface{}{_annotationsField: map[string]string{}}))
+	} else if podTemplate.Annotations == nil {
+		patches = append(patches, jsonpatch.NewOperation(_addPatchOperation, utils.JoinJSONPointer(append(metadataPath, _annotationsField)...), map[string]string{}))
+	}
+	if podTemplate.Annotations == nil {
+		podTemplate.Annotations = make(map[string]string)
+	}
+
+	scanInfoPatches, err := createScanInfoAnnotationPatches(containersScanInfoList, podTemplate.Annotations, metadataPath, configuration, vulnerabilityReportName)
+	if err != nil {
+		return nil, errors.Wrap(err, "AzdAnnotationsPatchGenerator failed to create the scan info annotations patches during CreatePodTemplateScanInfoAnnotationPatchAddIfNeeded")
+	}
+	return append(patches, scanInfoPatches...), nil
+}
+
+// GetFreshScanInfoInheritedFromOwnerTemplate returns the scan info of the Pod's annotation that it inherited from the pod template of its owner
+// (e.g. the ReplicaSet of a Deployment) in case that it can be reused instead of evaluating the containers of the Pod again -
+// the Pod has a controller owner with UID, the v1 scan info annotation is younger than MaxAgeInSeconds, and its images and digests match
+// the containers of the Pod. Otherwise, returns false.
+// The annotation is set by the creator of the Pod, so the caller should verify that it's the annotation of the pod template of the owner
+// (IOwnerPodTemplateScanInfoVerifier) before reusing it.
+func GetFreshScanInfoInheritedFromOwnerTemplate(workloadResource *admisionrequest.WorkloadResource, configuration *PodTemplateScanInfoConfiguration) ([]*contracts.ContainerVulnerabilityScanInfo, bool) {
+	if workloadResource == nil || workloadResource.Metadata == nil || configuration == nil {
+		return nil, false
+	}
+	// Only pods that are created by their controller from its pod template inherit its annotations
+	if workloadResource.PodTemplate != nil {
+		return nil, false
+	}
+	if owner := workloadResource.Metadata.GetControllerOwnerReference(); owner == nil || owner.UID == "" {
+		return nil, false
+	}
+	scanInfoList := GetScanInfoList(workloadResource.Metadata.Annotations)
+	if scanInfoList == nil || scanInfoList.GeneratedTimestamp.IsZero() {
+		return nil, false
+	}
+	if time.Now().UTC().Sub(scanInfoList.GeneratedTimestamp) > utils.GetSeconds(configuration.MaxAgeInSeconds) {
+		return nil, false
+	}
+	if !areScanInfoImagesMatching(getContainersImages(scanInfoList.Containers), workloadResource.Spec, true) {
+		return nil, false
+	}
+	return scanInfoList.Containers, true
+}
+
+// GetScanInfoImages returns the images of the containers (by container name) of the v1 scan info annotation, or of the v2 scan info annotation
+// in case that the v1 annotation is missing or its containers are compressed. Returns nil in case that both are missing or can't be parsed.
+func GetScanInfoImages(annotations map[string]string) map[string]*contracts.Image {
+	if scanInfoList := GetScanInfoList(annotations); scanInfoList != nil {
+		return getContainersImages(scanInfoList.Containers)
+	}
+	serScanInfoListV2, ok := annotations[contracts.ContainersVulnerabilityScanInfoV2AnnotationName]
+	if !ok {
+		return nil
+	}
+	scanInfoListV2 := new(contracts.ContainerVulnerabilityScanInfoListV2)
+	if err := json.Unmarshal([]byte(serScanInfoListV2), scanInfoListV2); err != nil {
+		return nil
+	}
+	images := make(map[string]*contracts.Image, len(scanInfoListV2.Containers))
+	for _, container := range scanInfoListV2.Containers {
+		if container != nil {
+			images[container.Name] = container.Image
+		}
+	}
+	return images
+}
+
+// GetScanInfoList returns the scan info list of the v1 scan info annotation.
+// Returns nil in case that it's missing, can't be parsed or its containers are compressed.
+func GetScanInfoList(annotations map[string]string) *contracts.ContainerVulnerabilityScanInfoList {
+	serScanInfoList, ok := annotations[contracts.ContainersVulnerabilityScanInfoAnnotationName]
+	if !ok {
+		return nil
+	}
+	scanInfoList := new(contracts.ContainerVulnerabilityScanInfoList)
+	if err := json.Unmarshal([]byte(serScanInfoList), scanInfoList); err != nil {
+		return nil
+	}
+	if scanInfoList.Encoding == contracts.GzipScanInfoEncoding {
+		return nil
+	}
+	return scanInfoList
+}
+
+// getContainersImages returns the images of the containers by container name
+func getContainersImages(containersScanInfoList []*contracts.ContainerVulnerabilityScanInfo) map[string]*contracts.Image {
+	images := make(map[string]*contracts.Image, len(containersScanInfoList))
+	for _, container := range containersScanInfoList {
+		if container != nil {
+			images[container.Name] = container.Image
+		}
+	}
+	return images
+}
+
+// areScanInfoImagesMatching returns true if the scan info has exactly the containers of the spec with the same images.
+// In case that digestsRequired, each image must have a resolved digest, and images that are pinned by digest must have the same digest.
+func areScanInfoImagesMatching(scanInfoImages map[string]*contracts.Image, spec *admisionrequest.PodSpec, digestsRequired bool) bool {
+	if scanInfoImages == nil || spec == nil {
+		return false
+	}
+	containers := append(append([]*admisionrequest.Container{}, spec.InitContainers...), spec.Containers...)
+	if len(containers) != len(scanInfoImages) {
+		return false
+	}
+	for _, container := range containers {
+		image, ok := scanInfoImages[container.Name]
+		if !ok || image == nil || image.Name != container.Image {
+			return false
+		}
+		if !digestsRequired {
+			continue
+		}
+		if image.Digest == "" {
+			return false
+		}
+		if separatorIndex := strings.LastIndex(container.Image, _digestSeparator); separatorIndex != -1 && container.Image[separatorIndex+1:] != image.Digest {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,252 @@
+package annotations
+
+import (
+	"encoding/json"
+	"github.com/Azure/AzureDefender-K8S-InClusterDefense/cmd/webhook/admisionrequest"
+	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/azdsecinfo/contracts"
+	"github.com/stretchr/testify/suite"
+	"gomodules.xyz/jsonpatch/v2"
+	"testing"
+	"time"
+)
+
+const (
+	_podTemplateTestImage  = "tomer.azurecr.io/app:1"
+	_podTemplateTestDigest = "sha256:4a1c4b21597c1b4415bdbecb28a3296c6b5e23ca4f9feeb599860a1dac6a0108"
+)
+
+type PodTemplateScanInfoTestSuite struct {
+	suite.Suite
+	containersScanInfo []*contracts.ContainerVulnerabilityScanInfo
+	spec               *admisionrequest.PodSpec
+	configuration      *PodTemplateScanInfoConfiguration
+}
+
+// This will run before each test in the suite
+func (suite *PodTemplateScanInfoTestSuite) SetupTest() {
+	suite.containersScanInfo = []*contracts.ContainerVulnerabilityScanInfo{
+		{
+			Name:         "app",
+			Image:        &contracts.Image{Name: _podTemplateTestImage, Digest: _podTemplateTestDigest},
+			ScanStatus:   contracts.UnhealthyScan,
+			ScanFindings: []*contracts.ScanFinding{{Id: "1", Severity: "High"}},
+		},
+	}
+	suite.spec = &admisionrequest.PodSpec{Containers: []*admisionrequest.Container{{Name: "app", Image: _podTemplateTestImage}}}
+	suite.configuration = &PodTemplateScanInfoConfiguration{Enabled: true, MaxAgeInSeconds: 60}
+}
+
+func (suite *PodTemplateScanInfoTestSuite) Test_CreatePodTemplateScanInfoAnnotationPatchAddIfNeeded_TemplateWithoutAnnotations_AnnotationsMapCreated() {
+	podTemplate := &admisionrequest.PodTemplate{Path: []string{"spec", "template"}, HasMetadata: true}
+
+	patches, err := CreatePodTemplateScanInfoAnnotationPatchAddIfNeeded(suite.containersScanInfo, suite.createWorkloadResource(podTemplate), &ScanInfoAnnotationConfiguration{}, "")
+
+	suite.Nil(err)
+	suite.Equal(2, len(patches))
+	suite.Equal(jsonpatch.NewOperation(_addPatchOperation, "/spec/template/metadata/annotations", map[string]string{}), patches[0])
+	suite.Equal("/spec/template/metadata/annotations/azuredefender.io~1containers.vulnerability.scan.info", patches[1].Path)
+	suite.Equal(patches[1].Value, podTemplate.Annotations[contracts.ContainersVulnerabilityScanInfoAnnotationName])
+}
+
+func (suite *PodTemplateScanInfoTestSuite) Test_CreatePodTemplateScanInfoAnnotationPatchAddIfNeeded_TemplateWithoutMetadata_MetadataAdded() {
+	podTemplate := &admisionrequest.PodTemplate{Path: []string{"spec", "jobTemplate", "spec", "template"}}
+
+	patches, err := CreatePodTemplateScanInfoAnnotationPatchAddIfNeeded(suite.containersScanInfo, suite.createWorkloadResource(podTemplate), &ScanInfoAnnotationConfiguration{}, "")
+
+	suite.Nil(err)
+	suite.Equal(2, len(patches))
+	suite.Equal(jsonpatch.NewOperation(_addPatchOperation, "/spec/jobTemplate/spec/template/metadata", map[string]interface{}{"annotations": map[string]string{}}), patches[0])
+	suite.True(podTemplate.HasMetadata)
+}
+
+func (suite *PodTemplateScanInfoTestSuite) Test_CreatePodTemplateScanInfoAnnotationPatchAddIfNeeded_TemplateWithScanInfoOfImages_NotChanged() {
+	annotations := map[string]string{contracts.ContainersVulnerabilityScanInfoAnnotationName: suite.marshalScanInfoList(time.Now().UTC().Add(-time.Hour), suite.containersScanInfo)}
+	podTemplate := &admisionrequest.PodTemplate{Path: []string{"spec", "template"}, HasMetadata: true, Annotations: annotations}
+
+	patches, err := CreatePodTemplateScanInfoAnnotationPatchAddIfNeeded(suite.containersScanInfo, suite.createWorkloadResource(podTemplate), &ScanInfoAnnotationConfiguration{}, "")
+
+	suite.Nil(err)
+	suite.Nil(patches)
+}
+
+func (suite *PodTemplateScanInfoTestSuite) Test_CreatePodTemplateScanInfoAnnotationPatchAddIfNeeded_TemplateWithV2ScanInfoOfImages_NotChanged() {
+	scanInfoListV2, err := marshalAnnotationInnerObject(createScanInfoListV2(time.Now().UTC(), suite.containersScanInfo))
+	suite.Require().Nil(err)
+	podTemplate := &admisionrequest.PodTemplate{Path: []string{"spec", "template"}, HasMetadata: true, Annotations: map[string]string{contracts.ContainersVulnerabilityScanInfoV2AnnotationName: scanInfoListV2}}
+
+	patches, err := CreatePodTemplateScanInfoAnnotationPatchAddIfNeeded(suite.containersScanInfo, suite.createWorkloadResource(podTemplate), &ScanInfoAnnotationConfiguration{SchemaVersions: []string{"v2"}}, "")
+
+	suite.Nil(err)
+	suite.Nil(patches)
+}
+
+func (suite *PodTemplateScanInfoTestSuite) Test_CreatePodTemplateScanInfoAnnotationPatchAddIfNeeded_TemplateWithScanInfoOfOtherImage_Replaced() {
+	oldScanInfo := []*contracts.ContainerVulnerabilityScanInfo{{Name: "app", Image: &contracts.Image{Name: "tomer.azurecr.io/app:0"}}}
+	annotations := map[string]string{"other": "annotation", contracts.ContainersVulnerabilityScanInfoAnnotationName: suite.marshalScanInfoList(time.Now().UTC(), oldScanInfo)}
+	podTemplate := &admisionrequest.PodTemplate{Path: []string{"spec", "template"}, HasMetadata: true, Annotations: annotations}
+
+	patches, err := CreatePodTemplateScanInfoAnnotationPatchAddIfNeeded(suite.containersScanInfo, suite.createWorkloadResource(podTemplate), &ScanInfoAnnotationConfiguration{}, "")
+
+	suite.Nil(err)
+	suite.Equal(1, len(patches))
+	suite.Equal("/spec/template/metadata/annotations/azuredefender.io~1containers.vulnerability.scan.info", patches[0].Path)
+	suite.Equal(2, len(podTemplate.Annotations))
+}
+
+func (suite *PodTemplateScanInfoTestSuite) Test_CreatePodTemplateScanInfoAnnotationPatchAddIfNeeded_Pod_NoPatches() {
+	patches, err := CreatePodTemplateScanInfoAnnotationPatchAddIfNeeded(suite.containersScanInfo, suite.createWorkloadResource(nil), &ScanInfoAnnotationConfiguration{}, "")
+
+	suite.Nil(err)
+	suite.Nil(patches)
+}
+
+func (suite *PodTemplateScanInfoTestSuite) Test_CreatePodTemplateScanInfoAnnotationPatchAddIfNeeded_WorkloadWithController_NotChanged() {
+	podTemplate := &admisionrequest.PodTemplate{Path: []string{"spec", "template"}, HasMetadata: true}
+	replicaSet := suite.createWorkloadResource(podTemplate)
+	replicaSet.Metadata.OwnerReferences = []*admisionrequest.OwnerReference{{APIVersion: "apps/v1", Kind: "Deployment", Name: "deploymentTest", UID: "deploymentUID", Controller: true}}
+
+	patches, err := CreatePodTemplateScanInfoAnnotationPatchAddIfNeeded(suite.containersScanInfo, replicaSet, &ScanInfoAnnotationConfiguration{}, "")
+
+	suite.Nil(err)
+	suite.Nil(patches)
+	suite.Nil(podTemplate.Annotations)
+}
+
+func (suite *PodTemplateScanInfoTestSuite) Test_CreatePodTemplateScanInfoAnnotationPatchAddIfNeeded_WorkloadWithOwnerThatIsNotController_Patched() {
+	podTemplate := &admisionrequest.PodTemplate{Path: []string{"spec", "template"}, HasMetadata: true}
+	workloadResource := suite.createWorkloadResource(podTemplate)
+	workloadResource.Metadata.OwnerReferences = []*admisionrequest.OwnerReference{{APIVersion: "v1", Kind: "ConfigMap", Name: "configMapTest", UID: "configMapUID"}}
+
+	patches, err := CreatePodTemplateScanInfoAnnotationPatchAddIfNeeded(suite.containersScanInfo, workloadResource, &ScanInfoAnnotationConfiguration{}, "")
+
+	suite.Nil(err)
+	suite.Equal(2, len(patches))
+}
+
+func (suite *PodTemplateScanInfoTestSuite) Test_GetFreshScanInfoInheritedFromOwnerTemplate_FreshMatchingScanInfo_Reused() {
+	pod := suite.createPod(time.Now().UTC().Add(-10*time.Second), suite.containersScanInfo)
+
+	containers, ok := GetFreshScanInfoInheritedFromOwnerTemplate(pod, suite.configuration)
+
+	suite.True(ok)
+	suite.Equal(1, len(containers))
+	suite.Equal(contracts.UnhealthyScan, containers[0].ScanStatus)
+}
+
+func (suite *PodTemplateScanInfoTestSuite) Test_GetFreshScanInfoInheritedFromOwnerTemplate_PinnedDigestMatching_Reused() {
+	suite.containersScanInfo[0].Image.Name = "tomer.azurecr.io/app@" + _podTemplateTestDigest
+	suite.spec.Containers[0].Image = suite.containersScanInfo[0].Image.Name
+	pod := suite.createPod(time.Now().UTC(), suite.containersScanInfo)
+
+	_, ok := GetFreshScanInfoInheritedFromOwnerTemplate(pod, suite.configuration)
+
+	suite.True(ok)
+}
+
+func (suite *PodTemplateScanInfoTestSuite) Test_GetFreshScanInfoInheritedFromOwnerTemplate_PinnedDigestNotMatching_NotReused() {
+	suite.containersScanInfo[0].Image.Name = "tomer.azurecr.io/app@sha256:other"
+	suite.spec.Containers[0].Image = suite.containersScanInfo[0].Image.Name
+	pod := suite.createPod(time.Now().UTC(), suite.containersScanInfo)
+
+	_, ok := GetFreshScanInfoInheritedFromOwnerTemplate(pod, suite.configuration)
+
+	suite.False(ok)
+}
+
+func (suite *PodTemplateScanInfoTestSuite) Test_GetFreshScanInfoInheritedFromOwnerTemplate_StaleScanInfo_NotReused() {
+	pod := suite.createPod(time.Now().UTC().Add(-2*time.Minute), suite.containersScanInfo)
+
+	_, ok := GetFreshScanInfoInheritedFromOwnerTemplate(pod, suite.configuration)
+
+	suite.False(ok)
+}
+
+func (suite *PodTemplateScanInfoTestSuite) Test_GetFreshScanInfoInheritedFromOwnerTemplate_PodWithoutOwner_NotReused() {
+	pod := suite.createPod(time.Now().UTC(), suite.containersScanInfo)
+	pod.Metadata.OwnerReferences = nil
+
+	_, ok := GetFreshScanInfoInheritedFromOwnerTemplate(pod, suite.configuration)
+
+	suite.False(ok)
+}
+
+func (suite *PodTemplateScanInfoTestSuite) Test_GetFreshScanInfoInheritedFromOwnerTemplate_OwnerIsNotController_NotReused() {
+	pod := suite.createPod(time.Now().UTC(), suite.containersScanInfo)
+	pod.Metadata.OwnerReferences[0].Controller = false
+
+	_, ok := GetFreshScanInfoInheritedFromOwnerTemplate(pod, suite.configuration)
+
+	suite.False(ok)
+}
+
+func (suite *PodTemplateScanInfoTestSuite) Test_GetFreshScanInfoInheritedFromOwnerTemplate_OtherImage_NotReused() {
+	pod := suite.createPod(time.Now().UTC(), suite.containersScanInfo)
+	pod.Spec.Containers[0].Image = "tomer.azurecr.io/app:2"
+
+	_, ok := GetFreshScanInfoInheritedFromOwnerTemplate(pod, suite.configuration)
+
+	suite.False(ok)
+}
+
+func (suite *PodTemplateScanInfoTestSuite) Test_GetFreshScanInfoInheritedFromOwnerTemplate_AdditionalContainer_NotReused() {
+	pod := suite.createPod(time.Now().UTC(), suite.containersScanInfo)
+	pod.Spec.InitContainers = []*admisionrequest.Container{{Name: "init", Image: _podTemplateTestImage}}
+
+	_, ok := GetFreshScanInfoInheritedFromOwnerTemplate(pod, suite.configuration)
+
+	suite.False(ok)
+}
+
+func (suite *PodTemplateScanInfoTestSuite) Test_GetFreshScanInfoInheritedFromOwnerTemplate_UnresolvedDigest_NotReused() {
+	suite.containersScanInfo[0].Image.Digest = ""
+	pod := suite.createPod(time.Now().UTC(), suite.containersScanInfo)
+
+	_, ok := GetFreshScanInfoInheritedFromOwnerTemplate(pod, suite.configuration)
+
+	suite.False(ok)
+}
+
+func (suite *PodTemplateScanInfoTestSuite) Test_GetFreshScanInfoInheritedFromOwnerTemplate_GzipEncoding_NotReused() {
+	pod := suite.createPod(time.Now().UTC(), suite.containersScanInfo)
+	scanInfoList := &contracts.ContainerVulnerabilityScanInfoList{GeneratedTimestamp: time.Now().UTC(), Encoding: contracts.GzipScanInfoEncoding, CompressedContainers: "H4sI"}
+	serScanInfoList, err := marshalAnnotationInnerObject(scanInfoList)
+	suite.Require().Nil(err)
+	pod.Metadata.Annotations[contracts.ContainersVulnerabilityScanInfoAnnotationName] = serScanInfoList
+
+	_, ok := GetFreshScanInfoInheritedFromOwnerTemplate(pod, suite.configuration)
+
+	suite.False(ok)
+}
+
+func (suite *PodTemplateScanInfoTestSuite) Test_GetFreshScanInfoInheritedFromOwnerTemplate_InvalidAnnotation_NotReused() {
+	pod := suite.createPod(time.Now().UTC(), suite.containersScanInfo)
+	pod.Metadata.Annotations[contracts.ContainersVulnerabilityScanInfoAnnotationName] = "not json"
+
+	_, ok := GetFreshScanInfoInheritedFromOwnerTemplate(pod, suite.configuration)
+
+	suite.False(ok)
+}
+
+func (suite *PodTemplateScanInfoTestSuite) createWorkloadResource(podTemplate *admisionrequest.PodTemplate) *admisionrequest.WorkloadResource {
+	return &admisionrequest.WorkloadResource{Metadata: &admisionrequest.ObjectMetadata{Name: "deploymentTest"}, Spec: suite.spec, PodTemplate: podTemplate}
+}
+
+func (suite *PodTemplateScanInfoTestSuite) createPod(generatedTimestamp time.Time, containersScanInfo []*contracts.ContainerVulnerabilityScanInfo) *admisionrequest.WorkloadResource {
+	return &admisionrequest.WorkloadResource{
+		Metadata: &admisionrequest.ObjectMetadata{
+			Annotations:     map[string]string{contracts.ContainersVulnerabilityScanInfoAnnotationName: suite.marshalScanInfoList(generatedTimestamp, containersScanInfo)},
+			OwnerReferences: []*admisionrequest.OwnerReference{{APIVersion: "apps/v1", Kind: "ReplicaSet", Name: "replicaSetTest", UID: "replicaSetUID", Controller: true}},
+		},
+		Spec: suite.spec,
+	}
+}
+
+func (suite *PodTemplateScanInfoTestSuite) marshalScanInfoList(generatedTimestamp time.Time, containersScanInfo []*contracts.ContainerVulnerabilityScanInfo) string {
+	serScanInfoList, err := json.Marshal(&contracts.ContainerVulnerabilityScanInfoList{GeneratedTimestamp: generatedTimestamp, SchemaVersion: contracts.SchemaVersionV1, Containers: containersScanInfo})
+	suite.Require().Nil(err)
+	return string(serScanInfoList)
+}
+
+func TestPodTemplateScanInfoTestSuite(t *testing.T) {
+	suite.Run(t, new(PodTemplateScanInfoTestSuite))
+}
@@ -0,0 +1,213 @@
+package annotations
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"encoding/json"
+	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/azdsecinfo/contracts"
+	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/utils"
+	"github.com/pkg/errors"
+	"sort"
+)
+
+const (
+	// _maxTotalAnnotationsSizeInBytes is the limit of kubernetes on the total size of the annotations (keys and values) of an object
+	_maxTotalAnnotationsSizeInBytes = 256 * 1024
+)
+
+var (
+	// ScanInfoAnnotationExceedsSizeBudgetError is returned when the scan info annotation exceeds the size budget even after it's summarized
+	ScanInfoAnnotationExceedsSizeBudgetError = errors.New("scan info annotation exceeds the size budget")
+
+	// _severityToLevel is the order of the severities of the scan findings - the most severe findings are kept in summarized encoding
+	_severityToLevel = map[string]int{"None": 0, "Low": 1, "Medium": 2, "High": 3}
+)
+
+// ScanInfoAnnotationConfiguration is configuration data for the size guard of the scan info annotation
+type ScanInfoAnnotationConfiguration struct {
+	// MaxSizeInBytes is the size budget of the scan info annotation value. The budget is also bounded by the kubernetes limit of the
+	// total annotations size (the other annotations of the workload are taken into account). Zero means only the kubernetes limit.
+	MaxSizeInBytes int
+	// OversizedEncoding is the encoding of scan info that exceeds the budget - "summarized" (default) or "gzip".
+	// In case that the gzip encoding still exceeds the budget, the scan info is summarized.
+	OversizedEncoding string
+	// SummarizedTopFindingsCount is the number of the most severe findings that are kept per container in the summarized encoding
+	SummarizedTopFindingsCount int
+	// SchemaVersions are the schema versions of the scan info annotations that are written - "v1" (default), "v2" or both (during migration).
+	// Each schema version is written to its own annotation.
+	SchemaVersions []string
+}
+
+// GetSchemaVersions returns the schema versions of the scan info annotations that are written - v1 if empty.
+// Returns error in case of unknown schema version.
+func (configuration *ScanInfoAnnotationConfiguration) GetSchemaVersions() (map[contracts.SchemaVersion]bool, error) {
+	if len(configuration.SchemaVersions) == 0 {
+		return map[contracts.SchemaVersion]bool{contracts.SchemaVersionV1: true}, nil
+	}
+
+	schemaVersions := make(map[contracts.SchemaVersion]bool, len(configuration.SchemaVersions))
+	for _, schemaVersion := range configuration.SchemaVersions {
+		switch contracts.SchemaVersion(schemaVersion) {
+		case contracts.SchemaVersionV1, contracts.SchemaVersionV2:
+			schemaVersions[contracts.SchemaVersion(schemaVersion)] = true
+		default:
+			return nil, errors.Wrapf(utils.InvalidConfiguration, "unknown scan info annotation schema version <%s>", schemaVersion)
+		}
+	}
+	return schemaVersions, nil
+}
+
+// encodeScanInfoList marshals the scan info list to annotation value that doesn't exceed the size budget.
+// The full scan info is used if it fits the budget. Otherwise, the oversized encoding of the configuration is used.
+// The chosen encoding is set in scanInfoList.Encoding.
+func encodeScanInfoList(scanInfoList *contracts.ContainerVulnerabilityScanInfoList, configuration *ScanInfoAnnotationConfiguration, annotations map[string]string) (string, error) {
+	sizeBudget := getScanInfoAnnotationSizeBudget(configuration, annotations, contracts.ContainersVulnerabilityScanInfoAnnotationName)
+
+	scanInfoList.SchemaVersion = contracts.SchemaVersionV1
+	scanInfoList.Encoding = contracts.FullScanInfoEncoding
+	value, err := marshalAnnotationInnerObject(scanInfoList)
+	if err != nil || len(value) <= sizeBudget {
+		return value, err
+	}
+
+	if contracts.ScanInfoEncoding(configuration.OversizedEncoding) == contracts.GzipScanInfoEncoding {
+		value, err = marshalGzipScanInfoList(scanInfoList)
+		if err != nil || len(value) <= sizeBudget {
+			return value, err
+		}
+	}
+
+	// Summarize with the top findings, and in case that it still exceeds the budget - only with the counts.
+	for _, topFindingsCount := range []int{configuration.SummarizedTopFindingsCount, 0} {
+		value, err = marshalAnnotationInnerObject(summarizeScanInfoList(scanInfoList, topFindingsCount))
+		if err != nil || len(value) <= sizeBudget {
+			return value, err
+		}
+	}
+	return "", errors.Wrapf(ScanInfoAnnotationExceedsSizeBudgetError, "summarized scan info size is %d bytes, the budget is %d bytes", len(value), sizeBudget)
+}
+
+// encodeScanInfoListV2 marshals the v2 scan info list. The v2 scan info has only the summaries of the containers, so it isn't
+// summarized further - error is returned in case that it exceeds the size budget.
+func encodeScanInfoListV2(scanInfoList *contracts.ContainerVulnerabilityScanInfoListV2, configuration *ScanInfoAnnotationConfiguration, annotations map[string]string) (string, error) {
+	sizeBudget := getScanInfoAnnotationSizeBudget(configuration, annotations, contracts.ContainersVulnerabilityScanInfoV2AnnotationName)
+	value, err := marshalAnnotationInnerObject(scanInfoList)
+	if err != nil {
+		return "", err
+	}
+	if len(value) > sizeBudget {
+		return "", errors.Wrapf(ScanInfoAnnotationExceedsSizeBudgetError, "v2 scan info size is %d bytes, the budget is %d bytes", len(value), sizeBudget)
+	}
+	return value, nil
+}
+
+// getScanInfoAnnotationSizeBudget returns the size budget of the value of the scan info annotation key - the configured budget
+// bounded by the space that is left by the other annotations in the kubernetes limit.
+func getScanInfoAnnotationSizeBudget(configuration *ScanInfoAnnotationConfiguration, annotations map[string]string, annotationKey string) int {
+	sizeBudget := _maxTotalAnnotationsSizeInBytes - len(annotationKey)
+	for key, value := range annotations {
+		if key != annotationKey {
+			sizeBudget -= len(key) + len(value)
+		}
+	}
+	if configuration.MaxSizeInBytes > 0 && configuration.MaxSizeInBytes < sizeBudget {
+		return configuration.MaxSizeInBytes
+	}
+	return sizeBudget
+}
+
+// marshalGzipScanInfoList marshals the scan info list in gzip encoding - the containers are gzip compressed and base64 encoded
+func marshalGzipScanInfoList(scanInfoList *contracts.ContainerVulnerabilityScanInfoList) (string, error) {
+	containers, err := json.Marshal(scanInfoList.Containers)
+	if err != nil {
+		return "", err
+	}
+
+	var compressed bytes.Buffer
+	writer := gzip.NewWriter(&compressed)
+	if _, err := writer.Write(containers); err != nil {
+		return "", errors.Wrap(err, "failed to compress the containers")
+	}
+	if err := writer.Close(); err != nil {
+		return "", errors.Wrap(err, "failed to compress the containers")
+	}
+
+	return marshalAnnotationInnerObject(&contracts.ContainerVulnerabilityScanInfoList{
+		GeneratedTimestamp:   scanInfoList.GeneratedTimestamp,
+		SchemaVersion:        scanInfoList.SchemaVersion,
+		Encoding:             contracts.GzipScanInfoEncoding,
+		CompressedContainers: base64.StdEncoding.EncodeToString(compressed.Bytes()),
+		VulnerabilityReport:  scanInfoList.VulnerabilityReport,
+	})
+}
+
+// summarizeScanInfoList returns the scan info list in summarized encoding - the findings of each container are counted per
+// severity and patchable flag, and only the topFindingsCount most severe findings are kept.
+// The containers of the given scan info list aren't changed.
+func summarizeScanInfoList(scanInfoList *contracts.ContainerVulnerabilityScanInfoList, topFindingsCount int) *contracts.ContainerVulnerabilityScanInfoList {
+	containers := make([]*contracts.ContainerVulnerabilityScanInfo, 0, len(scanInfoList.Containers))
+	for _, container := range scanInfoList.Containers {
+		summarizedContainer := *container
+		summarizedContainer.ScanFindingsSummary = countScanFindings(container.ScanFindings)
+		summarizedContainer.ScanFindings = getTopScanFindings(container.ScanFindings, topFindingsCount)
+		containers = append(containers, &summarizedContainer)
+	}
+
+	return &contracts.ContainerVulnerabilityScanInfoList{
+		GeneratedTimestamp:  scanInfoList.GeneratedTimestamp,
+		SchemaVersion:       scanInfoList.SchemaVersion,
+		Encoding:            contracts.SummarizedScanInfoEncoding,
+		Containers:          containers,
+		VulnerabilityReport: scanInfoList.VulnerabilityReport,
+	}
+}
+
+// countScanFindings counts the scan findings per severity and patchable flag. The counts are ordered by severity (the most
+// severe first) and patchable first.
+func countScanFindings(scanFindings []*contracts.ScanFinding) []*contracts.ScanFindingsCount {
+	counts := []*contracts.ScanFindingsCount{}
+	for _, scanFinding := range scanFindings {
+		found := false
+		for _, count := range counts {
+			if count.Severity == scanFinding.Severity && count.Patchable == scanFinding.Patchable {
+				count.Count++
+				found = true
+				break
+			}
+		}
+		if !found {
+			counts = append(counts, &contracts.ScanFindingsCount{Severity: scanFinding.Severity, Patchable: scanFinding.Patchable, Count: 1})
+		}
+	}
+
+	sort.SliceStable(counts, func(i, j int) bool {
+		return isMoreSevere(counts[i].Severity, counts[i].Patchable, counts[j].Severity, counts[j].Patchable)
+	})
+	return counts
+}
+
+// getTopScanFindings returns the topFindingsCount most severe scan findings (patchable first for the same severity)
+func getTopScanFindings(scanFindings []*contracts.ScanFinding, topFindingsCount int) []*contracts.ScanFinding {
+	sortedScanFindings := make([]*contracts.ScanFinding, len(scanFindings))
+	copy(sortedScanFindings, scanFindings)
+	sort.SliceStable(sortedScanFindings, func(i, j int) bool {
+		return isMoreSevere(sortedScanFindings[i].Severity, sortedScanFindings[i].Patchable, sortedScanFindings[j].Severity, sortedScanFindings[j].Patchable)
+	})
+
+	if topFindingsCount < 0 {
+		topFindingsCount = 0
+	}
+	if len(sortedScanFindings) > topFindingsCount {
+		sortedScanFindings = sortedScanFindings[:topFindingsCount]
+	}
+	return sortedScanFindings
+}
+
+// isMoreSevere returns true if the first severity is more severe than the second one. For the same severity, patchable is first.
+func isMoreSevere(severity string, patchable bool, otherSeverity string, otherPatchable bool) bool {
+	if _severityToLevel[severity] != _severityToLevel[otherSeverity] {
+		return _severityToLevel[severity] > _severityToLevel[otherSeverity]
+	}
+	return patchable && !otherPatchable
+}
@@ -0,0 +1,146 @@
+package annotations
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/azdsecinfo/contracts"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/suite"
+	"io/ioutil"
+	"strings"
+	"testing"
+	"time"
+)
+
+type ScanInfoAnnotationEncoderTestSuite struct {
+	suite.Suite
+	scanInfoList *contracts.ContainerVulnerabilityScanInfoList
+}
+
+// This will run before each test in the suite
+func (suite *ScanInfoAnnotationEncoderTestSuite) SetupTest() {
+	scanFindings := []*contracts.ScanFinding{}
+	for i := 0; i < 1000; i++ {
+		scanFindings = append(scanFindings, &contracts.ScanFinding{Id: fmt.Sprintf("low-%d", i), Severity: "Low", Patchable: i%2 == 0})
+	}
+	scanFindings = append(scanFindings,
+		&contracts.ScanFinding{Id: "medium", Severity: "Medium", Patchable: false},
+		&contracts.ScanFinding{Id: "high-not-patchable", Severity: "High", Patchable: false},
+		&contracts.ScanFinding{Id: "high-patchable", Severity: "High", Patchable: true},
+	)
+	suite.scanInfoList = &contracts.ContainerVulnerabilityScanInfoList{
+		GeneratedTimestamp: time.Now().UTC(),
+		Containers: []*contracts.ContainerVulnerabilityScanInfo{
+			{
+				Name:         "container1",
+				Image:        &contracts.Image{Name: "imageTest1", Digest: "imageDigest1"},
+				ScanStatus:   contracts.UnhealthyScan,
+				ScanFindings: scanFindings,
+			},
+		},
+	}
+}
+
+func (suite *ScanInfoAnnotationEncoderTestSuite) Test_encodeScanInfoList_BelowBudget_Full() {
+	value, err := encodeScanInfoList(suite.scanInfoList, &ScanInfoAnnotationConfiguration{}, nil)
+
+	suite.Nil(err)
+	actual := suite.unmarshal(value)
+	suite.Equal(contracts.FullScanInfoEncoding, actual.Encoding)
+	suite.Equal(1003, len(actual.Containers[0].ScanFindings))
+	suite.Nil(actual.Containers[0].ScanFindingsSummary)
+}
+
+func (suite *ScanInfoAnnotationEncoderTestSuite) Test_encodeScanInfoList_AboveBudget_Summarized() {
+	value, err := encodeScanInfoList(suite.scanInfoList, &ScanInfoAnnotationConfiguration{MaxSizeInBytes: 2048, SummarizedTopFindingsCount: 2}, nil)
+
+	suite.Nil(err)
+	suite.LessOrEqual(len(value), 2048)
+	actual := suite.unmarshal(value)
+	suite.Equal(contracts.SummarizedScanInfoEncoding, actual.Encoding)
+	suite.Equal([]*contracts.ScanFinding{
+		{Id: "high-patchable", Severity: "High", Patchable: true},
+		{Id: "high-not-patchable", Severity: "High", Patchable: false},
+	}, actual.Containers[0].ScanFindings)
+	suite.Equal([]*contracts.ScanFindingsCount{
+		{Severity: "High", Patchable: true, Count: 1},
+		{Severity: "High", Patchable: false, Count: 1},
+		{Severity: "Medium", Patchable: false, Count: 1},
+		{Severity: "Low", Patchable: true, Count: 500},
+		{Severity: "Low", Patchable: false, Count: 500},
+	}, actual.Containers[0].ScanFindingsSummary)
+	// The given scan info isn't changed
+	suite.Equal(1003, len(suite.scanInfoList.Containers[0].ScanFindings))
+	suite.Nil(suite.scanInfoList.Containers[0].ScanFindingsSummary)
+}
+
+func (suite *ScanInfoAnnotationEncoderTestSuite) Test_encodeScanInfoList_TopFindingsAboveBudget_SummarizedOnlyCounts() {
+	value, err := encodeScanInfoList(suite.scanInfoList, &ScanInfoAnnotationConfiguration{MaxSizeInBytes: 600, SummarizedTopFindingsCount: 100}, nil)
+
+	suite.Nil(err)
+	actual := suite.unmarshal(value)
+	suite.Equal(contracts.SummarizedScanInfoEncoding, actual.Encoding)
+	suite.Empty(actual.Containers[0].ScanFindings)
+	suite.Equal(5, len(actual.Containers[0].ScanFindingsSummary))
+}
+
+func (suite *ScanInfoAnnotationEncoderTestSuite) Test_encodeScanInfoList_AboveBudgetGzip_Compressed() {
+	value, err := encodeScanInfoList(suite.scanInfoList, &ScanInfoAnnotationConfiguration{MaxSizeInBytes: 16 * 1024, OversizedEncoding: "gzip"}, nil)
+
+	suite.Nil(err)
+	actual := suite.unmarshal(value)
+	suite.Equal(contracts.GzipScanInfoEncoding, actual.Encoding)
+	suite.Nil(actual.Containers)
+	suite.Equal(suite.scanInfoList.Containers, suite.decompress(actual.CompressedContainers))
+}
+
+func (suite *ScanInfoAnnotationEncoderTestSuite) Test_encodeScanInfoList_GzipAboveBudget_Summarized() {
+	value, err := encodeScanInfoList(suite.scanInfoList, &ScanInfoAnnotationConfiguration{MaxSizeInBytes: 1024, OversizedEncoding: "gzip"}, nil)
+
+	suite.Nil(err)
+	suite.Equal(contracts.SummarizedScanInfoEncoding, suite.unmarshal(value).Encoding)
+}
+
+func (suite *ScanInfoAnnotationEncoderTestSuite) Test_encodeScanInfoList_OtherAnnotationsLeaveNoSpace_Summarized() {
+	annotations := map[string]string{
+		"other": strings.Repeat("a", _maxTotalAnnotationsSizeInBytes-2048),
+		contracts.ContainersVulnerabilityScanInfoAnnotationName: strings.Repeat("a", _maxTotalAnnotationsSizeInBytes),
+	}
+
+	value, err := encodeScanInfoList(suite.scanInfoList, &ScanInfoAnnotationConfiguration{MaxSizeInBytes: 128 * 1024}, annotations)
+
+	suite.Nil(err)
+	suite.Equal(contracts.SummarizedScanInfoEncoding, suite.unmarshal(value).Encoding)
+}
+
+func (suite *ScanInfoAnnotationEncoderTestSuite) Test_encodeScanInfoList_SummarizedAboveBudget_Error() {
+	value, err := encodeScanInfoList(suite.scanInfoList, &ScanInfoAnnotationConfiguration{MaxSizeInBytes: 100}, nil)
+
+	suite.Equal(ScanInfoAnnotationExceedsSizeBudgetError, errors.Cause(err))
+	suite.Equal("", value)
+}
+
+func (suite *ScanInfoAnnotationEncoderTestSuite) unmarshal(value string) *contracts.ContainerVulnerabilityScanInfoList {
+	scanInfoList := new(contracts.ContainerVulnerabilityScanInfoList)
+	suite.Require().Nil(json.Unmarshal([]byte(value), scanInfoList))
+	return scanInfoList
+}
+
+func (suite *ScanInfoAnnotationEncoderTestSuite) decompress(compressedContainers string) []*contracts.ContainerVulnerabilityScanInfo {
+	compressed, err := base64.StdEncoding.DecodeString(compressedContainers)
+	suite.Require().Nil(err)
+	reader, err := gzip.NewReader(bytes.NewReader(compressed))
+	suite.Require().Nil(err)
+	content, err := ioutil.ReadAll(reader)
+	suite.Require().Nil(err)
+	var containers []*contracts.ContainerVulnerabilityScanInfo
+	suite.Require().Nil(json.Unmarshal(content, &containers))
+	return containers
+}
+
+func TestScanInfoAnnotationEncoderTestSuite(t *testing.T) {
+	suite.Run(t, new(ScanInfoAnnotationEncoderTestSuite))
+}
@@ -0,0 +1,63 @@
+package annotations
+
+import (
+	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/azdsecinfo/contracts"
+	"time"
+)
+
+// _summarySeverities are the severities that are always present in the counts of the summary
+var _summarySeverities = []string{"High", "Medium", "Low"}
+
+// createScanInfoListV2 creates the v2 scan info list - the scan findings of each container are summarized
+func createScanInfoListV2(generatedTimestamp time.Time, containersScanInfoList []*contracts.ContainerVulnerabilityScanInfo) *contracts.ContainerVulnerabilityScanInfoListV2 {
+	containers := make([]*contracts.ContainerVulnerabilityScanSummary, 0, len(containersScanInfoList))
+	for _, container := range containersScanInfoList {
+		containers = append(containers, &contracts.ContainerVulnerabilityScanSummary{
+			Name:                 container.Name,
+			Image:                container.Image,
+			ScanStatus:           container.ScanStatus,
+			Summary:              createVulnerabilitySummary(container, generatedTimestamp),
+			SignatureStatus:      container.SignatureStatus,
+			SupplyChainArtifacts: container.SupplyChainArtifacts,
+			AdditionalData:       container.AdditionalData,
+		})
+	}
+
+	return &contracts.ContainerVulnerabilityScanInfoListV2{
+		SchemaVersion:      contracts.SchemaVersionV2,
+		GeneratedTimestamp: generatedTimestamp,
+		Containers:         containers,
+	}
+}
+
+// createVulnerabilitySummary creates the summary of the scan findings of the container - nil if the container is unscanned
+func createVulnerabilitySummary(container *contracts.ContainerVulnerabilityScanInfo, evaluatedAt time.Time) *contracts.VulnerabilitySummary {
+	if container.ScanStatus == contracts.Unscanned {
+		return nil
+	}
+
+	summary := &contracts.VulnerabilitySummary{
+		SeverityCounts:          make(map[string]int, len(_summarySeverities)),
+		PatchableSeverityCounts: make(map[string]int, len(_summarySeverities)),
+		MaxSeverity:             "None",
+		EvaluatedAt:             evaluatedAt,
+		DataSource:              contracts.ARGDataSource,
+	}
+	for _, severity := range _summarySeverities {
+		summary.SeverityCounts[severity] = 0
+		summary.PatchableSeverityCounts[severity] = 0
+	}
+
+	for _, scanFinding := range container.ScanFindings {
+		summary.TotalCount++
+		summary.SeverityCounts[scanFinding.Severity]++
+		if scanFinding.Patchable {
+			summary.PatchableCount++
+			summary.PatchableSeverityCounts[scanFinding.Severity]++
+		}
+		if _severityToLevel[scanFinding.Severity] > _severityToLevel[summary.MaxSeverity] {
+			summary.MaxSeverity = scanFinding.Severity
+		}
+	}
+	return summary
+}
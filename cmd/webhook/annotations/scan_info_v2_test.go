@@ -0,0 +1,67 @@
+package annotations
+
+import (
+	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/azdsecinfo/contracts"
+	"github.com/stretchr/testify/suite"
+	"testing"
+	"time"
+)
+
+type ScanInfoV2TestSuite struct {
+	suite.Suite
+}
+
+func (suite *ScanInfoV2TestSuite) Test_createScanInfoListV2_UnhealthyScan_SummaryCounted() {
+	evaluatedAt := time.Now().UTC()
+	container := &contracts.ContainerVulnerabilityScanInfo{
+		Name:       "container1",
+		Image:      &contracts.Image{Name: "imageTest1", Digest: "imageDigest1"},
+		ScanStatus: contracts.UnhealthyScan,
+		ScanFindings: []*contracts.ScanFinding{
+			{Id: "1", Severity: "Low", Patchable: true},
+			{Id: "2", Severity: "Medium", Patchable: false},
+			{Id: "3", Severity: "Medium", Patchable: true},
+		},
+		SignatureStatus: contracts.SignatureVerified,
+	}
+
+	actual := createScanInfoListV2(evaluatedAt, []*contracts.ContainerVulnerabilityScanInfo{container})
+
+	suite.Equal(contracts.SchemaVersionV2, actual.SchemaVersion)
+	suite.Equal(&contracts.ContainerVulnerabilityScanSummary{
+		Name:       "container1",
+		Image:      container.Image,
+		ScanStatus: contracts.UnhealthyScan,
+		Summary: &contracts.VulnerabilitySummary{
+			SeverityCounts:          map[string]int{"High": 0, "Medium": 2, "Low": 1},
+			PatchableSeverityCounts: map[string]int{"High": 0, "Medium": 1, "Low": 1},
+			TotalCount:              3,
+			PatchableCount:          2,
+			MaxSeverity:             "Medium",
+			EvaluatedAt:             evaluatedAt,
+			DataSource:              contracts.ARGDataSource,
+		},
+		SignatureStatus: contracts.SignatureVerified,
+	}, actual.Containers[0])
+}
+
+func (suite *ScanInfoV2TestSuite) Test_createScanInfoListV2_HealthyScan_EmptySummary() {
+	actual := createScanInfoListV2(time.Now().UTC(), []*contracts.ContainerVulnerabilityScanInfo{{Name: "container1", ScanStatus: contracts.HealthyScan}})
+
+	suite.Equal("None", actual.Containers[0].Summary.MaxSeverity)
+	suite.Equal(0, actual.Containers[0].Summary.TotalCount)
+	suite.Equal(map[string]int{"High": 0, "Medium": 0, "Low": 0}, actual.Containers[0].Summary.SeverityCounts)
+}
+
+func (suite *ScanInfoV2TestSuite) Test_createScanInfoListV2_Unscanned_NoSummary() {
+	additionalData := map[string]string{contracts.UnscannedReasonAnnotationKey: string(contracts.ImageIsNotInACRRegistryUnscannedReason)}
+
+	actual := createScanInfoListV2(time.Now().UTC(), []*contracts.ContainerVulnerabilityScanInfo{{Name: "container1", ScanStatus: contracts.Unscanned, AdditionalData: additionalData}})
+
+	suite.Nil(actual.Containers[0].Summary)
+	suite.Equal(additionalData, actual.Containers[0].AdditionalData)
+}
+
+func TestScanInfoV2TestSuite(t *testing.T) {
+	suite.Run(t, new(ScanInfoV2TestSuite))
+}
@@ -5,18 +5,22 @@ import (
 	"context"
 	"fmt"
 	"github.com/Azure/AzureDefender-K8S-InClusterDefense/cmd/webhook/admisionrequest"
+	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/azdsecinfo/contracts"
+	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/decisionlog"
+	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/events"
 	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/instrumentation"
 	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/instrumentation/metric"
 	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/instrumentation/metric/util"
 	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/instrumentation/trace"
 	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/utils"
+	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/vulnerabilityreport"
 	admissionv1 "k8s.io/api/admission/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"net/http"
-	"reflect"
 	"time"
 
 	"github.com/Azure/AzureDefender-K8S-InClusterDefense/cmd/webhook/annotations"
+	"github.com/Azure/AzureDefender-K8S-InClusterDefense/cmd/webhook/labels"
 	webhookmetric "github.com/Azure/AzureDefender-K8S-InClusterDefense/cmd/webhook/metric"
 	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/azdsecinfo"
 	"github.com/pkg/errors"
@@ -32,6 +36,8 @@ const (
 	_patchedReason responseReason = "Patched"
 	//_patchedDeleteStaleContainersVulnerabilityScanInfoAnnotationsReason in case that error occurred and old annotations exist.
 	_patchedDeleteStaleContainersVulnerabilityScanInfoAnnotationsReason = "PatchedDeleteStaleContainersVulnerabilityScanInfoAnnotations"
+	// _reusedOwnerTemplateScanInfoReason in case that the pod inherited fresh scan info from the pod template of its owner, so it wasn't evaluated again.
+	_reusedOwnerTemplateScanInfoReason responseReason = "ReusedOwnerTemplateScanInfo"
 	// _notPatchedReason not patched response reason.
 	_notPatchedReason responseReason = "NotPatched"
 	// _notPatchedErrorReason not patched due to error response reason.
@@ -44,6 +50,17 @@ const (
 	_noMutationForOperationReason responseReason = "NotPatchedNotSupportedOperation"
 	// _noSelfManagementReason in case of resource in same namespace
 	_noSelfManagementReason responseReason = "NotPatchedResourceInTheSameNsOfHandler"
+	// _noSelfMutationReason in case of request of the handler itself (e.g. scan info annotation update of the image drift detector)
+	_noSelfMutationReason responseReason = "NotPatchedRequestOfHandler"
+)
+
+const (
+	// _batchGroup is the group of the Job and CronJob kinds
+	_batchGroup = "batch"
+	// _jobKind is the kind of Job
+	_jobKind = "Job"
+	// _cronJobKind is the kind of CronJob
+	_cronJobKind = "CronJob"
 )
 
 // Handler implements admission.Handler interface
@@ -58,35 +75,74 @@ type Handler struct {
 	metricSubmitter metric.IMetricSubmitter
 	// AzdSecInfoProvider provides azure defender security information
 	azdSecInfoProvider azdsecinfo.IAzdSecInfoProvider
-	// Configurations handler's config.
-	configuration *HandlerConfiguration
+	// getConfiguration returns the current configuration of the handler - the configuration that it was created with,
+	// or the reloaded configuration in case that a configuration provider is set.
+	getConfiguration func() *HandlerConfiguration
 	// Extractor extracts workload resource from admission request.
 	extractor admisionrequest.IExtractor
+	// decisionLogger logs the decision record of each request
+	decisionLogger decisionlog.IDecisionLogger
+	// eventEmitter emits events on the workloads that have vulnerable or unscanned images
+	eventEmitter events.IWorkloadEventEmitter
+	// reportWriter writes the ContainerVulnerabilityReport of the workloads
+	reportWriter vulnerabilityreport.IVulnerabilityReportWriter
+	// ownerScanInfoVerifier verifies that the scan info that a pod inherited is the scan info of the pod template of its owner
+	ownerScanInfoVerifier annotations.IOwnerPodTemplateScanInfoVerifier
 }
 
 // HandlerConfiguration configuration for handler
 type HandlerConfiguration struct {
 	// DryRun is flag that if it's true, it handles request but doesn't mutate the workLoadResource podSpec.
-	DryRun                               bool
-	SupportedKubernetesWorkloadResources []string
+	DryRun bool
+	// AdmissionTimeoutInSeconds is the timeout of the webhook in the admission configuration (timeoutSeconds).
+	// The request and all its lookups are canceled when the API server stops waiting for the response. Zero means no deadline.
+	AdmissionTimeoutInSeconds int
+	// DeadlineSafetyMarginInMS is subtracted from the admission timeout, so the response is sent before the API server stops waiting for it.
+	DeadlineSafetyMarginInMS int
+	// ScanInfoAnnotationConfiguration is the size guard of the scan info annotation - oversized scan info is summarized or compressed
+	ScanInfoAnnotationConfiguration annotations.ScanInfoAnnotationConfiguration
+	// ScanStatusLabelsConfiguration is whether the queryable scan status labels are set on the workload and its pod template
+	ScanStatusLabelsConfiguration labels.ScanStatusLabelsConfiguration
+	// PodTemplateScanInfoConfiguration is whether the scan info is set on the pod template, so pods that inherit fresh scan info aren't evaluated again
+	PodTemplateScanInfoConfiguration annotations.PodTemplateScanInfoConfiguration
 }
 
 // NewHandler Constructor for Handler
-func NewHandler(azdSecInfoProvider azdsecinfo.IAzdSecInfoProvider, configuration *HandlerConfiguration, instrumentationProvider instrumentation.IInstrumentationProvider, extractor admisionrequest.IExtractor) *Handler {
+func NewHandler(azdSecInfoProvider azdsecinfo.IAzdSecInfoProvider, configuration *HandlerConfiguration, instrumentationProvider instrumentation.IInstrumentationProvider, extractor admisionrequest.IExtractor, decisionLogger decisionlog.IDecisionLogger, eventEmitter events.IWorkloadEventEmitter, reportWriter vulnerabilityreport.IVulnerabilityReportWriter, ownerScanInfoVerifier annotations.IOwnerPodTemplateScanInfoVerifier) *Handler {
 
 	return &Handler{
-		tracerProvider:     instrumentationProvider.GetTracerProvider("Handler"),
-		metricSubmitter:    instrumentationProvider.GetMetricSubmitter(),
-		azdSecInfoProvider: azdSecInfoProvider,
-		configuration:      configuration,
-		extractor:          extractor,
+		tracerProvider:        instrumentationProvider.GetTracerProvider("Handler"),
+		metricSubmitter:       instrumentationProvider.GetMetricSubmitter(),
+		azdSecInfoProvider:    azdSecInfoProvider,
+		getConfiguration:      func() *HandlerConfiguration { return configuration },
+		extractor:             extractor,
+		decisionLogger:        decisionLogger,
+		eventEmitter:          eventEmitter,
+		reportWriter:          reportWriter,
+		ownerScanInfoVerifier: ownerScanInfoVerifier,
 	}
 }
 
+// SetConfigurationProvider makes the handler read its configuration from the provider on each use (e.g. from the
+// reloaded configuration), so the requests after a reload use the new configuration. Should be called before the handler is used.
+func (handler *Handler) SetConfigurationProvider(getConfiguration func() *HandlerConfiguration) {
+	handler.getConfiguration = getConfiguration
+}
+
 // Handle processes the AdmissionRequest by invoking the underlying function.
 func (handler *Handler) Handle(ctx context.Context, req admission.Request) admission.Response {
 	startTime := time.Now().UTC()
 	tracer := handler.tracerProvider.GetTracer("Handle")
+	// Abandoned lookups (e.g. fetching of the scan results after the API server stopped waiting) are canceled on the deadline
+	ctx, cancel := handler.withAdmissionDeadline(ctx, startTime)
+	defer cancel()
+	// All the spans of the request (including the spans of the dependencies) have the admission request UID attribute
+	ctx = trace.ContextWithSpanAttributes(ctx, "admissionUID", string(req.UID))
+	ctx, span := handler.tracerProvider.StartSpan(ctx, "Handle", "kind", req.Kind.Kind, "namespace", req.Namespace, "name", req.Name, "operation", string(req.Operation))
+	defer span.End()
+	// The decision record is populated by the handler and its dependencies (through ctx) and logged when the request is handled
+	record := decisionlog.NewDecisionRecord(string(req.UID), string(req.Operation), req.Namespace, req.Kind.Kind, req.Name)
+	ctx = decisionlog.ContextWithDecisionRecord(ctx, record)
 	response := admission.Response{}
 	reason := _notPatchedReason
 	workLoadResourceName := ""
@@ -99,6 +155,7 @@ func (handler *Handler) Handle(ctx context.Context, req admission.Request) admis
 			if !ok {
 				err = errors.New(fmt.Sprint(r))
 			}
+			span.RecordError(err)
 			tracer.Error(err, "Handler handle Panic error", "resource:", req.Resource, "namespace:", req.Namespace, "Name:", req.Name, "workLoadResourceOwnerRefrences:", workLoadResourceOwnerRefrences, "workLoadResourceName:", workLoadResourceName, "operation:", req.Operation, "reqKind:", req.Kind)
 			handler.metricSubmitter.SendMetric(1, util.NewErrorEncounteredMetric(err, "Handler.Handle.Panic"))
 			// Re throw panic
@@ -115,8 +172,11 @@ func (handler *Handler) Handle(ctx context.Context, req admission.Request) admis
 			responseCode = response.Result.Code
 			responseResultReasonStr = string(response.Result.Reason)
 		}
+		span.SetAttributes("allowed", response.Allowed, "resultReason", responseResultReasonStr, "code", int(responseCode), "patchCount", patchCount)
 		tracer.Info("Handle.Response.Result", "resource", req.Resource, "namespace:", req.Namespace, "Name:", req.Name, "Allowed", response.Allowed, "ResultReason", responseResultReasonStr, "code", responseCode, "patchCount", patchCount)
 		handler.metricSubmitter.SendMetric(util.GetDurationMilliseconds(startTime), webhookmetric.NewHandlerHandleLatencyMetric(req.Kind.Kind, response.Allowed, responseResultReasonStr, responseCode, patchCount))
+		record.Complete(response.Allowed, responseResultReasonStr, responseCode, util.GetDurationMilliseconds(startTime), err)
+		handler.decisionLogger.Log(record)
 	}()
 	// Logs
 	tracer.Info("received request", "resource:", req.Resource, "namespace:", req.Namespace, "Name:", req.Name, "operation:", req.Operation, "reqKind:", req.Kind)
@@ -132,6 +192,7 @@ func (handler *Handler) Handle(ctx context.Context, req admission.Request) admis
 	workloadResource, err := handler.extractor.ExtractWorkloadResourceFromAdmissionRequest(&req)
 	if err != nil {
 		err = errors.Wrap(err, "Handler.Handle received error on handleRequest")
+		span.RecordError(err)
 		tracer.Error(err, "")
 		handler.metricSubmitter.SendMetric(1, util.NewErrorEncounteredMetric(err, "Handle.handleRequest"))
 		reason = _notPatchedErrorReason
@@ -141,18 +202,20 @@ func (handler *Handler) Handle(ctx context.Context, req admission.Request) admis
 	tracer.Info("WorkLoadResource request unmarshall", "resource:", req.Resource, "namespace:", req.Namespace, "WorkLoadResourceOwnerRefrences:", workLoadResourceOwnerRefrences, "operation:", req.Operation, "reqKind:", req.Kind)
 	workLoadResourceName = workloadResource.Metadata.Name
 	workLoadResourceOwnerRefrences = workloadResource.Metadata.OwnerReferences
-	response, err = handler.handleWorkLoadResourceRequest(workloadResource)
+	record.SetWorkloadResource(workloadResource)
+	response, err = handler.handleWorkLoadResourceRequest(ctx, &req, workloadResource)
 	if err != nil {
 		err = errors.Wrap(err, "Handler.Handle received error on handleWorkLoadResourceRequest")
+		span.RecordError(err)
 		tracer.Error(err, "")
 		handler.metricSubmitter.SendMetric(1, util.NewErrorEncounteredMetric(err, "Handle.handleWorkLoadResourceRequest"))
-		response := handler.getResponseWhenErrorEncountered(workloadResource, err)
+		response = handler.getResponseWhenErrorEncountered(&req, workloadResource, err)
 		tracer.Info("Handler Responded", "resource:", req.Resource, "namespace:", req.Namespace, "Name:", req.Name, "operation:", req.Operation, "reqKind:", req.Kind, "response:", response)
 		return response
 	}
 
 	// In case of dryrun=true:  reset all patch operations
-	if handler.configuration.DryRun {
+	if handler.getConfiguration().DryRun {
 		tracer.Info("Handler.handleWorkLoadResourceRequest not mutating resource, because handler is on dryrun mode.", "ResponseInCaseOfNotDryRun", response)
 		reason = _notPatchedHandlerDryRunReason
 		// Override response with clean response.
@@ -165,11 +228,39 @@ func (handler *Handler) Handle(ctx context.Context, req admission.Request) admis
 	return response
 }
 
+// withAdmissionDeadline returns ctx with deadline of the admission timeout minus the safety margin since the request started.
+// ctx is returned without deadline (only cancelable) in case that the admission timeout isn't configured.
+func (handler *Handler) withAdmissionDeadline(ctx context.Context, startTime time.Time) (context.Context, context.CancelFunc) {
+	configuration := handler.getConfiguration()
+	if configuration.AdmissionTimeoutInSeconds <= 0 {
+		return context.WithCancel(ctx)
+	}
+	timeout := utils.GetSeconds(configuration.AdmissionTimeoutInSeconds) - utils.GetMilliseconds(configuration.DeadlineSafetyMarginInMS)
+	return context.WithDeadline(ctx, startTime.Add(timeout))
+}
+
 // handleWorkLoadResourceRequest gets request that should be handled and returned the response with the relevant patches.
-func (handler *Handler) handleWorkLoadResourceRequest(workloadResource *admisionrequest.WorkloadResource) (admission.Response, error) {
+func (handler *Handler) handleWorkLoadResourceRequest(ctx context.Context, req *admission.Request, workloadResource *admisionrequest.WorkloadResource) (admission.Response, error) {
 	tracer := handler.tracerProvider.GetTracer("handleWorkloadResourceRequest")
+	configuration := handler.getConfiguration()
+	if configuration.PodTemplateScanInfoConfiguration.Enabled {
+		if inheritedVulnSecInfoContainers, ok := annotations.GetFreshScanInfoInheritedFromOwnerTemplate(workloadResource, &configuration.PodTemplateScanInfoConfiguration); ok {
+			// The annotations of the pod are set by its creator - the scan info is reused only if the owner has it in its pod template
+			isScanInfoOfOwner, err := handler.ownerScanInfoVerifier.IsScanInfoOfOwnerPodTemplate(ctx, req.Namespace, workloadResource)
+			if err != nil {
+				err = errors.Wrap(err, "Handler.handleWorkLoadResourceRequest Failed to verify the inherited scan info - evaluating the pod")
+				tracer.Error(err, "")
+				handler.metricSubmitter.SendMetric(1, util.NewErrorEncounteredMetric(err, "handleWorkLoadResourceRequest.IsScanInfoOfOwnerPodTemplate"))
+			} else if isScanInfoOfOwner {
+				return handler.handleInheritedScanInfo(ctx, req, workloadResource, inheritedVulnSecInfoContainers)
+			} else {
+				tracer.Info("The inherited scan info isn't the scan info of the pod template of the owner - evaluating the pod", "ownerReferences", workloadResource.Metadata.OwnerReferences)
+			}
+		}
+	}
+
 	patches := []jsonpatch.JsonPatchOperation{}
-	vulnerabilitySecAnnotationsPatch, err := handler.getWorkLoadResourceContainersVulnerabilityScanInfoAnnotationsOperation(workloadResource)
+	vulnSecInfoContainers, vulnerabilitySecAnnotationsPatches, err := handler.getWorkLoadResourceContainersVulnerabilityScanInfoAnnotationsOperation(ctx, req, workloadResource)
 	if err != nil {
 		err = errors.Wrap(err, "Handler.handleWorkLoadResourceRequest Failed to getWorkLoadResourceContainersVulnerabilityScanInfoAnnotationsOperation for WorkLoadResource")
 		tracer.Error(err, "")
@@ -178,21 +269,60 @@ func (handler *Handler) handleWorkLoadResourceRequest(workloadResource *admision
 	}
 
 	// Add to response patches
-	patches = append(patches, *vulnerabilitySecAnnotationsPatch)
+	patches = append(patches, vulnerabilitySecAnnotationsPatches...)
+
+	// The pod template is shared by the pods of the workload (and its ReplicaSets), so its annotations don't point to a vulnerability report
+	if configuration.PodTemplateScanInfoConfiguration.Enabled {
+		podTemplateAnnotationsPatches, err := annotations.CreatePodTemplateScanInfoAnnotationPatchAddIfNeeded(vulnSecInfoContainers, workloadResource, &configuration.ScanInfoAnnotationConfiguration, "")
+		if err != nil {
+			err = errors.Wrap(err, "Handler.handleWorkLoadResourceRequest Failed to CreatePodTemplateScanInfoAnnotationPatchAddIfNeeded for WorkLoadResource")
+			tracer.Error(err, "")
+			handler.metricSubmitter.SendMetric(1, util.NewErrorEncounteredMetric(err, "handleWorkLoadResourceRequest.CreatePodTemplateScanInfoAnnotationPatchAddIfNeeded"))
+			return admission.Response{}, err
+		}
+		patches = append(patches, podTemplateAnnotationsPatches...)
+	}
+
+	scanStatusLabelsPatches, err := handler.getWorkLoadResourceScanStatusLabelsOperations(req, workloadResource, vulnSecInfoContainers)
+	if err != nil {
+		err = errors.Wrap(err, "Handler.handleWorkLoadResourceRequest Failed to getWorkLoadResourceScanStatusLabelsOperations for WorkLoadResource")
+		tracer.Error(err, "")
+		handler.metricSubmitter.SendMetric(1, util.NewErrorEncounteredMetric(err, "handleWorkLoadResourceRequest.getWorkLoadResourceScanStatusLabelsOperations"))
+		return admission.Response{}, err
+	}
+	patches = append(patches, scanStatusLabelsPatches...)
 
 	// Patch all patches operations
 	return admission.Patched(string(_patchedReason), patches...), nil
 }
 
+// handleInheritedScanInfo returns the response of a pod that inherited fresh scan info from the pod template of its owner - the scan info
+// annotation is already set, so only the scan status labels are patched. The owner was evaluated when its pod template was set, so
+// no events or vulnerability reports are written.
+func (handler *Handler) handleInheritedScanInfo(ctx context.Context, req *admission.Request, workloadResource *admisionrequest.WorkloadResource, vulnSecInfoContainers []*contracts.ContainerVulnerabilityScanInfo) (admission.Response, error) {
+	tracer := handler.tracerProvider.GetTracer("handleInheritedScanInfo")
+	tracer.Info("Reusing the scan info that the pod inherited from the pod template of its owner", "ownerReferences", workloadResource.Metadata.OwnerReferences)
+	decisionlog.SetContainers(ctx, vulnSecInfoContainers)
+
+	scanStatusLabelsPatches, err := handler.getWorkLoadResourceScanStatusLabelsOperations(req, workloadResource, vulnSecInfoContainers)
+	if err != nil {
+		err = errors.Wrap(err, "Handler.handleInheritedScanInfo Failed to getWorkLoadResourceScanStatusLabelsOperations for WorkLoadResource")
+		tracer.Error(err, "")
+		handler.metricSubmitter.SendMetric(1, util.NewErrorEncounteredMetric(err, "handleInheritedScanInfo.getWorkLoadResourceScanStatusLabelsOperations"))
+		return admission.Response{}, err
+	}
+	return admission.Patched(string(_reusedOwnerTemplateScanInfoReason), scanStatusLabelsPatches...), nil
+}
+
 // getResponseWhenErrorEncountered returns a response in which it deletes previous ContainersVulnerabilityScan annotations.
 // If no such annotations exist it returns handler.admissionErrorResponse with the original error.
-func (handler *Handler) getResponseWhenErrorEncountered(workloadResource *admisionrequest.WorkloadResource, originalError error) admission.Response {
+func (handler *Handler) getResponseWhenErrorEncountered(req *admission.Request, workloadResource *admisionrequest.WorkloadResource, originalError error) admission.Response {
 	tracer := handler.tracerProvider.GetTracer("getResponseWhenErrorEncountered")
 
 	patches := []jsonpatch.JsonPatchOperation{}
 
 	// returns nil if no deletion is needed.
-	patch, err := annotations.CreateAnnotationPatchToDeleteContainersVulnerabilityScanAnnotationIfNeeded(workloadResource)
+	annotationsPatches, err := annotations.CreateAnnotationPatchToDeleteContainersVulnerabilityScanAnnotationIfNeeded(workloadResource)
 
 	// if error encountered during CreateAnnotationPatchToDeleteContainersVulnerabilityScanAnnotationIfNeeded - response with the original error
 	if err != nil {
@@ -203,51 +333,81 @@ func (handler *Handler) getResponseWhenErrorEncountered(workloadResource *admisi
 		response := handler.admissionErrorResponse(errors.Wrap(originalError, string(reason)))
 		return response
 	}
+	patches = append(patches, annotationsPatches...)
 
-	// patch is nil when the workLoadResource's annotations doesn't contain the webhook annotations so there is no need
-	//to delete them. response with the original error
-	if patch == nil {
-		tracer.Info("ContainersVulnerabilityScanAnnotation dont exist - no need to delete them ")
+	// returns empty slice if no deletion is needed.
+	scanStatusLabelsPatches, err := labels.CreateLabelsPatchToDeleteScanStatusLabelsIfNeeded(workloadResource, isPodTemplateImmutable(req))
+	if err != nil {
+		err = errors.Wrap(err, "Handler.getResponseWhenErrorEncountered Failed to CreateLabelsPatchToDeleteScanStatusLabelsIfNeeded for workLoadResource")
+		tracer.Error(err, "")
+		handler.metricSubmitter.SendMetric(1, util.NewErrorEncounteredMetric(err, "Handler.getResponseWhenErrorEncountered"))
 		reason := _notPatchedErrorReason
 		response := handler.admissionErrorResponse(errors.Wrap(originalError, string(reason)))
 		return response
 	}
+	patches = append(patches, scanStatusLabelsPatches...)
 
-	// Add to response patches
-	patches = append(patches, *patch)
+	// patches is empty when the workLoadResource doesn't contain the webhook annotations and labels so there is no need
+	//to delete them. response with the original error
+	if len(patches) == 0 {
+		tracer.Info("ContainersVulnerabilityScanAnnotation and scan status labels dont exist - no need to delete them ")
+		reason := _notPatchedErrorReason
+		response := handler.admissionErrorResponse(errors.Wrap(originalError, string(reason)))
+		return response
+	}
 
 	// Patch all patches operations
 	return handler.admissionErrorResponseWithAnnotationsDelete(originalError, patches)
 }
 
-// getWorkLoadResourceContainersVulnerabilityScanInfoAnnotationsOperation receives a workLoadResource to generate a vuln scan annotation add operation
+// getWorkLoadResourceContainersVulnerabilityScanInfoAnnotationsOperation receives a workLoadResource to generate the vuln scan annotation operations
 // Get vuln scan infor from azdSecInfo provider, then create a json annotation for it on workLoadResources custom annotations of azd vuln scan info
-func (handler *Handler) getWorkLoadResourceContainersVulnerabilityScanInfoAnnotationsOperation(workloadResource *admisionrequest.WorkloadResource) (*jsonpatch.JsonPatchOperation, error) {
+// The containers vulnerability scan info is returned as well.
+func (handler *Handler) getWorkLoadResourceContainersVulnerabilityScanInfoAnnotationsOperation(ctx context.Context, req *admission.Request, workloadResource *admisionrequest.WorkloadResource) ([]*contracts.ContainerVulnerabilityScanInfo, []jsonpatch.JsonPatchOperation, error) {
 	tracer := handler.tracerProvider.GetTracer("getWorkLoadResourceContainersVulnerabilityScanInfoAnnotationsOperation")
 	handler.metricSubmitter.SendMetric(len(workloadResource.Spec.Containers)+len(workloadResource.Spec.InitContainers), webhookmetric.NewHandlerNumOfContainersPerworkLoadResourceMetric())
 
 	// Get workLoadResource's containers vulnerability scan info
-	vulnSecInfoContainers, err := handler.azdSecInfoProvider.GetContainersVulnerabilityScanInfo(workloadResource)
+	vulnSecInfoContainers, err := handler.azdSecInfoProvider.GetContainersVulnerabilityScanInfo(ctx, workloadResource)
 	if err != nil {
 		wrappedError := errors.Wrap(err, "Handler failed to GetContainersVulnerabilityScanInfo")
 		tracer.Error(wrappedError, "Handler.AzdSecInfoProvider.GetContainersVulnerabilityScanInfo")
 		handler.metricSubmitter.SendMetric(1, util.NewErrorEncounteredMetric(wrappedError, "Handler.AzdSecInfoProvider.GetContainersVulnerabilityScanInfo"))
-		return nil, wrappedError
+		return nil, nil, wrappedError
 	}
 
 	// Log result
 	tracer.Info("vulnSecInfoContainers", "vulnSecInfoContainers", vulnSecInfoContainers)
+	decisionlog.SetContainers(ctx, vulnSecInfoContainers)
+
+	// Emit event on the workload if it has vulnerable or unscanned images and write its vulnerability report.
+	// The webhook has no side effects on dry run requests.
+	vulnerabilityReportName := ""
+	if req.DryRun == nil || !*req.DryRun {
+		handler.eventEmitter.EmitContainersVulnerabilityScanEvent(ctx, req.Namespace, req.Kind, workloadResource, vulnSecInfoContainers)
+		vulnerabilityReportName = handler.reportWriter.WriteReport(ctx, req.Namespace, req.Kind, workloadResource, vulnSecInfoContainers)
+	}
 
-	// Create the annotations add json patch operation
-	vulnerabilitySecAnnotationsPatch, err := annotations.CreateContainersVulnerabilityScanAnnotationPatchAdd(vulnSecInfoContainers, workloadResource)
+	// Create the annotations json patch operations
+	vulnerabilitySecAnnotationsPatches, err := annotations.CreateContainersVulnerabilityScanAnnotationPatchAdd(vulnSecInfoContainers, workloadResource, &handler.getConfiguration().ScanInfoAnnotationConfiguration, vulnerabilityReportName)
 	if err != nil {
 		wrappedError := errors.Wrap(err, "Handler failed to CreateContainersVulnerabilityScanAnnotationPatchAdd")
 		tracer.Error(wrappedError, "Handler.annotations.CreateContainersVulnerabilityScanAnnotationPatchAdd")
 		handler.metricSubmitter.SendMetric(1, util.NewErrorEncounteredMetric(wrappedError, "Handler.annotations.CreateContainersVulnerabilityScanAnnotationPatchAdd"))
-		return nil, wrappedError
+		return nil, nil, wrappedError
 	}
 
-	return vulnerabilitySecAnnotationsPatch, nil
+	return vulnSecInfoContainers, vulnerabilitySecAnnotationsPatches, nil
+}
+
+// getWorkLoadResourceScanStatusLabelsOperations returns the operations that set the scan status labels of the workLoadResource
+// in case that they are enabled. Otherwise, the operations that delete the stale scan status labels (if there are any).
+func (handler *Handler) getWorkLoadResourceScanStatusLabelsOperations(req *admission.Request, workloadResource *admisionrequest.WorkloadResource, vulnSecInfoContainers []*contracts.ContainerVulnerabilityScanInfo) ([]jsonpatch.JsonPatchOperation, error) {
+	configuration := &handler.getConfiguration().ScanStatusLabelsConfiguration
+	if !configuration.Enabled {
+		return labels.CreateLabelsPatchToDeleteScanStatusLabelsIfNeeded(workloadResource, isPodTemplateImmutable(req))
+	}
+	return labels.CreateScanStatusLabelsPatchAdd(vulnSecInfoContainers, workloadResource, configuration, isPodTemplateImmutable(req))
 }
 
 // admissionErrorResponse generates an admission response error in case of handler failing to process request
@@ -288,10 +448,15 @@ func (handler *Handler) shouldRequestBeFiltered(req admission.Request) (bool, re
 		return true, _noSelfManagementReason
 	}
 
-	// Filter if the kind is not workload resource
-	tracer.Info("SupportedKubernetesWorkloadResources: ","array", handler.configuration.SupportedKubernetesWorkloadResources,"type",reflect.TypeOf(handler.configuration.SupportedKubernetesWorkloadResources[0]).Kind())
-	if !utils.StringInSlice(req.Kind.Kind, handler.configuration.SupportedKubernetesWorkloadResources) {
-		tracer.Info("Request filtered out due to the request is not supported kind: ", "ReqKind", req.Kind.Kind)
+	// If it's a request of the handler itself - its updates shouldn't be overridden
+	if serviceAccountUsername := utils.GetDeploymentInstance().GetServiceAccountUsername(); serviceAccountUsername != "" && req.UserInfo.Username == serviceAccountUsername {
+		tracer.Info("Request filtered out due to it is a request of the handler itself.", "Username", req.UserInfo.Username)
+		return true, _noSelfMutationReason
+	}
+
+	// Filter if the kind is not workload resource - matched by group, version and kind as the extraction of the workload resource
+	if !handler.extractor.IsSupportedKind(req.Kind) {
+		tracer.Info("Request filtered out due to the request is not supported kind: ", "ReqKind", req.Kind.Kind, "ReqGroup", req.Kind.Group, "ReqVersion", req.Kind.Version)
 		return true, _noMutationForKindReason
 	}
 
@@ -306,6 +471,13 @@ func (handler *Handler) shouldRequestBeFiltered(req admission.Request) (bool, re
 	return false, _patchedReason
 }
 
+// isPodTemplateImmutable returns true if the pod template of the request's resource mustn't be changed by the request - the pod template
+// of a Job is immutable after its creation, so it's patched only on CREATE. The job template of a CronJob is treated the same, so the
+// Jobs that are created from it keep the pod template they were admitted with.
+func isPodTemplateImmutable(req *admission.Request) bool {
+	return req.Operation == admissionv1.Update && req.Kind.Group == _batchGroup && (req.Kind.Kind == _jobKind || req.Kind.Kind == _cronJobKind)
+}
+
 // isOperationAllowed returns boolean if the operation is allowed.
 func isOperationAllowed(operation *admissionv1.Operation) bool {
 	return *operation == admissionv1.Create || *operation == admissionv1.Update
@@ -7,28 +7,44 @@ import (
 	"context"
 	"encoding/json"
 	"github.com/Azure/AzureDefender-K8S-InClusterDefense/cmd/webhook/admisionrequest"
+	"github.com/Azure/AzureDefender-K8S-InClusterDefense/cmd/webhook/annotations"
+	"github.com/Azure/AzureDefender-K8S-InClusterDefense/cmd/webhook/labels"
 	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/azdsecinfo/contracts"
 	azdsecinfoMocks "github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/azdsecinfo/mocks"
+	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/decisionlog"
+	decisionlogMocks "github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/decisionlog/mocks"
+	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/events"
+	eventsMocks "github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/events/mocks"
 	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/instrumentation"
 	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/utils"
+	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/vulnerabilityreport"
+	vulnerabilityreportMocks "github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/vulnerabilityreport/mocks"
 	"github.com/pkg/errors"
+	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/suite"
 	"gomodules.xyz/jsonpatch/v2"
 	admissionv1 "k8s.io/api/admission/v1"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	"log"
 	"net/http"
 	"reflect"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+	"strings"
 	"testing"
 	"time"
 )
 
 const (
-	_expectedTestAddPatchOperation   = "add"
-	_expectedTestAnnotationPatchPath = "/metadata/annotations"
+	_expectedTestAddPatchOperation    = "add"
+	_expectedTestRemovePatchOperation = "remove"
+	_expectedTestAnnotationPatchPath  = "/metadata/annotations"
+	_expectedTestScanInfoPatchPath    = "/metadata/annotations/azuredefender.io~1containers.vulnerability.scan.info"
 	_annotationTestKeyOne            = "cluster-autoscaler.kubernetes.io/safe-to-evict"
 	_annotationTestValueOne          = "true"
 	_annotationTestKeyTwo            = "container.seccomp.security.alpha.kubernetes.io/manager"
@@ -69,19 +85,9 @@ var (
 	_firstContainerVulnerabilityScanInfo  = &contracts.ContainerVulnerabilityScanInfo{Name: "Lior"}
 	_secondContainerVulnerabilityScanInfo = &contracts.ContainerVulnerabilityScanInfo{Name: "Or"}
 
-	_expectedPatchForErrorEncountered1 = jsonpatch.Operation{
-		Operation: "add",
-		Path:      _expectedTestAnnotationPatchPath,
-		Value: map[string]string{
-			_annotationTestKeyOne: _annotationTestValueOne,
-			_annotationTestKeyTwo: _annotationTestValueTwo,
-		},
-	}
-
-	_expectedPatchForErrorEncountered2 = jsonpatch.Operation{
-		Operation: "add",
-		Path:      _expectedTestAnnotationPatchPath,
-		Value:     map[string]string{},
+	_expectedPatchForErrorEncountered = jsonpatch.Operation{
+		Operation: _expectedTestRemovePatchOperation,
+		Path:      _expectedTestScanInfoPatchPath,
 	}
 )
 
@@ -89,6 +95,8 @@ type TestSuite struct {
 	suite.Suite
 	azdSecProviderMock *azdsecinfoMocks.IAzdSecInfoProvider
 	extractor          admisionrequest.IExtractor
+	// ownerScanInfoVerifier verifies the inherited scan info with the owners of the fake client
+	ownerScanInfoVerifier annotations.IOwnerPodTemplateScanInfoVerifier
 }
 
 // This will run before each test in the suite
@@ -100,6 +108,7 @@ func (suite *TestSuite) SetupTest() {
 	extractorConfig := admisionrequest.ExtractorConfiguration{SupportedKubernetesWorkloadResources: []string{"Pod", "Deployment",
 		"ReplicaSet", "StatefulSet", "DaemonSet", "Job", "CronJob", "ReplicationController"}}
 	suite.extractor = admisionrequest.NewExtractor(instrumentation.NewNoOpInstrumentationProvider(), &extractorConfig)
+	suite.ownerScanInfoVerifier = annotations.NewOwnerPodTemplateScanInfoVerifier(instrumentation.NewNoOpInstrumentationProvider(), fake.NewClientBuilder().WithScheme(clientgoscheme.Scheme).Build())
 }
 
 func (suite *TestSuite) Test_Handle_DryRunTrue_ShouldNotPatched() {
@@ -108,10 +117,9 @@ func (suite *TestSuite) Test_Handle_DryRunTrue_ShouldNotPatched() {
 	resource := createWorkloadResourceForTests([]*admisionrequest.Container{_containersAdmision[0]}, nil)
 	req := createRequestForTests(pod)
 	expectedInfo := []*contracts.ContainerVulnerabilityScanInfo{_firstContainerVulnerabilityScanInfo}
-	suite.azdSecProviderMock.On("GetContainersVulnerabilityScanInfo", resource).Return(expectedInfo, nil).Once()
+	suite.azdSecProviderMock.On("GetContainersVulnerabilityScanInfo", mock.Anything, resource).Return(expectedInfo, nil).Once()
 
-	handler := NewHandler(suite.azdSecProviderMock, &HandlerConfiguration{DryRun: true,SupportedKubernetesWorkloadResources: []string{"Pod", "Deployment",
-		"ReplicaSet", "StatefulSet", "DaemonSet", "Job", "CronJob", "ReplicationController"}}, instrumentation.NewNoOpInstrumentationProvider(), suite.extractor)
+	handler := NewHandler(suite.azdSecProviderMock, &HandlerConfiguration{DryRun: true}, instrumentation.NewNoOpInstrumentationProvider(), suite.extractor, decisionlog.NewNoOpDecisionLogger(), events.NewNoOpWorkloadEventEmitter(), vulnerabilityreport.NewNoOpVulnerabilityReportWriter(), suite.ownerScanInfoVerifier)
 	// Act
 	resp := handler.Handle(context.Background(), *req)
 	// Test
@@ -129,17 +137,17 @@ func (suite *TestSuite) Test_Handle_DryRunFalse_ShouldPatched() {
 		_firstContainerVulnerabilityScanInfo,
 	}
 
-	suite.azdSecProviderMock.On("GetContainersVulnerabilityScanInfo", resource).Return(expected, nil).Once()
+	suite.azdSecProviderMock.On("GetContainersVulnerabilityScanInfo", mock.Anything, resource).Return(expected, nil).Once()
 
-	handler := NewHandler(suite.azdSecProviderMock, &HandlerConfiguration{DryRun: false,SupportedKubernetesWorkloadResources: []string{"Pod", "Deployment",
-		"ReplicaSet", "StatefulSet", "DaemonSet", "Job", "CronJob", "ReplicationController"}}, instrumentation.NewNoOpInstrumentationProvider(), suite.extractor)
+	handler := NewHandler(suite.azdSecProviderMock, &HandlerConfiguration{DryRun: false}, instrumentation.NewNoOpInstrumentationProvider(), suite.extractor, decisionlog.NewNoOpDecisionLogger(), events.NewNoOpWorkloadEventEmitter(), vulnerabilityreport.NewNoOpVulnerabilityReportWriter(), suite.ownerScanInfoVerifier)
 	// Act
 	resp := handler.Handle(context.Background(), *req)
 	// Test
 	suite.Equal(admission.Allowed(string(_patchedReason)).AdmissionResponse, resp.AdmissionResponse)
 	suite.Equal(metav1.StatusReason(_patchedReason), resp.Result.Reason)
-	suite.Equal(1, len(resp.Patches))
-	patch := resp.Patches[0]
+	suite.Equal(2, len(resp.Patches))
+	suite.Equal(jsonpatch.NewOperation(_expectedTestAddPatchOperation, _expectedTestAnnotationPatchPath, map[string]string{}), resp.Patches[0])
+	patch := resp.Patches[1]
 
 	suite.checkPatch(expected, patch)
 	suite.azdSecProviderMock.AssertExpectations(suite.T())
@@ -152,8 +160,7 @@ func (suite *TestSuite) Test_Handle_RequestKindIsNotPod_ShouldNotPatched() {
 	req := createRequestForTests(pod)
 	req.Kind.Kind = "NotPodKind"
 
-	handler := NewHandler(suite.azdSecProviderMock, &HandlerConfiguration{DryRun: false,SupportedKubernetesWorkloadResources: []string{"Pod", "Deployment",
-		"ReplicaSet", "StatefulSet", "DaemonSet", "Job", "CronJob", "ReplicationController"}}, instrumentation.NewNoOpInstrumentationProvider(), suite.extractor)
+	handler := NewHandler(suite.azdSecProviderMock, &HandlerConfiguration{DryRun: false}, instrumentation.NewNoOpInstrumentationProvider(), suite.extractor, decisionlog.NewNoOpDecisionLogger(), events.NewNoOpWorkloadEventEmitter(), vulnerabilityreport.NewNoOpVulnerabilityReportWriter(), suite.ownerScanInfoVerifier)
 	// Act
 	resp := handler.Handle(context.Background(), *req)
 	// Test
@@ -162,6 +169,37 @@ func (suite *TestSuite) Test_Handle_RequestKindIsNotPod_ShouldNotPatched() {
 	suite.azdSecProviderMock.AssertExpectations(suite.T())
 }
 
+func (suite *TestSuite) Test_Handle_RequestKindOfOtherGroup_ShouldNotPatched() {
+	// Setup - Knative Service is supported, but the core Service (same kind, other group) isn't a workload resource
+	req := createRequestForTests(createPodForTests([]corev1.Container{_containers[0]}, nil))
+	req.Kind = metav1.GroupVersionKind{Group: "", Version: "v1", Kind: "Service"}
+	extractor := admisionrequest.NewExtractor(instrumentation.NewNoOpInstrumentationProvider(), &admisionrequest.ExtractorConfiguration{WorkloadResourceKinds: []*admisionrequest.WorkloadResourceKind{
+		{Group: "serving.knative.dev", Version: "v1", Kind: "Service", Resource: "services", PodSpecPaths: []string{"spec.template.spec"}},
+	}})
+
+	handler := NewHandler(suite.azdSecProviderMock, &HandlerConfiguration{DryRun: false}, instrumentation.NewNoOpInstrumentationProvider(), extractor, decisionlog.NewNoOpDecisionLogger(), events.NewNoOpWorkloadEventEmitter(), vulnerabilityreport.NewNoOpVulnerabilityReportWriter(), suite.ownerScanInfoVerifier)
+	// Act
+	resp := handler.Handle(context.Background(), *req)
+	// Test
+	suite.Equal(metav1.StatusReason(_noMutationForKindReason), resp.Result.Reason)
+	suite.azdSecProviderMock.AssertNotCalled(suite.T(), "GetContainersVulnerabilityScanInfo", mock.Anything, mock.Anything)
+}
+
+func (suite *TestSuite) Test_Handle_RequestOfHandlerServiceAccount_ShouldNotPatched() {
+	// Setup
+	utils.UpdateDeploymentForTests(&utils.DeploymentConfiguration{Namespace: "kube-system", ServiceAccountName: "azure-defender-proxy-admin"})
+	pod := createPodForTests([]corev1.Container{_containers[0]}, nil)
+	req := createRequestForTests(pod)
+	req.UserInfo.Username = "system:serviceaccount:kube-system:azure-defender-proxy-admin"
+
+	handler := NewHandler(suite.azdSecProviderMock, &HandlerConfiguration{DryRun: false}, instrumentation.NewNoOpInstrumentationProvider(), suite.extractor, decisionlog.NewNoOpDecisionLogger(), events.NewNoOpWorkloadEventEmitter(), vulnerabilityreport.NewNoOpVulnerabilityReportWriter(), suite.ownerScanInfoVerifier)
+	// Act
+	resp := handler.Handle(context.Background(), *req)
+	// Test
+	suite.Equal(admission.Allowed(string(_noSelfMutationReason)), resp)
+	suite.azdSecProviderMock.AssertExpectations(suite.T())
+}
+
 func (suite *TestSuite) Test_Handle_RequestDeleteOperation_ShouldNotPatched() {
 	// Setup
 	containers := []corev1.Container{_containers[0]}
@@ -169,8 +207,7 @@ func (suite *TestSuite) Test_Handle_RequestDeleteOperation_ShouldNotPatched() {
 	req := createRequestForTests(pod)
 	req.Operation = admissionv1.Delete
 
-	handler := NewHandler(suite.azdSecProviderMock, &HandlerConfiguration{DryRun: false,SupportedKubernetesWorkloadResources: []string{"Pod", "Deployment",
-		"ReplicaSet", "StatefulSet", "DaemonSet", "Job", "CronJob", "ReplicationController"}}, instrumentation.NewNoOpInstrumentationProvider(), suite.extractor)
+	handler := NewHandler(suite.azdSecProviderMock, &HandlerConfiguration{DryRun: false}, instrumentation.NewNoOpInstrumentationProvider(), suite.extractor, decisionlog.NewNoOpDecisionLogger(), events.NewNoOpWorkloadEventEmitter(), vulnerabilityreport.NewNoOpVulnerabilityReportWriter(), suite.ownerScanInfoVerifier)
 	// Act
 	resp := handler.Handle(context.Background(), *req)
 	// Test
@@ -186,8 +223,7 @@ func (suite *TestSuite) Test_Handle_RequestConnectOperation_ShouldNotPatched() {
 	req := createRequestForTests(pod)
 	req.Operation = admissionv1.Connect
 
-	handler := NewHandler(suite.azdSecProviderMock, &HandlerConfiguration{DryRun: false,SupportedKubernetesWorkloadResources: []string{"Pod", "Deployment",
-		"ReplicaSet", "StatefulSet", "DaemonSet", "Job", "CronJob", "ReplicationController"}}, instrumentation.NewNoOpInstrumentationProvider(), suite.extractor)
+	handler := NewHandler(suite.azdSecProviderMock, &HandlerConfiguration{DryRun: false}, instrumentation.NewNoOpInstrumentationProvider(), suite.extractor, decisionlog.NewNoOpDecisionLogger(), events.NewNoOpWorkloadEventEmitter(), vulnerabilityreport.NewNoOpVulnerabilityReportWriter(), suite.ownerScanInfoVerifier)
 	// Act
 	resp := handler.Handle(context.Background(), *req)
 	// Test
@@ -206,17 +242,17 @@ func (suite *TestSuite) Test_Handle_RequestUpdateOperation_ShouldPatched() {
 		_firstContainerVulnerabilityScanInfo,
 	}
 
-	suite.azdSecProviderMock.On("GetContainersVulnerabilityScanInfo", resource).Return(expected, nil).Once()
+	suite.azdSecProviderMock.On("GetContainersVulnerabilityScanInfo", mock.Anything, resource).Return(expected, nil).Once()
 
-	handler := NewHandler(suite.azdSecProviderMock, &HandlerConfiguration{DryRun: false, SupportedKubernetesWorkloadResources: []string{"Pod", "Deployment",
-		"ReplicaSet", "StatefulSet", "DaemonSet", "Job", "CronJob", "ReplicationController"}}, instrumentation.NewNoOpInstrumentationProvider(), suite.extractor)
+	handler := NewHandler(suite.azdSecProviderMock, &HandlerConfiguration{DryRun: false}, instrumentation.NewNoOpInstrumentationProvider(), suite.extractor, decisionlog.NewNoOpDecisionLogger(), events.NewNoOpWorkloadEventEmitter(), vulnerabilityreport.NewNoOpVulnerabilityReportWriter(), suite.ownerScanInfoVerifier)
 	// Act
 	resp := handler.Handle(context.Background(), *req)
 	// Test
 	suite.Equal(admission.Allowed(string(_patchedReason)).AdmissionResponse, resp.AdmissionResponse)
 	suite.Equal(metav1.StatusReason(_patchedReason), resp.Result.Reason)
-	suite.Equal(1, len(resp.Patches))
-	patch := resp.Patches[0]
+	suite.Equal(2, len(resp.Patches))
+	suite.Equal(jsonpatch.NewOperation(_expectedTestAddPatchOperation, _expectedTestAnnotationPatchPath, map[string]string{}), resp.Patches[0])
+	patch := resp.Patches[1]
 
 	suite.checkPatch(expected, patch)
 	suite.azdSecProviderMock.AssertExpectations(suite.T())
@@ -228,22 +264,22 @@ func (suite *TestSuite) Test_Handle_OneContainerZeroInitContainer_ShouldPatchedO
 	resource := createWorkloadResourceForTests([]*admisionrequest.Container{_containersAdmision[0]}, nil)
 	req := createRequestForTests(pod)
 	expectedInfo := []*contracts.ContainerVulnerabilityScanInfo{_firstContainerVulnerabilityScanInfo}
-	suite.azdSecProviderMock.On("GetContainersVulnerabilityScanInfo", resource).Return(expectedInfo, nil).Once()
+	suite.azdSecProviderMock.On("GetContainersVulnerabilityScanInfo", mock.Anything, resource).Return(expectedInfo, nil).Once()
 
 	expected := []*contracts.ContainerVulnerabilityScanInfo{
 		_firstContainerVulnerabilityScanInfo,
 	}
 
-	handler := NewHandler(suite.azdSecProviderMock, &HandlerConfiguration{DryRun: false, SupportedKubernetesWorkloadResources: []string{"Pod", "Deployment",
-		"ReplicaSet", "StatefulSet", "DaemonSet", "Job", "CronJob", "ReplicationController"}}, instrumentation.NewNoOpInstrumentationProvider(), suite.extractor)
+	handler := NewHandler(suite.azdSecProviderMock, &HandlerConfiguration{DryRun: false}, instrumentation.NewNoOpInstrumentationProvider(), suite.extractor, decisionlog.NewNoOpDecisionLogger(), events.NewNoOpWorkloadEventEmitter(), vulnerabilityreport.NewNoOpVulnerabilityReportWriter(), suite.ownerScanInfoVerifier)
 
 	// Act
 	resp := handler.Handle(context.Background(), *req)
 
 	// Test
 	suite.Equal(admission.Allowed(string(_patchedReason)).AdmissionResponse, resp.AdmissionResponse)
-	suite.Equal(1, len(resp.Patches))
-	patch := resp.Patches[0]
+	suite.Equal(2, len(resp.Patches))
+	suite.Equal(jsonpatch.NewOperation(_expectedTestAddPatchOperation, _expectedTestAnnotationPatchPath, map[string]string{}), resp.Patches[0])
+	patch := resp.Patches[1]
 
 	suite.checkPatch(expected, patch)
 	suite.azdSecProviderMock.AssertExpectations(suite.T())
@@ -255,17 +291,17 @@ func (suite *TestSuite) Test_Handle_TwoContainerZeroInitContainer_ShouldPatchedT
 	resource := createWorkloadResourceForTests([]*admisionrequest.Container{_containersAdmision[0], _containersAdmision[1]}, nil)
 	req := createRequestForTests(pod)
 	expectedInfo := []*contracts.ContainerVulnerabilityScanInfo{_firstContainerVulnerabilityScanInfo, _secondContainerVulnerabilityScanInfo}
-	suite.azdSecProviderMock.On("GetContainersVulnerabilityScanInfo", resource).Return(expectedInfo, nil).Once()
+	suite.azdSecProviderMock.On("GetContainersVulnerabilityScanInfo", mock.Anything, resource).Return(expectedInfo, nil).Once()
 
-	handler := NewHandler(suite.azdSecProviderMock, &HandlerConfiguration{DryRun: false,SupportedKubernetesWorkloadResources: []string{"Pod", "Deployment",
-		"ReplicaSet", "StatefulSet", "DaemonSet", "Job", "CronJob", "ReplicationController"}}, instrumentation.NewNoOpInstrumentationProvider(), suite.extractor)
+	handler := NewHandler(suite.azdSecProviderMock, &HandlerConfiguration{DryRun: false}, instrumentation.NewNoOpInstrumentationProvider(), suite.extractor, decisionlog.NewNoOpDecisionLogger(), events.NewNoOpWorkloadEventEmitter(), vulnerabilityreport.NewNoOpVulnerabilityReportWriter(), suite.ownerScanInfoVerifier)
 
 	// Act
 	resp := handler.Handle(context.Background(), *req)
 	// Test
 	suite.Equal(admission.Allowed(string(_patchedReason)).AdmissionResponse, resp.AdmissionResponse)
-	suite.Equal(1, len(resp.Patches))
-	patch := resp.Patches[0]
+	suite.Equal(2, len(resp.Patches))
+	suite.Equal(jsonpatch.NewOperation(_expectedTestAddPatchOperation, _expectedTestAnnotationPatchPath, map[string]string{}), resp.Patches[0])
+	patch := resp.Patches[1]
 
 	suite.checkPatch(expectedInfo, patch)
 }
@@ -277,18 +313,18 @@ func (suite *TestSuite) Test_Handle_ZeroContainerOneInitContainer_ShouldPatchedO
 	req := createRequestForTests(pod)
 
 	expectedInfo := []*contracts.ContainerVulnerabilityScanInfo{_firstContainerVulnerabilityScanInfo}
-	suite.azdSecProviderMock.On("GetContainersVulnerabilityScanInfo", resource).Return(expectedInfo, nil).Once()
+	suite.azdSecProviderMock.On("GetContainersVulnerabilityScanInfo", mock.Anything, resource).Return(expectedInfo, nil).Once()
 
-	handler := NewHandler(suite.azdSecProviderMock, &HandlerConfiguration{DryRun: false, SupportedKubernetesWorkloadResources: []string{"Pod", "Deployment",
-		"ReplicaSet", "StatefulSet", "DaemonSet", "Job", "CronJob", "ReplicationController"}}, instrumentation.NewNoOpInstrumentationProvider(), suite.extractor)
+	handler := NewHandler(suite.azdSecProviderMock, &HandlerConfiguration{DryRun: false}, instrumentation.NewNoOpInstrumentationProvider(), suite.extractor, decisionlog.NewNoOpDecisionLogger(), events.NewNoOpWorkloadEventEmitter(), vulnerabilityreport.NewNoOpVulnerabilityReportWriter(), suite.ownerScanInfoVerifier)
 
 	//Act
 	resp := handler.Handle(context.Background(), *req)
 
 	//Test
 	suite.Equal(admission.Allowed(string(_patchedReason)).AdmissionResponse, resp.AdmissionResponse)
-	suite.Equal(1, len(resp.Patches))
-	patch := resp.Patches[0]
+	suite.Equal(2, len(resp.Patches))
+	suite.Equal(jsonpatch.NewOperation(_expectedTestAddPatchOperation, _expectedTestAnnotationPatchPath, map[string]string{}), resp.Patches[0])
+	patch := resp.Patches[1]
 	suite.checkPatch(expectedInfo, patch)
 	suite.azdSecProviderMock.AssertExpectations(suite.T())
 }
@@ -300,18 +336,18 @@ func (suite *TestSuite) Test_Handle_ZeroContainerTwoInitContainer_ShouldPatchedT
 	req := createRequestForTests(pod)
 
 	expectedInfo := []*contracts.ContainerVulnerabilityScanInfo{_firstContainerVulnerabilityScanInfo, _secondContainerVulnerabilityScanInfo}
-	suite.azdSecProviderMock.On("GetContainersVulnerabilityScanInfo", resource).Return(expectedInfo, nil).Once()
+	suite.azdSecProviderMock.On("GetContainersVulnerabilityScanInfo", mock.Anything, resource).Return(expectedInfo, nil).Once()
 
-	handler := NewHandler(suite.azdSecProviderMock, &HandlerConfiguration{DryRun: false, SupportedKubernetesWorkloadResources: []string{"Pod", "Deployment",
-		"ReplicaSet", "StatefulSet", "DaemonSet", "Job", "CronJob", "ReplicationController"}}, instrumentation.NewNoOpInstrumentationProvider(), suite.extractor)
+	handler := NewHandler(suite.azdSecProviderMock, &HandlerConfiguration{DryRun: false}, instrumentation.NewNoOpInstrumentationProvider(), suite.extractor, decisionlog.NewNoOpDecisionLogger(), events.NewNoOpWorkloadEventEmitter(), vulnerabilityreport.NewNoOpVulnerabilityReportWriter(), suite.ownerScanInfoVerifier)
 
 	//Act
 	resp := handler.Handle(context.Background(), *req)
 
 	//Test
 	suite.Equal(admission.Allowed(string(_patchedReason)).AdmissionResponse, resp.AdmissionResponse)
-	suite.Equal(1, len(resp.Patches))
-	patch := resp.Patches[0]
+	suite.Equal(2, len(resp.Patches))
+	suite.Equal(jsonpatch.NewOperation(_expectedTestAddPatchOperation, _expectedTestAnnotationPatchPath, map[string]string{}), resp.Patches[0])
+	patch := resp.Patches[1]
 	suite.checkPatch(expectedInfo, patch)
 	suite.azdSecProviderMock.AssertExpectations(suite.T())
 
@@ -324,17 +360,17 @@ func (suite *TestSuite) Test_Handle_OneContainerOneInitContainer_ShouldPatchedTw
 	req := createRequestForTests(pod)
 
 	expectedInfo := []*contracts.ContainerVulnerabilityScanInfo{_firstContainerVulnerabilityScanInfo, _secondContainerVulnerabilityScanInfo}
-	suite.azdSecProviderMock.On("GetContainersVulnerabilityScanInfo", resource).Return(expectedInfo, nil).Once()
+	suite.azdSecProviderMock.On("GetContainersVulnerabilityScanInfo", mock.Anything, resource).Return(expectedInfo, nil).Once()
 
-	handler := NewHandler(suite.azdSecProviderMock, &HandlerConfiguration{DryRun: false, SupportedKubernetesWorkloadResources: []string{"Pod", "Deployment",
-		"ReplicaSet", "StatefulSet", "DaemonSet", "Job", "CronJob", "ReplicationController"}}, instrumentation.NewNoOpInstrumentationProvider(), suite.extractor)
+	handler := NewHandler(suite.azdSecProviderMock, &HandlerConfiguration{DryRun: false}, instrumentation.NewNoOpInstrumentationProvider(), suite.extractor, decisionlog.NewNoOpDecisionLogger(), events.NewNoOpWorkloadEventEmitter(), vulnerabilityreport.NewNoOpVulnerabilityReportWriter(), suite.ownerScanInfoVerifier)
 
 	// Act
 	resp := handler.Handle(context.Background(), *req)
 	// Test
 	suite.Equal(admission.Allowed(string(_patchedReason)).AdmissionResponse, resp.AdmissionResponse)
-	suite.Equal(1, len(resp.Patches))
-	patch := resp.Patches[0]
+	suite.Equal(2, len(resp.Patches))
+	suite.Equal(jsonpatch.NewOperation(_expectedTestAddPatchOperation, _expectedTestAnnotationPatchPath, map[string]string{}), resp.Patches[0])
+	patch := resp.Patches[1]
 	suite.checkPatch(expectedInfo, patch)
 	suite.azdSecProviderMock.AssertExpectations(suite.T())
 }
@@ -347,10 +383,9 @@ func (suite *TestSuite) Test_Handle_Error_AllowedTrueWithError_PodNoAnnotations(
 
 	err := errors.New("MockError!!")
 
-	suite.azdSecProviderMock.On("GetContainersVulnerabilityScanInfo", resource).Return(nil, err).Once()
+	suite.azdSecProviderMock.On("GetContainersVulnerabilityScanInfo", mock.Anything, resource).Return(nil, err).Once()
 
-	handler := NewHandler(suite.azdSecProviderMock, &HandlerConfiguration{DryRun: false, SupportedKubernetesWorkloadResources: []string{"Pod", "Deployment",
-		"ReplicaSet", "StatefulSet", "DaemonSet", "Job", "CronJob", "ReplicationController"}}, instrumentation.NewNoOpInstrumentationProvider(), suite.extractor)
+	handler := NewHandler(suite.azdSecProviderMock, &HandlerConfiguration{DryRun: false}, instrumentation.NewNoOpInstrumentationProvider(), suite.extractor, decisionlog.NewNoOpDecisionLogger(), events.NewNoOpWorkloadEventEmitter(), vulnerabilityreport.NewNoOpVulnerabilityReportWriter(), suite.ownerScanInfoVerifier)
 
 	// Act
 	resp := handler.Handle(context.Background(), *req)
@@ -374,10 +409,9 @@ func (suite *TestSuite) Test_Handle_Error_AllowedTrueWithError_PodWithAnnotation
 
 	err := errors.New("MockError!!")
 
-	suite.azdSecProviderMock.On("GetContainersVulnerabilityScanInfo", resource).Return(nil, err).Once()
+	suite.azdSecProviderMock.On("GetContainersVulnerabilityScanInfo", mock.Anything, resource).Return(nil, err).Once()
 
-	handler := NewHandler(suite.azdSecProviderMock, &HandlerConfiguration{DryRun: false, SupportedKubernetesWorkloadResources: []string{"Pod", "Deployment",
-		"ReplicaSet", "StatefulSet", "DaemonSet", "Job", "CronJob", "ReplicationController"}}, instrumentation.NewNoOpInstrumentationProvider(), suite.extractor)
+	handler := NewHandler(suite.azdSecProviderMock, &HandlerConfiguration{DryRun: false}, instrumentation.NewNoOpInstrumentationProvider(), suite.extractor, decisionlog.NewNoOpDecisionLogger(), events.NewNoOpWorkloadEventEmitter(), vulnerabilityreport.NewNoOpVulnerabilityReportWriter(), suite.ownerScanInfoVerifier)
 
 	// Act
 	resp := handler.Handle(context.Background(), *req)
@@ -401,10 +435,9 @@ func (suite *TestSuite) Test_Handle_Error_AllowedTrueWithError_PodWithAzdAnnotat
 
 	err := errors.New("MockError!!")
 
-	suite.azdSecProviderMock.On("GetContainersVulnerabilityScanInfo", resource).Return(nil, err).Once()
+	suite.azdSecProviderMock.On("GetContainersVulnerabilityScanInfo", mock.Anything, resource).Return(nil, err).Once()
 
-	handler := NewHandler(suite.azdSecProviderMock, &HandlerConfiguration{DryRun: false, SupportedKubernetesWorkloadResources: []string{"Pod", "Deployment",
-		"ReplicaSet", "StatefulSet", "DaemonSet", "Job", "CronJob", "ReplicationController"}}, instrumentation.NewNoOpInstrumentationProvider(), suite.extractor)
+	handler := NewHandler(suite.azdSecProviderMock, &HandlerConfiguration{DryRun: false}, instrumentation.NewNoOpInstrumentationProvider(), suite.extractor, decisionlog.NewNoOpDecisionLogger(), events.NewNoOpWorkloadEventEmitter(), vulnerabilityreport.NewNoOpVulnerabilityReportWriter(), suite.ownerScanInfoVerifier)
 
 	// Act
 	resp := handler.Handle(context.Background(), *req)
@@ -413,7 +446,7 @@ func (suite *TestSuite) Test_Handle_Error_AllowedTrueWithError_PodWithAzdAnnotat
 	suite.Equal(int32(http.StatusInternalServerError), resp.Result.Code)
 	suite.Equal(1, len(resp.Patches))
 	patch := resp.Patches[0]
-	suite.Equal(_expectedPatchForErrorEncountered1, patch)
+	suite.Equal(_expectedPatchForErrorEncountered, patch)
 	suite.azdSecProviderMock.AssertExpectations(suite.T())
 }
 
@@ -425,10 +458,9 @@ func (suite *TestSuite) Test_Handle_Error_AllowedTrueWithError_PodWithOnlyAzdAnn
 
 	err := errors.New("MockError!!")
 
-	suite.azdSecProviderMock.On("GetContainersVulnerabilityScanInfo", resource).Return(nil, err).Once()
+	suite.azdSecProviderMock.On("GetContainersVulnerabilityScanInfo", mock.Anything, resource).Return(nil, err).Once()
 
-	handler := NewHandler(suite.azdSecProviderMock, &HandlerConfiguration{DryRun: false, SupportedKubernetesWorkloadResources: []string{"Pod", "Deployment",
-		"ReplicaSet", "StatefulSet", "DaemonSet", "Job", "CronJob", "ReplicationController"}}, instrumentation.NewNoOpInstrumentationProvider(), suite.extractor)
+	handler := NewHandler(suite.azdSecProviderMock, &HandlerConfiguration{DryRun: false}, instrumentation.NewNoOpInstrumentationProvider(), suite.extractor, decisionlog.NewNoOpDecisionLogger(), events.NewNoOpWorkloadEventEmitter(), vulnerabilityreport.NewNoOpVulnerabilityReportWriter(), suite.ownerScanInfoVerifier)
 
 	// Act
 	resp := handler.Handle(context.Background(), *req)
@@ -437,21 +469,371 @@ func (suite *TestSuite) Test_Handle_Error_AllowedTrueWithError_PodWithOnlyAzdAnn
 	suite.Equal(int32(http.StatusInternalServerError), resp.Result.Code)
 	suite.Equal(1, len(resp.Patches))
 	patch := resp.Patches[0]
-	suite.Equal(_expectedPatchForErrorEncountered2, patch)
+	suite.Equal(_expectedPatchForErrorEncountered, patch)
+	suite.azdSecProviderMock.AssertExpectations(suite.T())
+}
+
+func (suite *TestSuite) Test_Handle_DecisionLogger_ShouldLogDecisionRecord() {
+	// Setup
+	pod := createPodForTests([]corev1.Container{_containers[0]}, nil)
+	resource := createWorkloadResourceForTests([]*admisionrequest.Container{_containersAdmision[0]}, nil)
+	req := createRequestForTests(pod)
+	req.UID = "uidTest"
+	info := &contracts.ContainerVulnerabilityScanInfo{
+		Name:           _containers[0].Name,
+		Image:          &contracts.Image{Name: _containers[0].Image, Digest: "sha256:digest"},
+		ScanStatus:     contracts.Unscanned,
+		AdditionalData: map[string]string{contracts.UnscannedReasonAnnotationKey: "unscannedReason"},
+	}
+	suite.azdSecProviderMock.On("GetContainersVulnerabilityScanInfo", mock.Anything, resource).Return([]*contracts.ContainerVulnerabilityScanInfo{info}, nil).Once()
+
+	decisionLoggerMock := &decisionlogMocks.IDecisionLogger{}
+	decisionLoggerMock.On("Log", mock.MatchedBy(func(record *decisionlog.DecisionRecord) bool {
+		return record.RequestUID == "uidTest" &&
+			record.Namespace == "default" &&
+			record.Kind == "Pod" &&
+			record.Name == "podTest" &&
+			record.Operation == string(admissionv1.Create) &&
+			record.Allowed &&
+			record.ResponseReason == string(_patchedReason) &&
+			record.Error == "" &&
+			reflect.DeepEqual([]*decisionlog.ContainerDecision{{Name: _containers[0].Name, Image: _containers[0].Image, Digest: "sha256:digest", ScanStatus: string(contracts.Unscanned), UnscannedReason: "unscannedReason"}}, record.Containers)
+	})).Once()
+
+	handler := NewHandler(suite.azdSecProviderMock, &HandlerConfiguration{DryRun: false}, instrumentation.NewNoOpInstrumentationProvider(), suite.extractor, decisionLoggerMock, events.NewNoOpWorkloadEventEmitter(), vulnerabilityreport.NewNoOpVulnerabilityReportWriter(), suite.ownerScanInfoVerifier)
+
+	// Act
+	resp := handler.Handle(context.Background(), *req)
+
+	// Test
+	suite.Equal(admission.Allowed(string(_patchedReason)).AdmissionResponse, resp.AdmissionResponse)
+	suite.azdSecProviderMock.AssertExpectations(suite.T())
+	decisionLoggerMock.AssertExpectations(suite.T())
+}
+
+func (suite *TestSuite) Test_Handle_AdmissionTimeoutConfigured_ProviderGetsDeadlineWithSafetyMargin() {
+	// Setup
+	pod := createPodForTests([]corev1.Container{_containers[0]}, nil)
+	resource := createWorkloadResourceForTests([]*admisionrequest.Container{_containersAdmision[0]}, nil)
+	req := createRequestForTests(pod)
+	start := time.Now()
+	suite.azdSecProviderMock.On("GetContainersVulnerabilityScanInfo", mock.MatchedBy(func(ctx context.Context) bool {
+		deadline, ok := ctx.Deadline()
+		return ok && !deadline.Before(start.Add(2900*time.Millisecond)) && deadline.Before(time.Now().Add(2900*time.Millisecond))
+	}), resource).Return([]*contracts.ContainerVulnerabilityScanInfo{}, nil).Once()
+
+	handler := NewHandler(suite.azdSecProviderMock, &HandlerConfiguration{DryRun: false, AdmissionTimeoutInSeconds: 3, DeadlineSafetyMarginInMS: 100},
+		instrumentation.NewNoOpInstrumentationProvider(), suite.extractor, decisionlog.NewNoOpDecisionLogger(), events.NewNoOpWorkloadEventEmitter(), vulnerabilityreport.NewNoOpVulnerabilityReportWriter(), suite.ownerScanInfoVerifier)
+
+	// Act
+	handler.Handle(context.Background(), *req)
+
+	// Test
+	suite.azdSecProviderMock.AssertExpectations(suite.T())
+}
+
+func (suite *TestSuite) Test_Handle_AdmissionTimeoutNotConfigured_ProviderGetsNoDeadline() {
+	// Setup
+	pod := createPodForTests([]corev1.Container{_containers[0]}, nil)
+	resource := createWorkloadResourceForTests([]*admisionrequest.Container{_containersAdmision[0]}, nil)
+	req := createRequestForTests(pod)
+	suite.azdSecProviderMock.On("GetContainersVulnerabilityScanInfo", mock.MatchedBy(func(ctx context.Context) bool {
+		_, ok := ctx.Deadline()
+		return !ok
+	}), resource).Return([]*contracts.ContainerVulnerabilityScanInfo{}, nil).Once()
+
+	handler := NewHandler(suite.azdSecProviderMock, &HandlerConfiguration{DryRun: false},
+		instrumentation.NewNoOpInstrumentationProvider(), suite.extractor, decisionlog.NewNoOpDecisionLogger(), events.NewNoOpWorkloadEventEmitter(), vulnerabilityreport.NewNoOpVulnerabilityReportWriter(), suite.ownerScanInfoVerifier)
+
+	// Act
+	handler.Handle(context.Background(), *req)
+
+	// Test
+	suite.azdSecProviderMock.AssertExpectations(suite.T())
+}
+
+func (suite *TestSuite) Test_Handle_EventEmitter_ShouldEmitEvent() {
+	// Setup
+	pod := createPodForTests([]corev1.Container{_containers[0]}, nil)
+	resource := createWorkloadResourceForTests([]*admisionrequest.Container{_containersAdmision[0]}, nil)
+	req := createRequestForTests(pod)
+	expectedInfo := []*contracts.ContainerVulnerabilityScanInfo{_firstContainerVulnerabilityScanInfo}
+	suite.azdSecProviderMock.On("GetContainersVulnerabilityScanInfo", mock.Anything, resource).Return(expectedInfo, nil).Once()
+	eventEmitterMock := &eventsMocks.IWorkloadEventEmitter{}
+	eventEmitterMock.On("EmitContainersVulnerabilityScanEvent", mock.Anything, "default", req.Kind, resource, expectedInfo).Once()
+
+	handler := NewHandler(suite.azdSecProviderMock, &HandlerConfiguration{DryRun: false}, instrumentation.NewNoOpInstrumentationProvider(), suite.extractor, decisionlog.NewNoOpDecisionLogger(), eventEmitterMock, vulnerabilityreport.NewNoOpVulnerabilityReportWriter(), suite.ownerScanInfoVerifier)
+
+	// Act
+	resp := handler.Handle(context.Background(), *req)
+
+	// Test
+	suite.Equal(admission.Allowed(string(_patchedReason)).AdmissionResponse, resp.AdmissionResponse)
+	suite.azdSecProviderMock.AssertExpectations(suite.T())
+	eventEmitterMock.AssertExpectations(suite.T())
+}
+
+func (suite *TestSuite) Test_Handle_DryRunRequest_ShouldNotEmitEvent() {
+	// Setup
+	pod := createPodForTests([]corev1.Container{_containers[0]}, nil)
+	resource := createWorkloadResourceForTests([]*admisionrequest.Container{_containersAdmision[0]}, nil)
+	req := createRequestForTests(pod)
+	dryRun := true
+	req.DryRun = &dryRun
+	expectedInfo := []*contracts.ContainerVulnerabilityScanInfo{_firstContainerVulnerabilityScanInfo}
+	suite.azdSecProviderMock.On("GetContainersVulnerabilityScanInfo", mock.Anything, resource).Return(expectedInfo, nil).Once()
+	eventEmitterMock := &eventsMocks.IWorkloadEventEmitter{}
+
+	handler := NewHandler(suite.azdSecProviderMock, &HandlerConfiguration{DryRun: false}, instrumentation.NewNoOpInstrumentationProvider(), suite.extractor, decisionlog.NewNoOpDecisionLogger(), eventEmitterMock, vulnerabilityreport.NewNoOpVulnerabilityReportWriter(), suite.ownerScanInfoVerifier)
+
+	// Act
+	resp := handler.Handle(context.Background(), *req)
+
+	// Test
+	suite.Equal(admission.Allowed(string(_patchedReason)).AdmissionResponse, resp.AdmissionResponse)
+	suite.azdSecProviderMock.AssertExpectations(suite.T())
+	eventEmitterMock.AssertNotCalled(suite.T(), "EmitContainersVulnerabilityScanEvent", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func (suite *TestSuite) Test_Handle_ReportWriter_ShouldWriteReportAndPointToIt() {
+	// Setup
+	pod := createPodForTests([]corev1.Container{_containers[0]}, nil)
+	resource := createWorkloadResourceForTests([]*admisionrequest.Container{_containersAdmision[0]}, nil)
+	req := createRequestForTests(pod)
+	expectedInfo := []*contracts.ContainerVulnerabilityScanInfo{_firstContainerVulnerabilityScanInfo}
+	suite.azdSecProviderMock.On("GetContainersVulnerabilityScanInfo", mock.Anything, resource).Return(expectedInfo, nil).Once()
+	reportWriterMock := &vulnerabilityreportMocks.IVulnerabilityReportWriter{}
+	reportWriterMock.On("WriteReport", mock.Anything, "default", req.Kind, resource, expectedInfo).Return("pod-podtest").Once()
+
+	handler := NewHandler(suite.azdSecProviderMock, &HandlerConfiguration{DryRun: false}, instrumentation.NewNoOpInstrumentationProvider(), suite.extractor, decisionlog.NewNoOpDecisionLogger(), events.NewNoOpWorkloadEventEmitter(), reportWriterMock, suite.ownerScanInfoVerifier)
+
+	// Act
+	resp := handler.Handle(context.Background(), *req)
+
+	// Test
+	suite.Equal(admission.Allowed(string(_patchedReason)).AdmissionResponse, resp.AdmissionResponse)
+	suite.Equal(2, len(resp.Patches))
+	strValue, ok := resp.Patches[1].Value.(string)
+	suite.True(ok)
+	scanInfoList := new(contracts.ContainerVulnerabilityScanInfoList)
+	suite.Nil(json.Unmarshal([]byte(strValue), scanInfoList))
+	suite.Equal("pod-podtest", scanInfoList.VulnerabilityReport)
+	suite.azdSecProviderMock.AssertExpectations(suite.T())
+	reportWriterMock.AssertExpectations(suite.T())
+}
+
+func (suite *TestSuite) Test_Handle_DryRunRequest_ShouldNotWriteReport() {
+	// Setup
+	pod := createPodForTests([]corev1.Container{_containers[0]}, nil)
+	resource := createWorkloadResourceForTests([]*admisionrequest.Container{_containersAdmision[0]}, nil)
+	req := createRequestForTests(pod)
+	dryRun := true
+	req.DryRun = &dryRun
+	expectedInfo := []*contracts.ContainerVulnerabilityScanInfo{_firstContainerVulnerabilityScanInfo}
+	suite.azdSecProviderMock.On("GetContainersVulnerabilityScanInfo", mock.Anything, resource).Return(expectedInfo, nil).Once()
+	reportWriterMock := &vulnerabilityreportMocks.IVulnerabilityReportWriter{}
+
+	handler := NewHandler(suite.azdSecProviderMock, &HandlerConfiguration{DryRun: false}, instrumentation.NewNoOpInstrumentationProvider(), suite.extractor, decisionlog.NewNoOpDecisionLogger(), events.NewNoOpWorkloadEventEmitter(), reportWriterMock, suite.ownerScanInfoVerifier)
+
+	// Act
+	resp := handler.Handle(context.Background(), *req)
+
+	// Test
+	suite.Equal(admission.Allowed(string(_patchedReason)).AdmissionResponse, resp.AdmissionResponse)
+	reportWriterMock.AssertNotCalled(suite.T(), "WriteReport", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func (suite *TestSuite) Test_Handle_ScanStatusLabelsEnabled_ShouldPatchLabels() {
+	// Setup
+	pod := createPodForTests([]corev1.Container{_containers[0]}, nil)
+	resource := createWorkloadResourceForTests([]*admisionrequest.Container{_containersAdmision[0]}, nil)
+	req := createRequestForTests(pod)
+	expectedInfo := []*contracts.ContainerVulnerabilityScanInfo{{Name: "containerTest1", ScanStatus: contracts.UnhealthyScan, ScanFindings: []*contracts.ScanFinding{{Id: "1", Severity: "Medium"}}}}
+	suite.azdSecProviderMock.On("GetContainersVulnerabilityScanInfo", mock.Anything, resource).Return(expectedInfo, nil).Once()
+
+	handler := NewHandler(suite.azdSecProviderMock, &HandlerConfiguration{DryRun: false, ScanStatusLabelsConfiguration: labels.ScanStatusLabelsConfiguration{Enabled: true}},
+		instrumentation.NewNoOpInstrumentationProvider(), suite.extractor, decisionlog.NewNoOpDecisionLogger(), events.NewNoOpWorkloadEventEmitter(), vulnerabilityreport.NewNoOpVulnerabilityReportWriter(), suite.ownerScanInfoVerifier)
+
+	// Act
+	resp := handler.Handle(context.Background(), *req)
+
+	// Test
+	suite.Equal(admission.Allowed(string(_patchedReason)).AdmissionResponse, resp.AdmissionResponse)
+	suite.Equal(6, len(resp.Patches))
+	suite.Equal([]jsonpatch.JsonPatchOperation{
+		jsonpatch.NewOperation("add", "/metadata/labels", map[string]string{}),
+		jsonpatch.NewOperation("add", "/metadata/labels/azuredefender.io~1scan-status", "unhealthy"),
+		jsonpatch.NewOperation("add", "/metadata/labels/azuredefender.io~1max-severity", "medium"),
+		jsonpatch.NewOperation("add", "/metadata/labels/azuredefender.io~1all-images-from-acr", "false"),
+	}, resp.Patches[2:])
+	suite.azdSecProviderMock.AssertExpectations(suite.T())
+}
+
+func (suite *TestSuite) Test_Handle_Error_PodWithOnlyScanStatusLabels_ShouldDeleteLabels() {
+	// Setup
+	pod := createPodForTests(nil, []corev1.Container{_containers[0]})
+	pod.Labels = map[string]string{labels.ScanStatusLabelName: "unhealthy", "app": "test"}
+	req := createRequestForTests(pod)
+	suite.azdSecProviderMock.On("GetContainersVulnerabilityScanInfo", mock.Anything, mock.Anything).Return(nil, errors.New("MockError!!")).Once()
+
+	handler := NewHandler(suite.azdSecProviderMock, &HandlerConfiguration{DryRun: false, ScanStatusLabelsConfiguration: labels.ScanStatusLabelsConfiguration{Enabled: true}},
+		instrumentation.NewNoOpInstrumentationProvider(), suite.extractor, decisionlog.NewNoOpDecisionLogger(), events.NewNoOpWorkloadEventEmitter(), vulnerabilityreport.NewNoOpVulnerabilityReportWriter(), suite.ownerScanInfoVerifier)
+
+	// Act
+	resp := handler.Handle(context.Background(), *req)
+
+	// Test
+	suite.Equal(int32(http.StatusInternalServerError), resp.Result.Code)
+	suite.True(resp.Allowed)
+	suite.Equal([]jsonpatch.JsonPatchOperation{jsonpatch.NewOperation("remove", "/metadata/labels/azuredefender.io~1scan-status", nil)}, resp.Patches)
+	suite.azdSecProviderMock.AssertExpectations(suite.T())
+}
+
+func (suite *TestSuite) Test_Handle_PodWithFreshInheritedScanInfo_ShouldReuseScanInfo() {
+	// Setup
+	info := &contracts.ContainerVulnerabilityScanInfo{Name: _containers[0].Name, Image: &contracts.Image{Name: _containers[0].Image, Digest: "sha256:digest"}, ScanStatus: contracts.HealthyScan}
+	serScanInfoList, err := json.Marshal(&contracts.ContainerVulnerabilityScanInfoList{GeneratedTimestamp: time.Now().UTC(), Containers: []*contracts.ContainerVulnerabilityScanInfo{info}})
+	suite.Require().Nil(err)
+	pod := createPodForTests([]corev1.Container{_containers[0]}, nil)
+	pod.Annotations = map[string]string{contracts.ContainersVulnerabilityScanInfoAnnotationName: string(serScanInfoList)}
+	pod.OwnerReferences = createControllerOwnerReferencesForTests()
+	req := createRequestForTests(pod)
+	reportWriterMock := &vulnerabilityreportMocks.IVulnerabilityReportWriter{}
+	suite.ownerScanInfoVerifier = createOwnerScanInfoVerifierForTests(string(serScanInfoList))
+
+	handler := NewHandler(suite.azdSecProviderMock, &HandlerConfiguration{DryRun: false, PodTemplateScanInfoConfiguration: annotations.PodTemplateScanInfoConfiguration{Enabled: true, MaxAgeInSeconds: 60}},
+		instrumentation.NewNoOpInstrumentationProvider(), suite.extractor, decisionlog.NewNoOpDecisionLogger(), events.NewNoOpWorkloadEventEmitter(), reportWriterMock, suite.ownerScanInfoVerifier)
+
+	// Act
+	resp := handler.Handle(context.Background(), *req)
+
+	// Test
+	suite.True(resp.Allowed)
+	suite.Equal(metav1.StatusReason(_reusedOwnerTemplateScanInfoReason), resp.Result.Reason)
+	suite.Empty(resp.Patches)
+	suite.azdSecProviderMock.AssertNotCalled(suite.T(), "GetContainersVulnerabilityScanInfo", mock.Anything, mock.Anything)
+	reportWriterMock.AssertNotCalled(suite.T(), "WriteReport", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func (suite *TestSuite) Test_Handle_PodWithForgedInheritedScanInfo_ShouldEvaluate() {
+	// Setup
+	info := &contracts.ContainerVulnerabilityScanInfo{Name: _containers[0].Name, Image: &contracts.Image{Name: _containers[0].Image, Digest: "sha256:digest"}, ScanStatus: contracts.HealthyScan}
+	serScanInfoList, err := json.Marshal(&contracts.ContainerVulnerabilityScanInfoList{GeneratedTimestamp: time.Now().UTC(), Containers: []*contracts.ContainerVulnerabilityScanInfo{info}})
+	suite.Require().Nil(err)
+	pod := createPodForTests([]corev1.Container{_containers[0]}, nil)
+	// The pod claims healthy scan info and an existing owner, but the pod template of the owner has other scan info
+	pod.Annotations = map[string]string{contracts.ContainersVulnerabilityScanInfoAnnotationName: string(serScanInfoList)}
+	pod.OwnerReferences = createControllerOwnerReferencesForTests()
+	req := createRequestForTests(pod)
+	unhealthyInfo := &contracts.ContainerVulnerabilityScanInfo{Name: _containers[0].Name, Image: &contracts.Image{Name: _containers[0].Image, Digest: "sha256:digest"}, ScanStatus: contracts.UnhealthyScan}
+	suite.azdSecProviderMock.On("GetContainersVulnerabilityScanInfo", mock.Anything, mock.Anything).Return([]*contracts.ContainerVulnerabilityScanInfo{unhealthyInfo}, nil).Once()
+	suite.ownerScanInfoVerifier = createOwnerScanInfoVerifierForTests(`{"generatedTimestamp":"2021-01-01T00:00:00Z","containers":[]}`)
+
+	handler := NewHandler(suite.azdSecProviderMock, &HandlerConfiguration{DryRun: false, PodTemplateScanInfoConfiguration: annotations.PodTemplateScanInfoConfiguration{Enabled: true, MaxAgeInSeconds: 60}},
+		instrumentation.NewNoOpInstrumentationProvider(), suite.extractor, decisionlog.NewNoOpDecisionLogger(), events.NewNoOpWorkloadEventEmitter(), vulnerabilityreport.NewNoOpVulnerabilityReportWriter(), suite.ownerScanInfoVerifier)
+
+	// Act
+	resp := handler.Handle(context.Background(), *req)
+
+	// Test
+	suite.Equal(metav1.StatusReason(_patchedReason), resp.Result.Reason)
+	suite.Equal(1, len(resp.Patches))
+	suite.checkPatch([]*contracts.ContainerVulnerabilityScanInfo{unhealthyInfo}, resp.Patches[0])
+	suite.azdSecProviderMock.AssertExpectations(suite.T())
+}
+
+func (suite *TestSuite) Test_Handle_PodWithStaleInheritedScanInfo_ShouldEvaluate() {
+	// Setup
+	info := &contracts.ContainerVulnerabilityScanInfo{Name: _containers[0].Name, Image: &contracts.Image{Name: _containers[0].Image, Digest: "sha256:digest"}, ScanStatus: contracts.HealthyScan}
+	serScanInfoList, err := json.Marshal(&contracts.ContainerVulnerabilityScanInfoList{GeneratedTimestamp: time.Now().UTC().Add(-time.Hour), Containers: []*contracts.ContainerVulnerabilityScanInfo{info}})
+	suite.Require().Nil(err)
+	pod := createPodForTests([]corev1.Container{_containers[0]}, nil)
+	pod.Annotations = map[string]string{contracts.ContainersVulnerabilityScanInfoAnnotationName: string(serScanInfoList)}
+	pod.OwnerReferences = createControllerOwnerReferencesForTests()
+	req := createRequestForTests(pod)
+	suite.azdSecProviderMock.On("GetContainersVulnerabilityScanInfo", mock.Anything, mock.Anything).Return([]*contracts.ContainerVulnerabilityScanInfo{info}, nil).Once()
+
+	handler := NewHandler(suite.azdSecProviderMock, &HandlerConfiguration{DryRun: false, PodTemplateScanInfoConfiguration: annotations.PodTemplateScanInfoConfiguration{Enabled: true, MaxAgeInSeconds: 60}},
+		instrumentation.NewNoOpInstrumentationProvider(), suite.extractor, decisionlog.NewNoOpDecisionLogger(), events.NewNoOpWorkloadEventEmitter(), vulnerabilityreport.NewNoOpVulnerabilityReportWriter(), suite.ownerScanInfoVerifier)
+
+	// Act
+	resp := handler.Handle(context.Background(), *req)
+
+	// Test
+	suite.Equal(metav1.StatusReason(_patchedReason), resp.Result.Reason)
+	suite.Equal(1, len(resp.Patches))
+	suite.azdSecProviderMock.AssertExpectations(suite.T())
+}
+
+func (suite *TestSuite) Test_Handle_DeploymentPodTemplateScanInfoEnabled_ShouldPatchPodTemplate() {
+	// Setup
+	deployment := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "deploymentTest", Namespace: "default"},
+		Spec: appsv1.DeploymentSpec{Template: corev1.PodTemplateSpec{
+			ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"app": "test"}},
+			Spec:       corev1.PodSpec{Containers: []corev1.Container{_containers[0]}},
+		}},
+	}
+	raw, err := json.Marshal(deployment)
+	suite.Require().Nil(err)
+	req := createRequestForTests(createPodForTests(nil, nil))
+	req.Kind = metav1.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"}
+	req.Object.Raw = raw
+	suite.azdSecProviderMock.On("GetContainersVulnerabilityScanInfo", mock.Anything, mock.Anything).Return([]*contracts.ContainerVulnerabilityScanInfo{_firstContainerVulnerabilityScanInfo}, nil).Once()
+
+	handler := NewHandler(suite.azdSecProviderMock, &HandlerConfiguration{DryRun: false, PodTemplateScanInfoConfiguration: annotations.PodTemplateScanInfoConfiguration{Enabled: true, MaxAgeInSeconds: 60}},
+		instrumentation.NewNoOpInstrumentationProvider(), suite.extractor, decisionlog.NewNoOpDecisionLogger(), events.NewNoOpWorkloadEventEmitter(), vulnerabilityreport.NewNoOpVulnerabilityReportWriter(), suite.ownerScanInfoVerifier)
+
+	// Act
+	resp := handler.Handle(context.Background(), *req)
+
+	// Test
+	suite.Equal(metav1.StatusReason(_patchedReason), resp.Result.Reason)
+	suite.Equal(4, len(resp.Patches))
+	suite.Equal(jsonpatch.NewOperation(_expectedTestAddPatchOperation, "/spec/template/metadata/annotations", map[string]string{}), resp.Patches[2])
+	suite.Equal("/spec/template/metadata/annotations/azuredefender.io~1containers.vulnerability.scan.info", resp.Patches[3].Path)
+	suite.azdSecProviderMock.AssertExpectations(suite.T())
+}
+
+func (suite *TestSuite) Test_Handle_JobUpdateScanStatusLabelsPodTemplateEnabled_ShouldNotPatchPodTemplateLabels() {
+	// Setup
+	job := &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{Name: "jobTest", Namespace: "default"},
+		Spec: batchv1.JobSpec{Template: corev1.PodTemplateSpec{
+			ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"app": "test"}},
+			Spec:       corev1.PodSpec{Containers: []corev1.Container{_containers[0]}},
+		}},
+	}
+	raw, err := json.Marshal(job)
+	suite.Require().Nil(err)
+	req := createRequestForTests(createPodForTests(nil, nil))
+	req.Kind = metav1.GroupVersionKind{Group: "batch", Version: "v1", Kind: "Job"}
+	req.Operation = admissionv1.Update
+	req.Object.Raw = raw
+	suite.azdSecProviderMock.On("GetContainersVulnerabilityScanInfo", mock.Anything, mock.Anything).Return([]*contracts.ContainerVulnerabilityScanInfo{_firstContainerVulnerabilityScanInfo}, nil).Once()
+
+	handler := NewHandler(suite.azdSecProviderMock, &HandlerConfiguration{DryRun: false, ScanStatusLabelsConfiguration: labels.ScanStatusLabelsConfiguration{Enabled: true, PodTemplateEnabled: true}},
+		instrumentation.NewNoOpInstrumentationProvider(), suite.extractor, decisionlog.NewNoOpDecisionLogger(), events.NewNoOpWorkloadEventEmitter(), vulnerabilityreport.NewNoOpVulnerabilityReportWriter(), suite.ownerScanInfoVerifier)
+
+	// Act
+	resp := handler.Handle(context.Background(), *req)
+
+	// Test
+	suite.Equal(metav1.StatusReason(_patchedReason), resp.Result.Reason)
+	suite.NotEmpty(resp.Patches)
+	for _, patch := range resp.Patches {
+		suite.False(strings.HasPrefix(patch.Path, "/spec/template"), patch.Path)
+	}
 	suite.azdSecProviderMock.AssertExpectations(suite.T())
 }
 
 func (suite *TestSuite) checkPatch(expected []*contracts.ContainerVulnerabilityScanInfo, patch jsonpatch.JsonPatchOperation) {
 	// Verify the operation and the patch
 	suite.Equal(_expectedTestAddPatchOperation, patch.Operation)
-	suite.Equal(_expectedTestAnnotationPatchPath, patch.Path)
+	suite.Equal(_expectedTestScanInfoPatchPath, patch.Path)
 
 	// Get data string
-	mapAnnotations, ok := patch.Value.(map[string]string)
-	suite.True(ok)
-
-	suite.Equal(1, len(mapAnnotations))
-	strValue, ok := mapAnnotations[contracts.ContainersVulnerabilityScanInfoAnnotationName]
+	strValue, ok := patch.Value.(string)
 	suite.True(ok)
 
 	// Unmarshal
@@ -472,6 +854,24 @@ func TestCreateContainersVulnerabilityScanAnnotationPatchAdd(t *testing.T) {
 	suite.Run(t, new(TestSuite))
 }
 
+// createControllerOwnerReferencesForTests returns the owner references of a pod that is created by the replica set of createOwnerScanInfoVerifierForTests
+func createControllerOwnerReferencesForTests() []metav1.OwnerReference {
+	controller := true
+	return []metav1.OwnerReference{{APIVersion: "apps/v1", Kind: "ReplicaSet", Name: "replicaSetTest", UID: "replicaSetUID", Controller: &controller}}
+}
+
+// createOwnerScanInfoVerifierForTests returns owner scan info verifier of a fake client with a replica set that its pod template has the scan info
+func createOwnerScanInfoVerifierForTests(scanInfo string) annotations.IOwnerPodTemplateScanInfoVerifier {
+	replicaSet := &appsv1.ReplicaSet{
+		ObjectMeta: metav1.ObjectMeta{Name: "replicaSetTest", Namespace: "default", UID: "replicaSetUID"},
+		Spec: appsv1.ReplicaSetSpec{Template: corev1.PodTemplateSpec{
+			ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{contracts.ContainersVulnerabilityScanInfoAnnotationName: scanInfo}},
+		}},
+	}
+	client := fake.NewClientBuilder().WithScheme(clientgoscheme.Scheme).WithObjects(replicaSet).Build()
+	return annotations.NewOwnerPodTemplateScanInfoVerifier(instrumentation.NewNoOpInstrumentationProvider(), client)
+}
+
 func createRequestForTests(pod *corev1.Pod) *admission.Request {
 	raw, err := json.Marshal(pod)
 	if err != nil {
@@ -0,0 +1,220 @@
+// Package labels contains the scan status labels that the webhook sets on the workload resources in addition to the
+// scan info annotations. Unlike annotations, labels can be used in label selectors (e.g. kubectl get pods -l, NetworkPolicies).
+package labels
+
+import (
+	"github.com/Azure/AzureDefender-K8S-InClusterDefense/cmd/webhook/admisionrequest"
+	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/azdsecinfo/contracts"
+	registryutils "github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/registry/utils"
+	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/utils"
+	"github.com/pkg/errors"
+	"gomodules.xyz/jsonpatch/v2"
+	"strconv"
+	"strings"
+)
+
+const (
+	// ScanStatusLabelName is the label of the worst scan status of the containers - unhealthy, unscanned or healthy
+	ScanStatusLabelName = contracts.AzdSecInfoAnnotationPrefix + "/scan-status"
+	// MaxSeverityLabelName is the label of the most severe finding of the containers - high, medium, low or none
+	MaxSeverityLabelName = contracts.AzdSecInfoAnnotationPrefix + "/max-severity"
+	// AllImagesFromACRLabelName is the label of whether the images of all the containers are from ACR - true or false
+	AllImagesFromACRLabelName = contracts.AzdSecInfoAnnotationPrefix + "/all-images-from-acr"
+
+	// _addPatchOperation operation type in json patch to add labels
+	_addPatchOperation = "add"
+	// _removePatchOperation operation type in json patch to remove labels
+	_removePatchOperation = "remove"
+	// _metadataField is the metadata field of the object and of the pod template
+	_metadataField = "metadata"
+	// _labelsField is the labels field of the metadata
+	_labelsField = "labels"
+
+	// _unhealthyLabelValue is the value of ScanStatusLabelName in case that any container is unhealthy
+	_unhealthyLabelValue = "unhealthy"
+	// _unscannedLabelValue is the value of ScanStatusLabelName in case that any container is unscanned (and none is unhealthy)
+	_unscannedLabelValue = "unscanned"
+	// _healthyLabelValue is the value of ScanStatusLabelName in case that all the containers are healthy
+	_healthyLabelValue = "healthy"
+	// _noneSeverityLabelValue is the value of MaxSeverityLabelName in case that there are no findings
+	_noneSeverityLabelValue = "none"
+)
+
+var (
+	// _scanStatusLabelNames are all the labels that are set by the webhook
+	_scanStatusLabelNames = []string{ScanStatusLabelName, MaxSeverityLabelName, AllImagesFromACRLabelName}
+
+	// _severityToLevel is the order of the severities of the scan findings
+	_severityToLevel = map[string]int{"Low": 1, "Medium": 2, "High": 3}
+)
+
+// ScanStatusLabelsConfiguration is configuration data for the scan status labels
+type ScanStatusLabelsConfiguration struct {
+	// Enabled is whether the scan status labels should be set on the workload resources
+	Enabled bool
+	// PodTemplateEnabled is whether the scan status labels should be set on the pod template of the workload resources as well.
+	// Notice that a change of the pod template labels of a Deployment (or DaemonSet, StatefulSet) rolls out its pods.
+	PodTemplateEnabled bool
+}
+
+// CreateScanStatusLabelsPatchAdd returns add type json patches that set the scan status labels on the WorkloadResource's labels
+// (and on its pod template's labels if PodTemplateEnabled). The patches are key-level (the keys are escaped according to RFC 6901),
+// so the existing labels aren't overridden, and the labels map is created only if it's missing. Only the labels that differ are patched.
+// The labels are summarized from the containers scan info - the worst scan status, the max severity and whether all the images are from ACR.
+// isPodTemplateImmutable is whether the pod template can't be changed by the request (e.g. UPDATE of a Job).
+func CreateScanStatusLabelsPatchAdd(containersScanInfoList []*contracts.ContainerVulnerabilityScanInfo, workloadResource *admisionrequest.WorkloadResource, configuration *ScanStatusLabelsConfiguration, isPodTemplateImmutable bool) ([]jsonpatch.JsonPatchOperation, error) {
+	if workloadResource == nil || workloadResource.Metadata == nil || configuration == nil {
+		return nil, errors.Wrap(utils.NilArgumentError, "CreateScanStatusLabelsPatchAdd got nil WorkloadResource or configuration")
+	}
+	scanStatusLabels := getScanStatusLabels(containersScanInfoList)
+
+	patches := []jsonpatch.JsonPatchOperation{}
+	// Create the labels map only if it's missing - otherwise the existing labels would be replaced
+	if workloadResource.Metadata.Labels == nil {
+		workloadResource.Metadata.Labels = make(map[string]string, len(scanStatusLabels))
+		patches = append(patches, jsonpatch.NewOperation(_addPatchOperation, utils.JoinJSONPointer(_metadataField, _labelsField), map[string]string{}))
+	}
+	patches = append(patches, setLabels(workloadResource.Metadata.Labels, []string{_metadataField}, scanStatusLabels)...)
+
+	if configuration.PodTemplateEnabled && isPodTemplateLabelsPatchAllowed(workloadResource, isPodTemplateImmutable) {
+		patches = append(patches, createPodTemplateLabelsPatches(workloadResource.PodTemplate, scanStatusLabels)...)
+	}
+	return patches, nil
+}
+
+// CreateLabelsPatchToDeleteScanStatusLabelsIfNeeded create remove patches of the scan status labels (stale labels) that the labels
+// of the WorkloadResource and of its pod template contain. The labels of the pod template are kept in the same cases that they aren't set
+// (see isPodTemplateLabelsPatchAllowed).
+// Otherwise, no deletion is needed - return empty slice.
+func CreateLabelsPatchToDeleteScanStatusLabelsIfNeeded(workloadResource *admisionrequest.WorkloadResource, isPodTemplateImmutable bool) ([]jsonpatch.JsonPatchOperation, error) {
+	if workloadResource == nil || workloadResource.Metadata == nil {
+		return nil, errors.Wrap(utils.NilArgumentError, "CreateLabelsPatchToDeleteScanStatusLabelsIfNeeded got nil WorkloadResource")
+	}
+
+	patches := removeScanStatusLabels(workloadResource.Metadata.Labels, []string{_metadataField})
+	if isPodTemplateLabelsPatchAllowed(workloadResource, isPodTemplateImmutable) {
+		patches = append(patches, removeScanStatusLabels(workloadResource.PodTemplate.Labels, getPodTemplateMetadataPath(workloadResource.PodTemplate))...)
+	}
+	return patches, nil
+}
+
+// getScanStatusLabels returns the scan status labels of the containers
+func getScanStatusLabels(containersScanInfoList []*contracts.ContainerVulnerabilityScanInfo) map[string]string {
+	scanStatus := _healthyLabelValue
+	maxSeverity := ""
+	allImagesFromACR := true
+	for _, container := range containersScanInfoList {
+		if container == nil {
+			continue
+		}
+		switch container.ScanStatus {
+		case contracts.UnhealthyScan:
+			scanStatus = _unhealthyLabelValue
+		case contracts.Unscanned:
+			if scanStatus != _unhealthyLabelValue {
+				scanStatus = _unscannedLabelValue
+			}
+		}
+		for _, scanFinding := range container.ScanFindings {
+			if _severityToLevel[scanFinding.Severity] > _severityToLevel[maxSeverity] {
+				maxSeverity = scanFinding.Severity
+			}
+		}
+		// Summarized scan info (e.g. inherited from an annotation) may have only the counts of the findings
+		for _, scanFindingsCount := range container.ScanFindingsSummary {
+			if scanFindingsCount.Count > 0 && _severityToLevel[scanFindingsCount.Severity] > _severityToLevel[maxSeverity] {
+				maxSeverity = scanFindingsCount.Severity
+			}
+		}
+		allImagesFromACR = allImagesFromACR && isImageFromACR(container.Image)
+	}
+
+	maxSeverityLabelValue := _noneSeverityLabelValue
+	if maxSeverity != "" {
+		maxSeverityLabelValue = strings.ToLower(maxSeverity)
+	}
+	return map[string]string{
+		ScanStatusLabelName:       scanStatus,
+		MaxSeverityLabelName:      maxSeverityLabelValue,
+		AllImagesFromACRLabelName: strconv.FormatBool(allImagesFromACR),
+	}
+}
+
+// isImageFromACR returns true if the registry of the image is ACR. Images that can't be parsed aren't from ACR.
+func isImageFromACR(image *contracts.Image) bool {
+	if image == nil {
+		return false
+	}
+	imageReference, err := registryutils.GetImageReference(image.Name)
+	if err != nil {
+		return false
+	}
+	return registryutils.IsRegistryEndpointACR(imageReference.Registry())
+}
+
+// isPodTemplateLabelsPatchAllowed returns true if the labels of the pod template of the WorkloadResource can be patched - it has pod template,
+// the pod template isn't immutable, and the WorkloadResource isn't managed by a controller (e.g. a ReplicaSet of a Deployment) - its pod
+// template is copied from the pod template of the controller, and a change of it would make the controller replace it.
+func isPodTemplateLabelsPatchAllowed(workloadResource *admisionrequest.WorkloadResource, isPodTemplateImmutable bool) bool {
+	return workloadResource.PodTemplate != nil && !isPodTemplateImmutable && workloadResource.Metadata.GetControllerOwnerReference() == nil
+}
+
+// createPodTemplateLabelsPatches returns add type json patches of the scan status labels of the pod template. In case that the pod template has no
+// metadata, the metadata is added with the labels. In case that it has no labels, the labels map is added.
+func createPodTemplateLabelsPatches(podTemplate *admisionrequest.PodTemplate, scanStatusLabels map[string]string) []jsonpatch.JsonPatchOperation {
+	metadataPath := getPodTemplateMetadataPath(podTemplate)
+	if !podTemplate.HasMetadata {
+		podTemplate.HasMetadata = true
+		podTemplate.Labels = make(map[string]string, len(scanStatusLabels))
+		for key, value := range scanStatusLabels {
+			podTemplate.Labels[key] = value
+		}
+		return []jsonpatch.JsonPatchOperation{jsonpatch.NewOperation(_addPatchOperation, utils.JoinJSONPointer(metadataPath...), map[string]interface{}{_labelsField: scanStatusLabels})}
+	}
+
+	patches := []jsonpatch.JsonPatchOperation{}
+	if podTemplate.Labels == nil {
+		podTemplate.Labels = make(map[string]string, len(scanStatusLabels))
+		patches = append(patches, jsonpatch.NewOperation(_addPatchOperation, utils.JoinJSONPointer(append(metadataPath, _labelsField)...), map[string]string{}))
+	}
+	return append(patches, setLabels(podTemplate.Labels, metadataPath, scanStatusLabels)...)
+}
+
+// getPodTemplateMetadataPath returns the path of the metadata of the pod template
+func getPodTemplateMetadataPath(podTemplate *admisionrequest.PodTemplate) []string {
+	return append(append([]string{}, podTemplate.Path...), _metadataField)
+}
+
+// setLabels sets the scan status labels in the labels (the labels map must exist), and returns key-level add patches of the labels
+// of the metadata in metadataPath that differ (a change of the pod template labels rolls out the pods of the workload).
+func setLabels(labels map[string]string, metadataPath []string, scanStatusLabels map[string]string) []jsonpatch.JsonPatchOperation {
+	patches := make([]jsonpatch.JsonPatchOperation, 0, len(scanStatusLabels))
+	// Iterate over _scanStatusLabelNames so the order of the patches is deterministic
+	for _, key := range _scanStatusLabelNames {
+		if value, ok := labels[key]; ok && value == scanStatusLabels[key] {
+			continue
+		}
+		labels[key] = scanStatusLabels[key]
+		patches = append(patches, jsonpatch.NewOperation(_addPatchOperation, getLabelPatchPath(metadataPath, key), scanStatusLabels[key]))
+	}
+	return patches
+}
+
+// removeScanStatusLabels deletes the scan status labels from the labels, and returns key-level remove patches of the labels that exist
+// in the metadata in metadataPath (a remove patch of a missing path fails).
+func removeScanStatusLabels(labels map[string]string, metadataPath []string) []jsonpatch.JsonPatchOperation {
+	patches := []jsonpatch.JsonPatchOperation{}
+	for _, key := range _scanStatusLabelNames {
+		if _, ok := labels[key]; !ok {
+			continue
+		}
+		delete(labels, key)
+		patches = append(patches, jsonpatch.NewOperation(_removePatchOperation, getLabelPatchPath(metadataPath, key), nil))
+	}
+	return patches
+}
+
+// getLabelPatchPath returns the json patch path of the label key of the metadata in metadataPath, escaped according to RFC 6901.
+func getLabelPatchPath(metadataPath []string, key string) string {
+	return utils.JoinJSONPointer(append(append([]string{}, metadataPath...), _labelsField, key)...)
+}
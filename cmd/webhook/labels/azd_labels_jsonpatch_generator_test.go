@@ -0,0 +1,259 @@
+package labels
+
+import (
+	"github.com/Azure/AzureDefender-K8S-InClusterDefense/cmd/webhook/admisionrequest"
+	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/azdsecinfo/contracts"
+	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/utils"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/suite"
+	"gomodules.xyz/jsonpatch/v2"
+	"testing"
+)
+
+const (
+	_labelTestKey   = "app"
+	_labelTestValue = "nginx"
+)
+
+type TestSuite struct {
+	suite.Suite
+	unhealthyInfo *contracts.ContainerVulnerabilityScanInfo
+	unscannedInfo *contracts.ContainerVulnerabilityScanInfo
+	healthyInfo   *contracts.ContainerVulnerabilityScanInfo
+}
+
+// This will run before each test in the suite
+func (suite *TestSuite) SetupTest() {
+	suite.unhealthyInfo = &contracts.ContainerVulnerabilityScanInfo{
+		Name:       "app",
+		Image:      &contracts.Image{Name: "tomer.azurecr.io/app:1"},
+		ScanStatus: contracts.UnhealthyScan,
+		ScanFindings: []*contracts.ScanFinding{
+			{Id: "1", Severity: "Medium"},
+			{Id: "2", Severity: "High"},
+			{Id: "3", Severity: "Low"},
+		},
+	}
+	suite.unscannedInfo = &contracts.ContainerVulnerabilityScanInfo{
+		Name:       "sidecar",
+		Image:      &contracts.Image{Name: "docker.io/sidecar:2"},
+		ScanStatus: contracts.Unscanned,
+	}
+	suite.healthyInfo = &contracts.ContainerVulnerabilityScanInfo{
+		Name:       "init",
+		Image:      &contracts.Image{Name: "tomer.azurecr.io/init:1"},
+		ScanStatus: contracts.HealthyScan,
+	}
+}
+
+func (suite *TestSuite) Test_CreateScanStatusLabelsPatchAdd_UnhealthyAndUnscanned_WorstStatusLabels() {
+	workloadResource := createWorkloadResourceForTest(map[string]string{_labelTestKey: _labelTestValue}, admisionrequest.PodTemplate{})
+
+	patches, err := CreateScanStatusLabelsPatchAdd([]*contracts.ContainerVulnerabilityScanInfo{suite.unscannedInfo, suite.unhealthyInfo, suite.healthyInfo}, workloadResource, &ScanStatusLabelsConfiguration{Enabled: true}, false)
+
+	suite.Nil(err)
+	suite.Equal([]jsonpatch.JsonPatchOperation{
+		jsonpatch.NewOperation("add", "/metadata/labels/azuredefender.io~1scan-status", "unhealthy"),
+		jsonpatch.NewOperation("add", "/metadata/labels/azuredefender.io~1max-severity", "high"),
+		jsonpatch.NewOperation("add", "/metadata/labels/azuredefender.io~1all-images-from-acr", "false"),
+	}, patches)
+	suite.Equal(map[string]string{
+		_labelTestKey:             _labelTestValue,
+		ScanStatusLabelName:       "unhealthy",
+		MaxSeverityLabelName:      "high",
+		AllImagesFromACRLabelName: "false",
+	}, workloadResource.Metadata.Labels)
+}
+
+func (suite *TestSuite) Test_CreateScanStatusLabelsPatchAdd_OnlyHealthyACRImagesWithoutLabels_LabelsMapCreated() {
+	workloadResource := createWorkloadResourceForTest(nil, admisionrequest.PodTemplate{})
+
+	patches, err := CreateScanStatusLabelsPatchAdd([]*contracts.ContainerVulnerabilityScanInfo{suite.healthyInfo}, workloadResource, &ScanStatusLabelsConfiguration{Enabled: true}, false)
+
+	suite.Nil(err)
+	suite.Equal([]jsonpatch.JsonPatchOperation{
+		jsonpatch.NewOperation("add", "/metadata/labels", map[string]string{}),
+		jsonpatch.NewOperation("add", "/metadata/labels/azuredefender.io~1scan-status", "healthy"),
+		jsonpatch.NewOperation("add", "/metadata/labels/azuredefender.io~1max-severity", "none"),
+		jsonpatch.NewOperation("add", "/metadata/labels/azuredefender.io~1all-images-from-acr", "true"),
+	}, patches)
+}
+
+func (suite *TestSuite) Test_CreateScanStatusLabelsPatchAdd_Unscanned_UnscannedLabel() {
+	workloadResource := createWorkloadResourceForTest(nil, admisionrequest.PodTemplate{})
+
+	_, err := CreateScanStatusLabelsPatchAdd([]*contracts.ContainerVulnerabilityScanInfo{suite.healthyInfo, suite.unscannedInfo}, workloadResource, &ScanStatusLabelsConfiguration{Enabled: true}, false)
+
+	suite.Nil(err)
+	suite.Equal("unscanned", workloadResource.Metadata.Labels[ScanStatusLabelName])
+}
+
+func (suite *TestSuite) Test_CreateScanStatusLabelsPatchAdd_SummarizedScanInfo_MaxSeverityFromCounts() {
+	workloadResource := createWorkloadResourceForTest(nil, admisionrequest.PodTemplate{})
+	suite.unhealthyInfo.ScanFindings = nil
+	suite.unhealthyInfo.ScanFindingsSummary = []*contracts.ScanFindingsCount{{Severity: "High", Count: 0}, {Severity: "Medium", Count: 2}}
+
+	_, err := CreateScanStatusLabelsPatchAdd([]*contracts.ContainerVulnerabilityScanInfo{suite.unhealthyInfo}, workloadResource, &ScanStatusLabelsConfiguration{Enabled: true}, false)
+
+	suite.Nil(err)
+	suite.Equal("medium", workloadResource.Metadata.Labels[MaxSeverityLabelName])
+}
+
+func (suite *TestSuite) Test_CreateScanStatusLabelsPatchAdd_PodTemplateEnabled_TemplateLabelsPatched() {
+	workloadResource := createWorkloadResourceForTest(map[string]string{}, admisionrequest.PodTemplate{Path: []string{"spec", "template"}, HasMetadata: true, Labels: map[string]string{_labelTestKey: _labelTestValue}})
+
+	patches, err := CreateScanStatusLabelsPatchAdd([]*contracts.ContainerVulnerabilityScanInfo{suite.healthyInfo}, workloadResource, &ScanStatusLabelsConfiguration{Enabled: true, PodTemplateEnabled: true}, false)
+
+	suite.Nil(err)
+	suite.Equal(6, len(patches))
+	suite.Equal([]jsonpatch.JsonPatchOperation{
+		jsonpatch.NewOperation("add", "/spec/template/metadata/labels/azuredefender.io~1scan-status", "healthy"),
+		jsonpatch.NewOperation("add", "/spec/template/metadata/labels/azuredefender.io~1max-severity", "none"),
+		jsonpatch.NewOperation("add", "/spec/template/metadata/labels/azuredefender.io~1all-images-from-acr", "true"),
+	}, patches[3:])
+	suite.Equal(_labelTestValue, workloadResource.PodTemplate.Labels[_labelTestKey])
+}
+
+func (suite *TestSuite) Test_CreateScanStatusLabelsPatchAdd_PodTemplateWithoutLabels_LabelsMapCreated() {
+	workloadResource := createWorkloadResourceForTest(map[string]string{}, admisionrequest.PodTemplate{Path: []string{"spec", "template"}, HasMetadata: true})
+
+	patches, err := CreateScanStatusLabelsPatchAdd([]*contracts.ContainerVulnerabilityScanInfo{suite.healthyInfo}, workloadResource, &ScanStatusLabelsConfiguration{Enabled: true, PodTemplateEnabled: true}, false)
+
+	suite.Nil(err)
+	suite.Equal(7, len(patches))
+	suite.Equal(jsonpatch.NewOperation("add", "/spec/template/metadata/labels", map[string]string{}), patches[3])
+}
+
+func (suite *TestSuite) Test_CreateScanStatusLabelsPatchAdd_PodTemplateWithoutMetadata_MetadataAdded() {
+	workloadResource := createWorkloadResourceForTest(map[string]string{}, admisionrequest.PodTemplate{Path: []string{"spec", "jobTemplate", "spec", "template"}})
+
+	patches, err := CreateScanStatusLabelsPatchAdd([]*contracts.ContainerVulnerabilityScanInfo{suite.healthyInfo}, workloadResource, &ScanStatusLabelsConfiguration{Enabled: true, PodTemplateEnabled: true}, false)
+
+	suite.Nil(err)
+	suite.Equal(4, len(patches))
+	suite.Equal(jsonpatch.NewOperation("add", "/spec/jobTemplate/spec/template/metadata", map[string]interface{}{"labels": map[string]string{
+		ScanStatusLabelName:       "healthy",
+		MaxSeverityLabelName:      "none",
+		AllImagesFromACRLabelName: "true",
+	}}), patches[3])
+}
+
+func (suite *TestSuite) Test_CreateScanStatusLabelsPatchAdd_PodTemplateDisabled_OnlyWorkloadPatched() {
+	workloadResource := createWorkloadResourceForTest(map[string]string{}, admisionrequest.PodTemplate{Path: []string{"spec", "template"}, HasMetadata: true})
+
+	patches, err := CreateScanStatusLabelsPatchAdd([]*contracts.ContainerVulnerabilityScanInfo{suite.healthyInfo}, workloadResource, &ScanStatusLabelsConfiguration{Enabled: true}, false)
+
+	suite.Nil(err)
+	suite.Equal(3, len(patches))
+	suite.Nil(workloadResource.PodTemplate.Labels)
+}
+
+func (suite *TestSuite) Test_CreateScanStatusLabelsPatchAdd_ControllerOwnedWorkload_OnlyWorkloadPatched() {
+	workloadResource := createWorkloadResourceForTest(map[string]string{}, admisionrequest.PodTemplate{Path: []string{"spec", "template"}, HasMetadata: true})
+	workloadResource.Metadata.OwnerReferences = []*admisionrequest.OwnerReference{{APIVersion: "apps/v1", Kind: "Deployment", Name: "deployment", Controller: true}}
+
+	patches, err := CreateScanStatusLabelsPatchAdd([]*contracts.ContainerVulnerabilityScanInfo{suite.healthyInfo}, workloadResource, &ScanStatusLabelsConfiguration{Enabled: true, PodTemplateEnabled: true}, false)
+
+	suite.Nil(err)
+	suite.Equal(3, len(patches))
+	suite.Nil(workloadResource.PodTemplate.Labels)
+}
+
+func (suite *TestSuite) Test_CreateScanStatusLabelsPatchAdd_PodTemplateImmutable_OnlyWorkloadPatched() {
+	workloadResource := createWorkloadResourceForTest(map[string]string{}, admisionrequest.PodTemplate{Path: []string{"spec", "template"}, HasMetadata: true})
+
+	patches, err := CreateScanStatusLabelsPatchAdd([]*contracts.ContainerVulnerabilityScanInfo{suite.healthyInfo}, workloadResource, &ScanStatusLabelsConfiguration{Enabled: true, PodTemplateEnabled: true}, true)
+
+	suite.Nil(err)
+	suite.Equal(3, len(patches))
+	suite.Nil(workloadResource.PodTemplate.Labels)
+}
+
+func (suite *TestSuite) Test_CreateScanStatusLabelsPatchAdd_LabelsAlreadyUpToDate_NoPatches() {
+	upToDateLabels := map[string]string{ScanStatusLabelName: "healthy", MaxSeverityLabelName: "none", AllImagesFromACRLabelName: "true"}
+	templateLabels := map[string]string{ScanStatusLabelName: "healthy", MaxSeverityLabelName: "none", AllImagesFromACRLabelName: "true"}
+	workloadResource := createWorkloadResourceForTest(upToDateLabels, admisionrequest.PodTemplate{Path: []string{"spec", "template"}, HasMetadata: true, Labels: templateLabels})
+
+	patches, err := CreateScanStatusLabelsPatchAdd([]*contracts.ContainerVulnerabilityScanInfo{suite.healthyInfo}, workloadResource, &ScanStatusLabelsConfiguration{Enabled: true, PodTemplateEnabled: true}, false)
+
+	suite.Nil(err)
+	suite.Empty(patches)
+}
+
+func (suite *TestSuite) Test_CreateScanStatusLabelsPatchAdd_SomeLabelsChanged_OnlyChangedLabelsPatched() {
+	labels := map[string]string{ScanStatusLabelName: "unhealthy", MaxSeverityLabelName: "high", AllImagesFromACRLabelName: "true"}
+	workloadResource := createWorkloadResourceForTest(labels, admisionrequest.PodTemplate{})
+
+	patches, err := CreateScanStatusLabelsPatchAdd([]*contracts.ContainerVulnerabilityScanInfo{suite.healthyInfo}, workloadResource, &ScanStatusLabelsConfiguration{Enabled: true}, false)
+
+	suite.Nil(err)
+	suite.Equal([]jsonpatch.JsonPatchOperation{
+		jsonpatch.NewOperation("add", "/metadata/labels/azuredefender.io~1scan-status", "healthy"),
+		jsonpatch.NewOperation("add", "/metadata/labels/azuredefender.io~1max-severity", "none"),
+	}, patches)
+}
+
+func (suite *TestSuite) Test_CreateScanStatusLabelsPatchAdd_NilWorkloadResource_Error() {
+	patches, err := CreateScanStatusLabelsPatchAdd(nil, nil, &ScanStatusLabelsConfiguration{Enabled: true}, false)
+
+	suite.Equal(utils.NilArgumentError, errors.Cause(err))
+	suite.Nil(patches)
+}
+
+func (suite *TestSuite) Test_CreateLabelsPatchToDeleteScanStatusLabelsIfNeeded_StaleLabels_Removed() {
+	staleLabels := map[string]string{_labelTestKey: _labelTestValue, ScanStatusLabelName: "unhealthy", MaxSeverityLabelName: "high"}
+	workloadResource := createWorkloadResourceForTest(staleLabels, admisionrequest.PodTemplate{Path: []string{"spec", "template"}, HasMetadata: true, Labels: map[string]string{AllImagesFromACRLabelName: "true"}})
+
+	patches, err := CreateLabelsPatchToDeleteScanStatusLabelsIfNeeded(workloadResource, false)
+
+	suite.Nil(err)
+	suite.Equal([]jsonpatch.JsonPatchOperation{
+		jsonpatch.NewOperation("remove", "/metadata/labels/azuredefender.io~1scan-status", nil),
+		jsonpatch.NewOperation("remove", "/metadata/labels/azuredefender.io~1max-severity", nil),
+		jsonpatch.NewOperation("remove", "/spec/template/metadata/labels/azuredefender.io~1all-images-from-acr", nil),
+	}, patches)
+	suite.Equal(map[string]string{_labelTestKey: _labelTestValue}, workloadResource.Metadata.Labels)
+}
+
+func (suite *TestSuite) Test_CreateLabelsPatchToDeleteScanStatusLabelsIfNeeded_PodTemplateImmutable_OnlyWorkloadLabelsRemoved() {
+	staleLabels := map[string]string{ScanStatusLabelName: "unhealthy"}
+	workloadResource := createWorkloadResourceForTest(staleLabels, admisionrequest.PodTemplate{Path: []string{"spec", "template"}, HasMetadata: true, Labels: map[string]string{AllImagesFromACRLabelName: "true"}})
+
+	patches, err := CreateLabelsPatchToDeleteScanStatusLabelsIfNeeded(workloadResource, true)
+
+	suite.Nil(err)
+	suite.Equal([]jsonpatch.JsonPatchOperation{
+		jsonpatch.NewOperation("remove", "/metadata/labels/azuredefender.io~1scan-status", nil),
+	}, patches)
+	suite.Equal(map[string]string{AllImagesFromACRLabelName: "true"}, workloadResource.PodTemplate.Labels)
+}
+
+func (suite *TestSuite) Test_CreateLabelsPatchToDeleteScanStatusLabelsIfNeeded_NoScanStatusLabels_NoPatches() {
+	workloadResource := createWorkloadResourceForTest(map[string]string{_labelTestKey: _labelTestValue}, admisionrequest.PodTemplate{Path: []string{"spec", "template"}})
+
+	patches, err := CreateLabelsPatchToDeleteScanStatusLabelsIfNeeded(workloadResource, false)
+
+	suite.Nil(err)
+	suite.Empty(patches)
+}
+
+func (suite *TestSuite) Test_CreateLabelsPatchToDeleteScanStatusLabelsIfNeeded_NilWorkloadResource_Error() {
+	patches, err := CreateLabelsPatchToDeleteScanStatusLabelsIfNeeded(nil, false)
+
+	suite.Equal(utils.NilArgumentError, errors.Cause(err))
+	suite.Nil(patches)
+}
+
+func createWorkloadResourceForTest(labels map[string]string, podTemplate admisionrequest.PodTemplate) *admisionrequest.WorkloadResource {
+	workloadResource := &admisionrequest.WorkloadResource{
+		Metadata: &admisionrequest.ObjectMetadata{Name: "workloadTest", Namespace: "default", Labels: labels},
+	}
+	if podTemplate.Path != nil {
+		workloadResource.PodTemplate = &podTemplate
+	}
+	return workloadResource
+}
+
+func TestCreateScanStatusLabelsPatch(t *testing.T) {
+	suite.Run(t, new(TestSuite))
+}
@@ -1,6 +1,7 @@
 package webhook
 
 import (
+	"fmt"
 	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/instrumentation"
 	"github.com/pkg/errors"
 	corev1 "k8s.io/api/core/v1"
@@ -33,6 +34,12 @@ type ManagerConfiguration struct {
 	Port int
 	// CertDir is the directory that the certificates are saved.
 	CertDir string
+	// HealthProbePort is the port that the manager serves the liveness (/healthz) and readiness (/readyz) probes on.
+	// If zero - the probes aren't served.
+	HealthProbePort int
+	// MetricsPort is the port that the manager serves the prometheus metrics (/metrics) on.
+	// If zero - the default port of controller-runtime (8080) is used.
+	MetricsPort int
 }
 
 // NewManagerFactory Constructor for ManagerFactory
@@ -79,5 +86,11 @@ func (factory *ManagerFactory) createOptions() (options *manager.Options, err er
 		Port:    factory.configuration.Port,
 		CertDir: factory.configuration.CertDir,
 	}
+	if factory.configuration.HealthProbePort > 0 {
+		options.HealthProbeBindAddress = fmt.Sprintf(":%d", factory.configuration.HealthProbePort)
+	}
+	if factory.configuration.MetricsPort > 0 {
+		options.MetricsBindAddress = fmt.Sprintf(":%d", factory.configuration.MetricsPort)
+	}
 	return options, nil
 }
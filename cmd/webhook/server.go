@@ -1,14 +1,18 @@
 package webhook
 
 import (
+	"context"
+	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/health"
+	healthmetric "github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/health/metric"
 	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/instrumentation"
 	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/instrumentation/metric"
 	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/instrumentation/metric/util"
 	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/instrumentation/trace"
 	"github.com/open-policy-agent/cert-controller/pkg/rotator"
 	"github.com/pkg/errors"
+	"net/http"
+	"sigs.k8s.io/controller-runtime/pkg/healthz"
 	"sigs.k8s.io/controller-runtime/pkg/manager"
-	"sigs.k8s.io/controller-runtime/pkg/manager/signals"
 	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
 )
 
@@ -26,6 +30,12 @@ type Server struct {
 	webhookHandler admission.Handler
 	// configuration  - Server configuration
 	configuration *ServerConfiguration
+	// certificatesHealthCheck is the health check of the cert rotation setup - healthy once certRotator is ready
+	certificatesHealthCheck *health.StatusHealthCheck
+	// webhookHealthCheck is the health check of the webhook registration - healthy once the webhook is registered
+	webhookHealthCheck *health.StatusHealthCheck
+	// dependenciesHealthChecks are the health checks of the server's dependencies (e.g. redis, identities' tokens)
+	dependenciesHealthChecks []health.IHealthCheck
 }
 
 // ServerConfiguration configuration
@@ -40,14 +50,18 @@ type ServerConfiguration struct {
 func NewServer(instrumentationProvider instrumentation.IInstrumentationProvider,
 	manager manager.Manager, certRotator *rotator.CertRotator,
 	webhookHandler admission.Handler,
-	configuration *ServerConfiguration) *Server {
+	configuration *ServerConfiguration,
+	dependenciesHealthChecks []health.IHealthCheck) *Server {
 	return &Server{
-		tracerProvider:  instrumentationProvider.GetTracerProvider("Handler"),
-		metricSubmitter: instrumentationProvider.GetMetricSubmitter(),
-		manager:         manager,
-		certRotator:     certRotator,
-		webhookHandler:  webhookHandler,
-		configuration:   configuration,
+		tracerProvider:           instrumentationProvider.GetTracerProvider("Handler"),
+		metricSubmitter:          instrumentationProvider.GetMetricSubmitter(),
+		manager:                  manager,
+		certRotator:              certRotator,
+		webhookHandler:           webhookHandler,
+		configuration:            configuration,
+		certificatesHealthCheck:  health.NewStatusHealthCheck("certificates", 0),
+		webhookHealthCheck:       health.NewStatusHealthCheck("webhook", 0),
+		dependenciesHealthChecks: dependenciesHealthChecks,
 	}
 }
 
@@ -55,7 +69,8 @@ func NewServer(instrumentationProvider instrumentation.IInstrumentationProvider,
 // It initializes the server with all the instrumentation, initialize the controllers, and register them.
 // There are 2 controllers - cert-controller (https://github.com/open-policy-agent/cert-controller) that manages
 // the certificates of the server and the mutation webhook server that is registered with the AzDSecInfo Handler.
-func (server *Server) Run() (err error) {
+// The server runs until ctx is done.
+func (server *Server) Run(ctx context.Context) (err error) {
 	tracer := server.tracerProvider.GetTracer("Run")
 
 	tracer.Info("Run() server")
@@ -67,11 +82,18 @@ func (server *Server) Run() (err error) {
 		return errors.Wrap(err, "Server.Run - failed to initialize cert controller")
 	}
 
+	// Add liveness and readiness checks - served by the manager on the health probe port.
+	if err = server.addHealthChecks(); err != nil {
+		tracer.Error(err, "addHealthChecks")
+		server.metricSubmitter.SendMetric(1, util.NewErrorEncounteredMetric(err, "Server.Run - failed to add health checks"))
+		return errors.Wrap(err, "Server.Run - failed to add health checks")
+	}
+
 	// Set up controllers.
 	go server.setupControllers()
 
 	// Start all registered controllers - webhook mutation as https server and cert controller.
-	if err := server.manager.Start(signals.SetupSignalHandler()); err != nil {
+	if err := server.manager.Start(ctx); err != nil {
 		tracer.Error(err, "manager Start")
 		server.metricSubmitter.SendMetric(1, util.NewErrorEncounteredMetric(err, "Server.Run unable to start manager"))
 		return errors.Wrap(err, "Server.Run unable to start manager")
@@ -109,6 +131,7 @@ func (server *Server) setupControllers() {
 	tracer.Info("waiting for cert rotation setup")
 	<-server.certRotator.IsReady
 	tracer.Info("done waiting for cert rotation setup")
+	server.certificatesHealthCheck.Report(nil)
 
 	// Register mutation webhook.
 	server.registerWebhook()
@@ -123,4 +146,34 @@ func (server *Server) registerWebhook() {
 	mutationWebhook := &admission.Webhook{Handler: server.webhookHandler}
 	server.manager.GetWebhookServer().Register(server.configuration.Path, mutationWebhook)
 	tracer.Info("Webhook registered successfully", "path", server.configuration.Path)
+	server.webhookHealthCheck.Report(nil)
+}
+
+// addHealthChecks adds the liveness check and the readiness checks of the server to the manager.
+// The liveness check only checks that the server is running, so failures of dependencies don't restart the server.
+// The readiness checks are the cert rotation setup, the webhook registration and the server's dependencies.
+func (server *Server) addHealthChecks() error {
+	tracer := server.tracerProvider.GetTracer("addHealthChecks")
+
+	if err := server.manager.AddHealthzCheck("ping", healthz.Ping); err != nil {
+		return errors.Wrap(err, "unable to add liveness check")
+	}
+
+	readinessChecks := append([]health.IHealthCheck{server.certificatesHealthCheck, server.webhookHealthCheck}, server.dependenciesHealthChecks...)
+	for _, check := range readinessChecks {
+		if err := server.manager.AddReadyzCheck(check.Name(), server.createReadinessChecker(check)); err != nil {
+			return errors.Wrapf(err, "unable to add readiness check <%s>", check.Name())
+		}
+		tracer.Info("Readiness check added", "check", check.Name())
+	}
+	return nil
+}
+
+// createReadinessChecker creates healthz.Checker of the health check that sends the status of the check as metric each time that it is checked.
+func (server *Server) createReadinessChecker(check health.IHealthCheck) healthz.Checker {
+	return func(req *http.Request) error {
+		err := check.Check(req)
+		server.metricSubmitter.SendMetric(1, healthmetric.NewHealthCheckStatusMetric(check.Name(), err == nil))
+		return err
+	}
 }
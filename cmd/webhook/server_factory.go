@@ -2,8 +2,9 @@
 package webhook
 
 import (
+	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/health"
 	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/instrumentation"
-	"github.com/pkg/errors"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
 	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
 )
 
@@ -22,26 +23,30 @@ type ServerFactory struct {
 	configuration *ServerConfiguration
 	// instrumentationProvider
 	instrumentationProvider instrumentation.IInstrumentationProvider
-	// ManagerFactory is the factory for manager
-	managerFactory IManagerFactory
+	// manager is the manager of the server. It's created before the server, so the handler's dependencies can use it (e.g. event recorder)
+	manager manager.Manager
 	// CertRotatorFactory is the factory for cert rotator
 	certRotatorFactory ICertRotatorFactory
 	// webhookHandler
 	webhookHandler admission.Handler
+	// dependenciesHealthChecks are the health checks of the server's dependencies that gate the server's readiness
+	dependenciesHealthChecks []health.IHealthCheck
 }
 
 // NewServerFactory constructor for ServerFactory
 func NewServerFactory(configuration *ServerConfiguration,
-	managerFactory IManagerFactory,
+	manager manager.Manager,
 	certRotatorFactory ICertRotatorFactory,
 	webhookHandler admission.Handler,
-	instrumentationProvider instrumentation.IInstrumentationProvider) (factory IServerFactory) {
+	instrumentationProvider instrumentation.IInstrumentationProvider,
+	dependenciesHealthChecks []health.IHealthCheck) (factory IServerFactory) {
 	return &ServerFactory{
-		configuration:           configuration,
-		managerFactory:          managerFactory,
-		certRotatorFactory:      certRotatorFactory,
-		webhookHandler:          webhookHandler,
-		instrumentationProvider: instrumentationProvider,
+		configuration:            configuration,
+		manager:                  manager,
+		certRotatorFactory:       certRotatorFactory,
+		webhookHandler:           webhookHandler,
+		instrumentationProvider:  instrumentationProvider,
+		dependenciesHealthChecks: dependenciesHealthChecks,
 	}
 }
 
@@ -51,13 +56,8 @@ func (factory *ServerFactory) CreateServer() (server *Server, err error) {
 	// Create CertRotator using ICertRotatorFactory
 	certRotator := factory.certRotatorFactory.CreateCertRotator()
 
-	// Create manager using IManagerFactory
-	mgr, err := factory.managerFactory.CreateManager()
-	if err != nil {
-		return nil, errors.Wrap(err, "unable to create Manager for server")
-	}
 	// Create Server
-	server = NewServer(factory.instrumentationProvider, mgr, certRotator, factory.webhookHandler, factory.configuration)
+	server = NewServer(factory.instrumentationProvider, factory.manager, certRotator, factory.webhookHandler, factory.configuration, factory.dependenciesHealthChecks)
 
 	return server, nil
 }
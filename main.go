@@ -7,15 +7,28 @@ import (
 	tivanInstrumentation "github.com/Azure/ASC-go-libs/pkg/instrumentation"
 	"github.com/Azure/AzureDefender-K8S-InClusterDefense/cmd/webhook"
 	"github.com/Azure/AzureDefender-K8S-InClusterDefense/cmd/webhook/admisionrequest"
+	"github.com/Azure/AzureDefender-K8S-InClusterDefense/cmd/webhook/annotations"
+	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/artifacts"
 	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/azdsecinfo"
 	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/dataproviders/arg"
 	argqueries "github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/dataproviders/arg/queries"
 	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/dataproviders/arg/wrappers"
+	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/decisionlog"
+	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/events"
+	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/imagedrift"
 	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/azureauth"
 	azureauthwrappers "github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/azureauth/wrappers"
 	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/cache"
 	cachewrappers "github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/cache/wrappers"
+	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/circuitbreaker"
+	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/cloudenvironment"
+	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/coalescing"
+	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/configreload"
+	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/health"
 	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/instrumentation"
+	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/instrumentation/metric"
+	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/instrumentation/opentelemetry"
+	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/instrumentation/prometheus"
 	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/instrumentation/tivan"
 	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/instrumentation/trace"
 	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/registry/acrauth"
@@ -24,20 +37,29 @@ import (
 	registrywrappers "github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/registry/wrappers"
 	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/retrypolicy"
 	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/utils"
+	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/signature"
 	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/tag2digest"
+	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/vulnerabilityreport"
+	"github.com/pkg/errors"
 	"k8s.io/client-go/kubernetes"
 	"log"
 	"net/http"
 	"os"
 	k8sclientconfig "sigs.k8s.io/controller-runtime/pkg/client/config"
+	"sigs.k8s.io/controller-runtime/pkg/manager/signals"
+	ctrlmetrics "sigs.k8s.io/controller-runtime/pkg/metrics"
+	"time"
 )
 
 var (
-	_cacheContext = context.Background()
+	_tokenHealthCheckContext = context.Background()
+	_otelContext             = context.Background()
 )
 
 const (
 	_configFileKey = "CONFIG_FILE"
+	// _decisionLoggerCloseTimeout is the maximal duration to wait for the queued decision records to be written on shutdown
+	_decisionLoggerCloseTimeout = 10 * time.Second
 )
 
 // main is the entrypoint to AzureDefenderInClusterDefense .
@@ -62,14 +84,22 @@ func main() {
 	metricSubmitterConfiguration := new(tivan.MetricSubmitterConfiguration)
 	tracerConfiguration := new(trace.TracerConfiguration)
 	instrumentationConfiguration := new(instrumentation.InstrumentationProviderConfiguration)
+	prometheusMetricSubmitterConfiguration := new(prometheus.PrometheusMetricSubmitterConfiguration)
+	otelTracingConfiguration := new(opentelemetry.OTelTracingConfiguration)
 	azdIdentityEnvAzureAuthorizerConfiguration := new(azureauth.MSIAzureAuthorizerConfiguration)
 	kubeletIdentityEnvAzureAuthorizerConfiguration := new(azureauth.MSIAzureAuthorizerConfiguration)
 	argClientConfiguration := new(arg.ARGClientConfiguration)
 	deploymentConfiguration := new(utils.DeploymentConfiguration)
+	cloudEnvironmentConfiguration := new(cloudenvironment.CloudEnvironmentConfiguration)
 	craneWrapperRetryPolicyConfiguration := new(retrypolicy.RetryPolicyConfiguration)
+	registryTransportProviderConfiguration := new(crane.RegistryTransportProviderConfiguration)
 	argBaseClientRetryPolicyConfiguration := new(retrypolicy.RetryPolicyConfiguration)
 	redisCacheClientRetryPolicyConfiguration := new(retrypolicy.RetryPolicyConfiguration)
 	acrTokenExchangerClientRetryPolicyConfiguration := new(retrypolicy.RetryPolicyConfiguration)
+	craneWrapperCircuitBreakerConfiguration := new(circuitbreaker.CircuitBreakerConfiguration)
+	argBaseClientCircuitBreakerConfiguration := new(circuitbreaker.CircuitBreakerConfiguration)
+	redisCacheClientCircuitBreakerConfiguration := new(circuitbreaker.CircuitBreakerConfiguration)
+	acrTokenExchangerCircuitBreakerConfiguration := new(circuitbreaker.CircuitBreakerConfiguration)
 	argDataProviderConfiguration := new(arg.ARGDataProviderConfiguration)
 	tag2DigestResolverConfiguration := new(tag2digest.Tag2DigestResolverConfiguration)
 	acrTokenProviderConfiguration := new(acrauth.ACRTokenProviderConfiguration)
@@ -77,31 +107,68 @@ func main() {
 	tokensCacheConfiguration := new(cachewrappers.FreeCacheInMemWrapperCacheConfiguration)
 	azdSecInfoProviderConfiguration := new(azdsecinfo.AzdSecInfoProviderConfiguration)
 	getContainersVulnerabilityScanInfoTimeoutDuration := new(utils.TimeoutConfiguration)
+	backgroundFetchTimeoutDuration := new(utils.TimeoutConfiguration)
+	supplyChainLookupsTimeoutDuration := new(utils.TimeoutConfiguration)
+	signatureVerifierConfiguration := new(signature.SignatureVerifierConfiguration)
+	artifactsDiscovererConfiguration := new(artifacts.ArtifactsDiscovererConfiguration)
+	healthChecksConfiguration := new(health.HealthChecksConfiguration)
+	decisionLoggerConfiguration := new(decisionlog.DecisionLoggerConfiguration)
+	fileDecisionLogSinkConfiguration := new(decisionlog.FileDecisionLogSinkConfiguration)
+	httpDecisionLogSinkConfiguration := new(decisionlog.HTTPDecisionLogSinkConfiguration)
+	workloadEventEmitterConfiguration := new(events.WorkloadEventEmitterConfiguration)
+	distributedLockConfiguration := new(coalescing.DistributedLockConfiguration)
+	configReloaderConfiguration := new(configreload.ConfigReloaderConfiguration)
+	vulnerabilityReportWriterConfiguration := new(vulnerabilityreport.VulnerabilityReportWriterConfiguration)
+	vulnerabilityReportRetryPolicyConfiguration := new(retrypolicy.RetryPolicyConfiguration)
+	imageDriftDetectorConfiguration := new(imagedrift.ImageDriftDetectorConfiguration)
 
 	// Create a map between configuration object and key in main config file
 	keyConfigMap := map[string]interface{}{
-		"webhook.managerConfiguration":                            managerConfiguration,
-		"webhook.certRotatorConfiguration":                        certRotatorConfiguration,
-		"webhook.serverConfiguration":                             serverConfiguration,
-		"webhook.handlerConfiguration":                            handlerConfiguration,
-		"webhook.extractorConfiguration":						   extractorConfiguration,
-		"instrumentation.tivan.tivanInstrumentationConfiguration": tivanInstrumentationConfiguration,
-		"instrumentation.trace.tracerConfiguration":               tracerConfiguration,
-		"azdIdentity.envAzureAuthorizerConfiguration":             azdIdentityEnvAzureAuthorizerConfiguration,
-		"kubeletIdentity.envAzureAuthorizerConfiguration":         kubeletIdentityEnvAzureAuthorizerConfiguration,
-		"arg.argBaseClient.retryPolicyConfiguration":              argBaseClientRetryPolicyConfiguration,
-		"acr.craneWrappersConfiguration.retryPolicyConfiguration": craneWrapperRetryPolicyConfiguration,
-		"acr.tokenExchanger.retryPolicyConfiguration":             acrTokenExchangerClientRetryPolicyConfiguration,
-		"acr.acrTokenProviderConfiguration":                       acrTokenProviderConfiguration,
-		"arg.argClientConfiguration":                              argClientConfiguration,
-		"arg.argDataProviderConfiguration":                        argDataProviderConfiguration,
-		"tag2digest.tag2DigestResolverConfiguration":              tag2DigestResolverConfiguration,
-		"deployment": deploymentConfiguration,
+		"webhook.managerConfiguration":                                      managerConfiguration,
+		"webhook.certRotatorConfiguration":                                  certRotatorConfiguration,
+		"webhook.serverConfiguration":                                       serverConfiguration,
+		"webhook.handlerConfiguration":                                      handlerConfiguration,
+		"webhook.extractorConfiguration":                                    extractorConfiguration,
+		"instrumentation.tivan.tivanInstrumentationConfiguration":           tivanInstrumentationConfiguration,
+		"instrumentation.trace.tracerConfiguration":                         tracerConfiguration,
+		"instrumentation.instrumentationProviderConfiguration":              instrumentationConfiguration,
+		"instrumentation.prometheus.prometheusMetricSubmitterConfiguration": prometheusMetricSubmitterConfiguration,
+		"instrumentation.opentelemetry.otelTracingConfiguration":            otelTracingConfiguration,
+		"azdIdentity.envAzureAuthorizerConfiguration":                       azdIdentityEnvAzureAuthorizerConfiguration,
+		"kubeletIdentity.envAzureAuthorizerConfiguration":                   kubeletIdentityEnvAzureAuthorizerConfiguration,
+		"arg.argBaseClient.retryPolicyConfiguration":                        argBaseClientRetryPolicyConfiguration,
+		"acr.craneWrappersConfiguration.retryPolicyConfiguration":           craneWrapperRetryPolicyConfiguration,
+		"registry.registryTransportProviderConfiguration":                   registryTransportProviderConfiguration,
+		"acr.tokenExchanger.retryPolicyConfiguration":                       acrTokenExchangerClientRetryPolicyConfiguration,
+		"acr.craneWrappersConfiguration.circuitBreakerConfiguration":        craneWrapperCircuitBreakerConfiguration,
+		"acr.tokenExchanger.circuitBreakerConfiguration":                    acrTokenExchangerCircuitBreakerConfiguration,
+		"arg.argBaseClient.circuitBreakerConfiguration":                     argBaseClientCircuitBreakerConfiguration,
+		"acr.acrTokenProviderConfiguration":                                 acrTokenProviderConfiguration,
+		"arg.argClientConfiguration":                                        argClientConfiguration,
+		"arg.argDataProviderConfiguration":                                  argDataProviderConfiguration,
+		"tag2digest.tag2DigestResolverConfiguration":                        tag2DigestResolverConfiguration,
+		"deployment":       deploymentConfiguration,
+		"cloudEnvironment": cloudEnvironmentConfiguration,
 		"cache.argDataProviderCacheConfiguration":                              argDataProviderCacheConfiguration,
 		"cache.tokensCacheConfiguration":                                       tokensCacheConfiguration,
 		"cache.redisClient.retryPolicyConfiguration":                           redisCacheClientRetryPolicyConfiguration,
+		"cache.redisClient.circuitBreakerConfiguration":                        redisCacheClientCircuitBreakerConfiguration,
 		"azdSecInfoProvider.getContainersVulnerabilityScanInfoTimeoutDuration": getContainersVulnerabilityScanInfoTimeoutDuration,
+		"azdSecInfoProvider.backgroundFetchTimeoutDuration":                    backgroundFetchTimeoutDuration,
+		"azdSecInfoProvider.supplyChainLookupsTimeoutDuration":                 supplyChainLookupsTimeoutDuration,
 		"azdSecInfoProvider.azdSecInfoProviderConfiguration":                   azdSecInfoProviderConfiguration,
+		"signature.signatureVerifierConfiguration":                             signatureVerifierConfiguration,
+		"artifacts.artifactsDiscovererConfiguration":                           artifactsDiscovererConfiguration,
+		"health.healthChecksConfiguration":                                     healthChecksConfiguration,
+		"decisionLog.decisionLoggerConfiguration":                              decisionLoggerConfiguration,
+		"decisionLog.fileDecisionLogSinkConfiguration":                         fileDecisionLogSinkConfiguration,
+		"decisionLog.httpDecisionLogSinkConfiguration":                         httpDecisionLogSinkConfiguration,
+		"events.workloadEventEmitterConfiguration":                             workloadEventEmitterConfiguration,
+		"coalescing.distributedLockConfiguration":                              distributedLockConfiguration,
+		"configReload.configReloaderConfiguration":                             configReloaderConfiguration,
+		"vulnerabilityReport.vulnerabilityReportWriterConfiguration":           vulnerabilityReportWriterConfiguration,
+		"vulnerabilityReport.retryPolicyConfiguration":                         vulnerabilityReportRetryPolicyConfiguration,
+		"imageDrift.imageDriftDetectorConfiguration":                           imageDriftDetectorConfiguration,
 	}
 
 	for key, configObject := range keyConfigMap {
@@ -127,11 +194,42 @@ func main() {
 		errMsg := fmt.Sprintf("Got non-positive cache TTL. Only positive values are allowed. Configuration name: <%s>", configurationName)
 		log.Fatal(errMsg, utils.InvalidConfiguration)
 	}
+	// Validate the frequency of the health checks - non-positive values are not allowed.
+	isValidConfiguration, configurationName = utils.ValidatePositiveInt(
+		&utils.PositiveIntValidationObject{VariableName: "healthChecksConfiguration.RedisPingFrequencyInSeconds", Variable: healthChecksConfiguration.RedisPingFrequencyInSeconds},
+		&utils.PositiveIntValidationObject{VariableName: "healthChecksConfiguration.TokenAcquisitionFrequencyInSeconds", Variable: healthChecksConfiguration.TokenAcquisitionFrequencyInSeconds},
+	)
+	if !isValidConfiguration {
+		errMsg := fmt.Sprintf("Got non-positive health check frequency. Only positive values are allowed. Configuration name: <%s>", configurationName)
+		log.Fatal(errMsg, utils.InvalidConfiguration)
+	}
+	// Validate the schema versions of the scan info annotations
+	if _, err := handlerConfiguration.ScanInfoAnnotationConfiguration.GetSchemaVersions(); err != nil {
+		log.Fatal("main.handlerConfiguration.ScanInfoAnnotationConfiguration.GetSchemaVersions", err)
+	}
+	// Validate the supported kinds of the extractor
+	if err := extractorConfiguration.Validate(); err != nil {
+		log.Fatal("main.extractorConfiguration.Validate", err)
+	}
+	// Validate the polling interval of the configuration file - non-positive values are not allowed if the reload is enabled.
+	if configReloaderConfiguration.Enabled {
+		isValidConfiguration, configurationName = utils.ValidatePositiveInt(
+			&utils.PositiveIntValidationObject{VariableName: "configReloaderConfiguration.PollingIntervalInSeconds", Variable: configReloaderConfiguration.PollingIntervalInSeconds},
+		)
+		if !isValidConfiguration {
+			errMsg := fmt.Sprintf("Got non-positive polling interval of the configuration file. Only positive values are allowed. Configuration name: <%s>", configurationName)
+			log.Fatal(errMsg, utils.InvalidConfiguration)
+		}
+	}
 	// Create deployment singleton.
 	deploymentInstance, err := utils.NewDeployment(deploymentConfiguration)
 	if err != nil {
 		log.Fatal("main.NewDeployment", err)
 	}
+	// Create cloud environment singleton - the registry suffixes, resource graph endpoint, ARM audience and login authority are derived from it.
+	if _, err = cloudenvironment.NewCloudEnvironment(cloudEnvironmentConfiguration, new(azureauthwrappers.AzureAuthWrapper)); err != nil {
+		log.Fatal("main.NewCloudEnvironment", err)
+	}
 	// Create Tivan's instrumentation
 	// TODO we need a way get the pod name (probably using kubectl).
 	tivanInstrumentationResult, err := tivan.NewTivanInstrumentationResult(tivanInstrumentationConfiguration)
@@ -142,11 +240,23 @@ func main() {
 	// Create factories
 	tracerFactory := tivan.NewTracerFactory(tracerConfiguration, tivanInstrumentationResult.Tracer)
 	metricSubmitterFactory := tivan.NewMetricSubmitterFactory(metricSubmitterConfiguration, tivanInstrumentationResult.MetricSubmitter)
-	instrumentationProviderFactory := instrumentation.NewInstrumentationProviderFactory(instrumentationConfiguration, tracerFactory, metricSubmitterFactory)
+	// Prometheus metrics are registered on controller-runtime's metrics registry - served by the manager's metrics endpoint
+	prometheusMetricSubmitterFactory := prometheus.NewPrometheusMetricSubmitterFactory(prometheusMetricSubmitterConfiguration, ctrlmetrics.Registry)
+	metricSubmitterFactories := map[string]metric.IMetricSubmitterFactory{
+		instrumentation.TivanMetricSubmitterBackend:      metricSubmitterFactory,
+		instrumentation.PrometheusMetricSubmitterBackend: prometheusMetricSubmitterFactory,
+	}
+	instrumentationProviderFactory := instrumentation.NewInstrumentationProviderFactory(instrumentationConfiguration, tracerFactory, metricSubmitterFactories)
 	instrumentationProvider, err := instrumentationProviderFactory.CreateInstrumentationProvider()
 	if err != nil {
 		log.Fatal("main.instrumentationProviderFactory.CreateInstrumentationProvider", err)
 	}
+	// Register the global OpenTelemetry tracer provider - exports the spans of the tracer providers over OTLP (if enabled)
+	otelTracerProviderFactory := opentelemetry.NewOTelTracerProviderFactory(otelTracingConfiguration)
+	shutdownOTelTracerProvider, err := otelTracerProviderFactory.RegisterGlobalTracerProvider(_otelContext)
+	if err != nil {
+		log.Fatal("main.otelTracerProviderFactory.RegisterGlobalTracerProvider", err)
+	}
 
 	kubeletIdentityAuthorizerFactory := azureauth.NewMSIEnvAzureAuthorizerFactory(instrumentationProvider, kubeletIdentityEnvAzureAuthorizerConfiguration, new(azureauthwrappers.AzureAuthWrapper))
 	kubeletIdentityAuthorizer, err := kubeletIdentityAuthorizerFactory.CreateARMAuthorizer()
@@ -169,12 +279,19 @@ func main() {
 		log.Fatal("main.kubeletIdentityAuthorizer.bearerAuthorizer type assertion", err)
 
 	}
+	// Health checks of the dependencies - the readiness of the server is gated on them
+	dependenciesHealthChecks := []health.IHealthCheck{}
+	// Configurations that are applied when the configuration file is changed - without restarting the webhook
+	reloadableConfigurations := []*configreload.ReloadableConfiguration{}
+
 	//Cache clients
 	//In mem
 	freeCacheInMemCache := cachewrappers.NewFreeCacheInMem(tokensCacheConfiguration)
 	freeCacheInMemCacheClient := cache.NewFreeCacheInMemCacheClient(instrumentationProvider, freeCacheInMemCache)
 	//Redis
 	var persistentCacheClient cache.ICacheClient
+	// Distributed lock - NoOp lock in case that it's disabled or there is no shared cache (local deployment)
+	var distributedLock coalescing.IDistributedLock = coalescing.NewNoOpDistributedLock()
 	// If this is local deployment - use in mem cache instead of redis
 	if deploymentInstance.IsLocalDevelopment() {
 		persistentCacheClient = freeCacheInMemCacheClient
@@ -185,30 +302,89 @@ func main() {
 		if err != nil {
 			log.Fatal("main.redisCacheBaseClientFactory.Create got invalid certificates or failed to load cert files or password file", err)
 		}
-		redisCacheRetryPolicy := retrypolicy.NewRetryPolicy(instrumentationProvider, redisCacheClientRetryPolicyConfiguration)
-		redisCacheClient := cache.NewRedisCacheClient(instrumentationProvider, redisCacheBaseClient, redisCacheRetryPolicy, _cacheContext)
+		redisCacheRetryPolicy := retrypolicy.NewRetryPolicy(instrumentationProvider, redisCacheClientRetryPolicyConfiguration, "RedisCacheClient")
+		reloadableConfigurations = append(reloadableConfigurations, createRetryPolicyReloadableConfiguration("cache.redisClient.retryPolicyConfiguration", redisCacheRetryPolicy))
+		redisCacheClient := cache.NewRedisCacheClient(instrumentationProvider, redisCacheBaseClient, redisCacheRetryPolicy, createCircuitBreaker(instrumentationProvider, redisCacheClientCircuitBreakerConfiguration, "RedisCacheClient"))
 
-		// Check connection every argDataProviderCacheConfiguration.HeartbeatFrequency in minutes
-		utils.RepeatEveryTick(utils.GetMinutes(argDataProviderCacheConfiguration.HeartbeatFrequency), redisCacheClient.Ping)
+		// Check connection every healthChecksConfiguration.RedisPingFrequencyInSeconds seconds - the readiness is gated on a recent successful ping
+		redisHealthCheck := health.NewStatusHealthCheck("redis", utils.GetSeconds(healthChecksConfiguration.RedisPingMaxAgeInSeconds))
+		redisHealthCheck.RunEveryTick(utils.GetSeconds(healthChecksConfiguration.RedisPingFrequencyInSeconds), func() error { return redisCacheClient.Ping(context.Background()) })
+		dependenciesHealthChecks = append(dependenciesHealthChecks, redisHealthCheck)
 
 		// Export the client
 		persistentCacheClient = redisCacheClient
+
+		if distributedLockConfiguration.Enabled {
+			distributedLock = coalescing.NewRedisDistributedLock(instrumentationProvider, redisCacheBaseClient, distributedLockConfiguration)
+		}
 	}
 
 	azureBearerAuthorizerTokenProvider := azureauth.NewBearerAuthorizerTokenProvider(azureBearerAuthorizer)
+	kubeletIdentityTokenHealthCheck := createTokenHealthCheck("kubeletIdentityToken", azureBearerAuthorizerTokenProvider, healthChecksConfiguration)
+	dependenciesHealthChecks = append(dependenciesHealthChecks, kubeletIdentityTokenHealthCheck)
 
-	acrTokenExchangerClientRetryPolicy := retrypolicy.NewRetryPolicy(instrumentationProvider, acrTokenExchangerClientRetryPolicyConfiguration)
-	acrTokenExchanger := registryauthazure.NewACRTokenExchanger(instrumentationProvider, &http.Client{}, acrTokenExchangerClientRetryPolicy)
+	acrTokenExchangerClientRetryPolicy := retrypolicy.NewRetryPolicy(instrumentationProvider, acrTokenExchangerClientRetryPolicyConfiguration, "ACRTokenExchanger")
+	acrTokenExchanger := registryauthazure.NewACRTokenExchanger(instrumentationProvider, &http.Client{}, acrTokenExchangerClientRetryPolicy, createCircuitBreaker(instrumentationProvider, acrTokenExchangerCircuitBreakerConfiguration, "ACRTokenExchanger"))
 	acrTokenProvider := registryauthazure.NewACRTokenProvider(instrumentationProvider, acrTokenExchanger, azureBearerAuthorizerTokenProvider, freeCacheInMemCacheClient, acrTokenProviderConfiguration)
+	reloadableConfigurations = append(reloadableConfigurations,
+		createRetryPolicyReloadableConfiguration("acr.tokenExchanger.retryPolicyConfiguration", acrTokenExchangerClientRetryPolicy),
+		&configreload.ReloadableConfiguration{
+			Key:              "acr.acrTokenProviderConfiguration",
+			NewConfiguration: func() interface{} { return new(acrauth.ACRTokenProviderConfiguration) },
+			Validate: func(configuration interface{}) error {
+				return validatePositiveInt(&utils.PositiveIntValidationObject{VariableName: "acrTokenProviderConfiguration.RegistryRefreshTokenCacheExpirationTime", Variable: configuration.(*acrauth.ACRTokenProviderConfiguration).RegistryRefreshTokenCacheExpirationTime})
+			},
+			Bind: func(getConfiguration func() interface{}) {
+				acrTokenProvider.SetConfigurationProvider(func() *acrauth.ACRTokenProviderConfiguration {
+					return getConfiguration().(*acrauth.ACRTokenProviderConfiguration)
+				})
+			},
+		},
+	)
 
 	k8sKeychainFactory := crane.NewK8SKeychainFactory(instrumentationProvider, clientK8s)
 	acrKeychainFactory := crane.NewACRKeychainFactory(instrumentationProvider, acrTokenProvider)
 
-	craneWrapperRetryPolicy := retrypolicy.NewRetryPolicy(instrumentationProvider, craneWrapperRetryPolicyConfiguration)
-	craneWrapper := registrywrappers.NewCraneWrapper(instrumentationProvider, craneWrapperRetryPolicy)
+	craneWrapperRetryPolicy := retrypolicy.NewRetryPolicy(instrumentationProvider, craneWrapperRetryPolicyConfiguration, "CraneWrapper")
+	craneWrapper := registrywrappers.NewCraneWrapper(instrumentationProvider, craneWrapperRetryPolicy, createCircuitBreaker(instrumentationProvider, craneWrapperCircuitBreakerConfiguration, "CraneWrapper"))
 	// Registry Client
-	registryClient := crane.NewCraneRegistryClient(instrumentationProvider, craneWrapper, acrKeychainFactory, k8sKeychainFactory)
-	tag2digestResolver := tag2digest.NewTag2DigestResolver(instrumentationProvider, registryClient, persistentCacheClient, tag2DigestResolverConfiguration)
+	registryTransportProvider, err := crane.NewRegistryTransportProvider(instrumentationProvider, registryTransportProviderConfiguration)
+	if err != nil {
+		log.Fatal("main.crane.NewRegistryTransportProvider", err)
+	}
+	registryClient := crane.NewCraneRegistryClient(instrumentationProvider, craneWrapper, acrKeychainFactory, k8sKeychainFactory, registryTransportProvider)
+	tag2digestResolver := tag2digest.NewTag2DigestResolver(instrumentationProvider, registryClient, persistentCacheClient, tag2DigestResolverConfiguration, coalescing.NewRequestCoalescer(instrumentationProvider, "Tag2DigestResolver"))
+	reloadableConfigurations = append(reloadableConfigurations,
+		createRetryPolicyReloadableConfiguration("acr.craneWrappersConfiguration.retryPolicyConfiguration", craneWrapperRetryPolicy),
+		&configreload.ReloadableConfiguration{
+			Key:              "tag2digest.tag2DigestResolverConfiguration",
+			NewConfiguration: func() interface{} { return new(tag2digest.Tag2DigestResolverConfiguration) },
+			Validate: func(configuration interface{}) error {
+				return validatePositiveInt(&utils.PositiveIntValidationObject{VariableName: "tag2DigestResolverConfiguration.CacheExpirationTimeForResults", Variable: configuration.(*tag2digest.Tag2DigestResolverConfiguration).CacheExpirationTimeForResults})
+			},
+			Bind: func(getConfiguration func() interface{}) {
+				tag2digestResolver.SetConfigurationProvider(func() *tag2digest.Tag2DigestResolverConfiguration {
+					return getConfiguration().(*tag2digest.Tag2DigestResolverConfiguration)
+				})
+			},
+		},
+	)
+
+	// Signature verifier - NoOp verifier in case that signature verification is disabled
+	var signatureVerifier signature.ISignatureVerifier = signature.NewNoOpSignatureVerifier()
+	if signatureVerifierConfiguration.Enabled {
+		verificationKeys, err := signature.LoadVerificationKeysFromDir(signatureVerifierConfiguration.VerificationKeysDirPath, signatureVerifierConfiguration.TransparencyLogKeysDirPath)
+		if err != nil {
+			log.Fatal("main.signature.LoadVerificationKeysFromDir", err)
+		}
+		signatureVerifier = signature.NewSignatureVerifier(instrumentationProvider, registryClient, verificationKeys)
+	}
+
+	// Artifacts discoverer - NoOp discoverer in case that artifacts discovery is disabled
+	var artifactsDiscoverer artifacts.IArtifactsDiscoverer = artifacts.NewNoOpArtifactsDiscoverer()
+	if artifactsDiscovererConfiguration.Enabled {
+		artifactsDiscoverer = artifacts.NewArtifactsDiscoverer(instrumentationProvider, registryClient, artifactsDiscovererConfiguration)
+	}
 
 	// ARG
 
@@ -217,40 +393,226 @@ func main() {
 	if err != nil {
 		log.Fatal("main.azdIdentityAuthorizerFactory.NewMSIEnvAzureAuthorizerFactory.CreateARMAuthorizer", err)
 	}
+	azdIdentityBearerAuthorizer, ok := azdIdentityAuthorizer.(azureauth.IBearerAuthorizer)
+	if !ok {
+		log.Fatal("main.azdIdentityAuthorizer.bearerAuthorizer type assertion")
+	}
+	azdIdentityTokenHealthCheck := createTokenHealthCheck("azdIdentityToken", azureauth.NewBearerAuthorizerTokenProvider(azdIdentityBearerAuthorizer), healthChecksConfiguration)
+	dependenciesHealthChecks = append(dependenciesHealthChecks, azdIdentityTokenHealthCheck)
 	argBaseClient, err := wrappers.NewArgBaseClientWrapper(argBaseClientRetryPolicyConfiguration, azdIdentityAuthorizer)
 	if err != nil {
 		log.Fatal("main.NewArgBaseClientWrapper", err)
 	}
 
-	argClientRetryPolicy := retrypolicy.NewRetryPolicy(instrumentationProvider, argBaseClientRetryPolicyConfiguration)
-	argClient := arg.NewARGClient(instrumentationProvider, argBaseClient, argClientConfiguration, argClientRetryPolicy)
+	argClientRetryPolicy := retrypolicy.NewRetryPolicy(instrumentationProvider, argBaseClientRetryPolicyConfiguration, "ARGClient")
+	argClient := arg.NewARGClient(instrumentationProvider, argBaseClient, argClientConfiguration, argClientRetryPolicy, createCircuitBreaker(instrumentationProvider, argBaseClientCircuitBreakerConfiguration, "ARGClient"))
 	argQueryGenerator, err := argqueries.CreateARGQueryGenerator(instrumentationProvider)
 	if err != nil {
 		log.Fatal("main.CreateARGQueryGenerator", err)
 	}
 	argDataProviderCacheClient := arg.NewARGDataProviderCacheClient(instrumentationProvider, persistentCacheClient, argDataProviderConfiguration)
-	argDataProvider := arg.NewARGDataProvider(instrumentationProvider, argClient, argQueryGenerator, argDataProviderCacheClient, argDataProviderConfiguration)
+	argDataProvider := arg.NewARGDataProvider(instrumentationProvider, argClient, argQueryGenerator, argDataProviderCacheClient, argDataProviderConfiguration, coalescing.NewRequestCoalescer(instrumentationProvider, "ARGDataProvider"))
+	reloadableConfigurations = append(reloadableConfigurations,
+		createRetryPolicyReloadableConfiguration("arg.argBaseClient.retryPolicyConfiguration", argClientRetryPolicy),
+		&configreload.ReloadableConfiguration{
+			Key:              "arg.argClientConfiguration",
+			NewConfiguration: func() interface{} { return new(arg.ARGClientConfiguration) },
+			Bind: func(getConfiguration func() interface{}) {
+				argClient.SetConfigurationProvider(func() *arg.ARGClientConfiguration { return getConfiguration().(*arg.ARGClientConfiguration) })
+			},
+		},
+		&configreload.ReloadableConfiguration{
+			Key:              "arg.argDataProviderConfiguration",
+			NewConfiguration: func() interface{} { return new(arg.ARGDataProviderConfiguration) },
+			Validate: func(configuration interface{}) error {
+				argDataProviderConfiguration := configuration.(*arg.ARGDataProviderConfiguration)
+				return validatePositiveInt(
+					&utils.PositiveIntValidationObject{VariableName: "argDataProviderConfiguration.CacheExpirationTimeScannedResults", Variable: argDataProviderConfiguration.CacheExpirationTimeScannedResults},
+					&utils.PositiveIntValidationObject{VariableName: "argDataProviderConfiguration.CacheExpirationTimeUnscannedResults", Variable: argDataProviderConfiguration.CacheExpirationTimeUnscannedResults},
+				)
+			},
+			Bind: func(getConfiguration func() interface{}) {
+				argDataProviderCacheClient.SetConfigurationProvider(func() *arg.ARGDataProviderConfiguration {
+					return getConfiguration().(*arg.ARGDataProviderConfiguration)
+				})
+			},
+		},
+	)
 
 	// Create Extractor
 	extractor := admisionrequest.NewExtractor(instrumentationProvider, extractorConfiguration)
+	reloadableConfigurations = append(reloadableConfigurations, &configreload.ReloadableConfiguration{
+		Key:              "webhook.extractorConfiguration",
+		NewConfiguration: func() interface{} { return new(admisionrequest.ExtractorConfiguration) },
+		Validate: func(configuration interface{}) error {
+			return configuration.(*admisionrequest.ExtractorConfiguration).Validate()
+		},
+		Bind: func(getConfiguration func() interface{}) {
+			extractor.SetConfigurationProvider(func() *admisionrequest.ExtractorConfiguration {
+				return getConfiguration().(*admisionrequest.ExtractorConfiguration)
+			})
+		},
+	})
 
 	// Handler and azdSecinfoProvider
 	azdSecInfoProviderCacheClient := azdsecinfo.NewAzdSecInfoProviderCacheClient(instrumentationProvider, persistentCacheClient, azdSecInfoProviderConfiguration)
-	azdSecInfoProvider := azdsecinfo.NewAzdSecInfoProvider(instrumentationProvider, argDataProvider, tag2digestResolver, getContainersVulnerabilityScanInfoTimeoutDuration, azdSecInfoProviderCacheClient)
-	handler := webhook.NewHandler(azdSecInfoProvider, handlerConfiguration, instrumentationProvider, extractor)
+	reloadableConfigurations = append(reloadableConfigurations, &configreload.ReloadableConfiguration{
+		Key:              "azdSecInfoProvider.azdSecInfoProviderConfiguration",
+		NewConfiguration: func() interface{} { return new(azdsecinfo.AzdSecInfoProviderConfiguration) },
+		Validate: func(configuration interface{}) error {
+			azdSecInfoProviderConfiguration := configuration.(*azdsecinfo.AzdSecInfoProviderConfiguration)
+			return validatePositiveInt(
+				&utils.PositiveIntValidationObject{VariableName: "azdSecInfoProviderConfiguration.CacheExpirationContainerVulnerabilityScanInfo", Variable: azdSecInfoProviderConfiguration.CacheExpirationContainerVulnerabilityScanInfo},
+				&utils.PositiveIntValidationObject{VariableName: "azdSecInfoProviderConfiguration.CacheExpirationTimeTimeout", Variable: azdSecInfoProviderConfiguration.CacheExpirationTimeTimeout},
+			)
+		},
+		Bind: func(getConfiguration func() interface{}) {
+			azdSecInfoProviderCacheClient.SetConfigurationProvider(func() *azdsecinfo.AzdSecInfoProviderConfiguration {
+				return getConfiguration().(*azdsecinfo.AzdSecInfoProviderConfiguration)
+			})
+		},
+	})
+	azdSecInfoProvider := azdsecinfo.NewAzdSecInfoProvider(instrumentationProvider, argDataProvider, tag2digestResolver, signatureVerifier, artifactsDiscoverer, getContainersVulnerabilityScanInfoTimeoutDuration, backgroundFetchTimeoutDuration, supplyChainLookupsTimeoutDuration, azdSecInfoProviderCacheClient, distributedLock)
+	// Decision logger - NoOp logger in case that the decision log is disabled
+	var decisionLogger decisionlog.IDecisionLogger = decisionlog.NewNoOpDecisionLogger()
+	if decisionLoggerConfiguration.Enabled {
+		decisionLogSink, err := decisionlog.CreateDecisionLogSink(decisionLoggerConfiguration.Sink, fileDecisionLogSinkConfiguration, httpDecisionLogSinkConfiguration)
+		if err != nil {
+			log.Fatal("main.decisionlog.CreateDecisionLogSink", err)
+		}
+		decisionLogger = decisionlog.NewDecisionLogger(instrumentationProvider, decisionLogSink, decisionLoggerConfiguration)
+	}
 
-	// Manager and server
+	// Manager - created before the handler, so the events are recorded by the manager's event recorder
 	managerFactory := webhook.NewManagerFactory(managerConfiguration, instrumentationProvider)
+	mgr, err := managerFactory.CreateManager()
+	if err != nil {
+		log.Fatal("main.managerFactory.CreateManager", err)
+	}
+
+	// Workload event emitter - NoOp emitter in case that the emission of events is disabled
+	var workloadEventEmitter events.IWorkloadEventEmitter = events.NewNoOpWorkloadEventEmitter()
+	if workloadEventEmitterConfiguration.Enabled {
+		workloadEventEmitter = events.NewWorkloadEventEmitter(instrumentationProvider, mgr.GetEventRecorderFor(events.EventRecorderName), freeCacheInMemCacheClient, workloadEventEmitterConfiguration)
+	}
+
+	// Vulnerability report writer - NoOp writer in case that the vulnerability reports are disabled
+	var vulnerabilityReportWriter vulnerabilityreport.IVulnerabilityReportWriter = vulnerabilityreport.NewNoOpVulnerabilityReportWriter()
+	if vulnerabilityReportWriterConfiguration.Enabled {
+		vulnerabilityReportRetryPolicy := retrypolicy.NewRetryPolicy(instrumentationProvider, vulnerabilityReportRetryPolicyConfiguration, "VulnerabilityReportWriter")
+		vulnerabilityReportWriter = vulnerabilityreport.NewVulnerabilityReportWriter(instrumentationProvider, mgr.GetClient(), vulnerabilityReportRetryPolicy)
+		reloadableConfigurations = append(reloadableConfigurations,
+			createRetryPolicyReloadableConfiguration("vulnerabilityReport.retryPolicyConfiguration", vulnerabilityReportRetryPolicy))
+	}
+	// Image drift detector - the pods are watched only in case that the drift detection is enabled
+	var imageDriftDetector *imagedrift.ImageDriftDetector
+	if imageDriftDetectorConfiguration.Enabled {
+		imageDriftDetector = imagedrift.NewImageDriftDetector(instrumentationProvider, mgr.GetClient(), mgr.GetEventRecorderFor(events.EventRecorderName), freeCacheInMemCacheClient, argDataProvider, &handlerConfiguration.ScanInfoAnnotationConfiguration, imageDriftDetectorConfiguration)
+		if err = imageDriftDetector.SetupWithManager(mgr); err != nil {
+			log.Fatal("main.imageDriftDetector.SetupWithManager", err)
+		}
+	}
+	// Owner scan info verifier - the owners of the pods are read from the API server (not from the manager's cache)
+	ownerPodTemplateScanInfoVerifier := annotations.NewOwnerPodTemplateScanInfoVerifier(instrumentationProvider, mgr.GetAPIReader())
+	handler := webhook.NewHandler(azdSecInfoProvider, handlerConfiguration, instrumentationProvider, extractor, decisionLogger, workloadEventEmitter, vulnerabilityReportWriter, ownerPodTemplateScanInfoVerifier)
+	reloadableConfigurations = append(reloadableConfigurations, &configreload.ReloadableConfiguration{
+		Key:              "webhook.handlerConfiguration",
+		NewConfiguration: func() interface{} { return new(webhook.HandlerConfiguration) },
+		Validate: func(configuration interface{}) error {
+			handlerConfiguration := configuration.(*webhook.HandlerConfiguration)
+			_, err := handlerConfiguration.ScanInfoAnnotationConfiguration.GetSchemaVersions()
+			return err
+		},
+		Bind: func(getConfiguration func() interface{}) {
+			handler.SetConfigurationProvider(func() *webhook.HandlerConfiguration { return getConfiguration().(*webhook.HandlerConfiguration) })
+			// The image drift detector patches the same scan info annotations as the handler, so it reads the reloaded configuration too
+			if imageDriftDetector != nil {
+				imageDriftDetector.SetScanInfoAnnotationConfigurationProvider(func() *annotations.ScanInfoAnnotationConfiguration {
+					return &getConfiguration().(*webhook.HandlerConfiguration).ScanInfoAnnotationConfiguration
+				})
+			}
+		},
+	})
+
+	// managerContext is done when the process is signaled to stop
+	managerContext := signals.SetupSignalHandler()
+
+	// Config reloader - applies the reloadable configurations when the mounted configuration file is changed
+	if configReloaderConfiguration.Enabled {
+		configReloader, err := configreload.NewConfigReloader(instrumentationProvider, mgr.GetEventRecorderFor(events.EventRecorderName), configFile, configReloaderConfiguration, deploymentInstance.GetNamespace(), reloadableConfigurations)
+		if err != nil {
+			log.Fatal("main.configreload.NewConfigReloader", err)
+		}
+		configReloader.ReloadEveryTick(managerContext, utils.GetSeconds(configReloaderConfiguration.PollingIntervalInSeconds))
+	}
+
+	// Server
 	certRotatorFactory := webhook.NewCertRotatorFactory(certRotatorConfiguration)
-	serverFactory := webhook.NewServerFactory(serverConfiguration, managerFactory, certRotatorFactory, handler, instrumentationProvider)
+	serverFactory := webhook.NewServerFactory(serverConfiguration, mgr, certRotatorFactory, handler, instrumentationProvider, dependenciesHealthChecks)
 
 	// Create Server
 	server, err := serverFactory.CreateServer()
 	if err != nil {
 		log.Fatal("main.serverFactory.CreateServer", err)
 	}
-	// Run server
-	if err = server.Run(); err != nil {
+	// Run server - until the process is signaled to stop
+	err = server.Run(managerContext)
+	// Write the decision records that weren't written yet
+	decisionLoggerCloseContext, cancelDecisionLoggerClose := context.WithTimeout(context.Background(), _decisionLoggerCloseTimeout)
+	if closeErr := decisionLogger.Close(decisionLoggerCloseContext); closeErr != nil {
+		log.Println("main.decisionLogger.Close", closeErr)
+	}
+	cancelDecisionLoggerClose()
+	// Export the spans that weren't exported yet
+	if shutdownErr := shutdownOTelTracerProvider(_otelContext); shutdownErr != nil {
+		log.Println("main.shutdownOTelTracerProvider", shutdownErr)
+	}
+	if err != nil {
 		log.Fatal("main.server.Run", err)
 	}
 }
+
+// createTokenHealthCheck creates health check of the identity's token acquisition - the token is acquired every
+// healthChecksConfiguration.TokenAcquisitionFrequencyInSeconds seconds and the readiness is gated on a recent successful acquisition.
+func createTokenHealthCheck(name string, tokenProvider azureauth.IBearerAuthorizerTokenProvider, healthChecksConfiguration *health.HealthChecksConfiguration) *health.StatusHealthCheck {
+	tokenHealthCheck := health.NewStatusHealthCheck(name, utils.GetSeconds(healthChecksConfiguration.TokenAcquisitionMaxAgeInSeconds))
+	tokenHealthCheck.RunEveryTick(utils.GetSeconds(healthChecksConfiguration.TokenAcquisitionFrequencyInSeconds), func() error {
+		_, err := tokenProvider.GetOAuthToken(_tokenHealthCheckContext)
+		return err
+	})
+	return tokenHealthCheck
+}
+
+// createCircuitBreaker creates the circuit breaker of the dependency - NoOp circuit breaker in case that it's disabled
+func createCircuitBreaker(instrumentationProvider instrumentation.IInstrumentationProvider, configuration *circuitbreaker.CircuitBreakerConfiguration, name string) circuitbreaker.ICircuitBreaker {
+	if !configuration.Enabled {
+		return circuitbreaker.NewNoOpCircuitBreaker()
+	}
+	return circuitbreaker.NewCircuitBreaker(instrumentationProvider, configuration, name)
+}
+
+// createRetryPolicyReloadableConfiguration creates reloadable configuration of the retry policy of the key
+func createRetryPolicyReloadableConfiguration(key string, retryPolicy *retrypolicy.RetryPolicy) *configreload.ReloadableConfiguration {
+	return &configreload.ReloadableConfiguration{
+		Key:              key,
+		NewConfiguration: func() interface{} { return new(retrypolicy.RetryPolicyConfiguration) },
+		Validate: func(configuration interface{}) error {
+			_, err := configuration.(*retrypolicy.RetryPolicyConfiguration).GetBackoffStrategy()
+			return err
+		},
+		Bind: func(getConfiguration func() interface{}) {
+			retryPolicy.SetConfigurationProvider(func() *retrypolicy.RetryPolicyConfiguration {
+				return getConfiguration().(*retrypolicy.RetryPolicyConfiguration)
+			})
+		},
+	}
+}
+
+// validatePositiveInt returns InvalidConfiguration error in case that one of the variables isn't positive
+func validatePositiveInt(positiveIntValidationObjects ...*utils.PositiveIntValidationObject) error {
+	isValidConfiguration, configurationName := utils.ValidatePositiveInt(positiveIntValidationObjects...)
+	if !isValidConfiguration {
+		return errors.Wrapf(utils.InvalidConfiguration, "got non-positive value. Only positive values are allowed. Configuration name: <%s>", configurationName)
+	}
+	return nil
+}
@@ -0,0 +1,249 @@
+package artifacts
+
+import (
+	"bytes"
+	"context"
+	artifactsmetric "github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/artifacts/metric"
+	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/azdsecinfo/contracts"
+	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/instrumentation"
+	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/instrumentation/metric"
+	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/instrumentation/metric/util"
+	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/instrumentation/trace"
+	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/registry"
+	registryerrors "github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/registry/errors"
+	registryutils "github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/registry/utils"
+	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/utils"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/pkg/errors"
+	"strings"
+)
+
+// IArtifactsDiscoverer responsible to discover the supply chain artifacts (SBOMs and attestations) of resolved image digests
+type IArtifactsDiscoverer interface {
+	// Discover receives an image reference, its resolved digest and the deployed resource auth context and returns
+	// the supply chain artifacts that are attached to the digest.
+	Discover(ctx context.Context, imageReference registry.IImageReference, digest string, authContext *registry.AuthContext) (*contracts.SupplyChainArtifacts, error)
+}
+
+// ArtifactsDiscoverer implements IArtifactsDiscoverer interface
+var _ IArtifactsDiscoverer = (*ArtifactsDiscoverer)(nil)
+
+// ArtifactsDiscoverer is registry based implementation of IArtifactsDiscoverer interface.
+// It discovers the artifacts that refer to the digest (OCI referrers) and the cosign attachments of the digest
+// using the registry client (same keychains that are used to resolve the digest).
+type ArtifactsDiscoverer struct {
+	//tracerProvider is tracer provider of ArtifactsDiscoverer
+	tracerProvider trace.ITracerProvider
+	//metricSubmitter is metric submitter of ArtifactsDiscoverer
+	metricSubmitter metric.IMetricSubmitter
+	// registryClient is the client of the registry which is used to fetch the artifacts
+	registryClient registry.IRegistryClient
+	// configuration is the configuration of ArtifactsDiscoverer
+	configuration *ArtifactsDiscovererConfiguration
+}
+
+// ArtifactsDiscovererConfiguration is configuration data for ArtifactsDiscoverer
+type ArtifactsDiscovererConfiguration struct {
+	// Enabled is whether the supply chain artifacts of the images should be discovered
+	Enabled bool
+	// ParseSBOM is whether the discovered SBOMs should be fetched and parsed in order to report the OS distro of the image
+	ParseSBOM bool
+}
+
+// NewArtifactsDiscoverer Ctor
+func NewArtifactsDiscoverer(instrumentationProvider instrumentation.IInstrumentationProvider, registryClient registry.IRegistryClient, configuration *ArtifactsDiscovererConfiguration) *ArtifactsDiscoverer {
+	return &ArtifactsDiscoverer{
+		tracerProvider:  instrumentationProvider.GetTracerProvider("ArtifactsDiscoverer"),
+		metricSubmitter: instrumentationProvider.GetMetricSubmitter(),
+		registryClient:  registryClient,
+		configuration:   configuration,
+	}
+}
+
+// Discover receives an image reference, its resolved digest and the deployed resource auth context and returns
+// the supply chain artifacts that are attached to the digest:
+// 1. The OCI referrers of the digest.
+// 2. The cosign SBOM and attestation attachments of the digest (sha256-<hex>.sbom and sha256-<hex>.att).
+// 3. The base image of the digest from its manifest annotations.
+// If ParseSBOM is enabled, the first JSON SBOM that reports an operating system is used to set the OS distro.
+func (discoverer *ArtifactsDiscoverer) Discover(ctx context.Context, imageReference registry.IImageReference, digest string, authContext *registry.AuthContext) (*contracts.SupplyChainArtifacts, error) {
+	tracer := discoverer.tracerProvider.GetTracer("Discover")
+	tracer.Info("Received:", "imageReference", imageReference, "digest", digest, "authContext", authContext)
+
+	// Argument validation
+	if imageReference == nil || authContext == nil || digest == "" {
+		err := errors.Wrap(utils.NilArgumentError, "ArtifactsDiscoverer.Discover")
+		tracer.Error(err, "")
+		discoverer.metricSubmitter.SendMetric(1, util.NewErrorEncounteredMetric(err, "ArtifactsDiscoverer.Discover"))
+		return nil, err
+	}
+
+	artifacts := &contracts.SupplyChainArtifacts{
+		SBOMs:        []*contracts.SBOM{},
+		Attestations: []*contracts.Attestation{},
+	}
+
+	if err := discoverer.discoverReferrers(ctx, imageReference, digest, authContext, artifacts); err != nil {
+		err = errors.Wrap(err, "ArtifactsDiscoverer.Discover failed to discover referrers")
+		tracer.Error(err, "")
+		discoverer.metricSubmitter.SendMetric(1, util.NewErrorEncounteredMetric(err, "ArtifactsDiscoverer.Discover"))
+		return nil, err
+	}
+
+	if err := discoverer.discoverCosignAttachments(ctx, imageReference, digest, authContext, artifacts); err != nil {
+		err = errors.Wrap(err, "ArtifactsDiscoverer.Discover failed to discover cosign attachments")
+		tracer.Error(err, "")
+		discoverer.metricSubmitter.SendMetric(1, util.NewErrorEncounteredMetric(err, "ArtifactsDiscoverer.Discover"))
+		return nil, err
+	}
+
+	baseImage, err := discoverer.getBaseImage(ctx, imageReference, digest, authContext)
+	if err != nil {
+		err = errors.Wrap(err, "ArtifactsDiscoverer.Discover failed to get base image")
+		tracer.Error(err, "")
+		discoverer.metricSubmitter.SendMetric(1, util.NewErrorEncounteredMetric(err, "ArtifactsDiscoverer.Discover"))
+		return nil, err
+	}
+	artifacts.BaseImage = baseImage
+
+	tracer.Info("Artifacts discovery finished", "imageReference", imageReference, "digest", digest, "numOfSBOMs", len(artifacts.SBOMs), "numOfAttestations", len(artifacts.Attestations), "osDistro", artifacts.OSDistro, "baseImage", artifacts.BaseImage)
+	discoverer.metricSubmitter.SendMetric(1, artifactsmetric.NewSupplyChainArtifactsMetric(len(artifacts.SBOMs) > 0, len(artifacts.Attestations) > 0))
+	return artifacts, nil
+}
+
+// discoverReferrers lists the OCI referrers of the digest and adds the SBOMs and attestations to the artifacts.
+// Referrers that don't report their artifact type are classified by the config media type of their manifest.
+func (discoverer *ArtifactsDiscoverer) discoverReferrers(ctx context.Context, imageReference registry.IImageReference, digest string, authContext *registry.AuthContext, artifacts *contracts.SupplyChainArtifacts) error {
+	tracer := discoverer.tracerProvider.GetTracer("discoverReferrers")
+
+	referrers, err := discoverer.registryClient.ListReferrers(ctx, imageReference, digest, authContext)
+	if err != nil {
+		return errors.Wrap(err, "failed to list referrers")
+	}
+
+	for _, referrer := range referrers {
+		artifactType := referrer.ArtifactType
+		var manifest *v1.Manifest
+		if artifactType == "" {
+			manifest, err = discoverer.getManifest(ctx, registryutils.GetDigestReferenceInRepository(imageReference, referrer.Digest), authContext)
+			if err != nil {
+				return errors.Wrapf(err, "failed to get manifest of referrer <%s>", referrer.Digest)
+			}
+			artifactType = string(manifest.Config.MediaType)
+		}
+
+		if format, isSBOM := getSBOMFormat(artifactType); isSBOM {
+			artifacts.SBOMs = append(artifacts.SBOMs, &contracts.SBOM{Format: format, Digest: referrer.Digest})
+			if discoverer.shouldParseSBOM(artifacts) {
+				if manifest == nil {
+					manifest, err = discoverer.getManifest(ctx, registryutils.GetDigestReferenceInRepository(imageReference, referrer.Digest), authContext)
+					if err != nil {
+						return errors.Wrapf(err, "failed to get manifest of referrer <%s>", referrer.Digest)
+					}
+				}
+				for _, layer := range manifest.Layers {
+					discoverer.setOSDistro(ctx, imageReference, layer, format, authContext, artifacts)
+				}
+			}
+		} else if isAttestationMediaType(artifactType) {
+			artifacts.Attestations = append(artifacts.Attestations, &contracts.Attestation{PredicateType: referrer.Annotations[_inTotoPredicateTypeAnnotationKey], Digest: referrer.Digest})
+		} else {
+			tracer.Info("Skipping referrer of unsupported artifact type", "referrer", referrer.Digest, "artifactType", artifactType)
+		}
+	}
+	return nil
+}
+
+// discoverCosignAttachments fetches the cosign SBOM and attestation attachment images of the digest and adds their layers to the artifacts.
+func (discoverer *ArtifactsDiscoverer) discoverCosignAttachments(ctx context.Context, imageReference registry.IImageReference, digest string, authContext *registry.AuthContext, artifacts *contracts.SupplyChainArtifacts) error {
+	sbomManifest, err := discoverer.getCosignAttachmentManifest(ctx, imageReference, digest, _cosignSBOMTagSuffix, authContext)
+	if err != nil {
+		return errors.Wrap(err, "failed to get cosign SBOM attachment")
+	}
+	if sbomManifest != nil {
+		for _, layer := range sbomManifest.Layers {
+			format, isSBOM := getSBOMFormat(string(layer.MediaType))
+			if !isSBOM {
+				continue
+			}
+			artifacts.SBOMs = append(artifacts.SBOMs, &contracts.SBOM{Format: format, Digest: layer.Digest.String()})
+			if discoverer.shouldParseSBOM(artifacts) {
+				discoverer.setOSDistro(ctx, imageReference, layer, format, authContext, artifacts)
+			}
+		}
+	}
+
+	attestationManifest, err := discoverer.getCosignAttachmentManifest(ctx, imageReference, digest, _cosignAttestationTagSuffix, authContext)
+	if err != nil {
+		return errors.Wrap(err, "failed to get cosign attestation attachment")
+	}
+	if attestationManifest != nil {
+		for _, layer := range attestationManifest.Layers {
+			artifacts.Attestations = append(artifacts.Attestations, &contracts.Attestation{PredicateType: layer.Annotations[_cosignPredicateTypeAnnotationKey], Digest: layer.Digest.String()})
+		}
+	}
+	return nil
+}
+
+// getCosignAttachmentManifest returns the manifest of the cosign attachment image of the digest with the given tag suffix.
+// Returns nil in case that the attachment image doesn't exist.
+func (discoverer *ArtifactsDiscoverer) getCosignAttachmentManifest(ctx context.Context, imageReference registry.IImageReference, digest string, tagSuffix string, authContext *registry.AuthContext) (*v1.Manifest, error) {
+	tag := strings.Replace(digest, ":", "-", 1) + tagSuffix
+	manifest, err := discoverer.getManifest(ctx, registryutils.GetTagReferenceInRepository(imageReference, tag), authContext)
+	if err != nil {
+		if _, isNotFound := errors.Cause(err).(*registryerrors.ImageIsNotFoundErr); isNotFound {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return manifest, nil
+}
+
+// getBaseImage returns the base image name annotation of the digest manifest (empty if it isn't annotated)
+func (discoverer *ArtifactsDiscoverer) getBaseImage(ctx context.Context, imageReference registry.IImageReference, digest string, authContext *registry.AuthContext) (string, error) {
+	manifest, err := discoverer.getManifest(ctx, registryutils.GetDigestReferenceInRepository(imageReference, digest), authContext)
+	if err != nil {
+		return "", err
+	}
+	return manifest.Annotations[_baseImageNameAnnotationKey], nil
+}
+
+// getManifest gets and parses the manifest of the image reference
+func (discoverer *ArtifactsDiscoverer) getManifest(ctx context.Context, imageReference registry.IImageReference, authContext *registry.AuthContext) (*v1.Manifest, error) {
+	manifestBytes, err := discoverer.registryClient.GetManifest(ctx, imageReference, authContext)
+	if err != nil {
+		return nil, err
+	}
+	manifest, err := v1.ParseManifest(bytes.NewReader(manifestBytes))
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to parse manifest of <%s>", imageReference.Original())
+	}
+	return manifest, nil
+}
+
+// shouldParseSBOM returns whether the next SBOM should be parsed - only if ParseSBOM is enabled and the OS distro wasn't found yet.
+func (discoverer *ArtifactsDiscoverer) shouldParseSBOM(artifacts *contracts.SupplyChainArtifacts) bool {
+	return discoverer.configuration.ParseSBOM && artifacts.OSDistro == ""
+}
+
+// setOSDistro fetches the SBOM layer and sets the OS distro that it reports on the artifacts.
+// SBOM parsing is best effort - failures are traced and don't fail the discovery.
+func (discoverer *ArtifactsDiscoverer) setOSDistro(ctx context.Context, imageReference registry.IImageReference, layer v1.Descriptor, format contracts.SBOMFormat, authContext *registry.AuthContext, artifacts *contracts.SupplyChainArtifacts) {
+	tracer := discoverer.tracerProvider.GetTracer("setOSDistro")
+	if artifacts.OSDistro != "" || !isJSONMediaType(string(layer.MediaType)) {
+		return
+	}
+
+	sbom, err := discoverer.registryClient.GetBlob(ctx, imageReference, layer.Digest.String(), authContext)
+	if err != nil {
+		tracer.Error(errors.Wrapf(err, "failed to get SBOM <%s> - skipping it", layer.Digest.String()), "")
+		return
+	}
+
+	osDistro, err := parseOSDistro(format, sbom)
+	if err != nil {
+		tracer.Error(errors.Wrapf(err, "failed to parse SBOM <%s> - skipping it", layer.Digest.String()), "")
+		return
+	}
+	artifacts.OSDistro = osDistro
+}
@@ -0,0 +1,205 @@
+package artifacts
+
+import (
+	"context"
+	"fmt"
+	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/azdsecinfo/contracts"
+	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/circuitbreaker"
+	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/instrumentation"
+	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/registry"
+	registrycrane "github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/registry/crane"
+	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/registry/crane/mocks"
+	registrymocks "github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/registry/mocks"
+	registryutils "github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/registry/utils"
+	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/registry/wrappers"
+	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/retrypolicy"
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/crane"
+	"github.com/google/go-containerregistry/pkg/name"
+	ggcrregistry "github.com/google/go-containerregistry/pkg/registry"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/google/go-containerregistry/pkg/v1/static"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+const (
+	_repository         = "test/app"
+	_baseImage          = "docker.io/library/alpine:3.15"
+	_spdxMediaType      = "application/spdx+json"
+	_cycloneDXMediaType = "application/vnd.cyclonedx+json"
+	_dsseMediaType      = "application/vnd.dsse.envelope.v1+json"
+	_inTotoMediaType    = "application/vnd.in-toto+json"
+	_slsaPredicateType  = "https://slsa.dev/provenance/v0.2"
+	_spdxSBOM           = `{"spdxVersion":"SPDX-2.3","packages":[{"name":"musl","versionInfo":"1.2.2"},{"name":"alpine","versionInfo":"3.15.0","primaryPackagePurpose":"OPERATING-SYSTEM"}]}`
+	_cycloneDXSBOM      = `{"bomFormat":"CycloneDX","components":[{"type":"library","name":"musl","version":"1.2.2"},{"type":"operating-system","name":"debian","version":"11"}]}`
+)
+
+type ArtifactsDiscovererTestSuite struct {
+	suite.Suite
+	server         *httptest.Server
+	registryHost   string
+	imageReference registry.IImageReference
+	digest         string
+	authContext    *registry.AuthContext
+	discoverer     *ArtifactsDiscoverer
+}
+
+// This will run before each test in the suite - creates a local registry with a pushed image that is annotated with its base image.
+func (suite *ArtifactsDiscovererTestSuite) SetupTest() {
+	suite.server = httptest.NewServer(ggcrregistry.New())
+	suite.registryHost = strings.TrimPrefix(suite.server.URL, "http://")
+
+	image, err := random.Image(1024, 1)
+	suite.Nil(err)
+	image = mutate.Annotations(image, map[string]string{_baseImageNameAnnotationKey: _baseImage}).(v1.Image)
+	original := fmt.Sprintf("%s/%s:v1", suite.registryHost, _repository)
+	suite.Nil(crane.Push(image, original))
+	imageDigest, err := image.Digest()
+	suite.Nil(err)
+	suite.digest = imageDigest.String()
+	suite.imageReference = registry.NewTag(original, suite.registryHost, _repository, "v1")
+	suite.authContext = registry.NewAuthContext("default", []string{}, "")
+
+	suite.discoverer = suite.createDiscoverer(&ArtifactsDiscovererConfiguration{Enabled: true, ParseSBOM: true})
+}
+
+func (suite *ArtifactsDiscovererTestSuite) TearDownTest() {
+	suite.server.Close()
+}
+
+func (suite *ArtifactsDiscovererTestSuite) Test_Discover_NoArtifacts_EmptyArtifactsWithBaseImage() {
+	artifacts, err := suite.discoverer.Discover(context.Background(), suite.imageReference, suite.digest, suite.authContext)
+
+	suite.Nil(err)
+	suite.Equal(&contracts.SupplyChainArtifacts{
+		SBOMs:        []*contracts.SBOM{},
+		Attestations: []*contracts.Attestation{},
+		BaseImage:    _baseImage,
+	}, artifacts)
+}
+
+func (suite *ArtifactsDiscovererTestSuite) Test_Discover_Referrers_SBOMAndAttestationDiscovered() {
+	sbomDigest, attestationDigest := suite.pushReferrers()
+
+	artifacts, err := suite.discoverer.Discover(context.Background(), suite.imageReference, suite.digest, suite.authContext)
+
+	suite.Nil(err)
+	suite.Equal(&contracts.SupplyChainArtifacts{
+		SBOMs:        []*contracts.SBOM{{Format: contracts.SPDX, Digest: sbomDigest}},
+		Attestations: []*contracts.Attestation{{PredicateType: _slsaPredicateType, Digest: attestationDigest}},
+		OSDistro:     "alpine 3.15.0",
+		BaseImage:    _baseImage,
+	}, artifacts)
+}
+
+func (suite *ArtifactsDiscovererTestSuite) Test_Discover_ParseSBOMDisabled_OSDistroIsEmpty() {
+	sbomDigest, _ := suite.pushReferrers()
+	discoverer := suite.createDiscoverer(&ArtifactsDiscovererConfiguration{Enabled: true, ParseSBOM: false})
+
+	artifacts, err := discoverer.Discover(context.Background(), suite.imageReference, suite.digest, suite.authContext)
+
+	suite.Nil(err)
+	suite.Equal([]*contracts.SBOM{{Format: contracts.SPDX, Digest: sbomDigest}}, artifacts.SBOMs)
+	suite.Equal("", artifacts.OSDistro)
+}
+
+func (suite *ArtifactsDiscovererTestSuite) Test_Discover_CosignAttachments_SBOMAndAttestationDiscovered() {
+	sbomLayer := static.NewLayer([]byte(_cycloneDXSBOM), _cycloneDXMediaType)
+	sbomLayerDigest, err := sbomLayer.Digest()
+	suite.Nil(err)
+	suite.pushCosignAttachment(_cosignSBOMTagSuffix, mutate.Addendum{Layer: sbomLayer})
+
+	attestationLayer := static.NewLayer([]byte(`{"payloadType":"application/vnd.in-toto+json"}`), _dsseMediaType)
+	attestationLayerDigest, err := attestationLayer.Digest()
+	suite.Nil(err)
+	suite.pushCosignAttachment(_cosignAttestationTagSuffix, mutate.Addendum{Layer: attestationLayer, Annotations: map[string]string{_cosignPredicateTypeAnnotationKey: _slsaPredicateType}})
+
+	artifacts, err := suite.discoverer.Discover(context.Background(), suite.imageReference, suite.digest, suite.authContext)
+
+	suite.Nil(err)
+	suite.Equal(&contracts.SupplyChainArtifacts{
+		SBOMs:        []*contracts.SBOM{{Format: contracts.CycloneDX, Digest: sbomLayerDigest.String()}},
+		Attestations: []*contracts.Attestation{{PredicateType: _slsaPredicateType, Digest: attestationLayerDigest.String()}},
+		OSDistro:     "debian 11",
+		BaseImage:    _baseImage,
+	}, artifacts)
+}
+
+func (suite *ArtifactsDiscovererTestSuite) Test_Discover_RegistryError_Error() {
+	registryClientMock := new(registrymocks.IRegistryClient)
+	registryClientMock.On("ListReferrers", mock.Anything, suite.imageReference, suite.digest, suite.authContext).Return(nil, errors.New("registry error")).Once()
+	discoverer := NewArtifactsDiscoverer(instrumentation.NewNoOpInstrumentationProvider(), registryClientMock, &ArtifactsDiscovererConfiguration{Enabled: true})
+
+	artifacts, err := discoverer.Discover(context.Background(), suite.imageReference, suite.digest, suite.authContext)
+
+	suite.NotNil(err)
+	suite.Nil(artifacts)
+	registryClientMock.AssertExpectations(suite.T())
+}
+
+func (suite *ArtifactsDiscovererTestSuite) Test_Discover_NilArgument_Error() {
+	artifacts, err := suite.discoverer.Discover(context.Background(), suite.imageReference, suite.digest, nil)
+
+	suite.NotNil(err)
+	suite.Nil(artifacts)
+}
+
+// createDiscoverer creates a discoverer that uses crane registry client against the local registry.
+func (suite *ArtifactsDiscovererTestSuite) createDiscoverer(configuration *ArtifactsDiscovererConfiguration) *ArtifactsDiscoverer {
+	instrumentationProvider := instrumentation.NewNoOpInstrumentationProvider()
+	retryPolicy := retrypolicy.NewRetryPolicy(instrumentationProvider, &retrypolicy.RetryPolicyConfiguration{RetryAttempts: 1, RetryDurationInMS: 1}, "CraneWrapper")
+	k8sKeychainFactoryMock := new(mocks.IK8SKeychainFactory)
+	k8sKeychainFactoryMock.On("Create", mock.Anything, mock.Anything, mock.Anything).Return(authn.DefaultKeychain, nil)
+	transportProvider, err := registrycrane.NewRegistryTransportProvider(instrumentationProvider, &registrycrane.RegistryTransportProviderConfiguration{})
+	suite.Nil(err)
+	registryClient := registrycrane.NewCraneRegistryClient(instrumentationProvider, wrappers.NewCraneWrapper(instrumentationProvider, retryPolicy, circuitbreaker.NewNoOpCircuitBreaker()), new(mocks.IACRKeychainFactory), k8sKeychainFactoryMock, transportProvider)
+	return NewArtifactsDiscoverer(instrumentationProvider, registryClient, configuration)
+}
+
+// pushReferrers pushes SPDX SBOM and attestation artifacts to the local registry as referrers of the digest
+// (using the referrers tag schema) and returns their digests.
+func (suite *ArtifactsDiscovererTestSuite) pushReferrers() (string, string) {
+	sbom, err := mutate.Append(empty.Image, mutate.Addendum{Layer: static.NewLayer([]byte(_spdxSBOM), _spdxMediaType)})
+	suite.Nil(err)
+	sbom = mutate.ConfigMediaType(sbom, _spdxMediaType)
+	sbomDigest, err := sbom.Digest()
+	suite.Nil(err)
+
+	attestation, err := mutate.Append(empty.Image, mutate.Addendum{Layer: static.NewLayer([]byte(`{}`), _inTotoMediaType)})
+	suite.Nil(err)
+	attestation = mutate.ConfigMediaType(attestation, _inTotoMediaType)
+	attestationDigest, err := attestation.Digest()
+	suite.Nil(err)
+
+	index := mutate.AppendManifests(mutate.IndexMediaType(empty.Index, types.OCIImageIndex),
+		mutate.IndexAddendum{Add: sbom},
+		mutate.IndexAddendum{Add: attestation, Descriptor: v1.Descriptor{Annotations: map[string]string{_inTotoPredicateTypeAnnotationKey: _slsaPredicateType}}},
+	)
+	referrersTag := registryutils.GetTagReferenceInRepository(suite.imageReference, strings.Replace(suite.digest, ":", "-", 1))
+	ref, err := name.NewTag(referrersTag.Original())
+	suite.Nil(err)
+	suite.Nil(remote.WriteIndex(ref, index))
+	return sbomDigest.String(), attestationDigest.String()
+}
+
+// pushCosignAttachment pushes cosign attachment image of the digest with the given tag suffix to the local registry
+func (suite *ArtifactsDiscovererTestSuite) pushCosignAttachment(tagSuffix string, addendum mutate.Addendum) {
+	attachment, err := mutate.Append(empty.Image, addendum)
+	suite.Nil(err)
+	tag := strings.Replace(suite.digest, ":", "-", 1) + tagSuffix
+	suite.Nil(crane.Push(attachment, registryutils.GetTagReferenceInRepository(suite.imageReference, tag).Original()))
+}
+
+func TestArtifactsDiscovererTestSuite(t *testing.T) {
+	suite.Run(t, new(ArtifactsDiscovererTestSuite))
+}
@@ -0,0 +1,51 @@
+package artifacts
+
+import (
+	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/azdsecinfo/contracts"
+	"strings"
+)
+
+// Cosign attaches SBOMs and attestations as OCI images in the repository of the image, tagged by the digest of the image
+// (e.g. sha256-<hex>.sbom and sha256-<hex>.att). Each layer of the attachment image is a single SBOM or attestation.
+// See https://github.com/sigstore/cosign/blob/main/specs/ATTESTATION_SPEC.md
+const (
+	// _cosignSBOMTagSuffix is the suffix of the tag of the cosign SBOM attachment image
+	_cosignSBOMTagSuffix = ".sbom"
+	// _cosignAttestationTagSuffix is the suffix of the tag of the cosign attestation attachment image
+	_cosignAttestationTagSuffix = ".att"
+	// _cosignPredicateTypeAnnotationKey is the layer annotation key of the predicate type of cosign attestation
+	_cosignPredicateTypeAnnotationKey = "predicateType"
+)
+
+const (
+	// _inTotoPredicateTypeAnnotationKey is the annotation key of the predicate type of OCI referrer attestations
+	_inTotoPredicateTypeAnnotationKey = "in-toto.io/predicate-type"
+	// _baseImageNameAnnotationKey is the OCI image manifest annotation key of the base image name
+	_baseImageNameAnnotationKey = "org.opencontainers.image.base.name"
+)
+
+// getSBOMFormat returns the SBOM format of the artifact media type (e.g. "application/spdx+json", "application/vnd.cyclonedx+json")
+// and whether the media type is of an SBOM
+func getSBOMFormat(mediaType string) (contracts.SBOMFormat, bool) {
+	mediaType = strings.ToLower(mediaType)
+	switch {
+	case strings.Contains(mediaType, "spdx"):
+		return contracts.SPDX, true
+	case strings.Contains(mediaType, "cyclonedx"):
+		return contracts.CycloneDX, true
+	default:
+		return "", false
+	}
+}
+
+// isAttestationMediaType returns whether the artifact media type is of an in-toto attestation
+// (e.g. "application/vnd.in-toto+json", "application/vnd.dsse.envelope.v1+json")
+func isAttestationMediaType(mediaType string) bool {
+	mediaType = strings.ToLower(mediaType)
+	return strings.Contains(mediaType, "in-toto") || strings.Contains(mediaType, "dsse")
+}
+
+// isJSONMediaType returns whether the artifact media type is JSON encoded
+func isJSONMediaType(mediaType string) bool {
+	return strings.HasSuffix(strings.ToLower(mediaType), "json")
+}
@@ -0,0 +1,36 @@
+package metric
+
+import (
+	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/instrumentation/metric"
+	"strconv"
+)
+
+// SupplyChainArtifactsMetric implements metric.IMetric interface
+var _ metric.IMetric = (*SupplyChainArtifactsMetric)(nil)
+
+// SupplyChainArtifactsMetric is metric of ArtifactsDiscoverer to report whether each discovered digest has SBOMs and attestations
+type SupplyChainArtifactsMetric struct {
+	// hasSBOM is whether at least one SBOM is attached to the digest
+	hasSBOM bool
+	// hasAttestation is whether at least one attestation is attached to the digest
+	hasAttestation bool
+}
+
+// NewSupplyChainArtifactsMetric Ctor for SupplyChainArtifactsMetric
+func NewSupplyChainArtifactsMetric(hasSBOM bool, hasAttestation bool) *SupplyChainArtifactsMetric {
+	return &SupplyChainArtifactsMetric{
+		hasSBOM:        hasSBOM,
+		hasAttestation: hasAttestation,
+	}
+}
+
+func (m *SupplyChainArtifactsMetric) MetricName() string {
+	return "SupplyChainArtifacts"
+}
+
+func (m *SupplyChainArtifactsMetric) MetricDimension() []metric.Dimension {
+	return []metric.Dimension{
+		{Key: "HasSBOM", Value: strconv.FormatBool(m.hasSBOM)},
+		{Key: "HasAttestation", Value: strconv.FormatBool(m.hasAttestation)},
+	}
+}
@@ -0,0 +1,25 @@
+package artifacts
+
+import (
+	"context"
+	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/azdsecinfo/contracts"
+	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/registry"
+)
+
+// NoOpArtifactsDiscoverer implements IArtifactsDiscoverer interface
+var _ IArtifactsDiscoverer = (*NoOpArtifactsDiscoverer)(nil)
+
+// NoOpArtifactsDiscoverer is implementation that does nothing of IArtifactsDiscoverer.
+// It is used when artifacts discovery is disabled - the returned artifacts are nil, so they're omitted from the annotation.
+type NoOpArtifactsDiscoverer struct {
+}
+
+// NewNoOpArtifactsDiscoverer Ctor for NoOpArtifactsDiscoverer
+func NewNoOpArtifactsDiscoverer() *NoOpArtifactsDiscoverer {
+	return &NoOpArtifactsDiscoverer{}
+}
+
+// Discover returns nil artifacts
+func (discoverer *NoOpArtifactsDiscoverer) Discover(ctx context.Context, imageReference registry.IImageReference, digest string, authContext *registry.AuthContext) (*contracts.SupplyChainArtifacts, error) {
+	return nil, nil
+}
@@ -0,0 +1,85 @@
+package artifacts
+
+import (
+	"encoding/json"
+	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/azdsecinfo/contracts"
+	"github.com/pkg/errors"
+	"strings"
+)
+
+const (
+	// _spdxOperatingSystemPurpose is the SPDX primary package purpose of the operating system package
+	_spdxOperatingSystemPurpose = "OPERATING-SYSTEM"
+	// _cycloneDXOperatingSystemType is the CycloneDX component type of the operating system component
+	_cycloneDXOperatingSystemType = "operating-system"
+)
+
+// spdxDocument is the subset of SPDX JSON document that is needed to find the OS distro
+type spdxDocument struct {
+	Packages []spdxPackage `json:"packages"`
+}
+
+// spdxPackage is the subset of SPDX JSON package
+type spdxPackage struct {
+	Name                  string `json:"name"`
+	VersionInfo           string `json:"versionInfo"`
+	PrimaryPackagePurpose string `json:"primaryPackagePurpose"`
+}
+
+// cycloneDXDocument is the subset of CycloneDX JSON document that is needed to find the OS distro
+type cycloneDXDocument struct {
+	Metadata   cycloneDXMetadata    `json:"metadata"`
+	Components []cycloneDXComponent `json:"components"`
+}
+
+// cycloneDXMetadata is the subset of CycloneDX JSON metadata
+type cycloneDXMetadata struct {
+	Component *cycloneDXComponent `json:"component"`
+}
+
+// cycloneDXComponent is the subset of CycloneDX JSON component
+type cycloneDXComponent struct {
+	Type    string `json:"type"`
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+// parseOSDistro parses JSON encoded SBOM of the given format and returns the OS distro that it reports (e.g. "alpine 3.15.0").
+// Returns empty string in case that the SBOM doesn't report an operating system.
+func parseOSDistro(format contracts.SBOMFormat, sbom []byte) (string, error) {
+	switch format {
+	case contracts.SPDX:
+		document := &spdxDocument{}
+		if err := json.Unmarshal(sbom, document); err != nil {
+			return "", errors.Wrap(err, "failed to unmarshal SPDX document")
+		}
+		for _, spdxPackage := range document.Packages {
+			if spdxPackage.PrimaryPackagePurpose == _spdxOperatingSystemPurpose {
+				return formatOSDistro(spdxPackage.Name, spdxPackage.VersionInfo), nil
+			}
+		}
+		return "", nil
+	case contracts.CycloneDX:
+		document := &cycloneDXDocument{}
+		if err := json.Unmarshal(sbom, document); err != nil {
+			return "", errors.Wrap(err, "failed to unmarshal CycloneDX document")
+		}
+		components := document.Components
+		if document.Metadata.Component != nil {
+			components = append(components, *document.Metadata.Component)
+		}
+		for _, component := range components {
+			if component.Type == _cycloneDXOperatingSystemType {
+				return formatOSDistro(component.Name, component.Version), nil
+			}
+		}
+		return "", nil
+	default:
+		return "", errors.Errorf("unsupported SBOM format <%s>", format)
+	}
+}
+
+// formatOSDistro returns "<name> <version>" of the OS distro
+func formatOSDistro(name string, version string) string {
+	return strings.TrimSpace(name + " " + version)
+}
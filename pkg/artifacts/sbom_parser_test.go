@@ -0,0 +1,50 @@
+package artifacts
+
+import (
+	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/azdsecinfo/contracts"
+	"github.com/stretchr/testify/suite"
+	"testing"
+)
+
+type SBOMParserTestSuite struct {
+	suite.Suite
+}
+
+func (suite *SBOMParserTestSuite) Test_parseOSDistro_SPDXWithOperatingSystemPackage_OSDistro() {
+	osDistro, err := parseOSDistro(contracts.SPDX, []byte(_spdxSBOM))
+
+	suite.Nil(err)
+	suite.Equal("alpine 3.15.0", osDistro)
+}
+
+func (suite *SBOMParserTestSuite) Test_parseOSDistro_CycloneDXWithOperatingSystemComponent_OSDistro() {
+	osDistro, err := parseOSDistro(contracts.CycloneDX, []byte(_cycloneDXSBOM))
+
+	suite.Nil(err)
+	suite.Equal("debian 11", osDistro)
+}
+
+func (suite *SBOMParserTestSuite) Test_parseOSDistro_CycloneDXWithOperatingSystemMetadataComponent_OSDistro() {
+	osDistro, err := parseOSDistro(contracts.CycloneDX, []byte(`{"metadata":{"component":{"type":"operating-system","name":"ubuntu","version":"20.04"}}}`))
+
+	suite.Nil(err)
+	suite.Equal("ubuntu 20.04", osDistro)
+}
+
+func (suite *SBOMParserTestSuite) Test_parseOSDistro_NoOperatingSystem_Empty() {
+	osDistro, err := parseOSDistro(contracts.SPDX, []byte(`{"packages":[{"name":"musl","versionInfo":"1.2.2"}]}`))
+
+	suite.Nil(err)
+	suite.Equal("", osDistro)
+}
+
+func (suite *SBOMParserTestSuite) Test_parseOSDistro_InvalidJSON_Error() {
+	osDistro, err := parseOSDistro(contracts.SPDX, []byte(`SPDXVersion: SPDX-2.2`))
+
+	suite.NotNil(err)
+	suite.Equal("", osDistro)
+}
+
+func TestSBOMParserTestSuite(t *testing.T) {
+	suite.Run(t, new(SBOMParserTestSuite))
+}
@@ -1,6 +1,7 @@
 package azdsecinfo
 
 import (
+	"context"
 	"encoding/json"
 	"github.com/Azure/AzureDefender-K8S-InClusterDefense/cmd/webhook/admisionrequest"
 	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/azdsecinfo/contracts"
@@ -34,22 +35,22 @@ type IAzdSecInfoProviderCacheClient interface {
 	// GetContainerVulnerabilityScanInfofromCache try to get ContainerVulnerabilityScanInfo from cache.
 	// It gets the results from the cache and parse it to containerVulnerabilityCacheResultsWrapper object.
 	// If there is an error with the cache or the value is invalid returns an error.
-	GetContainerVulnerabilityScanInfofromCache(podSpecCacheKey string) ([]*contracts.ContainerVulnerabilityScanInfo, error, error)
+	GetContainerVulnerabilityScanInfofromCache(ctx context.Context, podSpecCacheKey string) ([]*contracts.ContainerVulnerabilityScanInfo, error, error)
 
 	// SetContainerVulnerabilityScanInfoInCache set ContainerVulnerabilityScanInfo in cache
 	// No error is reported back, only tracing it
-	SetContainerVulnerabilityScanInfoInCache(podSpecCacheKey string, containerVulnerabilityScanInfo []*contracts.ContainerVulnerabilityScanInfo, err error) error
+	SetContainerVulnerabilityScanInfoInCache(ctx context.Context, podSpecCacheKey string, containerVulnerabilityScanInfo []*contracts.ContainerVulnerabilityScanInfo, err error) error
 
 	// GetTimeOutStatus gets the timeout status of the podSpec from cache - how many times timeout has occurred for this podSpec
-	GetTimeOutStatus(podSpecCacheKey string) (int, error)
+	GetTimeOutStatus(ctx context.Context, podSpecCacheKey string) (int, error)
 
 	// SetTimeOutStatusAfterEncounteredTimeout update the timeout status if already exist in cache or set for the first time timeout status
-	SetTimeOutStatusAfterEncounteredTimeout(podSpecCacheKey string, timeOutStatus int) error
+	SetTimeOutStatusAfterEncounteredTimeout(ctx context.Context, podSpecCacheKey string, timeOutStatus int) error
 
 	// ResetTimeOutInCacheAfterGettingScanResults resets the timeout status in cache after scanResults was received.
 	// If scanResults was received the timeout is no longer relevant and needs to be reset.
 	// If no timeout occurred before, do nothing.
-	ResetTimeOutInCacheAfterGettingScanResults(podSpecCacheKey string) error
+	ResetTimeOutInCacheAfterGettingScanResults(ctx context.Context, podSpecCacheKey string) error
 
 	// GetPodSpecCacheKey get the cache key without the prefix of a given podSpec
 	GetPodSpecCacheKey(podSpec *admisionrequest.PodSpec) string
@@ -67,10 +68,9 @@ type AzdSecInfoProviderCacheClient struct {
 	metricSubmitter metric.IMetricSubmitter
 	// cacheClient is a cache for mapping digest to scan results and save timeout status
 	cacheClient cache.ICacheClient
-	// cacheExpirationTimeTimeout is the expiration time **IN MINUTES** for timout.
-	cacheExpirationTimeTimeout time.Duration
-	// cacheExpirationContainerVulnerabilityScanInfo is the expiration time **IN SECONDS** for ContainerVulnerabilityScanInfo.
-	cacheExpirationContainerVulnerabilityScanInfo time.Duration
+	// getConfiguration returns the current configuration of the expiration times - the configuration that the cache
+	// client was created with, or the reloaded configuration in case that a configuration provider is set.
+	getConfiguration func() *AzdSecInfoProviderConfiguration
 }
 
 // containerVulnerabilityCacheResultsWrapper is a wrapper for ContainerVulnerabilityScanInfo
@@ -85,27 +85,32 @@ type containerVulnerabilityCacheResultsWrapper struct {
 // NewAzdSecInfoProviderCacheClient - AzdSecInfoProviderCacheClient Ctor
 func NewAzdSecInfoProviderCacheClient(instrumentationProvider instrumentation.IInstrumentationProvider, cacheClient cache.ICacheClient, azdSecInfoProviderConfiguration *AzdSecInfoProviderConfiguration) *AzdSecInfoProviderCacheClient {
 	return &AzdSecInfoProviderCacheClient{
-		tracerProvider:             instrumentationProvider.GetTracerProvider("AzdSecInfoProviderCacheClient"),
-		metricSubmitter:            instrumentationProvider.GetMetricSubmitter(),
-		cacheClient:                cacheClient,
-		cacheExpirationTimeTimeout: utils.GetMinutes(azdSecInfoProviderConfiguration.CacheExpirationTimeTimeout),
-		cacheExpirationContainerVulnerabilityScanInfo: utils.GetSeconds(azdSecInfoProviderConfiguration.CacheExpirationContainerVulnerabilityScanInfo),
+		tracerProvider:   instrumentationProvider.GetTracerProvider("AzdSecInfoProviderCacheClient"),
+		metricSubmitter:  instrumentationProvider.GetMetricSubmitter(),
+		cacheClient:      cacheClient,
+		getConfiguration: func() *AzdSecInfoProviderConfiguration { return azdSecInfoProviderConfiguration },
 	}
 }
 
+// SetConfigurationProvider makes the cache client read the expiration times from the provider on each use (e.g. from the
+// reloaded configuration), so the values that are set after a reload use the new expiration times. Should be called before the cache client is used.
+func (client *AzdSecInfoProviderCacheClient) SetConfigurationProvider(getConfiguration func() *AzdSecInfoProviderConfiguration) {
+	client.getConfiguration = getConfiguration
+}
+
 // GetContainerVulnerabilityScanInfofromCache try to get ContainerVulnerabilityScanInfo from cache.
 // It gets the results from the cache and parse it to containerVulnerabilityCacheResultsWrapper object.
 // Returns:
 // []*contracts.ContainerVulnerabilityScanInfo - If scan results was STORED in cache as value from previous scans, otherwise nil
 // error - If error was STORED in cache as value from previous scans, otherwise nil
 //If there is an error with the cache or the value is invalid returns an error.
-func (client *AzdSecInfoProviderCacheClient) GetContainerVulnerabilityScanInfofromCache(podSpecCacheKey string) ([]*contracts.ContainerVulnerabilityScanInfo, error, error) {
+func (client *AzdSecInfoProviderCacheClient) GetContainerVulnerabilityScanInfofromCache(ctx context.Context, podSpecCacheKey string) ([]*contracts.ContainerVulnerabilityScanInfo, error, error) {
 	tracer := client.tracerProvider.GetTracer("GetContainerVulnerabilityScanInfofromCache")
 	// Get the key
 	ContainerVulnerabilityScanInfoCacheKey := client.getContainerVulnerabilityScanInfoCacheKey(podSpecCacheKey)
 
 	// get result from cache
-	scanInfoWrapperStringFromCache, err := client.cacheClient.Get(ContainerVulnerabilityScanInfoCacheKey)
+	scanInfoWrapperStringFromCache, err := client.cacheClient.Get(ctx, ContainerVulnerabilityScanInfoCacheKey)
 	if err != nil { // Key don't exist in cache or error with cache functionality
 		// Check if the error is MissingKeyCacheError
 		if cache.IsMissingKeyCacheError(err) { // The key not in the cache
@@ -144,7 +149,7 @@ func (client *AzdSecInfoProviderCacheClient) GetContainerVulnerabilityScanInfofr
 
 // SetContainerVulnerabilityScanInfoInCache set ContainerVulnerabilityScanInfo in cache
 // No error is reported back, only tracing it
-func (client *AzdSecInfoProviderCacheClient) SetContainerVulnerabilityScanInfoInCache(podSpecCacheKey string, containerVulnerabilityScanInfo []*contracts.ContainerVulnerabilityScanInfo, err error) error {
+func (client *AzdSecInfoProviderCacheClient) SetContainerVulnerabilityScanInfoInCache(ctx context.Context, podSpecCacheKey string, containerVulnerabilityScanInfo []*contracts.ContainerVulnerabilityScanInfo, err error) error {
 	tracer := client.tracerProvider.GetTracer("SetContainerVulnerabilityScanInfoInCache")
 	// Convert results to resultsString
 	resultsString, err := client.marshalScanResults(containerVulnerabilityScanInfo, err)
@@ -155,7 +160,7 @@ func (client *AzdSecInfoProviderCacheClient) SetContainerVulnerabilityScanInfoIn
 		return err
 	}
 	// Try to set resultsString in cache
-	if err = client.cacheClient.Set(client.getContainerVulnerabilityScanInfoCacheKey(podSpecCacheKey), resultsString, client.cacheExpirationContainerVulnerabilityScanInfo); err != nil {
+	if err = client.cacheClient.Set(ctx, client.getContainerVulnerabilityScanInfoCacheKey(podSpecCacheKey), resultsString, client.getCacheExpirationContainerVulnerabilityScanInfo()); err != nil {
 		err = errors.Wrap(err, "error encountered while trying to set new timeout in cache.")
 		tracer.Error(err, "")
 		client.metricSubmitter.SendMetric(1, util.NewErrorEncounteredMetric(err, "AzdSecInfoProviderCacheClient.SetContainerVulnerabilityScanInfoInCache"))
@@ -167,13 +172,13 @@ func (client *AzdSecInfoProviderCacheClient) SetContainerVulnerabilityScanInfoIn
 }
 
 // GetTimeOutStatus gets the timeout status of the podSpec from cache - how many times timeout has occurred for this podSpec
-func (client *AzdSecInfoProviderCacheClient) GetTimeOutStatus(podSpecCacheKey string) (int, error) {
+func (client *AzdSecInfoProviderCacheClient) GetTimeOutStatus(ctx context.Context, podSpecCacheKey string) (int, error) {
 	tracer := client.tracerProvider.GetTracer("GetTimeOutStatus")
 
 	// Get key for cache
 	timeOutCacheKey := client.getTimeOutCacheKey(podSpecCacheKey)
 	// Get timeoutStatus from cache
-	timeoutStatusString, err := client.cacheClient.Get(timeOutCacheKey)
+	timeoutStatusString, err := client.cacheClient.Get(ctx, timeOutCacheKey)
 	// Key don't exist in cache or error with cache functionality
 	if err != nil {
 		// Check if the error is MissingKeyCacheError
@@ -202,21 +207,21 @@ func (client *AzdSecInfoProviderCacheClient) GetTimeOutStatus(podSpecCacheKey st
 }
 
 // SetTimeOutStatusAfterEncounteredTimeout update the timeout status if already exist in cache or set for the first time timeout status
-func (client *AzdSecInfoProviderCacheClient) SetTimeOutStatusAfterEncounteredTimeout(podSpecCacheKey string, timeOutStatus int) error {
+func (client *AzdSecInfoProviderCacheClient) SetTimeOutStatusAfterEncounteredTimeout(ctx context.Context, podSpecCacheKey string, timeOutStatus int) error {
 	tracer := client.tracerProvider.GetTracer("SetTimeOutStatusAfterEncounteredTimeout")
 	tracer.Info("Try to set timeOutStatus in cache", "timeOutStatus", timeOutStatus)
 	// TODO handle race condition and locks for redis
-	return client.setTimeOutStatus(podSpecCacheKey, timeOutStatus, client.cacheExpirationTimeTimeout)
+	return client.setTimeOutStatus(ctx, podSpecCacheKey, timeOutStatus, client.getCacheExpirationTimeTimeout())
 }
 
 // setTimeOutStatus set a given timeOutStatus in cache.
-func (client *AzdSecInfoProviderCacheClient) setTimeOutStatus(podSpecCacheKey string, timeOutStatus int, expirationTime time.Duration) error {
+func (client *AzdSecInfoProviderCacheClient) setTimeOutStatus(ctx context.Context, podSpecCacheKey string, timeOutStatus int, expirationTime time.Duration) error {
 	tracer := client.tracerProvider.GetTracer("setTimeOutStatus")
 
 	// Get key for cache
 	timeOutCacheKey := client.getTimeOutCacheKey(podSpecCacheKey)
 	timeOutStatusString := strconv.Itoa(timeOutStatus)
-	if err := client.cacheClient.Set(timeOutCacheKey, timeOutStatusString, expirationTime); err != nil {
+	if err := client.cacheClient.Set(ctx, timeOutCacheKey, timeOutStatusString, expirationTime); err != nil {
 		err = errors.Wrap(err, "error encountered while trying to set new timeout in cache.")
 		tracer.Error(err, "")
 		client.metricSubmitter.SendMetric(1, util.NewErrorEncounteredMetric(err, "AzdSecInfoProviderCacheClient.SetTimeOutStatusAfterEncounteredTimeout"))
@@ -229,13 +234,13 @@ func (client *AzdSecInfoProviderCacheClient) setTimeOutStatus(podSpecCacheKey st
 // ResetTimeOutInCacheAfterGettingScanResults resets the timeout status in cache after scanResults was received.
 // If scanResults was received the timeout is no longer relevant and needs to be reset.
 // If no timeout occurred before, do nothing.
-func (client *AzdSecInfoProviderCacheClient) ResetTimeOutInCacheAfterGettingScanResults(podSpecCacheKey string) error {
+func (client *AzdSecInfoProviderCacheClient) ResetTimeOutInCacheAfterGettingScanResults(ctx context.Context, podSpecCacheKey string) error {
 	tracer := client.tracerProvider.GetTracer("ResetTimeOutInCacheAfterGettingScanResults")
 
 	// Get key for cache
 	timeOutCacheKey := client.getTimeOutCacheKey(podSpecCacheKey)
 	// Check if the timeOutCacheKey is already in cache
-	timeoutEncounteredString, err := client.cacheClient.Get(timeOutCacheKey)
+	timeoutEncounteredString, err := client.cacheClient.Get(ctx, timeOutCacheKey)
 	if err != nil {
 		if cache.IsMissingKeyCacheError(err) {
 			tracer.Info("Missing key. TimeOutCacheKey is not in cache", "timeOutCacheKey", timeOutCacheKey)
@@ -266,7 +271,7 @@ func (client *AzdSecInfoProviderCacheClient) ResetTimeOutInCacheAfterGettingScan
 	}
 	// In case timeout encountered  - reset timeout status (to _noTimeOutEncountered) because we succeeded to get results before timeout
 	// Set in cache failed
-	if err := client.setTimeOutStatus(podSpecCacheKey, _noTimeOutEncountered, _resetTimeoutTTL); err != nil {
+	if err := client.setTimeOutStatus(ctx, podSpecCacheKey, _noTimeOutEncountered, _resetTimeoutTTL); err != nil {
 		client.metricSubmitter.SendMetric(1, util.NewErrorEncounteredMetric(err, err.Error()))
 		err = errors.Wrap(err, "error encountered while trying to reset timeOut status in cache.")
 		tracer.Error(err, "")
@@ -361,3 +366,13 @@ func (client *AzdSecInfoProviderCacheClient) getTimeOutCacheKey(podSpecCacheKey
 func (client *AzdSecInfoProviderCacheClient) getContainerVulnerabilityScanInfoCacheKey(podSpecCacheKey string) string {
 	return _containerVulnerabilityScanInfoPrefixForCacheKey + podSpecCacheKey
 }
+
+// getCacheExpirationTimeTimeout returns the current expiration time of timeout status
+func (client *AzdSecInfoProviderCacheClient) getCacheExpirationTimeTimeout() time.Duration {
+	return utils.GetMinutes(client.getConfiguration().CacheExpirationTimeTimeout)
+}
+
+// getCacheExpirationContainerVulnerabilityScanInfo returns the current expiration time of ContainerVulnerabilityScanInfo
+func (client *AzdSecInfoProviderCacheClient) getCacheExpirationContainerVulnerabilityScanInfo() time.Duration {
+	return utils.GetSeconds(client.getConfiguration().CacheExpirationContainerVulnerabilityScanInfo)
+}
@@ -1,6 +1,7 @@
 package azdsecinfo
 
 import (
+	"context"
 	"github.com/Azure/AzureDefender-K8S-InClusterDefense/cmd/webhook/admisionrequest"
 	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/cache"
 	cachemock "github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/cache/mocks"
@@ -40,8 +41,8 @@ func (suite *AzdSecInfoProviderCacheClientTestSuite) SetupTest() {
 }
 
 func (suite *AzdSecInfoProviderCacheClientTestSuite) Test_getContainerVulnerabilityScanInfofromCache_KeyNotFound() {
-	suite.cacheClientMock.On("Get", _containerVulnerabilityScanInfoKeyTest1).Return("", new(cache.MissingKeyCacheError))
-	containersVulnerabilityScanInfo, errorStoredInCache, err := suite.azdSecInfoProviderCacheClient.GetContainerVulnerabilityScanInfofromCache(_podSpecCacheKeyTest1)
+	suite.cacheClientMock.On("Get", mock.Anything, _containerVulnerabilityScanInfoKeyTest1).Return("", new(cache.MissingKeyCacheError))
+	containersVulnerabilityScanInfo, errorStoredInCache, err := suite.azdSecInfoProviderCacheClient.GetContainerVulnerabilityScanInfofromCache(context.Background(), _podSpecCacheKeyTest1)
 	suite.Nil(containersVulnerabilityScanInfo)
 	suite.Nil(errorStoredInCache)
 	suite.IsTypef(cache.NewMissingKeyCacheError(""), err, "")
@@ -49,8 +50,8 @@ func (suite *AzdSecInfoProviderCacheClientTestSuite) Test_getContainerVulnerabil
 }
 
 func (suite *AzdSecInfoProviderCacheClientTestSuite) Test_getContainerVulnerabilityScanInfofromCache_UnmarshalError() {
-	suite.cacheClientMock.On("Get", _containerVulnerabilityScanInfoKeyTest1).Return("", nil)
-	containersVulnerabilityScanInfo, errorStoredInCache, err := suite.azdSecInfoProviderCacheClient.GetContainerVulnerabilityScanInfofromCache(_podSpecCacheKeyTest1)
+	suite.cacheClientMock.On("Get", mock.Anything, _containerVulnerabilityScanInfoKeyTest1).Return("", nil)
+	containersVulnerabilityScanInfo, errorStoredInCache, err := suite.azdSecInfoProviderCacheClient.GetContainerVulnerabilityScanInfofromCache(context.Background(), _podSpecCacheKeyTest1)
 	suite.Nil(containersVulnerabilityScanInfo)
 	suite.Nil(errorStoredInCache)
 	suite.NotNil(err)
@@ -58,8 +59,8 @@ func (suite *AzdSecInfoProviderCacheClientTestSuite) Test_getContainerVulnerabil
 }
 
 func (suite *AzdSecInfoProviderCacheClientTestSuite) Test_getContainerVulnerabilityScanInfofromCache_ErrorStoredInCache() {
-	suite.cacheClientMock.On("Get", _containerVulnerabilityScanInfoKeyTest1).Return(_resultsStringTestWithError, nil)
-	result, errorStoredInCache, err := suite.azdSecInfoProviderCacheClient.GetContainerVulnerabilityScanInfofromCache(_podSpecCacheKeyTest1)
+	suite.cacheClientMock.On("Get", mock.Anything, _containerVulnerabilityScanInfoKeyTest1).Return(_resultsStringTestWithError, nil)
+	result, errorStoredInCache, err := suite.azdSecInfoProviderCacheClient.GetContainerVulnerabilityScanInfofromCache(context.Background(), _podSpecCacheKeyTest1)
 	suite.Nil(err)
 	suite.Equal(errorStoredInCache.Error(), _expectedErrorString)
 	suite.Nil(result)
@@ -67,8 +68,8 @@ func (suite *AzdSecInfoProviderCacheClientTestSuite) Test_getContainerVulnerabil
 }
 
 func (suite *AzdSecInfoProviderCacheClientTestSuite) Test_getContainerVulnerabilityScanInfofromCache() {
-	suite.cacheClientMock.On("Get", _containerVulnerabilityScanInfoKeyTest1).Return(_expectedResultsStringTestScanned, nil)
-	result, errorStoredInCache, err := suite.azdSecInfoProviderCacheClient.GetContainerVulnerabilityScanInfofromCache(_podSpecCacheKeyTest1)
+	suite.cacheClientMock.On("Get", mock.Anything, _containerVulnerabilityScanInfoKeyTest1).Return(_expectedResultsStringTestScanned, nil)
+	result, errorStoredInCache, err := suite.azdSecInfoProviderCacheClient.GetContainerVulnerabilityScanInfofromCache(context.Background(), _podSpecCacheKeyTest1)
 	suite.Nil(err)
 	suite.Nil(errorStoredInCache)
 	suite.Equal(result, _expectedResultsTest1)
@@ -76,127 +77,127 @@ func (suite *AzdSecInfoProviderCacheClientTestSuite) Test_getContainerVulnerabil
 }
 
 func (suite *AzdSecInfoProviderCacheClientTestSuite) Test_setContainerVulnerabilityScanInfoInCache_SetGotError() {
-	suite.cacheClientMock.On("Set", _containerVulnerabilityScanInfoKeyTest1, _expectedResultsStringTestScanned, mock.Anything).Return(utils.NilArgumentError)
-	err := suite.azdSecInfoProviderCacheClient.SetContainerVulnerabilityScanInfoInCache(_podSpecCacheKeyTest1, _expectedResultsWrapperTest1.ContainerVulnerabilityScanInfo, nil)
+	suite.cacheClientMock.On("Set", mock.Anything, _containerVulnerabilityScanInfoKeyTest1, _expectedResultsStringTestScanned, mock.Anything).Return(utils.NilArgumentError)
+	err := suite.azdSecInfoProviderCacheClient.SetContainerVulnerabilityScanInfoInCache(context.Background(), _podSpecCacheKeyTest1, _expectedResultsWrapperTest1.ContainerVulnerabilityScanInfo, nil)
 	suite.NotNil(err)
 	suite.cacheClientMock.AssertExpectations(suite.T())
 }
 
 func (suite *AzdSecInfoProviderCacheClientTestSuite) Test_setContainerVulnerabilityScanInfoInCache_SetErrorInCache() {
-	suite.cacheClientMock.On("Set", _containerVulnerabilityScanInfoKeyTest1, _resultsStringTestWithError, mock.Anything).Return(nil)
-	err := suite.azdSecInfoProviderCacheClient.SetContainerVulnerabilityScanInfoInCache(_podSpecCacheKeyTest1, nil, utils.NilArgumentError)
+	suite.cacheClientMock.On("Set", mock.Anything, _containerVulnerabilityScanInfoKeyTest1, _resultsStringTestWithError, mock.Anything).Return(nil)
+	err := suite.azdSecInfoProviderCacheClient.SetContainerVulnerabilityScanInfoInCache(context.Background(), _podSpecCacheKeyTest1, nil, utils.NilArgumentError)
 	suite.Nil(err)
 	suite.cacheClientMock.AssertExpectations(suite.T())
 }
 
 func (suite *AzdSecInfoProviderCacheClientTestSuite) Test_setContainerVulnerabilityScanInfoInCache() {
-	suite.cacheClientMock.On("Set", _containerVulnerabilityScanInfoKeyTest1, _expectedResultsStringTestScanned, mock.Anything).Return(nil)
-	err := suite.azdSecInfoProviderCacheClient.SetContainerVulnerabilityScanInfoInCache(_podSpecCacheKeyTest1, _expectedResultsWrapperTest1.ContainerVulnerabilityScanInfo, nil)
+	suite.cacheClientMock.On("Set", mock.Anything, _containerVulnerabilityScanInfoKeyTest1, _expectedResultsStringTestScanned, mock.Anything).Return(nil)
+	err := suite.azdSecInfoProviderCacheClient.SetContainerVulnerabilityScanInfoInCache(context.Background(), _podSpecCacheKeyTest1, _expectedResultsWrapperTest1.ContainerVulnerabilityScanInfo, nil)
 	suite.Nil(err)
 	suite.cacheClientMock.AssertExpectations(suite.T())
 }
 
 func (suite *AzdSecInfoProviderCacheClientTestSuite) Test_getTimeOutStatus_KeyNotFound() {
-	suite.cacheClientMock.On("Get", _timeoutKeyTest1).Return("", new(cache.MissingKeyCacheError))
-	timeoutStatus, err := suite.azdSecInfoProviderCacheClient.GetTimeOutStatus(_podSpecCacheKeyTest1)
+	suite.cacheClientMock.On("Get", mock.Anything, _timeoutKeyTest1).Return("", new(cache.MissingKeyCacheError))
+	timeoutStatus, err := suite.azdSecInfoProviderCacheClient.GetTimeOutStatus(context.Background(), _podSpecCacheKeyTest1)
 	suite.Nil(err)
 	suite.Equal(timeoutStatus, _noTimeOutEncountered)
 	suite.cacheClientMock.AssertExpectations(suite.T())
 }
 
 func (suite *AzdSecInfoProviderCacheClientTestSuite) Test_getTimeOutStatus_GotError() {
-	suite.cacheClientMock.On("Get", _timeoutKeyTest1).Return("", utils.NilArgumentError)
-	timeoutStatus, err := suite.azdSecInfoProviderCacheClient.GetTimeOutStatus(_podSpecCacheKeyTest1)
+	suite.cacheClientMock.On("Get", mock.Anything, _timeoutKeyTest1).Return("", utils.NilArgumentError)
+	timeoutStatus, err := suite.azdSecInfoProviderCacheClient.GetTimeOutStatus(context.Background(), _podSpecCacheKeyTest1)
 	suite.NotNil(err)
 	suite.Equal(timeoutStatus, _unknownTimeOutStatus)
 	suite.cacheClientMock.AssertExpectations(suite.T())
 }
 
 func (suite *AzdSecInfoProviderCacheClientTestSuite) Test_getTimeOutStatus_ValueNotValidInt() {
-	suite.cacheClientMock.On("Get", _timeoutKeyTest1).Return("invalid int", nil)
-	timeoutStatus, err := suite.azdSecInfoProviderCacheClient.GetTimeOutStatus(_podSpecCacheKeyTest1)
+	suite.cacheClientMock.On("Get", mock.Anything, _timeoutKeyTest1).Return("invalid int", nil)
+	timeoutStatus, err := suite.azdSecInfoProviderCacheClient.GetTimeOutStatus(context.Background(), _podSpecCacheKeyTest1)
 	suite.NotNil(err)
 	suite.Equal(timeoutStatus, _unknownTimeOutStatus)
 	suite.cacheClientMock.AssertExpectations(suite.T())
 }
 
 func (suite *AzdSecInfoProviderCacheClientTestSuite) Test_getTimeOutStatus() {
-	suite.cacheClientMock.On("Get", _timeoutKeyTest1).Return("1", nil)
-	timeoutStatus, err := suite.azdSecInfoProviderCacheClient.GetTimeOutStatus(_podSpecCacheKeyTest1)
+	suite.cacheClientMock.On("Get", mock.Anything, _timeoutKeyTest1).Return("1", nil)
+	timeoutStatus, err := suite.azdSecInfoProviderCacheClient.GetTimeOutStatus(context.Background(), _podSpecCacheKeyTest1)
 	suite.Nil(err)
 	suite.Equal(timeoutStatus, 1)
 	suite.cacheClientMock.AssertExpectations(suite.T())
 }
 
 func (suite *AzdSecInfoProviderCacheClientTestSuite) Test_setTimeOutStatusAfterEncounteredTimeout_TwoEncountered() {
-	suite.cacheClientMock.On("Set", _timeoutKeyTest1, "2", mock.Anything).Return(nil)
-	err := suite.azdSecInfoProviderCacheClient.SetTimeOutStatusAfterEncounteredTimeout(_podSpecCacheKeyTest1, 2)
+	suite.cacheClientMock.On("Set", mock.Anything, _timeoutKeyTest1, "2", mock.Anything).Return(nil)
+	err := suite.azdSecInfoProviderCacheClient.SetTimeOutStatusAfterEncounteredTimeout(context.Background(), _podSpecCacheKeyTest1, 2)
 	suite.Nil(err)
 	suite.cacheClientMock.AssertExpectations(suite.T())
 }
 
 func (suite *AzdSecInfoProviderCacheClientTestSuite) Test_setTimeOutStatusAfterEncounteredTimeout_OneEncountered() {
-	suite.cacheClientMock.On("Set", _timeoutKeyTest1, "1", mock.Anything).Return(nil)
-	err := suite.azdSecInfoProviderCacheClient.SetTimeOutStatusAfterEncounteredTimeout(_podSpecCacheKeyTest1, 1)
+	suite.cacheClientMock.On("Set", mock.Anything, _timeoutKeyTest1, "1", mock.Anything).Return(nil)
+	err := suite.azdSecInfoProviderCacheClient.SetTimeOutStatusAfterEncounteredTimeout(context.Background(), _podSpecCacheKeyTest1, 1)
 	suite.Nil(err)
 	suite.cacheClientMock.AssertExpectations(suite.T())
 }
 
 func (suite *AzdSecInfoProviderCacheClientTestSuite) Test_setTimeOutStatusAfterEncounteredTimeout_Error() {
-	suite.cacheClientMock.On("Set", _timeoutKeyTest1, "1", mock.Anything).Return(utils.NilArgumentError)
-	err := suite.azdSecInfoProviderCacheClient.SetTimeOutStatusAfterEncounteredTimeout(_podSpecCacheKeyTest1, 1)
+	suite.cacheClientMock.On("Set", mock.Anything, _timeoutKeyTest1, "1", mock.Anything).Return(utils.NilArgumentError)
+	err := suite.azdSecInfoProviderCacheClient.SetTimeOutStatusAfterEncounteredTimeout(context.Background(), _podSpecCacheKeyTest1, 1)
 	suite.NotNil(err)
 	suite.cacheClientMock.AssertExpectations(suite.T())
 }
 
 func (suite *AzdSecInfoProviderCacheClientTestSuite) Test_resetTimeOutInCacheAfterGettingScanResults_KeyNotFound() {
-	suite.cacheClientMock.On("Get", _timeoutKeyTest1).Return("", new(cache.MissingKeyCacheError))
-	err := suite.azdSecInfoProviderCacheClient.ResetTimeOutInCacheAfterGettingScanResults(_podSpecCacheKeyTest1)
+	suite.cacheClientMock.On("Get", mock.Anything, _timeoutKeyTest1).Return("", new(cache.MissingKeyCacheError))
+	err := suite.azdSecInfoProviderCacheClient.ResetTimeOutInCacheAfterGettingScanResults(context.Background(), _podSpecCacheKeyTest1)
 	suite.Nil(err)
 	suite.cacheClientMock.AssertExpectations(suite.T())
 }
 
 func (suite *AzdSecInfoProviderCacheClientTestSuite) Test_resetTimeOutInCacheAfterGettingScanResults_GetError() {
-	suite.cacheClientMock.On("Get", _timeoutKeyTest1).Return("", utils.NilArgumentError)
-	err := suite.azdSecInfoProviderCacheClient.ResetTimeOutInCacheAfterGettingScanResults(_podSpecCacheKeyTest1)
+	suite.cacheClientMock.On("Get", mock.Anything, _timeoutKeyTest1).Return("", utils.NilArgumentError)
+	err := suite.azdSecInfoProviderCacheClient.ResetTimeOutInCacheAfterGettingScanResults(context.Background(), _podSpecCacheKeyTest1)
 	suite.NotNil(err)
 	suite.cacheClientMock.AssertExpectations(suite.T())
 }
 
 func (suite *AzdSecInfoProviderCacheClientTestSuite) Test_resetTimeOutInCacheAfterGettingScanResults_GetInvalidValue() {
-	suite.cacheClientMock.On("Get", _timeoutKeyTest1).Return("invalid value", nil)
-	err := suite.azdSecInfoProviderCacheClient.ResetTimeOutInCacheAfterGettingScanResults(_podSpecCacheKeyTest1)
+	suite.cacheClientMock.On("Get", mock.Anything, _timeoutKeyTest1).Return("invalid value", nil)
+	err := suite.azdSecInfoProviderCacheClient.ResetTimeOutInCacheAfterGettingScanResults(context.Background(), _podSpecCacheKeyTest1)
 	suite.NotNil(err)
 	suite.cacheClientMock.AssertExpectations(suite.T())
 }
 
 func (suite *AzdSecInfoProviderCacheClientTestSuite) Test_resetTimeOutInCacheAfterGettingScanResults_GotZero() {
-	suite.cacheClientMock.On("Get", _timeoutKeyTest1).Return("0", nil)
-	err := suite.azdSecInfoProviderCacheClient.ResetTimeOutInCacheAfterGettingScanResults(_podSpecCacheKeyTest1)
+	suite.cacheClientMock.On("Get", mock.Anything, _timeoutKeyTest1).Return("0", nil)
+	err := suite.azdSecInfoProviderCacheClient.ResetTimeOutInCacheAfterGettingScanResults(context.Background(), _podSpecCacheKeyTest1)
 	suite.Nil(err)
 	suite.cacheClientMock.AssertExpectations(suite.T())
 }
 
 func (suite *AzdSecInfoProviderCacheClientTestSuite) Test_resetTimeOutInCacheAfterGettingScanResults_GotOne() {
-	suite.cacheClientMock.On("Get", _timeoutKeyTest1).Return("1", nil)
-	suite.cacheClientMock.On("Set", _timeoutKeyTest1, "0", time.Duration(1)).Return(nil)
-	err := suite.azdSecInfoProviderCacheClient.ResetTimeOutInCacheAfterGettingScanResults(_podSpecCacheKeyTest1)
+	suite.cacheClientMock.On("Get", mock.Anything, _timeoutKeyTest1).Return("1", nil)
+	suite.cacheClientMock.On("Set", mock.Anything, _timeoutKeyTest1, "0", time.Duration(1)).Return(nil)
+	err := suite.azdSecInfoProviderCacheClient.ResetTimeOutInCacheAfterGettingScanResults(context.Background(), _podSpecCacheKeyTest1)
 	suite.Nil(err)
 	suite.cacheClientMock.AssertExpectations(suite.T())
 }
 
 func (suite *AzdSecInfoProviderCacheClientTestSuite) Test_resetTimeOutInCacheAfterGettingScanResults_GotTwo() {
-	suite.cacheClientMock.On("Get", _timeoutKeyTest1).Return("2", nil)
-	suite.cacheClientMock.On("Set", _timeoutKeyTest1, "0", time.Duration(1)).Return(nil)
-	err := suite.azdSecInfoProviderCacheClient.ResetTimeOutInCacheAfterGettingScanResults(_podSpecCacheKeyTest1)
+	suite.cacheClientMock.On("Get", mock.Anything, _timeoutKeyTest1).Return("2", nil)
+	suite.cacheClientMock.On("Set", mock.Anything, _timeoutKeyTest1, "0", time.Duration(1)).Return(nil)
+	err := suite.azdSecInfoProviderCacheClient.ResetTimeOutInCacheAfterGettingScanResults(context.Background(), _podSpecCacheKeyTest1)
 	suite.Nil(err)
 	suite.cacheClientMock.AssertExpectations(suite.T())
 }
 
 func (suite *AzdSecInfoProviderCacheClientTestSuite) Test_resetTimeOutInCacheAfterGettingScanResults_SetError() {
-	suite.cacheClientMock.On("Get", _timeoutKeyTest1).Return("2", nil)
-	suite.cacheClientMock.On("Set", _timeoutKeyTest1, "0", time.Duration(1)).Return(utils.NilArgumentError)
-	err := suite.azdSecInfoProviderCacheClient.ResetTimeOutInCacheAfterGettingScanResults(_podSpecCacheKeyTest1)
+	suite.cacheClientMock.On("Get", mock.Anything, _timeoutKeyTest1).Return("2", nil)
+	suite.cacheClientMock.On("Set", mock.Anything, _timeoutKeyTest1, "0", time.Duration(1)).Return(utils.NilArgumentError)
+	err := suite.azdSecInfoProviderCacheClient.ResetTimeOutInCacheAfterGettingScanResults(context.Background(), _podSpecCacheKeyTest1)
 	suite.NotNil(err)
 	suite.cacheClientMock.AssertExpectations(suite.T())
 }
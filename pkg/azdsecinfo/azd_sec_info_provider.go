@@ -1,18 +1,25 @@
 package azdsecinfo
 
 import (
+	"context"
 	"github.com/Azure/AzureDefender-K8S-InClusterDefense/cmd/webhook/admisionrequest"
+	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/artifacts"
 	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/azdsecinfo/contracts"
 	azdsecinfometrics "github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/azdsecinfo/metric"
 	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/dataproviders/arg"
+	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/decisionlog"
 	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/cache"
+	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/coalescing"
+	coalescingmetric "github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/coalescing/metric"
 	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/instrumentation"
 	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/instrumentation/metric"
 	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/instrumentation/metric/util"
 	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/instrumentation/trace"
+	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/registry"
 	registryerrors "github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/registry/errors"
 	registryutils "github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/registry/utils"
 	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/utils"
+	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/signature"
 	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/tag2digest"
 	"github.com/pkg/errors"
 	"time"
@@ -21,6 +28,21 @@ import (
 // Default time duration for GetContainersVulnerabilityScanInfo IN MILLISECONDS
 const _defaultTimeDurationGetContainersVulnerabilityScanInfo = 2850 * time.Millisecond // 2.85 seconds - can't multiply float in seconds
 
+// Default time duration of the background fetch of the results - the fetch continues after the request is done, so its
+// results are saved in cache for the next requests with the same pod spec.
+const _defaultBackgroundFetchTimeoutDuration = 30 * time.Second
+
+// Default time duration of the signature verification and the supply chain artifacts discovery of a digest - they don't
+// delay the scan info of the container for longer than it.
+const _defaultSupplyChainLookupsTimeoutDuration = 1 * time.Second
+
+const (
+	// _inProcessCoalescingLayer is the layer of the coalescing metrics of concurrent requests with the same pod spec
+	_inProcessCoalescingLayer = "AzdSecInfoProvider"
+	// _replicasCoalescingLayer is the layer of the coalescing metrics of replicas that wait for the lock holder's results
+	_replicasCoalescingLayer = "AzdSecInfoProviderReplicas"
+)
+
 // The status of timeout during the run
 const (
 	_unknownTimeOutStatus                = -1
@@ -31,7 +53,7 @@ const (
 // IAzdSecInfoProvider represents interface for providing azure defender security information
 type IAzdSecInfoProvider interface {
 	// GetContainersVulnerabilityScanInfo receives pod template spec containing containers list, and returns their fetched ContainersVulnerabilityScanInfo
-	GetContainersVulnerabilityScanInfo(workloadResource *admisionrequest.WorkloadResource) ([]*contracts.ContainerVulnerabilityScanInfo, error)
+	GetContainersVulnerabilityScanInfo(ctx context.Context, workloadResource *admisionrequest.WorkloadResource) ([]*contracts.ContainerVulnerabilityScanInfo, error)
 }
 
 // AzdSecInfoProvider implements IAzdSecInfoProvider interface
@@ -47,13 +69,28 @@ type AzdSecInfoProvider struct {
 	argDataProvider arg.IARGDataProvider
 	// tag2digestResolver is the resolver of images to their digests
 	tag2digestResolver tag2digest.ITag2DigestResolver
+	// signatureVerifier is the verifier of the signatures of the resolved digests
+	signatureVerifier signature.ISignatureVerifier
+	// artifactsDiscoverer is the discoverer of the supply chain artifacts (SBOMs and attestations) of the resolved digests
+	artifactsDiscoverer artifacts.IArtifactsDiscoverer
 	// getContainersVulnerabilityScanInfoTimeoutDuration is the duration of  GetContainersVulnerabilityScanInfo that AzdSecInfoProvider
 	//will try to fetch the results of some digest,
 	//if the duration will exceed, the program will return result of the first container that unscanned reason .
 	//the results still will be saved in the cache.
 	getContainersVulnerabilityScanInfoTimeoutDuration time.Duration
+	// backgroundFetchTimeoutDuration is the duration that the fetch of the results continues in the background - it doesn't
+	// inherit the request's deadline, so the results of lookups that are slower than the request are still saved in the cache.
+	backgroundFetchTimeoutDuration time.Duration
+	// supplyChainLookupsTimeoutDuration is the duration of the signature verification and the supply chain artifacts discovery
+	// of a digest - in case that it's exceeded, the signature status is unknown and the artifacts are omitted.
+	supplyChainLookupsTimeoutDuration time.Duration
 	// cacheClient is a cache client for AzdSecInfoProvider (mapping podSpec to scan results and save timeout status)
 	cacheClient IAzdSecInfoProviderCacheClient
+	// inFlightFetches tracks the in flight fetches, so concurrent requests with the same pod spec share one fetch
+	inFlightFetches *inFlightFetches
+	// distributedLock is the lock of each pod spec that is shared by the replicas of the webhook, so the replicas
+	// wait for the results of the replica that fetches them instead of fetching the same results concurrently
+	distributedLock coalescing.IDistributedLock
 }
 
 // AzdSecInfoProviderConfiguration is configuration data for AzdSecInfoProvider
@@ -68,21 +105,42 @@ type AzdSecInfoProviderConfiguration struct {
 func NewAzdSecInfoProvider(instrumentationProvider instrumentation.IInstrumentationProvider,
 	argDataProvider arg.IARGDataProvider,
 	tag2digestResolver tag2digest.ITag2DigestResolver,
+	signatureVerifier signature.ISignatureVerifier,
+	artifactsDiscoverer artifacts.IArtifactsDiscoverer,
 	GetContainersVulnerabilityScanInfoTimeoutDuration *utils.TimeoutConfiguration,
-	cacheClient IAzdSecInfoProviderCacheClient) *AzdSecInfoProvider {
+	backgroundFetchTimeoutConfiguration *utils.TimeoutConfiguration,
+	supplyChainLookupsTimeoutConfiguration *utils.TimeoutConfiguration,
+	cacheClient IAzdSecInfoProviderCacheClient,
+	distributedLock coalescing.IDistributedLock) *AzdSecInfoProvider {
 
 	// In case that GetContainersVulnerabilityScanInfoTimeoutDuration.TimeDurationInMS is empty (zero) - use default value.
 	getContainersVulnerabilityScanInfoTimeoutDuration := _defaultTimeDurationGetContainersVulnerabilityScanInfo
 	if GetContainersVulnerabilityScanInfoTimeoutDuration.TimeDurationInMS > 0 {
 		getContainersVulnerabilityScanInfoTimeoutDuration = GetContainersVulnerabilityScanInfoTimeoutDuration.ParseTimeoutConfigurationToDuration()
 	}
+	// In case that backgroundFetchTimeoutConfiguration.TimeDurationInMS is empty (zero) - use default value.
+	backgroundFetchTimeoutDuration := _defaultBackgroundFetchTimeoutDuration
+	if backgroundFetchTimeoutConfiguration.TimeDurationInMS > 0 {
+		backgroundFetchTimeoutDuration = backgroundFetchTimeoutConfiguration.ParseTimeoutConfigurationToDuration()
+	}
+	// In case that supplyChainLookupsTimeoutConfiguration.TimeDurationInMS is empty (zero) - use default value.
+	supplyChainLookupsTimeoutDuration := _defaultSupplyChainLookupsTimeoutDuration
+	if supplyChainLookupsTimeoutConfiguration.TimeDurationInMS > 0 {
+		supplyChainLookupsTimeoutDuration = supplyChainLookupsTimeoutConfiguration.ParseTimeoutConfigurationToDuration()
+	}
 	return &AzdSecInfoProvider{
-		tracerProvider:     instrumentationProvider.GetTracerProvider("AzdSecInfoProvider"),
-		metricSubmitter:    instrumentationProvider.GetMetricSubmitter(),
-		argDataProvider:    argDataProvider,
-		tag2digestResolver: tag2digestResolver,
+		tracerProvider:      instrumentationProvider.GetTracerProvider("AzdSecInfoProvider"),
+		metricSubmitter:     instrumentationProvider.GetMetricSubmitter(),
+		argDataProvider:     argDataProvider,
+		tag2digestResolver:  tag2digestResolver,
+		signatureVerifier:   signatureVerifier,
+		artifactsDiscoverer: artifactsDiscoverer,
 		getContainersVulnerabilityScanInfoTimeoutDuration: getContainersVulnerabilityScanInfoTimeoutDuration,
-		cacheClient: cacheClient,
+		backgroundFetchTimeoutDuration:                    backgroundFetchTimeoutDuration,
+		supplyChainLookupsTimeoutDuration:                 supplyChainLookupsTimeoutDuration,
+		cacheClient:                                       cacheClient,
+		inFlightFetches:                                   newInFlightFetches(),
+		distributedLock:                                   distributedLock,
 	}
 }
 
@@ -96,13 +154,16 @@ func NewAzdSecInfoProvider(instrumentationProvider instrumentation.IInstrumentat
 // Otherwise return an error and don't block the request
 // If no timeout occurred - save the results in the cache, reset the timeout status and return the results
 // For more information - see README
-func (provider *AzdSecInfoProvider) GetContainersVulnerabilityScanInfo(workloadResource *admisionrequest.WorkloadResource) ([]*contracts.ContainerVulnerabilityScanInfo, error) {
+func (provider *AzdSecInfoProvider) GetContainersVulnerabilityScanInfo(ctx context.Context, workloadResource *admisionrequest.WorkloadResource) ([]*contracts.ContainerVulnerabilityScanInfo, error) {
 	tracer := provider.tracerProvider.GetTracer("GetContainersVulnerabilityScanInfo")
+	ctx, span := provider.tracerProvider.StartSpan(ctx, "GetContainersVulnerabilityScanInfo")
+	defer span.End()
 	tracer.Info("Received:", "podSpec", &workloadResource.Spec, "resourceMetadata", &workloadResource.Metadata)
 
 	// Arguments validation
 	if &workloadResource.Spec == nil || &workloadResource.Metadata == nil {
 		err := errors.Wrap(utils.NilArgumentError, "AzdSecInfoProvider.GetContainersVulnerabilityScanInfo")
+		span.RecordError(err)
 		tracer.Error(err, "")
 		provider.metricSubmitter.SendMetric(1, util.NewErrorEncounteredMetric(err, "AzdSecInfoProvider.GetContainersVulnerabilityScanInfo"))
 		return nil, err
@@ -116,7 +177,8 @@ func (provider *AzdSecInfoProvider) GetContainersVulnerabilityScanInfo(workloadR
 	// If error is nil - There are results from cache. Two options for the results from cache:
 	// 		1. The result is an error occurred in previous run. Return the error occurred
 	// 		2. The result is ContainerVulnerabilityScanInfo  -  no errors occurred in previous run. Return the results
-	ContainersVulnerabilityScanInfo, errorStoredInCache, err := provider.cacheClient.GetContainerVulnerabilityScanInfofromCache(podSpecCacheKey)
+	ContainersVulnerabilityScanInfo, errorStoredInCache, err := provider.cacheClient.GetContainerVulnerabilityScanInfofromCache(ctx, podSpecCacheKey)
+	decisionlog.AddCacheLookup(ctx, decisionlog.AzdSecInfoProviderCacheLayer, podSpecCacheKey, err == nil)
 	if err != nil { // failed to get results from cache - skip and get results from providers
 		if cache.IsMissingKeyCacheError(err) {
 			tracer.Info("Missing key. Couldn't get ContainerVulnerabilityScanInfo from cache")
@@ -129,51 +191,147 @@ func (provider *AzdSecInfoProvider) GetContainersVulnerabilityScanInfo(workloadR
 		// If an error was stored in cache (error from previous results) return the error in order to avoid multiple failed requests
 		if errorStoredInCache != nil {
 			errorStoredInCache = errors.Wrap(errorStoredInCache, "Got error from ContainerVulnerabilityScanInfo stored in cache")
+			span.SetAttributes("fromCache", true)
+			span.RecordError(errorStoredInCache)
 			tracer.Error(errorStoredInCache, "")
 			return nil, errorStoredInCache
 		}
 		// Results are valid - return the results
 		tracer.Info("Got ContainersVulnerabilityScanInfo from cache successfully")
+		span.SetAttributes("fromCache", true)
 		return ContainersVulnerabilityScanInfo, nil
 	}
 
 	// Try to get containers vulnerabilities in diff thread.
-	chanTimeout := make(chan *utils.ChannelDataWrapper, 1)
-	go provider.getContainersVulnerabilityScanInfoSyncWrapper(workloadResource.Spec, workloadResource.Metadata, chanTimeout, podSpecCacheKey)
+	// The context is detached from the request's cancellation and deadline, so the results are fetched (and saved in cache) even
+	// after timeout, and their spans are still children of the request's span. It has its own background timeout, so the lookups
+	// are canceled (and their connections are freed) in case that a dependency doesn't respond at all.
+	// The results of the containers are collected as they arrive, so in case of timeout only the pending containers are unscanned.
+	// Concurrent requests with the same pod spec join the in flight fetch instead of fetching the same results again.
+	// In case that the joined fetch failed on its context (it's the context of the request that started it), the request starts
+	// or joins a fetch again instead of returning the context error of the other request - the same timeout applies to all the fetches.
+	span.SetAttributes("fromCache", false)
+	timeout := time.After(provider.getContainersVulnerabilityScanInfoTimeoutDuration)
+	for {
+		fetch, isStarted := provider.inFlightFetches.startOrJoin(podSpecCacheKey)
+		if isStarted {
+			provider.metricSubmitter.SendMetric(1, coalescingmetric.NewRequestCoalescingMetric(_inProcessCoalescingLayer, coalescingmetric.ExecutedCoalescingResult))
+			fetchCtx, cancelFetch := utils.NewDetachedContextWithTimeout(ctx, provider.backgroundFetchTimeoutDuration)
+			go provider.getContainersVulnerabilityScanInfoSyncWrapper(fetchCtx, cancelFetch, workloadResource.Spec, workloadResource.Metadata, fetch, podSpecCacheKey)
+		} else {
+			tracer.Info("Joined in flight fetch of the pod spec", "podSpecCacheKey", podSpecCacheKey)
+			span.SetAttributes("coalesced", true)
+			provider.metricSubmitter.SendMetric(1, coalescingmetric.NewRequestCoalescingMetric(_inProcessCoalescingLayer, coalescingmetric.CoalescedCoalescingResult))
+		}
 
-	// Choose the first thread that finish.
-	select {
-	// No timeout case:
-	case channelData, isChannelOpen := <-chanTimeout:
-		return provider.noTimeoutEncounteredGetContainersVulnerabilityScanInfo(workloadResource.Spec, chanTimeout, channelData, isChannelOpen, podSpecCacheKey)
-	// Timeout case:
-	case <-time.After(provider.getContainersVulnerabilityScanInfoTimeoutDuration):
-		return provider.timeoutEncounteredGetContainersVulnerabilityScanInfo(workloadResource.Spec, podSpecCacheKey)
+		// Choose the first thread that finish.
+		select {
+		// No timeout case:
+		case <-fetch.done:
+			if !isStarted && fetch.isFailedOnContext() && ctx.Err() == nil {
+				tracer.Info("Joined fetch failed on the context of another request - fetching again", "podSpecCacheKey", podSpecCacheKey)
+				provider.metricSubmitter.SendMetric(1, coalescingmetric.NewRequestCoalescingMetric(_inProcessCoalescingLayer, coalescingmetric.RejoinedCoalescingResult))
+				continue
+			}
+			ContainersVulnerabilityScanInfo, err = provider.noTimeoutEncounteredGetContainersVulnerabilityScanInfo(ctx, workloadResource.Spec, fetch.channelData, podSpecCacheKey)
+		// Timeout case:
+		case <-timeout:
+			span.AddEvent("Timeout", "timeoutDuration", provider.getContainersVulnerabilityScanInfoTimeoutDuration.String())
+			span.SetAttributes("timeout", true)
+			ContainersVulnerabilityScanInfo, err = provider.timeoutEncounteredGetContainersVulnerabilityScanInfo(ctx, workloadResource.Spec, podSpecCacheKey, fetch.collector)
+		// Request's deadline case (the API server stops waiting for the response before the timeout):
+		case <-ctx.Done():
+			span.AddEvent("DeadlineExceeded")
+			err = errors.Wrap(ctx.Err(), "AzdSecInfoProvider.GetContainersVulnerabilityScanInfo: request is done before getting the results")
+			tracer.Error(err, "")
+			provider.metricSubmitter.SendMetric(1, util.NewErrorEncounteredMetric(err, "AzdSecInfoProvider.GetContainersVulnerabilityScanInfo"))
+			ContainersVulnerabilityScanInfo = nil
+		}
+		span.RecordError(err)
+		return ContainersVulnerabilityScanInfo, err
 	}
 }
 
-// getContainersVulnerabilityScanInfoSyncWrapper runs getContainersVulnerabilityScanInfo and insert the result into the channel.
-func (provider *AzdSecInfoProvider) getContainersVulnerabilityScanInfoSyncWrapper(podSpec *admisionrequest.PodSpec, resourceMetadata *admisionrequest.ObjectMetadata, chanTimeout chan *utils.ChannelDataWrapper, podSpecCacheKey string) {
+// getContainersVulnerabilityScanInfoSyncWrapper runs getContainersVulnerabilityScanInfo and completes the fetch with the results.
+// cancel is called when the results are fetched, so the resources of ctx are released.
+// The result of each container is added to the collector of the fetch as soon as it is fetched.
+// In case that another replica of the webhook holds the lock of the pod spec, its results are used instead of fetching them.
+func (provider *AzdSecInfoProvider) getContainersVulnerabilityScanInfoSyncWrapper(ctx context.Context, cancel context.CancelFunc, podSpec *admisionrequest.PodSpec, resourceMetadata *admisionrequest.ObjectMetadata, fetch *containersVulnerabilityScanInfoFetch, podSpecCacheKey string) {
 	tracer := provider.tracerProvider.GetTracer("getContainersVulnerabilityScanInfoSyncWrapper")
-	containerVulnerabilityScanInfo, err := provider.getContainersVulnerabilityScanInfo(podSpec, resourceMetadata)
 
-	// Set both ContainersVulnerabilityScanInfo and err in cache
+	// Errors of the lock are traced by the lock - in that case the results are fetched without it.
+	lockToken, isLocked, lockErr := provider.distributedLock.TryLock(ctx, podSpecCacheKey)
+	if lockErr == nil && !isLocked {
+		if replicaResults, errorStoredInCache, isFound := provider.waitForReplicaResults(ctx, podSpecCacheKey); isFound {
+			cancel()
+			provider.inFlightFetches.complete(podSpecCacheKey, fetch, utils.NewChannelDataWrapper(replicaResults, errorStoredInCache))
+			return
+		}
+	}
+
+	containerVulnerabilityScanInfo, err := provider.getContainersVulnerabilityScanInfo(ctx, podSpec, resourceMetadata, fetch.collector)
+	// The lookups are done - release the resources of the context
+	cancel()
+
 	// Set results in cache here and not in the upper function in order to set results in cache even if timeout has occurred.
+	// The lock is released only after the results are set, so the waiting replicas find them in cache.
+	cacheCtx := utils.NewDetachedContext(ctx)
 	go func() {
-		errFromCache := provider.cacheClient.SetContainerVulnerabilityScanInfoInCache(podSpecCacheKey, containerVulnerabilityScanInfo, err)
-		if errFromCache != nil {
-			errFromCache = errors.Wrap(errFromCache, "Failed to set containerVulnerabilityScanInfo in cache")
-			tracer.Error(errFromCache, "")
-			provider.metricSubmitter.SendMetric(1, util.NewErrorEncounteredMetric(err, "AzdSecInfoProvider.getContainersVulnerabilityScanInfoSyncWrapper failed to store results in cache"))
+		// Results of containers that are unscanned because of a transient failure of a dependency (e.g. throttling), or that their
+		// signature verification timed out, aren't saved in cache - otherwise the pod spec would be evaluated with them until they expire.
+		if err == nil && hasTransientResults(containerVulnerabilityScanInfo) {
+			tracer.Info("Results are transient - results aren't saved in cache", "podSpecCacheKey", podSpecCacheKey)
 		} else {
-			tracer.Info("Set containerVulnerabilityScanInfo in cache successfully")
+			// Set both ContainersVulnerabilityScanInfo and err in cache - also in case that the background timeout has exceeded,
+			// so the next requests with the same pod spec don't start over and time out the same way.
+			provider.setContainersVulnerabilityScanInfoInCache(cacheCtx, podSpecCacheKey, containerVulnerabilityScanInfo, err)
+		}
+		if isLocked {
+			// Errors are traced by the lock - the lock expires anyway
+			_ = provider.distributedLock.Unlock(cacheCtx, podSpecCacheKey, lockToken)
 		}
 	}()
 
-	// Send results to the channel
+	// Complete the fetch - notify all the requests that wait for it
 	channelData := utils.NewChannelDataWrapper(containerVulnerabilityScanInfo, err)
-	chanTimeout <- channelData
-	tracer.Info("channelData inserted to chanTimeout successfully", "channelData", channelData)
+	provider.inFlightFetches.complete(podSpecCacheKey, fetch, channelData)
+	tracer.Info("Fetch completed successfully", "channelData", channelData)
+}
+
+// waitForReplicaResults waits until the replica of the webhook that holds the lock of the pod spec releases it, and gets its results from cache.
+// Returns false in case that the results aren't in cache (e.g. the replica failed or the lock expired) - the results have to be fetched.
+func (provider *AzdSecInfoProvider) waitForReplicaResults(ctx context.Context, podSpecCacheKey string) ([]*contracts.ContainerVulnerabilityScanInfo, error, bool) {
+	tracer := provider.tracerProvider.GetTracer("waitForReplicaResults")
+	tracer.Info("Another replica holds the lock of the pod spec - waiting for its results", "podSpecCacheKey", podSpecCacheKey)
+
+	// Errors are traced by the lock
+	if err := provider.distributedLock.WaitUntilReleased(ctx, podSpecCacheKey); err != nil {
+		provider.metricSubmitter.SendMetric(1, coalescingmetric.NewRequestCoalescingMetric(_replicasCoalescingLayer, coalescingmetric.NoReplicaResultsCoalescingResult))
+		return nil, nil, false
+	}
+
+	containerVulnerabilityScanInfo, errorStoredInCache, err := provider.cacheClient.GetContainerVulnerabilityScanInfofromCache(ctx, podSpecCacheKey)
+	if err != nil {
+		tracer.Info("Results of the replica aren't in cache - fetching them", "err", err)
+		provider.metricSubmitter.SendMetric(1, coalescingmetric.NewRequestCoalescingMetric(_replicasCoalescingLayer, coalescingmetric.NoReplicaResultsCoalescingResult))
+		return nil, nil, false
+	}
+	tracer.Info("Got results of the replica from cache")
+	provider.metricSubmitter.SendMetric(1, coalescingmetric.NewRequestCoalescingMetric(_replicasCoalescingLayer, coalescingmetric.GotReplicaResultsCoalescingResult))
+	return containerVulnerabilityScanInfo, errorStoredInCache, true
+}
+
+// setContainersVulnerabilityScanInfoInCache sets both ContainersVulnerabilityScanInfo and err in cache - errors are only traced
+func (provider *AzdSecInfoProvider) setContainersVulnerabilityScanInfoInCache(ctx context.Context, podSpecCacheKey string, containerVulnerabilityScanInfo []*contracts.ContainerVulnerabilityScanInfo, err error) {
+	tracer := provider.tracerProvider.GetTracer("setContainersVulnerabilityScanInfoInCache")
+	errFromCache := provider.cacheClient.SetContainerVulnerabilityScanInfoInCache(ctx, podSpecCacheKey, containerVulnerabilityScanInfo, err)
+	if errFromCache != nil {
+		errFromCache = errors.Wrap(errFromCache, "Failed to set containerVulnerabilityScanInfo in cache")
+		tracer.Error(errFromCache, "")
+		provider.metricSubmitter.SendMetric(1, util.NewErrorEncounteredMetric(errFromCache, "AzdSecInfoProvider.setContainersVulnerabilityScanInfoInCache"))
+		return
+	}
+	tracer.Info("Set containerVulnerabilityScanInfo in cache successfully")
 }
 
 // extractContainersVulnerabilityScanInfoFromChannelData is method that gets *utils.ChannelDataWrapper and tries to extract the data from the channel.
@@ -208,8 +366,11 @@ func (provider *AzdSecInfoProvider) extractContainersVulnerabilityScanInfoFromCh
 }
 
 // getContainersVulnerabilityScanInfo try to get containers vulnerabilities scan info
-func (provider *AzdSecInfoProvider) getContainersVulnerabilityScanInfo(podSpec *admisionrequest.PodSpec, resourceMetadata *admisionrequest.ObjectMetadata) ([]*contracts.ContainerVulnerabilityScanInfo, error) {
+// Its span may outlive the span of GetContainersVulnerabilityScanInfo (in case of timeout).
+func (provider *AzdSecInfoProvider) getContainersVulnerabilityScanInfo(ctx context.Context, podSpec *admisionrequest.PodSpec, resourceMetadata *admisionrequest.ObjectMetadata, collector *containerVulnerabilityScanInfoCollector) ([]*contracts.ContainerVulnerabilityScanInfo, error) {
 	tracer := provider.tracerProvider.GetTracer("getContainersVulnerabilityScanInfo")
+	ctx, span := provider.tracerProvider.StartSpan(ctx, "getContainersVulnerabilityScanInfo")
+	defer span.End()
 
 	// Convert pull secrets from reference object to strings
 	imagePullSecrets := make([]string, 0, len(podSpec.ImagePullSecrets))
@@ -223,9 +384,10 @@ func (provider *AzdSecInfoProvider) getContainersVulnerabilityScanInfo(podSpec *
 	tracer.Info("resourceCtx", "resourceCtx", resourceCtx)
 
 	// insert container vulnerability scan information for init containers and containers to vulnSecInfoContainers
-	vulnSecInfoContainers, err := provider.getVulnSecInfoContainers(podSpec, resourceCtx)
+	vulnSecInfoContainers, err := provider.getVulnSecInfoContainers(ctx, podSpec, resourceCtx, collector)
 	if err != nil {
 		wrappedError := errors.Wrap(err, "Handler failed to getVulnSecInfoContainers")
+		span.RecordError(wrappedError)
 		tracer.Error(wrappedError, "")
 		provider.metricSubmitter.SendMetric(1, util.NewErrorEncounteredMetric(wrappedError, "AzdSecInfoProvider.getContainersVulnerabilityScanInfo"))
 		return nil, wrappedError
@@ -236,7 +398,8 @@ func (provider *AzdSecInfoProvider) getContainersVulnerabilityScanInfo(podSpec *
 
 // getVulnSecInfoContainers gets vulnSecInfoContainers array with the scan results of the given containers.
 // It runs each container scan in parallel and returns only when all the scans are finished and the array is updated
-func (provider *AzdSecInfoProvider) getVulnSecInfoContainers(podSpec *admisionrequest.PodSpec, resourceCtx *tag2digest.ResourceContext) ([]*contracts.ContainerVulnerabilityScanInfo, error) {
+// The result of each scan is also added to the collector as soon as the scan is finished.
+func (provider *AzdSecInfoProvider) getVulnSecInfoContainers(ctx context.Context, podSpec *admisionrequest.PodSpec, resourceCtx *tag2digest.ResourceContext, collector *containerVulnerabilityScanInfoCollector) ([]*contracts.ContainerVulnerabilityScanInfo, error) {
 	tracer := provider.tracerProvider.GetTracer("getVulnSecInfoContainers")
 
 	// Initialize container vuln scan info list
@@ -254,10 +417,10 @@ func (provider *AzdSecInfoProvider) getVulnSecInfoContainers(podSpec *admisionre
 	// Get container vulnerability scan information in parallel
 	// Each call send data to channel vulnerabilitySecInfoChannel
 	for i := range podSpec.InitContainers {
-		go provider.getSingleContainerVulnerabilityScanInfoSyncWrapper(podSpec.InitContainers[i], resourceCtx, vulnerabilitySecInfoChannel)
+		go provider.getSingleContainerVulnerabilityScanInfoSyncWrapper(ctx, podSpec.InitContainers[i], resourceCtx, vulnerabilitySecInfoChannel, collector)
 	}
 	for i := range podSpec.Containers {
-		go provider.getSingleContainerVulnerabilityScanInfoSyncWrapper(podSpec.Containers[i], resourceCtx, vulnerabilitySecInfoChannel)
+		go provider.getSingleContainerVulnerabilityScanInfoSyncWrapper(ctx, podSpec.Containers[i], resourceCtx, vulnerabilitySecInfoChannel, collector)
 	}
 
 	for i := 0; i < len(podSpec.InitContainers)+len(podSpec.Containers); i++ { // No deadlock as a result of the loop because the number of receivers is identical to the number of senders
@@ -298,28 +461,36 @@ func (provider *AzdSecInfoProvider) getVulnSecInfoContainers(podSpec *admisionre
 	return vulnSecInfoContainers, nil
 }
 
-//getSingleContainerVulnerabilityScanInfoSyncWrapper wrap getSingleContainerVulnerabilityScanInfo.
-// It sends getSingleContainerVulnerabilityScanInfo results to the channel
-func (provider *AzdSecInfoProvider) getSingleContainerVulnerabilityScanInfoSyncWrapper(container *admisionrequest.Container, resourceCtx *tag2digest.ResourceContext, vulnerabilitySecInfoChannel chan *utils.ChannelDataWrapper) {
-	info, err := provider.getSingleContainerVulnerabilityScanInfo(container, resourceCtx)
+// getSingleContainerVulnerabilityScanInfoSyncWrapper wrap getSingleContainerVulnerabilityScanInfo.
+// It sends getSingleContainerVulnerabilityScanInfo results to the channel and adds successful results to the collector
+func (provider *AzdSecInfoProvider) getSingleContainerVulnerabilityScanInfoSyncWrapper(ctx context.Context, container *admisionrequest.Container, resourceCtx *tag2digest.ResourceContext, vulnerabilitySecInfoChannel chan *utils.ChannelDataWrapper, collector *containerVulnerabilityScanInfoCollector) {
+	info, err := provider.getSingleContainerVulnerabilityScanInfo(ctx, container, resourceCtx)
+	if err == nil {
+		collector.add(container, info)
+	}
 	vulnerabilitySecInfoChannel <- utils.NewChannelDataWrapper(info, err)
 }
 
 // getSingleContainerVulnerabilityScanInfo receives a container, and it's belonged deployed resource context, and returns fetched ContainerVulnerabilityScanInfo
-func (provider *AzdSecInfoProvider) getSingleContainerVulnerabilityScanInfo(container *admisionrequest.Container, resourceCtx *tag2digest.ResourceContext) (*contracts.ContainerVulnerabilityScanInfo, error) {
+func (provider *AzdSecInfoProvider) getSingleContainerVulnerabilityScanInfo(ctx context.Context, container *admisionrequest.Container, resourceCtx *tag2digest.ResourceContext) (*contracts.ContainerVulnerabilityScanInfo, error) {
 	tracer := provider.tracerProvider.GetTracer("getSingleContainerVulnerabilityScanInfo")
+	ctx, span := provider.tracerProvider.StartSpan(ctx, "getSingleContainerVulnerabilityScanInfo")
+	defer span.End()
 	tracer.Info("Received:", "container image ref", container.Image, "resourceCtx", resourceCtx)
 
 	if container == nil || resourceCtx == nil {
 		err := errors.Wrap(utils.NilArgumentError, "AzdSecInfoProvider.getSingleContainerVulnerabilityScanInfo")
+		span.RecordError(err)
 		tracer.Error(err, "")
 		return nil, err
 	}
+	span.SetAttributes("container", container.Name, "image", container.Image)
 
 	// Get image ref
 	imageRef, err := registryutils.GetImageReference(container.Image)
 	if err != nil {
 		err = errors.Wrap(err, "AzdSecInfoProvider.GetContainersVulnerabilityScanInfo.registry.GetImageReference")
+		span.RecordError(err)
 		tracer.Error(err, "")
 		return nil, err
 	}
@@ -331,42 +502,113 @@ func (provider *AzdSecInfoProvider) getSingleContainerVulnerabilityScanInfo(cont
 		return provider.buildContainerVulnerabilityScanInfoUnScannedWithReason(container, contracts.ImageIsNotInACRRegistryUnscannedReason), nil
 	}
 
-	digest, err := provider.tag2digestResolver.Resolve(imageRef, resourceCtx)
+	digest, err := provider.tag2digestResolver.Resolve(ctx, imageRef, resourceCtx)
 	if err != nil {
 		// TODO wait until @maayaan merge his PR and then add tests for this method. ( Maayan already created IAZdSecInfoProvider mock)
 		unscannedReason, isErrParsedToUnscannedReason := registryerrors.TryParseErrToUnscannedWithReason(err)
 		if !isErrParsedToUnscannedReason {
 			err = errors.Wrap(err, "Unexpected error while trying to resolve digest")
+			span.RecordError(err)
 			tracer.Error(err, "")
 			return nil, err
 		}
 
 		// ErrString parsed successfully to known unscanned reason.
 		tracer.Info("ErrString from Tag2DigestResolver parsed successfully to known unscanned reason", "ErrString", err, "unscannedReason", unscannedReason)
+		span.SetAttributes("unscannedReason", string(*unscannedReason))
 		return provider.buildContainerVulnerabilityScanInfoUnScannedWithReason(container, *unscannedReason), nil
 	}
 
-	scanStatus, scanFindings, err := provider.argDataProvider.GetImageVulnerabilityScanResults(imageRef.Registry(), imageRef.Repository(), digest)
+	// The signature verification and the supply chain artifacts discovery have their own short timeout, so they don't delay the scan info.
+	supplyChainLookupsCtx, cancelSupplyChainLookups := context.WithTimeout(ctx, provider.supplyChainLookupsTimeoutDuration)
+	defer cancelSupplyChainLookups()
+	// Verify the signature of the digest in parallel to fetching the scan results
+	signatureStatusChannel := make(chan contracts.SignatureStatus, 1)
+	go provider.getSignatureStatusSyncWrapper(supplyChainLookupsCtx, imageRef, digest, resourceCtx, signatureStatusChannel)
+	// Discover the supply chain artifacts of the digest in parallel to fetching the scan results
+	supplyChainArtifactsChannel := make(chan *contracts.SupplyChainArtifacts, 1)
+	go provider.getSupplyChainArtifactsSyncWrapper(supplyChainLookupsCtx, imageRef, digest, resourceCtx, supplyChainArtifactsChannel)
+
+	scanStatus, scanFindings, err := provider.argDataProvider.GetImageVulnerabilityScanResults(ctx, imageRef.Registry(), imageRef.Repository(), digest)
 	if err != nil {
 		// TODO wait until @maayaan merge his PR and then add tests for this method. ( Maayan already created IAZdSecInfoProvider mock)
 		unscannedReason, isErrParsedToUnscannedReason := registryerrors.TryParseErrToUnscannedWithReason(err)
 		if !isErrParsedToUnscannedReason {
 			err = errors.Wrap(err, "Unexpected error while trying to get results from ARGDataProvider")
+			span.RecordError(err)
 			tracer.Error(err, "")
 			return nil, err
 		}
 		// ErrString parsed successfully to known unscanned reason.
 		tracer.Info("ErrString from ARGDataProvider parsed successfully to known unscanned reason", "ErrString", err, "unscannedReason", unscannedReason)
+		span.SetAttributes("unscannedReason", string(*unscannedReason))
 		return provider.buildContainerVulnerabilityScanInfoUnScannedWithReason(container, *unscannedReason), nil
 	}
 
 	tracer.Info("results from ARG data provider", "scanStatus", scanStatus, "scanFindings", scanFindings)
 	// Build scan info from provided scan results
 	info := provider.buildContainerVulnerabilityScanInfoFromResult(container, digest, scanStatus, scanFindings)
+	info.SignatureStatus = provider.waitForSignatureStatus(supplyChainLookupsCtx, signatureStatusChannel)
+	info.SupplyChainArtifacts = provider.waitForSupplyChainArtifacts(supplyChainLookupsCtx, supplyChainArtifactsChannel)
 
 	return info, nil
 }
 
+// getSignatureStatusSyncWrapper wraps signatureVerifier.Verify and sends the signature status to the channel.
+// Signature verification errors don't fail the scan info - the returned status (contracts.SignatureUnverified) is used instead,
+// or contracts.SignatureUnknown in case that the verification was stopped by the timeout of ctx.
+func (provider *AzdSecInfoProvider) getSignatureStatusSyncWrapper(ctx context.Context, imageRef registry.IImageReference, digest string, resourceCtx *tag2digest.ResourceContext, signatureStatusChannel chan contracts.SignatureStatus) {
+	tracer := provider.tracerProvider.GetTracer("getSignatureStatusSyncWrapper")
+	signatureStatus, err := provider.signatureVerifier.Verify(ctx, imageRef, digest, resourceCtx.AuthContext())
+	if err != nil {
+		err = errors.Wrap(err, "failed to verify signature")
+		tracer.Error(err, "")
+		provider.metricSubmitter.SendMetric(1, util.NewErrorEncounteredMetric(err, "AzdSecInfoProvider.getSignatureStatusSyncWrapper"))
+		if ctx.Err() != nil {
+			signatureStatus = contracts.SignatureUnknown
+		}
+	}
+	signatureStatusChannel <- signatureStatus
+}
+
+// waitForSignatureStatus returns the signature status of the channel, or contracts.SignatureUnknown in case that ctx is done first
+func (provider *AzdSecInfoProvider) waitForSignatureStatus(ctx context.Context, signatureStatusChannel chan contracts.SignatureStatus) contracts.SignatureStatus {
+	select {
+	case signatureStatus := <-signatureStatusChannel:
+		return signatureStatus
+	case <-ctx.Done():
+		tracer := provider.tracerProvider.GetTracer("waitForSignatureStatus")
+		tracer.Info("Signature verification didn't complete in time - signature status is unknown", "timeoutDuration", provider.supplyChainLookupsTimeoutDuration.String())
+		return contracts.SignatureUnknown
+	}
+}
+
+// waitForSupplyChainArtifacts returns the supply chain artifacts of the channel, or nil (unknown) in case that ctx is done first
+func (provider *AzdSecInfoProvider) waitForSupplyChainArtifacts(ctx context.Context, supplyChainArtifactsChannel chan *contracts.SupplyChainArtifacts) *contracts.SupplyChainArtifacts {
+	select {
+	case supplyChainArtifacts := <-supplyChainArtifactsChannel:
+		return supplyChainArtifacts
+	case <-ctx.Done():
+		tracer := provider.tracerProvider.GetTracer("waitForSupplyChainArtifacts")
+		tracer.Info("Supply chain artifacts discovery didn't complete in time - artifacts are omitted", "timeoutDuration", provider.supplyChainLookupsTimeoutDuration.String())
+		return nil
+	}
+}
+
+// getSupplyChainArtifactsSyncWrapper wraps artifactsDiscoverer.Discover and sends the supply chain artifacts to the channel.
+// Artifacts discovery errors don't fail the scan info - nil artifacts are sent instead, so they're omitted from the annotation.
+func (provider *AzdSecInfoProvider) getSupplyChainArtifactsSyncWrapper(ctx context.Context, imageRef registry.IImageReference, digest string, resourceCtx *tag2digest.ResourceContext, supplyChainArtifactsChannel chan *contracts.SupplyChainArtifacts) {
+	tracer := provider.tracerProvider.GetTracer("getSupplyChainArtifactsSyncWrapper")
+	supplyChainArtifacts, err := provider.artifactsDiscoverer.Discover(ctx, imageRef, digest, resourceCtx.AuthContext())
+	if err != nil {
+		err = errors.Wrap(err, "failed to discover supply chain artifacts")
+		tracer.Error(err, "")
+		provider.metricSubmitter.SendMetric(1, util.NewErrorEncounteredMetric(err, "AzdSecInfoProvider.getSupplyChainArtifactsSyncWrapper"))
+		supplyChainArtifacts = nil
+	}
+	supplyChainArtifactsChannel <- supplyChainArtifacts
+}
+
 // buildContainerVulnerabilityScanInfoFromResult build the info object from data provided
 func (provider *AzdSecInfoProvider) buildContainerVulnerabilityScanInfoFromResult(container *admisionrequest.Container, digest string, scanStatus contracts.ScanStatus, scanFindigs []*contracts.ScanFinding) *contracts.ContainerVulnerabilityScanInfo {
 	info := &contracts.ContainerVulnerabilityScanInfo{
@@ -406,16 +648,27 @@ func (provider *AzdSecInfoProvider) buildContainerVulnerabilityScanInfoUnScanned
 }
 
 // buildListOfContainerVulnerabilityScanInfoWhenTimeout is method that is called when the GetContainerVulnerabilityScanInfo
-// got timeout (GetContainersVulnerabilityScanInfoTimeoutDuration) and returns list with one empty container with
-//unscanned status and contracts.GetContainersVulnerabilityScanInfoTimeoutUnscannedReason.
-// TODO In public preview, we should add timeout without empty container (bad UX) (should be changed also in the REGO).
-func (provider *AzdSecInfoProvider) buildListOfContainerVulnerabilityScanInfoWhenTimeout(podSpec *admisionrequest.PodSpec) ([]*contracts.ContainerVulnerabilityScanInfo, error) {
-	var containerVulnerabilityScanInfoList []*contracts.ContainerVulnerabilityScanInfo
+// got timeout (GetContainersVulnerabilityScanInfoTimeoutDuration) and returns list with the partial results (in the order of the pod spec):
+// containers that were already fetched have their real results, and only the pending containers are unscanned with
+// contracts.GetContainersVulnerabilityScanInfoTimeoutUnscannedReason.
+func (provider *AzdSecInfoProvider) buildListOfContainerVulnerabilityScanInfoWhenTimeout(podSpec *admisionrequest.PodSpec, collector *containerVulnerabilityScanInfoCollector) ([]*contracts.ContainerVulnerabilityScanInfo, error) {
+	tracer := provider.tracerProvider.GetTracer("buildListOfContainerVulnerabilityScanInfoWhenTimeout")
+	containerVulnerabilityScanInfoList := make([]*contracts.ContainerVulnerabilityScanInfo, 0, len(podSpec.InitContainers)+len(podSpec.Containers))
+	pendingContainers := 0
 
 	// Iterate over all podSpec containers
-	containers := append(podSpec.InitContainers, podSpec.Containers...)
+	containers := make([]*admisionrequest.Container, 0, len(podSpec.InitContainers)+len(podSpec.Containers))
+	containers = append(containers, podSpec.InitContainers...)
+	containers = append(containers, podSpec.Containers...)
 	for _, container := range containers {
-		// For each container create info object containing the container name and image name with unscanned status.
+		// Containers that were already fetched keep their results
+		if fetchedInfo, isFetched := collector.get(container); isFetched {
+			containerVulnerabilityScanInfoList = append(containerVulnerabilityScanInfoList, fetchedInfo)
+			continue
+		}
+
+		// For each pending container create info object containing the container name and image name with unscanned status.
+		pendingContainers++
 		info := &contracts.ContainerVulnerabilityScanInfo{
 			Name: container.Name,
 			Image: &contracts.Image{
@@ -432,21 +685,25 @@ func (provider *AzdSecInfoProvider) buildListOfContainerVulnerabilityScanInfoWhe
 		// Add info to list
 		containerVulnerabilityScanInfoList = append(containerVulnerabilityScanInfoList, info)
 	}
-	provider.metricSubmitter.SendMetric(1, azdsecinfometrics.NewContainerVulnScanInfoMetricWithUnscannedReason(contracts.Unscanned, contracts.GetContainersVulnerabilityScanInfoTimeoutUnscannedReason))
+	if pendingContainers > 0 {
+		provider.metricSubmitter.SendMetric(pendingContainers, azdsecinfometrics.NewContainerVulnScanInfoMetricWithUnscannedReason(contracts.Unscanned, contracts.GetContainersVulnerabilityScanInfoTimeoutUnscannedReason))
+	}
+	tracer.Info("Built partial results", "fetchedContainers", len(containers)-pendingContainers, "pendingContainers", pendingContainers)
 
 	return containerVulnerabilityScanInfoList, nil
 }
 
 // timeoutEncounteredGetContainersVulnerabilityScanInfo is called when timeout is encountered in GetContainersVulnerabilityScanInfo function
 // It checks if it is the first or second time that the request got an error (request is defined as the images of the request).
-// If it is the first or the second time, it adds the images to the cache and returns unscanned with metadata.
+// If it is the first or the second time, it adds the images to the cache and returns the partial results - the containers
+// that weren't fetched yet are unscanned with metadata.
 // If it is the third time or there is an error in the communication with the cache, it returns an error.
 // TODO Add tests for this behavior.
-func (provider *AzdSecInfoProvider) timeoutEncounteredGetContainersVulnerabilityScanInfo(podSpec *admisionrequest.PodSpec, podSpecCacheKey string) ([]*contracts.ContainerVulnerabilityScanInfo, error) {
+func (provider *AzdSecInfoProvider) timeoutEncounteredGetContainersVulnerabilityScanInfo(ctx context.Context, podSpec *admisionrequest.PodSpec, podSpecCacheKey string, collector *containerVulnerabilityScanInfoCollector) ([]*contracts.ContainerVulnerabilityScanInfo, error) {
 	tracer := provider.tracerProvider.GetTracer("timeoutEncounteredGetContainersVulnerabilityScanInfo")
 
 	// Get the timeoutStatus from cache
-	timeoutStatus, err := provider.cacheClient.GetTimeOutStatus(podSpecCacheKey)
+	timeoutStatus, err := provider.cacheClient.GetTimeOutStatus(ctx, podSpecCacheKey)
 	// If an error occurred while getting timeout status from cache return an error because we shouldn't block the pod request
 	if err != nil {
 		if cache.IsMissingKeyCacheError(err) {
@@ -470,26 +727,20 @@ func (provider *AzdSecInfoProvider) timeoutEncounteredGetContainersVulnerability
 	}
 	// If this is the first or second time there is a timeout - set new timeout status in cache and return unscanned and timeout.
 	// If an error occurred while setting timeout status in cache return an error because if we can't update timeout status we shouldn't block the pod request.
-	if err := provider.cacheClient.SetTimeOutStatusAfterEncounteredTimeout(podSpecCacheKey, timeoutStatus); err != nil {
+	if err := provider.cacheClient.SetTimeOutStatusAfterEncounteredTimeout(ctx, podSpecCacheKey, timeoutStatus); err != nil {
 		err = errors.Wrap(err, "error encountered while trying to set new timeout in cache.")
 		tracer.Error(err, "")
 		provider.metricSubmitter.SendMetric(1, util.NewErrorEncounteredMetric(err, "AzdSecInfoProvider.timeoutEncounteredGetContainersVulnerabilityScanInfo"))
 		return nil, err
 	}
 
-	tracer.Info("GetContainersVulnerabilityScanInfo got timeout - returning unscanned", "timeDurationOfTimeout", provider.getContainersVulnerabilityScanInfoTimeoutDuration)
-	return provider.buildListOfContainerVulnerabilityScanInfoWhenTimeout(podSpec)
+	tracer.Info("GetContainersVulnerabilityScanInfo got timeout - returning partial results", "timeDurationOfTimeout", provider.getContainersVulnerabilityScanInfoTimeoutDuration)
+	return provider.buildListOfContainerVulnerabilityScanInfoWhenTimeout(podSpec, collector)
 }
 
 // noTimeoutEncounteredGetContainersVulnerabilityScanInfo getting the scan results, set the results in the cache and reset timeout status
-func (provider *AzdSecInfoProvider) noTimeoutEncounteredGetContainersVulnerabilityScanInfo(podSpec *admisionrequest.PodSpec, chanTimeout chan *utils.ChannelDataWrapper, channelData *utils.ChannelDataWrapper, isChannelOpen bool, podSpecCacheKey string) ([]*contracts.ContainerVulnerabilityScanInfo, error) {
+func (provider *AzdSecInfoProvider) noTimeoutEncounteredGetContainersVulnerabilityScanInfo(ctx context.Context, podSpec *admisionrequest.PodSpec, channelData *utils.ChannelDataWrapper, podSpecCacheKey string) ([]*contracts.ContainerVulnerabilityScanInfo, error) {
 	tracer := provider.tracerProvider.GetTracer("noTimeoutEncounteredGetContainersVulnerabilityScanInfo")
-	// Check if channel is open
-	if !isChannelOpen {
-		err := errors.Wrap(utils.ReadFromClosedChannelError, "Channel closed unexpectedly")
-		tracer.Error(err, "")
-		return nil, err
-	}
 
 	// Try to extract []*contracts.ContainerVulnerabilityScanInfo from channelData
 	containerVulnerabilityScanInfo, err := provider.extractContainersVulnerabilityScanInfoFromChannelData(channelData)
@@ -501,9 +752,10 @@ func (provider *AzdSecInfoProvider) noTimeoutEncounteredGetContainersVulnerabili
 	}
 
 	// Success path:
-	// Update cache to no timeout encountered for this pod
+	// Update cache to no timeout encountered for this pod - the update isn't bound to ctx's cancellation, so it's completed even if the request is done
+	cacheCtx := utils.NewDetachedContext(ctx)
 	go func() {
-		err := provider.cacheClient.ResetTimeOutInCacheAfterGettingScanResults(podSpecCacheKey)
+		err := provider.cacheClient.ResetTimeOutInCacheAfterGettingScanResults(cacheCtx, podSpecCacheKey)
 		if err != nil {
 			err = errors.Wrap(err, "failed ResetTimeOutInCacheAfterGettingScanResults")
 			tracer.Error(err, "")
@@ -511,7 +763,23 @@ func (provider *AzdSecInfoProvider) noTimeoutEncounteredGetContainersVulnerabili
 		}
 	}()
 
-	close(chanTimeout)
 	tracer.Info("GetContainersVulnerabilityScanInfo finished extract []*contracts.ContainerVulnerabilityScanInfo.", "podSpec", podSpec, "ContainerVulnerabilityScanInfo", containerVulnerabilityScanInfo)
 	return containerVulnerabilityScanInfo, nil
 }
+
+// hasTransientResults returns true if one of the containers is unscanned because of a transient failure of a dependency,
+// or its signature status is unknown because the signature verification timed out
+func hasTransientResults(containerVulnerabilityScanInfo []*contracts.ContainerVulnerabilityScanInfo) bool {
+	for _, info := range containerVulnerabilityScanInfo {
+		if info == nil {
+			continue
+		}
+		if info.SignatureStatus == contracts.SignatureUnknown {
+			return true
+		}
+		if info.ScanStatus == contracts.Unscanned && contracts.IsTransientUnscannedReason(contracts.UnscannedReason(info.AdditionalData[contracts.UnscannedReasonAnnotationKey])) {
+			return true
+		}
+	}
+	return false
+}
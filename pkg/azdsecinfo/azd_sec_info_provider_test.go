@@ -1,21 +1,27 @@
 package azdsecinfo
 
 import (
+	"context"
 	"github.com/Azure/AzureDefender-K8S-InClusterDefense/cmd/webhook/admisionrequest"
+	artifactsMocks "github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/artifacts/mocks"
 	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/azdsecinfo/contracts"
 	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/azdsecinfo/mocks"
 	argDataProviderMocks "github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/dataproviders/arg/mocks"
 	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/cache"
+	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/coalescing"
+	coalescingMocks "github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/coalescing/mocks"
 	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/instrumentation"
 	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/registry"
 	registryErrors "github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/registry/errors"
 	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/utils"
+	signatureMocks "github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/signature/mocks"
 	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/tag2digest"
 	tag2DigestResolverMocks "github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/tag2digest/mocks"
 	"github.com/pkg/errors"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/suite"
 	"math"
+	"sync"
 	"testing"
 	"time"
 )
@@ -109,10 +115,12 @@ var (
 
 type AzdSecInfoProviderTestSuite struct {
 	suite.Suite
-	tag2DigestResolverMock *tag2DigestResolverMocks.ITag2DigestResolver
-	argDataProviderMock    *argDataProviderMocks.IARGDataProvider
-	azdSecInfoProvider     *AzdSecInfoProvider
-	cacheClientMock        *mocks.IAzdSecInfoProviderCacheClient
+	tag2DigestResolverMock  *tag2DigestResolverMocks.ITag2DigestResolver
+	argDataProviderMock     *argDataProviderMocks.IARGDataProvider
+	signatureVerifierMock   *signatureMocks.ISignatureVerifier
+	artifactsDiscovererMock *artifactsMocks.IArtifactsDiscoverer
+	azdSecInfoProvider      *AzdSecInfoProvider
+	cacheClientMock         *mocks.IAzdSecInfoProviderCacheClient
 }
 
 // This will run before each test in the suite
@@ -120,8 +128,12 @@ func (suite *AzdSecInfoProviderTestSuite) SetupTest() {
 	// Mock
 	suite.tag2DigestResolverMock = &tag2DigestResolverMocks.ITag2DigestResolver{}
 	suite.argDataProviderMock = &argDataProviderMocks.IARGDataProvider{}
+	suite.signatureVerifierMock = &signatureMocks.ISignatureVerifier{}
+	suite.signatureVerifierMock.On("Verify", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(contracts.SignatureStatus(""), nil).Maybe()
+	suite.artifactsDiscovererMock = &artifactsMocks.IArtifactsDiscoverer{}
+	suite.artifactsDiscovererMock.On("Discover", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil, nil).Maybe()
 	suite.cacheClientMock = new(mocks.IAzdSecInfoProviderCacheClient)
-	suite.azdSecInfoProvider = NewAzdSecInfoProvider(instrumentation.NewNoOpInstrumentationProvider(), suite.argDataProviderMock, suite.tag2DigestResolverMock, &utils.TimeoutConfiguration{TimeDurationInMS: _TimeDurationGetContainersVulnerabilityScanInfo}, suite.cacheClientMock)
+	suite.azdSecInfoProvider = NewAzdSecInfoProvider(instrumentation.NewNoOpInstrumentationProvider(), suite.argDataProviderMock, suite.tag2DigestResolverMock, suite.signatureVerifierMock, suite.artifactsDiscovererMock, &utils.TimeoutConfiguration{TimeDurationInMS: _TimeDurationGetContainersVulnerabilityScanInfo}, &utils.TimeoutConfiguration{}, &utils.TimeoutConfiguration{}, suite.cacheClientMock, coalescing.NewNoOpDistributedLock())
 }
 
 func (suite *AzdSecInfoProviderTestSuite) Test_getContainersVulnerabilityScanInfo_NoResultsInCache_ScannedResults() {
@@ -129,48 +141,240 @@ func (suite *AzdSecInfoProviderTestSuite) Test_getContainersVulnerabilityScanInf
 	workloadResource := createWorkloadResourceForTests(containers, nil)
 
 	suite.cacheClientMock.On("GetPodSpecCacheKey", workloadResource.Spec).Return(_imageOriginalTest1).Once()
-	suite.cacheClientMock.On("GetContainerVulnerabilityScanInfofromCache", _imageOriginalTest1).Return(nil, nil, new(cache.MissingKeyCacheError)).Once()
-	suite.cacheClientMock.On("ResetTimeOutInCacheAfterGettingScanResults", _imageOriginalTest1).Return(nil).Maybe()
-	suite.cacheClientMock.On("SetContainerVulnerabilityScanInfoInCache", _imageOriginalTest1, _expectedResultsTest1, nil).Return(nil).Maybe()
+	suite.cacheClientMock.On("GetContainerVulnerabilityScanInfofromCache", mock.Anything, _imageOriginalTest1).Return(nil, nil, new(cache.MissingKeyCacheError)).Once()
+	suite.cacheClientMock.On("ResetTimeOutInCacheAfterGettingScanResults", mock.Anything, _imageOriginalTest1).Return(nil).Maybe()
+	suite.cacheClientMock.On("SetContainerVulnerabilityScanInfoInCache", mock.Anything, _imageOriginalTest1, _expectedResultsTest1, nil).Return(nil).Maybe()
 
-	suite.tag2DigestResolverMock.On("Resolve", _imageRedTest1, _resourceCtxTest1).Return(_digestTest1, nil).Once()
-	suite.argDataProviderMock.On("GetImageVulnerabilityScanResults", _imageRedTest1.Registry(), _imageRedTest1.Repository(), _digestTest1).Once().Return(_scanStatus, _scanFindings, nil)
+	suite.tag2DigestResolverMock.On("Resolve", mock.Anything, _imageRedTest1, _resourceCtxTest1).Return(_digestTest1, nil).Once()
+	suite.argDataProviderMock.On("GetImageVulnerabilityScanResults", mock.Anything, _imageRedTest1.Registry(), _imageRedTest1.Repository(), _digestTest1).Once().Return(_scanStatus, _scanFindings, nil)
 
 	// Act
-	res, _ := suite.azdSecInfoProvider.GetContainersVulnerabilityScanInfo(workloadResource)
+	res, _ := suite.azdSecInfoProvider.GetContainersVulnerabilityScanInfo(context.Background(), workloadResource)
 	// Test
 	suite.Equal(res, _expectedResultsTest1)
 	suite.AssertExpectation()
 }
 
+func (suite *AzdSecInfoProviderTestSuite) Test_getContainersVulnerabilityScanInfo_NoResultsInCache_ScannedResults_SignatureVerified() {
+	containers := []*admisionrequest.Container{&_containers[0]}
+	workloadResource := createWorkloadResourceForTests(containers, nil)
+	suite.signatureVerifierMock = &signatureMocks.ISignatureVerifier{}
+	suite.azdSecInfoProvider = NewAzdSecInfoProvider(instrumentation.NewNoOpInstrumentationProvider(), suite.argDataProviderMock, suite.tag2DigestResolverMock, suite.signatureVerifierMock, suite.artifactsDiscovererMock, &utils.TimeoutConfiguration{TimeDurationInMS: _TimeDurationGetContainersVulnerabilityScanInfo}, &utils.TimeoutConfiguration{}, &utils.TimeoutConfiguration{}, suite.cacheClientMock, coalescing.NewNoOpDistributedLock())
+
+	suite.cacheClientMock.On("GetPodSpecCacheKey", workloadResource.Spec).Return(_imageOriginalTest1).Once()
+	suite.cacheClientMock.On("GetContainerVulnerabilityScanInfofromCache", mock.Anything, _imageOriginalTest1).Return(nil, nil, new(cache.MissingKeyCacheError)).Once()
+	suite.cacheClientMock.On("ResetTimeOutInCacheAfterGettingScanResults", mock.Anything, _imageOriginalTest1).Return(nil).Maybe()
+	suite.cacheClientMock.On("SetContainerVulnerabilityScanInfoInCache", mock.Anything, _imageOriginalTest1, mock.Anything, nil).Return(nil).Maybe()
+
+	suite.tag2DigestResolverMock.On("Resolve", mock.Anything, _imageRedTest1, _resourceCtxTest1).Return(_digestTest1, nil).Once()
+	suite.signatureVerifierMock.On("Verify", mock.Anything, _imageRedTest1, _digestTest1, _resourceCtxTest1.AuthContext()).Return(contracts.SignatureVerified, nil).Once()
+	suite.argDataProviderMock.On("GetImageVulnerabilityScanResults", mock.Anything, _imageRedTest1.Registry(), _imageRedTest1.Repository(), _digestTest1).Once().Return(_scanStatus, _scanFindings, nil)
+
+	// Act
+	res, err := suite.azdSecInfoProvider.GetContainersVulnerabilityScanInfo(context.Background(), workloadResource)
+	// Test
+	suite.Nil(err)
+	suite.Equal(_scanStatus, res[0].ScanStatus)
+	suite.Equal(contracts.SignatureVerified, res[0].SignatureStatus)
+	suite.AssertExpectation()
+}
+
+func (suite *AzdSecInfoProviderTestSuite) Test_getContainersVulnerabilityScanInfo_NoResultsInCache_ScannedResults_SupplyChainArtifactsDiscovered() {
+	containers := []*admisionrequest.Container{&_containers[0]}
+	workloadResource := createWorkloadResourceForTests(containers, nil)
+	suite.artifactsDiscovererMock = &artifactsMocks.IArtifactsDiscoverer{}
+	suite.azdSecInfoProvider = NewAzdSecInfoProvider(instrumentation.NewNoOpInstrumentationProvider(), suite.argDataProviderMock, suite.tag2DigestResolverMock, suite.signatureVerifierMock, suite.artifactsDiscovererMock, &utils.TimeoutConfiguration{TimeDurationInMS: _TimeDurationGetContainersVulnerabilityScanInfo}, &utils.TimeoutConfiguration{}, &utils.TimeoutConfiguration{}, suite.cacheClientMock, coalescing.NewNoOpDistributedLock())
+	supplyChainArtifacts := &contracts.SupplyChainArtifacts{
+		SBOMs:        []*contracts.SBOM{{Format: contracts.SPDX, Digest: "sha256:aaaa"}},
+		Attestations: []*contracts.Attestation{},
+		OSDistro:     "alpine 3.15.0",
+	}
+
+	suite.cacheClientMock.On("GetPodSpecCacheKey", workloadResource.Spec).Return(_imageOriginalTest1).Once()
+	suite.cacheClientMock.On("GetContainerVulnerabilityScanInfofromCache", mock.Anything, _imageOriginalTest1).Return(nil, nil, new(cache.MissingKeyCacheError)).Once()
+	suite.cacheClientMock.On("ResetTimeOutInCacheAfterGettingScanResults", mock.Anything, _imageOriginalTest1).Return(nil).Maybe()
+	suite.cacheClientMock.On("SetContainerVulnerabilityScanInfoInCache", mock.Anything, _imageOriginalTest1, mock.Anything, nil).Return(nil).Maybe()
+
+	suite.tag2DigestResolverMock.On("Resolve", mock.Anything, _imageRedTest1, _resourceCtxTest1).Return(_digestTest1, nil).Once()
+	suite.artifactsDiscovererMock.On("Discover", mock.Anything, _imageRedTest1, _digestTest1, _resourceCtxTest1.AuthContext()).Return(supplyChainArtifacts, nil).Once()
+	suite.argDataProviderMock.On("GetImageVulnerabilityScanResults", mock.Anything, _imageRedTest1.Registry(), _imageRedTest1.Repository(), _digestTest1).Once().Return(_scanStatus, _scanFindings, nil)
+
+	// Act
+	res, err := suite.azdSecInfoProvider.GetContainersVulnerabilityScanInfo(context.Background(), workloadResource)
+	// Test
+	suite.Nil(err)
+	suite.Equal(_scanStatus, res[0].ScanStatus)
+	suite.Equal(supplyChainArtifacts, res[0].SupplyChainArtifacts)
+	suite.AssertExpectation()
+}
+
+func (suite *AzdSecInfoProviderTestSuite) Test_getContainersVulnerabilityScanInfo_SupplyChainLookupsTimeoutExceeded_SignatureUnknownAndNotCached() {
+	containers := []*admisionrequest.Container{&_containers[0]}
+	workloadResource := createWorkloadResourceForTests(containers, nil)
+	isUnlocked := make(chan struct{})
+	distributedLockMock := &coalescingMocks.IDistributedLock{}
+	suite.signatureVerifierMock = &signatureMocks.ISignatureVerifier{}
+	suite.artifactsDiscovererMock = &artifactsMocks.IArtifactsDiscoverer{}
+	suite.azdSecInfoProvider = NewAzdSecInfoProvider(instrumentation.NewNoOpInstrumentationProvider(), suite.argDataProviderMock, suite.tag2DigestResolverMock, suite.signatureVerifierMock, suite.artifactsDiscovererMock, &utils.TimeoutConfiguration{TimeDurationInMS: _TimeDurationGetContainersVulnerabilityScanInfo}, &utils.TimeoutConfiguration{}, &utils.TimeoutConfiguration{TimeDurationInMS: 50}, suite.cacheClientMock, distributedLockMock)
+
+	distributedLockMock.On("TryLock", mock.Anything, _imageOriginalTest1).Return("token", true, nil).Once()
+	distributedLockMock.On("Unlock", mock.Anything, _imageOriginalTest1, "token").Return(nil).Once().Run(func(args mock.Arguments) {
+		close(isUnlocked)
+	})
+	suite.cacheClientMock.On("GetPodSpecCacheKey", workloadResource.Spec).Return(_imageOriginalTest1).Once()
+	suite.cacheClientMock.On("GetContainerVulnerabilityScanInfofromCache", mock.Anything, _imageOriginalTest1).Return(nil, nil, new(cache.MissingKeyCacheError)).Once()
+	suite.cacheClientMock.On("ResetTimeOutInCacheAfterGettingScanResults", mock.Anything, _imageOriginalTest1).Return(nil).Maybe()
+	suite.tag2DigestResolverMock.On("Resolve", mock.Anything, _imageRedTest1, _resourceCtxTest1).Return(_digestTest1, nil).Once()
+	// The signature verification and the artifacts discovery don't respond - they return only when their context is done
+	suite.signatureVerifierMock.On("Verify", mock.Anything, _imageRedTest1, _digestTest1, _resourceCtxTest1.AuthContext()).Return(contracts.SignatureUnverified, context.DeadlineExceeded).Once().Run(func(args mock.Arguments) {
+		<-args.Get(0).(context.Context).Done()
+	})
+	suite.artifactsDiscovererMock.On("Discover", mock.Anything, _imageRedTest1, _digestTest1, _resourceCtxTest1.AuthContext()).Return(nil, context.DeadlineExceeded).Once().Run(func(args mock.Arguments) {
+		<-args.Get(0).(context.Context).Done()
+	})
+	suite.argDataProviderMock.On("GetImageVulnerabilityScanResults", mock.Anything, _imageRedTest1.Registry(), _imageRedTest1.Repository(), _digestTest1).Once().Return(_scanStatus, _scanFindings, nil)
+
+	// Act
+	res, err := suite.azdSecInfoProvider.GetContainersVulnerabilityScanInfo(context.Background(), workloadResource)
+
+	// Test - the scan results are returned with unknown signature status and without artifacts, and they aren't set in cache
+	suite.Nil(err)
+	suite.Equal(_scanStatus, res[0].ScanStatus)
+	suite.Equal(contracts.SignatureUnknown, res[0].SignatureStatus)
+	suite.Nil(res[0].SupplyChainArtifacts)
+	select {
+	case <-isUnlocked:
+	case <-time.After(time.Second):
+		suite.Fail("lock wasn't released")
+	}
+	suite.cacheClientMock.AssertNotCalled(suite.T(), "SetContainerVulnerabilityScanInfoInCache", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	suite.AssertExpectation()
+	distributedLockMock.AssertExpectations(suite.T())
+}
+
 func (suite *AzdSecInfoProviderTestSuite) Test_getContainersVulnerabilityScanInfo_NoResultsInCache_UnscannedResults() {
 	containers := []*admisionrequest.Container{&_containers[0]}
 	workloadResource := createWorkloadResourceForTests(containers, nil)
 
 	suite.cacheClientMock.On("GetPodSpecCacheKey", workloadResource.Spec).Return(_imageOriginalTest1).Once()
-	suite.cacheClientMock.On("GetContainerVulnerabilityScanInfofromCache", _imageOriginalTest1).Return(nil, nil, new(cache.MissingKeyCacheError)).Once()
-	suite.cacheClientMock.On("ResetTimeOutInCacheAfterGettingScanResults", _imageOriginalTest1).Return(nil).Maybe()
-	suite.cacheClientMock.On("SetContainerVulnerabilityScanInfoInCache", _imageOriginalTest1, _expectedResultsTest2, nil).Return(nil).Maybe()
+	suite.cacheClientMock.On("GetContainerVulnerabilityScanInfofromCache", mock.Anything, _imageOriginalTest1).Return(nil, nil, new(cache.MissingKeyCacheError)).Once()
+	suite.cacheClientMock.On("ResetTimeOutInCacheAfterGettingScanResults", mock.Anything, _imageOriginalTest1).Return(nil).Maybe()
+	suite.cacheClientMock.On("SetContainerVulnerabilityScanInfoInCache", mock.Anything, _imageOriginalTest1, _expectedResultsTest2, nil).Return(nil).Maybe()
 
-	suite.tag2DigestResolverMock.On("Resolve", _imageRedTest1, _resourceCtxTest1).Return(_digestTest1, nil).Once()
-	suite.argDataProviderMock.On("GetImageVulnerabilityScanResults", _imageRedTest1.Registry(), _imageRedTest1.Repository(), _digestTest1).Once().Return(contracts.Unscanned, nil, registryErrors.NewImageIsNotFoundErr("", errors.New("")))
+	suite.tag2DigestResolverMock.On("Resolve", mock.Anything, _imageRedTest1, _resourceCtxTest1).Return(_digestTest1, nil).Once()
+	suite.argDataProviderMock.On("GetImageVulnerabilityScanResults", mock.Anything, _imageRedTest1.Registry(), _imageRedTest1.Repository(), _digestTest1).Once().Return(contracts.Unscanned, nil, registryErrors.NewImageIsNotFoundErr("", errors.New("")))
 
 	// Act
-	res, _ := suite.azdSecInfoProvider.GetContainersVulnerabilityScanInfo(workloadResource)
+	res, _ := suite.azdSecInfoProvider.GetContainersVulnerabilityScanInfo(context.Background(), workloadResource)
 	// Test
 	suite.Equal(res[0].ScanStatus, contracts.Unscanned)
 	suite.AssertExpectation()
 }
 
+func (suite *AzdSecInfoProviderTestSuite) Test_getContainersVulnerabilityScanInfo_NoResultsInCache_DependencyErrors_UnscannedWithReason() {
+	tests := []struct {
+		name                    string
+		resolveErr              error
+		argErr                  error
+		expectedUnscannedReason contracts.UnscannedReason
+	}{
+		{
+			name:                    "ARG unauthorized",
+			argErr:                  errors.Wrap(registryErrors.NewDataProviderUnauthorizedErr("ARG", errors.New("403")), "wrap"),
+			expectedUnscannedReason: contracts.DataProviderUnauthorizedUnscannedReason,
+		},
+		{
+			name:                    "ARG throttled",
+			argErr:                  errors.Wrap(registryErrors.NewDataProviderThrottledErr("ARG", errors.New("429")), "wrap"),
+			expectedUnscannedReason: contracts.DataProviderThrottledUnscannedReason,
+		},
+		{
+			name:                    "ARG unavailable",
+			argErr:                  errors.Wrap(registryErrors.NewDataProviderUnavailableErr("ARG", errors.New("503")), "wrap"),
+			expectedUnscannedReason: contracts.DataProviderUnavailableUnscannedReason,
+		},
+		{
+			name:                    "identity unavailable",
+			resolveErr:              errors.Wrap(registryErrors.NewIdentityUnavailableErr(_imageRegistry, errors.New("MSIError")), "wrap"),
+			expectedUnscannedReason: contracts.IdentityUnavailableUnscannedReason,
+		},
+		{
+			name:                    "digest resolution timeout",
+			resolveErr:              errors.Wrap(registryErrors.NewDigestResolutionTimeoutErr(_imageOriginalTest1, context.DeadlineExceeded), "wrap"),
+			expectedUnscannedReason: contracts.DigestResolutionTimeoutUnscannedReason,
+		},
+	}
+	for _, tt := range tests {
+		suite.Run(tt.name, func() {
+			suite.SetupTest()
+			containers := []*admisionrequest.Container{&_containers[0]}
+			workloadResource := createWorkloadResourceForTests(containers, nil)
+
+			suite.cacheClientMock.On("GetPodSpecCacheKey", workloadResource.Spec).Return(_imageOriginalTest1).Once()
+			suite.cacheClientMock.On("GetContainerVulnerabilityScanInfofromCache", mock.Anything, _imageOriginalTest1).Return(nil, nil, new(cache.MissingKeyCacheError)).Once()
+			suite.cacheClientMock.On("ResetTimeOutInCacheAfterGettingScanResults", mock.Anything, _imageOriginalTest1).Return(nil).Maybe()
+			suite.cacheClientMock.On("SetContainerVulnerabilityScanInfoInCache", mock.Anything, _imageOriginalTest1, mock.Anything, nil).Return(nil).Maybe()
+
+			if tt.resolveErr != nil {
+				suite.tag2DigestResolverMock.On("Resolve", mock.Anything, _imageRedTest1, _resourceCtxTest1).Return("", tt.resolveErr).Once()
+			} else {
+				suite.tag2DigestResolverMock.On("Resolve", mock.Anything, _imageRedTest1, _resourceCtxTest1).Return(_digestTest1, nil).Once()
+				suite.argDataProviderMock.On("GetImageVulnerabilityScanResults", mock.Anything, _imageRedTest1.Registry(), _imageRedTest1.Repository(), _digestTest1).Once().Return(contracts.ScanStatus(""), nil, tt.argErr)
+			}
+
+			// Act
+			res, err := suite.azdSecInfoProvider.GetContainersVulnerabilityScanInfo(context.Background(), workloadResource)
+			// Test
+			suite.Nil(err)
+			suite.Equal(contracts.Unscanned, res[0].ScanStatus)
+			suite.Equal(string(tt.expectedUnscannedReason), res[0].AdditionalData[contracts.UnscannedReasonAnnotationKey])
+			suite.AssertExpectation()
+		})
+	}
+}
+
+func (suite *AzdSecInfoProviderTestSuite) Test_getContainersVulnerabilityScanInfo_TransientUnscannedReason_NotCached() {
+	containers := []*admisionrequest.Container{&_containers[0]}
+	workloadResource := createWorkloadResourceForTests(containers, nil)
+	isUnlocked := make(chan struct{})
+	distributedLockMock := &coalescingMocks.IDistributedLock{}
+	suite.azdSecInfoProvider = NewAzdSecInfoProvider(instrumentation.NewNoOpInstrumentationProvider(), suite.argDataProviderMock, suite.tag2DigestResolverMock, suite.signatureVerifierMock, suite.artifactsDiscovererMock, &utils.TimeoutConfiguration{TimeDurationInMS: _TimeDurationGetContainersVulnerabilityScanInfo}, &utils.TimeoutConfiguration{}, &utils.TimeoutConfiguration{}, suite.cacheClientMock, distributedLockMock)
+
+	distributedLockMock.On("TryLock", mock.Anything, _imageOriginalTest1).Return("token", true, nil).Once()
+	distributedLockMock.On("Unlock", mock.Anything, _imageOriginalTest1, "token").Return(nil).Once().Run(func(args mock.Arguments) {
+		close(isUnlocked)
+	})
+	suite.cacheClientMock.On("GetPodSpecCacheKey", workloadResource.Spec).Return(_imageOriginalTest1).Once()
+	suite.cacheClientMock.On("GetContainerVulnerabilityScanInfofromCache", mock.Anything, _imageOriginalTest1).Return(nil, nil, new(cache.MissingKeyCacheError)).Once()
+	suite.cacheClientMock.On("ResetTimeOutInCacheAfterGettingScanResults", mock.Anything, _imageOriginalTest1).Return(nil).Maybe()
+	suite.tag2DigestResolverMock.On("Resolve", mock.Anything, _imageRedTest1, _resourceCtxTest1).Return(_digestTest1, nil).Once()
+	suite.argDataProviderMock.On("GetImageVulnerabilityScanResults", mock.Anything, _imageRedTest1.Registry(), _imageRedTest1.Repository(), _digestTest1).Once().Return(contracts.ScanStatus(""), nil, registryErrors.NewDataProviderThrottledErr("ARG", errors.New("429")))
+
+	// Act
+	res, err := suite.azdSecInfoProvider.GetContainersVulnerabilityScanInfo(context.Background(), workloadResource)
+
+	// Test - the throttled container is unscanned, but the results aren't set in cache (the lock is released after the cache is skipped)
+	suite.Nil(err)
+	suite.Equal(string(contracts.DataProviderThrottledUnscannedReason), res[0].AdditionalData[contracts.UnscannedReasonAnnotationKey])
+	select {
+	case <-isUnlocked:
+	case <-time.After(time.Second):
+		suite.Fail("lock wasn't released")
+	}
+	suite.cacheClientMock.AssertNotCalled(suite.T(), "SetContainerVulnerabilityScanInfoInCache", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	suite.AssertExpectation()
+	distributedLockMock.AssertExpectations(suite.T())
+}
+
 func (suite *AzdSecInfoProviderTestSuite) Test_getContainersVulnerabilityScanInfo_ResultsInCache_ScannedResults() {
 	containers := []*admisionrequest.Container{&_containers[0]}
 	workloadResource := createWorkloadResourceForTests(containers, nil)
 
 	suite.cacheClientMock.On("GetPodSpecCacheKey", workloadResource.Spec).Return(_imageOriginalTest1).Once()
-	suite.cacheClientMock.On("GetContainerVulnerabilityScanInfofromCache", _imageOriginalTest1).Return(_expectedResultsTest1, nil, nil).Once()
+	suite.cacheClientMock.On("GetContainerVulnerabilityScanInfofromCache", mock.Anything, _imageOriginalTest1).Return(_expectedResultsTest1, nil, nil).Once()
 
 	// Act
-	res, _ := suite.azdSecInfoProvider.GetContainersVulnerabilityScanInfo(workloadResource)
+	res, _ := suite.azdSecInfoProvider.GetContainersVulnerabilityScanInfo(context.Background(), workloadResource)
 	// Test
 	suite.Equal(res, _expectedResultsTest1)
 	suite.AssertExpectation()
@@ -181,10 +385,10 @@ func (suite *AzdSecInfoProviderTestSuite) Test_getContainersVulnerabilityScanInf
 	workloadResource := createWorkloadResourceForTests(containers, nil)
 
 	suite.cacheClientMock.On("GetPodSpecCacheKey", workloadResource.Spec).Return(_imageOriginalTest1).Once()
-	suite.cacheClientMock.On("GetContainerVulnerabilityScanInfofromCache", _imageOriginalTest1).Return(nil, errors.New(""), nil).Once()
+	suite.cacheClientMock.On("GetContainerVulnerabilityScanInfofromCache", mock.Anything, _imageOriginalTest1).Return(nil, errors.New(""), nil).Once()
 
 	// Act
-	res, err := suite.azdSecInfoProvider.GetContainersVulnerabilityScanInfo(workloadResource)
+	res, err := suite.azdSecInfoProvider.GetContainersVulnerabilityScanInfo(context.Background(), workloadResource)
 	// Test
 	suite.NotNil(err)
 	suite.Nil(res)
@@ -196,15 +400,15 @@ func (suite *AzdSecInfoProviderTestSuite) Test_getContainersVulnerabilityScanInf
 	workloadResource := createWorkloadResourceForTests(containers, nil)
 
 	suite.cacheClientMock.On("GetPodSpecCacheKey", workloadResource.Spec).Return(_imageOriginalTest1).Once()
-	suite.cacheClientMock.On("GetContainerVulnerabilityScanInfofromCache", _imageOriginalTest1).Return(nil, nil, errors.New("")).Once()
-	suite.cacheClientMock.On("ResetTimeOutInCacheAfterGettingScanResults", _imageOriginalTest1).Return(nil).Maybe()
-	suite.cacheClientMock.On("SetContainerVulnerabilityScanInfoInCache", _imageOriginalTest1, _expectedResultsTest1, nil).Return(nil).Maybe()
+	suite.cacheClientMock.On("GetContainerVulnerabilityScanInfofromCache", mock.Anything, _imageOriginalTest1).Return(nil, nil, errors.New("")).Once()
+	suite.cacheClientMock.On("ResetTimeOutInCacheAfterGettingScanResults", mock.Anything, _imageOriginalTest1).Return(nil).Maybe()
+	suite.cacheClientMock.On("SetContainerVulnerabilityScanInfoInCache", mock.Anything, _imageOriginalTest1, _expectedResultsTest1, nil).Return(nil).Maybe()
 
-	suite.tag2DigestResolverMock.On("Resolve", _imageRedTest1, _resourceCtxTest1).Return(_digestTest1, nil).Once()
-	suite.argDataProviderMock.On("GetImageVulnerabilityScanResults", _imageRedTest1.Registry(), _imageRedTest1.Repository(), _digestTest1).Once().Return(_scanStatus, _scanFindings, nil)
+	suite.tag2DigestResolverMock.On("Resolve", mock.Anything, _imageRedTest1, _resourceCtxTest1).Return(_digestTest1, nil).Once()
+	suite.argDataProviderMock.On("GetImageVulnerabilityScanResults", mock.Anything, _imageRedTest1.Registry(), _imageRedTest1.Repository(), _digestTest1).Once().Return(_scanStatus, _scanFindings, nil)
 
 	// Act
-	res, _ := suite.azdSecInfoProvider.GetContainersVulnerabilityScanInfo(workloadResource)
+	res, _ := suite.azdSecInfoProvider.GetContainersVulnerabilityScanInfo(context.Background(), workloadResource)
 	// Test
 	suite.Equal(res, _expectedResultsTest1)
 	suite.AssertExpectation()
@@ -215,40 +419,300 @@ func (suite *AzdSecInfoProviderTestSuite) Test_getContainersVulnerabilityScanInf
 	workloadResource := createWorkloadResourceForTests(containers, nil)
 
 	suite.cacheClientMock.On("GetPodSpecCacheKey", workloadResource.Spec).Return(_imageOriginalTest1).Once()
-	suite.cacheClientMock.On("GetContainerVulnerabilityScanInfofromCache", _imageOriginalTest1).Return(nil, nil, new(cache.MissingKeyCacheError)).Once()
-	suite.cacheClientMock.On("GetTimeOutStatus", _imageOriginalTest1).Return(0, nil).Once()
-	suite.cacheClientMock.On("SetTimeOutStatusAfterEncounteredTimeout", _imageOriginalTest1, 1).Return(nil).Once()
-	suite.cacheClientMock.On("SetContainerVulnerabilityScanInfoInCache", _imageOriginalTest1, _expectedResultsTest1, nil).Return(nil).Maybe()
-
-	suite.tag2DigestResolverMock.On("Resolve", _imageRedTest1, _resourceCtxTest1).Return(_digestTest1, nil).Once()
-	suite.argDataProviderMock.On("GetImageVulnerabilityScanResults", _imageRedTest1.Registry(), _imageRedTest1.Repository(), _digestTest1).Once().Return(_scanStatus, _scanFindings, nil).Run(func(args mock.Arguments) {
-		time.Sleep(_defaultTimeDurationGetContainersVulnerabilityScanInfo)
+	suite.cacheClientMock.On("GetContainerVulnerabilityScanInfofromCache", mock.Anything, _imageOriginalTest1).Return(nil, nil, new(cache.MissingKeyCacheError)).Once()
+	suite.cacheClientMock.On("GetTimeOutStatus", mock.Anything, _imageOriginalTest1).Return(0, nil).Once()
+	suite.cacheClientMock.On("SetTimeOutStatusAfterEncounteredTimeout", mock.Anything, _imageOriginalTest1, 1).Return(nil).Once()
+	suite.cacheClientMock.On("SetContainerVulnerabilityScanInfoInCache", mock.Anything, _imageOriginalTest1, _expectedResultsTest1, nil).Return(nil).Maybe()
+
+	suite.tag2DigestResolverMock.On("Resolve", mock.Anything, _imageRedTest1, _resourceCtxTest1).Return(_digestTest1, nil).Once()
+	suite.argDataProviderMock.On("GetImageVulnerabilityScanResults", mock.Anything, _imageRedTest1.Registry(), _imageRedTest1.Repository(), _digestTest1).Once().Return(_scanStatus, _scanFindings, nil).Run(func(args mock.Arguments) {
+		time.Sleep(_defaultTimeDurationGetContainersVulnerabilityScanInfo + 500*time.Millisecond)
 	})
 
 	// Act
-	res, _ := suite.azdSecInfoProvider.GetContainersVulnerabilityScanInfo(workloadResource)
+	res, _ := suite.azdSecInfoProvider.GetContainersVulnerabilityScanInfo(context.Background(), workloadResource)
 	// Test
 	suite.Equal(res, _expectedResultsTest3)
 	suite.AssertExpectation()
 }
 
+func (suite *AzdSecInfoProviderTestSuite) Test_getContainersVulnerabilityScanInfo_EncounteredTimeout_FetchedContainersKeepResults() {
+	containers := []*admisionrequest.Container{&_containers[0], &_containers[1]}
+	workloadResource := createWorkloadResourceForTests(containers, nil)
+	pendingInfo := &contracts.ContainerVulnerabilityScanInfo{
+		Name: _containers[1].Name,
+		Image: &contracts.Image{
+			Name:   _containers[1].Image,
+			Digest: "",
+		},
+		ScanStatus:   contracts.Unscanned,
+		ScanFindings: nil,
+		AdditionalData: map[string]string{
+			contracts.UnscannedReasonAnnotationKey: string(contracts.GetContainersVulnerabilityScanInfoTimeoutUnscannedReason),
+		},
+	}
+
+	suite.cacheClientMock.On("GetPodSpecCacheKey", workloadResource.Spec).Return(_imageOriginalTest1).Once()
+	suite.cacheClientMock.On("GetContainerVulnerabilityScanInfofromCache", mock.Anything, _imageOriginalTest1).Return(nil, nil, new(cache.MissingKeyCacheError)).Once()
+	suite.cacheClientMock.On("GetTimeOutStatus", mock.Anything, _imageOriginalTest1).Return(0, nil).Once()
+	suite.cacheClientMock.On("SetTimeOutStatusAfterEncounteredTimeout", mock.Anything, _imageOriginalTest1, 1).Return(nil).Once()
+	suite.cacheClientMock.On("SetContainerVulnerabilityScanInfoInCache", mock.Anything, _imageOriginalTest1, mock.Anything, nil).Return(nil).Maybe()
+
+	suite.tag2DigestResolverMock.On("Resolve", mock.Anything, _imageRedTest1, _resourceCtxTest1).Return(_digestTest1, nil).Once()
+	suite.argDataProviderMock.On("GetImageVulnerabilityScanResults", mock.Anything, _imageRedTest1.Registry(), _imageRedTest1.Repository(), _digestTest1).Once().Return(_scanStatus, _scanFindings, nil)
+	suite.tag2DigestResolverMock.On("Resolve", mock.Anything, _imageRedTest2, _resourceCtxTest2).Return(_digestTest2, nil).Once()
+	suite.argDataProviderMock.On("GetImageVulnerabilityScanResults", mock.Anything, _imageRedTest2.Registry(), _imageRedTest2.Repository(), _digestTest2).Once().Return(_scanStatus, _scanFindings, nil).Run(func(args mock.Arguments) {
+		time.Sleep(_defaultTimeDurationGetContainersVulnerabilityScanInfo + 500*time.Millisecond)
+	})
+
+	// Act
+	res, err := suite.azdSecInfoProvider.GetContainersVulnerabilityScanInfo(context.Background(), workloadResource)
+	// Test - the fetched container keeps its real results and only the pending container is unscanned
+	suite.Nil(err)
+	suite.Equal([]*contracts.ContainerVulnerabilityScanInfo{_containerVulnerabilityScanInfo, pendingInfo}, res)
+	suite.AssertExpectation()
+}
+
+func (suite *AzdSecInfoProviderTestSuite) Test_GetContainersVulnerabilityScanInfo_RequestDeadlineExceeded_LookupsNotCanceledAndResultsCached() {
+	containers := []*admisionrequest.Container{&_containers[0]}
+	workloadResource := createWorkloadResourceForTests(containers, nil)
+	isSetInCache := make(chan struct{})
+
+	suite.cacheClientMock.On("GetPodSpecCacheKey", workloadResource.Spec).Return(_imageOriginalTest1).Once()
+	suite.cacheClientMock.On("GetContainerVulnerabilityScanInfofromCache", mock.Anything, _imageOriginalTest1).Return(nil, nil, new(cache.MissingKeyCacheError)).Once()
+	suite.cacheClientMock.On("SetContainerVulnerabilityScanInfoInCache", mock.Anything, _imageOriginalTest1, _expectedResultsTest1, nil).Return(nil).Once().Run(func(args mock.Arguments) {
+		close(isSetInCache)
+	})
+	// The lookup is slower than the request's deadline
+	suite.tag2DigestResolverMock.On("Resolve", mock.Anything, _imageRedTest1, _resourceCtxTest1).Once().Return(_digestTest1, nil).Run(func(args mock.Arguments) {
+		time.Sleep(100 * time.Millisecond)
+		suite.Nil(args.Get(0).(context.Context).Err())
+	})
+	suite.argDataProviderMock.On("GetImageVulnerabilityScanResults", mock.Anything, _imageRedTest1.Registry(), _imageRedTest1.Repository(), _digestTest1).Once().Return(_scanStatus, _scanFindings, nil)
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	// Act
+	res, err := suite.azdSecInfoProvider.GetContainersVulnerabilityScanInfo(ctx, workloadResource)
+
+	// Test - the request is done, but the background fetch isn't canceled and its results are set in cache
+	suite.Nil(res)
+	suite.Equal(context.DeadlineExceeded, errors.Cause(err))
+	select {
+	case <-isSetInCache:
+	case <-time.After(time.Second):
+		suite.Fail("results of the background fetch weren't set in cache")
+	}
+	suite.AssertExpectation()
+}
+
+func (suite *AzdSecInfoProviderTestSuite) Test_GetContainersVulnerabilityScanInfo_BackgroundFetchTimeoutExceeded_ErrorCached() {
+	containers := []*admisionrequest.Container{&_containers[0]}
+	workloadResource := createWorkloadResourceForTests(containers, nil)
+	suite.azdSecInfoProvider = NewAzdSecInfoProvider(instrumentation.NewNoOpInstrumentationProvider(), suite.argDataProviderMock, suite.tag2DigestResolverMock, suite.signatureVerifierMock, suite.artifactsDiscovererMock, &utils.TimeoutConfiguration{TimeDurationInMS: _TimeDurationGetContainersVulnerabilityScanInfo}, &utils.TimeoutConfiguration{TimeDurationInMS: 50}, &utils.TimeoutConfiguration{}, suite.cacheClientMock, coalescing.NewNoOpDistributedLock())
+	isSetInCache := make(chan struct{})
+
+	suite.cacheClientMock.On("GetPodSpecCacheKey", workloadResource.Spec).Return(_imageOriginalTest1).Once()
+	suite.cacheClientMock.On("GetContainerVulnerabilityScanInfofromCache", mock.Anything, _imageOriginalTest1).Return(nil, nil, new(cache.MissingKeyCacheError)).Once()
+	suite.cacheClientMock.On("SetContainerVulnerabilityScanInfoInCache", mock.Anything, _imageOriginalTest1, mock.Anything, mock.Anything).Return(nil).Once().Run(func(args mock.Arguments) {
+		suite.Equal(context.DeadlineExceeded, errors.Cause(args.Error(3)))
+		close(isSetInCache)
+	})
+	// The lookup doesn't respond until the background fetch is canceled
+	suite.tag2DigestResolverMock.On("Resolve", mock.Anything, _imageRedTest1, _resourceCtxTest1).Once().Return("", context.DeadlineExceeded).Run(func(args mock.Arguments) {
+		<-args.Get(0).(context.Context).Done()
+	})
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	// Act
+	_, err := suite.azdSecInfoProvider.GetContainersVulnerabilityScanInfo(ctx, workloadResource)
+
+	// Test - the error of the background fetch is set in cache, so the next requests don't start over
+	suite.NotNil(err)
+	select {
+	case <-isSetInCache:
+	case <-time.After(time.Second):
+		suite.Fail("error of the background fetch wasn't set in cache")
+	}
+	suite.AssertExpectation()
+}
+
+func (suite *AzdSecInfoProviderTestSuite) Test_GetContainersVulnerabilityScanInfo_ConcurrentRequestsSamePodSpec_FetchedOnce() {
+	containers := []*admisionrequest.Container{&_containers[0]}
+	firstWorkloadResource := createWorkloadResourceForTests(containers, nil)
+	secondWorkloadResource := createWorkloadResourceForTests(containers, nil)
+
+	suite.cacheClientMock.On("GetPodSpecCacheKey", mock.Anything).Return(_imageOriginalTest1).Twice()
+	suite.cacheClientMock.On("GetContainerVulnerabilityScanInfofromCache", mock.Anything, _imageOriginalTest1).Return(nil, nil, new(cache.MissingKeyCacheError)).Twice()
+	suite.cacheClientMock.On("SetContainerVulnerabilityScanInfoInCache", mock.Anything, _imageOriginalTest1, _expectedResultsTest1, nil).Return(nil).Maybe()
+	suite.cacheClientMock.On("ResetTimeOutInCacheAfterGettingScanResults", mock.Anything, _imageOriginalTest1).Return(nil).Maybe()
+
+	// The lookups are slow, so the second request is received while the fetch of the first request is in flight
+	suite.tag2DigestResolverMock.On("Resolve", mock.Anything, _imageRedTest1, _resourceCtxTest1).Return(_digestTest1, nil).Once().Run(func(args mock.Arguments) {
+		time.Sleep(300 * time.Millisecond)
+	})
+	suite.argDataProviderMock.On("GetImageVulnerabilityScanResults", mock.Anything, _imageRedTest1.Registry(), _imageRedTest1.Repository(), _digestTest1).Once().Return(_scanStatus, _scanFindings, nil)
+
+	// Act
+	var firstResults, secondResults []*contracts.ContainerVulnerabilityScanInfo
+	var firstErr, secondErr error
+	wg := sync.WaitGroup{}
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		firstResults, firstErr = suite.azdSecInfoProvider.GetContainersVulnerabilityScanInfo(context.Background(), firstWorkloadResource)
+	}()
+	go func() {
+		defer wg.Done()
+		time.Sleep(50 * time.Millisecond)
+		secondResults, secondErr = suite.azdSecInfoProvider.GetContainersVulnerabilityScanInfo(context.Background(), secondWorkloadResource)
+	}()
+	wg.Wait()
+
+	// Test - both requests got the results of the single fetch (Resolve and GetImageVulnerabilityScanResults are called once)
+	suite.Nil(firstErr)
+	suite.Nil(secondErr)
+	suite.Equal(_expectedResultsTest1, firstResults)
+	suite.Equal(_expectedResultsTest1, secondResults)
+	suite.AssertExpectation()
+}
+
+func (suite *AzdSecInfoProviderTestSuite) Test_GetContainersVulnerabilityScanInfo_JoinedFetchFailedOnContext_FetchedAgain() {
+	containers := []*admisionrequest.Container{&_containers[0]}
+	workloadResource := createWorkloadResourceForTests(containers, nil)
+
+	suite.cacheClientMock.On("GetPodSpecCacheKey", workloadResource.Spec).Return(_imageOriginalTest1).Once()
+	suite.cacheClientMock.On("GetContainerVulnerabilityScanInfofromCache", mock.Anything, _imageOriginalTest1).Return(nil, nil, new(cache.MissingKeyCacheError)).Once()
+	suite.cacheClientMock.On("SetContainerVulnerabilityScanInfoInCache", mock.Anything, _imageOriginalTest1, _expectedResultsTest1, nil).Return(nil).Maybe()
+	suite.cacheClientMock.On("ResetTimeOutInCacheAfterGettingScanResults", mock.Anything, _imageOriginalTest1).Return(nil).Maybe()
+	suite.tag2DigestResolverMock.On("Resolve", mock.Anything, _imageRedTest1, _resourceCtxTest1).Return(_digestTest1, nil).Once()
+	suite.argDataProviderMock.On("GetImageVulnerabilityScanResults", mock.Anything, _imageRedTest1.Registry(), _imageRedTest1.Repository(), _digestTest1).Once().Return(_scanStatus, _scanFindings, nil)
+
+	// The fetch of another request is in flight and fails on its context while the request waits for it
+	otherFetch, _ := suite.azdSecInfoProvider.inFlightFetches.startOrJoin(_imageOriginalTest1)
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		suite.azdSecInfoProvider.inFlightFetches.complete(_imageOriginalTest1, otherFetch, utils.NewChannelDataWrapper(nil, errors.Wrap(context.DeadlineExceeded, "lookup failed")))
+	}()
+
+	// Act
+	res, err := suite.azdSecInfoProvider.GetContainersVulnerabilityScanInfo(context.Background(), workloadResource)
+
+	// Test - the request fetched the results itself instead of returning the context error of the other request
+	suite.Nil(err)
+	suite.Equal(_expectedResultsTest1, res)
+	suite.AssertExpectation()
+}
+
+func (suite *AzdSecInfoProviderTestSuite) Test_GetContainersVulnerabilityScanInfo_JoinedFetchFailed_ErrorReturned() {
+	containers := []*admisionrequest.Container{&_containers[0]}
+	workloadResource := createWorkloadResourceForTests(containers, nil)
+	expectedErr := errors.New("lookup failed")
+
+	suite.cacheClientMock.On("GetPodSpecCacheKey", workloadResource.Spec).Return(_imageOriginalTest1).Once()
+	suite.cacheClientMock.On("GetContainerVulnerabilityScanInfofromCache", mock.Anything, _imageOriginalTest1).Return(nil, nil, new(cache.MissingKeyCacheError)).Once()
+	suite.cacheClientMock.On("ResetTimeOutInCacheAfterGettingScanResults", mock.Anything, _imageOriginalTest1).Return(nil).Maybe()
+
+	otherFetch, _ := suite.azdSecInfoProvider.inFlightFetches.startOrJoin(_imageOriginalTest1)
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		suite.azdSecInfoProvider.inFlightFetches.complete(_imageOriginalTest1, otherFetch, utils.NewChannelDataWrapper(nil, expectedErr))
+	}()
+
+	// Act
+	res, err := suite.azdSecInfoProvider.GetContainersVulnerabilityScanInfo(context.Background(), workloadResource)
+
+	// Test - errors that aren't context errors are shared with the joined requests
+	suite.Equal(expectedErr, errors.Cause(err))
+	suite.Nil(res)
+	suite.tag2DigestResolverMock.AssertNotCalled(suite.T(), "Resolve", mock.Anything, mock.Anything, mock.Anything)
+	suite.AssertExpectation()
+}
+
+func (suite *AzdSecInfoProviderTestSuite) Test_GetContainersVulnerabilityScanInfo_LockHeldByReplica_ReplicaResultsReturnedWithoutFetching() {
+	containers := []*admisionrequest.Container{&_containers[0]}
+	workloadResource := createWorkloadResourceForTests(containers, nil)
+	distributedLockMock := &coalescingMocks.IDistributedLock{}
+	suite.azdSecInfoProvider = NewAzdSecInfoProvider(instrumentation.NewNoOpInstrumentationProvider(), suite.argDataProviderMock, suite.tag2DigestResolverMock, suite.signatureVerifierMock, suite.artifactsDiscovererMock, &utils.TimeoutConfiguration{TimeDurationInMS: _TimeDurationGetContainersVulnerabilityScanInfo}, &utils.TimeoutConfiguration{}, &utils.TimeoutConfiguration{}, suite.cacheClientMock, distributedLockMock)
+
+	distributedLockMock.On("TryLock", mock.Anything, _imageOriginalTest1).Return("", false, nil).Once()
+	distributedLockMock.On("WaitUntilReleased", mock.Anything, _imageOriginalTest1).Return(nil).Once()
+	suite.cacheClientMock.On("GetPodSpecCacheKey", workloadResource.Spec).Return(_imageOriginalTest1).Once()
+	// First - the cache lookup of the request (miss). Second - the results of the replica that held the lock.
+	suite.cacheClientMock.On("GetContainerVulnerabilityScanInfofromCache", mock.Anything, _imageOriginalTest1).Return(nil, nil, new(cache.MissingKeyCacheError)).Once()
+	suite.cacheClientMock.On("GetContainerVulnerabilityScanInfofromCache", mock.Anything, _imageOriginalTest1).Return(_expectedResultsTest1, nil, nil).Once()
+	suite.cacheClientMock.On("ResetTimeOutInCacheAfterGettingScanResults", mock.Anything, _imageOriginalTest1).Return(nil).Maybe()
+
+	// Act
+	res, err := suite.azdSecInfoProvider.GetContainersVulnerabilityScanInfo(context.Background(), workloadResource)
+
+	// Test - no lookups (tag2digest and ARG mocks have no expectations) and the results aren't set in cache again
+	suite.Nil(err)
+	suite.Equal(_expectedResultsTest1, res)
+	suite.AssertExpectation()
+	distributedLockMock.AssertExpectations(suite.T())
+}
+
+func (suite *AzdSecInfoProviderTestSuite) Test_GetContainersVulnerabilityScanInfo_LockAcquired_LockReleasedAfterResultsSetInCache() {
+	containers := []*admisionrequest.Container{&_containers[0]}
+	workloadResource := createWorkloadResourceForTests(containers, nil)
+	distributedLockMock := &coalescingMocks.IDistributedLock{}
+	suite.azdSecInfoProvider = NewAzdSecInfoProvider(instrumentation.NewNoOpInstrumentationProvider(), suite.argDataProviderMock, suite.tag2DigestResolverMock, suite.signatureVerifierMock, suite.artifactsDiscovererMock, &utils.TimeoutConfiguration{TimeDurationInMS: _TimeDurationGetContainersVulnerabilityScanInfo}, &utils.TimeoutConfiguration{}, &utils.TimeoutConfiguration{}, suite.cacheClientMock, distributedLockMock)
+	isSetInCache := make(chan struct{})
+	isUnlocked := make(chan struct{})
+
+	distributedLockMock.On("TryLock", mock.Anything, _imageOriginalTest1).Return("token", true, nil).Once()
+	distributedLockMock.On("Unlock", mock.Anything, _imageOriginalTest1, "token").Return(nil).Once().Run(func(args mock.Arguments) {
+		// The lock has to be released only after the results are set in cache
+		select {
+		case <-isSetInCache:
+		default:
+			suite.Fail("lock released before the results are set in cache")
+		}
+		close(isUnlocked)
+	})
+	suite.cacheClientMock.On("GetPodSpecCacheKey", workloadResource.Spec).Return(_imageOriginalTest1).Once()
+	suite.cacheClientMock.On("GetContainerVulnerabilityScanInfofromCache", mock.Anything, _imageOriginalTest1).Return(nil, nil, new(cache.MissingKeyCacheError)).Once()
+	suite.cacheClientMock.On("SetContainerVulnerabilityScanInfoInCache", mock.Anything, _imageOriginalTest1, _expectedResultsTest1, nil).Return(nil).Once().Run(func(args mock.Arguments) {
+		close(isSetInCache)
+	})
+	suite.cacheClientMock.On("ResetTimeOutInCacheAfterGettingScanResults", mock.Anything, _imageOriginalTest1).Return(nil).Maybe()
+	suite.tag2DigestResolverMock.On("Resolve", mock.Anything, _imageRedTest1, _resourceCtxTest1).Return(_digestTest1, nil).Once()
+	suite.argDataProviderMock.On("GetImageVulnerabilityScanResults", mock.Anything, _imageRedTest1.Registry(), _imageRedTest1.Repository(), _digestTest1).Once().Return(_scanStatus, _scanFindings, nil)
+
+	// Act
+	res, err := suite.azdSecInfoProvider.GetContainersVulnerabilityScanInfo(context.Background(), workloadResource)
+
+	// Test
+	suite.Nil(err)
+	suite.Equal(_expectedResultsTest1, res)
+	select {
+	case <-isUnlocked:
+	case <-time.After(time.Second):
+		suite.Fail("lock wasn't released")
+	}
+	suite.AssertExpectation()
+	distributedLockMock.AssertExpectations(suite.T())
+}
+
 func (suite *AzdSecInfoProviderTestSuite) Test_getContainersVulnerabilityScanInfo_EncounteredTimeout_SecondTimeout() {
 	containers := []*admisionrequest.Container{&_containers[0]}
 	workloadResource := createWorkloadResourceForTests(containers, nil)
 
 	suite.cacheClientMock.On("GetPodSpecCacheKey", workloadResource.Spec).Return(_imageOriginalTest1).Once()
-	suite.cacheClientMock.On("GetContainerVulnerabilityScanInfofromCache", _imageOriginalTest1).Return(nil, nil, new(cache.MissingKeyCacheError)).Once()
-	suite.cacheClientMock.On("GetTimeOutStatus", _imageOriginalTest1).Return(1, nil).Once()
-	suite.cacheClientMock.On("SetTimeOutStatusAfterEncounteredTimeout", _imageOriginalTest1, 2).Return(nil).Once()
-	suite.cacheClientMock.On("SetContainerVulnerabilityScanInfoInCache", _imageOriginalTest1, _expectedResultsTest1, nil).Return(nil).Maybe()
-
-	suite.tag2DigestResolverMock.On("Resolve", _imageRedTest1, _resourceCtxTest1).Return(_digestTest1, nil).Once()
-	suite.argDataProviderMock.On("GetImageVulnerabilityScanResults", _imageRedTest1.Registry(), _imageRedTest1.Repository(), _digestTest1).Once().Return(_scanStatus, _scanFindings, nil).Run(func(args mock.Arguments) {
-		time.Sleep(_defaultTimeDurationGetContainersVulnerabilityScanInfo)
+	suite.cacheClientMock.On("GetContainerVulnerabilityScanInfofromCache", mock.Anything, _imageOriginalTest1).Return(nil, nil, new(cache.MissingKeyCacheError)).Once()
+	suite.cacheClientMock.On("GetTimeOutStatus", mock.Anything, _imageOriginalTest1).Return(1, nil).Once()
+	suite.cacheClientMock.On("SetTimeOutStatusAfterEncounteredTimeout", mock.Anything, _imageOriginalTest1, 2).Return(nil).Once()
+	suite.cacheClientMock.On("SetContainerVulnerabilityScanInfoInCache", mock.Anything, _imageOriginalTest1, _expectedResultsTest1, nil).Return(nil).Maybe()
+
+	suite.tag2DigestResolverMock.On("Resolve", mock.Anything, _imageRedTest1, _resourceCtxTest1).Return(_digestTest1, nil).Once()
+	suite.argDataProviderMock.On("GetImageVulnerabilityScanResults", mock.Anything, _imageRedTest1.Registry(), _imageRedTest1.Repository(), _digestTest1).Once().Return(_scanStatus, _scanFindings, nil).Run(func(args mock.Arguments) {
+		time.Sleep(_defaultTimeDurationGetContainersVulnerabilityScanInfo + 500*time.Millisecond)
 	})
 
 	// Act
-	res, _ := suite.azdSecInfoProvider.GetContainersVulnerabilityScanInfo(workloadResource)
+	res, _ := suite.azdSecInfoProvider.GetContainersVulnerabilityScanInfo(context.Background(), workloadResource)
 	// Test
 	suite.Equal(res, _expectedResultsTest3)
 	suite.AssertExpectation()
@@ -259,17 +723,17 @@ func (suite *AzdSecInfoProviderTestSuite) Test_getContainersVulnerabilityScanInf
 	workloadResource := createWorkloadResourceForTests(containers, nil)
 
 	suite.cacheClientMock.On("GetPodSpecCacheKey", workloadResource.Spec).Return(_imageOriginalTest1).Once()
-	suite.cacheClientMock.On("GetContainerVulnerabilityScanInfofromCache", _imageOriginalTest1).Return(nil, nil, new(cache.MissingKeyCacheError)).Once()
-	suite.cacheClientMock.On("GetTimeOutStatus", _imageOriginalTest1).Return(2, nil).Once()
-	suite.cacheClientMock.On("SetContainerVulnerabilityScanInfoInCache", _imageOriginalTest1, _expectedResultsTest1, nil).Return(nil).Maybe()
+	suite.cacheClientMock.On("GetContainerVulnerabilityScanInfofromCache", mock.Anything, _imageOriginalTest1).Return(nil, nil, new(cache.MissingKeyCacheError)).Once()
+	suite.cacheClientMock.On("GetTimeOutStatus", mock.Anything, _imageOriginalTest1).Return(2, nil).Once()
+	suite.cacheClientMock.On("SetContainerVulnerabilityScanInfoInCache", mock.Anything, _imageOriginalTest1, _expectedResultsTest1, nil).Return(nil).Maybe()
 
-	suite.tag2DigestResolverMock.On("Resolve", _imageRedTest1, _resourceCtxTest1).Return(_digestTest1, nil).Once()
-	suite.argDataProviderMock.On("GetImageVulnerabilityScanResults", _imageRedTest1.Registry(), _imageRedTest1.Repository(), _digestTest1).Once().Return(_scanStatus, _scanFindings, nil).Run(func(args mock.Arguments) {
+	suite.tag2DigestResolverMock.On("Resolve", mock.Anything, _imageRedTest1, _resourceCtxTest1).Return(_digestTest1, nil).Once()
+	suite.argDataProviderMock.On("GetImageVulnerabilityScanResults", mock.Anything, _imageRedTest1.Registry(), _imageRedTest1.Repository(), _digestTest1).Once().Return(_scanStatus, _scanFindings, nil).Run(func(args mock.Arguments) {
 		time.Sleep(_defaultTimeDurationGetContainersVulnerabilityScanInfo * time.Second)
 	})
 
 	// Act
-	res, err := suite.azdSecInfoProvider.GetContainersVulnerabilityScanInfo(workloadResource)
+	res, err := suite.azdSecInfoProvider.GetContainersVulnerabilityScanInfo(context.Background(), workloadResource)
 	// Test
 	suite.Nil(res)
 	suite.NotNil(err)
@@ -281,17 +745,17 @@ func (suite *AzdSecInfoProviderTestSuite) Test_getContainersVulnerabilityScanInf
 	workloadResource := createWorkloadResourceForTests(containers, nil)
 
 	suite.cacheClientMock.On("GetPodSpecCacheKey", workloadResource.Spec).Return(_imageOriginalTest1).Once()
-	suite.cacheClientMock.On("GetContainerVulnerabilityScanInfofromCache", _imageOriginalTest1).Return(nil, nil, new(cache.MissingKeyCacheError)).Once()
-	suite.cacheClientMock.On("GetTimeOutStatus", _imageOriginalTest1).Return(-1, errors.New("")).Once()
-	suite.cacheClientMock.On("SetContainerVulnerabilityScanInfoInCache", _imageOriginalTest1, _expectedResultsTest1, nil).Return(nil).Maybe()
+	suite.cacheClientMock.On("GetContainerVulnerabilityScanInfofromCache", mock.Anything, _imageOriginalTest1).Return(nil, nil, new(cache.MissingKeyCacheError)).Once()
+	suite.cacheClientMock.On("GetTimeOutStatus", mock.Anything, _imageOriginalTest1).Return(-1, errors.New("")).Once()
+	suite.cacheClientMock.On("SetContainerVulnerabilityScanInfoInCache", mock.Anything, _imageOriginalTest1, _expectedResultsTest1, nil).Return(nil).Maybe()
 
-	suite.tag2DigestResolverMock.On("Resolve", _imageRedTest1, _resourceCtxTest1).Return(_digestTest1, nil).Once()
-	suite.argDataProviderMock.On("GetImageVulnerabilityScanResults", _imageRedTest1.Registry(), _imageRedTest1.Repository(), _digestTest1).Once().Return(_scanStatus, _scanFindings, nil).Run(func(args mock.Arguments) {
+	suite.tag2DigestResolverMock.On("Resolve", mock.Anything, _imageRedTest1, _resourceCtxTest1).Return(_digestTest1, nil).Once()
+	suite.argDataProviderMock.On("GetImageVulnerabilityScanResults", mock.Anything, _imageRedTest1.Registry(), _imageRedTest1.Repository(), _digestTest1).Once().Return(_scanStatus, _scanFindings, nil).Run(func(args mock.Arguments) {
 		time.Sleep(_defaultTimeDurationGetContainersVulnerabilityScanInfo * time.Second)
 	})
 
 	// Act
-	res, err := suite.azdSecInfoProvider.GetContainersVulnerabilityScanInfo(workloadResource)
+	res, err := suite.azdSecInfoProvider.GetContainersVulnerabilityScanInfo(context.Background(), workloadResource)
 	// Test
 	suite.Nil(res)
 	suite.NotNil(err)
@@ -301,13 +765,13 @@ func (suite *AzdSecInfoProviderTestSuite) Test_getContainersVulnerabilityScanInf
 func (suite *AzdSecInfoProviderTestSuite) Test_GetContainersVulnerabilityScanInfo_Run_In_Parallel_AllContainersNil() {
 
 	suite.cacheClientMock.On("GetPodSpecCacheKey", mock.Anything).Return(_imageOriginalTest1).Once()
-	suite.cacheClientMock.On("GetContainerVulnerabilityScanInfofromCache", mock.Anything).Return(nil, nil, new(cache.MissingKeyCacheError)).Once()
-	suite.cacheClientMock.On("ResetTimeOutInCacheAfterGettingScanResults", mock.Anything).Return(nil).Maybe()
-	suite.cacheClientMock.On("SetContainerVulnerabilityScanInfoInCache", mock.Anything, mock.Anything, mock.Anything).Return(nil).Maybe()
+	suite.cacheClientMock.On("GetContainerVulnerabilityScanInfofromCache", mock.Anything, mock.Anything).Return(nil, nil, new(cache.MissingKeyCacheError)).Once()
+	suite.cacheClientMock.On("ResetTimeOutInCacheAfterGettingScanResults", mock.Anything, mock.Anything).Return(nil).Maybe()
+	suite.cacheClientMock.On("SetContainerVulnerabilityScanInfoInCache", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil).Maybe()
 
 	workloadResource := createWorkloadResourceForTests(nil, nil)
 	// Act
-	res, err := suite.azdSecInfoProvider.GetContainersVulnerabilityScanInfo(workloadResource)
+	res, err := suite.azdSecInfoProvider.GetContainersVulnerabilityScanInfo(context.Background(), workloadResource)
 	suite.NotNil(res)
 	suite.Nil(err)
 	suite.Len(res, 0)
@@ -315,25 +779,25 @@ func (suite *AzdSecInfoProviderTestSuite) Test_GetContainersVulnerabilityScanInf
 
 func (suite *AzdSecInfoProviderTestSuite) Test_GetContainersVulnerabilityScanInfo_Run_In_Parallel_InitContainersNil() {
 	suite.cacheClientMock.On("GetPodSpecCacheKey", mock.Anything).Return(_imageOriginalTest1)
-	suite.cacheClientMock.On("GetContainerVulnerabilityScanInfofromCache", mock.Anything).Return(nil, nil, new(cache.MissingKeyCacheError))
-	suite.cacheClientMock.On("ResetTimeOutInCacheAfterGettingScanResults", mock.Anything).Return(nil).Maybe()
-	suite.cacheClientMock.On("SetContainerVulnerabilityScanInfoInCache", mock.Anything, mock.Anything, mock.Anything).Return(nil).Maybe()
+	suite.cacheClientMock.On("GetContainerVulnerabilityScanInfofromCache", mock.Anything, mock.Anything).Return(nil, nil, new(cache.MissingKeyCacheError))
+	suite.cacheClientMock.On("ResetTimeOutInCacheAfterGettingScanResults", mock.Anything, mock.Anything).Return(nil).Maybe()
+	suite.cacheClientMock.On("SetContainerVulnerabilityScanInfoInCache", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil).Maybe()
 	suite.goroutineTest(suite.getContainersVulnerabilityScanInfoTest_InitContainersNil)
 }
 
 func (suite *AzdSecInfoProviderTestSuite) Test_GetContainersVulnerabilityScanInfo_Run_In_Parallel_ContainersNil() {
 	suite.cacheClientMock.On("GetPodSpecCacheKey", mock.Anything).Return(_imageOriginalTest1)
-	suite.cacheClientMock.On("GetContainerVulnerabilityScanInfofromCache", mock.Anything).Return(nil, nil, new(cache.MissingKeyCacheError))
-	suite.cacheClientMock.On("ResetTimeOutInCacheAfterGettingScanResults", mock.Anything).Return(nil).Maybe()
-	suite.cacheClientMock.On("SetContainerVulnerabilityScanInfoInCache", mock.Anything, mock.Anything, mock.Anything).Return(nil).Maybe()
+	suite.cacheClientMock.On("GetContainerVulnerabilityScanInfofromCache", mock.Anything, mock.Anything).Return(nil, nil, new(cache.MissingKeyCacheError))
+	suite.cacheClientMock.On("ResetTimeOutInCacheAfterGettingScanResults", mock.Anything, mock.Anything).Return(nil).Maybe()
+	suite.cacheClientMock.On("SetContainerVulnerabilityScanInfoInCache", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil).Maybe()
 	suite.goroutineTest(suite.getContainersVulnerabilityScanInfoTest_ContainersNil)
 }
 
 func (suite *AzdSecInfoProviderTestSuite) Test_GetContainersVulnerabilityScanInfo_Run_In_Parallel_OneContainerOneInitContainer() {
 	suite.cacheClientMock.On("GetPodSpecCacheKey", mock.Anything).Return(_imageOriginalTest1)
-	suite.cacheClientMock.On("GetContainerVulnerabilityScanInfofromCache", mock.Anything).Return(nil, nil, new(cache.MissingKeyCacheError))
-	suite.cacheClientMock.On("ResetTimeOutInCacheAfterGettingScanResults", mock.Anything).Return(nil).Maybe()
-	suite.cacheClientMock.On("SetContainerVulnerabilityScanInfoInCache", mock.Anything, mock.Anything, mock.Anything).Return(nil).Maybe()
+	suite.cacheClientMock.On("GetContainerVulnerabilityScanInfofromCache", mock.Anything, mock.Anything).Return(nil, nil, new(cache.MissingKeyCacheError))
+	suite.cacheClientMock.On("ResetTimeOutInCacheAfterGettingScanResults", mock.Anything, mock.Anything).Return(nil).Maybe()
+	suite.cacheClientMock.On("SetContainerVulnerabilityScanInfoInCache", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil).Maybe()
 	suite.goroutineTest(suite.getContainersVulnerabilityScanInfoTest_OneContainerOneInitContainer)
 }
 
@@ -376,16 +840,16 @@ func (suite *AzdSecInfoProviderTestSuite) getContainersVulnerabilityScanInfoTest
 
 func (suite *AzdSecInfoProviderTestSuite) getContainersVulnerabilityScanInfoTest(workloadResource *admisionrequest.WorkloadResource, waitFirstContainer time.Duration, waitSecondContainer time.Duration) {
 
-	suite.tag2DigestResolverMock.On("Resolve", _imageRedTest1, _resourceCtxTest1).Return(_digestTest1, nil).Once().Run(func(args mock.Arguments) {
+	suite.tag2DigestResolverMock.On("Resolve", mock.Anything, _imageRedTest1, _resourceCtxTest1).Return(_digestTest1, nil).Once().Run(func(args mock.Arguments) {
 		time.Sleep(waitFirstContainer * time.Second)
 	})
-	suite.argDataProviderMock.On("GetImageVulnerabilityScanResults", _imageRedTest1.Registry(), _imageRedTest1.Repository(), _digestTest1).Once().Return(contracts.Unscanned, nil, nil)
-	suite.tag2DigestResolverMock.On("Resolve", _imageRedTest2, _resourceCtxTest2).Return(_digestTest2, nil).Once().Run(func(args mock.Arguments) {
+	suite.argDataProviderMock.On("GetImageVulnerabilityScanResults", mock.Anything, _imageRedTest1.Registry(), _imageRedTest1.Repository(), _digestTest1).Once().Return(contracts.Unscanned, nil, nil)
+	suite.tag2DigestResolverMock.On("Resolve", mock.Anything, _imageRedTest2, _resourceCtxTest2).Return(_digestTest2, nil).Once().Run(func(args mock.Arguments) {
 		time.Sleep(waitSecondContainer * time.Second)
 	})
-	suite.argDataProviderMock.On("GetImageVulnerabilityScanResults", _imageRedTest2.Registry(), _imageRedTest2.Repository(), _digestTest2).Once().Return(contracts.Unscanned, nil, nil)
+	suite.argDataProviderMock.On("GetImageVulnerabilityScanResults", mock.Anything, _imageRedTest2.Registry(), _imageRedTest2.Repository(), _digestTest2).Once().Return(contracts.Unscanned, nil, nil)
 	// Act
-	res, _ := suite.azdSecInfoProvider.GetContainersVulnerabilityScanInfo(workloadResource)
+	res, _ := suite.azdSecInfoProvider.GetContainersVulnerabilityScanInfo(context.Background(), workloadResource)
 	// Test
 	suite.Equal(res[0].ScanStatus, contracts.Unscanned)
 	suite.Equal(res[1].ScanStatus, contracts.Unscanned)
@@ -394,6 +858,8 @@ func (suite *AzdSecInfoProviderTestSuite) getContainersVulnerabilityScanInfoTest
 }
 
 func (suite *AzdSecInfoProviderTestSuite) AssertExpectation() {
+	suite.signatureVerifierMock.AssertExpectations(suite.T())
+	suite.artifactsDiscovererMock.AssertExpectations(suite.T())
 	suite.argDataProviderMock.AssertExpectations(suite.T())
 	suite.tag2DigestResolverMock.AssertExpectations(suite.T())
 	suite.cacheClientMock.AssertExpectations(suite.T())
@@ -0,0 +1,114 @@
+package azdsecinfo
+
+import (
+	"context"
+	"fmt"
+	"github.com/Azure/AzureDefender-K8S-InClusterDefense/cmd/webhook/admisionrequest"
+	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/azdsecinfo/contracts"
+	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/utils"
+	"github.com/pkg/errors"
+	"sync"
+)
+
+// containerVulnerabilityScanInfoCollector collects the ContainerVulnerabilityScanInfo of each container as soon as it is fetched,
+// so in case of timeout the containers that were already fetched are returned with their real results.
+// It is safe for concurrent use - the results are added by the fetching goroutines and read by the requests' goroutines.
+type containerVulnerabilityScanInfoCollector struct {
+	// results maps each container (name and image - the same format as the pod spec cache key, so requests with the
+	// same pod spec cache key share the results) to its fetched ContainerVulnerabilityScanInfo
+	results map[string]*contracts.ContainerVulnerabilityScanInfo
+	// lock protects results
+	lock sync.RWMutex
+}
+
+// newContainerVulnerabilityScanInfoCollector Ctor for containerVulnerabilityScanInfoCollector
+func newContainerVulnerabilityScanInfoCollector() *containerVulnerabilityScanInfoCollector {
+	return &containerVulnerabilityScanInfoCollector{
+		results: make(map[string]*contracts.ContainerVulnerabilityScanInfo),
+	}
+}
+
+// add adds the fetched ContainerVulnerabilityScanInfo of the container
+func (collector *containerVulnerabilityScanInfoCollector) add(container *admisionrequest.Container, info *contracts.ContainerVulnerabilityScanInfo) {
+	collector.lock.Lock()
+	defer collector.lock.Unlock()
+	collector.results[getContainerKey(container)] = info
+}
+
+// get returns the fetched ContainerVulnerabilityScanInfo of the container - false if the container is still pending
+func (collector *containerVulnerabilityScanInfoCollector) get(container *admisionrequest.Container) (*contracts.ContainerVulnerabilityScanInfo, bool) {
+	collector.lock.RLock()
+	defer collector.lock.RUnlock()
+	info, isFetched := collector.results[getContainerKey(container)]
+	return info, isFetched
+}
+
+// containersVulnerabilityScanInfoFetch is a fetch of the ContainersVulnerabilityScanInfo of a pod spec. It's shared by the
+// concurrent requests with the same pod spec cache key, so only one of them fetches the results.
+type containersVulnerabilityScanInfoFetch struct {
+	// collector collects the results of the containers as they arrive (partial results in case of timeout)
+	collector *containerVulnerabilityScanInfoCollector
+	// done is closed when the fetch is completed
+	done chan struct{}
+	// channelData is the results of the fetch ([]*contracts.ContainerVulnerabilityScanInfo and error) - set before done is closed
+	channelData *utils.ChannelDataWrapper
+}
+
+// isFailedOnContext returns true in case that the completed fetch failed because its context was canceled or its deadline passed
+func (fetch *containersVulnerabilityScanInfoFetch) isFailedOnContext() bool {
+	if fetch.channelData == nil {
+		return false
+	}
+	_, err := fetch.channelData.GetData()
+	return errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded)
+}
+
+// inFlightFetches tracks the in flight fetch of each pod spec cache key
+type inFlightFetches struct {
+	// fetches maps pod spec cache key to its in flight fetch
+	fetches map[string]*containersVulnerabilityScanInfoFetch
+	// lock protects fetches
+	lock sync.Mutex
+}
+
+// newInFlightFetches Ctor for inFlightFetches
+func newInFlightFetches() *inFlightFetches {
+	return &inFlightFetches{
+		fetches: make(map[string]*containersVulnerabilityScanInfoFetch),
+	}
+}
+
+// startOrJoin returns the in flight fetch of the pod spec cache key. In case that there is no in flight fetch, a new fetch
+// is started and true is returned - the caller is responsible to fetch the results and to complete the fetch.
+func (inFlight *inFlightFetches) startOrJoin(podSpecCacheKey string) (*containersVulnerabilityScanInfoFetch, bool) {
+	inFlight.lock.Lock()
+	defer inFlight.lock.Unlock()
+	if fetch, isInFlight := inFlight.fetches[podSpecCacheKey]; isInFlight {
+		return fetch, false
+	}
+
+	fetch := &containersVulnerabilityScanInfoFetch{
+		collector: newContainerVulnerabilityScanInfoCollector(),
+		done:      make(chan struct{}),
+	}
+	inFlight.fetches[podSpecCacheKey] = fetch
+	return fetch, true
+}
+
+// complete sets the results of the fetch, notifies its waiting requests and removes it from the in flight fetches,
+// so the next requests (that missed the cache) start a new fetch.
+func (inFlight *inFlightFetches) complete(podSpecCacheKey string, fetch *containersVulnerabilityScanInfoFetch, channelData *utils.ChannelDataWrapper) {
+	inFlight.lock.Lock()
+	if inFlight.fetches[podSpecCacheKey] == fetch {
+		delete(inFlight.fetches, podSpecCacheKey)
+	}
+	inFlight.lock.Unlock()
+
+	fetch.channelData = channelData
+	close(fetch.done)
+}
+
+// getContainerKey returns the key of the container - its name and image
+func getContainerKey(container *admisionrequest.Container) string {
+	return fmt.Sprintf("%s:%s", container.Name, container.Image)
+}
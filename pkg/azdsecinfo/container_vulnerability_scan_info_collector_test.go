@@ -0,0 +1,117 @@
+package azdsecinfo
+
+import (
+	"context"
+	"fmt"
+	"github.com/Azure/AzureDefender-K8S-InClusterDefense/cmd/webhook/admisionrequest"
+	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/azdsecinfo/contracts"
+	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/utils"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/suite"
+	"sync"
+	"testing"
+)
+
+type ContainerVulnerabilityScanInfoCollectorTestSuite struct {
+	suite.Suite
+	collector *containerVulnerabilityScanInfoCollector
+}
+
+// This will run before each test in the suite
+func (suite *ContainerVulnerabilityScanInfoCollectorTestSuite) SetupTest() {
+	suite.collector = newContainerVulnerabilityScanInfoCollector()
+}
+
+func (suite *ContainerVulnerabilityScanInfoCollectorTestSuite) Test_get_ContainerNotAdded_NotFetched() {
+	info, isFetched := suite.collector.get(&admisionrequest.Container{Name: "containerTest1"})
+	suite.False(isFetched)
+	suite.Nil(info)
+}
+
+func (suite *ContainerVulnerabilityScanInfoCollectorTestSuite) Test_get_ContainerAdded_ReturnsItsResults() {
+	container := &admisionrequest.Container{Name: "containerTest1"}
+	otherContainer := &admisionrequest.Container{Name: "containerTest2"}
+	expected := &contracts.ContainerVulnerabilityScanInfo{Name: container.Name, ScanStatus: contracts.HealthyScan}
+
+	suite.collector.add(container, expected)
+
+	info, isFetched := suite.collector.get(container)
+	suite.True(isFetched)
+	suite.Equal(expected, info)
+	_, isFetched = suite.collector.get(otherContainer)
+	suite.False(isFetched)
+}
+
+func (suite *ContainerVulnerabilityScanInfoCollectorTestSuite) Test_add_Concurrently_AllResultsCollected() {
+	containers := make([]*admisionrequest.Container, 50)
+	wg := sync.WaitGroup{}
+	for i := range containers {
+		containers[i] = &admisionrequest.Container{Name: fmt.Sprintf("containerTest%d", i), Image: _imageOriginalTest1}
+		wg.Add(1)
+		go func(container *admisionrequest.Container) {
+			defer wg.Done()
+			suite.collector.add(container, &contracts.ContainerVulnerabilityScanInfo{})
+		}(containers[i])
+	}
+	wg.Wait()
+
+	for _, container := range containers {
+		_, isFetched := suite.collector.get(container)
+		suite.True(isFetched)
+	}
+}
+
+func (suite *ContainerVulnerabilityScanInfoCollectorTestSuite) Test_startOrJoin_FetchInFlight_Joined() {
+	inFlight := newInFlightFetches()
+
+	fetch, isStarted := inFlight.startOrJoin(_imageOriginalTest1)
+	joinedFetch, isJoinedStarted := inFlight.startOrJoin(_imageOriginalTest1)
+	otherFetch, isOtherStarted := inFlight.startOrJoin(_imageOriginalTest2)
+
+	suite.True(isStarted)
+	suite.False(isJoinedStarted)
+	suite.Same(fetch, joinedFetch)
+	suite.True(isOtherStarted)
+	suite.NotSame(fetch, otherFetch)
+}
+
+func (suite *ContainerVulnerabilityScanInfoCollectorTestSuite) Test_complete_FetchCompleted_WaitingRequestsNotifiedAndNextRequestStartsNewFetch() {
+	inFlight := newInFlightFetches()
+	fetch, _ := inFlight.startOrJoin(_imageOriginalTest1)
+	channelData := utils.NewChannelDataWrapper(_expectedResultsTest1, nil)
+
+	inFlight.complete(_imageOriginalTest1, fetch, channelData)
+
+	select {
+	case <-fetch.done:
+	default:
+		suite.Fail("fetch isn't done")
+	}
+	suite.Equal(channelData, fetch.channelData)
+	nextFetch, isStarted := inFlight.startOrJoin(_imageOriginalTest1)
+	suite.True(isStarted)
+	suite.NotSame(fetch, nextFetch)
+}
+
+func (suite *ContainerVulnerabilityScanInfoCollectorTestSuite) Test_isFailedOnContext() {
+	inFlight := newInFlightFetches()
+	tests := []struct {
+		channelData *utils.ChannelDataWrapper
+		expected    bool
+	}{
+		{utils.NewChannelDataWrapper(nil, errors.Wrap(context.Canceled, "canceled")), true},
+		{utils.NewChannelDataWrapper(nil, errors.Wrap(context.DeadlineExceeded, "deadline exceeded")), true},
+		{utils.NewChannelDataWrapper(nil, errors.New("other error")), false},
+		{utils.NewChannelDataWrapper(_expectedResultsTest1, nil), false},
+	}
+
+	for _, test := range tests {
+		fetch, _ := inFlight.startOrJoin(_imageOriginalTest1)
+		inFlight.complete(_imageOriginalTest1, fetch, test.channelData)
+		suite.Equal(test.expected, fetch.isFailedOnContext(), test.channelData)
+	}
+}
+
+func TestContainerVulnerabilityScanInfoCollector(t *testing.T) {
+	suite.Run(t, new(ContainerVulnerabilityScanInfoCollectorTestSuite))
+}
@@ -7,8 +7,21 @@ import (
 const (
 	AzdSecInfoAnnotationPrefix                    = "azuredefender.io"
 	ContainersVulnerabilityScanInfoAnnotationName = AzdSecInfoAnnotationPrefix + "/containers.vulnerability.scan.info"
+	// ContainersVulnerabilityScanInfoV2AnnotationName is the annotation of the v2 schema (ContainerVulnerabilityScanInfoListV2)
+	ContainersVulnerabilityScanInfoV2AnnotationName = ContainersVulnerabilityScanInfoAnnotationName + ".v2"
 )
 
+// SchemaVersion Enum
+const (
+	// SchemaVersionV1 is the schema of ContainerVulnerabilityScanInfoList - the scan findings of each container
+	SchemaVersionV1 SchemaVersion = "v1"
+	// SchemaVersionV2 is the schema of ContainerVulnerabilityScanInfoListV2 - precomputed summary of each container
+	SchemaVersionV2 SchemaVersion = "v2"
+)
+
+// SchemaVersion represents the schema version of the scan info annotation enum
+type SchemaVersion string
+
 // ScanStatus Enum
 const (
 	Unscanned     ScanStatus = "unscanned"
@@ -19,13 +32,66 @@ const (
 // ScanStatus represents container image scan status enum
 type ScanStatus string
 
+// SignatureStatus Enum
+const (
+	// SignatureVerified at least one signature of the image digest was verified using the configured verification keys
+	SignatureVerified SignatureStatus = "verified"
+	// Unsigned no signature is attached to the image digest
+	Unsigned SignatureStatus = "unsigned"
+	// InvalidSignature signatures are attached to the image digest but none of them was verified using the configured verification keys
+	InvalidSignature SignatureStatus = "invalidSignature"
+	// SignatureUnverified the signature verification couldn't be completed (e.g. registry error)
+	SignatureUnverified SignatureStatus = "unverified"
+	// SignatureUnknown the signature verification didn't complete before its timeout
+	SignatureUnknown SignatureStatus = "unknown"
+)
+
+// SignatureStatus represents container image signature verification status enum
+type SignatureStatus string
+
+// SBOMFormat Enum
+const (
+	SPDX      SBOMFormat = "spdx"
+	CycloneDX SBOMFormat = "cyclonedx"
+)
+
+// SBOMFormat represents the format of a software bill of materials enum
+type SBOMFormat string
+
+// ScanInfoEncoding Enum
+const (
+	// FullScanInfoEncoding all the scan findings of the containers are in Containers
+	FullScanInfoEncoding ScanInfoEncoding = "full"
+	// SummarizedScanInfoEncoding the scan findings of each container are summarized to counts (ScanFindingsSummary) and only
+	// the most severe scan findings are kept in ScanFindings
+	SummarizedScanInfoEncoding ScanInfoEncoding = "summarized"
+	// GzipScanInfoEncoding the containers are gzip compressed and base64 encoded in CompressedContainers (Containers is nil)
+	GzipScanInfoEncoding ScanInfoEncoding = "gzip"
+)
+
+// ScanInfoEncoding represents the encoding of the containers of ContainerVulnerabilityScanInfoList enum
+type ScanInfoEncoding string
+
 // ContainerVulnerabilityScanInfoList a list of container vulnerability scan info
 type ContainerVulnerabilityScanInfoList struct {
 	//GeneratedTimestamp represents the time the scan info list (this) was generated
 	GeneratedTimestamp time.Time `json:"generatedTimestamp"`
 
+	// SchemaVersion represents the schema version of the list (SchemaVersionV1). Omitted in lists that were generated before it was added.
+	SchemaVersion SchemaVersion `json:"schemaVersion,omitempty"`
+
+	// Encoding represents the encoding of the containers. Omitted means FullScanInfoEncoding.
+	Encoding ScanInfoEncoding `json:"encoding,omitempty"`
+
 	//Containers List of ContainerVulnerabilityScanInfo that represents all the scan info of containers
 	Containers []*ContainerVulnerabilityScanInfo `json:"containers"`
+
+	// CompressedContainers the json of Containers - gzip compressed and base64 encoded. Set only in GzipScanInfoEncoding.
+	CompressedContainers string `json:"compressedContainers,omitempty"`
+
+	// VulnerabilityReport represents the name of the ContainerVulnerabilityReport of the workload (in the namespace of the workload)
+	// that holds the full scan info. Omitted if no report is written.
+	VulnerabilityReport string `json:"vulnerabilityReport,omitempty"`
 }
 
 // ContainerVulnerabilityScanInfo represents containers vulnerability scan information
@@ -42,6 +108,15 @@ type ContainerVulnerabilityScanInfo struct {
 	// ScanFindings vulnerability scan findings for image
 	ScanFindings []*ScanFinding `json:"scanFindings"`
 
+	// ScanFindingsSummary counts of all the scan findings per severity and patchable flag. Set only in SummarizedScanInfoEncoding.
+	ScanFindingsSummary []*ScanFindingsCount `json:"scanFindingsSummary,omitempty"`
+
+	// SignatureStatus image signature verification status of the resolved digest (empty if signature verification is disabled)
+	SignatureStatus SignatureStatus `json:"signatureStatus,omitempty"`
+
+	// SupplyChainArtifacts the SBOMs and attestations that are attached to the resolved digest (nil if artifacts discovery is disabled or failed)
+	SupplyChainArtifacts *SupplyChainArtifacts `json:"supplyChainArtifacts,omitempty"`
+
 	// Additional data to add on annotaitons like URL or error if it skipped ( TODO for some reson omitempty doesnt work here and it is still printed on nil
 	AdditionalData map[string]string `json:"additionalData,omitempty"`
 }
@@ -56,6 +131,39 @@ type Image struct {
 	Digest string `json:"digest"`
 }
 
+// SupplyChainArtifacts represents the supply chain artifacts that are attached to an image digest
+type SupplyChainArtifacts struct {
+	// SBOMs the software bill of materials that are attached to the digest
+	SBOMs []*SBOM `json:"sboms"`
+
+	// Attestations the in-toto attestations that are attached to the digest
+	Attestations []*Attestation `json:"attestations"`
+
+	// OSDistro the operating system distribution of the image as reported by its SBOM (e.g. "alpine 3.15.0") - empty if unknown
+	OSDistro string `json:"osDistro,omitempty"`
+
+	// BaseImage the base image of the image as reported by its manifest annotations - empty if unknown
+	BaseImage string `json:"baseImage,omitempty"`
+}
+
+// SBOM represents a software bill of materials that is attached to an image digest
+type SBOM struct {
+	// Format the format of the SBOM
+	Format SBOMFormat `json:"format"`
+
+	// Digest the digest of the SBOM artifact
+	Digest string `json:"digest"`
+}
+
+// Attestation represents an in-toto attestation that is attached to an image digest
+type Attestation struct {
+	// PredicateType the predicate type of the attestation (e.g. "https://slsa.dev/provenance/v0.2") - empty if unknown
+	PredicateType string `json:"predicateType,omitempty"`
+
+	// Digest the digest of the attestation artifact
+	Digest string `json:"digest"`
+}
+
 // ScanFinding represents a single findings of image vulnerability scan
 type ScanFinding struct {
 	// Patchable represents whether finding is patchable
@@ -68,6 +176,18 @@ type ScanFinding struct {
 	Severity string `json:"severity"`
 }
 
+// ScanFindingsCount represents the count of the scan findings with the same severity and patchable flag
+type ScanFindingsCount struct {
+	// Severity represents the findings' severity (e.g. "High")
+	Severity string `json:"severity"`
+
+	// Patchable represents whether the findings are patchable
+	Patchable bool `json:"patchable"`
+
+	// Count represents the number of the findings
+	Count int `json:"count"`
+}
+
 // UnscannedReason represents the reason to unscanned status
 type UnscannedReason string
 
@@ -82,4 +202,28 @@ const (
 	RegistryUnauthorizedUnscannedReason                      UnscannedReason = "RegistryUnauthorized"
 	ImageDoesNotExistUnscannedReason                         UnscannedReason = "ImageDoesNotExist"
 	RegistryDoesNotExistUnscannedReason                      UnscannedReason = "RegistryDoesNotExist"
+	RegistryTLSErrorUnscannedReason                          UnscannedReason = "RegistryTLSError"
+	CircuitBreakerIsOpenUnscannedReason                      UnscannedReason = "CircuitBreakerIsOpen"
+	DataProviderUnauthorizedUnscannedReason                  UnscannedReason = "DataProviderUnauthorized"
+	DataProviderThrottledUnscannedReason                     UnscannedReason = "DataProviderThrottled"
+	DataProviderUnavailableUnscannedReason                   UnscannedReason = "DataProviderUnavailable"
+	IdentityUnavailableUnscannedReason                       UnscannedReason = "IdentityUnavailable"
+	DigestResolutionTimeoutUnscannedReason                   UnscannedReason = "DigestResolutionTimeout"
+)
+
+var (
+	// _transientUnscannedReasons are the unscanned reasons of failures of the dependencies that are expected to pass shortly
+	// (e.g. throttling or open circuit), so the scan results of the next request may differ.
+	_transientUnscannedReasons = map[UnscannedReason]bool{
+		CircuitBreakerIsOpenUnscannedReason:    true,
+		DataProviderThrottledUnscannedReason:   true,
+		DataProviderUnavailableUnscannedReason: true,
+		IdentityUnavailableUnscannedReason:     true,
+		DigestResolutionTimeoutUnscannedReason: true,
+	}
 )
+
+// IsTransientUnscannedReason returns true if the unscanned reason is a transient failure of a dependency
+func IsTransientUnscannedReason(reason UnscannedReason) bool {
+	return _transientUnscannedReasons[reason]
+}
@@ -100,6 +100,17 @@ func (suite *TestSuite) Test_ScanStausEnumsValues() {
 	suite.Equal(string(Unscanned), "unscanned")
 }
 
+func (suite *TestSuite) Test_IsTransientUnscannedReason() {
+	suite.True(IsTransientUnscannedReason(DataProviderThrottledUnscannedReason))
+	suite.True(IsTransientUnscannedReason(DataProviderUnavailableUnscannedReason))
+	suite.True(IsTransientUnscannedReason(IdentityUnavailableUnscannedReason))
+	suite.True(IsTransientUnscannedReason(DigestResolutionTimeoutUnscannedReason))
+	suite.True(IsTransientUnscannedReason(CircuitBreakerIsOpenUnscannedReason))
+	suite.False(IsTransientUnscannedReason(ImageDoesNotExistUnscannedReason))
+	suite.False(IsTransientUnscannedReason(DataProviderUnauthorizedUnscannedReason))
+	suite.False(IsTransientUnscannedReason(UnscannedReason("")))
+}
+
 func Test_ContainerScanVulnerabilities(t *testing.T) {
 	suite.Run(t, new(TestSuite))
 }
@@ -0,0 +1,74 @@
+package contracts
+
+import (
+	"time"
+)
+
+const (
+	// ARGDataSource the scan results are of Defender for Cloud, queried from Azure Resource Graph
+	ARGDataSource = "AzureResourceGraph"
+)
+
+// ContainerVulnerabilityScanInfoListV2 a list of container vulnerability scan summaries (SchemaVersionV2)
+type ContainerVulnerabilityScanInfoListV2 struct {
+	// SchemaVersion represents the schema version of the list (SchemaVersionV2)
+	SchemaVersion SchemaVersion `json:"schemaVersion"`
+
+	// GeneratedTimestamp represents the time the scan info list (this) was generated
+	GeneratedTimestamp time.Time `json:"generatedTimestamp"`
+
+	// Containers List of ContainerVulnerabilityScanSummary that represents the scan summaries of all the containers
+	Containers []*ContainerVulnerabilityScanSummary `json:"containers"`
+
+	// VulnerabilityReport represents the name of the ContainerVulnerabilityReport of the workload (in the namespace of the workload)
+	// that holds the full scan info. Omitted if no report is written.
+	VulnerabilityReport string `json:"vulnerabilityReport,omitempty"`
+}
+
+// ContainerVulnerabilityScanSummary represents containers vulnerability scan summary
+type ContainerVulnerabilityScanSummary struct {
+	// Name container name in resource spec
+	Name string `json:"name"`
+
+	// Image container's image
+	Image *Image `json:"image"`
+
+	// ScanStatus vulnerability scan status for image
+	ScanStatus ScanStatus `json:"scanStatus"`
+
+	// Summary of the vulnerability scan findings for image (nil if the image is unscanned)
+	Summary *VulnerabilitySummary `json:"summary,omitempty"`
+
+	// SignatureStatus image signature verification status of the resolved digest (empty if signature verification is disabled)
+	SignatureStatus SignatureStatus `json:"signatureStatus,omitempty"`
+
+	// SupplyChainArtifacts the SBOMs and attestations that are attached to the resolved digest (nil if artifacts discovery is disabled or failed)
+	SupplyChainArtifacts *SupplyChainArtifacts `json:"supplyChainArtifacts,omitempty"`
+
+	// AdditionalData additional data of the container (e.g. UnscannedReason)
+	AdditionalData map[string]string `json:"additionalData,omitempty"`
+}
+
+// VulnerabilitySummary represents the precomputed summary of the vulnerability scan findings of an image
+type VulnerabilitySummary struct {
+	// SeverityCounts represents the count of the findings per severity (e.g. "High": 3). High, Medium and Low are always present.
+	SeverityCounts map[string]int `json:"severityCounts"`
+
+	// PatchableSeverityCounts represents the count of the patchable findings per severity. High, Medium and Low are always present.
+	PatchableSeverityCounts map[string]int `json:"patchableSeverityCounts"`
+
+	// TotalCount represents the count of all the findings
+	TotalCount int `json:"totalCount"`
+
+	// PatchableCount represents the count of the patchable findings
+	PatchableCount int `json:"patchableCount"`
+
+	// MaxSeverity represents the highest severity of the findings ("None" if there are no findings)
+	MaxSeverity string `json:"maxSeverity"`
+
+	// EvaluatedAt represents the time the scan findings were evaluated by the webhook
+	EvaluatedAt time.Time `json:"evaluatedAt"`
+
+	// DataSource represents the source of the scan findings (e.g. ARGDataSource)
+	DataSource string `json:"dataSource"`
+}
@@ -3,6 +3,8 @@
 package mocks
 
 import (
+	context "context"
+
 	admisionrequest "github.com/Azure/AzureDefender-K8S-InClusterDefense/cmd/webhook/admisionrequest"
 
 	contracts "github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/azdsecinfo/contracts"
@@ -15,13 +17,13 @@ type IAzdSecInfoProvider struct {
 	mock.Mock
 }
 
-// GetContainersVulnerabilityScanInfo provides a mock function with given fields: workloadResource
-func (_m *IAzdSecInfoProvider) GetContainersVulnerabilityScanInfo(workloadResource *admisionrequest.WorkloadResource) ([]*contracts.ContainerVulnerabilityScanInfo, error) {
-	ret := _m.Called(workloadResource)
+// GetContainersVulnerabilityScanInfo provides a mock function with given fields: ctx, workloadResource
+func (_m *IAzdSecInfoProvider) GetContainersVulnerabilityScanInfo(ctx context.Context, workloadResource *admisionrequest.WorkloadResource) ([]*contracts.ContainerVulnerabilityScanInfo, error) {
+	ret := _m.Called(ctx, workloadResource)
 
 	var r0 []*contracts.ContainerVulnerabilityScanInfo
-	if rf, ok := ret.Get(0).(func(*admisionrequest.WorkloadResource) []*contracts.ContainerVulnerabilityScanInfo); ok {
-		r0 = rf(workloadResource)
+	if rf, ok := ret.Get(0).(func(context.Context, *admisionrequest.WorkloadResource) []*contracts.ContainerVulnerabilityScanInfo); ok {
+		r0 = rf(ctx, workloadResource)
 	} else {
 		if ret.Get(0) != nil {
 			r0 = ret.Get(0).([]*contracts.ContainerVulnerabilityScanInfo)
@@ -29,8 +31,8 @@ func (_m *IAzdSecInfoProvider) GetContainersVulnerabilityScanInfo(workloadResour
 	}
 
 	var r1 error
-	if rf, ok := ret.Get(1).(func(*admisionrequest.WorkloadResource) error); ok {
-		r1 = rf(workloadResource)
+	if rf, ok := ret.Get(1).(func(context.Context, *admisionrequest.WorkloadResource) error); ok {
+		r1 = rf(ctx, workloadResource)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -3,6 +3,8 @@
 package mocks
 
 import (
+	context "context"
+
 	admisionrequest "github.com/Azure/AzureDefender-K8S-InClusterDefense/cmd/webhook/admisionrequest"
 
 	contracts "github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/azdsecinfo/contracts"
@@ -15,13 +17,13 @@ type IAzdSecInfoProviderCacheClient struct {
 	mock.Mock
 }
 
-// GetContainerVulnerabilityScanInfofromCache provides a mock function with given fields: podSpecCacheKey
-func (_m *IAzdSecInfoProviderCacheClient) GetContainerVulnerabilityScanInfofromCache(podSpecCacheKey string) ([]*contracts.ContainerVulnerabilityScanInfo, error, error) {
-	ret := _m.Called(podSpecCacheKey)
+// GetContainerVulnerabilityScanInfofromCache provides a mock function with given fields: ctx, podSpecCacheKey
+func (_m *IAzdSecInfoProviderCacheClient) GetContainerVulnerabilityScanInfofromCache(ctx context.Context, podSpecCacheKey string) ([]*contracts.ContainerVulnerabilityScanInfo, error, error) {
+	ret := _m.Called(ctx, podSpecCacheKey)
 
 	var r0 []*contracts.ContainerVulnerabilityScanInfo
-	if rf, ok := ret.Get(0).(func(string) []*contracts.ContainerVulnerabilityScanInfo); ok {
-		r0 = rf(podSpecCacheKey)
+	if rf, ok := ret.Get(0).(func(context.Context, string) []*contracts.ContainerVulnerabilityScanInfo); ok {
+		r0 = rf(ctx, podSpecCacheKey)
 	} else {
 		if ret.Get(0) != nil {
 			r0 = ret.Get(0).([]*contracts.ContainerVulnerabilityScanInfo)
@@ -29,15 +31,15 @@ func (_m *IAzdSecInfoProviderCacheClient) GetContainerVulnerabilityScanInfofromC
 	}
 
 	var r1 error
-	if rf, ok := ret.Get(1).(func(string) error); ok {
-		r1 = rf(podSpecCacheKey)
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, podSpecCacheKey)
 	} else {
 		r1 = ret.Error(1)
 	}
 
 	var r2 error
-	if rf, ok := ret.Get(2).(func(string) error); ok {
-		r2 = rf(podSpecCacheKey)
+	if rf, ok := ret.Get(2).(func(context.Context, string) error); ok {
+		r2 = rf(ctx, podSpecCacheKey)
 	} else {
 		r2 = ret.Error(2)
 	}
@@ -59,20 +61,20 @@ func (_m *IAzdSecInfoProviderCacheClient) GetPodSpecCacheKey(podSpec *admisionre
 	return r0
 }
 
-// GetTimeOutStatus provides a mock function with given fields: podSpecCacheKey
-func (_m *IAzdSecInfoProviderCacheClient) GetTimeOutStatus(podSpecCacheKey string) (int, error) {
-	ret := _m.Called(podSpecCacheKey)
+// GetTimeOutStatus provides a mock function with given fields: ctx, podSpecCacheKey
+func (_m *IAzdSecInfoProviderCacheClient) GetTimeOutStatus(ctx context.Context, podSpecCacheKey string) (int, error) {
+	ret := _m.Called(ctx, podSpecCacheKey)
 
 	var r0 int
-	if rf, ok := ret.Get(0).(func(string) int); ok {
-		r0 = rf(podSpecCacheKey)
+	if rf, ok := ret.Get(0).(func(context.Context, string) int); ok {
+		r0 = rf(ctx, podSpecCacheKey)
 	} else {
 		r0 = ret.Get(0).(int)
 	}
 
 	var r1 error
-	if rf, ok := ret.Get(1).(func(string) error); ok {
-		r1 = rf(podSpecCacheKey)
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, podSpecCacheKey)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -80,13 +82,13 @@ func (_m *IAzdSecInfoProviderCacheClient) GetTimeOutStatus(podSpecCacheKey strin
 	return r0, r1
 }
 
-// ResetTimeOutInCacheAfterGettingScanResults provides a mock function with given fields: podSpecCacheKey
-func (_m *IAzdSecInfoProviderCacheClient) ResetTimeOutInCacheAfterGettingScanResults(podSpecCacheKey string) error {
-	ret := _m.Called(podSpecCacheKey)
+// ResetTimeOutInCacheAfterGettingScanResults provides a mock function with given fields: ctx, podSpecCacheKey
+func (_m *IAzdSecInfoProviderCacheClient) ResetTimeOutInCacheAfterGettingScanResults(ctx context.Context, podSpecCacheKey string) error {
+	ret := _m.Called(ctx, podSpecCacheKey)
 
 	var r0 error
-	if rf, ok := ret.Get(0).(func(string) error); ok {
-		r0 = rf(podSpecCacheKey)
+	if rf, ok := ret.Get(0).(func(context.Context, string) error); ok {
+		r0 = rf(ctx, podSpecCacheKey)
 	} else {
 		r0 = ret.Error(0)
 	}
@@ -94,13 +96,13 @@ func (_m *IAzdSecInfoProviderCacheClient) ResetTimeOutInCacheAfterGettingScanRes
 	return r0
 }
 
-// SetContainerVulnerabilityScanInfoInCache provides a mock function with given fields: podSpecCacheKey, containerVulnerabilityScanInfo, err
-func (_m *IAzdSecInfoProviderCacheClient) SetContainerVulnerabilityScanInfoInCache(podSpecCacheKey string, containerVulnerabilityScanInfo []*contracts.ContainerVulnerabilityScanInfo, err error) error {
-	ret := _m.Called(podSpecCacheKey, containerVulnerabilityScanInfo, err)
+// SetContainerVulnerabilityScanInfoInCache provides a mock function with given fields: ctx, podSpecCacheKey, containerVulnerabilityScanInfo, err
+func (_m *IAzdSecInfoProviderCacheClient) SetContainerVulnerabilityScanInfoInCache(ctx context.Context, podSpecCacheKey string, containerVulnerabilityScanInfo []*contracts.ContainerVulnerabilityScanInfo, err error) error {
+	ret := _m.Called(ctx, podSpecCacheKey, containerVulnerabilityScanInfo, err)
 
 	var r0 error
-	if rf, ok := ret.Get(0).(func(string, []*contracts.ContainerVulnerabilityScanInfo, error) error); ok {
-		r0 = rf(podSpecCacheKey, containerVulnerabilityScanInfo, err)
+	if rf, ok := ret.Get(0).(func(context.Context, string, []*contracts.ContainerVulnerabilityScanInfo, error) error); ok {
+		r0 = rf(ctx, podSpecCacheKey, containerVulnerabilityScanInfo, err)
 	} else {
 		r0 = ret.Error(0)
 	}
@@ -108,13 +110,13 @@ func (_m *IAzdSecInfoProviderCacheClient) SetContainerVulnerabilityScanInfoInCac
 	return r0
 }
 
-// SetTimeOutStatusAfterEncounteredTimeout provides a mock function with given fields: podSpecCacheKey, timeOutStatus
-func (_m *IAzdSecInfoProviderCacheClient) SetTimeOutStatusAfterEncounteredTimeout(podSpecCacheKey string, timeOutStatus int) error {
-	ret := _m.Called(podSpecCacheKey, timeOutStatus)
+// SetTimeOutStatusAfterEncounteredTimeout provides a mock function with given fields: ctx, podSpecCacheKey, timeOutStatus
+func (_m *IAzdSecInfoProviderCacheClient) SetTimeOutStatusAfterEncounteredTimeout(ctx context.Context, podSpecCacheKey string, timeOutStatus int) error {
+	ret := _m.Called(ctx, podSpecCacheKey, timeOutStatus)
 
 	var r0 error
-	if rf, ok := ret.Get(0).(func(string, int) error); ok {
-		r0 = rf(podSpecCacheKey, timeOutStatus)
+	if rf, ok := ret.Get(0).(func(context.Context, string, int) error); ok {
+		r0 = rf(ctx, podSpecCacheKey, timeOutStatus)
 	} else {
 		r0 = ret.Error(0)
 	}
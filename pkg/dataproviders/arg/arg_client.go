@@ -4,6 +4,8 @@ import (
 	"context"
 	"fmt"
 	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/dataproviders/arg/wrappers"
+	argerrors "github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/dataproviders/arg/wrappers/errors"
+	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/circuitbreaker"
 	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/instrumentation"
 	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/instrumentation/metric"
 	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/instrumentation/metric/util"
@@ -16,6 +18,9 @@ import (
 // MAX_TOP_RESULTS_IN_PAGE_OF_ARG is the maximum. please see more information in https://docs.microsoft.com/en-us/azure/governance/resource-graph/concepts/work-with-data#paging-results
 const MAX_TOP_RESULTS_IN_PAGE_OF_ARG = 1000
 
+// _circuitBreakerKey is the key of ARG's circuit in the circuit breaker
+const _circuitBreakerKey = "ARG"
+
 var (
 	_errArgQueryResponseIsNotAnObjectListFormat = fmt.Errorf("ARGClient.QueryResources ARG query response data is not an object list")
 	_errEmptyResultFromARG                      = fmt.Errorf("ARGClient.QueryResources ARG query response with no records")
@@ -24,7 +29,7 @@ var (
 // IARGClient is an interface for our arg client implementation
 type IARGClient interface {
 	// QueryResources gets a query and return an array object as a result
-	QueryResources(query string) ([]interface{}, error)
+	QueryResources(ctx context.Context, query string) ([]interface{}, error)
 }
 
 // ARGClient implements IARGClient interface
@@ -38,9 +43,13 @@ type ARGClient struct {
 	argBaseClientWrapper wrappers.IARGBaseClientWrapper
 	//argQueryReqOptions is the options for query evaluation of the ARGClient
 	argQueryReqOptions *argsdk.QueryRequestOptions
-	subscriptions      *[]string
+	// getConfiguration returns the current configuration of the client - the configuration that it was created with,
+	// or the reloaded configuration in case that a configuration provider is set.
+	getConfiguration func() *ARGClientConfiguration
 	//retryPolicy retry policy for communication with ARG.
 	retryPolicy retrypolicy.IRetryPolicy
+	// circuitBreaker rejects the queries while ARG is failing
+	circuitBreaker circuitbreaker.ICircuitBreaker
 }
 
 type ARGClientConfiguration struct {
@@ -49,74 +58,95 @@ type ARGClientConfiguration struct {
 }
 
 // NewARGClient Constructor
-func NewARGClient(instrumentationProvider instrumentation.IInstrumentationProvider, argBaseClientWrapper wrappers.IARGBaseClientWrapper, configuration *ARGClientConfiguration, retryPolicy retrypolicy.IRetryPolicy) *ARGClient {
+func NewARGClient(instrumentationProvider instrumentation.IInstrumentationProvider, argBaseClientWrapper wrappers.IARGBaseClientWrapper, configuration *ARGClientConfiguration, retryPolicy retrypolicy.IRetryPolicy, circuitBreaker circuitbreaker.ICircuitBreaker) *ARGClient {
 	// We need this var for unittests - in unittests we reduce it from 1000 to smaller number.
 	requestQueryTop := int32(MAX_TOP_RESULTS_IN_PAGE_OF_ARG)
-	subscriptions := &configuration.Subscriptions
-	// If the subscriptions is empty then work on tenat scope.
-	// TODO Is it the behavior that we want? bad performance.
-	if len(*subscriptions) == 0 {
-		subscriptions = nil
-	}
 
 	return &ARGClient{
 		tracerProvider:       instrumentationProvider.GetTracerProvider("ARGClient"),
 		metricSubmitter:      instrumentationProvider.GetMetricSubmitter(),
 		argBaseClientWrapper: argBaseClientWrapper,
 		argQueryReqOptions:   &argsdk.QueryRequestOptions{ResultFormat: argsdk.ResultFormatObjectArray, Top: &requestQueryTop},
-		subscriptions:        subscriptions,
+		getConfiguration:     func() *ARGClientConfiguration { return configuration },
 		retryPolicy:          retryPolicy,
+		circuitBreaker:       circuitBreaker,
 	}
 }
 
+// SetConfigurationProvider makes the client read its configuration from the provider on each query (e.g. from the
+// reloaded configuration), so the queries after a reload use the new subscriptions. Should be called before the client is used.
+func (client *ARGClient) SetConfigurationProvider(getConfiguration func() *ARGClientConfiguration) {
+	client.getConfiguration = getConfiguration
+}
+
 // QueryResources gets a query and return an array object as a result
-func (client *ARGClient) QueryResources(query string) ([]interface{}, error) {
+func (client *ARGClient) QueryResources(ctx context.Context, query string) ([]interface{}, error) {
 	tracer := client.tracerProvider.GetTracer("QueryResources")
+	ctx, span := client.tracerProvider.StartSpan(ctx, "QueryResources")
+	defer span.End()
 	// Creates new request
 	request := client.initDefaultQueryRequest(query)
-	var totalResults []interface{}
-	var err error
 
 	// TODO add UT
-	err = client.retryPolicy.RetryAction(
-		// Action - set the values total result and err.
-		func() error {
-			totalResults, err = client.fetchAllResults(&request)
-			if err != nil {
-				return err
-			}
-			if len(totalResults) == 0 {
-				return _errEmptyResultFromARG
-			}
-			return nil
+	value, err := client.circuitBreaker.Execute(
+		_circuitBreakerKey,
+		func() (interface{}, error) {
+			return client.retryPolicy.RetryActionInterface(
+				ctx,
+				// Action - returns the total results of the query.
+				func() (interface{}, error) {
+					totalResults, err := client.fetchAllResults(ctx, &request)
+					if err != nil {
+						return nil, err
+					}
+					if len(totalResults) == 0 {
+						return totalResults, _errEmptyResultFromARG
+					}
+					return totalResults, nil
+				},
+				// HandleError - if the empty records or ARG throttled the query with retry after hint, retry.
+				// TODO make sure all errors type/value compare are not wrapped + UT
+				func(err error) bool {
+					var retryAfterErr *retrypolicy.RetryAfterErr
+					return errors.Is(err, _errEmptyResultFromARG) || errors.As(err, &retryAfterErr)
+				},
+			)
 		},
-		// HandleError - if the empty records, retry.
-		// TODO make sure all errors type/value compare are not wrapped + UT
-		func(err error) bool { return errors.Is(err, _errEmptyResultFromARG) },
+		// IsFailure - empty records aren't failure of ARG
+		func(err error) bool { return !errors.Is(err, _errEmptyResultFromARG) },
 	)
 
 	// Err is not nil and also not empty retry error
 	if err != nil && !errors.Is(err, _errEmptyResultFromARG) {
+		span.RecordError(err)
 		tracer.Error(err, "failed on fetchAllResults ")
 		client.metricSubmitter.SendMetric(1, util.NewErrorEncounteredMetric(err, "ARGClient.QueryResources"))
 		return nil, err
 	}
 
+	// In case that all the attempts returned empty results - the query has no results
+	if errors.Is(err, _errEmptyResultFromARG) {
+		value = []interface{}{}
+	}
+
 	// In case that totalResults is still nil - shouldn't happen
+	totalResults, _ := value.([]interface{})
 	if totalResults == nil {
 		nilError := errors.New("nil error")
+		span.RecordError(nilError)
 		tracer.Error(nilError, "totalResults is nil - unknown behavior")
 		client.metricSubmitter.SendMetric(1, util.NewErrorEncounteredMetric(nilError, "ARGClient.QueryResources"))
 		return nil, nilError
 	}
 
 	tracer.Info("ARG query", "totalResults", len(totalResults))
+	span.SetAttributes("totalResults", len(totalResults))
 	return totalResults, nil
 }
 
 // fetchAllResults from ARG using pagination. the pagination based on the skiptoken that is returned in the
 // response of ARG.
-func (client *ARGClient) fetchAllResults(request *argsdk.QueryRequest) ([]interface{}, error) {
+func (client *ARGClient) fetchAllResults(ctx context.Context, request *argsdk.QueryRequest) ([]interface{}, error) {
 	tracer := client.tracerProvider.GetTracer("fetchAllResults")
 	// Create new totalResults array - default value is nil
 	var totalResults []interface{}
@@ -124,8 +154,19 @@ func (client *ARGClient) fetchAllResults(request *argsdk.QueryRequest) ([]interf
 	// While loop - pagination
 	for totalResults == nil || request.Options.SkipToken != nil {
 		// Execute query and get the response.
-		response, err := client.argBaseClientWrapper.Resources(context.Background(), *request)
+		response, err := client.argBaseClientWrapper.Resources(ctx, *request)
 		if err != nil {
+			retryAfter, isRetryAfterHinted := argerrors.GetRetryAfter(err)
+			// Convert known failures (e.g. unauthorized, throttled) to known errors, so they're reported as unscanned with reason.
+			// In case that ctx is done, the caller stopped waiting - it isn't a failure of ARG.
+			if knownErr, ok := argerrors.TryParseARGErrToKnownErr(err); ok && ctx.Err() == nil {
+				tracer.Info("Success to parse ARG error to known error", "knownErr", knownErr)
+				err = knownErr
+			}
+			// In case that ARG throttles the query - the query is retried after its hint
+			if isRetryAfterHinted {
+				err = retrypolicy.NewRetryAfterErr(err, retryAfter)
+			}
 			return nil, errors.Wrap(err, "ARGClient.QueryResources failed on baseClient.Resources")
 		}
 
@@ -168,7 +209,17 @@ func (client *ARGClient) initDefaultQueryRequest(query string) argsdk.QueryReque
 	request := argsdk.QueryRequest{
 		Query:         &query,
 		Options:       &requestOptions,
-		Subscriptions: client.subscriptions,
+		Subscriptions: subscriptionsFromConfiguration(client.getConfiguration()),
 	}
 	return request
 }
+
+// subscriptionsFromConfiguration returns the subscriptions of the configuration - nil in case that there are no subscriptions.
+func subscriptionsFromConfiguration(configuration *ARGClientConfiguration) *[]string {
+	// If the subscriptions is empty then work on tenat scope.
+	// TODO Is it the behavior that we want? bad performance.
+	if len(configuration.Subscriptions) == 0 {
+		return nil
+	}
+	return &configuration.Subscriptions
+}
@@ -4,14 +4,21 @@ import (
 	"context"
 	"errors"
 	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/dataproviders/arg/wrappers/mocks"
+	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/circuitbreaker"
 	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/instrumentation"
+	registryerrors "github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/registry/errors"
 	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/retrypolicy"
 	argsdk "github.com/Azure/azure-sdk-for-go/services/resourcegraph/mgmt/2021-03-01/resourcegraph"
+	"github.com/Azure/go-autorest/autorest"
+	pkgerrors "github.com/pkg/errors"
+	"net"
+	"net/http"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/suite"
 	"reflect"
 	"strings"
 	"testing"
+	"time"
 )
 
 // We'll be able to store suite-wide
@@ -41,7 +48,7 @@ var (
 func (suite *TestSuite) SetupTest() {
 	suite.argBaseClientWrapperMock = &mocks.IARGBaseClientWrapper{}
 	retryPolicyConfiguration := &retrypolicy.RetryPolicyConfiguration{RetryAttempts: 2, RetryDurationInMS: 10}
-	_retryPolicy = retrypolicy.NewRetryPolicy(instrumentation.NewNoOpInstrumentationProvider(), retryPolicyConfiguration)
+	_retryPolicy = retrypolicy.NewRetryPolicy(instrumentation.NewNoOpInstrumentationProvider(), retryPolicyConfiguration, "ARGClient")
 
 	var top int32 = 1000
 	// Create the query request
@@ -58,11 +65,11 @@ func (suite *TestSuite) Test_QueryResources_ReturnedErrorFromArgBaseClient_Shoul
 	// Setup
 	query := _invalidQuery
 	_request.Query = &query
-	suite.argBaseClientWrapperMock.On("Resources", context.Background(), _request).Return(_emptyQueryResponse, _emptyErrorString).Once()
-	client := NewARGClient(instrumentation.NewNoOpInstrumentationProvider(), suite.argBaseClientWrapperMock, _getARGClientConfiguration(), _retryPolicy)
+	suite.argBaseClientWrapperMock.On("Resources", mock.Anything, _request).Return(_emptyQueryResponse, _emptyErrorString).Once()
+	client := NewARGClient(instrumentation.NewNoOpInstrumentationProvider(), suite.argBaseClientWrapperMock, _getARGClientConfiguration(), _retryPolicy, circuitbreaker.NewNoOpCircuitBreaker())
 
 	// Act
-	resources, err := client.QueryResources(query)
+	resources, err := client.QueryResources(context.Background(), query)
 
 	// Test
 	suite.Nil(resources)
@@ -70,17 +77,112 @@ func (suite *TestSuite) Test_QueryResources_ReturnedErrorFromArgBaseClient_Shoul
 	suite.argBaseClientWrapperMock.AssertExpectations(suite.T())
 }
 
+func (suite *TestSuite) Test_QueryResources_KnownErrorFromArgBaseClient_ShouldReturnKnownError() {
+	tests := []struct {
+		name          string
+		err           error
+		expectedCause error
+	}{
+		{name: "unauthorized", err: autorest.DetailedError{PackageType: "resourcegraph.BaseClient", StatusCode: http.StatusUnauthorized}, expectedCause: &registryerrors.DataProviderUnauthorizedErr{}},
+		{name: "forbidden", err: autorest.DetailedError{PackageType: "resourcegraph.BaseClient", StatusCode: http.StatusForbidden}, expectedCause: &registryerrors.DataProviderUnauthorizedErr{}},
+		{name: "throttled", err: autorest.DetailedError{PackageType: "resourcegraph.BaseClient", StatusCode: http.StatusTooManyRequests}, expectedCause: &registryerrors.DataProviderThrottledErr{}},
+		{name: "server error", err: autorest.DetailedError{PackageType: "resourcegraph.BaseClient", StatusCode: http.StatusServiceUnavailable}, expectedCause: &registryerrors.DataProviderUnavailableErr{}},
+		{name: "network failure", err: autorest.DetailedError{PackageType: "resourcegraph.BaseClient", StatusCode: autorest.UndefinedStatusCode, Original: &net.OpError{Op: "dial", Err: errors.New("connection refused")}}, expectedCause: &registryerrors.DataProviderUnavailableErr{}},
+		{name: "token refresh failure", err: autorest.DetailedError{PackageType: "azure.BearerAuthorizer", StatusCode: http.StatusBadRequest}, expectedCause: &registryerrors.IdentityUnavailableErr{}},
+		{name: "bad request", err: autorest.DetailedError{PackageType: "resourcegraph.BaseClient", StatusCode: http.StatusBadRequest}, expectedCause: autorest.DetailedError{}},
+	}
+	for _, tt := range tests {
+		suite.Run(tt.name, func() {
+			query := _invalidQuery
+			_request.Query = &query
+			argBaseClientWrapperMock := &mocks.IARGBaseClientWrapper{}
+			argBaseClientWrapperMock.On("Resources", mock.Anything, _request).Return(_emptyQueryResponse, tt.err).Once()
+			client := NewARGClient(instrumentation.NewNoOpInstrumentationProvider(), argBaseClientWrapperMock, _getARGClientConfiguration(), _retryPolicy, circuitbreaker.NewNoOpCircuitBreaker())
+
+			resources, err := client.QueryResources(context.Background(), query)
+
+			suite.Nil(resources)
+			suite.IsType(tt.expectedCause, pkgerrors.Cause(err))
+			// The original error is kept in the chain
+			var detailedError autorest.DetailedError
+			suite.True(errors.As(err, &detailedError))
+			suite.Equal(tt.err.(autorest.DetailedError).StatusCode, detailedError.StatusCode)
+			argBaseClientWrapperMock.AssertExpectations(suite.T())
+		})
+	}
+}
+
+func (suite *TestSuite) Test_QueryResources_ContextDeadlineExceeded_ShouldNotReturnKnownError() {
+	query := _invalidQuery
+	_request.Query = &query
+	argErr := autorest.DetailedError{PackageType: "resourcegraph.BaseClient", StatusCode: autorest.UndefinedStatusCode, Original: context.DeadlineExceeded}
+	argBaseClientWrapperMock := &mocks.IARGBaseClientWrapper{}
+	argBaseClientWrapperMock.On("Resources", mock.Anything, _request).Return(_emptyQueryResponse, argErr)
+	client := NewARGClient(instrumentation.NewNoOpInstrumentationProvider(), argBaseClientWrapperMock, _getARGClientConfiguration(), _retryPolicy, circuitbreaker.NewNoOpCircuitBreaker())
+	ctx, cancel := context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+	defer cancel()
+
+	resources, err := client.QueryResources(ctx, query)
+
+	// The caller stopped waiting - it isn't a failure of ARG
+	suite.Nil(resources)
+	suite.NotNil(err)
+	var unavailableErr *registryerrors.DataProviderUnavailableErr
+	suite.False(errors.As(err, &unavailableErr))
+}
+
+func (suite *TestSuite) Test_QueryResources_ThrottledWithRetryAfter_ShouldRetryAfterHint() {
+	// Setup
+	query := _invalidQuery
+	_request.Query = &query
+	throttledErr := autorest.DetailedError{PackageType: "resourcegraph.BaseClient", StatusCode: http.StatusTooManyRequests, Response: &http.Response{StatusCode: http.StatusTooManyRequests, Header: http.Header{"Retry-After": []string{"0"}}}}
+	arrayData := []interface{}{_firstObjectForDataArray}
+	totalRecords := int64(1)
+	response := argsdk.QueryResponse{Data: arrayData, TotalRecords: &totalRecords, Count: &totalRecords}
+	suite.argBaseClientWrapperMock.On("Resources", mock.Anything, _request).Return(_emptyQueryResponse, throttledErr).Once()
+	suite.argBaseClientWrapperMock.On("Resources", mock.Anything, _request).Return(response, nil).Once()
+	client := NewARGClient(instrumentation.NewNoOpInstrumentationProvider(), suite.argBaseClientWrapperMock, _getARGClientConfiguration(), _retryPolicy, circuitbreaker.NewNoOpCircuitBreaker())
+
+	// Act
+	resources, err := client.QueryResources(context.Background(), query)
+
+	// Test
+	suite.Nil(err)
+	suite.Equal(arrayData, resources)
+	suite.argBaseClientWrapperMock.AssertExpectations(suite.T())
+}
+
+func (suite *TestSuite) Test_QueryResources_ThrottledWithRetryAfterAttemptsExhausted_ShouldReturnRetryAfterErr() {
+	// Setup
+	query := _invalidQuery
+	_request.Query = &query
+	throttledErr := autorest.DetailedError{PackageType: "resourcegraph.BaseClient", StatusCode: http.StatusTooManyRequests, Response: &http.Response{StatusCode: http.StatusTooManyRequests, Header: http.Header{"Retry-After": []string{"0"}}}}
+	suite.argBaseClientWrapperMock.On("Resources", mock.Anything, _request).Return(_emptyQueryResponse, throttledErr).Twice()
+	client := NewARGClient(instrumentation.NewNoOpInstrumentationProvider(), suite.argBaseClientWrapperMock, _getARGClientConfiguration(), _retryPolicy, circuitbreaker.NewNoOpCircuitBreaker())
+
+	// Act
+	resources, err := client.QueryResources(context.Background(), query)
+
+	// Test
+	suite.Nil(resources)
+	var retryAfterErr *retrypolicy.RetryAfterErr
+	suite.True(errors.As(err, &retryAfterErr))
+	var throttledKnownErr *registryerrors.DataProviderThrottledErr
+	suite.True(errors.As(err, &throttledKnownErr))
+	suite.argBaseClientWrapperMock.AssertExpectations(suite.T())
+}
+
 func (suite *TestSuite) Test_QueryResources_ResponseDataIsNil_ShouldReturnError() {
 	// Setup
 	query := _invalidQuery
 	_request.Query = &query
 	totalRecords := int64(1)
 	response := argsdk.QueryResponse{TotalRecords: &totalRecords}
-	suite.argBaseClientWrapperMock.On("Resources", context.Background(), _request).Return(response, nil).Once()
-	client := NewARGClient(instrumentation.NewNoOpInstrumentationProvider(), suite.argBaseClientWrapperMock, _getARGClientConfiguration(), _retryPolicy)
+	suite.argBaseClientWrapperMock.On("Resources", mock.Anything, _request).Return(response, nil).Once()
+	client := NewARGClient(instrumentation.NewNoOpInstrumentationProvider(), suite.argBaseClientWrapperMock, _getARGClientConfiguration(), _retryPolicy, circuitbreaker.NewNoOpCircuitBreaker())
 
 	// Act
-	resources, err := client.QueryResources(query)
+	resources, err := client.QueryResources(context.Background(), query)
 
 	// Test
 	suite.Nil(resources)
@@ -94,11 +196,11 @@ func (suite *TestSuite) Test_QueryResources_ResponseTotalRecordsIsNil_ShouldRetu
 	_request.Query = &query
 	totalRecords := int64(1)
 	response := argsdk.QueryResponse{Data: &totalRecords}
-	suite.argBaseClientWrapperMock.On("Resources", context.Background(), _request).Return(response, nil).Once()
-	client := NewARGClient(instrumentation.NewNoOpInstrumentationProvider(), suite.argBaseClientWrapperMock, _getARGClientConfiguration(), _retryPolicy)
+	suite.argBaseClientWrapperMock.On("Resources", mock.Anything, _request).Return(response, nil).Once()
+	client := NewARGClient(instrumentation.NewNoOpInstrumentationProvider(), suite.argBaseClientWrapperMock, _getARGClientConfiguration(), _retryPolicy, circuitbreaker.NewNoOpCircuitBreaker())
 
 	// Act
-	resources, err := client.QueryResources(query)
+	resources, err := client.QueryResources(context.Background(), query)
 
 	// Test
 	suite.Nil(resources)
@@ -115,11 +217,11 @@ func (suite *TestSuite) Test_QueryResources_ResponseBadFormatOfResponseData_Shou
 	tableData := argsdk.Table{}
 	totalRecords := int64(0)
 	response := argsdk.QueryResponse{Data: tableData, TotalRecords: &totalRecords}
-	suite.argBaseClientWrapperMock.On("Resources", context.Background(), _request).Return(response, nil).Once()
-	client := NewARGClient(instrumentation.NewNoOpInstrumentationProvider(), suite.argBaseClientWrapperMock, _getARGClientConfiguration(), _retryPolicy)
+	suite.argBaseClientWrapperMock.On("Resources", mock.Anything, _request).Return(response, nil).Once()
+	client := NewARGClient(instrumentation.NewNoOpInstrumentationProvider(), suite.argBaseClientWrapperMock, _getARGClientConfiguration(), _retryPolicy, circuitbreaker.NewNoOpCircuitBreaker())
 	client.argQueryReqOptions.ResultFormat = argsdk.ResultFormatTable
 	// Act
-	resources, err := client.QueryResources(query)
+	resources, err := client.QueryResources(context.Background(), query)
 
 	// Test
 	suite.Nil(resources)
@@ -134,11 +236,11 @@ func (suite *TestSuite) Test_QueryResources_ResponseZeroData_ShouldReturnEmptyAr
 	_request.Query = &query
 	totalRecords := int64(0)
 	response := argsdk.QueryResponse{Data: arrayData, TotalRecords: &totalRecords}
-	suite.argBaseClientWrapperMock.On("Resources", context.Background(), _request).Return(response, nil).Times(2)
-	client := NewARGClient(instrumentation.NewNoOpInstrumentationProvider(), suite.argBaseClientWrapperMock, _getARGClientConfiguration(), _retryPolicy)
+	suite.argBaseClientWrapperMock.On("Resources", mock.Anything, _request).Return(response, nil).Times(2)
+	client := NewARGClient(instrumentation.NewNoOpInstrumentationProvider(), suite.argBaseClientWrapperMock, _getARGClientConfiguration(), _retryPolicy, circuitbreaker.NewNoOpCircuitBreaker())
 
 	// Act
-	resources, err := client.QueryResources(query)
+	resources, err := client.QueryResources(context.Background(), query)
 
 	// Test
 	suite.NotNil(resources)
@@ -156,11 +258,11 @@ func (suite *TestSuite) Test_QueryResources_Response2Items_ShouldReturnArrayWith
 	arrayData = append(arrayData, _firstObjectForDataArray, _secondObjectForDataArray)
 	totalRecords := int64(2)
 	response := argsdk.QueryResponse{Data: arrayData, TotalRecords: &totalRecords, Count: &totalRecords}
-	suite.argBaseClientWrapperMock.On("Resources", context.Background(), _request).Return(response, nil).Once()
-	client := NewARGClient(instrumentation.NewNoOpInstrumentationProvider(), suite.argBaseClientWrapperMock, _getARGClientConfiguration(), _retryPolicy)
+	suite.argBaseClientWrapperMock.On("Resources", mock.Anything, _request).Return(response, nil).Once()
+	client := NewARGClient(instrumentation.NewNoOpInstrumentationProvider(), suite.argBaseClientWrapperMock, _getARGClientConfiguration(), _retryPolicy, circuitbreaker.NewNoOpCircuitBreaker())
 
 	// Act
-	resources, err := client.QueryResources(query)
+	resources, err := client.QueryResources(context.Background(), query)
 
 	// Test
 	suite.NotNil(resources)
@@ -184,7 +286,7 @@ func (suite *TestSuite) Test_QueryResources_Response3ItemsTop2_ShouldReturnArray
 	skipToken := "skiptoken"
 	firstResponse := argsdk.QueryResponse{Data: firstArrayData, TotalRecords: &totalRecords, Count: &firstCount, SkipToken: &skipToken}
 	requestSkipTokenNilArgument := mock.MatchedBy(func(req argsdk.QueryRequest) bool { return req.Options.SkipToken == nil })
-	suite.argBaseClientWrapperMock.On("Resources", context.Background(), requestSkipTokenNilArgument).Return(firstResponse, nil)
+	suite.argBaseClientWrapperMock.On("Resources", mock.Anything, requestSkipTokenNilArgument).Return(firstResponse, nil)
 
 	// Set second response
 	secondArrayData := make([]interface{}, 0, 1)
@@ -194,12 +296,12 @@ func (suite *TestSuite) Test_QueryResources_Response3ItemsTop2_ShouldReturnArray
 	secondResponse := argsdk.QueryResponse{Data: secondArrayData, TotalRecords: &totalRecords, Count: &secondCount}
 
 	requestSkipTokenNotNilArgument := mock.MatchedBy(func(req argsdk.QueryRequest) bool { return req.Options.SkipToken != nil })
-	suite.argBaseClientWrapperMock.On("Resources", context.Background(), requestSkipTokenNotNilArgument).Return(secondResponse, nil)
+	suite.argBaseClientWrapperMock.On("Resources", mock.Anything, requestSkipTokenNotNilArgument).Return(secondResponse, nil)
 
-	client := NewARGClient(instrumentation.NewNoOpInstrumentationProvider(), suite.argBaseClientWrapperMock, _getARGClientConfiguration(), _retryPolicy)
+	client := NewARGClient(instrumentation.NewNoOpInstrumentationProvider(), suite.argBaseClientWrapperMock, _getARGClientConfiguration(), _retryPolicy, circuitbreaker.NewNoOpCircuitBreaker())
 
 	// Act
-	resources, err := client.QueryResources(_invalidQuery)
+	resources, err := client.QueryResources(context.Background(), _invalidQuery)
 
 	// Test
 	suite.NotNil(resources)
@@ -222,7 +324,7 @@ func (suite *TestSuite) Test_QueryResources_ResponseErrorInTheSecondPage_ShouldR
 	skipToken := "skiptoken"
 	firstResponse := argsdk.QueryResponse{Data: firstArrayData, TotalRecords: &totalRecords, Count: &firstCount, SkipToken: &skipToken}
 	requestSkipTokenNilArgument := mock.MatchedBy(func(req argsdk.QueryRequest) bool { return req.Options.SkipToken == nil })
-	suite.argBaseClientWrapperMock.On("Resources", context.Background(), requestSkipTokenNilArgument).Return(firstResponse, nil)
+	suite.argBaseClientWrapperMock.On("Resources", mock.Anything, requestSkipTokenNilArgument).Return(firstResponse, nil)
 
 	// Set second response
 	secondArrayData := make([]interface{}, 0, 1)
@@ -233,12 +335,12 @@ func (suite *TestSuite) Test_QueryResources_ResponseErrorInTheSecondPage_ShouldR
 	secondResponse := argsdk.QueryResponse{Data: secondArrayData, Count: &secondCount}
 
 	requestSkipTokenNotNilArgument := mock.MatchedBy(func(req argsdk.QueryRequest) bool { return req.Options.SkipToken != nil })
-	suite.argBaseClientWrapperMock.On("Resources", context.Background(), requestSkipTokenNotNilArgument).Return(secondResponse, nil)
+	suite.argBaseClientWrapperMock.On("Resources", mock.Anything, requestSkipTokenNotNilArgument).Return(secondResponse, nil)
 
-	client := NewARGClient(instrumentation.NewNoOpInstrumentationProvider(), suite.argBaseClientWrapperMock, _getARGClientConfiguration(), _retryPolicy)
+	client := NewARGClient(instrumentation.NewNoOpInstrumentationProvider(), suite.argBaseClientWrapperMock, _getARGClientConfiguration(), _retryPolicy, circuitbreaker.NewNoOpCircuitBreaker())
 
 	// Act
-	resources, err := client.QueryResources(_invalidQuery)
+	resources, err := client.QueryResources(context.Background(), _invalidQuery)
 
 	// Test
 	suite.Nil(resources)
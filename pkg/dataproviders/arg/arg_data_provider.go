@@ -1,15 +1,19 @@
 package arg
 
 import (
+	"context"
 	"encoding/json"
 	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/azdsecinfo/contracts"
 	argmetric "github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/dataproviders/arg/metric"
 	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/dataproviders/arg/queries"
+	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/decisionlog"
 	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/cache"
+	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/coalescing"
 	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/instrumentation"
 	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/instrumentation/metric"
 	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/instrumentation/metric/util"
 	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/instrumentation/trace"
+	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/utils"
 	"github.com/pkg/errors"
 	"strings"
 	"time"
@@ -26,7 +30,7 @@ type IARGDataProvider interface {
 	// If scanStatus is Unscanned, nil scan findings array
 	// If scan status is Healthy, empty scan findings array
 	// If scan status is Unhealthy, findings presented in scan findings array
-	GetImageVulnerabilityScanResults(registry string, repository string, digest string) (scanStatus contracts.ScanStatus, scanFindings []*contracts.ScanFinding, err error)
+	GetImageVulnerabilityScanResults(ctx context.Context, registry string, repository string, digest string) (scanStatus contracts.ScanStatus, scanFindings []*contracts.ScanFinding, err error)
 }
 
 // ARGDataProvider implements IARGDataProvider interface
@@ -46,6 +50,8 @@ type ARGDataProvider struct {
 	cacheClient IARGDataProviderCacheClient
 	// ARGDataProviderConfiguration is configuration data for ARGDataProvider
 	argDataProviderConfiguration *ARGDataProviderConfiguration
+	// coalescer coalesces concurrent lookups of the same digest
+	coalescer coalescing.IRequestCoalescer
 }
 
 // ARGDataProviderConfiguration is configuration data for ARGDataProvider
@@ -65,7 +71,7 @@ type ScanFindingsInCache struct {
 }
 
 // NewARGDataProvider Constructor
-func NewARGDataProvider(instrumentationProvider instrumentation.IInstrumentationProvider, argClient IARGClient, queryGenerator queries.IARGQueryGenerator, cacheClient IARGDataProviderCacheClient, configuration *ARGDataProviderConfiguration) *ARGDataProvider {
+func NewARGDataProvider(instrumentationProvider instrumentation.IInstrumentationProvider, argClient IARGClient, queryGenerator queries.IARGQueryGenerator, cacheClient IARGDataProviderCacheClient, configuration *ARGDataProviderConfiguration, coalescer coalescing.IRequestCoalescer) *ARGDataProvider {
 	return &ARGDataProvider{
 		tracerProvider:               instrumentationProvider.GetTracerProvider("ARGDataProvider"),
 		metricSubmitter:              instrumentationProvider.GetMetricSubmitter(),
@@ -73,6 +79,7 @@ func NewARGDataProvider(instrumentationProvider instrumentation.IInstrumentation
 		argClient:                    argClient,
 		cacheClient:                  cacheClient,
 		argDataProviderConfiguration: configuration,
+		coalescer:                    coalescer,
 	}
 }
 
@@ -81,12 +88,13 @@ func NewARGDataProvider(instrumentationProvider instrumentation.IInstrumentation
 // If scanStatus is Unscanned, nil scan findings array
 // If scan status is Healthy, empty scan findings array
 // If scan status is Unhealthy, findings presented in scan findings array
-func (provider *ARGDataProvider) GetImageVulnerabilityScanResults(registry string, repository string, digest string) (contracts.ScanStatus, []*contracts.ScanFinding, error) {
+func (provider *ARGDataProvider) GetImageVulnerabilityScanResults(ctx context.Context, registry string, repository string, digest string) (contracts.ScanStatus, []*contracts.ScanFinding, error) {
 	tracer := provider.tracerProvider.GetTracer("GetImageVulnerabilityScanResults")
 	tracer.Info("Received", "registry", registry, "repository", repository, "digest", digest)
 
 	// Try to get results from cache. If a key doesn't exist or an error occurred - continue without cache
-	scanStatus, scanFindings, err := provider.cacheClient.GetResultsFromCache(digest)
+	scanStatus, scanFindings, err := provider.cacheClient.GetResultsFromCache(ctx, digest)
+	decisionlog.AddCacheLookup(ctx, decisionlog.ARGDataProviderCacheLayer, digest, err == nil)
 	if err != nil { // Couldn't get ImageVulnerabilityScanResults from cache - skip and get results from provider
 		if cache.IsMissingKeyCacheError(err){
 			tracer.Info("Missin key. Couldn't get ImageVulnerabilityScanResults from cache: Digest not in cache", "digest", digest)
@@ -100,25 +108,39 @@ func (provider *ARGDataProvider) GetImageVulnerabilityScanResults(registry strin
 		return scanStatus, scanFindings, nil
 	}
 
-	// Try to get results from ARG
-	scanStatus, scanFindings, err = provider.getResultsFromArg(registry, repository, digest)
+	// Try to get results from ARG - concurrent lookups of the same digest are coalesced, so only one of them queries ARG
+	results, err := provider.coalescer.Do(ctx, digest, func() (interface{}, error) {
+		return provider.getResultsFromArgAndSetInCache(ctx, registry, repository, digest)
+	})
+	if err != nil {
+		return "", nil, err
+	}
+	scanFindingsResults := results.(*ScanFindingsInCache)
+	return scanFindingsResults.ScanStatus, scanFindingsResults.ScanFindings, nil
+}
+
+// getResultsFromArgAndSetInCache gets scan results from arg and saves them in cache
+func (provider *ARGDataProvider) getResultsFromArgAndSetInCache(ctx context.Context, registry string, repository string, digest string) (*ScanFindingsInCache, error) {
+	tracer := provider.tracerProvider.GetTracer("getResultsFromArgAndSetInCache")
+	scanStatus, scanFindings, err := provider.getResultsFromArg(ctx, registry, repository, digest)
 	if err != nil {
 		err = errors.Wrap(err, "Failed to get get results from Arg")
 		tracer.Error(err, "")
-		provider.metricSubmitter.SendMetric(1, util.NewErrorEncounteredMetric(err, "ARGDataProvider.GetImageVulnerabilityScanResults"))
-		return "", nil, err
+		provider.metricSubmitter.SendMetric(1, util.NewErrorEncounteredMetric(err, "ARGDataProvider.getResultsFromArgAndSetInCache"))
+		return nil, err
 	}
 	tracer.Info("got results from Arg")
 
 	// Set scan findings in cache
 	// In case error occurred - continue without cache
-	go provider.cacheClient.SetScanFindingsInCache(scanFindings, scanStatus, digest)
+	// The results are saved even if the request is done before the set is completed, so the set isn't bound to ctx's cancellation.
+	go provider.cacheClient.SetScanFindingsInCache(utils.NewDetachedContext(ctx), scanFindings, scanStatus, digest)
 
-	return scanStatus, scanFindings, nil
+	return &ScanFindingsInCache{ScanStatus: scanStatus, ScanFindings: scanFindings}, nil
 }
 
 // getResultsFromArg gets scan results from arg
-func (provider *ARGDataProvider) getResultsFromArg(registry string, repository string, digest string) (contracts.ScanStatus, []*contracts.ScanFinding, error) {
+func (provider *ARGDataProvider) getResultsFromArg(ctx context.Context, registry string, repository string, digest string) (contracts.ScanStatus, []*contracts.ScanFinding, error) {
 	tracer := provider.tracerProvider.GetTracer("getResultsFromArg")
 
 	// Generate image scan result ARG query for this specific image
@@ -138,7 +160,7 @@ func (provider *ARGDataProvider) getResultsFromArg(registry string, repository s
 	tracer.Info("Query", "Query", query)
 
 	// Query arg for scan results for image
-	results, err := provider.argClient.QueryResources(query)
+	results, err := provider.argClient.QueryResources(ctx, query)
 	if err != nil {
 		err = errors.Wrap(err, "Failed on argClient.QueryResources")
 		tracer.Error(err, "")
@@ -1,6 +1,7 @@
 package arg
 
 import (
+	"context"
 	"encoding/json"
 	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/azdsecinfo/contracts"
 	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/cache"
@@ -20,10 +21,10 @@ type IARGDataProviderCacheClient interface {
 	// The cache mapping digest to scan results or to known errors.
 	// If the digest exist in cache - return the value (scan results or error) and a flag _gotResultsFromCache
 	// If the digest dont exist in cache or any other unknown error occurred - return "", nil, nil and _didntGotResultsFromCache
-	GetResultsFromCache(digest string) (contracts.ScanStatus, []*contracts.ScanFinding, error)
+	GetResultsFromCache(ctx context.Context, digest string) (contracts.ScanStatus, []*contracts.ScanFinding, error)
 
 	// SetScanFindingsInCache map digest to scan results
-	SetScanFindingsInCache(scanFindings []*contracts.ScanFinding, scanStatus contracts.ScanStatus, digest string) error
+	SetScanFindingsInCache(ctx context.Context, scanFindings []*contracts.ScanFinding, scanStatus contracts.ScanStatus, digest string) error
 }
 
 // ARGDataProviderCacheClient implements IARGDataProviderCacheClient interface
@@ -38,31 +39,35 @@ type ARGDataProviderCacheClient struct {
 	metricSubmitter metric.IMetricSubmitter
 	// cacheClient is a cache for mapping digest to scan results and save timeout status
 	cacheClient cache.ICacheClient
-	// CacheExpirationTimeUnscannedResults is the expiration time **IN MINUTES** for unscanned results in the cache client
-	cacheExpirationTimeUnscannedResults time.Duration
-	// CacheExpirationTimeScannedResults is the expiration time **IN HOURS** for scan results in the cache client
-	cacheExpirationTimeScannedResults time.Duration
+	// getConfiguration returns the current configuration of the expiration times - the configuration that the cache
+	// client was created with, or the reloaded configuration in case that a configuration provider is set.
+	getConfiguration func() *ARGDataProviderConfiguration
 }
 
 // NewARGDataProviderCacheClient - ARGDataProviderCacheClient Ctor
 func NewARGDataProviderCacheClient(instrumentationProvider instrumentation.IInstrumentationProvider, cacheClient cache.ICacheClient, argDataProviderConfiguration *ARGDataProviderConfiguration) *ARGDataProviderCacheClient {
 	return &ARGDataProviderCacheClient{
-		tracerProvider:                      instrumentationProvider.GetTracerProvider("ARGDataProviderCacheClient"),
-		metricSubmitter:                     instrumentationProvider.GetMetricSubmitter(),
-		cacheClient:                         cacheClient,
-		cacheExpirationTimeUnscannedResults: utils.GetMinutes(argDataProviderConfiguration.CacheExpirationTimeUnscannedResults),
-		cacheExpirationTimeScannedResults:   utils.GetHours(argDataProviderConfiguration.CacheExpirationTimeScannedResults),
+		tracerProvider:   instrumentationProvider.GetTracerProvider("ARGDataProviderCacheClient"),
+		metricSubmitter:  instrumentationProvider.GetMetricSubmitter(),
+		cacheClient:      cacheClient,
+		getConfiguration: func() *ARGDataProviderConfiguration { return argDataProviderConfiguration },
 	}
 }
 
+// SetConfigurationProvider makes the cache client read the expiration times from the provider on each use (e.g. from the
+// reloaded configuration), so the results that are set after a reload use the new expiration times. Should be called before the cache client is used.
+func (client *ARGDataProviderCacheClient) SetConfigurationProvider(getConfiguration func() *ARGDataProviderConfiguration) {
+	client.getConfiguration = getConfiguration
+}
+
 // GetResultsFromCache try to get ImageVulnerabilityScanResults from cache.
 // The cache mapping digest to scan results or to known errors.
 // If the digest exist in cache - return the value (scan results or error) and a flag _gotResultsFromCache
 // If the digest dont exist in cache or any other unknown error occurred - return "", nil, nil and _didntGotResultsFromCache
-func (client *ARGDataProviderCacheClient) GetResultsFromCache(digest string) (contracts.ScanStatus, []*contracts.ScanFinding, error) {
+func (client *ARGDataProviderCacheClient) GetResultsFromCache(ctx context.Context, digest string) (contracts.ScanStatus, []*contracts.ScanFinding, error) {
 	tracer := client.tracerProvider.GetTracer("GetResultsFromCache")
 
-	scanFindingsString, err := client.cacheClient.Get(digest)
+	scanFindingsString, err := client.cacheClient.Get(ctx, digest)
 
 	// Nothing found in cache for digest as key
 	if err != nil { // Error as a result of key doesn't exist or other error from the cache functionality are treated the same (skip cache)
@@ -91,7 +96,7 @@ func (client *ARGDataProviderCacheClient) GetResultsFromCache(digest string) (co
 }
 
 // SetScanFindingsInCache map digest to scan results
-func (client *ARGDataProviderCacheClient) SetScanFindingsInCache(scanFindings []*contracts.ScanFinding, scanStatus contracts.ScanStatus, digest string) error {
+func (client *ARGDataProviderCacheClient) SetScanFindingsInCache(ctx context.Context, scanFindings []*contracts.ScanFinding, scanStatus contracts.ScanStatus, digest string) error {
 	tracer := client.tracerProvider.GetTracer("SetScanFindingsInCache")
 
 	// Convert results to string in order to set the results in the cache
@@ -106,13 +111,10 @@ func (client *ARGDataProviderCacheClient) SetScanFindingsInCache(scanFindings []
 	scanFindingsString := string(scanFindingsBuffer)
 
 	// Set TTL. Different TTL for different scan status
-	expirationTime := client.cacheExpirationTimeScannedResults // Default
-	if scanStatus == contracts.Unscanned {
-		expirationTime = client.cacheExpirationTimeUnscannedResults
-	}
+	expirationTime := client.getExpirationTime(scanStatus)
 
 	// Set results in cache
-	err = client.cacheClient.Set(digest, scanFindingsString, expirationTime)
+	err = client.cacheClient.Set(ctx, digest, scanFindingsString, expirationTime)
 	if err != nil {
 		err = errors.Wrap(err, "Failed to set digest in cache")
 		tracer.Error(err, "")
@@ -138,3 +140,12 @@ func (client *ARGDataProviderCacheClient) parseScanFindingsFromCache(scanFinding
 	}
 	return scanFindingsFromCache.ScanStatus, scanFindingsFromCache.ScanFindings, nil
 }
+
+// getExpirationTime returns the current expiration time of the scan status. Different TTL for different scan status
+func (client *ARGDataProviderCacheClient) getExpirationTime(scanStatus contracts.ScanStatus) time.Duration {
+	configuration := client.getConfiguration()
+	if scanStatus == contracts.Unscanned {
+		return utils.GetMinutes(configuration.CacheExpirationTimeUnscannedResults)
+	}
+	return utils.GetHours(configuration.CacheExpirationTimeScannedResults)
+}
@@ -1,6 +1,7 @@
 package arg
 
 import (
+	"context"
 	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/azdsecinfo/contracts"
 	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/cache"
 	cachemock "github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/cache/mocks"
@@ -28,8 +29,8 @@ func (suite *ARGDataProviderCacheClientTestSuite) SetupTest() {
 }
 
 func (suite *ARGDataProviderCacheClientTestSuite) Test_getResultsFromCache_GetMissingKey() {
-	suite.cacheMock.On("Get", _digest).Return("", new(cache.MissingKeyCacheError)).Once()
-	scanStatus, scanFindings, err := suite.argDataProviderCacheClient.GetResultsFromCache(_digest)
+	suite.cacheMock.On("Get", mock.Anything, _digest).Return("", new(cache.MissingKeyCacheError)).Once()
+	scanStatus, scanFindings, err := suite.argDataProviderCacheClient.GetResultsFromCache(context.Background(), _digest)
 	suite.Equal("", string(scanStatus))
 	suite.Nil(scanFindings)
 	suite.NotNil(err)
@@ -37,8 +38,8 @@ func (suite *ARGDataProviderCacheClientTestSuite) Test_getResultsFromCache_GetMi
 }
 
 func (suite *ARGDataProviderCacheClientTestSuite) Test_getResultsFromCache_GetError() {
-	suite.cacheMock.On("Get", _digest).Return("", utils.NilArgumentError).Once()
-	scanStatus, scanFindings, err := suite.argDataProviderCacheClient.GetResultsFromCache(_digest)
+	suite.cacheMock.On("Get", mock.Anything, _digest).Return("", utils.NilArgumentError).Once()
+	scanStatus, scanFindings, err := suite.argDataProviderCacheClient.GetResultsFromCache(context.Background(), _digest)
 	suite.Equal("", string(scanStatus))
 	suite.Nil(scanFindings)
 	suite.NotNil(err)
@@ -46,8 +47,8 @@ func (suite *ARGDataProviderCacheClientTestSuite) Test_getResultsFromCache_GetEr
 }
 
 func (suite *ARGDataProviderCacheClientTestSuite) Test_getResultsFromCache_GetInvalidString() {
-	suite.cacheMock.On("Get", _digest).Return("", nil).Once()
-	scanStatus, scanFindings, err := suite.argDataProviderCacheClient.GetResultsFromCache(_digest)
+	suite.cacheMock.On("Get", mock.Anything, _digest).Return("", nil).Once()
+	scanStatus, scanFindings, err := suite.argDataProviderCacheClient.GetResultsFromCache(context.Background(), _digest)
 	suite.Equal("", string(scanStatus))
 	suite.Nil(scanFindings)
 	suite.NotNil(err)
@@ -55,8 +56,8 @@ func (suite *ARGDataProviderCacheClientTestSuite) Test_getResultsFromCache_GetIn
 }
 
 func (suite *ARGDataProviderCacheClientTestSuite) Test_getResultsFromCache() {
-	suite.cacheMock.On("Get", _digest).Return(_setToCacheTest1, nil).Once()
-	scanStatus, scanFindings, err := suite.argDataProviderCacheClient.GetResultsFromCache(_digest)
+	suite.cacheMock.On("Get", mock.Anything, _digest).Return(_setToCacheTest1, nil).Once()
+	scanStatus, scanFindings, err := suite.argDataProviderCacheClient.GetResultsFromCache(context.Background(), _digest)
 	suite.Equal(contracts.UnhealthyScan, scanStatus)
 	suite.Equal(expected_results, scanFindings)
 	suite.Nil(err)
@@ -64,29 +65,29 @@ func (suite *ARGDataProviderCacheClientTestSuite) Test_getResultsFromCache() {
 }
 
 func (suite *ARGDataProviderCacheClientTestSuite) Test_setScanFindingsInCache_SetError() {
-	suite.cacheMock.On("Set", _digest, _setToCacheTest1, mock.Anything).Return(utils.NilArgumentError).Once()
-	err := suite.argDataProviderCacheClient.SetScanFindingsInCache(expected_results, contracts.UnhealthyScan, _digest)
+	suite.cacheMock.On("Set", mock.Anything, _digest, _setToCacheTest1, mock.Anything).Return(utils.NilArgumentError).Once()
+	err := suite.argDataProviderCacheClient.SetScanFindingsInCache(context.Background(), expected_results, contracts.UnhealthyScan, _digest)
 	suite.NotNil(err)
 	suite.cacheMock.AssertExpectations(suite.T())
 }
 
 func (suite *ARGDataProviderCacheClientTestSuite) Test_setScanFindingsInCache_SetUnscanned() {
-	suite.cacheMock.On("Set", _digest, _setToCacheTest2, _expirationTimeUnscanned*time.Minute).Return(nil).Once()
-	err := suite.argDataProviderCacheClient.SetScanFindingsInCache(nil, contracts.Unscanned, _digest)
+	suite.cacheMock.On("Set", mock.Anything, _digest, _setToCacheTest2, _expirationTimeUnscanned*time.Minute).Return(nil).Once()
+	err := suite.argDataProviderCacheClient.SetScanFindingsInCache(context.Background(), nil, contracts.Unscanned, _digest)
 	suite.Nil(err)
 	suite.cacheMock.AssertExpectations(suite.T())
 }
 
 func (suite *ARGDataProviderCacheClientTestSuite) Test_setScanFindingsInCache_SetUnscannedArrayWithNil() {
-	suite.cacheMock.On("Set", _digest, _setToCacheTest2, _expirationTimeUnscanned*time.Minute).Return(nil).Once()
-	err := suite.argDataProviderCacheClient.SetScanFindingsInCache([]*contracts.ScanFinding(nil), contracts.Unscanned, _digest)
+	suite.cacheMock.On("Set", mock.Anything, _digest, _setToCacheTest2, _expirationTimeUnscanned*time.Minute).Return(nil).Once()
+	err := suite.argDataProviderCacheClient.SetScanFindingsInCache(context.Background(), []*contracts.ScanFinding(nil), contracts.Unscanned, _digest)
 	suite.Nil(err)
 	suite.cacheMock.AssertExpectations(suite.T())
 }
 
 func (suite *ARGDataProviderCacheClientTestSuite) Test_setScanFindingsInCache_SetScanned() {
-	suite.cacheMock.On("Set", _digest, _setToCacheTest1, _expirationTimeScanned*time.Hour).Return(nil).Once()
-	err := suite.argDataProviderCacheClient.SetScanFindingsInCache(expected_results, contracts.UnhealthyScan, _digest)
+	suite.cacheMock.On("Set", mock.Anything, _digest, _setToCacheTest1, _expirationTimeScanned*time.Hour).Return(nil).Once()
+	err := suite.argDataProviderCacheClient.SetScanFindingsInCache(context.Background(), expected_results, contracts.UnhealthyScan, _digest)
 	suite.Nil(err)
 	suite.cacheMock.AssertExpectations(suite.T())
 }
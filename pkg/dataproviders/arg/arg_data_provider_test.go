@@ -1,10 +1,12 @@
 package arg
 
 import (
+	"context"
 	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/azdsecinfo/contracts"
 	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/dataproviders/arg/mocks"
 	queriesmock "github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/dataproviders/arg/queries/mocks"
 	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/cache"
+	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/coalescing"
 	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/instrumentation"
 	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/utils"
 	"github.com/stretchr/testify/mock"
@@ -73,16 +75,16 @@ func (suite *ARGDataProviderTestSuite) SetupTest() {
 		&ARGDataProviderConfiguration{
 			CacheExpirationTimeScannedResults:   _expirationTimeScanned,
 			CacheExpirationTimeUnscannedResults: _expirationTimeUnscanned,
-		})
+		}, coalescing.NewRequestCoalescer(instrumentation.NewNoOpInstrumentationProvider(), "ARGDataProvider"))
 }
 
 func (suite *ARGDataProviderTestSuite) Test_GetImageVulnerabilityScanResults_NoKeyInCache() {
-	suite.cacheMock.On("GetResultsFromCache", _digest).Return(contracts.ScanStatus(""), nil, new(cache.MissingKeyCacheError)).Once()
-	suite.cacheMock.On("SetScanFindingsInCache", expected_results, contracts.UnhealthyScan, _digest).Return(nil).Maybe()
+	suite.cacheMock.On("GetResultsFromCache", mock.Anything, _digest).Return(contracts.ScanStatus(""), nil, new(cache.MissingKeyCacheError)).Once()
+	suite.cacheMock.On("SetScanFindingsInCache", mock.Anything, expected_results, contracts.UnhealthyScan, _digest).Return(nil).Maybe()
 	suite.queryGeneratorMock.On("GenerateImageVulnerabilityScanQuery", mock.Anything).Once().Return("Test1", nil)
-	suite.argClientMock.On("QueryResources", "Test1").Once().Return(_results, nil)
+	suite.argClientMock.On("QueryResources", mock.Anything, "Test1").Once().Return(_results, nil)
 
-	scanStatus, scanFindings, err := suite.provider.GetImageVulnerabilityScanResults(_registry, _repository, _digest)
+	scanStatus, scanFindings, err := suite.provider.GetImageVulnerabilityScanResults(context.Background(), _registry, _repository, _digest)
 	suite.Nil(err)
 	suite.Equal(scanStatus, contracts.UnhealthyScan)
 	suite.Equal(scanFindings, expected_results)
@@ -90,9 +92,9 @@ func (suite *ARGDataProviderTestSuite) Test_GetImageVulnerabilityScanResults_NoK
 }
 
 func (suite *ARGDataProviderTestSuite) Test_GetImageVulnerabilityScanResults_KeyInCache() {
-	suite.cacheMock.On("GetResultsFromCache", _digest).Return(contracts.UnhealthyScan, expected_results, nil).Once()
+	suite.cacheMock.On("GetResultsFromCache", mock.Anything, _digest).Return(contracts.UnhealthyScan, expected_results, nil).Once()
 
-	scanStatus, scanFindings, err := suite.provider.GetImageVulnerabilityScanResults(_registry, _repository, _digest)
+	scanStatus, scanFindings, err := suite.provider.GetImageVulnerabilityScanResults(context.Background(), _registry, _repository, _digest)
 	suite.Nil(err)
 	suite.Equal(scanStatus, contracts.UnhealthyScan)
 	suite.Equal(scanFindings, expected_results)
@@ -100,17 +102,17 @@ func (suite *ARGDataProviderTestSuite) Test_GetImageVulnerabilityScanResults_Key
 }
 
 func (suite *ARGDataProviderTestSuite) Test_GetImageVulnerabilityScanResults_NoKeyInCache_SetKey_GetKeySecondTryBeforeExpirationTime_ScannedResults() {
-	suite.cacheMock.On("GetResultsFromCache", _digest).Return(contracts.ScanStatus(""), nil, new(cache.MissingKeyCacheError)).Once()
-	suite.cacheMock.On("GetResultsFromCache", _digest).Return(contracts.UnhealthyScan, expected_results, nil).Once()
-	suite.cacheMock.On("SetScanFindingsInCache", expected_results, contracts.UnhealthyScan, _digest).Return(nil).Maybe()
+	suite.cacheMock.On("GetResultsFromCache", mock.Anything, _digest).Return(contracts.ScanStatus(""), nil, new(cache.MissingKeyCacheError)).Once()
+	suite.cacheMock.On("GetResultsFromCache", mock.Anything, _digest).Return(contracts.UnhealthyScan, expected_results, nil).Once()
+	suite.cacheMock.On("SetScanFindingsInCache", mock.Anything, expected_results, contracts.UnhealthyScan, _digest).Return(nil).Maybe()
 	suite.queryGeneratorMock.On("GenerateImageVulnerabilityScanQuery", mock.Anything).Once().Return("Test1", nil)
-	suite.argClientMock.On("QueryResources", "Test1").Once().Return(_results, nil)
+	suite.argClientMock.On("QueryResources", mock.Anything, "Test1").Once().Return(_results, nil)
 
-	scanStatus, scanFindings, err := suite.provider.GetImageVulnerabilityScanResults(_registry, _repository, _digest)
+	scanStatus, scanFindings, err := suite.provider.GetImageVulnerabilityScanResults(context.Background(), _registry, _repository, _digest)
 	suite.Nil(err)
 	suite.Equal(scanStatus, contracts.UnhealthyScan)
 	suite.Equal(scanFindings, expected_results)
-	scanStatus, scanFindings, err = suite.provider.GetImageVulnerabilityScanResults(_registry, _repository, _digest)
+	scanStatus, scanFindings, err = suite.provider.GetImageVulnerabilityScanResults(context.Background(), _registry, _repository, _digest)
 	suite.Nil(err)
 	suite.Equal(scanStatus, contracts.UnhealthyScan)
 	suite.Equal(scanFindings, expected_results)
@@ -118,17 +120,17 @@ func (suite *ARGDataProviderTestSuite) Test_GetImageVulnerabilityScanResults_NoK
 }
 
 func (suite *ARGDataProviderTestSuite) Test_GetImageVulnerabilityScanResults_NoKeyInCache_SetKey_GetKeySecondTryBeforeExpirationTime_UncannedResults() {
-	suite.cacheMock.On("GetResultsFromCache", _digest).Return(contracts.ScanStatus(""), nil, new(cache.MissingKeyCacheError)).Once()
-	suite.cacheMock.On("GetResultsFromCache", _digest).Return(contracts.Unscanned, nil, nil).Once()
-	suite.cacheMock.On("SetScanFindingsInCache", []*contracts.ScanFinding(nil), contracts.Unscanned, _digest).Return(nil).Maybe()
+	suite.cacheMock.On("GetResultsFromCache", mock.Anything, _digest).Return(contracts.ScanStatus(""), nil, new(cache.MissingKeyCacheError)).Once()
+	suite.cacheMock.On("GetResultsFromCache", mock.Anything, _digest).Return(contracts.Unscanned, nil, nil).Once()
+	suite.cacheMock.On("SetScanFindingsInCache", mock.Anything, []*contracts.ScanFinding(nil), contracts.Unscanned, _digest).Return(nil).Maybe()
 	suite.queryGeneratorMock.On("GenerateImageVulnerabilityScanQuery", mock.Anything).Once().Return("Test1", nil)
-	suite.argClientMock.On("QueryResources", "Test1").Once().Return(_resultsTest2, nil)
+	suite.argClientMock.On("QueryResources", mock.Anything, "Test1").Once().Return(_resultsTest2, nil)
 
-	scanStatus, scanFindings, err := suite.provider.GetImageVulnerabilityScanResults(_registry, _repository, _digest)
+	scanStatus, scanFindings, err := suite.provider.GetImageVulnerabilityScanResults(context.Background(), _registry, _repository, _digest)
 	suite.Nil(err)
 	suite.Equal(scanStatus, contracts.Unscanned)
 	suite.Nil(scanFindings)
-	scanStatus, scanFindings, err = suite.provider.GetImageVulnerabilityScanResults(_registry, _repository, _digest)
+	scanStatus, scanFindings, err = suite.provider.GetImageVulnerabilityScanResults(context.Background(), _registry, _repository, _digest)
 	suite.Nil(err)
 	suite.Equal(scanStatus, contracts.Unscanned)
 	suite.Nil(scanFindings)
@@ -136,16 +138,16 @@ func (suite *ARGDataProviderTestSuite) Test_GetImageVulnerabilityScanResults_NoK
 }
 
 func (suite *ARGDataProviderTestSuite) Test_GetImageVulnerabilityScanResults_NoKeyInCache_SetKey_GetKeySecondTryAfterExpirationTime_UncannedResults() {
-	suite.cacheMock.On("GetResultsFromCache", _digest).Return(contracts.ScanStatus(""), nil, new(cache.MissingKeyCacheError)).Twice()
-	suite.cacheMock.On("SetScanFindingsInCache", []*contracts.ScanFinding(nil), contracts.Unscanned, _digest).Return(nil).Maybe()
+	suite.cacheMock.On("GetResultsFromCache", mock.Anything, _digest).Return(contracts.ScanStatus(""), nil, new(cache.MissingKeyCacheError)).Twice()
+	suite.cacheMock.On("SetScanFindingsInCache", mock.Anything, []*contracts.ScanFinding(nil), contracts.Unscanned, _digest).Return(nil).Maybe()
 	suite.queryGeneratorMock.On("GenerateImageVulnerabilityScanQuery", mock.Anything).Twice().Return("Test1", nil)
-	suite.argClientMock.On("QueryResources", "Test1").Twice().Return(_resultsTest2, nil)
+	suite.argClientMock.On("QueryResources", mock.Anything, "Test1").Twice().Return(_resultsTest2, nil)
 
-	scanStatus, scanFindings, err := suite.provider.GetImageVulnerabilityScanResults(_registry, _repository, _digest)
+	scanStatus, scanFindings, err := suite.provider.GetImageVulnerabilityScanResults(context.Background(), _registry, _repository, _digest)
 	suite.Nil(err)
 	suite.Equal(scanStatus, contracts.Unscanned)
 	suite.Nil(scanFindings)
-	scanStatus, scanFindings, err = suite.provider.GetImageVulnerabilityScanResults(_registry, _repository, _digest)
+	scanStatus, scanFindings, err = suite.provider.GetImageVulnerabilityScanResults(context.Background(), _registry, _repository, _digest)
 	suite.Nil(err)
 	suite.Equal(scanStatus, contracts.Unscanned)
 	suite.Nil(scanFindings)
@@ -153,12 +155,12 @@ func (suite *ARGDataProviderTestSuite) Test_GetImageVulnerabilityScanResults_NoK
 }
 
 func (suite *ARGDataProviderTestSuite) Test_GetImageVulnerabilityScanResults_ErrGetFromCache() {
-	suite.cacheMock.On("GetResultsFromCache", _digest).Return(contracts.ScanStatus(""), nil, utils.NilArgumentError).Once()
-	suite.cacheMock.On("SetScanFindingsInCache", expected_results, contracts.UnhealthyScan, _digest).Return(nil).Maybe()
+	suite.cacheMock.On("GetResultsFromCache", mock.Anything, _digest).Return(contracts.ScanStatus(""), nil, utils.NilArgumentError).Once()
+	suite.cacheMock.On("SetScanFindingsInCache", mock.Anything, expected_results, contracts.UnhealthyScan, _digest).Return(nil).Maybe()
 	suite.queryGeneratorMock.On("GenerateImageVulnerabilityScanQuery", mock.Anything).Once().Return("Test1", nil)
-	suite.argClientMock.On("QueryResources", "Test1").Once().Return(_results, nil)
+	suite.argClientMock.On("QueryResources", mock.Anything, "Test1").Once().Return(_results, nil)
 
-	scanStatus, scanFindings, err := suite.provider.GetImageVulnerabilityScanResults(_registry, _repository, _digest)
+	scanStatus, scanFindings, err := suite.provider.GetImageVulnerabilityScanResults(context.Background(), _registry, _repository, _digest)
 	suite.Nil(err)
 	suite.Equal(scanStatus, contracts.UnhealthyScan)
 	suite.Equal(scanFindings, expected_results)
@@ -166,12 +168,12 @@ func (suite *ARGDataProviderTestSuite) Test_GetImageVulnerabilityScanResults_Err
 }
 
 func (suite *ARGDataProviderTestSuite) Test_GetImageVulnerabilityScanResults_ErrSetToCache() {
-	suite.cacheMock.On("GetResultsFromCache", _digest).Return(contracts.ScanStatus(""), nil, new(cache.MissingKeyCacheError)).Once()
-	suite.cacheMock.On("SetScanFindingsInCache", expected_results, contracts.UnhealthyScan, _digest).Once().Return(utils.NilArgumentError)
+	suite.cacheMock.On("GetResultsFromCache", mock.Anything, _digest).Return(contracts.ScanStatus(""), nil, new(cache.MissingKeyCacheError)).Once()
+	suite.cacheMock.On("SetScanFindingsInCache", mock.Anything, expected_results, contracts.UnhealthyScan, _digest).Once().Return(utils.NilArgumentError)
 	suite.queryGeneratorMock.On("GenerateImageVulnerabilityScanQuery", mock.Anything).Once().Return("Test1", nil)
-	suite.argClientMock.On("QueryResources", "Test1").Once().Return(_results, nil)
+	suite.argClientMock.On("QueryResources", mock.Anything, "Test1").Once().Return(_results, nil)
 
-	scanStatus, scanFindings, err := suite.provider.GetImageVulnerabilityScanResults(_registry, _repository, _digest)
+	scanStatus, scanFindings, err := suite.provider.GetImageVulnerabilityScanResults(context.Background(), _registry, _repository, _digest)
 	time.Sleep(time.Second)
 	suite.Nil(err)
 	suite.Equal(scanStatus, contracts.UnhealthyScan)
@@ -182,7 +184,7 @@ func (suite *ARGDataProviderTestSuite) Test_GetImageVulnerabilityScanResults_Err
 func (suite *ARGDataProviderTestSuite) Test_GetImageVulnerabilityScanResults() {
 
 	//	 TODO
-	//status, findings , err := suite.provider.GetImageVulnerabilityScanResults(_registryMock, _repositoryMock, _digestMock)
+	//status, findings , err := suite.provider.GetImageVulnerabilityScanResults(context.Background(), _registryMock, _repositoryMock, _digestMock)
 
 }
 
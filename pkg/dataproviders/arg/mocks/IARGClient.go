@@ -2,20 +2,24 @@
 
 package mocks
 
-import mock "github.com/stretchr/testify/mock"
+import (
+	context "context"
+
+	mock "github.com/stretchr/testify/mock"
+)
 
 // IARGClient is an autogenerated mock type for the IARGClient type
 type IARGClient struct {
 	mock.Mock
 }
 
-// QueryResources provides a mock function with given fields: query
-func (_m *IARGClient) QueryResources(query string) ([]interface{}, error) {
-	ret := _m.Called(query)
+// QueryResources provides a mock function with given fields: ctx, query
+func (_m *IARGClient) QueryResources(ctx context.Context, query string) ([]interface{}, error) {
+	ret := _m.Called(ctx, query)
 
 	var r0 []interface{}
-	if rf, ok := ret.Get(0).(func(string) []interface{}); ok {
-		r0 = rf(query)
+	if rf, ok := ret.Get(0).(func(context.Context, string) []interface{}); ok {
+		r0 = rf(ctx, query)
 	} else {
 		if ret.Get(0) != nil {
 			r0 = ret.Get(0).([]interface{})
@@ -23,8 +27,8 @@ func (_m *IARGClient) QueryResources(query string) ([]interface{}, error) {
 	}
 
 	var r1 error
-	if rf, ok := ret.Get(1).(func(string) error); ok {
-		r1 = rf(query)
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, query)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -3,6 +3,8 @@
 package mocks
 
 import (
+	context "context"
+
 	contracts "github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/azdsecinfo/contracts"
 	mock "github.com/stretchr/testify/mock"
 )
@@ -12,20 +14,20 @@ type IARGDataProvider struct {
 	mock.Mock
 }
 
-// GetImageVulnerabilityScanResults provides a mock function with given fields: registry, repository, digest
-func (_m *IARGDataProvider) GetImageVulnerabilityScanResults(registry string, repository string, digest string) (contracts.ScanStatus, []*contracts.ScanFinding, error) {
-	ret := _m.Called(registry, repository, digest)
+// GetImageVulnerabilityScanResults provides a mock function with given fields: ctx, registry, repository, digest
+func (_m *IARGDataProvider) GetImageVulnerabilityScanResults(ctx context.Context, registry string, repository string, digest string) (contracts.ScanStatus, []*contracts.ScanFinding, error) {
+	ret := _m.Called(ctx, registry, repository, digest)
 
 	var r0 contracts.ScanStatus
-	if rf, ok := ret.Get(0).(func(string, string, string) contracts.ScanStatus); ok {
-		r0 = rf(registry, repository, digest)
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, string) contracts.ScanStatus); ok {
+		r0 = rf(ctx, registry, repository, digest)
 	} else {
 		r0 = ret.Get(0).(contracts.ScanStatus)
 	}
 
 	var r1 []*contracts.ScanFinding
-	if rf, ok := ret.Get(1).(func(string, string, string) []*contracts.ScanFinding); ok {
-		r1 = rf(registry, repository, digest)
+	if rf, ok := ret.Get(1).(func(context.Context, string, string, string) []*contracts.ScanFinding); ok {
+		r1 = rf(ctx, registry, repository, digest)
 	} else {
 		if ret.Get(1) != nil {
 			r1 = ret.Get(1).([]*contracts.ScanFinding)
@@ -33,8 +35,8 @@ func (_m *IARGDataProvider) GetImageVulnerabilityScanResults(registry string, re
 	}
 
 	var r2 error
-	if rf, ok := ret.Get(2).(func(string, string, string) error); ok {
-		r2 = rf(registry, repository, digest)
+	if rf, ok := ret.Get(2).(func(context.Context, string, string, string) error); ok {
+		r2 = rf(ctx, registry, repository, digest)
 	} else {
 		r2 = ret.Error(2)
 	}
@@ -3,6 +3,8 @@
 package mocks
 
 import (
+	context "context"
+
 	contracts "github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/azdsecinfo/contracts"
 	mock "github.com/stretchr/testify/mock"
 )
@@ -12,20 +14,20 @@ type IARGDataProviderCacheClient struct {
 	mock.Mock
 }
 
-// GetResultsFromCache provides a mock function with given fields: digest
-func (_m *IARGDataProviderCacheClient) GetResultsFromCache(digest string) (contracts.ScanStatus, []*contracts.ScanFinding, error) {
-	ret := _m.Called(digest)
+// GetResultsFromCache provides a mock function with given fields: ctx, digest
+func (_m *IARGDataProviderCacheClient) GetResultsFromCache(ctx context.Context, digest string) (contracts.ScanStatus, []*contracts.ScanFinding, error) {
+	ret := _m.Called(ctx, digest)
 
 	var r0 contracts.ScanStatus
-	if rf, ok := ret.Get(0).(func(string) contracts.ScanStatus); ok {
-		r0 = rf(digest)
+	if rf, ok := ret.Get(0).(func(context.Context, string) contracts.ScanStatus); ok {
+		r0 = rf(ctx, digest)
 	} else {
 		r0 = ret.Get(0).(contracts.ScanStatus)
 	}
 
 	var r1 []*contracts.ScanFinding
-	if rf, ok := ret.Get(1).(func(string) []*contracts.ScanFinding); ok {
-		r1 = rf(digest)
+	if rf, ok := ret.Get(1).(func(context.Context, string) []*contracts.ScanFinding); ok {
+		r1 = rf(ctx, digest)
 	} else {
 		if ret.Get(1) != nil {
 			r1 = ret.Get(1).([]*contracts.ScanFinding)
@@ -33,8 +35,8 @@ func (_m *IARGDataProviderCacheClient) GetResultsFromCache(digest string) (contr
 	}
 
 	var r2 error
-	if rf, ok := ret.Get(2).(func(string) error); ok {
-		r2 = rf(digest)
+	if rf, ok := ret.Get(2).(func(context.Context, string) error); ok {
+		r2 = rf(ctx, digest)
 	} else {
 		r2 = ret.Error(2)
 	}
@@ -42,13 +44,13 @@ func (_m *IARGDataProviderCacheClient) GetResultsFromCache(digest string) (contr
 	return r0, r1, r2
 }
 
-// SetScanFindingsInCache provides a mock function with given fields: scanFindings, scanStatus, digest
-func (_m *IARGDataProviderCacheClient) SetScanFindingsInCache(scanFindings []*contracts.ScanFinding, scanStatus contracts.ScanStatus, digest string) error {
-	ret := _m.Called(scanFindings, scanStatus, digest)
+// SetScanFindingsInCache provides a mock function with given fields: ctx, scanFindings, scanStatus, digest
+func (_m *IARGDataProviderCacheClient) SetScanFindingsInCache(ctx context.Context, scanFindings []*contracts.ScanFinding, scanStatus contracts.ScanStatus, digest string) error {
+	ret := _m.Called(ctx, scanFindings, scanStatus, digest)
 
 	var r0 error
-	if rf, ok := ret.Get(0).(func([]*contracts.ScanFinding, contracts.ScanStatus, string) error); ok {
-		r0 = rf(scanFindings, scanStatus, digest)
+	if rf, ok := ret.Get(0).(func(context.Context, []*contracts.ScanFinding, contracts.ScanStatus, string) error); ok {
+		r0 = rf(ctx, scanFindings, scanStatus, digest)
 	} else {
 		r0 = ret.Error(0)
 	}
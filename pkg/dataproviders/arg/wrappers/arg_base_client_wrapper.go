@@ -2,6 +2,7 @@ package wrappers
 
 import (
 	"context"
+	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/cloudenvironment"
 	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/retrypolicy"
 	argbase "github.com/Azure/azure-sdk-for-go/services/resourcegraph/mgmt/2021-03-01/resourcegraph"
 	"github.com/Azure/go-autorest/autorest"
@@ -18,8 +19,8 @@ type IARGBaseClientWrapper interface {
 
 // NewArgBaseClientWrapper get authorizer from auth.NewAuthorizerFromCLIWithResource
 func NewArgBaseClientWrapper(retryPolicyConfig *retrypolicy.RetryPolicyConfiguration, authorizer autorest.Authorizer) (*argbase.BaseClient, error) {
-	// Create new client
-	argBaseClient := argbase.New()
+	// Create new client of the resource graph endpoint of the cloud environment
+	argBaseClient := argbase.NewWithBaseURI(cloudenvironment.GetCloudEnvironmentInstance().GetResourceGraphEndpoint())
 	// Assign the retry policy configuration to the client.
 	argBaseClient.RetryAttempts = retryPolicyConfig.RetryAttempts
 	retryDuration := retryPolicyConfig.GetBackOffDuration()
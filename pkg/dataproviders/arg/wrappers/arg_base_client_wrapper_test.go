@@ -0,0 +1,47 @@
+package wrappers
+
+import (
+	"testing"
+
+	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/cloudenvironment"
+	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/retrypolicy"
+	"github.com/Azure/go-autorest/autorest"
+	"github.com/Azure/go-autorest/autorest/azure"
+	"github.com/stretchr/testify/suite"
+)
+
+type TestSuite struct {
+	suite.Suite
+}
+
+func (suite *TestSuite) TearDownTest() {
+	cloudenvironment.ResetCloudEnvironmentForTests()
+}
+
+func (suite *TestSuite) Test_NewArgBaseClientWrapper_CloudEnvironments_ResourceGraphEndpointOfCloud() {
+	tests := []struct {
+		name            string
+		environment     azure.Environment
+		expectedBaseURI string
+	}{
+		{name: "public", environment: azure.PublicCloud, expectedBaseURI: "https://management.azure.com"},
+		{name: "usgovernment", environment: azure.USGovernmentCloud, expectedBaseURI: "https://management.usgovcloudapi.net"},
+		{name: "china", environment: azure.ChinaCloud, expectedBaseURI: "https://management.chinacloudapi.cn"},
+	}
+	for _, tt := range tests {
+		suite.Run(tt.name, func() {
+			cloudenvironment.UpdateCloudEnvironmentForTests(tt.environment)
+			authorizer := autorest.NullAuthorizer{}
+
+			argBaseClient, err := NewArgBaseClientWrapper(&retrypolicy.RetryPolicyConfiguration{RetryAttempts: 1, RetryDurationInMS: 10}, authorizer)
+
+			suite.Nil(err)
+			suite.Equal(tt.expectedBaseURI, argBaseClient.BaseURI)
+			suite.Equal(authorizer, argBaseClient.Authorizer)
+		})
+	}
+}
+
+func TestArgBaseClientWrapper(t *testing.T) {
+	suite.Run(t, new(TestSuite))
+}
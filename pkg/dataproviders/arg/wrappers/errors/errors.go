@@ -0,0 +1,86 @@
+package errors
+
+import (
+	stderrors "errors"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/registry/errors"
+	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/retrypolicy"
+	"github.com/Azure/go-autorest/autorest"
+	"github.com/Azure/go-autorest/autorest/adal"
+)
+
+const (
+	// ARGDataProvider is the name of ARG in the known errors
+	ARGDataProvider = "ARG"
+	// _bearerAuthorizerPackageType is the package type of the autorest errors of token refresh failures of the bearer authorizer
+	_bearerAuthorizerPackageType = "azure.BearerAuthorizer"
+)
+
+// isIdentityErr returns true if the error is a failure to acquire the token of the identity (e.g. the MSI endpoint failed).
+// The error of the bearer authorizer is returned as is by the ARG client, so its status code is the status code of the token request.
+func isIdentityErr(err error) bool {
+	var tokenRefreshError adal.TokenRefreshError
+	if stderrors.As(err, &tokenRefreshError) {
+		return true
+	}
+	var detailedError autorest.DetailedError
+	return stderrors.As(err, &detailedError) && detailedError.PackageType == _bearerAuthorizerPackageType
+}
+
+// getStatusCode returns the status code of the response of ARG, or false if the error has no response (e.g. network failure).
+func getStatusCode(err error) (int, bool) {
+	var detailedError autorest.DetailedError
+	if !stderrors.As(err, &detailedError) {
+		return 0, false
+	}
+	statusCode, ok := detailedError.StatusCode.(int)
+	if !ok || statusCode == autorest.UndefinedStatusCode {
+		return 0, false
+	}
+	return statusCode, true
+}
+
+// TryParseARGErrToKnownErr Gets an err of the ARG base client and try to convert it to known err
+func TryParseARGErrToKnownErr(err error) (error, bool) {
+	if err == nil {
+		return nil, false
+	}
+	if isIdentityErr(err) {
+		return errors.NewIdentityUnavailableErr(ARGDataProvider, err), true
+	}
+
+	if statusCode, ok := getStatusCode(err); ok {
+		switch {
+		case statusCode == http.StatusUnauthorized || statusCode == http.StatusForbidden:
+			return errors.NewDataProviderUnauthorizedErr(ARGDataProvider, err), true
+		case statusCode == http.StatusTooManyRequests:
+			return errors.NewDataProviderThrottledErr(ARGDataProvider, err), true
+		case statusCode >= http.StatusInternalServerError:
+			return errors.NewDataProviderUnavailableErr(ARGDataProvider, err), true
+		}
+	}
+
+	// Network failures (e.g. connection refused, timeout)
+	var netError net.Error
+	if stderrors.As(err, &netError) {
+		return errors.NewDataProviderUnavailableErr(ARGDataProvider, err), true
+	}
+	// Unknown error
+	return err, false
+}
+
+// GetRetryAfter returns the wait duration that ARG hinted by the Retry-After header of a throttled (429) response.
+// Returns false in case that the error isn't throttling of ARG or the response has no valid Retry-After header.
+func GetRetryAfter(err error) (time.Duration, bool) {
+	if statusCode, ok := getStatusCode(err); !ok || statusCode != http.StatusTooManyRequests {
+		return 0, false
+	}
+	var detailedError autorest.DetailedError
+	if !stderrors.As(err, &detailedError) {
+		return 0, false
+	}
+	return retrypolicy.GetRetryAfter(detailedError.Response)
+}
@@ -0,0 +1,40 @@
+package decisionlog
+
+import (
+	"fmt"
+	"github.com/pkg/errors"
+)
+
+const (
+	// FileDecisionLogSinkType is the type of FileDecisionLogSink
+	FileDecisionLogSinkType = "file"
+	// HTTPDecisionLogSinkType is the type of HTTPDecisionLogSink
+	HTTPDecisionLogSinkType = "http"
+)
+
+// IDecisionLogSink is the destination that the decision records are written to
+type IDecisionLogSink interface {
+	// Write writes the decision record to the sink
+	Write(record *DecisionRecord) error
+	// Close releases the resources of the sink (e.g. the opened file). Records can't be written after the sink is closed.
+	Close() error
+}
+
+// CreateDecisionLogSink creates the decision log sink of the sink type (FileDecisionLogSinkType or HTTPDecisionLogSinkType)
+func CreateDecisionLogSink(sinkType string, fileSinkConfiguration *FileDecisionLogSinkConfiguration, httpSinkConfiguration *HTTPDecisionLogSinkConfiguration) (IDecisionLogSink, error) {
+	switch sinkType {
+	case FileDecisionLogSinkType:
+		sink, err := NewFileDecisionLogSink(fileSinkConfiguration)
+		if err != nil {
+			return nil, errors.Wrap(err, "decisionlog.CreateDecisionLogSink failed to create file sink")
+		}
+		return sink, nil
+	case HTTPDecisionLogSinkType:
+		if httpSinkConfiguration == nil || httpSinkConfiguration.Endpoint == "" {
+			return nil, errors.New("decisionlog.CreateDecisionLogSink http sink requires an endpoint")
+		}
+		return NewHTTPDecisionLogSink(httpSinkConfiguration, newHTTPClient(httpSinkConfiguration)), nil
+	default:
+		return nil, fmt.Errorf("decisionlog.CreateDecisionLogSink unknown sink type <%s>", sinkType)
+	}
+}
@@ -0,0 +1,133 @@
+package decisionlog
+
+import (
+	"bufio"
+	"encoding/json"
+	httpmocks "github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/httpclient/mocks"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const (
+	_endpoint = "https://siem.example.com/decisions"
+)
+
+type DecisionLogSinkTestSuite struct {
+	suite.Suite
+	record         *DecisionRecord
+	httpClientMock *httpmocks.IHttpClient
+	httpSink       *HTTPDecisionLogSink
+}
+
+// This will run before each test in the suite
+func (suite *DecisionLogSinkTestSuite) SetupTest() {
+	suite.record = NewDecisionRecord("uid", "CREATE", "default", "Pod", "podTest")
+	suite.httpClientMock = &httpmocks.IHttpClient{}
+	suite.httpSink = NewHTTPDecisionLogSink(&HTTPDecisionLogSinkConfiguration{
+		Endpoint: _endpoint,
+		Headers:  map[string]string{"Authorization": "Bearer token"},
+	}, suite.httpClientMock)
+}
+
+func (suite *DecisionLogSinkTestSuite) Test_FileSink_Write_RecordsAppendedAsJSONLines() {
+	filePath := filepath.Join(suite.T().TempDir(), "dir", "decisions.jsonl")
+	sink, err := NewFileDecisionLogSink(&FileDecisionLogSinkConfiguration{FilePath: filePath})
+	suite.Nil(err)
+	secondRecord := NewDecisionRecord("uid2", "UPDATE", "default", "Deployment", "deploymentTest")
+
+	suite.Nil(sink.Write(suite.record))
+	suite.Nil(sink.Write(secondRecord))
+
+	file, err := os.Open(filePath)
+	suite.Nil(err)
+	defer file.Close()
+	scanner := bufio.NewScanner(file)
+	var requestUIDs []string
+	for scanner.Scan() {
+		record := &decisionRecordJSON{}
+		suite.Nil(json.Unmarshal(scanner.Bytes(), record))
+		requestUIDs = append(requestUIDs, record.RequestUID)
+	}
+	suite.Equal([]string{"uid", "uid2"}, requestUIDs)
+}
+
+func (suite *DecisionLogSinkTestSuite) Test_FileSink_ExistingFile_RecordAppended() {
+	filePath := filepath.Join(suite.T().TempDir(), "decisions.jsonl")
+	suite.Nil(ioutil.WriteFile(filePath, []byte("existing\n"), 0644))
+	sink, err := NewFileDecisionLogSink(&FileDecisionLogSinkConfiguration{FilePath: filePath})
+	suite.Nil(err)
+
+	suite.Nil(sink.Write(suite.record))
+
+	content, err := ioutil.ReadFile(filePath)
+	suite.Nil(err)
+	lines := strings.Split(strings.TrimSuffix(string(content), "\n"), "\n")
+	suite.Equal(2, len(lines))
+	suite.Equal("existing", lines[0])
+}
+
+func (suite *DecisionLogSinkTestSuite) Test_HTTPSink_Write_RecordPosted() {
+	suite.httpClientMock.On("Do", mock.MatchedBy(func(request *http.Request) bool {
+		body, err := ioutil.ReadAll(request.Body)
+		if err != nil {
+			return false
+		}
+		record := &decisionRecordJSON{}
+		return json.Unmarshal(body, record) == nil &&
+			record.RequestUID == "uid" &&
+			request.Method == http.MethodPost &&
+			request.URL.String() == _endpoint &&
+			request.Header.Get("Content-Type") == "application/json" &&
+			request.Header.Get("Authorization") == "Bearer token"
+	})).Return(&http.Response{StatusCode: http.StatusNoContent, Body: ioutil.NopCloser(strings.NewReader(""))}, nil).Once()
+
+	err := suite.httpSink.Write(suite.record)
+
+	suite.Nil(err)
+	suite.httpClientMock.AssertExpectations(suite.T())
+}
+
+func (suite *DecisionLogSinkTestSuite) Test_HTTPSink_Write_UnexpectedStatusCode_Error() {
+	suite.httpClientMock.On("Do", mock.Anything).Return(&http.Response{StatusCode: http.StatusServiceUnavailable, Body: ioutil.NopCloser(strings.NewReader("unavailable"))}, nil).Once()
+
+	err := suite.httpSink.Write(suite.record)
+
+	suite.NotNil(err)
+	suite.Contains(err.Error(), "503")
+	suite.httpClientMock.AssertExpectations(suite.T())
+}
+
+func (suite *DecisionLogSinkTestSuite) Test_HTTPSink_Write_ClientError_Error() {
+	clientErr := errors.New("connection refused")
+	suite.httpClientMock.On("Do", mock.Anything).Return(nil, clientErr).Once()
+
+	err := suite.httpSink.Write(suite.record)
+
+	suite.Equal(clientErr, errors.Cause(err))
+	suite.httpClientMock.AssertExpectations(suite.T())
+}
+
+func (suite *DecisionLogSinkTestSuite) Test_CreateDecisionLogSink_UnknownSinkType_Error() {
+	sink, err := CreateDecisionLogSink("kafka", nil, nil)
+
+	suite.Nil(sink)
+	suite.NotNil(err)
+}
+
+func (suite *DecisionLogSinkTestSuite) Test_CreateDecisionLogSink_HTTPSinkWithoutEndpoint_Error() {
+	sink, err := CreateDecisionLogSink(HTTPDecisionLogSinkType, nil, &HTTPDecisionLogSinkConfiguration{})
+
+	suite.Nil(sink)
+	suite.NotNil(err)
+}
+
+func TestDecisionLogSink(t *testing.T) {
+	suite.Run(t, new(DecisionLogSinkTestSuite))
+}
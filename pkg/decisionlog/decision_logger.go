@@ -0,0 +1,147 @@
+package decisionlog
+
+import (
+	"context"
+	decisionlogmetric "github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/decisionlog/metric"
+	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/instrumentation"
+	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/instrumentation/metric"
+	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/instrumentation/metric/util"
+	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/instrumentation/trace"
+	"github.com/pkg/errors"
+	"math/rand"
+	"sync"
+)
+
+const (
+	// _defaultQueueSize is the size of the queue of the records that weren't written yet in case that no size is configured
+	_defaultQueueSize = 1000
+)
+
+// IDecisionLogger logs the decision record of each admission request
+type IDecisionLogger interface {
+	// Log logs the decision record. It doesn't block the admission request - the record is written to the sink in the background.
+	Log(record *DecisionRecord)
+	// Close stops logging new records, waits until the queued records are written (or ctx is done) and closes the sink.
+	Close(ctx context.Context) error
+}
+
+// DecisionLogger implements IDecisionLogger interface
+var _ IDecisionLogger = (*DecisionLogger)(nil)
+
+// DecisionLogger is IDecisionLogger that samples the records, redacts them (if configured) and writes them to the sink
+// in the background. Records are dropped (and reported by metric) if the queue is full or the sink failed to write them.
+type DecisionLogger struct {
+	//tracerProvider is tracer provider of DecisionLogger
+	tracerProvider trace.ITracerProvider
+	//metricSubmitter is metric submitter of DecisionLogger
+	metricSubmitter metric.IMetricSubmitter
+	// sink is the destination of the records
+	sink IDecisionLogSink
+	// configuration is the configuration of DecisionLogger
+	configuration *DecisionLoggerConfiguration
+	// queue is the queue of the records that weren't written to the sink yet
+	queue chan *DecisionRecord
+	// randFloat64 returns a random number in [0.0,1.0) - used to sample the records
+	randFloat64 func() float64
+	// lock guards queue from being closed while records are queued
+	lock sync.RWMutex
+	// isClosed is true once Close is called - records that are logged afterwards are dropped
+	isClosed bool
+	// writerDone is closed once all the queued records were written to the sink
+	writerDone chan struct{}
+}
+
+// DecisionLoggerConfiguration is configuration data for DecisionLogger
+type DecisionLoggerConfiguration struct {
+	// Enabled is whether the decision records should be logged
+	Enabled bool
+	// Sink is the type of the sink of the records (FileDecisionLogSinkType or HTTPDecisionLogSinkType)
+	Sink string
+	// SamplingRate is the fraction of the requests that their records are logged (0.0 - none, 1.0 - all)
+	SamplingRate float64
+	// RedactImagePullSecrets is whether the names of the image pull secrets should be redacted
+	RedactImagePullSecrets bool
+	// QueueSize is the maximal number of records that weren't written to the sink yet
+	QueueSize int
+}
+
+// NewDecisionLogger Ctor for DecisionLogger - starts the background writing of the records to the sink
+func NewDecisionLogger(instrumentationProvider instrumentation.IInstrumentationProvider, sink IDecisionLogSink, configuration *DecisionLoggerConfiguration) *DecisionLogger {
+	queueSize := configuration.QueueSize
+	if queueSize <= 0 {
+		queueSize = _defaultQueueSize
+	}
+
+	logger := &DecisionLogger{
+		tracerProvider:  instrumentationProvider.GetTracerProvider("DecisionLogger"),
+		metricSubmitter: instrumentationProvider.GetMetricSubmitter(),
+		sink:            sink,
+		configuration:   configuration,
+		queue:           make(chan *DecisionRecord, queueSize),
+		randFloat64:     rand.Float64,
+		writerDone:      make(chan struct{}),
+	}
+	go logger.writeRecords()
+	return logger
+}
+
+// Log samples the record, redacts it (if configured) and queues it to be written to the sink
+func (logger *DecisionLogger) Log(record *DecisionRecord) {
+	tracer := logger.tracerProvider.GetTracer("Log")
+	if record == nil || logger.randFloat64() >= logger.configuration.SamplingRate {
+		return
+	}
+	if logger.configuration.RedactImagePullSecrets {
+		record.RedactImagePullSecrets()
+	}
+
+	logger.lock.RLock()
+	defer logger.lock.RUnlock()
+	if logger.isClosed {
+		tracer.Info("Decision record was dropped - the logger is closed", "RequestUID", record.RequestUID)
+		logger.metricSubmitter.SendMetric(1, decisionlogmetric.NewDecisionLogDroppedMetric(decisionlogmetric.ClosedDecisionLogDroppedReason))
+		return
+	}
+	select {
+	case logger.queue <- record:
+	default:
+		tracer.Info("Decision record was dropped - the queue is full", "RequestUID", record.RequestUID)
+		logger.metricSubmitter.SendMetric(1, decisionlogmetric.NewDecisionLogDroppedMetric(decisionlogmetric.QueueFullDecisionLogDroppedReason))
+	}
+}
+
+// Close stops logging new records, waits until the queued records are written to the sink and closes the sink.
+// If ctx is done before all the queued records are written, the error of ctx is returned and the sink isn't closed,
+// since the remaining records are still being written to it.
+func (logger *DecisionLogger) Close(ctx context.Context) error {
+	logger.lock.Lock()
+	if !logger.isClosed {
+		logger.isClosed = true
+		close(logger.queue)
+	}
+	logger.lock.Unlock()
+
+	select {
+	case <-logger.writerDone:
+	case <-ctx.Done():
+		return errors.Wrapf(ctx.Err(), "DecisionLogger.Close stopped waiting for <%d> queued records", len(logger.queue))
+	}
+	if err := logger.sink.Close(); err != nil {
+		return errors.Wrap(err, "DecisionLogger.Close failed to close the sink")
+	}
+	return nil
+}
+
+// writeRecords writes the queued records to the sink until the queue is closed
+func (logger *DecisionLogger) writeRecords() {
+	tracer := logger.tracerProvider.GetTracer("writeRecords")
+	defer close(logger.writerDone)
+	for record := range logger.queue {
+		if err := logger.sink.Write(record); err != nil {
+			err = errors.Wrapf(err, "DecisionLogger.writeRecords failed to write the record of request <%s>", record.RequestUID)
+			tracer.Error(err, "")
+			logger.metricSubmitter.SendMetric(1, util.NewErrorEncounteredMetric(err, "DecisionLogger.writeRecords"))
+			logger.metricSubmitter.SendMetric(1, decisionlogmetric.NewDecisionLogDroppedMetric(decisionlogmetric.SinkErrorDecisionLogDroppedReason))
+		}
+	}
+}
@@ -0,0 +1,243 @@
+package decisionlog
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/Azure/AzureDefender-K8S-InClusterDefense/cmd/webhook/admisionrequest"
+	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/azdsecinfo/contracts"
+	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/instrumentation"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/suite"
+	corev1 "k8s.io/api/core/v1"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+const (
+	_recordWaitTimeout = time.Second
+)
+
+// channelDecisionLogSink is IDecisionLogSink that sends the written records to a channel
+type channelDecisionLogSink struct {
+	records  chan *DecisionRecord
+	err      error
+	isClosed bool
+}
+
+func (sink *channelDecisionLogSink) Write(record *DecisionRecord) error {
+	sink.records <- record
+	return sink.err
+}
+
+func (sink *channelDecisionLogSink) Close() error {
+	sink.isClosed = true
+	return nil
+}
+
+type DecisionLoggerTestSuite struct {
+	suite.Suite
+	sink   *channelDecisionLogSink
+	record *DecisionRecord
+}
+
+// This will run before each test in the suite
+func (suite *DecisionLoggerTestSuite) SetupTest() {
+	suite.sink = &channelDecisionLogSink{records: make(chan *DecisionRecord, 10)}
+	suite.record = NewDecisionRecord("uid", "CREATE", "default", "Pod", "podTest")
+	suite.record.SetWorkloadResource(&admisionrequest.WorkloadResource{
+		Metadata: &admisionrequest.ObjectMetadata{
+			Name:            "podTest",
+			OwnerReferences: []*admisionrequest.OwnerReference{{APIVersion: "apps/v1", Kind: "ReplicaSet", Name: "rs"}},
+		},
+		Spec: &admisionrequest.PodSpec{
+			ServiceAccountName: "serviceAccount",
+			ImagePullSecrets:   []*corev1.LocalObjectReference{{Name: "secret1"}, {Name: "secret2"}},
+		},
+	})
+}
+
+func (suite *DecisionLoggerTestSuite) Test_Log_SamplingRateOne_RecordWritten() {
+	logger := suite.newLogger(&DecisionLoggerConfiguration{SamplingRate: 1})
+
+	logger.Log(suite.record)
+
+	suite.Equal(suite.record, suite.waitForRecord())
+	suite.Equal([]string{"secret1", "secret2"}, suite.record.ImagePullSecrets)
+}
+
+func (suite *DecisionLoggerTestSuite) Test_Log_SamplingRateZero_RecordNotWritten() {
+	logger := suite.newLogger(&DecisionLoggerConfiguration{SamplingRate: 0})
+
+	logger.Log(suite.record)
+
+	suite.Nil(suite.waitForRecord())
+}
+
+func (suite *DecisionLoggerTestSuite) Test_Log_RandomAboveSamplingRate_RecordNotWritten() {
+	logger := suite.newLogger(&DecisionLoggerConfiguration{SamplingRate: 0.5})
+	logger.randFloat64 = func() float64 { return 0.5 }
+
+	logger.Log(suite.record)
+
+	suite.Nil(suite.waitForRecord())
+}
+
+func (suite *DecisionLoggerTestSuite) Test_Log_RandomBelowSamplingRate_RecordWritten() {
+	logger := suite.newLogger(&DecisionLoggerConfiguration{SamplingRate: 0.5})
+	logger.randFloat64 = func() float64 { return 0.49 }
+
+	logger.Log(suite.record)
+
+	suite.Equal(suite.record, suite.waitForRecord())
+}
+
+func (suite *DecisionLoggerTestSuite) Test_Log_RedactImagePullSecrets_SecretsRedacted() {
+	logger := suite.newLogger(&DecisionLoggerConfiguration{SamplingRate: 1, RedactImagePullSecrets: true})
+
+	logger.Log(suite.record)
+
+	record := suite.waitForRecord()
+	suite.Equal([]string{_redactedValue, _redactedValue}, record.ImagePullSecrets)
+	suite.Equal("serviceAccount", record.ServiceAccountName)
+}
+
+func (suite *DecisionLoggerTestSuite) Test_Log_SinkError_NextRecordsWritten() {
+	suite.sink.err = errors.New("sink error")
+	logger := suite.newLogger(&DecisionLoggerConfiguration{SamplingRate: 1})
+	secondRecord := NewDecisionRecord("uid2", "CREATE", "default", "Pod", "podTest2")
+
+	logger.Log(suite.record)
+	logger.Log(secondRecord)
+
+	suite.Equal(suite.record, suite.waitForRecord())
+	suite.Equal(secondRecord, suite.waitForRecord())
+}
+
+func (suite *DecisionLoggerTestSuite) Test_Log_NilRecord_NothingWritten() {
+	logger := suite.newLogger(&DecisionLoggerConfiguration{SamplingRate: 1})
+
+	logger.Log(nil)
+
+	suite.Nil(suite.waitForRecord())
+}
+
+func (suite *DecisionLoggerTestSuite) Test_Close_RecordsLoggedBeforeClose_RecordsPersisted() {
+	filePath := filepath.Join(suite.T().TempDir(), "decisions.jsonl")
+	sink, err := NewFileDecisionLogSink(&FileDecisionLogSinkConfiguration{FilePath: filePath})
+	suite.Nil(err)
+	logger := NewDecisionLogger(instrumentation.NewNoOpInstrumentationProvider(), sink, &DecisionLoggerConfiguration{SamplingRate: 1, QueueSize: 100})
+	var expectedRequestUIDs []string
+	for i := 0; i < 50; i++ {
+		uid := fmt.Sprintf("uid%d", i)
+		expectedRequestUIDs = append(expectedRequestUIDs, uid)
+		logger.Log(NewDecisionRecord(uid, "CREATE", "default", "Pod", "podTest"))
+	}
+
+	suite.Nil(logger.Close(context.Background()))
+
+	file, err := os.Open(filePath)
+	suite.Nil(err)
+	defer file.Close()
+	scanner := bufio.NewScanner(file)
+	var requestUIDs []string
+	for scanner.Scan() {
+		record := &decisionRecordJSON{}
+		suite.Nil(json.Unmarshal(scanner.Bytes(), record))
+		requestUIDs = append(requestUIDs, record.RequestUID)
+	}
+	suite.Equal(expectedRequestUIDs, requestUIDs)
+	suite.NotNil(sink.Write(suite.record))
+}
+
+func (suite *DecisionLoggerTestSuite) Test_Close_RecordLoggedAfterClose_RecordDropped() {
+	logger := suite.newLogger(&DecisionLoggerConfiguration{SamplingRate: 1})
+	suite.Nil(logger.Close(context.Background()))
+
+	logger.Log(suite.record)
+
+	suite.Nil(suite.waitForRecord())
+	suite.True(suite.sink.isClosed)
+}
+
+func (suite *DecisionLoggerTestSuite) Test_Close_ContextDoneBeforeQueueDrained_ErrorAndSinkNotClosed() {
+	// The sink is blocked since nobody reads the written records
+	suite.sink.records = make(chan *DecisionRecord)
+	logger := suite.newLogger(&DecisionLoggerConfiguration{SamplingRate: 1})
+	logger.Log(suite.record)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err := logger.Close(ctx)
+
+	suite.True(errors.Is(err, context.Canceled))
+	suite.False(suite.sink.isClosed)
+	suite.Equal(suite.record, suite.waitForRecord())
+}
+
+func (suite *DecisionLoggerTestSuite) Test_MarshalJSON_EmptyRecord_AllFieldsWritten() {
+	record := NewDecisionRecord("uid", "CREATE", "default", "Pod", "")
+
+	recordJSON, err := json.Marshal(record)
+	suite.Nil(err)
+
+	fields := map[string]interface{}{}
+	suite.Nil(json.Unmarshal(recordJSON, &fields))
+	for _, field := range []string{"schemaVersion", "timestamp", "requestUID", "operation", "namespace", "kind", "name", "ownerReferences",
+		"serviceAccountName", "imagePullSecrets", "containers", "cacheLookups", "allowed", "responseReason", "responseCode", "error", "latencyInMS"} {
+		suite.Contains(fields, field)
+	}
+	suite.Equal(17, len(fields))
+	suite.Equal([]interface{}{}, fields["containers"])
+	suite.Equal([]interface{}{}, fields["cacheLookups"])
+	suite.Equal(DecisionRecordSchemaVersion, fields["schemaVersion"])
+}
+
+func (suite *DecisionLoggerTestSuite) Test_MarshalJSON_FullRecord_FieldsWritten() {
+	suite.record.SetContainers([]*contracts.ContainerVulnerabilityScanInfo{
+		{
+			Name:           "container",
+			Image:          &contracts.Image{Name: "image.com/app:1", Digest: "sha256:digest"},
+			ScanStatus:     contracts.Unscanned,
+			AdditionalData: map[string]string{contracts.UnscannedReasonAnnotationKey: "reason"},
+		},
+	})
+	suite.record.AddCacheLookup(Tag2DigestResolverCacheLayer, "image.com/app:1", true)
+	suite.record.Complete(true, "Patched", 200, 12, errors.New("error"))
+
+	recordJSON, err := json.Marshal(suite.record)
+	suite.Nil(err)
+
+	record := &decisionRecordJSON{}
+	suite.Nil(json.Unmarshal(recordJSON, record))
+	suite.Equal([]*OwnerReference{{Kind: "ReplicaSet", Name: "rs"}}, record.OwnerReferences)
+	suite.Equal([]*ContainerDecision{{Name: "container", Image: "image.com/app:1", Digest: "sha256:digest", ScanStatus: "unscanned", UnscannedReason: "reason"}}, record.Containers)
+	suite.Equal([]*CacheLookup{{Layer: Tag2DigestResolverCacheLayer, Key: "image.com/app:1", Hit: true}}, record.CacheLookups)
+	suite.True(record.Allowed)
+	suite.Equal("Patched", record.ResponseReason)
+	suite.Equal(int32(200), record.ResponseCode)
+	suite.Equal(12, record.LatencyInMS)
+	suite.Equal("error", record.Error)
+}
+
+// newLogger creates DecisionLogger that writes to the suite's sink
+func (suite *DecisionLoggerTestSuite) newLogger(configuration *DecisionLoggerConfiguration) *DecisionLogger {
+	return NewDecisionLogger(instrumentation.NewNoOpInstrumentationProvider(), suite.sink, configuration)
+}
+
+// waitForRecord returns the next record that was written to the sink - nil if no record was written until timeout
+func (suite *DecisionLoggerTestSuite) waitForRecord() *DecisionRecord {
+	select {
+	case record := <-suite.sink.records:
+		return record
+	case <-time.After(_recordWaitTimeout):
+		return nil
+	}
+}
+
+func TestDecisionLogger(t *testing.T) {
+	suite.Run(t, new(DecisionLoggerTestSuite))
+}
@@ -0,0 +1,213 @@
+// Package decisionlog contains the decision audit log of the admission requests - a record of what the webhook decided
+// for each request and why, that is written to a pluggable sink (e.g. JSON-lines file, HTTP endpoint of SIEM).
+package decisionlog
+
+import (
+	"encoding/json"
+	"github.com/Azure/AzureDefender-K8S-InClusterDefense/cmd/webhook/admisionrequest"
+	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/azdsecinfo/contracts"
+	"sync"
+	"time"
+)
+
+const (
+	// DecisionRecordSchemaVersion is the version of the schema of DecisionRecord. Should be changed on any change of the schema.
+	DecisionRecordSchemaVersion = "1.0"
+	// _redactedValue is the value that redacted values are replaced with
+	_redactedValue = "REDACTED"
+)
+
+// CacheLayer is enum of the caches that are looked up while handling admission request
+type CacheLayer string
+
+const (
+	// AzdSecInfoProviderCacheLayer is the cache of the containers vulnerability scan info of the pod spec
+	AzdSecInfoProviderCacheLayer CacheLayer = "AzdSecInfoProvider"
+	// Tag2DigestResolverCacheLayer is the cache of the digest (or known error) of the image
+	Tag2DigestResolverCacheLayer CacheLayer = "Tag2DigestResolver"
+	// ARGDataProviderCacheLayer is the cache of the scan results of the digest
+	ARGDataProviderCacheLayer CacheLayer = "ARGDataProvider"
+)
+
+// DecisionRecord is the record of the decision of the webhook for a single admission request.
+// All the fields are always written (slices are empty and not null) so every record has the same schema.
+// DecisionRecord is safe for concurrent use - it's populated by the handler and by the dependencies (e.g. cache lookups of the
+// per container goroutines) through the request's context (see ContextWithDecisionRecord).
+type DecisionRecord struct {
+	// SchemaVersion is the version of the schema of the record (DecisionRecordSchemaVersion)
+	SchemaVersion string `json:"schemaVersion"`
+	// Timestamp is the time that the request was received
+	Timestamp time.Time `json:"timestamp"`
+	// RequestUID is the UID of the admission request
+	RequestUID string `json:"requestUID"`
+	// Operation is the operation of the admission request (e.g. CREATE)
+	Operation string `json:"operation"`
+	// Namespace is the namespace of the workload resource
+	Namespace string `json:"namespace"`
+	// Kind is the kind of the workload resource (e.g. Pod, Deployment)
+	Kind string `json:"kind"`
+	// Name is the name of the workload resource
+	Name string `json:"name"`
+	// OwnerReferences are the owners of the workload resource
+	OwnerReferences []*OwnerReference `json:"ownerReferences"`
+	// ServiceAccountName is the service account of the workload resource's pod spec
+	ServiceAccountName string `json:"serviceAccountName"`
+	// ImagePullSecrets are the names of the image pull secrets of the workload resource's pod spec (redacted if configured)
+	ImagePullSecrets []string `json:"imagePullSecrets"`
+	// Containers are the decisions of each container (init containers included)
+	Containers []*ContainerDecision `json:"containers"`
+	// CacheLookups are the results of the cache lookups of all cache layers
+	CacheLookups []*CacheLookup `json:"cacheLookups"`
+	// Allowed is whether the request was allowed
+	Allowed bool `json:"allowed"`
+	// ResponseReason is the reason of the response (e.g. Patched, NotPatchedNotSupportedKind)
+	ResponseReason string `json:"responseReason"`
+	// ResponseCode is the code of the response's result
+	ResponseCode int32 `json:"responseCode"`
+	// Error is the error that was encountered while handling the request - empty if no error was encountered
+	Error string `json:"error"`
+	// LatencyInMS is the latency of the handling of the request in milliseconds
+	LatencyInMS int `json:"latencyInMS"`
+
+	// lock protects the record's fields
+	lock sync.Mutex
+}
+
+// OwnerReference is owner of the workload resource
+type OwnerReference struct {
+	// Kind is the kind of the owner
+	Kind string `json:"kind"`
+	// Name is the name of the owner
+	Name string `json:"name"`
+}
+
+// ContainerDecision is the decision of a single container
+type ContainerDecision struct {
+	// Name is the name of the container
+	Name string `json:"name"`
+	// Image is the image of the container as it appears in the pod spec
+	Image string `json:"image"`
+	// Digest is the resolved digest of the image - empty if it wasn't resolved
+	Digest string `json:"digest"`
+	// ScanStatus is the scan status of the digest
+	ScanStatus string `json:"scanStatus"`
+	// UnscannedReason is the reason that the digest is unscanned - empty if the scan status isn't unscanned
+	UnscannedReason string `json:"unscannedReason"`
+}
+
+// CacheLookup is the result of a single cache lookup
+type CacheLookup struct {
+	// Layer is the cache that was looked up
+	Layer CacheLayer `json:"layer"`
+	// Key is the looked up key (e.g. image, digest)
+	Key string `json:"key"`
+	// Hit is whether the key was found in the cache
+	Hit bool `json:"hit"`
+}
+
+// NewDecisionRecord Ctor for DecisionRecord
+func NewDecisionRecord(requestUID string, operation string, namespace string, kind string, name string) *DecisionRecord {
+	return &DecisionRecord{
+		SchemaVersion:    DecisionRecordSchemaVersion,
+		Timestamp:        time.Now().UTC(),
+		RequestUID:       requestUID,
+		Operation:        operation,
+		Namespace:        namespace,
+		Kind:             kind,
+		Name:             name,
+		OwnerReferences:  []*OwnerReference{},
+		ImagePullSecrets: []string{},
+		Containers:       []*ContainerDecision{},
+		CacheLookups:     []*CacheLookup{},
+	}
+}
+
+// SetWorkloadResource sets the name, owners, service account and image pull secrets of the workload resource
+func (record *DecisionRecord) SetWorkloadResource(workloadResource *admisionrequest.WorkloadResource) {
+	if workloadResource == nil || workloadResource.Metadata == nil || workloadResource.Spec == nil {
+		return
+	}
+	record.lock.Lock()
+	defer record.lock.Unlock()
+
+	if workloadResource.Metadata.Name != "" {
+		record.Name = workloadResource.Metadata.Name
+	}
+	ownerReferences := make([]*OwnerReference, 0, len(workloadResource.Metadata.OwnerReferences))
+	for _, ownerReference := range workloadResource.Metadata.OwnerReferences {
+		ownerReferences = append(ownerReferences, &OwnerReference{Kind: ownerReference.Kind, Name: ownerReference.Name})
+	}
+	record.OwnerReferences = ownerReferences
+
+	record.ServiceAccountName = workloadResource.Spec.ServiceAccountName
+	imagePullSecrets := make([]string, 0, len(workloadResource.Spec.ImagePullSecrets))
+	for _, imagePullSecret := range workloadResource.Spec.ImagePullSecrets {
+		imagePullSecrets = append(imagePullSecrets, imagePullSecret.Name)
+	}
+	record.ImagePullSecrets = imagePullSecrets
+}
+
+// SetContainers sets the decisions of the containers from their vulnerability scan info
+func (record *DecisionRecord) SetContainers(containersVulnerabilityScanInfo []*contracts.ContainerVulnerabilityScanInfo) {
+	containers := make([]*ContainerDecision, 0, len(containersVulnerabilityScanInfo))
+	for _, info := range containersVulnerabilityScanInfo {
+		if info == nil {
+			continue
+		}
+		container := &ContainerDecision{
+			Name:            info.Name,
+			ScanStatus:      string(info.ScanStatus),
+			UnscannedReason: info.AdditionalData[contracts.UnscannedReasonAnnotationKey],
+		}
+		if info.Image != nil {
+			container.Image = info.Image.Name
+			container.Digest = info.Image.Digest
+		}
+		containers = append(containers, container)
+	}
+
+	record.lock.Lock()
+	defer record.lock.Unlock()
+	record.Containers = containers
+}
+
+// AddCacheLookup adds the result of a cache lookup
+func (record *DecisionRecord) AddCacheLookup(layer CacheLayer, key string, hit bool) {
+	record.lock.Lock()
+	defer record.lock.Unlock()
+	record.CacheLookups = append(record.CacheLookups, &CacheLookup{Layer: layer, Key: key, Hit: hit})
+}
+
+// Complete sets the response of the request, its latency and the error that was encountered (nil if no error was encountered)
+func (record *DecisionRecord) Complete(allowed bool, responseReason string, responseCode int32, latencyInMS int, err error) {
+	record.lock.Lock()
+	defer record.lock.Unlock()
+
+	record.Allowed = allowed
+	record.ResponseReason = responseReason
+	record.ResponseCode = responseCode
+	record.LatencyInMS = latencyInMS
+	if err != nil {
+		record.Error = err.Error()
+	}
+}
+
+// RedactImagePullSecrets replaces the names of the image pull secrets with a redacted value (the number of secrets is kept)
+func (record *DecisionRecord) RedactImagePullSecrets() {
+	record.lock.Lock()
+	defer record.lock.Unlock()
+
+	for i := range record.ImagePullSecrets {
+		record.ImagePullSecrets[i] = _redactedValue
+	}
+}
+
+// decisionRecordJSON is DecisionRecord without its methods - used to marshal the record without recursion
+type decisionRecordJSON DecisionRecord
+
+// MarshalJSON marshals the record while its fields are locked (cache lookups may be added after timeout)
+func (record *DecisionRecord) MarshalJSON() ([]byte, error) {
+	record.lock.Lock()
+	defer record.lock.Unlock()
+	return json.Marshal((*decisionRecordJSON)(record))
+}
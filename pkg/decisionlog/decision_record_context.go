@@ -0,0 +1,35 @@
+package decisionlog
+
+import (
+	"context"
+	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/azdsecinfo/contracts"
+)
+
+// decisionRecordContextKey is the key of the decision record in the context
+type decisionRecordContextKey struct{}
+
+// ContextWithDecisionRecord returns copy of ctx with the decision record of the request, so the dependencies that handle
+// the request can add their data to the record (see AddCacheLookup)
+func ContextWithDecisionRecord(ctx context.Context, record *DecisionRecord) context.Context {
+	return context.WithValue(ctx, decisionRecordContextKey{}, record)
+}
+
+// decisionRecordFromContext returns the decision record of ctx - nil if ctx has no decision record
+func decisionRecordFromContext(ctx context.Context) *DecisionRecord {
+	record, _ := ctx.Value(decisionRecordContextKey{}).(*DecisionRecord)
+	return record
+}
+
+// AddCacheLookup adds the result of a cache lookup to the decision record of ctx. Does nothing if ctx has no decision record.
+func AddCacheLookup(ctx context.Context, layer CacheLayer, key string, hit bool) {
+	if record := decisionRecordFromContext(ctx); record != nil {
+		record.AddCacheLookup(layer, key, hit)
+	}
+}
+
+// SetContainers sets the decisions of the containers in the decision record of ctx. Does nothing if ctx has no decision record.
+func SetContainers(ctx context.Context, containersVulnerabilityScanInfo []*contracts.ContainerVulnerabilityScanInfo) {
+	if record := decisionRecordFromContext(ctx); record != nil {
+		record.SetContainers(containersVulnerabilityScanInfo)
+	}
+}
@@ -0,0 +1,82 @@
+package decisionlog
+
+import (
+	"encoding/json"
+	"github.com/pkg/errors"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+const (
+	// _defaultDecisionLogFilePath is the file that the decision records are written to in case that no file is configured.
+	// /var/log/azuredefender is mounted from the host, so the records can be collected by the node's log agent.
+	_defaultDecisionLogFilePath = "/var/log/azuredefender/decisions.jsonl"
+)
+
+// FileDecisionLogSink implements IDecisionLogSink interface
+var _ IDecisionLogSink = (*FileDecisionLogSink)(nil)
+
+// FileDecisionLogSink is IDecisionLogSink that appends each decision record as a JSON line to a file
+type FileDecisionLogSink struct {
+	// file is the opened file that the records are appended to
+	file *os.File
+	// lock makes sure that each record is written as a whole line
+	lock sync.Mutex
+}
+
+// FileDecisionLogSinkConfiguration is the configuration of FileDecisionLogSink
+type FileDecisionLogSinkConfiguration struct {
+	// FilePath is the path of the JSON-lines file - the file and its directory are created if they don't exist
+	FilePath string
+}
+
+// NewFileDecisionLogSink Ctor for FileDecisionLogSink - opens (or creates) the file of the configuration for appending
+func NewFileDecisionLogSink(configuration *FileDecisionLogSinkConfiguration) (*FileDecisionLogSink, error) {
+	filePath := _defaultDecisionLogFilePath
+	if configuration != nil && configuration.FilePath != "" {
+		filePath = configuration.FilePath
+	}
+
+	if err := os.MkdirAll(filepath.Dir(filePath), 0755); err != nil {
+		return nil, errors.Wrapf(err, "decisionlog.NewFileDecisionLogSink failed to create the directory of <%s>", filePath)
+	}
+	file, err := os.OpenFile(filePath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, errors.Wrapf(err, "decisionlog.NewFileDecisionLogSink failed to open <%s>", filePath)
+	}
+
+	return &FileDecisionLogSink{
+		file: file,
+	}, nil
+}
+
+// Write appends the decision record as a JSON line to the file
+func (sink *FileDecisionLogSink) Write(record *DecisionRecord) error {
+	recordJSON, err := json.Marshal(record)
+	if err != nil {
+		return errors.Wrap(err, "FileDecisionLogSink.Write failed to marshal the record")
+	}
+	recordJSON = append(recordJSON, '\n')
+
+	sink.lock.Lock()
+	defer sink.lock.Unlock()
+	if _, err = sink.file.Write(recordJSON); err != nil {
+		return errors.Wrapf(err, "FileDecisionLogSink.Write failed to write the record to <%s>", sink.file.Name())
+	}
+	return nil
+}
+
+// Close syncs the written records to the disk and closes the file
+func (sink *FileDecisionLogSink) Close() error {
+	sink.lock.Lock()
+	defer sink.lock.Unlock()
+	if err := sink.file.Sync(); err != nil {
+		_ = sink.file.Close()
+		return errors.Wrapf(err, "FileDecisionLogSink.Close failed to sync <%s>", sink.file.Name())
+	}
+	if err := sink.file.Close(); err != nil {
+		return errors.Wrapf(err, "FileDecisionLogSink.Close failed to close <%s>", sink.file.Name())
+	}
+	return nil
+}
@@ -0,0 +1,84 @@
+package decisionlog
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/httpclient"
+	"github.com/pkg/errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"time"
+)
+
+// HTTPDecisionLogSink implements IDecisionLogSink interface
+var _ IDecisionLogSink = (*HTTPDecisionLogSink)(nil)
+
+// HTTPDecisionLogSink is IDecisionLogSink that posts each decision record as JSON to an HTTP endpoint (e.g. SIEM collector)
+type HTTPDecisionLogSink struct {
+	// configuration is the configuration of the sink
+	configuration *HTTPDecisionLogSinkConfiguration
+	// httpClient is the client that posts the records
+	httpClient httpclient.IHttpClient
+}
+
+// HTTPDecisionLogSinkConfiguration is the configuration of HTTPDecisionLogSink
+type HTTPDecisionLogSinkConfiguration struct {
+	// Endpoint is the URL that the records are posted to
+	Endpoint string
+	// TimeoutInMS is the timeout of each post in milliseconds
+	TimeoutInMS int
+	// Headers are additional headers of each post (e.g. authorization header of the collector)
+	Headers map[string]string
+}
+
+// NewHTTPDecisionLogSink Ctor for HTTPDecisionLogSink
+func NewHTTPDecisionLogSink(configuration *HTTPDecisionLogSinkConfiguration, httpClient httpclient.IHttpClient) *HTTPDecisionLogSink {
+	return &HTTPDecisionLogSink{
+		configuration: configuration,
+		httpClient:    httpClient,
+	}
+}
+
+// Write posts the decision record as JSON to the endpoint - returns error if the response's status isn't 2xx
+func (sink *HTTPDecisionLogSink) Write(record *DecisionRecord) error {
+	recordJSON, err := json.Marshal(record)
+	if err != nil {
+		return errors.Wrap(err, "HTTPDecisionLogSink.Write failed to marshal the record")
+	}
+
+	request, err := http.NewRequest(http.MethodPost, sink.configuration.Endpoint, bytes.NewReader(recordJSON))
+	if err != nil {
+		return errors.Wrapf(err, "HTTPDecisionLogSink.Write failed to create request to <%s>", sink.configuration.Endpoint)
+	}
+	request.Header.Set("Content-Type", "application/json")
+	for key, value := range sink.configuration.Headers {
+		request.Header.Set(key, value)
+	}
+
+	response, err := sink.httpClient.Do(request)
+	if err != nil {
+		return errors.Wrapf(err, "HTTPDecisionLogSink.Write failed to post the record to <%s>", sink.configuration.Endpoint)
+	}
+	defer response.Body.Close()
+	// Drain the body so the connection can be reused
+	_, _ = io.Copy(ioutil.Discard, response.Body)
+
+	if response.StatusCode < http.StatusOK || response.StatusCode >= http.StatusMultipleChoices {
+		return fmt.Errorf("HTTPDecisionLogSink.Write post of the record to <%s> returned unexpected status code <%d>", sink.configuration.Endpoint, response.StatusCode)
+	}
+	return nil
+}
+
+// Close does nothing - each record is posted synchronously by Write
+func (sink *HTTPDecisionLogSink) Close() error {
+	return nil
+}
+
+// newHTTPClient creates the http client of HTTPDecisionLogSink with the timeout of the configuration
+func newHTTPClient(configuration *HTTPDecisionLogSinkConfiguration) *http.Client {
+	return &http.Client{
+		Timeout: time.Duration(configuration.TimeoutInMS) * time.Millisecond,
+	}
+}
@@ -0,0 +1,43 @@
+package metric
+
+import (
+	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/instrumentation/metric"
+)
+
+// DecisionLogDroppedReason is enum of the reasons that a decision record is dropped
+type DecisionLogDroppedReason string
+
+const (
+	// QueueFullDecisionLogDroppedReason is when the queue of the records that weren't written yet is full
+	QueueFullDecisionLogDroppedReason DecisionLogDroppedReason = "QueueFull"
+	// SinkErrorDecisionLogDroppedReason is when the sink failed to write the record
+	SinkErrorDecisionLogDroppedReason DecisionLogDroppedReason = "SinkError"
+	// ClosedDecisionLogDroppedReason is when the record is logged after the logger was closed
+	ClosedDecisionLogDroppedReason DecisionLogDroppedReason = "Closed"
+)
+
+// DecisionLogDroppedMetric implements metric.IMetric interface
+var _ metric.IMetric = (*DecisionLogDroppedMetric)(nil)
+
+// DecisionLogDroppedMetric is metric of DecisionLogger to report each decision record that wasn't written to the sink
+type DecisionLogDroppedMetric struct {
+	// reason is the reason that the record was dropped
+	reason DecisionLogDroppedReason
+}
+
+// NewDecisionLogDroppedMetric Ctor for DecisionLogDroppedMetric
+func NewDecisionLogDroppedMetric(reason DecisionLogDroppedReason) *DecisionLogDroppedMetric {
+	return &DecisionLogDroppedMetric{
+		reason: reason,
+	}
+}
+
+func (m *DecisionLogDroppedMetric) MetricName() string {
+	return "DecisionLogDropped"
+}
+
+func (m *DecisionLogDroppedMetric) MetricDimension() []metric.Dimension {
+	return []metric.Dimension{
+		{Key: "Reason", Value: string(m.reason)},
+	}
+}
@@ -0,0 +1,25 @@
+package decisionlog
+
+import "context"
+
+// NoOpDecisionLogger implements IDecisionLogger interface
+var _ IDecisionLogger = (*NoOpDecisionLogger)(nil)
+
+// NoOpDecisionLogger is implementation that does nothing of IDecisionLogger.
+// It is used when the decision log is disabled.
+type NoOpDecisionLogger struct {
+}
+
+// NewNoOpDecisionLogger Ctor for NoOpDecisionLogger
+func NewNoOpDecisionLogger() *NoOpDecisionLogger {
+	return &NoOpDecisionLogger{}
+}
+
+// Log does nothing
+func (logger *NoOpDecisionLogger) Log(record *DecisionRecord) {
+}
+
+// Close does nothing
+func (logger *NoOpDecisionLogger) Close(ctx context.Context) error {
+	return nil
+}
@@ -0,0 +1,47 @@
+package metric
+
+import (
+	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/instrumentation/metric"
+)
+
+// WorkloadEventStatus is enum of the statuses of the events that should be emitted on the workloads
+type WorkloadEventStatus string
+
+const (
+	// EmittedWorkloadEventStatus is when the event was emitted
+	EmittedWorkloadEventStatus WorkloadEventStatus = "Emitted"
+	// DeduplicatedWorkloadEventStatus is when the same event was emitted on the workload recently
+	DeduplicatedWorkloadEventStatus WorkloadEventStatus = "Deduplicated"
+	// RateLimitedWorkloadEventStatus is when another event was emitted on the workload recently
+	RateLimitedWorkloadEventStatus WorkloadEventStatus = "RateLimited"
+)
+
+// WorkloadEventMetric implements metric.IMetric interface
+var _ metric.IMetric = (*WorkloadEventMetric)(nil)
+
+// WorkloadEventMetric is metric of WorkloadEventEmitter to report each event that should be emitted on a workload and its status
+type WorkloadEventMetric struct {
+	// reason is the reason of the event
+	reason string
+	// status is whether the event was emitted or suppressed
+	status WorkloadEventStatus
+}
+
+// NewWorkloadEventMetric Ctor for WorkloadEventMetric
+func NewWorkloadEventMetric(reason string, status WorkloadEventStatus) *WorkloadEventMetric {
+	return &WorkloadEventMetric{
+		reason: reason,
+		status: status,
+	}
+}
+
+func (m *WorkloadEventMetric) MetricName() string {
+	return "WorkloadEvent"
+}
+
+func (m *WorkloadEventMetric) MetricDimension() []metric.Dimension {
+	return []metric.Dimension{
+		{Key: "Reason", Value: m.reason},
+		{Key: "Status", Value: string(m.status)},
+	}
+}
@@ -0,0 +1,25 @@
+package events
+
+import (
+	"context"
+	"github.com/Azure/AzureDefender-K8S-InClusterDefense/cmd/webhook/admisionrequest"
+	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/azdsecinfo/contracts"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// NoOpWorkloadEventEmitter implements IWorkloadEventEmitter interface
+var _ IWorkloadEventEmitter = (*NoOpWorkloadEventEmitter)(nil)
+
+// NoOpWorkloadEventEmitter is implementation that does nothing of IWorkloadEventEmitter.
+// It is used when the emission of events is disabled.
+type NoOpWorkloadEventEmitter struct {
+}
+
+// NewNoOpWorkloadEventEmitter Ctor for NoOpWorkloadEventEmitter
+func NewNoOpWorkloadEventEmitter() *NoOpWorkloadEventEmitter {
+	return &NoOpWorkloadEventEmitter{}
+}
+
+// EmitContainersVulnerabilityScanEvent does nothing
+func (emitter *NoOpWorkloadEventEmitter) EmitContainersVulnerabilityScanEvent(ctx context.Context, namespace string, kind metav1.GroupVersionKind, workloadResource *admisionrequest.WorkloadResource, containersVulnerabilityScanInfo []*contracts.ContainerVulnerabilityScanInfo) {
+}
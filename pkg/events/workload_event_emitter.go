@@ -0,0 +1,215 @@
+// Package events contains the emission of Kubernetes events on the workloads that have vulnerable or unscanned images,
+// so the developers see the problem directly (e.g. by kubectl describe) and not only in the annotation.
+package events
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"github.com/Azure/AzureDefender-K8S-InClusterDefense/cmd/webhook/admisionrequest"
+	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/azdsecinfo/contracts"
+	eventsmetric "github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/events/metric"
+	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/cache"
+	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/instrumentation"
+	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/instrumentation/metric"
+	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/instrumentation/metric/util"
+	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/instrumentation/trace"
+	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/utils"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"strings"
+	"sync"
+)
+
+const (
+	// EventRecorderName is the name of the component that the events are reported by
+	EventRecorderName = "azure-defender-proxy"
+	// _rateLimitCacheKeyPrefix is the prefix of the cache key of the last event of the workload
+	_rateLimitCacheKeyPrefix = "WorkloadEventEmitter:RateLimit:"
+	// _deduplicationCacheKeyPrefix is the prefix of the cache key of an event message of the workload
+	_deduplicationCacheKeyPrefix = "WorkloadEventEmitter:Deduplication:"
+	// _emittedCacheValue is the value of the cache keys of the emitted events
+	_emittedCacheValue = "emitted"
+)
+
+// IWorkloadEventEmitter emits events on the workloads that have vulnerable or unscanned containers' images
+type IWorkloadEventEmitter interface {
+	// EmitContainersVulnerabilityScanEvent emits a Warning event with summary of the unhealthy and unscanned containers of the workload.
+	// namespace and kind are of the admission request of the workload. Nothing is emitted if all the containers are healthy.
+	EmitContainersVulnerabilityScanEvent(ctx context.Context, namespace string, kind metav1.GroupVersionKind, workloadResource *admisionrequest.WorkloadResource, containersVulnerabilityScanInfo []*contracts.ContainerVulnerabilityScanInfo)
+}
+
+// WorkloadEventEmitter implements IWorkloadEventEmitter interface
+var _ IWorkloadEventEmitter = (*WorkloadEventEmitter)(nil)
+
+// WorkloadEventEmitter is IWorkloadEventEmitter that emits the events using the event recorder of the manager.
+// The events are de-duplicated (the same message isn't emitted on the workload during the deduplication window) and
+// rate-limited (at most one event is emitted on the workload during the rate limit interval).
+type WorkloadEventEmitter struct {
+	//tracerProvider is tracer provider of WorkloadEventEmitter
+	tracerProvider trace.ITracerProvider
+	//metricSubmitter is metric submitter of WorkloadEventEmitter
+	metricSubmitter metric.IMetricSubmitter
+	// eventRecorder records the events
+	eventRecorder record.EventRecorder
+	// cacheClient is the cache of the recently emitted events of the workloads (in-mem cache)
+	cacheClient cache.ICacheClient
+	// configuration is the configuration of WorkloadEventEmitter
+	configuration *WorkloadEventEmitterConfiguration
+	// lock makes sure that concurrent requests of the same workload (e.g. pods of a replica set) don't emit the same event
+	lock sync.Mutex
+}
+
+// WorkloadEventEmitterConfiguration is configuration data for WorkloadEventEmitter
+type WorkloadEventEmitterConfiguration struct {
+	// Enabled is whether events should be emitted on the workloads
+	Enabled bool
+	// RateLimitIntervalInSeconds is the minimal interval between two events on the same workload
+	RateLimitIntervalInSeconds int
+	// DeduplicationWindowInSeconds is the interval that the same event isn't emitted again on the same workload
+	DeduplicationWindowInSeconds int
+}
+
+// NewWorkloadEventEmitter Ctor for WorkloadEventEmitter
+func NewWorkloadEventEmitter(instrumentationProvider instrumentation.IInstrumentationProvider, eventRecorder record.EventRecorder, cacheClient cache.ICacheClient, configuration *WorkloadEventEmitterConfiguration) *WorkloadEventEmitter {
+	return &WorkloadEventEmitter{
+		tracerProvider:  instrumentationProvider.GetTracerProvider("WorkloadEventEmitter"),
+		metricSubmitter: instrumentationProvider.GetMetricSubmitter(),
+		eventRecorder:   eventRecorder,
+		cacheClient:     cacheClient,
+		configuration:   configuration,
+	}
+}
+
+// EmitContainersVulnerabilityScanEvent emits a Warning event with summary of the unhealthy and unscanned containers of the workload.
+// The event is emitted on the workload if it already exists (has UID), otherwise on its owner (e.g. the replica set of a new pod).
+// If neither has UID (new workload without owner), the event is emitted on the workload's name.
+func (emitter *WorkloadEventEmitter) EmitContainersVulnerabilityScanEvent(ctx context.Context, namespace string, kind metav1.GroupVersionKind, workloadResource *admisionrequest.WorkloadResource, containersVulnerabilityScanInfo []*contracts.ContainerVulnerabilityScanInfo) {
+	tracer := emitter.tracerProvider.GetTracer("EmitContainersVulnerabilityScanEvent")
+	_, span := emitter.tracerProvider.StartSpan(ctx, "EmitContainersVulnerabilityScanEvent")
+	defer span.End()
+
+	if workloadResource == nil || workloadResource.Metadata == nil {
+		err := errors.Wrap(utils.NilArgumentError, "WorkloadEventEmitter.EmitContainersVulnerabilityScanEvent")
+		span.RecordError(err)
+		tracer.Error(err, "")
+		emitter.metricSubmitter.SendMetric(1, util.NewErrorEncounteredMetric(err, "WorkloadEventEmitter.EmitContainersVulnerabilityScanEvent"))
+		return
+	}
+
+	summary := summarizeContainersVulnerabilityScanInfo(containersVulnerabilityScanInfo)
+	if summary == nil {
+		tracer.Info("All the containers are healthy - no event is emitted")
+		return
+	}
+
+	involvedObject := getInvolvedObject(namespace, kind, workloadResource.Metadata)
+	if involvedObject == nil {
+		tracer.Info("Workload has no name and no owner with UID - no event is emitted")
+		return
+	}
+	span.SetAttributes("involvedObjectKind", involvedObject.Kind, "involvedObjectName", involvedObject.Name, "reason", string(summary.reason))
+
+	status := emitter.shouldEmit(ctx, involvedObject, summary)
+	emitter.metricSubmitter.SendMetric(1, eventsmetric.NewWorkloadEventMetric(string(summary.reason), status))
+	if status != eventsmetric.EmittedWorkloadEventStatus {
+		tracer.Info("Event is suppressed", "status", status, "involvedObject", involvedObject)
+		return
+	}
+
+	emitter.eventRecorder.Event(involvedObject, corev1.EventTypeWarning, string(summary.reason), summary.message)
+	tracer.Info("Event is emitted", "involvedObject", involvedObject, "reason", summary.reason)
+}
+
+// shouldEmit checks whether the event was recently emitted (or another event was emitted) on the involved object.
+// If it should be emitted - it's stored as emitted in the cache.
+func (emitter *WorkloadEventEmitter) shouldEmit(ctx context.Context, involvedObject *corev1.ObjectReference, summary *workloadEventSummary) eventsmetric.WorkloadEventStatus {
+	objectKey := getObjectKey(involvedObject)
+	messageHash := sha256.Sum256([]byte(string(summary.reason) + summary.message))
+	deduplicationKey := _deduplicationCacheKeyPrefix + objectKey + ":" + hex.EncodeToString(messageHash[:])
+	rateLimitKey := _rateLimitCacheKeyPrefix + objectKey
+
+	emitter.lock.Lock()
+	defer emitter.lock.Unlock()
+
+	// Failures of the cache are ignored - it's preferred to emit duplicated events than to miss events
+	if emitter.existInCache(ctx, deduplicationKey) {
+		return eventsmetric.DeduplicatedWorkloadEventStatus
+	}
+	if emitter.existInCache(ctx, rateLimitKey) {
+		return eventsmetric.RateLimitedWorkloadEventStatus
+	}
+	emitter.setInCache(ctx, deduplicationKey, emitter.configuration.DeduplicationWindowInSeconds)
+	emitter.setInCache(ctx, rateLimitKey, emitter.configuration.RateLimitIntervalInSeconds)
+	return eventsmetric.EmittedWorkloadEventStatus
+}
+
+// existInCache returns whether the key exists in the cache
+func (emitter *WorkloadEventEmitter) existInCache(ctx context.Context, key string) bool {
+	tracer := emitter.tracerProvider.GetTracer("existInCache")
+	_, err := emitter.cacheClient.Get(ctx, key)
+	if err != nil && !cache.IsMissingKeyCacheError(err) {
+		err = errors.Wrap(err, "WorkloadEventEmitter.existInCache failed to get key from cache")
+		tracer.Error(err, "")
+		emitter.metricSubmitter.SendMetric(1, util.NewErrorEncounteredMetric(err, "WorkloadEventEmitter.existInCache"))
+	}
+	return err == nil
+}
+
+// setInCache sets the key in the cache for expirationInSeconds. Does nothing if expirationInSeconds isn't positive.
+func (emitter *WorkloadEventEmitter) setInCache(ctx context.Context, key string, expirationInSeconds int) {
+	tracer := emitter.tracerProvider.GetTracer("setInCache")
+	if expirationInSeconds <= 0 {
+		return
+	}
+	if err := emitter.cacheClient.Set(ctx, key, _emittedCacheValue, utils.GetSeconds(expirationInSeconds)); err != nil {
+		err = errors.Wrap(err, "WorkloadEventEmitter.setInCache failed to set key in cache")
+		tracer.Error(err, "")
+		emitter.metricSubmitter.SendMetric(1, util.NewErrorEncounteredMetric(err, "WorkloadEventEmitter.setInCache"))
+	}
+}
+
+// getInvolvedObject returns the object that the event should be emitted on - the workload if it has UID, otherwise the first
+// owner with UID, otherwise the workload by its name. Returns nil if the workload has no name and no owner with UID.
+// The UID is needed to show the event in kubectl describe, and it's missing on CREATE requests (set after the admission).
+func getInvolvedObject(namespace string, kind metav1.GroupVersionKind, metadata *admisionrequest.ObjectMetadata) *corev1.ObjectReference {
+	if metadata.Namespace != "" {
+		namespace = metadata.Namespace
+	}
+	workloadReference := &corev1.ObjectReference{
+		APIVersion: schema.GroupVersion{Group: kind.Group, Version: kind.Version}.String(),
+		Kind:       kind.Kind,
+		Namespace:  namespace,
+		Name:       metadata.Name,
+		UID:        types.UID(metadata.UID),
+	}
+	if metadata.UID != "" && metadata.Name != "" {
+		return workloadReference
+	}
+
+	for _, owner := range metadata.OwnerReferences {
+		if owner != nil && owner.UID != "" && owner.Name != "" {
+			return &corev1.ObjectReference{
+				APIVersion: owner.APIVersion,
+				Kind:       owner.Kind,
+				Namespace:  namespace,
+				Name:       owner.Name,
+				UID:        types.UID(owner.UID),
+			}
+		}
+	}
+
+	if metadata.Name != "" {
+		return workloadReference
+	}
+	return nil
+}
+
+// getObjectKey returns a unique key of the object
+func getObjectKey(object *corev1.ObjectReference) string {
+	return strings.Join([]string{object.Namespace, object.Kind, object.Name, string(object.UID)}, "/")
+}
@@ -0,0 +1,205 @@
+package events
+
+import (
+	"context"
+	"github.com/Azure/AzureDefender-K8S-InClusterDefense/cmd/webhook/admisionrequest"
+	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/azdsecinfo/contracts"
+	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/cache"
+	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/cache/wrappers"
+	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/instrumentation"
+	"github.com/stretchr/testify/suite"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	"strings"
+	"testing"
+)
+
+var (
+	_podKind        = metav1.GroupVersionKind{Group: "", Version: "v1", Kind: "Pod"}
+	_deploymentKind = metav1.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"}
+)
+
+type WorkloadEventEmitterTestSuite struct {
+	suite.Suite
+	recorder      *record.FakeRecorder
+	emitter       *WorkloadEventEmitter
+	unhealthyInfo *contracts.ContainerVulnerabilityScanInfo
+	unscannedInfo *contracts.ContainerVulnerabilityScanInfo
+	healthyInfo   *contracts.ContainerVulnerabilityScanInfo
+}
+
+// This will run before each test in the suite
+func (suite *WorkloadEventEmitterTestSuite) SetupTest() {
+	suite.recorder = record.NewFakeRecorder(10)
+	cacheClient := cache.NewFreeCacheInMemCacheClient(instrumentation.NewNoOpInstrumentationProvider(), wrappers.NewFreeCacheInMem(&wrappers.FreeCacheInMemWrapperCacheConfiguration{CacheSize: 10000000}))
+	suite.emitter = NewWorkloadEventEmitter(instrumentation.NewNoOpInstrumentationProvider(), suite.recorder, cacheClient, &WorkloadEventEmitterConfiguration{
+		Enabled:                      true,
+		RateLimitIntervalInSeconds:   60,
+		DeduplicationWindowInSeconds: 3600,
+	})
+	suite.unhealthyInfo = &contracts.ContainerVulnerabilityScanInfo{
+		Name:       "app",
+		Image:      &contracts.Image{Name: "image.com/app:1"},
+		ScanStatus: contracts.UnhealthyScan,
+		ScanFindings: []*contracts.ScanFinding{
+			{Id: "1", Severity: "Low"},
+			{Id: "2", Severity: "High"},
+			{Id: "3", Severity: "High"},
+			{Id: "4", Severity: "Medium"},
+		},
+	}
+	suite.unscannedInfo = &contracts.ContainerVulnerabilityScanInfo{
+		Name:           "sidecar",
+		Image:          &contracts.Image{Name: "docker.io/sidecar:2"},
+		ScanStatus:     contracts.Unscanned,
+		AdditionalData: map[string]string{contracts.UnscannedReasonAnnotationKey: string(contracts.ImageIsNotInACRRegistryUnscannedReason)},
+	}
+	suite.healthyInfo = &contracts.ContainerVulnerabilityScanInfo{
+		Name:       "init",
+		Image:      &contracts.Image{Name: "image.com/init:1"},
+		ScanStatus: contracts.HealthyScan,
+	}
+}
+
+func (suite *WorkloadEventEmitterTestSuite) Test_Emit_UnhealthyAndUnscanned_VulnerableImagesEventWithSummary() {
+	workload := createWorkloadResource("podTest", "podUID", nil)
+
+	suite.emitter.EmitContainersVulnerabilityScanEvent(context.Background(), "default", _podKind, workload, []*contracts.ContainerVulnerabilityScanInfo{suite.unhealthyInfo, suite.unscannedInfo, suite.healthyInfo})
+
+	suite.Equal([]string{"Warning VulnerableImages Azure Defender: 1 of 3 containers have vulnerable images: app (image.com/app:1) [High: 2, Medium: 1, Low: 1]. " +
+		"1 of 3 containers have unscanned images: sidecar (docker.io/sidecar:2) [reason: ImageIsNotInACR]."}, suite.emittedEvents())
+}
+
+func (suite *WorkloadEventEmitterTestSuite) Test_Emit_OnlyUnscanned_UnscannedImagesEvent() {
+	workload := createWorkloadResource("podTest", "podUID", nil)
+
+	suite.emitter.EmitContainersVulnerabilityScanEvent(context.Background(), "default", _podKind, workload, []*contracts.ContainerVulnerabilityScanInfo{suite.unscannedInfo})
+
+	events := suite.emittedEvents()
+	suite.Equal(1, len(events))
+	suite.True(strings.HasPrefix(events[0], "Warning UnscannedImages "))
+}
+
+func (suite *WorkloadEventEmitterTestSuite) Test_Emit_AllHealthy_NoEvent() {
+	workload := createWorkloadResource("podTest", "podUID", nil)
+
+	suite.emitter.EmitContainersVulnerabilityScanEvent(context.Background(), "default", _podKind, workload, []*contracts.ContainerVulnerabilityScanInfo{suite.healthyInfo})
+
+	suite.Empty(suite.emittedEvents())
+}
+
+func (suite *WorkloadEventEmitterTestSuite) Test_Emit_SameEventTwice_Deduplicated() {
+	workload := createWorkloadResource("podTest", "podUID", nil)
+	infos := []*contracts.ContainerVulnerabilityScanInfo{suite.unhealthyInfo}
+
+	suite.emitter.EmitContainersVulnerabilityScanEvent(context.Background(), "default", _podKind, workload, infos)
+	suite.emitter.EmitContainersVulnerabilityScanEvent(context.Background(), "default", _podKind, workload, infos)
+
+	suite.Equal(1, len(suite.emittedEvents()))
+}
+
+func (suite *WorkloadEventEmitterTestSuite) Test_Emit_DifferentEventWithinInterval_RateLimited() {
+	workload := createWorkloadResource("podTest", "podUID", nil)
+
+	suite.emitter.EmitContainersVulnerabilityScanEvent(context.Background(), "default", _podKind, workload, []*contracts.ContainerVulnerabilityScanInfo{suite.unhealthyInfo})
+	suite.emitter.EmitContainersVulnerabilityScanEvent(context.Background(), "default", _podKind, workload, []*contracts.ContainerVulnerabilityScanInfo{suite.unscannedInfo})
+
+	suite.Equal(1, len(suite.emittedEvents()))
+}
+
+func (suite *WorkloadEventEmitterTestSuite) Test_Emit_DifferentWorkloads_BothEmitted() {
+	infos := []*contracts.ContainerVulnerabilityScanInfo{suite.unhealthyInfo}
+
+	suite.emitter.EmitContainersVulnerabilityScanEvent(context.Background(), "default", _podKind, createWorkloadResource("podTest1", "podUID1", nil), infos)
+	suite.emitter.EmitContainersVulnerabilityScanEvent(context.Background(), "default", _podKind, createWorkloadResource("podTest2", "podUID2", nil), infos)
+
+	suite.Equal(2, len(suite.emittedEvents()))
+}
+
+func (suite *WorkloadEventEmitterTestSuite) Test_Emit_NoRateLimitAndDeduplication_AllEmitted() {
+	suite.emitter.configuration = &WorkloadEventEmitterConfiguration{Enabled: true}
+	workload := createWorkloadResource("podTest", "podUID", nil)
+	infos := []*contracts.ContainerVulnerabilityScanInfo{suite.unhealthyInfo}
+
+	suite.emitter.EmitContainersVulnerabilityScanEvent(context.Background(), "default", _podKind, workload, infos)
+	suite.emitter.EmitContainersVulnerabilityScanEvent(context.Background(), "default", _podKind, workload, infos)
+
+	suite.Equal(2, len(suite.emittedEvents()))
+}
+
+func (suite *WorkloadEventEmitterTestSuite) Test_Emit_NilWorkload_NoEvent() {
+	suite.emitter.EmitContainersVulnerabilityScanEvent(context.Background(), "default", _podKind, nil, []*contracts.ContainerVulnerabilityScanInfo{suite.unhealthyInfo})
+
+	suite.Empty(suite.emittedEvents())
+}
+
+func (suite *WorkloadEventEmitterTestSuite) Test_getInvolvedObject_WorkloadWithUID_Workload() {
+	workload := createWorkloadResource("deploymentTest", "deploymentUID", []*admisionrequest.OwnerReference{{APIVersion: "v1", Kind: "Owner", Name: "owner", UID: "ownerUID"}})
+
+	involvedObject := getInvolvedObject("default", _deploymentKind, workload.Metadata)
+
+	suite.Equal(&corev1.ObjectReference{APIVersion: "apps/v1", Kind: "Deployment", Namespace: "default", Name: "deploymentTest", UID: "deploymentUID"}, involvedObject)
+}
+
+func (suite *WorkloadEventEmitterTestSuite) Test_getInvolvedObject_NewPodWithOwner_Owner() {
+	workload := createWorkloadResource("", "", []*admisionrequest.OwnerReference{
+		{APIVersion: "v1", Kind: "Owner", Name: "ownerWithoutUID"},
+		{APIVersion: "apps/v1", Kind: "ReplicaSet", Name: "replicaSet", UID: "replicaSetUID"},
+	})
+
+	involvedObject := getInvolvedObject("default", _podKind, workload.Metadata)
+
+	suite.Equal(&corev1.ObjectReference{APIVersion: "apps/v1", Kind: "ReplicaSet", Namespace: "default", Name: "replicaSet", UID: "replicaSetUID"}, involvedObject)
+}
+
+func (suite *WorkloadEventEmitterTestSuite) Test_getInvolvedObject_NewWorkloadWithoutOwner_WorkloadByName() {
+	workload := createWorkloadResource("deploymentTest", "", nil)
+	workload.Metadata.Namespace = "namespaceTest"
+
+	involvedObject := getInvolvedObject("default", _deploymentKind, workload.Metadata)
+
+	suite.Equal(&corev1.ObjectReference{APIVersion: "apps/v1", Kind: "Deployment", Namespace: "namespaceTest", Name: "deploymentTest"}, involvedObject)
+}
+
+func (suite *WorkloadEventEmitterTestSuite) Test_getInvolvedObject_NoNameAndNoOwner_Nil() {
+	workload := createWorkloadResource("", "", nil)
+
+	suite.Nil(getInvolvedObject("default", _podKind, workload.Metadata))
+}
+
+func (suite *WorkloadEventEmitterTestSuite) Test_summarize_LongMessage_Truncated() {
+	infos := make([]*contracts.ContainerVulnerabilityScanInfo, 0, 100)
+	for i := 0; i < 100; i++ {
+		infos = append(infos, suite.unhealthyInfo)
+	}
+
+	summary := summarizeContainersVulnerabilityScanInfo(infos)
+
+	suite.Equal(_maxEventMessageLength, len(summary.message))
+	suite.True(strings.HasSuffix(summary.message, _truncatedMessageSuffix))
+}
+
+// emittedEvents returns the events that were emitted to the fake recorder
+func (suite *WorkloadEventEmitterTestSuite) emittedEvents() []string {
+	var events []string
+	for {
+		select {
+		case event := <-suite.recorder.Events:
+			events = append(events, event)
+		default:
+			return events
+		}
+	}
+}
+
+func createWorkloadResource(name string, uid string, ownerReferences []*admisionrequest.OwnerReference) *admisionrequest.WorkloadResource {
+	return &admisionrequest.WorkloadResource{
+		Metadata: &admisionrequest.ObjectMetadata{Name: name, UID: uid, OwnerReferences: ownerReferences},
+		Spec:     &admisionrequest.PodSpec{},
+	}
+}
+
+func TestWorkloadEventEmitter(t *testing.T) {
+	suite.Run(t, new(WorkloadEventEmitterTestSuite))
+}
@@ -0,0 +1,122 @@
+package events
+
+import (
+	"fmt"
+	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/azdsecinfo/contracts"
+	"sort"
+	"strings"
+)
+
+const (
+	// _maxEventMessageLength is the maximal length of the message of the event (the api server limits events messages to 1KB)
+	_maxEventMessageLength = 1024
+	// _truncatedMessageSuffix is the suffix of truncated messages
+	_truncatedMessageSuffix = "..."
+)
+
+// EventReason is enum of the reasons of the events that are emitted on the workloads
+type EventReason string
+
+const (
+	// VulnerableImagesEventReason is the reason of the event when at least one container's image has vulnerabilities
+	VulnerableImagesEventReason EventReason = "VulnerableImages"
+	// UnscannedImagesEventReason is the reason of the event when no container's image has vulnerabilities but at least one is unscanned
+	UnscannedImagesEventReason EventReason = "UnscannedImages"
+)
+
+var (
+	// _severitiesOrder is the order of the known severities in the summary - unknown severities are ordered after them alphabetically
+	_severitiesOrder = map[string]int{"High": 0, "Medium": 1, "Low": 2}
+)
+
+// workloadEventSummary is the summary of the containers vulnerability scan info that is emitted as event on the workload
+type workloadEventSummary struct {
+	// reason is the reason of the event
+	reason EventReason
+	// message is the short human-readable summary of the unhealthy and unscanned containers
+	message string
+}
+
+// summarizeContainersVulnerabilityScanInfo summarizes the unhealthy and unscanned containers.
+// Returns nil if all the containers are healthy (no event should be emitted).
+func summarizeContainersVulnerabilityScanInfo(containersVulnerabilityScanInfo []*contracts.ContainerVulnerabilityScanInfo) *workloadEventSummary {
+	var unhealthyContainers []string
+	var unscannedContainers []string
+	for _, info := range containersVulnerabilityScanInfo {
+		if info == nil {
+			continue
+		}
+		switch info.ScanStatus {
+		case contracts.UnhealthyScan:
+			unhealthyContainers = append(unhealthyContainers, fmt.Sprintf("%s [%s]", describeContainer(info), summarizeSeverities(info.ScanFindings)))
+		case contracts.Unscanned:
+			unscannedContainers = append(unscannedContainers, fmt.Sprintf("%s [reason: %s]", describeContainer(info), info.AdditionalData[contracts.UnscannedReasonAnnotationKey]))
+		}
+	}
+	if len(unhealthyContainers) == 0 && len(unscannedContainers) == 0 {
+		return nil
+	}
+
+	reason := UnscannedImagesEventReason
+	var parts []string
+	if len(unhealthyContainers) > 0 {
+		reason = VulnerableImagesEventReason
+		parts = append(parts, fmt.Sprintf("%d of %d containers have vulnerable images: %s.", len(unhealthyContainers), len(containersVulnerabilityScanInfo), strings.Join(unhealthyContainers, ", ")))
+	}
+	if len(unscannedContainers) > 0 {
+		parts = append(parts, fmt.Sprintf("%d of %d containers have unscanned images: %s.", len(unscannedContainers), len(containersVulnerabilityScanInfo), strings.Join(unscannedContainers, ", ")))
+	}
+
+	return &workloadEventSummary{
+		reason:  reason,
+		message: truncateMessage("Azure Defender: " + strings.Join(parts, " ")),
+	}
+}
+
+// describeContainer returns the name and the image of the container
+func describeContainer(info *contracts.ContainerVulnerabilityScanInfo) string {
+	if info.Image == nil || info.Image.Name == "" {
+		return info.Name
+	}
+	return fmt.Sprintf("%s (%s)", info.Name, info.Image.Name)
+}
+
+// summarizeSeverities returns the count of the findings per severity (e.g. "High: 2, Medium: 1")
+func summarizeSeverities(scanFindings []*contracts.ScanFinding) string {
+	countPerSeverity := map[string]int{}
+	for _, finding := range scanFindings {
+		if finding != nil {
+			countPerSeverity[finding.Severity]++
+		}
+	}
+
+	severities := make([]string, 0, len(countPerSeverity))
+	for severity := range countPerSeverity {
+		severities = append(severities, severity)
+	}
+	sort.Slice(severities, func(i, j int) bool {
+		iOrder, iKnown := _severitiesOrder[severities[i]]
+		jOrder, jKnown := _severitiesOrder[severities[j]]
+		if iKnown && jKnown {
+			return iOrder < jOrder
+		}
+		if iKnown != jKnown {
+			return iKnown
+		}
+		return severities[i] < severities[j]
+	})
+
+	counts := make([]string, 0, len(severities))
+	for _, severity := range severities {
+		counts = append(counts, fmt.Sprintf("%s: %d", severity, countPerSeverity[severity]))
+	}
+	return strings.Join(counts, ", ")
+}
+
+// truncateMessage truncates the message to _maxEventMessageLength
+func truncateMessage(message string) string {
+	if len(message) <= _maxEventMessageLength {
+		return message
+	}
+	return message[:_maxEventMessageLength-len(_truncatedMessageSuffix)] + _truncatedMessageSuffix
+}
@@ -0,0 +1,327 @@
+// Package imagedrift contains the detection of runtime image drift - a container runs a digest other than the digest that
+// was resolved from its tag and evaluated at admission time (e.g. the tag was pushed again before the kubelet pulled it).
+package imagedrift
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/Azure/AzureDefender-K8S-InClusterDefense/cmd/webhook/admisionrequest"
+	"github.com/Azure/AzureDefender-K8S-InClusterDefense/cmd/webhook/annotations"
+	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/azdsecinfo/contracts"
+	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/dataproviders/arg"
+	imagedriftmetric "github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/imagedrift/metric"
+	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/cache"
+	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/instrumentation"
+	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/instrumentation/metric"
+	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/instrumentation/metric/util"
+	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/instrumentation/trace"
+	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/registry"
+	registryutils "github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/registry/utils"
+	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/utils"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"strings"
+)
+
+const (
+	// DriftDetectedEventReason is the reason of the event when a container runs a digest other than the admitted digest
+	DriftDetectedEventReason = "DriftDetected"
+	// AdmittedDigestAdditionalDataKey is the key of the admitted digest in the additional data of a re-evaluated container
+	AdmittedDigestAdditionalDataKey = "AdmittedDigest"
+	// _controllerName is the name of the controller of ImageDriftDetector
+	_controllerName = "image-drift-detector"
+	// _deduplicationCacheKeyPrefix is the prefix of the cache key of a detected drift of a container
+	_deduplicationCacheKeyPrefix = "ImageDriftDetector:Deduplication:"
+	// _detectedCacheValue is the value of the cache keys of the detected drifts
+	_detectedCacheValue = "detected"
+	// _dockerPullableImageIDPrefix is the prefix of the image IDs that docker reports in the container statuses
+	_dockerPullableImageIDPrefix = "docker-pullable://"
+)
+
+// ImageDriftDetector implements reconcile.Reconciler interface
+var _ reconcile.Reconciler = (*ImageDriftDetector)(nil)
+
+// ImageDriftDetector is a controller that watches the status of the pods and compares the digest of the running image of each
+// container (status.containerStatuses[].imageID) with the digest in the scan info annotation.
+// On mismatch, a DriftDetected event is recorded on the pod, and the running digest is optionally re-evaluated.
+type ImageDriftDetector struct {
+	//tracerProvider is tracer provider of ImageDriftDetector
+	tracerProvider trace.ITracerProvider
+	//metricSubmitter is metric submitter of ImageDriftDetector
+	metricSubmitter metric.IMetricSubmitter
+	// client is the client of the manager - reads the pods and patches their annotations
+	client client.Client
+	// eventRecorder records the events
+	eventRecorder record.EventRecorder
+	// cacheClient is the cache of the recently detected drifts (in-mem cache)
+	cacheClient cache.ICacheClient
+	// argDataProvider gets the scan results of the running digests
+	argDataProvider arg.IARGDataProvider
+	// getScanInfoAnnotationConfiguration returns the current configuration of the scan info annotations that are updated on
+	// re-evaluation - the configuration that the detector was created with, or the reloaded configuration in case that a provider is set.
+	getScanInfoAnnotationConfiguration func() *annotations.ScanInfoAnnotationConfiguration
+	// configuration is the configuration of ImageDriftDetector
+	configuration *ImageDriftDetectorConfiguration
+}
+
+// ImageDriftDetectorConfiguration is configuration data for ImageDriftDetector
+type ImageDriftDetectorConfiguration struct {
+	// Enabled is whether the drift of the running images is detected
+	Enabled bool
+	// ReevaluationEnabled is whether the running digest of a drifted container is evaluated and the scan info annotations are updated
+	ReevaluationEnabled bool
+	// DeduplicationWindowInSeconds is the interval that the same drift of a container isn't reported again
+	DeduplicationWindowInSeconds int
+}
+
+// imageDrift is a container that runs a digest other than the admitted digest
+type imageDrift struct {
+	// containerName is the name of the container
+	containerName string
+	// admittedDigest is the digest in the scan info annotation
+	admittedDigest string
+	// runningImage is the digest based reference of the running image
+	runningImage *registry.Digest
+}
+
+// NewImageDriftDetector Ctor for ImageDriftDetector
+func NewImageDriftDetector(instrumentationProvider instrumentation.IInstrumentationProvider, client client.Client, eventRecorder record.EventRecorder, cacheClient cache.ICacheClient, argDataProvider arg.IARGDataProvider, scanInfoAnnotationConfiguration *annotations.ScanInfoAnnotationConfiguration, configuration *ImageDriftDetectorConfiguration) *ImageDriftDetector {
+	return &ImageDriftDetector{
+		tracerProvider:  instrumentationProvider.GetTracerProvider("ImageDriftDetector"),
+		metricSubmitter: instrumentationProvider.GetMetricSubmitter(),
+		client:          client,
+		eventRecorder:   eventRecorder,
+		cacheClient:     cacheClient,
+		argDataProvider: argDataProvider,
+		configuration:   configuration,
+		getScanInfoAnnotationConfiguration: func() *annotations.ScanInfoAnnotationConfiguration {
+			return scanInfoAnnotationConfiguration
+		},
+	}
+}
+
+// SetScanInfoAnnotationConfigurationProvider makes the detector read the configuration of the scan info annotations from the
+// provider on each re-evaluation (e.g. from the reloaded configuration of the handler), so the patches after a reload use the
+// same configuration as the handler. Should be called before the detector is used.
+func (detector *ImageDriftDetector) SetScanInfoAnnotationConfigurationProvider(getScanInfoAnnotationConfiguration func() *annotations.ScanInfoAnnotationConfiguration) {
+	detector.getScanInfoAnnotationConfiguration = getScanInfoAnnotationConfiguration
+}
+
+// SetupWithManager registers ImageDriftDetector as a controller of the pods that have scan info annotations
+func (detector *ImageDriftDetector) SetupWithManager(mgr manager.Manager) error {
+	err := builder.ControllerManagedBy(mgr).
+		Named(_controllerName).
+		For(&corev1.Pod{}, builder.WithPredicates(predicate.NewPredicateFuncs(hasScanInfoAnnotation))).
+		Complete(detector)
+	if err != nil {
+		return errors.Wrap(err, "ImageDriftDetector.SetupWithManager failed to create controller")
+	}
+	return nil
+}
+
+// Reconcile detects the containers of the pod that run a digest other than the admitted digest.
+// Each drift is reported once during the deduplication window - by DriftDetectedEventReason event on the pod and by ImageDriftMetric.
+func (detector *ImageDriftDetector) Reconcile(ctx context.Context, request reconcile.Request) (reconcile.Result, error) {
+	tracer := detector.tracerProvider.GetTracer("Reconcile")
+	pod := &corev1.Pod{}
+	if err := detector.client.Get(ctx, request.NamespacedName, pod); err != nil {
+		if apierrors.IsNotFound(err) {
+			tracer.Info("Pod was deleted", "pod", request.NamespacedName)
+			return reconcile.Result{}, nil
+		}
+		err = errors.Wrap(err, "ImageDriftDetector.Reconcile failed to get pod")
+		tracer.Error(err, "")
+		detector.metricSubmitter.SendMetric(1, util.NewErrorEncounteredMetric(err, "ImageDriftDetector.Reconcile"))
+		return reconcile.Result{}, err
+	}
+
+	var newDrifts []*imageDrift
+	for _, drift := range getImageDrifts(pod) {
+		if detector.isNewDrift(ctx, pod, drift) {
+			newDrifts = append(newDrifts, drift)
+		}
+	}
+	if len(newDrifts) == 0 {
+		return reconcile.Result{}, nil
+	}
+
+	reevaluationStatus := imagedriftmetric.DisabledReevaluationStatus
+	if detector.configuration.ReevaluationEnabled {
+		reevaluationStatus = detector.reevaluate(ctx, pod, newDrifts)
+	}
+	for _, drift := range newDrifts {
+		detector.metricSubmitter.SendMetric(1, imagedriftmetric.NewImageDriftMetric(reevaluationStatus))
+		detector.eventRecorder.Eventf(pod, corev1.EventTypeWarning, DriftDetectedEventReason,
+			"Container %s runs the image digest %s instead of the admitted digest %s", drift.containerName, drift.runningImage.Digest(), drift.admittedDigest)
+		tracer.Info("Drift is detected", "pod", request.NamespacedName, "container", drift.containerName, "admittedDigest", drift.admittedDigest, "runningDigest", drift.runningImage.Digest(), "reevaluationStatus", reevaluationStatus)
+	}
+	return reconcile.Result{}, nil
+}
+
+// reevaluate gets the scan results of the running digests of the drifted containers and updates them in the scan info annotations of the pod.
+// The annotations are updated only in case that the v1 annotation has the full scan info of the containers - otherwise the scan info
+// of the other containers would be lost.
+func (detector *ImageDriftDetector) reevaluate(ctx context.Context, pod *corev1.Pod, drifts []*imageDrift) imagedriftmetric.ReevaluationStatus {
+	tracer := detector.tracerProvider.GetTracer("reevaluate")
+	scanInfoList := annotations.GetScanInfoList(pod.Annotations)
+	if scanInfoList == nil || (scanInfoList.Encoding != "" && scanInfoList.Encoding != contracts.FullScanInfoEncoding) {
+		tracer.Info("The v1 scan info annotation is missing or isn't fully encoded - the annotations aren't updated", "pod", pod.Name)
+		return imagedriftmetric.SkippedReevaluationStatus
+	}
+
+	for _, drift := range drifts {
+		container := getContainerScanInfo(scanInfoList.Containers, drift.containerName)
+		if container == nil {
+			tracer.Info("The container is missing in the scan info annotation", "pod", pod.Name, "container", drift.containerName)
+			return imagedriftmetric.SkippedReevaluationStatus
+		}
+		scanStatus, scanFindings, err := detector.argDataProvider.GetImageVulnerabilityScanResults(ctx, drift.runningImage.Registry(), drift.runningImage.Repository(), drift.runningImage.Digest())
+		if err != nil {
+			err = errors.Wrap(err, "ImageDriftDetector.reevaluate failed to get the scan results of the running digest")
+			tracer.Error(err, "")
+			detector.metricSubmitter.SendMetric(1, util.NewErrorEncounteredMetric(err, "ImageDriftDetector.reevaluate"))
+			return imagedriftmetric.FailedReevaluationStatus
+		}
+		setReevaluatedScanInfo(container, drift, scanStatus, scanFindings)
+	}
+
+	if err := detector.patchScanInfoAnnotations(ctx, pod, scanInfoList); err != nil {
+		tracer.Error(err, "")
+		detector.metricSubmitter.SendMetric(1, util.NewErrorEncounteredMetric(err, "ImageDriftDetector.reevaluate"))
+		return imagedriftmetric.FailedReevaluationStatus
+	}
+	return imagedriftmetric.ReevaluatedReevaluationStatus
+}
+
+// patchScanInfoAnnotations patches the scan info annotations of the pod with the containers of the scan info list.
+// The requests of the handler's service account aren't mutated by the handler, so the patch isn't overridden on admission.
+func (detector *ImageDriftDetector) patchScanInfoAnnotations(ctx context.Context, pod *corev1.Pod, scanInfoList *contracts.ContainerVulnerabilityScanInfoList) error {
+	podAnnotations := make(map[string]string, len(pod.Annotations))
+	for key, value := range pod.Annotations {
+		podAnnotations[key] = value
+	}
+	workloadResource := &admisionrequest.WorkloadResource{Metadata: &admisionrequest.ObjectMetadata{Name: pod.Name, Namespace: pod.Namespace, Annotations: podAnnotations}}
+	patches, err := annotations.CreateContainersVulnerabilityScanAnnotationPatchAdd(scanInfoList.Containers, workloadResource, detector.getScanInfoAnnotationConfiguration(), scanInfoList.VulnerabilityReport)
+	if err != nil {
+		return errors.Wrap(err, "ImageDriftDetector.patchScanInfoAnnotations failed to create the scan info annotations patches")
+	}
+	serPatches, err := json.Marshal(patches)
+	if err != nil {
+		return errors.Wrap(err, "ImageDriftDetector.patchScanInfoAnnotations failed to marshal the patches")
+	}
+	if err = detector.client.Patch(ctx, pod, client.RawPatch(types.JSONPatchType, serPatches)); err != nil {
+		return errors.Wrap(err, "ImageDriftDetector.patchScanInfoAnnotations failed to patch the pod")
+	}
+	return nil
+}
+
+// isNewDrift checks whether the drift of the container to the running digest was recently detected.
+// If it's new - it's stored as detected in the cache. Failures of the cache are ignored - it's preferred to report duplicated drifts than to miss drifts.
+func (detector *ImageDriftDetector) isNewDrift(ctx context.Context, pod *corev1.Pod, drift *imageDrift) bool {
+	tracer := detector.tracerProvider.GetTracer("isNewDrift")
+	key := _deduplicationCacheKeyPrefix + strings.Join([]string{pod.Namespace, pod.Name, string(pod.UID), drift.containerName, drift.runningImage.Digest()}, "/")
+	_, err := detector.cacheClient.Get(ctx, key)
+	if err == nil {
+		return false
+	}
+	if !cache.IsMissingKeyCacheError(err) {
+		err = errors.Wrap(err, "ImageDriftDetector.isNewDrift failed to get key from cache")
+		tracer.Error(err, "")
+		detector.metricSubmitter.SendMetric(1, util.NewErrorEncounteredMetric(err, "ImageDriftDetector.isNewDrift"))
+	}
+	if detector.configuration.DeduplicationWindowInSeconds > 0 {
+		if err = detector.cacheClient.Set(ctx, key, _detectedCacheValue, utils.GetSeconds(detector.configuration.DeduplicationWindowInSeconds)); err != nil {
+			err = errors.Wrap(err, "ImageDriftDetector.isNewDrift failed to set key in cache")
+			tracer.Error(err, "")
+			detector.metricSubmitter.SendMetric(1, util.NewErrorEncounteredMetric(err, "ImageDriftDetector.isNewDrift"))
+		}
+	}
+	return true
+}
+
+// getImageDrifts returns the containers of the pod that run a digest other than the digest in the scan info annotation.
+// Containers without admitted digest (e.g. unscanned) or without running digest (e.g. not pulled yet) are ignored.
+func getImageDrifts(pod *corev1.Pod) []*imageDrift {
+	admittedImages := annotations.GetScanInfoImages(pod.Annotations)
+	if len(admittedImages) == 0 {
+		return nil
+	}
+	containerStatuses := append(append([]corev1.ContainerStatus{}, pod.Status.InitContainerStatuses...), pod.Status.ContainerStatuses...)
+	var drifts []*imageDrift
+	for _, containerStatus := range containerStatuses {
+		admittedImage, ok := admittedImages[containerStatus.Name]
+		if !ok || admittedImage == nil || admittedImage.Digest == "" {
+			continue
+		}
+		runningImage := getRunningImage(containerStatus.ImageID)
+		if runningImage == nil || runningImage.Digest() == admittedImage.Digest {
+			continue
+		}
+		drifts = append(drifts, &imageDrift{containerName: containerStatus.Name, admittedDigest: admittedImage.Digest, runningImage: runningImage})
+	}
+	return drifts
+}
+
+// getRunningImage returns the digest based reference of the image ID of a container status (e.g. docker-pullable://registry/repo@sha256:...).
+// Returns nil in case that the image ID isn't a digest based reference (e.g. empty before the image is pulled, or an image config ID).
+func getRunningImage(imageID string) *registry.Digest {
+	imageReference, err := registryutils.GetImageReference(strings.TrimPrefix(imageID, _dockerPullableImageIDPrefix))
+	if err != nil {
+		return nil
+	}
+	digest, ok := imageReference.(*registry.Digest)
+	if !ok {
+		return nil
+	}
+	return digest
+}
+
+// getContainerScanInfo returns the scan info of the container by its name, or nil if it's missing
+func getContainerScanInfo(containers []*contracts.ContainerVulnerabilityScanInfo, containerName string) *contracts.ContainerVulnerabilityScanInfo {
+	for _, container := range containers {
+		if container != nil && container.Name == containerName {
+			return container
+		}
+	}
+	return nil
+}
+
+// setReevaluatedScanInfo sets the scan results of the running digest in the scan info of the container.
+// The signature, the supply chain artifacts and the unscanned reason are of the admitted digest, so they're cleared.
+func setReevaluatedScanInfo(container *contracts.ContainerVulnerabilityScanInfo, drift *imageDrift, scanStatus contracts.ScanStatus, scanFindings []*contracts.ScanFinding) {
+	imageName := drift.runningImage.Original()
+	if container.Image != nil {
+		imageName = container.Image.Name
+	}
+	container.Image = &contracts.Image{Name: imageName, Digest: drift.runningImage.Digest()}
+	container.ScanStatus = scanStatus
+	container.ScanFindings = scanFindings
+	container.ScanFindingsSummary = nil
+	container.SignatureStatus = ""
+	container.SupplyChainArtifacts = nil
+	additionalData := make(map[string]string, len(container.AdditionalData)+1)
+	for key, value := range container.AdditionalData {
+		// The unscanned reason is of the admitted digest
+		if key != contracts.UnscannedReasonAnnotationKey {
+			additionalData[key] = value
+		}
+	}
+	additionalData[AdmittedDigestAdditionalDataKey] = drift.admittedDigest
+	container.AdditionalData = additionalData
+}
+
+// hasScanInfoAnnotation returns true if the object has a scan info annotation of one of the schema versions
+func hasScanInfoAnnotation(object client.Object) bool {
+	objectAnnotations := object.GetAnnotations()
+	_, hasV1 := objectAnnotations[contracts.ContainersVulnerabilityScanInfoAnnotationName]
+	_, hasV2 := objectAnnotations[contracts.ContainersVulnerabilityScanInfoV2AnnotationName]
+	return hasV1 || hasV2
+}
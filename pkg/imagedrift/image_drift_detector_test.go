@@ -0,0 +1,248 @@
+package imagedrift
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/Azure/AzureDefender-K8S-InClusterDefense/cmd/webhook/annotations"
+	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/azdsecinfo/contracts"
+	argmocks "github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/dataproviders/arg/mocks"
+	imagedriftmetric "github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/imagedrift/metric"
+	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/cache"
+	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/cache/wrappers"
+	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/instrumentation"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"strings"
+	"testing"
+	"time"
+)
+
+const (
+	_admittedDigest = "sha256:1111111111111111111111111111111111111111111111111111111111111111"
+	_runningDigest  = "sha256:2222222222222222222222222222222222222222222222222222222222222222"
+)
+
+var (
+	_podRequest = reconcile.Request{NamespacedName: types.NamespacedName{Namespace: "default", Name: "podTest"}}
+)
+
+type ImageDriftDetectorTestSuite struct {
+	suite.Suite
+	recorder        *record.FakeRecorder
+	argDataProvider *argmocks.IARGDataProvider
+	configuration   *ImageDriftDetectorConfiguration
+}
+
+// This will run before each test in the suite
+func (suite *ImageDriftDetectorTestSuite) SetupTest() {
+	suite.recorder = record.NewFakeRecorder(10)
+	suite.argDataProvider = &argmocks.IARGDataProvider{}
+	suite.configuration = &ImageDriftDetectorConfiguration{Enabled: true, DeduplicationWindowInSeconds: 3600}
+}
+
+func (suite *ImageDriftDetectorTestSuite) Test_Reconcile_RunningDigestIsAdmitted_NoEvent() {
+	detector, _ := suite.createDetector(createPodForTest(suite.T(), "docker-pullable://tomer.azurecr.io/app@"+_admittedDigest))
+
+	_, err := detector.Reconcile(context.Background(), _podRequest)
+
+	suite.Nil(err)
+	suite.Empty(suite.recorder.Events)
+}
+
+func (suite *ImageDriftDetectorTestSuite) Test_Reconcile_RunningDigestIsDifferent_DriftDetectedEvent() {
+	detector, _ := suite.createDetector(createPodForTest(suite.T(), "docker-pullable://tomer.azurecr.io/app@"+_runningDigest))
+
+	_, err := detector.Reconcile(context.Background(), _podRequest)
+
+	suite.Nil(err)
+	suite.Require().Len(suite.recorder.Events, 1)
+	event := <-suite.recorder.Events
+	suite.True(strings.HasPrefix(event, "Warning "+DriftDetectedEventReason))
+	suite.Contains(event, _runningDigest)
+	suite.Contains(event, _admittedDigest)
+	suite.argDataProvider.AssertNotCalled(suite.T(), "GetImageVulnerabilityScanResults", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func (suite *ImageDriftDetectorTestSuite) Test_Reconcile_SameDriftTwice_Deduplicated() {
+	detector, _ := suite.createDetector(createPodForTest(suite.T(), "tomer.azurecr.io/app@"+_runningDigest))
+
+	_, err := detector.Reconcile(context.Background(), _podRequest)
+	suite.Nil(err)
+	_, err = detector.Reconcile(context.Background(), _podRequest)
+	suite.Nil(err)
+
+	suite.Len(suite.recorder.Events, 1)
+}
+
+func (suite *ImageDriftDetectorTestSuite) Test_Reconcile_ImageIDIsNotDigest_NoEvent() {
+	detector, _ := suite.createDetector(createPodForTest(suite.T(), _runningDigest))
+
+	_, err := detector.Reconcile(context.Background(), _podRequest)
+
+	suite.Nil(err)
+	suite.Empty(suite.recorder.Events)
+}
+
+func (suite *ImageDriftDetectorTestSuite) Test_Reconcile_PodNotFound_NoError() {
+	detector, _ := suite.createDetector()
+
+	_, err := detector.Reconcile(context.Background(), _podRequest)
+
+	suite.Nil(err)
+	suite.Empty(suite.recorder.Events)
+}
+
+func (suite *ImageDriftDetectorTestSuite) Test_Reconcile_ReevaluationEnabled_AnnotationUpdatedWithRunningDigest() {
+	suite.configuration.ReevaluationEnabled = true
+	detector, fakeClient := suite.createDetector(createPodForTest(suite.T(), "docker-pullable://tomer.azurecr.io/app@"+_runningDigest))
+	scanFindings := []*contracts.ScanFinding{{Id: "1", Severity: "High", Patchable: true}}
+	suite.argDataProvider.On("GetImageVulnerabilityScanResults", mock.Anything, "tomer.azurecr.io", "app", _runningDigest).Return(contracts.UnhealthyScan, scanFindings, nil).Once()
+
+	_, err := detector.Reconcile(context.Background(), _podRequest)
+
+	suite.Nil(err)
+	suite.Len(suite.recorder.Events, 1)
+	pod := &corev1.Pod{}
+	suite.Require().Nil(fakeClient.Get(context.Background(), _podRequest.NamespacedName, pod))
+	scanInfoList := annotations.GetScanInfoList(pod.Annotations)
+	suite.Require().NotNil(scanInfoList)
+	suite.Require().Len(scanInfoList.Containers, 1)
+	container := scanInfoList.Containers[0]
+	suite.Equal(&contracts.Image{Name: "tomer.azurecr.io/app:1", Digest: _runningDigest}, container.Image)
+	suite.Equal(contracts.UnhealthyScan, container.ScanStatus)
+	suite.Equal(scanFindings, container.ScanFindings)
+	suite.Equal(contracts.SignatureStatus(""), container.SignatureStatus)
+	suite.Equal(map[string]string{AdmittedDigestAdditionalDataKey: _admittedDigest}, container.AdditionalData)
+	suite.Equal("other", pod.Annotations["other"])
+
+	// The running digest is admitted now - no drift
+	_, err = detector.Reconcile(context.Background(), _podRequest)
+	suite.Nil(err)
+	suite.Len(suite.recorder.Events, 1)
+	suite.argDataProvider.AssertExpectations(suite.T())
+}
+
+func (suite *ImageDriftDetectorTestSuite) Test_Reconcile_ScanInfoAnnotationConfigurationProviderSet_ProvidedConfigurationUsed() {
+	suite.configuration.ReevaluationEnabled = true
+	detector, fakeClient := suite.createDetector(createPodForTest(suite.T(), "docker-pullable://tomer.azurecr.io/app@"+_runningDigest))
+	// The configuration is reloaded after the detector was created
+	detector.SetScanInfoAnnotationConfigurationProvider(func() *annotations.ScanInfoAnnotationConfiguration {
+		return &annotations.ScanInfoAnnotationConfiguration{MaxSizeInBytes: 131072, OversizedEncoding: "summarized", SummarizedTopFindingsCount: 20, SchemaVersions: []string{"v1", "v2"}}
+	})
+	suite.argDataProvider.On("GetImageVulnerabilityScanResults", mock.Anything, "tomer.azurecr.io", "app", _runningDigest).Return(contracts.HealthyScan, []*contracts.ScanFinding{}, nil).Once()
+
+	_, err := detector.Reconcile(context.Background(), _podRequest)
+
+	suite.Nil(err)
+	pod := &corev1.Pod{}
+	suite.Require().Nil(fakeClient.Get(context.Background(), _podRequest.NamespacedName, pod))
+	suite.Contains(pod.Annotations, contracts.ContainersVulnerabilityScanInfoAnnotationName)
+	suite.Contains(pod.Annotations, contracts.ContainersVulnerabilityScanInfoV2AnnotationName)
+	suite.argDataProvider.AssertExpectations(suite.T())
+}
+
+func (suite *ImageDriftDetectorTestSuite) Test_Reconcile_ReevaluationFailed_AnnotationNotUpdated() {
+	suite.configuration.ReevaluationEnabled = true
+	originalPod := createPodForTest(suite.T(), "docker-pullable://tomer.azurecr.io/app@"+_runningDigest)
+	detector, fakeClient := suite.createDetector(originalPod)
+	suite.argDataProvider.On("GetImageVulnerabilityScanResults", mock.Anything, "tomer.azurecr.io", "app", _runningDigest).Return(contracts.ScanStatus(""), nil, errors.New("error")).Once()
+
+	_, err := detector.Reconcile(context.Background(), _podRequest)
+
+	suite.Nil(err)
+	suite.Len(suite.recorder.Events, 1)
+	pod := &corev1.Pod{}
+	suite.Require().Nil(fakeClient.Get(context.Background(), _podRequest.NamespacedName, pod))
+	suite.Equal(originalPod.Annotations, pod.Annotations)
+}
+
+func (suite *ImageDriftDetectorTestSuite) Test_Reconcile_ReevaluationOfSummarizedAnnotation_Skipped() {
+	suite.configuration.ReevaluationEnabled = true
+	originalPod := createPodForTest(suite.T(), "docker-pullable://tomer.azurecr.io/app@"+_runningDigest)
+	scanInfoList := annotations.GetScanInfoList(originalPod.Annotations)
+	scanInfoList.Encoding = contracts.SummarizedScanInfoEncoding
+	serScanInfoList, err := json.Marshal(scanInfoList)
+	suite.Require().Nil(err)
+	originalPod.Annotations[contracts.ContainersVulnerabilityScanInfoAnnotationName] = string(serScanInfoList)
+	detector, _ := suite.createDetector(originalPod)
+
+	status := detector.reevaluate(context.Background(), originalPod, getImageDrifts(originalPod))
+
+	suite.Equal(imagedriftmetric.SkippedReevaluationStatus, status)
+	suite.argDataProvider.AssertNotCalled(suite.T(), "GetImageVulnerabilityScanResults", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func (suite *ImageDriftDetectorTestSuite) Test_getRunningImage_ImageIDs_AsExpected() {
+	runningImage := getRunningImage("docker-pullable://tomer.azurecr.io/app@" + _runningDigest)
+	suite.Require().NotNil(runningImage)
+	suite.Equal("tomer.azurecr.io", runningImage.Registry())
+	suite.Equal("app", runningImage.Repository())
+	suite.Equal(_runningDigest, runningImage.Digest())
+
+	suite.NotNil(getRunningImage("tomer.azurecr.io/app@" + _runningDigest))
+	suite.Nil(getRunningImage(_runningDigest))
+	suite.Nil(getRunningImage(""))
+	suite.Nil(getRunningImage("tomer.azurecr.io/app:1"))
+}
+
+func (suite *ImageDriftDetectorTestSuite) Test_hasScanInfoAnnotation_AsExpected() {
+	suite.True(hasScanInfoAnnotation(&corev1.Pod{ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{contracts.ContainersVulnerabilityScanInfoAnnotationName: "{}"}}}))
+	suite.True(hasScanInfoAnnotation(&corev1.Pod{ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{contracts.ContainersVulnerabilityScanInfoV2AnnotationName: "{}"}}}))
+	suite.False(hasScanInfoAnnotation(&corev1.Pod{ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{"other": "other"}}}))
+	suite.False(hasScanInfoAnnotation(&corev1.Pod{}))
+}
+
+func TestImageDriftDetectorTestSuite(t *testing.T) {
+	suite.Run(t, new(ImageDriftDetectorTestSuite))
+}
+
+// createDetector returns ImageDriftDetector with fake client of the given objects
+func (suite *ImageDriftDetectorTestSuite) createDetector(objects ...client.Object) (*ImageDriftDetector, client.Client) {
+	fakeClient := fake.NewClientBuilder().WithScheme(clientgoscheme.Scheme).WithObjects(objects...).Build()
+	cacheClient := cache.NewFreeCacheInMemCacheClient(instrumentation.NewNoOpInstrumentationProvider(), wrappers.NewFreeCacheInMem(&wrappers.FreeCacheInMemWrapperCacheConfiguration{CacheSize: 10000000}))
+	scanInfoAnnotationConfiguration := &annotations.ScanInfoAnnotationConfiguration{MaxSizeInBytes: 131072, OversizedEncoding: "summarized", SummarizedTopFindingsCount: 20}
+	detector := NewImageDriftDetector(instrumentation.NewNoOpInstrumentationProvider(), fakeClient, suite.recorder, cacheClient, suite.argDataProvider, scanInfoAnnotationConfiguration, suite.configuration)
+	return detector, fakeClient
+}
+
+// createPodForTest returns a pod with a scan info annotation of a container with the admitted digest that runs the image ID
+func createPodForTest(t *testing.T, imageID string) *corev1.Pod {
+	scanInfoList := &contracts.ContainerVulnerabilityScanInfoList{
+		GeneratedTimestamp: time.Now().UTC(),
+		Containers: []*contracts.ContainerVulnerabilityScanInfo{{
+			Name:            "app",
+			Image:           &contracts.Image{Name: "tomer.azurecr.io/app:1", Digest: _admittedDigest},
+			ScanStatus:      contracts.HealthyScan,
+			ScanFindings:    []*contracts.ScanFinding{},
+			SignatureStatus: contracts.SignatureVerified,
+		}},
+	}
+	serScanInfoList, err := json.Marshal(scanInfoList)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "podTest",
+			Namespace: "default",
+			UID:       "podUID",
+			Annotations: map[string]string{
+				contracts.ContainersVulnerabilityScanInfoAnnotationName: string(serScanInfoList),
+				"other": "other",
+			},
+		},
+		Spec: corev1.PodSpec{Containers: []corev1.Container{{Name: "app", Image: "tomer.azurecr.io/app:1"}}},
+		Status: corev1.PodStatus{
+			ContainerStatuses: []corev1.ContainerStatus{{Name: "app", Image: "tomer.azurecr.io/app:1", ImageID: imageID}},
+		},
+	}
+}
@@ -0,0 +1,45 @@
+package metric
+
+import (
+	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/instrumentation/metric"
+)
+
+// ReevaluationStatus is enum of the statuses of the re-evaluations of the running digests of the drifted containers
+type ReevaluationStatus string
+
+const (
+	// DisabledReevaluationStatus is when the re-evaluation is disabled
+	DisabledReevaluationStatus ReevaluationStatus = "Disabled"
+	// ReevaluatedReevaluationStatus is when the running digest was evaluated and the scan info annotations were updated
+	ReevaluatedReevaluationStatus ReevaluationStatus = "Reevaluated"
+	// SkippedReevaluationStatus is when the scan info annotation can't be updated (e.g. the v1 annotation is missing or summarized)
+	SkippedReevaluationStatus ReevaluationStatus = "Skipped"
+	// FailedReevaluationStatus is when the running digest couldn't be evaluated or the scan info annotations couldn't be updated
+	FailedReevaluationStatus ReevaluationStatus = "Failed"
+)
+
+// ImageDriftMetric implements metric.IMetric interface
+var _ metric.IMetric = (*ImageDriftMetric)(nil)
+
+// ImageDriftMetric is metric of ImageDriftDetector to report each container that runs a digest other than the admitted digest
+type ImageDriftMetric struct {
+	// reevaluationStatus is whether the running digest was re-evaluated
+	reevaluationStatus ReevaluationStatus
+}
+
+// NewImageDriftMetric Ctor for ImageDriftMetric
+func NewImageDriftMetric(reevaluationStatus ReevaluationStatus) *ImageDriftMetric {
+	return &ImageDriftMetric{
+		reevaluationStatus: reevaluationStatus,
+	}
+}
+
+func (m *ImageDriftMetric) MetricName() string {
+	return "ImageDriftDetected"
+}
+
+func (m *ImageDriftMetric) MetricDimension() []metric.Dimension {
+	return []metric.Dimension{
+		{Key: "ReevaluationStatus", Value: string(m.reevaluationStatus)},
+	}
+}
@@ -1,6 +1,7 @@
 package azureauth
 
 import (
+	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/cloudenvironment"
 	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/instrumentation"
 	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/instrumentation/trace"
 	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/utils"
@@ -62,12 +63,16 @@ func (factory *MSIAzureAuthorizerFactory) CreateARMAuthorizer() (autorest.Author
 		return nil, err
 	}
 
-	resourceManagerEndpoint := settings.GetEnvironment().ResourceManagerEndpoint
+	cloudEnvironment := cloudenvironment.GetCloudEnvironmentInstance()
+	// Set the cloud environment in settings (login authority of the cloud)
+	*settings.GetEnvironment() = cloudEnvironment.GetEnvironment()
 
-	// Set ARM as the resource to authorize in settings
-	settings.GetValues()[auth.Resource] = resourceManagerEndpoint
+	resourceManagerAudience := cloudEnvironment.GetResourceManagerAudience()
 
-	tracer.Info("Settings", "Resource", resourceManagerEndpoint)
+	// Set ARM of the cloud as the resource to authorize in settings
+	settings.GetValues()[auth.Resource] = resourceManagerAudience
+
+	tracer.Info("Settings", "CloudEnvironment", cloudEnvironment.GetName(), "Resource", resourceManagerAudience, "Authority", cloudEnvironment.GetActiveDirectoryAuthority())
 
 	// Generate the MSI authorizer by settings provided and factory's configurations
 	authorizer, err := factory.createAuthorizer(settings)
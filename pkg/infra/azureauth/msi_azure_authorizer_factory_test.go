@@ -2,6 +2,7 @@ package azureauth
 
 import (
 	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/azureauth/wrappers/mocks"
+	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/cloudenvironment"
 	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/instrumentation"
 	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/utils"
 	"github.com/pkg/errors"
@@ -47,8 +48,10 @@ func (suite *TestSuite) SetupTest() {
 		MSIClientId: CLIENT_ID,
 	}
 
+	cloudenvironment.ResetCloudEnvironmentForTests()
 	suite.values = map[string]string{}
-	suite.env = &azure.PublicCloud
+	env := azure.PublicCloud
+	suite.env = &env
 	suite.authSettingsMock = &mocks.IEnvironmentSettingsWrapper{}
 	suite.authWrapperMock = &mocks.IAzureAuthWrapper{}
 	suite.factory = NewMSIEnvAzureAuthorizerFactory(instrumentation.NewNoOpInstrumentationProvider(), _configuration, suite.authWrapperMock)
@@ -132,6 +135,46 @@ func (suite *TestSuite) Test_GetAuthorizerError() {
 	assertExpectations(suite)
 }
 
+func (suite *TestSuite) TestAzureAuthorizerFromEnvFactory_CreateArmAuthorizer_CloudEnvironments_ResourceAndEnvironmentOfCloud() {
+	tests := []struct {
+		name             string
+		environment      azure.Environment
+		expectedResource string
+		expectedLogin    string
+	}{
+		{name: "public", environment: azure.PublicCloud, expectedResource: "https://management.azure.com/", expectedLogin: "https://login.microsoftonline.com/"},
+		{name: "usgovernment", environment: azure.USGovernmentCloud, expectedResource: "https://management.usgovcloudapi.net/", expectedLogin: "https://login.microsoftonline.us/"},
+		{name: "china", environment: azure.ChinaCloud, expectedResource: "https://management.chinacloudapi.cn/", expectedLogin: "https://login.chinacloudapi.cn/"},
+	}
+	for _, tt := range tests {
+		suite.Run(tt.name, func() {
+			cloudenvironment.UpdateCloudEnvironmentForTests(tt.environment)
+			utils.UpdateDeploymentForTests(&utils.DeploymentConfiguration{IsLocalDevelopment: false})
+			values := map[string]string{}
+			// The environment settings detected the public cloud
+			env := azure.PublicCloud
+			authSettingsMock := &mocks.IEnvironmentSettingsWrapper{}
+			authSettingsMock.On("GetEnvironment").Return(&env).Once()
+			authSettingsMock.On("GetValues").Return(values).Twice()
+			authSettingsMock.On("GetMSIAuthorizer").Return(suite.authorizer, nil).Once()
+			authWrapperMock := &mocks.IAzureAuthWrapper{}
+			authWrapperMock.On("GetSettingsFromEnvironment").Return(authSettingsMock, nil).Once()
+			factory := NewMSIEnvAzureAuthorizerFactory(instrumentation.NewNoOpInstrumentationProvider(), _configuration, authWrapperMock)
+
+			authorizer, err := factory.CreateARMAuthorizer()
+
+			suite.Nil(err)
+			suite.Equal(suite.authorizer, authorizer)
+			suite.Equal(map[string]string{auth.ClientID: CLIENT_ID, auth.Resource: tt.expectedResource}, values)
+			suite.Equal(tt.environment, env)
+			suite.Equal(tt.expectedLogin, env.ActiveDirectoryEndpoint)
+			authSettingsMock.AssertExpectations(suite.T())
+			authWrapperMock.AssertExpectations(suite.T())
+		})
+	}
+	cloudenvironment.ResetCloudEnvironmentForTests()
+}
+
 func assertExpectations(suite *TestSuite) {
 	suite.authSettingsMock.AssertExpectations(suite.T())
 	suite.authWrapperMock.AssertExpectations(suite.T())
@@ -3,6 +3,8 @@
 package mocks
 
 import (
+	context "context"
+
 	time "time"
 
 	mock "github.com/stretchr/testify/mock"
@@ -13,20 +15,20 @@ type ICacheClient struct {
 	mock.Mock
 }
 
-// Get provides a mock function with given fields: key
-func (_m *ICacheClient) Get(key string) (string, error) {
-	ret := _m.Called(key)
+// Get provides a mock function with given fields: ctx, key
+func (_m *ICacheClient) Get(ctx context.Context, key string) (string, error) {
+	ret := _m.Called(ctx, key)
 
 	var r0 string
-	if rf, ok := ret.Get(0).(func(string) string); ok {
-		r0 = rf(key)
+	if rf, ok := ret.Get(0).(func(context.Context, string) string); ok {
+		r0 = rf(ctx, key)
 	} else {
 		r0 = ret.Get(0).(string)
 	}
 
 	var r1 error
-	if rf, ok := ret.Get(1).(func(string) error); ok {
-		r1 = rf(key)
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, key)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -34,13 +36,13 @@ func (_m *ICacheClient) Get(key string) (string, error) {
 	return r0, r1
 }
 
-// Set provides a mock function with given fields: key, value, expiration
-func (_m *ICacheClient) Set(key string, value string, expiration time.Duration) error {
-	ret := _m.Called(key, value, expiration)
+// Set provides a mock function with given fields: ctx, key, value, expiration
+func (_m *ICacheClient) Set(ctx context.Context, key string, value string, expiration time.Duration) error {
+	ret := _m.Called(ctx, key, value, expiration)
 
 	var r0 error
-	if rf, ok := ret.Get(0).(func(string, string, time.Duration) error); ok {
-		r0 = rf(key, value, expiration)
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, time.Duration) error); ok {
+		r0 = rf(ctx, key, value, expiration)
 	} else {
 		r0 = ret.Error(0)
 	}
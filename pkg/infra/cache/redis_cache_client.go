@@ -5,6 +5,7 @@ import (
 	cachemetrics "github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/cache/metric"
 	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/cache/operations"
 	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/cache/wrappers"
+	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/circuitbreaker"
 	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/instrumentation"
 	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/instrumentation/metric"
 	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/instrumentation/metric/util"
@@ -19,6 +20,8 @@ import (
 const (
 	_redisClientType    = "RedisCacheClient"
 	_expectedPingResult = "PONG"
+	// _circuitBreakerKey is the key of redis' circuit in the circuit breaker
+	_circuitBreakerKey = "Redis"
 )
 
 // RedisCacheClient implements ICacheClient interface
@@ -36,34 +39,37 @@ type RedisCacheClient struct {
 	metricSubmitter metric.IMetricSubmitter
 	//retryPolicy retry policy for communication with redis cluster.
 	retryPolicy retrypolicy.IRetryPolicy
-	// cacheContext is the cache cacheContext
-	cacheContext context.Context
+	// circuitBreaker rejects the calls to redis while it's failing, so the callers skip the cache immediately
+	circuitBreaker circuitbreaker.ICircuitBreaker
 }
 
 // NewRedisCacheClient is factory for RedisCacheClient
-func NewRedisCacheClient(instrumentationProvider instrumentation.IInstrumentationProvider, redisBaseClient wrappers.IRedisBaseClientWrapper, retryPolicy retrypolicy.IRetryPolicy, cacheContext context.Context) *RedisCacheClient {
+func NewRedisCacheClient(instrumentationProvider instrumentation.IInstrumentationProvider, redisBaseClient wrappers.IRedisBaseClientWrapper, retryPolicy retrypolicy.IRetryPolicy, circuitBreaker circuitbreaker.ICircuitBreaker) *RedisCacheClient {
 
 	return &RedisCacheClient{
 		tracerProvider:  instrumentationProvider.GetTracerProvider("RedisCacheClient"),
 		metricSubmitter: instrumentationProvider.GetMetricSubmitter(),
 		redisClient:     redisBaseClient,
 		retryPolicy:     retryPolicy,
-		cacheContext:    cacheContext,
+		circuitBreaker:  circuitBreaker,
 	}
 }
 
 // Get gets a value from the redis cache. It returns error when key does not exist.
-func (client *RedisCacheClient) Get(key string) (string, error) {
+func (client *RedisCacheClient) Get(ctx context.Context, key string) (string, error) {
 	tracer := client.tracerProvider.GetTracer("Get")
 	tracer.Info("Get key executed", "Key", key)
-	value, err := client.retryPolicy.RetryActionString(
-		/*action ActionString get key using client.redisClient */
-		func() (string, error) { return client.redisClient.Get(client.cacheContext, key).Result() },
-		/*handler ShouldRetryOnSpecificError - handle with key is missing error*/
-		func(err error) bool {
-			return !errors.Is(err, redis.Nil)
-		},
-	)
+	value, err := client.execute(func() (string, error) {
+		return client.retryPolicy.RetryActionString(
+			ctx,
+			/*action ActionString get key using client.redisClient */
+			func() (string, error) { return client.redisClient.Get(ctx, key).Result() },
+			/*handler ShouldRetryOnSpecificError - handle with key is missing error*/
+			func(err error) bool {
+				return !errors.Is(err, redis.Nil)
+			},
+		)
+	})
 	// In case that get failed
 	if err != nil {
 		// Check if it is unexpected error
@@ -91,7 +97,7 @@ func (client *RedisCacheClient) Get(key string) (string, error) {
 //Zero expiration means the key has no expiration time.
 // It returns error when there was a problem trying to set the key.
 // expiration must be non-negative expiration.
-func (client *RedisCacheClient) Set(key string, value string, expiration time.Duration) error {
+func (client *RedisCacheClient) Set(ctx context.Context, key string, value string, expiration time.Duration) error {
 	tracer := client.tracerProvider.GetTracer("Set")
 	tracer.Info("Set new key", "Key", key, "Value", value, "Expiration", expiration)
 
@@ -103,13 +109,16 @@ func (client *RedisCacheClient) Set(key string, value string, expiration time.Du
 		return err
 	}
 
-	err := client.retryPolicy.RetryAction(
-		// Action - set the values redis client.
-		func() error { return client.redisClient.Set(client.cacheContext, key, value, expiration).Err() },
-		// HandleError - if the err is redis.Nil then it means that the get is not exist.
-		// TODO @liorkesten -- How is this related to set??
-		func(err error) bool { return err != redis.Nil },
-	)
+	_, err := client.execute(func() (string, error) {
+		return "", client.retryPolicy.RetryAction(
+			ctx,
+			// Action - set the values redis client.
+			func() error { return client.redisClient.Set(ctx, key, value, expiration).Err() },
+			// HandleError - if the err is redis.Nil then it means that the get is not exist.
+			// TODO @liorkesten -- How is this related to set??
+			func(err error) bool { return err != redis.Nil },
+		)
+	})
 
 	if err != nil && !errors.Is(err, redis.Nil) {
 		client.metricSubmitter.SendMetric(1, cachemetrics.NewSetErrEncounteredMetric(err, _redisClientType))
@@ -122,20 +131,26 @@ func (client *RedisCacheClient) Set(key string, value string, expiration time.Du
 	return nil
 }
 
-func (client *RedisCacheClient) Ping() error {
+func (client *RedisCacheClient) Ping(ctx context.Context) error {
 	tracer := client.tracerProvider.GetTracer("Ping")
 	tracer.Info("Ping executed")
 
-	value, err := client.retryPolicy.RetryActionString(
-		/*action ActionString ping using client.redisClient */
-		func() (string, error) { return client.redisClient.Ping(client.cacheContext).Result() },
-		/*handler ShouldRetryOnSpecificError - handle when received an error from ping result*/
-		func(err error) bool {
-			return !errors.Is(err, redis.Nil)
-		},
-	)
+	value, err := client.execute(func() (string, error) {
+		return client.retryPolicy.RetryActionString(
+			ctx,
+			/*action ActionString ping using client.redisClient */
+			func() (string, error) { return client.redisClient.Ping(ctx).Result() },
+			/*handler ShouldRetryOnSpecificError - handle when received an error from ping result*/
+			func(err error) bool {
+				return !errors.Is(err, redis.Nil)
+			},
+		)
+	})
 	// Check if there was an error
-	if err != nil || value != _expectedPingResult {
+	if err == nil && value != _expectedPingResult {
+		err = errors.Errorf("unexpected ping result <%s>", value)
+	}
+	if err != nil {
 		tracer.Error(err, "Failed to connect to Redis server - Ping failed")
 		client.metricSubmitter.SendMetric(1, util.NewErrorEncounteredMetric(err, "RedisCacheClient.Ping"))
 		return err
@@ -146,3 +161,16 @@ func (client *RedisCacheClient) Ping() error {
 	tracer.Info("Received pong from Redis server")
 	return nil
 }
+
+// execute executes the action with the circuit breaker - missing key (redis.Nil) isn't failure of redis
+func (client *RedisCacheClient) execute(action func() (string, error)) (string, error) {
+	value, err := client.circuitBreaker.Execute(
+		_circuitBreakerKey,
+		func() (interface{}, error) { return action() },
+		func(err error) bool { return !errors.Is(err, redis.Nil) },
+	)
+	if err != nil {
+		return "", err
+	}
+	return value.(string), nil
+}
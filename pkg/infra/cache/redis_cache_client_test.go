@@ -2,6 +2,7 @@ package cache
 
 import (
 	"context"
+	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/circuitbreaker"
 	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/instrumentation"
 	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/retrypolicy"
 	"github.com/go-redis/redis/v8"
@@ -36,8 +37,8 @@ func (suite *TestSuiteRedisCache) SetupTest() {
 	// TODO retry mocking in all places
 	_redisClientMock, _redisMock = redismock.NewClientMock()
 	retryPolicyConfiguration := &retrypolicy.RetryPolicyConfiguration{RetryAttempts: 1, RetryDurationInMS: 10}
-	_retryPolicy = retrypolicy.NewRetryPolicy(instrumentation.NewNoOpInstrumentationProvider(), retryPolicyConfiguration)
-	_client = NewRedisCacheClient(instrumentation.NewNoOpInstrumentationProvider(), _redisClientMock, _retryPolicy, _cacheContext)
+	_retryPolicy = retrypolicy.NewRetryPolicy(instrumentation.NewNoOpInstrumentationProvider(), retryPolicyConfiguration, "RedisCacheClient")
+	_client = NewRedisCacheClient(instrumentation.NewNoOpInstrumentationProvider(), _redisClientMock, _retryPolicy, circuitbreaker.NewNoOpCircuitBreaker())
 }
 
 func (suite *TestSuiteRedisCache) Test_Get_KeyIsExist_ShouldReturnValue() {
@@ -47,7 +48,7 @@ func (suite *TestSuiteRedisCache) Test_Get_KeyIsExist_ShouldReturnValue() {
 	_redisMock.ExpectGet(_key).SetVal(expectedValue)
 
 	// Act
-	actual, err := _client.Get(_key)
+	actual, err := _client.Get(_cacheContext, _key)
 
 	// Test
 	suite.Nil(err)
@@ -59,7 +60,7 @@ func (suite *TestSuiteRedisCache) Test_Get_KeyIsNotExist_ShouldReturnErr() {
 	_redisMock.ExpectGet(_key).SetErr(redis.Nil)
 
 	// Act
-	_, err := _client.Get(_key)
+	_, err := _client.Get(_cacheContext, _key)
 
 	// Test
 	suite.NotNil(err)
@@ -71,7 +72,7 @@ func (suite *TestSuiteRedisCache) Test_Set_NewKey_ShouldReturnNil() {
 	_redisMock.ExpectSet(_key, _value, duration).RedisNil()
 
 	// Act
-	err := _client.Set(_key, _value, duration)
+	err := _client.Set(_cacheContext, _key, _value, duration)
 	suite.Nil(err)
 }
 
@@ -81,7 +82,7 @@ func (suite *TestSuiteRedisCache) Test_Set_NegativeExpiration_ShouldReturnErr()
 	_redisMock.ExpectSet(_key, _value, duration).SetVal(_value)
 
 	// Act
-	err := _client.Set(_key, _value, duration)
+	err := _client.Set(_cacheContext, _key, _value, duration)
 	suite.IsType(&NegativeExpirationCacheError{}, err)
 }
 
@@ -90,7 +91,7 @@ func (suite *TestSuiteRedisCache) Test_Ping_Pong() {
 	_redisMock.ExpectPing().SetVal(_expectedPingResult)
 
 	// Act
-	err := _client.Ping()
+	err := _client.Ping(_cacheContext)
 	suite.Nil(err)
 }
 
@@ -99,7 +100,7 @@ func (suite *TestSuiteRedisCache) Test_Ping_NotPong() {
 	_redisMock.ExpectPing().RedisNil()
 
 	// Act
-	err := _client.Ping()
+	err := _client.Ping(_cacheContext)
 	suite.IsType(redis.Nil, err)
 }
 
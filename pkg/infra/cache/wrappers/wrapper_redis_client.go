@@ -23,6 +23,13 @@ type IRedisBaseClientWrapper interface {
 	// returns redis.Nil error if unsuccessfully received a pong from the server.
 	// returns ('PONG', nil) if successfully received a pong from the server
 	Ping(ctx context.Context) *redis.StatusCmd
+
+	// SetNX Redis `SET key value [expiration] NX` command - sets the key only if it doesn't exist.
+	// Returns true if the key was set.
+	SetNX(ctx context.Context, key string, value interface{}, expiration time.Duration) *redis.BoolCmd
+
+	// Eval Redis `EVAL script numkeys [key ...] [arg ...]` command - runs the lua script atomically.
+	Eval(ctx context.Context, script string, keys []string, args ...interface{}) *redis.Cmd
 }
 
 // RedisCacheClientConfiguration redis cache client configuration
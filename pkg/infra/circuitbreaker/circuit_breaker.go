@@ -0,0 +1,339 @@
+package circuitbreaker
+
+import (
+	"context"
+	circuitbreakermetric "github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/circuitbreaker/metric"
+	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/instrumentation"
+	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/instrumentation/metric"
+	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/instrumentation/trace"
+	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/utils"
+	"github.com/pkg/errors"
+	"sync"
+	"time"
+)
+
+const (
+	// _defaultFailureRateThresholdPercentage is the failure rate that opens the circuit in case that no threshold is configured
+	_defaultFailureRateThresholdPercentage = 50
+	// _defaultMinimumNumberOfCalls is the minimum number of calls in a window before the circuit can be opened in case that no minimum is configured
+	_defaultMinimumNumberOfCalls = 10
+	// _defaultWindowDuration is the duration of the window that the failure rate is calculated on in case that no duration is configured
+	_defaultWindowDuration = 10 * time.Second
+	// _defaultOpenDuration is the duration that the circuit stays open before trial calls are allowed in case that no duration is configured
+	_defaultOpenDuration = 5 * time.Second
+	// _defaultHalfOpenMaxCalls is the number of trial calls in half open state in case that no number is configured
+	_defaultHalfOpenMaxCalls = 1
+	// _defaultMaxCircuits is the maximal number of circuits (keys) that are held in case that no maximum is configured
+	_defaultMaxCircuits = 1000
+	// _defaultIdleCircuitExpiration is the duration without calls after which a circuit is removed in case that no duration is configured
+	_defaultIdleCircuitExpiration = 10 * time.Minute
+)
+
+// ICircuitBreaker stops calling a dependency (ARG, registry, redis...) while it's failing, so the callers fail fast
+// instead of waiting (and retrying) until their timeout.
+type ICircuitBreaker interface {
+	// Execute executes the action in case that the circuit of the key isn't open, and records its result.
+	// Returns CircuitIsOpenErr without executing the action in case that the circuit is open.
+	// isFailure returns true if the action's error is a failure of the dependency (e.g. not found errors aren't failures).
+	// Errors of canceled contexts or passed deadlines of the caller aren't recorded as failures nor as successes.
+	Execute(key string, action func() (interface{}, error), isFailure func(error) bool) (interface{}, error)
+	// GetState returns the state of the circuit of the key
+	GetState(key string) State
+}
+
+// CircuitBreaker implements ICircuitBreaker interface
+var _ ICircuitBreaker = (*CircuitBreaker)(nil)
+
+// CircuitBreaker is ICircuitBreaker that holds a circuit for each key (e.g. a circuit for each registry), so failures
+// of one key don't affect the calls of the other keys.
+// Each circuit is closed (calls are executed) until the failure rate in a window of calls is above the threshold,
+// then it's open (calls are rejected) for the open duration, then it's half open (limited number of trial calls are executed) -
+// if all the trial calls succeed the circuit is closed, otherwise it's opened again.
+type CircuitBreaker struct {
+	//tracerProvider is tracer provider of CircuitBreaker
+	tracerProvider trace.ITracerProvider
+	//metricSubmitter is metric submitter of CircuitBreaker
+	metricSubmitter metric.IMetricSubmitter
+	// name is the name of the dependency that the circuit breaker protects - used as dimension of the circuit breaker metrics
+	name string
+	// failureRateThresholdPercentage is the failure rate (percentage) that opens the circuit
+	failureRateThresholdPercentage int
+	// minimumNumberOfCalls is the minimum number of calls in a window before the circuit can be opened
+	minimumNumberOfCalls int
+	// windowDuration is the duration of the window that the failure rate is calculated on
+	windowDuration time.Duration
+	// openDuration is the duration that the circuit stays open before trial calls are allowed
+	openDuration time.Duration
+	// halfOpenMaxCalls is the number of trial calls in half open state
+	halfOpenMaxCalls int
+	// maxCircuits is the maximal number of circuits that are held - the least recently used circuit is removed when a new key exceeds it
+	maxCircuits int
+	// idleCircuitExpiration is the duration without calls after which a circuit is removed
+	idleCircuitExpiration time.Duration
+	// circuits maps key to its circuit
+	circuits map[string]*circuit
+	// lastExpiration is the last time that the idle circuits were removed
+	lastExpiration time.Time
+	// lock protects circuits and their fields
+	lock sync.Mutex
+	// now returns the current time
+	now func() time.Time
+}
+
+// CircuitBreakerConfiguration is configuration data for CircuitBreaker
+type CircuitBreakerConfiguration struct {
+	// Enabled - calls of the dependency are rejected while it's failing. If false - all the calls are executed.
+	Enabled bool
+	// FailureRateThresholdPercentage is the failure rate (percentage of the calls in the window) that opens the circuit
+	FailureRateThresholdPercentage int
+	// MinimumNumberOfCalls is the minimum number of calls in a window before the circuit can be opened
+	MinimumNumberOfCalls int
+	// WindowDurationInMS is the duration **IN MILLISECONDS** of the window that the failure rate is calculated on
+	WindowDurationInMS int
+	// OpenDurationInMS is the duration **IN MILLISECONDS** that the circuit stays open before trial calls are allowed
+	OpenDurationInMS int
+	// HalfOpenMaxCalls is the number of trial calls in half open state - all of them should succeed to close the circuit
+	HalfOpenMaxCalls int
+	// MaxCircuits is the maximal number of circuits (keys, e.g. registries) that are held - the least recently used circuit is removed
+	// when a new key exceeds it
+	MaxCircuits int
+	// IdleCircuitExpirationInMS is the duration **IN MILLISECONDS** without calls after which a circuit is removed
+	IdleCircuitExpirationInMS int
+}
+
+// circuit is the state of a single key of the circuit breaker
+type circuit struct {
+	// state is the state of the circuit
+	state State
+	// windowStart is the start time of the current window (closed state)
+	windowStart time.Time
+	// calls is the number of recorded calls in the current window (closed state)
+	calls int
+	// failures is the number of recorded failures in the current window (closed state)
+	failures int
+	// openedAt is the time that the circuit was opened (open state)
+	openedAt time.Time
+	// trialCalls is the number of trial calls that were executed (half open state)
+	trialCalls int
+	// trialSuccesses is the number of trial calls that succeeded (half open state)
+	trialSuccesses int
+	// lastUsed is the last time that a call of the circuit was allowed or recorded
+	lastUsed time.Time
+}
+
+// NewCircuitBreaker Ctor for CircuitBreaker
+func NewCircuitBreaker(instrumentationProvider instrumentation.IInstrumentationProvider, configuration *CircuitBreakerConfiguration, name string) *CircuitBreaker {
+	// In case that the thresholds aren't configured (zero) - use default values.
+	circuitBreaker := &CircuitBreaker{
+		tracerProvider:                 instrumentationProvider.GetTracerProvider("CircuitBreaker"),
+		metricSubmitter:                instrumentationProvider.GetMetricSubmitter(),
+		name:                           name,
+		failureRateThresholdPercentage: _defaultFailureRateThresholdPercentage,
+		minimumNumberOfCalls:           _defaultMinimumNumberOfCalls,
+		windowDuration:                 _defaultWindowDuration,
+		openDuration:                   _defaultOpenDuration,
+		halfOpenMaxCalls:               _defaultHalfOpenMaxCalls,
+		maxCircuits:                    _defaultMaxCircuits,
+		idleCircuitExpiration:          _defaultIdleCircuitExpiration,
+		circuits:                       make(map[string]*circuit),
+		now:                            time.Now,
+	}
+	if configuration.FailureRateThresholdPercentage > 0 {
+		circuitBreaker.failureRateThresholdPercentage = configuration.FailureRateThresholdPercentage
+	}
+	if configuration.MinimumNumberOfCalls > 0 {
+		circuitBreaker.minimumNumberOfCalls = configuration.MinimumNumberOfCalls
+	}
+	if configuration.WindowDurationInMS > 0 {
+		circuitBreaker.windowDuration = utils.GetMilliseconds(configuration.WindowDurationInMS)
+	}
+	if configuration.OpenDurationInMS > 0 {
+		circuitBreaker.openDuration = utils.GetMilliseconds(configuration.OpenDurationInMS)
+	}
+	if configuration.HalfOpenMaxCalls > 0 {
+		circuitBreaker.halfOpenMaxCalls = configuration.HalfOpenMaxCalls
+	}
+	if configuration.MaxCircuits > 0 {
+		circuitBreaker.maxCircuits = configuration.MaxCircuits
+	}
+	if configuration.IdleCircuitExpirationInMS > 0 {
+		circuitBreaker.idleCircuitExpiration = utils.GetMilliseconds(configuration.IdleCircuitExpirationInMS)
+	}
+	circuitBreaker.lastExpiration = circuitBreaker.now()
+	return circuitBreaker
+}
+
+// Execute executes the action in case that the circuit of the key isn't open, and records its result.
+func (circuitBreaker *CircuitBreaker) Execute(key string, action func() (interface{}, error), isFailure func(error) bool) (interface{}, error) {
+	tracer := circuitBreaker.tracerProvider.GetTracer("Execute")
+	if action == nil || isFailure == nil {
+		err := errors.Wrap(utils.NilArgumentError, "action and isFailure can't be nil")
+		tracer.Error(err, "")
+		return nil, err
+	}
+
+	if !circuitBreaker.allow(key) {
+		err := NewCircuitIsOpenErr(circuitBreaker.name, key)
+		tracer.Info("Call rejected - circuit is open", "name", circuitBreaker.name, "key", key)
+		circuitBreaker.metricSubmitter.SendMetric(1, circuitbreakermetric.NewCircuitBreakerRejectedCallMetric(circuitBreaker.name))
+		return nil, err
+	}
+
+	value, err := action()
+	switch {
+	case err == nil:
+		circuitBreaker.record(key, false)
+	case errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded):
+		// The caller gave up or ran out of time - it says nothing about the dependency.
+		circuitBreaker.release(key)
+	default:
+		circuitBreaker.record(key, isFailure(err))
+	}
+	return value, err
+}
+
+// GetState returns the state of the circuit of the key
+func (circuitBreaker *CircuitBreaker) GetState(key string) State {
+	circuitBreaker.lock.Lock()
+	defer circuitBreaker.lock.Unlock()
+	c := circuitBreaker.getCircuit(key)
+	circuitBreaker.submitState(key, c)
+	return c.state
+}
+
+// allow returns true if a call of the key can be executed. Moves open circuit that its open duration is passed to half open.
+func (circuitBreaker *CircuitBreaker) allow(key string) bool {
+	circuitBreaker.lock.Lock()
+	defer circuitBreaker.lock.Unlock()
+	c := circuitBreaker.getCircuit(key)
+	c.lastUsed = circuitBreaker.now()
+	defer circuitBreaker.submitState(key, c)
+
+	if c.state == OpenState {
+		if circuitBreaker.now().Sub(c.openedAt) < circuitBreaker.openDuration {
+			return false
+		}
+		circuitBreaker.transition(key, c, HalfOpenState)
+	}
+
+	if c.state == HalfOpenState {
+		if c.trialCalls >= circuitBreaker.halfOpenMaxCalls {
+			return false
+		}
+		c.trialCalls++
+	}
+	return true
+}
+
+// record records the result of an executed call of the key and updates the state of its circuit
+func (circuitBreaker *CircuitBreaker) record(key string, isFailure bool) {
+	circuitBreaker.lock.Lock()
+	defer circuitBreaker.lock.Unlock()
+	c := circuitBreaker.getCircuit(key)
+	c.lastUsed = circuitBreaker.now()
+	defer circuitBreaker.submitState(key, c)
+
+	switch c.state {
+	case HalfOpenState:
+		if isFailure {
+			circuitBreaker.transition(key, c, OpenState)
+			return
+		}
+		c.trialSuccesses++
+		if c.trialSuccesses >= circuitBreaker.halfOpenMaxCalls {
+			circuitBreaker.transition(key, c, ClosedState)
+		}
+	case ClosedState:
+		now := circuitBreaker.now()
+		if now.Sub(c.windowStart) >= circuitBreaker.windowDuration {
+			c.windowStart, c.calls, c.failures = now, 0, 0
+		}
+		c.calls++
+		if isFailure {
+			c.failures++
+		}
+		if c.calls >= circuitBreaker.minimumNumberOfCalls && c.failures*100 >= circuitBreaker.failureRateThresholdPercentage*c.calls {
+			circuitBreaker.transition(key, c, OpenState)
+		}
+	default:
+		// The circuit was opened by another call while this call was executed - nothing to record.
+	}
+}
+
+// release releases a call of the key that its result isn't recorded, so another trial call can be executed in half open state
+func (circuitBreaker *CircuitBreaker) release(key string) {
+	circuitBreaker.lock.Lock()
+	defer circuitBreaker.lock.Unlock()
+	c := circuitBreaker.getCircuit(key)
+	if c.state == HalfOpenState && c.trialCalls > 0 {
+		c.trialCalls--
+	}
+}
+
+// transition moves the circuit of the key to the state, resets the counters of the state and submits the state as metric.
+// Should be called while holding the lock.
+func (circuitBreaker *CircuitBreaker) transition(key string, c *circuit, state State) {
+	tracer := circuitBreaker.tracerProvider.GetTracer("transition")
+	tracer.Info("Circuit state changed", "name", circuitBreaker.name, "key", key, "from", c.state, "to", state, "calls", c.calls, "failures", c.failures)
+
+	now := circuitBreaker.now()
+	c.state = state
+	c.windowStart, c.calls, c.failures = now, 0, 0
+	c.trialCalls, c.trialSuccesses = 0, 0
+	if state == OpenState {
+		c.openedAt = now
+	}
+	circuitBreaker.metricSubmitter.SendMetric(1, circuitbreakermetric.NewCircuitBreakerStateMetric(circuitBreaker.name, string(state)))
+}
+
+// submitState submits the current state of the circuit of the key as gauge metric.
+// Should be called while holding the lock.
+func (circuitBreaker *CircuitBreaker) submitState(key string, c *circuit) {
+	circuitBreaker.metricSubmitter.SendMetric(stateGaugeValues[c.state], circuitbreakermetric.NewCircuitBreakerCurrentStateMetric(circuitBreaker.name, key))
+}
+
+// getCircuit returns the circuit of the key - creates closed circuit if it doesn't exist.
+// Before a circuit is created, the idle circuits are removed and in case that there are still maxCircuits circuits - the least
+// recently used circuit is removed, so the circuits of keys that aren't called anymore don't accumulate.
+// Should be called while holding the lock.
+func (circuitBreaker *CircuitBreaker) getCircuit(key string) *circuit {
+	c, exists := circuitBreaker.circuits[key]
+	if !exists {
+		now := circuitBreaker.now()
+		if now.Sub(circuitBreaker.lastExpiration) >= circuitBreaker.idleCircuitExpiration {
+			circuitBreaker.removeIdleCircuits(now)
+		}
+		if len(circuitBreaker.circuits) >= circuitBreaker.maxCircuits {
+			circuitBreaker.removeLeastRecentlyUsedCircuit()
+		}
+		c = &circuit{state: ClosedState, windowStart: now, lastUsed: now}
+		circuitBreaker.circuits[key] = c
+	}
+	return c
+}
+
+// removeIdleCircuits removes the circuits that weren't used during the idle circuit expiration.
+// Should be called while holding the lock.
+func (circuitBreaker *CircuitBreaker) removeIdleCircuits(now time.Time) {
+	for key, c := range circuitBreaker.circuits {
+		if now.Sub(c.lastUsed) >= circuitBreaker.idleCircuitExpiration {
+			delete(circuitBreaker.circuits, key)
+		}
+	}
+	circuitBreaker.lastExpiration = now
+}
+
+// removeLeastRecentlyUsedCircuit removes the circuit that its last use is the oldest.
+// Should be called while holding the lock.
+func (circuitBreaker *CircuitBreaker) removeLeastRecentlyUsedCircuit() {
+	var leastRecentlyUsedKey string
+	var leastRecentlyUsed *circuit
+	for key, c := range circuitBreaker.circuits {
+		if leastRecentlyUsed == nil || c.lastUsed.Before(leastRecentlyUsed.lastUsed) {
+			leastRecentlyUsedKey, leastRecentlyUsed = key, c
+		}
+	}
+	if leastRecentlyUsed != nil {
+		delete(circuitBreaker.circuits, leastRecentlyUsedKey)
+	}
+}
@@ -0,0 +1,249 @@
+package circuitbreaker
+
+import (
+	"context"
+	circuitbreakermetric "github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/circuitbreaker/metric"
+	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/instrumentation"
+	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/instrumentation/metric/mocks"
+	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/utils"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/suite"
+	"testing"
+	"time"
+)
+
+const (
+	_key      = "tomer.azurecr.io"
+	_otherKey = "other.azurecr.io"
+)
+
+var (
+	_errForTests    = errors.New("dependency is down")
+	_configuration  = &CircuitBreakerConfiguration{Enabled: true, FailureRateThresholdPercentage: 50, MinimumNumberOfCalls: 4, WindowDurationInMS: 1000, OpenDurationInMS: 500, HalfOpenMaxCalls: 2}
+	_alwaysFailure  = func(error) bool { return true }
+	_successAction  = func() (interface{}, error) { return "value", nil }
+	_failureAction  = func() (interface{}, error) { return nil, _errForTests }
+	_canceledAction = func() (interface{}, error) { return nil, errors.Wrap(context.Canceled, "canceled") }
+	_deadlineAction = func() (interface{}, error) { return nil, errors.Wrap(context.DeadlineExceeded, "deadline") }
+)
+
+type CircuitBreakerTestSuite struct {
+	suite.Suite
+	circuitBreaker *CircuitBreaker
+	now            time.Time
+	countActions   int
+}
+
+func (suite *CircuitBreakerTestSuite) SetupTest() {
+	suite.circuitBreaker = NewCircuitBreaker(instrumentation.NewNoOpInstrumentationProvider(), _configuration, "Dependency")
+	suite.now = time.Now()
+	suite.circuitBreaker.now = func() time.Time { return suite.now }
+	suite.countActions = 0
+}
+
+func (suite *CircuitBreakerTestSuite) Test_NewCircuitBreaker_ZeroConfiguration_ShouldUseDefaults() {
+	circuitBreaker := NewCircuitBreaker(instrumentation.NewNoOpInstrumentationProvider(), &CircuitBreakerConfiguration{}, "Dependency")
+
+	suite.Equal(_defaultFailureRateThresholdPercentage, circuitBreaker.failureRateThresholdPercentage)
+	suite.Equal(_defaultMinimumNumberOfCalls, circuitBreaker.minimumNumberOfCalls)
+	suite.Equal(_defaultWindowDuration, circuitBreaker.windowDuration)
+	suite.Equal(_defaultOpenDuration, circuitBreaker.openDuration)
+	suite.Equal(_defaultHalfOpenMaxCalls, circuitBreaker.halfOpenMaxCalls)
+	suite.Equal(_defaultMaxCircuits, circuitBreaker.maxCircuits)
+	suite.Equal(_defaultIdleCircuitExpiration, circuitBreaker.idleCircuitExpiration)
+}
+
+func (suite *CircuitBreakerTestSuite) Test_Execute_NilAction_ShouldReturnError() {
+	value, err := suite.circuitBreaker.Execute(_key, nil, _alwaysFailure)
+
+	suite.Equal(utils.NilArgumentError, errors.Cause(err))
+	suite.Nil(value)
+}
+
+func (suite *CircuitBreakerTestSuite) Test_Execute_Success_ShouldReturnValueAndStayClosed() {
+	value, err := suite.circuitBreaker.Execute(_key, _successAction, _alwaysFailure)
+
+	suite.Nil(err)
+	suite.Equal("value", value)
+	suite.Equal(ClosedState, suite.circuitBreaker.GetState(_key))
+}
+
+func (suite *CircuitBreakerTestSuite) Test_Execute_FailureRateBelowThreshold_ShouldStayClosed() {
+	suite.executeMany(_successAction, 3)
+	suite.executeMany(_failureAction, 1)
+
+	suite.Equal(ClosedState, suite.circuitBreaker.GetState(_key))
+}
+
+func (suite *CircuitBreakerTestSuite) Test_Execute_FailuresBelowMinimumNumberOfCalls_ShouldStayClosed() {
+	suite.executeMany(_failureAction, 3)
+
+	suite.Equal(ClosedState, suite.circuitBreaker.GetState(_key))
+}
+
+func (suite *CircuitBreakerTestSuite) Test_Execute_FailureRateAboveThreshold_ShouldOpenAndRejectCalls() {
+	suite.executeMany(_successAction, 2)
+	suite.executeMany(_failureAction, 2)
+	suite.Equal(OpenState, suite.circuitBreaker.GetState(_key))
+
+	value, err := suite.circuitBreaker.Execute(_key, suite.countingAction(_successAction), _alwaysFailure)
+
+	suite.Nil(value)
+	suite.IsType(&CircuitIsOpenErr{}, err)
+	suite.Equal(0, suite.countActions)
+}
+
+func (suite *CircuitBreakerTestSuite) Test_Execute_OpenCircuit_ShouldNotAffectOtherKeys() {
+	suite.executeMany(_failureAction, 4)
+
+	value, err := suite.circuitBreaker.Execute(_otherKey, _successAction, _alwaysFailure)
+
+	suite.Nil(err)
+	suite.Equal("value", value)
+	suite.Equal(OpenState, suite.circuitBreaker.GetState(_key))
+	suite.Equal(ClosedState, suite.circuitBreaker.GetState(_otherKey))
+}
+
+func (suite *CircuitBreakerTestSuite) Test_Execute_NotFailureErrors_ShouldStayClosed() {
+	for i := 0; i < 4; i++ {
+		_, err := suite.circuitBreaker.Execute(_key, _failureAction, func(error) bool { return false })
+		suite.Equal(_errForTests, err)
+	}
+
+	suite.Equal(ClosedState, suite.circuitBreaker.GetState(_key))
+}
+
+func (suite *CircuitBreakerTestSuite) Test_Execute_CanceledErrors_ShouldNotBeRecorded() {
+	suite.executeMany(_canceledAction, 4)
+	suite.Equal(ClosedState, suite.circuitBreaker.GetState(_key))
+
+	suite.executeMany(_failureAction, 3)
+	suite.Equal(ClosedState, suite.circuitBreaker.GetState(_key))
+}
+
+func (suite *CircuitBreakerTestSuite) Test_Execute_DeadlineExceededErrors_ShouldNotBeRecorded() {
+	suite.executeMany(_deadlineAction, 4)
+	suite.Equal(ClosedState, suite.circuitBreaker.GetState(_key))
+
+	suite.executeMany(_failureAction, 3)
+	suite.Equal(ClosedState, suite.circuitBreaker.GetState(_key))
+}
+
+func (suite *CircuitBreakerTestSuite) Test_Execute_OpenCircuit_ShouldSubmitCurrentStateOnEachRead() {
+	suite.executeMany(_failureAction, 4)
+	metricSubmitterMock := new(mocks.IMetricSubmitter)
+	metricSubmitterMock.On("SendMetric", stateGaugeValues[OpenState], circuitbreakermetric.NewCircuitBreakerCurrentStateMetric("Dependency", _key)).Times(4)
+	metricSubmitterMock.On("SendMetric", 1, circuitbreakermetric.NewCircuitBreakerRejectedCallMetric("Dependency")).Times(3)
+	suite.circuitBreaker.metricSubmitter = metricSubmitterMock
+
+	suite.executeMany(_successAction, 3)
+	suite.Equal(OpenState, suite.circuitBreaker.GetState(_key))
+
+	metricSubmitterMock.AssertExpectations(suite.T())
+}
+
+func (suite *CircuitBreakerTestSuite) Test_Execute_IdleCircuit_ShouldBeRemovedWhenNewKeyIsAdded() {
+	suite.executeMany(_failureAction, 4)
+	suite.Equal(OpenState, suite.circuitBreaker.GetState(_key))
+
+	suite.now = suite.now.Add(_defaultIdleCircuitExpiration)
+	_, _ = suite.circuitBreaker.Execute(_otherKey, _successAction, _alwaysFailure)
+
+	suite.NotContains(suite.circuitBreaker.circuits, _key)
+	suite.Contains(suite.circuitBreaker.circuits, _otherKey)
+}
+
+func (suite *CircuitBreakerTestSuite) Test_Execute_MaxCircuitsReached_ShouldRemoveLeastRecentlyUsedCircuit() {
+	configuration := *_configuration
+	configuration.MaxCircuits = 2
+	suite.circuitBreaker = NewCircuitBreaker(instrumentation.NewNoOpInstrumentationProvider(), &configuration, "Dependency")
+	suite.circuitBreaker.now = func() time.Time { return suite.now }
+
+	for _, key := range []string{_key, _otherKey, _key, "third.azurecr.io"} {
+		suite.now = suite.now.Add(time.Millisecond)
+		_, _ = suite.circuitBreaker.Execute(key, _successAction, _alwaysFailure)
+	}
+
+	suite.Len(suite.circuitBreaker.circuits, 2)
+	suite.Contains(suite.circuitBreaker.circuits, _key)
+	suite.Contains(suite.circuitBreaker.circuits, "third.azurecr.io")
+}
+
+func (suite *CircuitBreakerTestSuite) Test_Execute_WindowPassed_ShouldResetCounters() {
+	suite.executeMany(_failureAction, 3)
+	suite.now = suite.now.Add(time.Second)
+	suite.executeMany(_successAction, 1)
+
+	suite.Equal(ClosedState, suite.circuitBreaker.GetState(_key))
+	suite.Equal(1, suite.circuitBreaker.circuits[_key].calls)
+	suite.Equal(0, suite.circuitBreaker.circuits[_key].failures)
+}
+
+func (suite *CircuitBreakerTestSuite) Test_Execute_OpenDurationPassedAndTrialCallsSucceed_ShouldClose() {
+	suite.executeMany(_failureAction, 4)
+	suite.now = suite.now.Add(500 * time.Millisecond)
+
+	suite.executeMany(_successAction, 1)
+	suite.Equal(HalfOpenState, suite.circuitBreaker.GetState(_key))
+	suite.executeMany(_successAction, 1)
+
+	suite.Equal(ClosedState, suite.circuitBreaker.GetState(_key))
+}
+
+func (suite *CircuitBreakerTestSuite) Test_Execute_OpenDurationPassedAndTrialCallFails_ShouldOpenAgain() {
+	suite.executeMany(_failureAction, 4)
+	suite.now = suite.now.Add(500 * time.Millisecond)
+
+	suite.executeMany(_failureAction, 1)
+
+	suite.Equal(OpenState, suite.circuitBreaker.GetState(_key))
+	_, err := suite.circuitBreaker.Execute(_key, _successAction, _alwaysFailure)
+	suite.IsType(&CircuitIsOpenErr{}, err)
+}
+
+func (suite *CircuitBreakerTestSuite) Test_Execute_HalfOpenMaxCallsInFlight_ShouldRejectOtherCalls() {
+	suite.executeMany(_failureAction, 4)
+	suite.now = suite.now.Add(500 * time.Millisecond)
+
+	// The trial calls are in flight while the next call is executed
+	var err error
+	_, _ = suite.circuitBreaker.Execute(_key, func() (interface{}, error) {
+		_, _ = suite.circuitBreaker.Execute(_key, func() (interface{}, error) {
+			_, err = suite.circuitBreaker.Execute(_key, _successAction, _alwaysFailure)
+			return nil, nil
+		}, _alwaysFailure)
+		return nil, nil
+	}, _alwaysFailure)
+
+	suite.IsType(&CircuitIsOpenErr{}, err)
+	suite.Equal(ClosedState, suite.circuitBreaker.GetState(_key))
+}
+
+func (suite *CircuitBreakerTestSuite) Test_NoOpCircuitBreaker_Execute_ShouldAlwaysExecute() {
+	circuitBreaker := NewNoOpCircuitBreaker()
+	for i := 0; i < 20; i++ {
+		_, _ = circuitBreaker.Execute(_key, suite.countingAction(_failureAction), _alwaysFailure)
+	}
+
+	suite.Equal(20, suite.countActions)
+	suite.Equal(ClosedState, circuitBreaker.GetState(_key))
+}
+
+// executeMany executes the action count times with the key
+func (suite *CircuitBreakerTestSuite) executeMany(action func() (interface{}, error), count int) {
+	for i := 0; i < count; i++ {
+		_, _ = suite.circuitBreaker.Execute(_key, action, _alwaysFailure)
+	}
+}
+
+// countingAction returns action that counts its executions and executes the given action
+func (suite *CircuitBreakerTestSuite) countingAction(action func() (interface{}, error)) func() (interface{}, error) {
+	return func() (interface{}, error) {
+		suite.countActions++
+		return action()
+	}
+}
+
+func TestCircuitBreakerTestSuite(t *testing.T) {
+	suite.Run(t, new(CircuitBreakerTestSuite))
+}
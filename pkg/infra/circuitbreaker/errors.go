@@ -0,0 +1,25 @@
+package circuitbreaker
+
+import (
+	"fmt"
+)
+
+// CircuitIsOpenErr implements errors.error interface
+var _ error = (*CircuitIsOpenErr)(nil)
+
+// CircuitIsOpenErr is error that returns when a call is rejected because the circuit of the dependency is open
+type CircuitIsOpenErr struct {
+	// name is the name of the dependency
+	name string
+	// key is the key of the circuit (e.g. registry)
+	key string
+}
+
+// NewCircuitIsOpenErr Constructor for CircuitIsOpenErr
+func NewCircuitIsOpenErr(name string, key string) *CircuitIsOpenErr {
+	return &CircuitIsOpenErr{name: name, key: key}
+}
+
+func (err *CircuitIsOpenErr) Error() string {
+	return fmt.Sprintf("circuit of <%s> is open for key <%s> - call rejected", err.name, err.key)
+}
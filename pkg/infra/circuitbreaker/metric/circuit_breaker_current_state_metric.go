@@ -0,0 +1,36 @@
+package metric
+
+import (
+	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/instrumentation/metric"
+)
+
+// CircuitBreakerCurrentStateMetric implements metric.IMetric interface
+var _ metric.IMetric = (*CircuitBreakerCurrentStateMetric)(nil)
+
+// CircuitBreakerCurrentStateMetric is metric of the current state of a circuit - submitted as gauge whenever the state is read,
+// its value is the state (0 - closed, 1 - half open, 2 - open).
+type CircuitBreakerCurrentStateMetric struct {
+	// name is the name of the dependency that the circuit breaker protects - e.g. ARGClient, CraneWrapper
+	name string
+	// key is the key of the circuit - e.g. registry
+	key string
+}
+
+// NewCircuitBreakerCurrentStateMetric Ctor for CircuitBreakerCurrentStateMetric
+func NewCircuitBreakerCurrentStateMetric(name string, key string) *CircuitBreakerCurrentStateMetric {
+	return &CircuitBreakerCurrentStateMetric{
+		name: name,
+		key:  key,
+	}
+}
+
+func (m *CircuitBreakerCurrentStateMetric) MetricName() string {
+	return "CircuitBreakerCurrentState"
+}
+
+func (m *CircuitBreakerCurrentStateMetric) MetricDimension() []metric.Dimension {
+	return []metric.Dimension{
+		{Key: "Name", Value: m.name},
+		{Key: "Key", Value: m.key},
+	}
+}
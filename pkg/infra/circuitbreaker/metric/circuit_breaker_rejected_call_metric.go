@@ -0,0 +1,31 @@
+package metric
+
+import (
+	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/instrumentation/metric"
+)
+
+// CircuitBreakerRejectedCallMetric implements metric.IMetric interface
+var _ metric.IMetric = (*CircuitBreakerRejectedCallMetric)(nil)
+
+// CircuitBreakerRejectedCallMetric is metric that counts the calls that were rejected because their circuit is open
+type CircuitBreakerRejectedCallMetric struct {
+	// name is the name of the dependency that the circuit breaker protects - e.g. ARGClient, CraneWrapper
+	name string
+}
+
+// NewCircuitBreakerRejectedCallMetric Ctor for CircuitBreakerRejectedCallMetric
+func NewCircuitBreakerRejectedCallMetric(name string) *CircuitBreakerRejectedCallMetric {
+	return &CircuitBreakerRejectedCallMetric{
+		name: name,
+	}
+}
+
+func (m *CircuitBreakerRejectedCallMetric) MetricName() string {
+	return "CircuitBreakerRejectedCall"
+}
+
+func (m *CircuitBreakerRejectedCallMetric) MetricDimension() []metric.Dimension {
+	return []metric.Dimension{
+		{Key: "Name", Value: m.name},
+	}
+}
@@ -0,0 +1,35 @@
+package metric
+
+import (
+	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/instrumentation/metric"
+)
+
+// CircuitBreakerStateMetric implements metric.IMetric interface
+var _ metric.IMetric = (*CircuitBreakerStateMetric)(nil)
+
+// CircuitBreakerStateMetric is metric of the circuit breaker's states - counts the transitions of the circuits to each state
+type CircuitBreakerStateMetric struct {
+	// name is the name of the dependency that the circuit breaker protects - e.g. ARGClient, CraneWrapper
+	name string
+	// state is the state that the circuit moved to
+	state string
+}
+
+// NewCircuitBreakerStateMetric Ctor for CircuitBreakerStateMetric
+func NewCircuitBreakerStateMetric(name string, state string) *CircuitBreakerStateMetric {
+	return &CircuitBreakerStateMetric{
+		name:  name,
+		state: state,
+	}
+}
+
+func (m *CircuitBreakerStateMetric) MetricName() string {
+	return "CircuitBreakerState"
+}
+
+func (m *CircuitBreakerStateMetric) MetricDimension() []metric.Dimension {
+	return []metric.Dimension{
+		{Key: "Name", Value: m.name},
+		{Key: "State", Value: m.state},
+	}
+}
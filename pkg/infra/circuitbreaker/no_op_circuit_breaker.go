@@ -0,0 +1,22 @@
+package circuitbreaker
+
+// NoOpCircuitBreaker implements ICircuitBreaker interface
+var _ ICircuitBreaker = (*NoOpCircuitBreaker)(nil)
+
+// NoOpCircuitBreaker is ICircuitBreaker that always executes the action - used when the circuit breaker is disabled
+type NoOpCircuitBreaker struct{}
+
+// NewNoOpCircuitBreaker Ctor for NoOpCircuitBreaker
+func NewNoOpCircuitBreaker() *NoOpCircuitBreaker {
+	return &NoOpCircuitBreaker{}
+}
+
+// Execute executes the action
+func (circuitBreaker *NoOpCircuitBreaker) Execute(key string, action func() (interface{}, error), isFailure func(error) bool) (interface{}, error) {
+	return action()
+}
+
+// GetState always returns ClosedState
+func (circuitBreaker *NoOpCircuitBreaker) GetState(key string) State {
+	return ClosedState
+}
@@ -0,0 +1,20 @@
+package circuitbreaker
+
+// State is the state of a circuit
+type State string
+
+const (
+	// ClosedState - the calls are executed and their results are recorded
+	ClosedState State = "Closed"
+	// OpenState - the calls are rejected without being executed
+	OpenState State = "Open"
+	// HalfOpenState - limited number of trial calls are executed in order to check if the dependency recovered
+	HalfOpenState State = "HalfOpen"
+)
+
+// stateGaugeValues maps each state to its value in the current state gauge - higher is worse
+var stateGaugeValues = map[State]int{
+	ClosedState:   0,
+	HalfOpenState: 1,
+	OpenState:     2,
+}
@@ -0,0 +1,125 @@
+package cloudenvironment
+
+import (
+	"strings"
+
+	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/azureauth/wrappers"
+	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/utils"
+	"github.com/Azure/go-autorest/autorest/azure"
+	"github.com/pkg/errors"
+)
+
+const (
+	// _dnsSuffixSeparator is the separator between the registry name and the container registry dns suffix (e.g. tomer.azurecr.io)
+	_dnsSuffixSeparator = "."
+	// _endpointSeparator is the trailing separator of the endpoints of azure.Environment (e.g. https://management.azure.com/)
+	_endpointSeparator = "/"
+)
+
+var (
+	// _singleton is the only instance of cloudEnvironment struct.
+	_singleton *cloudEnvironment = nil
+	// _defaultCloudEnvironment is the cloud environment in case that the singleton is not initialized - the public cloud.
+	_defaultCloudEnvironment = &cloudEnvironment{environment: azure.PublicCloud}
+)
+
+// cloudEnvironment is struct that helps to derive all the endpoints and suffixes of the azure cloud that the server runs in
+// (public, US government or china). In order to use it, you should use the singleton instance only.
+type cloudEnvironment struct {
+	// environment is the azure environment of the cloud
+	environment azure.Environment
+}
+
+// CloudEnvironmentConfiguration is cloud environment configuration
+type CloudEnvironmentConfiguration struct {
+	// Name is the name of the azure cloud environment - "AzurePublicCloud", "AzureUSGovernmentCloud" or "AzureChinaCloud" (case-insensitive).
+	// Empty name detects the cloud environment from the environment settings (AZURE_ENVIRONMENT environment variable, public cloud if not set).
+	Name string
+}
+
+// NewCloudEnvironment creates new cloud environment in case of singleton is not initialized yet.
+// returns error when there is already initialized instance or the cloud environment is unknown.
+func NewCloudEnvironment(configuration *CloudEnvironmentConfiguration, authWrapper wrappers.IAzureAuthWrapper) (*cloudEnvironment, error) {
+	if _singleton != nil {
+		return nil, errors.New("can't create another instance of CloudEnvironment")
+	} else if configuration == nil {
+		return nil, errors.Wrap(utils.NilArgumentError, "*CloudEnvironmentConfiguration can't be nil")
+	} else if authWrapper == nil {
+		return nil, errors.Wrap(utils.NilArgumentError, "IAzureAuthWrapper can't be nil")
+	}
+
+	environment, err := getEnvironment(configuration, authWrapper)
+	if err != nil {
+		return nil, err
+	}
+	_singleton = &cloudEnvironment{environment: environment}
+	return _singleton, nil
+}
+
+// GetCloudEnvironmentInstance returns the singleton instance of cloud environment.
+// in case that the cloud environment is not initialized, returns the public cloud environment.
+func GetCloudEnvironmentInstance() *cloudEnvironment {
+	if _singleton == nil {
+		return _defaultCloudEnvironment
+	}
+	return _singleton
+}
+
+// GetName returns the name of the azure cloud environment (e.g. AzurePublicCloud)
+func (c *cloudEnvironment) GetName() string {
+	return c.environment.Name
+}
+
+// GetEnvironment returns a copy of the azure environment of the cloud
+func (c *cloudEnvironment) GetEnvironment() azure.Environment {
+	return c.environment
+}
+
+// GetContainerRegistrySuffix returns the suffix of the ACR registries in the cloud (e.g. ".azurecr.io", ".azurecr.us", ".azurecr.cn")
+func (c *cloudEnvironment) GetContainerRegistrySuffix() string {
+	return _dnsSuffixSeparator + c.environment.ContainerRegistryDNSSuffix
+}
+
+// GetResourceGraphEndpoint returns the base uri of the Azure Resource Graph in the cloud (e.g. https://management.azure.com)
+func (c *cloudEnvironment) GetResourceGraphEndpoint() string {
+	return strings.TrimSuffix(c.environment.ResourceManagerEndpoint, _endpointSeparator)
+}
+
+// GetResourceManagerAudience returns the resource of the ARM tokens in the cloud (e.g. https://management.azure.com/)
+func (c *cloudEnvironment) GetResourceManagerAudience() string {
+	return c.environment.ResourceManagerEndpoint
+}
+
+// GetActiveDirectoryAuthority returns the login authority of the cloud (e.g. https://login.microsoftonline.com/)
+func (c *cloudEnvironment) GetActiveDirectoryAuthority() string {
+	return c.environment.ActiveDirectoryEndpoint
+}
+
+// UpdateCloudEnvironmentForTests is used for update the singleton for tests purpose.
+// ------ Shouldn't be used in production code ---------------
+func UpdateCloudEnvironmentForTests(environment azure.Environment) {
+	_singleton = &cloudEnvironment{environment: environment}
+}
+
+// ResetCloudEnvironmentForTests is used for reset the singleton (public cloud) for tests purpose.
+// ------ Shouldn't be used in production code ---------------
+func ResetCloudEnvironmentForTests() {
+	_singleton = nil
+}
+
+// getEnvironment returns the azure environment of the configured name, or of the environment settings if the name is empty.
+func getEnvironment(configuration *CloudEnvironmentConfiguration, authWrapper wrappers.IAzureAuthWrapper) (azure.Environment, error) {
+	if configuration.Name != "" {
+		environment, err := azure.EnvironmentFromName(configuration.Name)
+		if err != nil {
+			return azure.Environment{}, errors.Wrapf(utils.InvalidConfiguration, "unknown cloud environment %s: %s", configuration.Name, err.Error())
+		}
+		return environment, nil
+	}
+
+	settings, err := authWrapper.GetSettingsFromEnvironment()
+	if err != nil {
+		return azure.Environment{}, errors.Wrap(err, "error in GetSettingsFromEnvironment")
+	}
+	return *settings.GetEnvironment(), nil
+}
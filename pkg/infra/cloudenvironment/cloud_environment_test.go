@@ -0,0 +1,167 @@
+package cloudenvironment
+
+import (
+	"testing"
+
+	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/azureauth/wrappers/mocks"
+	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/utils"
+	"github.com/Azure/go-autorest/autorest/azure"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/suite"
+)
+
+type TestSuite struct {
+	suite.Suite
+	authWrapperMock *mocks.IAzureAuthWrapper
+}
+
+func (suite *TestSuite) SetupTest() {
+	ResetCloudEnvironmentForTests()
+	suite.authWrapperMock = &mocks.IAzureAuthWrapper{}
+}
+
+func (suite *TestSuite) TearDownTest() {
+	ResetCloudEnvironmentForTests()
+}
+
+func (suite *TestSuite) Test_NewCloudEnvironment_ConfiguredName_EndpointsOfCloud() {
+	tests := []struct {
+		name                             string
+		configuredName                   string
+		expectedName                     string
+		expectedContainerRegistrySuffix  string
+		expectedResourceGraphEndpoint    string
+		expectedResourceManagerAudience  string
+		expectedActiveDirectoryAuthority string
+	}{
+		{
+			name:                             "public",
+			configuredName:                   "AzurePublicCloud",
+			expectedName:                     "AzurePublicCloud",
+			expectedContainerRegistrySuffix:  ".azurecr.io",
+			expectedResourceGraphEndpoint:    "https://management.azure.com",
+			expectedResourceManagerAudience:  "https://management.azure.com/",
+			expectedActiveDirectoryAuthority: "https://login.microsoftonline.com/",
+		},
+		{
+			name:                             "usgovernment",
+			configuredName:                   "AzureUSGovernmentCloud",
+			expectedName:                     "AzureUSGovernmentCloud",
+			expectedContainerRegistrySuffix:  ".azurecr.us",
+			expectedResourceGraphEndpoint:    "https://management.usgovcloudapi.net",
+			expectedResourceManagerAudience:  "https://management.usgovcloudapi.net/",
+			expectedActiveDirectoryAuthority: "https://login.microsoftonline.us/",
+		},
+		{
+			name:                             "china",
+			configuredName:                   "AzureChinaCloud",
+			expectedName:                     "AzureChinaCloud",
+			expectedContainerRegistrySuffix:  ".azurecr.cn",
+			expectedResourceGraphEndpoint:    "https://management.chinacloudapi.cn",
+			expectedResourceManagerAudience:  "https://management.chinacloudapi.cn/",
+			expectedActiveDirectoryAuthority: "https://login.chinacloudapi.cn/",
+		},
+		{
+			name:                             "case insensitive name",
+			configuredName:                   "azureusgovernmentcloud",
+			expectedName:                     "AzureUSGovernmentCloud",
+			expectedContainerRegistrySuffix:  ".azurecr.us",
+			expectedResourceGraphEndpoint:    "https://management.usgovcloudapi.net",
+			expectedResourceManagerAudience:  "https://management.usgovcloudapi.net/",
+			expectedActiveDirectoryAuthority: "https://login.microsoftonline.us/",
+		},
+	}
+	for _, tt := range tests {
+		suite.Run(tt.name, func() {
+			ResetCloudEnvironmentForTests()
+
+			cloudEnvironment, err := NewCloudEnvironment(&CloudEnvironmentConfiguration{Name: tt.configuredName}, suite.authWrapperMock)
+
+			suite.Nil(err)
+			suite.Equal(cloudEnvironment, GetCloudEnvironmentInstance())
+			suite.Equal(tt.expectedName, cloudEnvironment.GetName())
+			suite.Equal(tt.expectedContainerRegistrySuffix, cloudEnvironment.GetContainerRegistrySuffix())
+			suite.Equal(tt.expectedResourceGraphEndpoint, cloudEnvironment.GetResourceGraphEndpoint())
+			suite.Equal(tt.expectedResourceManagerAudience, cloudEnvironment.GetResourceManagerAudience())
+			suite.Equal(tt.expectedActiveDirectoryAuthority, cloudEnvironment.GetActiveDirectoryAuthority())
+		})
+	}
+	suite.authWrapperMock.AssertNotCalled(suite.T(), "GetSettingsFromEnvironment")
+}
+
+func (suite *TestSuite) Test_NewCloudEnvironment_EmptyName_DetectedFromEnvironmentSettings() {
+	tests := []struct {
+		name        string
+		environment azure.Environment
+	}{
+		{name: "public", environment: azure.PublicCloud},
+		{name: "usgovernment", environment: azure.USGovernmentCloud},
+		{name: "china", environment: azure.ChinaCloud},
+	}
+	for _, tt := range tests {
+		suite.Run(tt.name, func() {
+			ResetCloudEnvironmentForTests()
+			environment := tt.environment
+			settingsMock := &mocks.IEnvironmentSettingsWrapper{}
+			settingsMock.On("GetEnvironment").Return(&environment).Once()
+			authWrapperMock := &mocks.IAzureAuthWrapper{}
+			authWrapperMock.On("GetSettingsFromEnvironment").Return(settingsMock, nil).Once()
+
+			cloudEnvironment, err := NewCloudEnvironment(&CloudEnvironmentConfiguration{}, authWrapperMock)
+
+			suite.Nil(err)
+			suite.Equal(tt.environment, cloudEnvironment.GetEnvironment())
+			suite.Equal(tt.environment.Name, GetCloudEnvironmentInstance().GetName())
+			settingsMock.AssertExpectations(suite.T())
+			authWrapperMock.AssertExpectations(suite.T())
+		})
+	}
+}
+
+func (suite *TestSuite) Test_NewCloudEnvironment_EnvironmentSettingsError_ShouldReturnError() {
+	expectedError := errors.New("SettingsError")
+	suite.authWrapperMock.On("GetSettingsFromEnvironment").Return(nil, expectedError).Once()
+
+	cloudEnvironment, err := NewCloudEnvironment(&CloudEnvironmentConfiguration{}, suite.authWrapperMock)
+
+	suite.True(errors.Is(err, expectedError))
+	suite.Nil(cloudEnvironment)
+	suite.Equal(azure.PublicCloud, GetCloudEnvironmentInstance().GetEnvironment())
+	suite.authWrapperMock.AssertExpectations(suite.T())
+}
+
+func (suite *TestSuite) Test_NewCloudEnvironment_UnknownName_ShouldReturnError() {
+	cloudEnvironment, err := NewCloudEnvironment(&CloudEnvironmentConfiguration{Name: "AzureMoonCloud"}, suite.authWrapperMock)
+
+	suite.True(errors.Is(err, utils.InvalidConfiguration))
+	suite.Nil(cloudEnvironment)
+}
+
+func (suite *TestSuite) Test_NewCloudEnvironment_Create2Instances_ShouldReturnError() {
+	cloudEnvironment, err := NewCloudEnvironment(&CloudEnvironmentConfiguration{Name: "AzureChinaCloud"}, suite.authWrapperMock)
+	suite.Nil(err)
+	suite.NotNil(cloudEnvironment)
+
+	cloudEnvironment, err = NewCloudEnvironment(&CloudEnvironmentConfiguration{Name: "AzureChinaCloud"}, suite.authWrapperMock)
+	suite.NotNil(err)
+	suite.Nil(cloudEnvironment)
+}
+
+func (suite *TestSuite) Test_NewCloudEnvironment_NilConfiguration_ShouldReturnError() {
+	cloudEnvironment, err := NewCloudEnvironment(nil, suite.authWrapperMock)
+
+	suite.True(errors.Is(err, utils.NilArgumentError))
+	suite.Nil(cloudEnvironment)
+}
+
+func (suite *TestSuite) Test_GetCloudEnvironmentInstance_NotInitialized_PublicCloud() {
+	cloudEnvironment := GetCloudEnvironmentInstance()
+
+	suite.Equal(azure.PublicCloud.Name, cloudEnvironment.GetName())
+	suite.Equal(".azurecr.io", cloudEnvironment.GetContainerRegistrySuffix())
+	suite.Equal("https://management.azure.com", cloudEnvironment.GetResourceGraphEndpoint())
+}
+
+func TestCloudEnvironment(t *testing.T) {
+	suite.Run(t, new(TestSuite))
+}
@@ -0,0 +1,169 @@
+package coalescing
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/cache/wrappers"
+	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/instrumentation"
+	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/instrumentation/metric"
+	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/instrumentation/metric/util"
+	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/instrumentation/trace"
+	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/utils"
+	"github.com/go-redis/redis/v8"
+	"github.com/pkg/errors"
+	"time"
+)
+
+const (
+	// _lockPrefixForCacheKey is the prefix of the keys of the locks in redis
+	_lockPrefixForCacheKey = "lock:"
+	// _defaultLockExpiration is the expiration of the lock in case that no expiration is configured
+	_defaultLockExpiration = 3 * time.Second
+	// _defaultPollInterval is the interval of checking if the lock is released in case that no interval is configured
+	_defaultPollInterval = 50 * time.Millisecond
+	// _unlockScript deletes the lock only if it's still held by the token, so a lock that expired and was acquired by
+	// another replica isn't released by mistake.
+	_unlockScript = `if redis.call("GET", KEYS[1]) == ARGV[1] then return redis.call("DEL", KEYS[1]) else return 0 end`
+)
+
+// IDistributedLock is a short-lived lock that is shared by the replicas of the webhook
+type IDistributedLock interface {
+	// TryLock tries to acquire the lock of the key without waiting.
+	// Returns the token of the lock (that is used to unlock it) and true if the lock is acquired.
+	TryLock(ctx context.Context, key string) (string, bool, error)
+	// Unlock releases the lock of the key if it's still held by the token
+	Unlock(ctx context.Context, key string, token string) error
+	// WaitUntilReleased waits until the lock of the key is released (or expired) or ctx is done
+	WaitUntilReleased(ctx context.Context, key string) error
+}
+
+// RedisDistributedLock implements IDistributedLock interface
+var _ IDistributedLock = (*RedisDistributedLock)(nil)
+
+// RedisDistributedLock is IDistributedLock that is stored in redis (SET NX with expiration)
+type RedisDistributedLock struct {
+	//tracerProvider is tracer provider of RedisDistributedLock
+	tracerProvider trace.ITracerProvider
+	//metricSubmitter is metric submitter of RedisDistributedLock
+	metricSubmitter metric.IMetricSubmitter
+	// redisClient is the redis client that the locks are stored in
+	redisClient wrappers.IRedisBaseClientWrapper
+	// expiration is the expiration of each lock - the lock is released after it even if its holder didn't unlock it (e.g. the replica crashed)
+	expiration time.Duration
+	// pollInterval is the interval of checking if the lock is released while waiting for it
+	pollInterval time.Duration
+}
+
+// DistributedLockConfiguration is configuration data for RedisDistributedLock
+type DistributedLockConfiguration struct {
+	// Enabled - replicas of the webhook wait for the results of the replica that holds the lock instead of executing identical lookups
+	Enabled bool
+	// ExpirationInMS is the expiration of each lock **IN MILLISECONDS**
+	ExpirationInMS int
+	// PollIntervalInMS is the interval **IN MILLISECONDS** of checking if the lock is released while waiting for it
+	PollIntervalInMS int
+}
+
+// NewRedisDistributedLock Ctor for RedisDistributedLock
+func NewRedisDistributedLock(instrumentationProvider instrumentation.IInstrumentationProvider, redisClient wrappers.IRedisBaseClientWrapper, configuration *DistributedLockConfiguration) *RedisDistributedLock {
+	// In case that the durations aren't configured (zero) - use default values.
+	expiration := _defaultLockExpiration
+	if configuration.ExpirationInMS > 0 {
+		expiration = utils.GetMilliseconds(configuration.ExpirationInMS)
+	}
+	pollInterval := _defaultPollInterval
+	if configuration.PollIntervalInMS > 0 {
+		pollInterval = utils.GetMilliseconds(configuration.PollIntervalInMS)
+	}
+
+	return &RedisDistributedLock{
+		tracerProvider:  instrumentationProvider.GetTracerProvider("RedisDistributedLock"),
+		metricSubmitter: instrumentationProvider.GetMetricSubmitter(),
+		redisClient:     redisClient,
+		expiration:      expiration,
+		pollInterval:    pollInterval,
+	}
+}
+
+// TryLock tries to acquire the lock of the key without waiting.
+func (lock *RedisDistributedLock) TryLock(ctx context.Context, key string) (string, bool, error) {
+	tracer := lock.tracerProvider.GetTracer("TryLock")
+
+	token, err := generateLockToken()
+	if err != nil {
+		err = errors.Wrap(err, "RedisDistributedLock.TryLock failed to generate token")
+		tracer.Error(err, "")
+		lock.metricSubmitter.SendMetric(1, util.NewErrorEncounteredMetric(err, "RedisDistributedLock.TryLock"))
+		return "", false, err
+	}
+
+	isAcquired, err := lock.redisClient.SetNX(ctx, lock.getLockKey(key), token, lock.expiration).Result()
+	if err != nil {
+		err = errors.Wrapf(err, "RedisDistributedLock.TryLock failed to set lock of <%s>", key)
+		tracer.Error(err, "")
+		lock.metricSubmitter.SendMetric(1, util.NewErrorEncounteredMetric(err, "RedisDistributedLock.TryLock"))
+		return "", false, err
+	}
+
+	tracer.Info("Tried to acquire lock", "key", key, "isAcquired", isAcquired)
+	if !isAcquired {
+		return "", false, nil
+	}
+	return token, true, nil
+}
+
+// Unlock releases the lock of the key if it's still held by the token
+func (lock *RedisDistributedLock) Unlock(ctx context.Context, key string, token string) error {
+	tracer := lock.tracerProvider.GetTracer("Unlock")
+
+	if err := lock.redisClient.Eval(ctx, _unlockScript, []string{lock.getLockKey(key)}, token).Err(); err != nil {
+		err = errors.Wrapf(err, "RedisDistributedLock.Unlock failed to delete lock of <%s>", key)
+		tracer.Error(err, "")
+		lock.metricSubmitter.SendMetric(1, util.NewErrorEncounteredMetric(err, "RedisDistributedLock.Unlock"))
+		return err
+	}
+	tracer.Info("Lock released", "key", key)
+	return nil
+}
+
+// WaitUntilReleased waits until the lock of the key is released (or expired) or ctx is done
+func (lock *RedisDistributedLock) WaitUntilReleased(ctx context.Context, key string) error {
+	tracer := lock.tracerProvider.GetTracer("WaitUntilReleased")
+	ticker := time.NewTicker(lock.pollInterval)
+	defer ticker.Stop()
+
+	for {
+		err := lock.redisClient.Get(ctx, lock.getLockKey(key)).Err()
+		if errors.Is(err, redis.Nil) {
+			tracer.Info("Lock is released", "key", key)
+			return nil
+		}
+		if err != nil {
+			err = errors.Wrapf(err, "RedisDistributedLock.WaitUntilReleased failed to get lock of <%s>", key)
+			tracer.Error(err, "")
+			lock.metricSubmitter.SendMetric(1, util.NewErrorEncounteredMetric(err, "RedisDistributedLock.WaitUntilReleased"))
+			return err
+		}
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return errors.Wrapf(ctx.Err(), "RedisDistributedLock.WaitUntilReleased context is done before lock of <%s> is released", key)
+		}
+	}
+}
+
+// getLockKey returns the redis key of the lock of the key
+func (lock *RedisDistributedLock) getLockKey(key string) string {
+	return _lockPrefixForCacheKey + key
+}
+
+// generateLockToken generates random token that identifies the holder of the lock
+func generateLockToken() (string, error) {
+	tokenBytes := make([]byte, 16)
+	if _, err := rand.Read(tokenBytes); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(tokenBytes), nil
+}
@@ -0,0 +1,108 @@
+package coalescing
+
+import (
+	"context"
+	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/instrumentation"
+	"github.com/go-redis/redis/v8"
+	"github.com/go-redis/redismock/v8"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/suite"
+	"testing"
+	"time"
+)
+
+const (
+	_key      = "containerTest1:playground.azurecr.io/testrepo:1.0"
+	_lockKey  = _lockPrefixForCacheKey + _key
+	_token    = "token"
+	_tokenExp = "^[0-9a-f]{32}$"
+)
+
+type RedisDistributedLockTestSuite struct {
+	suite.Suite
+	lock      *RedisDistributedLock
+	redisMock redismock.ClientMock
+}
+
+// This will run before each test in the suite
+func (suite *RedisDistributedLockTestSuite) SetupTest() {
+	var redisClient *redis.Client
+	redisClient, suite.redisMock = redismock.NewClientMock()
+	suite.lock = NewRedisDistributedLock(instrumentation.NewNoOpInstrumentationProvider(), redisClient, &DistributedLockConfiguration{
+		Enabled:          true,
+		ExpirationInMS:   2000,
+		PollIntervalInMS: 10,
+	})
+}
+
+func (suite *RedisDistributedLockTestSuite) TearDownTest() {
+	suite.Nil(suite.redisMock.ExpectationsWereMet())
+}
+
+func (suite *RedisDistributedLockTestSuite) Test_NewRedisDistributedLock_NotConfigured_DefaultValues() {
+	lock := NewRedisDistributedLock(instrumentation.NewNoOpInstrumentationProvider(), nil, &DistributedLockConfiguration{Enabled: true})
+
+	suite.Equal(_defaultLockExpiration, lock.expiration)
+	suite.Equal(_defaultPollInterval, lock.pollInterval)
+}
+
+func (suite *RedisDistributedLockTestSuite) Test_TryLock_KeyNotLocked_Acquired() {
+	suite.redisMock.Regexp().ExpectSetNX(_lockKey, _tokenExp, 2*time.Second).SetVal(true)
+
+	token, isAcquired, err := suite.lock.TryLock(context.Background(), _key)
+
+	suite.Nil(err)
+	suite.True(isAcquired)
+	suite.Regexp(_tokenExp, token)
+}
+
+func (suite *RedisDistributedLockTestSuite) Test_TryLock_KeyLocked_NotAcquired() {
+	suite.redisMock.Regexp().ExpectSetNX(_lockKey, _tokenExp, 2*time.Second).SetVal(false)
+
+	token, isAcquired, err := suite.lock.TryLock(context.Background(), _key)
+
+	suite.Nil(err)
+	suite.False(isAcquired)
+	suite.Equal("", token)
+}
+
+func (suite *RedisDistributedLockTestSuite) Test_TryLock_RedisError_ErrorReturned() {
+	suite.redisMock.Regexp().ExpectSetNX(_lockKey, _tokenExp, 2*time.Second).SetErr(errors.New("connection refused"))
+
+	_, isAcquired, err := suite.lock.TryLock(context.Background(), _key)
+
+	suite.NotNil(err)
+	suite.False(isAcquired)
+}
+
+func (suite *RedisDistributedLockTestSuite) Test_Unlock_DeletesLockOfToken() {
+	suite.redisMock.ExpectEval(_unlockScript, []string{_lockKey}, _token).SetVal(int64(1))
+
+	err := suite.lock.Unlock(context.Background(), _key, _token)
+
+	suite.Nil(err)
+}
+
+func (suite *RedisDistributedLockTestSuite) Test_WaitUntilReleased_LockReleasedAfterPolls_ReturnsNil() {
+	suite.redisMock.ExpectGet(_lockKey).SetVal(_token)
+	suite.redisMock.ExpectGet(_lockKey).SetVal(_token)
+	suite.redisMock.ExpectGet(_lockKey).RedisNil()
+
+	err := suite.lock.WaitUntilReleased(context.Background(), _key)
+
+	suite.Nil(err)
+}
+
+func (suite *RedisDistributedLockTestSuite) Test_WaitUntilReleased_ContextDone_ReturnsError() {
+	ctx, cancel := context.WithCancel(context.Background())
+	suite.redisMock.ExpectGet(_lockKey).SetVal(_token)
+	cancel()
+
+	err := suite.lock.WaitUntilReleased(ctx, _key)
+
+	suite.True(errors.Is(err, context.Canceled))
+}
+
+func TestRedisDistributedLock(t *testing.T) {
+	suite.Run(t, new(RedisDistributedLockTestSuite))
+}
@@ -0,0 +1,54 @@
+package metric
+
+import (
+	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/instrumentation/metric"
+)
+
+// CoalescingResult is the result of a lookup that went through request coalescing
+type CoalescingResult string
+
+const (
+	// ExecutedCoalescingResult - the lookup was executed by the caller (no identical lookup was in flight)
+	ExecutedCoalescingResult CoalescingResult = "Executed"
+	// CoalescedCoalescingResult - the caller joined an identical lookup that was already in flight and got its results
+	CoalescedCoalescingResult CoalescingResult = "Coalesced"
+	// AbandonedCoalescingResult - the caller stopped waiting for the in flight lookup because its context is done
+	AbandonedCoalescingResult CoalescingResult = "Abandoned"
+	// RejoinedCoalescingResult - the in flight lookup that the caller joined failed on the context of the caller that executed it,
+	// while the context of this caller is still alive - so the caller coalesces again instead of returning the other caller's error
+	RejoinedCoalescingResult CoalescingResult = "Rejoined"
+	// GotReplicaResultsCoalescingResult - the replica waited for the lock holder and got its results from the cache
+	GotReplicaResultsCoalescingResult CoalescingResult = "GotReplicaResults"
+	// NoReplicaResultsCoalescingResult - the replica waited for the lock holder but its results weren't in the cache, so the lookup was executed
+	NoReplicaResultsCoalescingResult CoalescingResult = "NoReplicaResults"
+)
+
+// RequestCoalescingMetric implements metric.IMetric interface
+var _ metric.IMetric = (*RequestCoalescingMetric)(nil)
+
+// RequestCoalescingMetric is metric of the request coalescing - counts the lookups of each layer according to their coalescing result
+type RequestCoalescingMetric struct {
+	// layer is the layer of the coalesced lookups - e.g. Tag2DigestResolver, ARGDataProvider
+	layer string
+	// result is the coalescing result of the lookup
+	result CoalescingResult
+}
+
+// NewRequestCoalescingMetric Ctor for RequestCoalescingMetric
+func NewRequestCoalescingMetric(layer string, result CoalescingResult) *RequestCoalescingMetric {
+	return &RequestCoalescingMetric{
+		layer:  layer,
+		result: result,
+	}
+}
+
+func (m *RequestCoalescingMetric) MetricName() string {
+	return "RequestCoalescing"
+}
+
+func (m *RequestCoalescingMetric) MetricDimension() []metric.Dimension {
+	return []metric.Dimension{
+		{Key: "Layer", Value: m.layer},
+		{Key: "Result", Value: string(m.result)},
+	}
+}
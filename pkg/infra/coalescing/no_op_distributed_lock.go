@@ -0,0 +1,32 @@
+package coalescing
+
+import (
+	"context"
+)
+
+// NoOpDistributedLock implements IDistributedLock interface
+var _ IDistributedLock = (*NoOpDistributedLock)(nil)
+
+// NoOpDistributedLock is IDistributedLock that always acquires the lock - used when the distributed lock is disabled
+// (or there is no shared cache, e.g. local development), so each replica executes its own lookups.
+type NoOpDistributedLock struct{}
+
+// NewNoOpDistributedLock Ctor for NoOpDistributedLock
+func NewNoOpDistributedLock() *NoOpDistributedLock {
+	return &NoOpDistributedLock{}
+}
+
+// TryLock always acquires the lock
+func (lock *NoOpDistributedLock) TryLock(ctx context.Context, key string) (string, bool, error) {
+	return "", true, nil
+}
+
+// Unlock does nothing
+func (lock *NoOpDistributedLock) Unlock(ctx context.Context, key string, token string) error {
+	return nil
+}
+
+// WaitUntilReleased returns immediately
+func (lock *NoOpDistributedLock) WaitUntilReleased(ctx context.Context, key string) error {
+	return nil
+}
@@ -0,0 +1,94 @@
+package coalescing
+
+import (
+	"context"
+	coalescingmetric "github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/coalescing/metric"
+	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/instrumentation"
+	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/instrumentation/metric"
+	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/instrumentation/metric/util"
+	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/instrumentation/trace"
+	"github.com/pkg/errors"
+	"golang.org/x/sync/singleflight"
+)
+
+// IRequestCoalescer coalesces concurrent identical lookups, so only one of them reaches the dependency (registry, ARG...)
+type IRequestCoalescer interface {
+	// Do executes fn only once for all the concurrent calls with the same key, and returns its results to all of them.
+	// The caller stops waiting once its ctx is done - fn keeps running for the other callers.
+	// fn runs with the context of the caller that executes it. In case that it fails because that context is done while the
+	// context of a caller that joined it is still alive, the joined caller doesn't get the error - it coalesces again.
+	Do(ctx context.Context, key string, fn func() (interface{}, error)) (interface{}, error)
+}
+
+// RequestCoalescer implements IRequestCoalescer interface
+var _ IRequestCoalescer = (*RequestCoalescer)(nil)
+
+// RequestCoalescer is IRequestCoalescer that coalesces the concurrent calls of the process (singleflight)
+type RequestCoalescer struct {
+	//tracerProvider is tracer provider of RequestCoalescer
+	tracerProvider trace.ITracerProvider
+	//metricSubmitter is metric submitter of RequestCoalescer
+	metricSubmitter metric.IMetricSubmitter
+	// layer is the layer of the coalesced lookups (e.g. Tag2DigestResolver) - used as dimension of the coalescing metrics
+	layer string
+	// group tracks the in flight calls of each key
+	group singleflight.Group
+}
+
+// NewRequestCoalescer Ctor for RequestCoalescer
+func NewRequestCoalescer(instrumentationProvider instrumentation.IInstrumentationProvider, layer string) *RequestCoalescer {
+	return &RequestCoalescer{
+		tracerProvider:  instrumentationProvider.GetTracerProvider("RequestCoalescer"),
+		metricSubmitter: instrumentationProvider.GetMetricSubmitter(),
+		layer:           layer,
+	}
+}
+
+// Do executes fn only once for all the concurrent calls with the same key, and returns its results to all of them.
+// A joined call that failed on a context error is joined (or executed) again as long as ctx of the caller isn't done,
+// since the error belongs to the context of the caller that executed it.
+func (coalescer *RequestCoalescer) Do(ctx context.Context, key string, fn func() (interface{}, error)) (interface{}, error) {
+	tracer := coalescer.tracerProvider.GetTracer("Do")
+	for {
+		value, isExecuted, err := coalescer.do(ctx, tracer, key, fn)
+		if isExecuted || !isContextError(err) || ctx.Err() != nil {
+			return value, err
+		}
+		tracer.Info("Coalesced call failed on the context of another caller - coalescing again", "layer", coalescer.layer, "key", key, "err", err)
+		coalescer.metricSubmitter.SendMetric(1, coalescingmetric.NewRequestCoalescingMetric(coalescer.layer, coalescingmetric.RejoinedCoalescingResult))
+	}
+}
+
+// do executes fn or joins the in flight call with the same key and waits for its results until ctx is done.
+// Returns whether fn of this call is the one that was executed.
+func (coalescer *RequestCoalescer) do(ctx context.Context, tracer trace.ITracer, key string, fn func() (interface{}, error)) (interface{}, bool, error) {
+	// isExecuted is set only if fn of this call is the one that is executed. It's read only after the results are received,
+	// so there is no race with the goroutine that executes fn.
+	isExecuted := false
+	resultsChannel := coalescer.group.DoChan(key, func() (interface{}, error) {
+		isExecuted = true
+		return fn()
+	})
+
+	select {
+	case results := <-resultsChannel:
+		if isExecuted {
+			coalescer.metricSubmitter.SendMetric(1, coalescingmetric.NewRequestCoalescingMetric(coalescer.layer, coalescingmetric.ExecutedCoalescingResult))
+		} else {
+			tracer.Info("Got results of coalesced call", "layer", coalescer.layer, "key", key)
+			coalescer.metricSubmitter.SendMetric(1, coalescingmetric.NewRequestCoalescingMetric(coalescer.layer, coalescingmetric.CoalescedCoalescingResult))
+		}
+		return results.Val, isExecuted, results.Err
+	case <-ctx.Done():
+		err := errors.Wrapf(ctx.Err(), "RequestCoalescer.Do: context is done while waiting for the results of <%s>", key)
+		tracer.Error(err, "", "layer", coalescer.layer)
+		coalescer.metricSubmitter.SendMetric(1, coalescingmetric.NewRequestCoalescingMetric(coalescer.layer, coalescingmetric.AbandonedCoalescingResult))
+		coalescer.metricSubmitter.SendMetric(1, util.NewErrorEncounteredMetric(err, "RequestCoalescer.Do"))
+		return nil, false, err
+	}
+}
+
+// isContextError returns true in case that the error is caused by a canceled context or a context that its deadline passed
+func isContextError(err error) bool {
+	return errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded)
+}
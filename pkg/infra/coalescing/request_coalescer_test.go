@@ -0,0 +1,144 @@
+package coalescing
+
+import (
+	"context"
+	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/instrumentation"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/suite"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type RequestCoalescerTestSuite struct {
+	suite.Suite
+	coalescer *RequestCoalescer
+}
+
+// This will run before each test in the suite
+func (suite *RequestCoalescerTestSuite) SetupTest() {
+	suite.coalescer = NewRequestCoalescer(instrumentation.NewNoOpInstrumentationProvider(), "Test")
+}
+
+func (suite *RequestCoalescerTestSuite) Test_Do_ConcurrentCallsSameKey_ExecutedOnce() {
+	var executions int32
+	release := make(chan struct{})
+	fn := func() (interface{}, error) {
+		atomic.AddInt32(&executions, 1)
+		<-release
+		return "digest", nil
+	}
+
+	results := make([]interface{}, 5)
+	wg := sync.WaitGroup{}
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			result, err := suite.coalescer.Do(context.Background(), "key", fn)
+			suite.Nil(err)
+			results[i] = result
+		}(i)
+	}
+	// Let all the calls join the in flight call before it returns
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	suite.Equal(int32(1), atomic.LoadInt32(&executions))
+	for _, result := range results {
+		suite.Equal("digest", result)
+	}
+}
+
+func (suite *RequestCoalescerTestSuite) Test_Do_DifferentKeys_ExecutedForEachKey() {
+	first, err := suite.coalescer.Do(context.Background(), "first", func() (interface{}, error) { return "first", nil })
+	suite.Nil(err)
+	second, err := suite.coalescer.Do(context.Background(), "second", func() (interface{}, error) { return "second", nil })
+	suite.Nil(err)
+
+	suite.Equal("first", first)
+	suite.Equal("second", second)
+}
+
+func (suite *RequestCoalescerTestSuite) Test_Do_SequentialCallsSameKey_ExecutedForEachCall() {
+	var executions int32
+	fn := func() (interface{}, error) {
+		atomic.AddInt32(&executions, 1)
+		return nil, nil
+	}
+
+	_, _ = suite.coalescer.Do(context.Background(), "key", fn)
+	_, _ = suite.coalescer.Do(context.Background(), "key", fn)
+
+	suite.Equal(int32(2), atomic.LoadInt32(&executions))
+}
+
+func (suite *RequestCoalescerTestSuite) Test_Do_Error_ErrorReturned() {
+	expectedErr := errors.New("registry error")
+
+	result, err := suite.coalescer.Do(context.Background(), "key", func() (interface{}, error) { return nil, expectedErr })
+
+	suite.Nil(result)
+	suite.Equal(expectedErr, err)
+}
+
+func (suite *RequestCoalescerTestSuite) Test_Do_ContextDoneWhileWaiting_StopsWaiting() {
+	release := make(chan struct{})
+	defer close(release)
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	result, err := suite.coalescer.Do(ctx, "key", func() (interface{}, error) {
+		<-release
+		return "digest", nil
+	})
+
+	suite.Nil(result)
+	suite.True(errors.Is(err, context.DeadlineExceeded))
+}
+
+func (suite *RequestCoalescerTestSuite) Test_Do_FirstCallerContextExpiresBeforeJoiner_JoinerExecutesAgain() {
+	var executions int32
+	firstCtx, cancelFirst := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancelFirst()
+	// fn fails with the error of the context of the caller that executes it
+	newFn := func(ctx context.Context) func() (interface{}, error) {
+		return func() (interface{}, error) {
+			if atomic.AddInt32(&executions, 1) == 1 {
+				<-ctx.Done()
+				return nil, errors.Wrap(ctx.Err(), "registry call canceled")
+			}
+			return "digest", nil
+		}
+	}
+
+	firstErrChannel := make(chan error, 1)
+	go func() {
+		_, err := suite.coalescer.Do(firstCtx, "key", newFn(firstCtx))
+		firstErrChannel <- err
+	}()
+	// Let the first call start executing before the joiner joins it
+	time.Sleep(10 * time.Millisecond)
+	joinerResult, joinerErr := suite.coalescer.Do(context.Background(), "key", newFn(context.Background()))
+
+	suite.True(errors.Is(<-firstErrChannel, context.DeadlineExceeded))
+	suite.Nil(joinerErr)
+	suite.Equal("digest", joinerResult)
+	suite.Equal(int32(2), atomic.LoadInt32(&executions))
+}
+
+func (suite *RequestCoalescerTestSuite) Test_Do_ContextDoneAndContextError_ErrorReturned() {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	result, err := suite.coalescer.Do(ctx, "key", func() (interface{}, error) { return nil, context.Canceled })
+
+	suite.Nil(result)
+	suite.True(errors.Is(err, context.Canceled))
+}
+
+func TestRequestCoalescer(t *testing.T) {
+	suite.Run(t, new(RequestCoalescerTestSuite))
+}
@@ -0,0 +1,253 @@
+// Package configreload contains the reload of the safe-to-change configurations (e.g. TTLs, retry policies, supported kinds)
+// when the mounted configuration file is changed, so they're applied without restarting the webhook.
+package configreload
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"github.com/Azure/ASC-go-libs/pkg/config"
+	configreloadmetric "github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/configreload/metric"
+	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/instrumentation"
+	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/instrumentation/metric"
+	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/instrumentation/metric/util"
+	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/instrumentation/trace"
+	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/utils"
+	"github.com/pkg/errors"
+	"io/ioutil"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/tools/record"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// ConfigReloadedEventReason is the reason of the event that is emitted when the changed configuration was applied
+	ConfigReloadedEventReason = "ConfigurationReloaded"
+	// ConfigReloadFailedEventReason is the reason of the event that is emitted when the changed configuration couldn't be applied
+	ConfigReloadFailedEventReason = "ConfigurationReloadFailed"
+)
+
+// IConfigReloader reloads the configuration file and applies the safe-to-change configurations
+type IConfigReloader interface {
+	// Reload applies the reloadable configurations in case that the configuration file was changed since the last reload.
+	// The configurations are applied only if all of them are loaded and valid - otherwise nothing is applied and error is returned.
+	Reload() error
+	// ReloadEveryTick reloads the configuration every tick (in the background) until ctx is done
+	ReloadEveryTick(ctx context.Context, duration time.Duration)
+}
+
+// ConfigReloader implements IConfigReloader interface
+var _ IConfigReloader = (*ConfigReloader)(nil)
+
+// ConfigReloader is IConfigReloader that detects changes of the configuration file by its content (the mounted ConfigMap
+// is replaced by a symlink swap, so the content is compared instead of the modification time).
+// All the reloadable configurations are held in one immutable snapshot that is swapped as a whole on reload, so the
+// components never see a mix of configurations from two versions of the configuration file.
+// Each reload is reported as metric and as event on the ConfigMap.
+type ConfigReloader struct {
+	//tracerProvider is tracer provider of ConfigReloader
+	tracerProvider trace.ITracerProvider
+	//metricSubmitter is metric submitter of ConfigReloader
+	metricSubmitter metric.IMetricSubmitter
+	// eventRecorder records the reload events on the ConfigMap
+	eventRecorder record.EventRecorder
+	// configFilePath is the path of the configuration file
+	configFilePath string
+	// configMapReference is the ConfigMap that the configuration file is mounted from - the involved object of the events.
+	// nil in case that the ConfigMap isn't configured (e.g. local development) - no events are emitted.
+	configMapReference *corev1.ObjectReference
+	// reloadableConfigurations are the configurations that are applied on reload
+	reloadableConfigurations []*ReloadableConfiguration
+	// snapshot holds the current *configurationSnapshot - it's replaced (never modified) on reload
+	snapshot atomic.Value
+	// configFileHash is the hash of the content of the configuration file of the last reload
+	configFileHash string
+	// lock makes sure that reloads aren't executed concurrently
+	lock sync.Mutex
+}
+
+// configurationSnapshot is an immutable snapshot of the reloadable configurations
+type configurationSnapshot struct {
+	// configurations are the configurations by their keys - the map and the configurations aren't modified after the
+	// snapshot is created
+	configurations map[string]interface{}
+}
+
+// ConfigReloaderConfiguration is configuration data for ConfigReloader
+type ConfigReloaderConfiguration struct {
+	// Enabled is whether the configuration file should be watched and reloaded on change
+	Enabled bool
+	// PollingIntervalInSeconds is the interval between two checks of the configuration file
+	PollingIntervalInSeconds int
+	// ConfigMapName is the name of the ConfigMap that the configuration file is mounted from - the reload events are
+	// emitted on it. If empty - no events are emitted.
+	ConfigMapName string
+}
+
+// ReloadableConfiguration is a configuration that can be changed without restarting the webhook
+type ReloadableConfiguration struct {
+	// Key is the key of the configuration in the configuration file
+	Key string
+	// NewConfiguration returns new (empty) configuration object that the reloaded configuration is unmarshalled into
+	NewConfiguration func() interface{}
+	// Validate returns error in case that the reloaded configuration is invalid. Optional.
+	Validate func(configuration interface{}) error
+	// Bind makes the components that use the configuration read it on each use by getConfiguration, which returns the
+	// configuration of the current snapshot. Called once - when the ConfigReloader is created.
+	Bind func(getConfiguration func() interface{})
+}
+
+// NewConfigReloader Ctor for ConfigReloader. The current content of the configuration file is loaded as the first
+// snapshot and the reloadable configurations are bound to it.
+func NewConfigReloader(instrumentationProvider instrumentation.IInstrumentationProvider, eventRecorder record.EventRecorder, configFilePath string, configuration *ConfigReloaderConfiguration, namespace string, reloadableConfigurations []*ReloadableConfiguration) (*ConfigReloader, error) {
+	configFileHash, err := getConfigFileHash(configFilePath)
+	if err != nil {
+		return nil, errors.Wrap(err, "ConfigReloader failed to read the configuration file")
+	}
+
+	var configMapReference *corev1.ObjectReference
+	if configuration.ConfigMapName != "" {
+		configMapReference = &corev1.ObjectReference{
+			APIVersion: "v1",
+			Kind:       "ConfigMap",
+			Namespace:  namespace,
+			Name:       configuration.ConfigMapName,
+		}
+	}
+
+	reloader := &ConfigReloader{
+		tracerProvider:           instrumentationProvider.GetTracerProvider("ConfigReloader"),
+		metricSubmitter:          instrumentationProvider.GetMetricSubmitter(),
+		eventRecorder:            eventRecorder,
+		configFilePath:           configFilePath,
+		configMapReference:       configMapReference,
+		reloadableConfigurations: reloadableConfigurations,
+		configFileHash:           configFileHash,
+	}
+	snapshot, err := reloader.loadSnapshot()
+	if err != nil {
+		return nil, errors.Wrap(err, "ConfigReloader failed to load the configuration")
+	}
+	reloader.snapshot.Store(snapshot)
+
+	for _, reloadableConfiguration := range reloadableConfigurations {
+		key := reloadableConfiguration.Key
+		reloadableConfiguration.Bind(func() interface{} {
+			return reloader.getSnapshot().configurations[key]
+		})
+	}
+	return reloader, nil
+}
+
+// Reload applies the reloadable configurations in case that the configuration file was changed since the last reload.
+// A changed file is reloaded once - in case that it's invalid, it's reloaded again only after it's changed again.
+func (reloader *ConfigReloader) Reload() error {
+	tracer := reloader.tracerProvider.GetTracer("Reload")
+	reloader.lock.Lock()
+	defer reloader.lock.Unlock()
+
+	configFileHash, err := getConfigFileHash(reloader.configFilePath)
+	if err != nil {
+		err = errors.Wrap(err, "ConfigReloader.Reload failed to read the configuration file")
+		tracer.Error(err, "")
+		reloader.metricSubmitter.SendMetric(1, util.NewErrorEncounteredMetric(err, "ConfigReloader.Reload"))
+		return err
+	}
+	if configFileHash == reloader.configFileHash {
+		return nil
+	}
+	reloader.configFileHash = configFileHash
+	tracer.Info("Configuration file changed - reloading", "configFilePath", reloader.configFilePath)
+
+	snapshot, err := reloader.loadSnapshot()
+	if err != nil {
+		err = errors.Wrap(err, "ConfigReloader.Reload failed to reload the configuration - the previous configuration is kept")
+		tracer.Error(err, "")
+		reloader.metricSubmitter.SendMetric(1, util.NewErrorEncounteredMetric(err, "ConfigReloader.Reload"))
+		reloader.metricSubmitter.SendMetric(1, configreloadmetric.NewConfigReloadMetric(configreloadmetric.FailedConfigReloadResult))
+		reloader.recordEvent(corev1.EventTypeWarning, ConfigReloadFailedEventReason, err.Error())
+		return err
+	}
+
+	// All the configurations are replaced at once
+	reloader.snapshot.Store(snapshot)
+	keys := make([]string, 0, len(reloader.reloadableConfigurations))
+	for _, reloadableConfiguration := range reloader.reloadableConfigurations {
+		keys = append(keys, reloadableConfiguration.Key)
+	}
+	tracer.Info("Configuration reloaded", "keys", keys)
+	reloader.metricSubmitter.SendMetric(1, configreloadmetric.NewConfigReloadMetric(configreloadmetric.SucceededConfigReloadResult))
+	reloader.recordEvent(corev1.EventTypeNormal, ConfigReloadedEventReason, fmt.Sprintf("Configuration reloaded: %s", strings.Join(keys, ", ")))
+	return nil
+}
+
+// ReloadEveryTick reloads the configuration every tick (in the background) until ctx is done
+func (reloader *ConfigReloader) ReloadEveryTick(ctx context.Context, duration time.Duration) {
+	go func() {
+		ticker := time.NewTicker(duration)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				// The errors are reported by Reload
+				_ = reloader.Reload()
+			}
+		}
+	}()
+}
+
+// getSnapshot returns the current snapshot of the reloadable configurations
+func (reloader *ConfigReloader) getSnapshot() *configurationSnapshot {
+	return reloader.snapshot.Load().(*configurationSnapshot)
+}
+
+// loadSnapshot loads the configuration file and unmarshals and validates each of the reloadable configurations
+// into a new snapshot.
+func (reloader *ConfigReloader) loadSnapshot() (*configurationSnapshot, error) {
+	appConfig, err := config.LoadConfig(reloader.configFilePath)
+	if err != nil {
+		return nil, err
+	}
+
+	configurations := make(map[string]interface{}, len(reloader.reloadableConfigurations))
+	for _, reloadableConfiguration := range reloader.reloadableConfigurations {
+		configuration := reloadableConfiguration.NewConfiguration()
+		if err := config.CreateSubConfiguration(appConfig, reloadableConfiguration.Key, configuration); err != nil {
+			return nil, errors.Wrapf(err, "failed to load configuration <%s>", reloadableConfiguration.Key)
+		}
+		if reloadableConfiguration.Validate != nil {
+			if err := reloadableConfiguration.Validate(configuration); err != nil {
+				return nil, errors.Wrapf(err, "invalid configuration <%s>", reloadableConfiguration.Key)
+			}
+		}
+		configurations[reloadableConfiguration.Key] = configuration
+	}
+	return &configurationSnapshot{configurations: configurations}, nil
+}
+
+// recordEvent records the event on the ConfigMap - nothing is recorded in case that the ConfigMap isn't configured
+func (reloader *ConfigReloader) recordEvent(eventType string, reason string, message string) {
+	if reloader.configMapReference == nil {
+		return
+	}
+	reloader.eventRecorder.Event(reloader.configMapReference, eventType, reason, message)
+}
+
+// getConfigFileHash returns the hash of the content of the configuration file
+func getConfigFileHash(configFilePath string) (string, error) {
+	if configFilePath == "" {
+		return "", errors.Wrap(utils.NilArgumentError, "configFilePath can't be empty")
+	}
+	content, err := ioutil.ReadFile(configFilePath)
+	if err != nil {
+		return "", err
+	}
+	hash := sha256.Sum256(content)
+	return hex.EncodeToString(hash[:]), nil
+}
@@ -0,0 +1,246 @@
+package configreload
+
+import (
+	"context"
+	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/instrumentation"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/suite"
+	"io/ioutil"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/tools/record"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+const (
+	_initialConfig = `
+handler:
+  dryRun: false
+  supportedKinds: ["Pod"]
+cache:
+  ttl: 10
+`
+	_changedConfig = `
+handler:
+  dryRun: true
+  supportedKinds: ["Pod", "Deployment"]
+cache:
+  ttl: 20
+`
+	_invalidConfig = `
+handler:
+  dryRun: true
+  supportedKinds: ["Pod"]
+cache:
+  ttl: 0
+`
+	_missingKeyConfig = `
+handler:
+  dryRun: true
+  supportedKinds: ["Pod"]
+`
+)
+
+type testHandlerConfiguration struct {
+	DryRun         bool
+	SupportedKinds []string
+}
+
+type testCacheConfiguration struct {
+	TTL int
+}
+
+type ConfigReloaderTestSuite struct {
+	suite.Suite
+	configFilePath          string
+	recorder                *record.FakeRecorder
+	getHandlerConfiguration func() interface{}
+	getCacheConfiguration   func() interface{}
+	reloader                *ConfigReloader
+}
+
+// This will run before each test in the suite
+func (suite *ConfigReloaderTestSuite) SetupTest() {
+	suite.configFilePath = filepath.Join(suite.T().TempDir(), "config.yaml")
+	suite.writeConfig(_initialConfig)
+	suite.recorder = record.NewFakeRecorder(10)
+	suite.reloader = suite.createReloader("azdproxy-config")
+}
+
+func (suite *ConfigReloaderTestSuite) Test_Reload_FileNotChanged_NothingApplied() {
+	err := suite.reloader.Reload()
+
+	suite.Nil(err)
+	suite.False(suite.handlerConfiguration().DryRun)
+	suite.Equal(10, suite.cacheConfiguration().TTL)
+	suite.Empty(suite.recorder.Events)
+}
+
+func (suite *ConfigReloaderTestSuite) Test_Reload_FileChanged_AllConfigurationsApplied() {
+	suite.writeConfig(_changedConfig)
+
+	err := suite.reloader.Reload()
+
+	suite.Nil(err)
+	suite.True(suite.handlerConfiguration().DryRun)
+	suite.Equal([]string{"Pod", "Deployment"}, suite.handlerConfiguration().SupportedKinds)
+	suite.Equal(20, suite.cacheConfiguration().TTL)
+	suite.Equal("Normal ConfigurationReloaded Configuration reloaded: handler, cache", <-suite.recorder.Events)
+}
+
+func (suite *ConfigReloaderTestSuite) Test_Reload_InvalidConfiguration_NothingApplied() {
+	suite.writeConfig(_invalidConfig)
+
+	err := suite.reloader.Reload()
+
+	suite.NotNil(err)
+	suite.False(suite.handlerConfiguration().DryRun)
+	suite.Equal(10, suite.cacheConfiguration().TTL)
+	event := <-suite.recorder.Events
+	suite.True(strings.HasPrefix(event, "Warning ConfigurationReloadFailed"), event)
+	suite.Contains(event, "invalid configuration <cache>")
+}
+
+func (suite *ConfigReloaderTestSuite) Test_Reload_InvalidConfigurationReloadedAgain_ReportedOnce() {
+	suite.writeConfig(_invalidConfig)
+	suite.NotNil(suite.reloader.Reload())
+	<-suite.recorder.Events
+
+	err := suite.reloader.Reload()
+
+	suite.Nil(err)
+	suite.Empty(suite.recorder.Events)
+}
+
+func (suite *ConfigReloaderTestSuite) Test_Reload_InvalidConfigurationFixed_Applied() {
+	suite.writeConfig(_invalidConfig)
+	suite.NotNil(suite.reloader.Reload())
+	<-suite.recorder.Events
+	suite.writeConfig(_changedConfig)
+
+	err := suite.reloader.Reload()
+
+	suite.Nil(err)
+	suite.Equal(20, suite.cacheConfiguration().TTL)
+	suite.Equal("Normal ConfigurationReloaded Configuration reloaded: handler, cache", <-suite.recorder.Events)
+}
+
+func (suite *ConfigReloaderTestSuite) Test_Reload_MissingKey_NothingApplied() {
+	suite.writeConfig(_missingKeyConfig)
+
+	err := suite.reloader.Reload()
+
+	suite.NotNil(err)
+	suite.Contains(err.Error(), "failed to load configuration <cache>")
+	suite.False(suite.handlerConfiguration().DryRun)
+}
+
+func (suite *ConfigReloaderTestSuite) Test_Reload_ConfigMapNotConfigured_NoEvents() {
+	reloader := suite.createReloader("")
+	suite.writeConfig(_changedConfig)
+
+	err := reloader.Reload()
+
+	suite.Nil(err)
+	suite.Equal(20, suite.cacheConfiguration().TTL)
+	suite.Empty(suite.recorder.Events)
+}
+
+func (suite *ConfigReloaderTestSuite) Test_Reload_FileChanged_PreviousSnapshotNotModified() {
+	previousSnapshot := suite.reloader.getSnapshot()
+	suite.writeConfig(_changedConfig)
+
+	err := suite.reloader.Reload()
+
+	suite.Nil(err)
+	suite.NotSame(previousSnapshot, suite.reloader.getSnapshot())
+	suite.False(previousSnapshot.configurations["handler"].(*testHandlerConfiguration).DryRun)
+	suite.Equal(10, previousSnapshot.configurations["cache"].(*testCacheConfiguration).TTL)
+}
+
+func (suite *ConfigReloaderTestSuite) Test_ReloadEveryTick_ContextDone_StopsReloading() {
+	ctx, cancel := context.WithCancel(context.Background())
+	suite.reloader.ReloadEveryTick(ctx, time.Millisecond)
+	cancel()
+	// Let a tick that raced with the cancellation finish
+	time.Sleep(20 * time.Millisecond)
+
+	suite.writeConfig(_changedConfig)
+	time.Sleep(20 * time.Millisecond)
+
+	suite.False(suite.handlerConfiguration().DryRun)
+	suite.Equal(10, suite.cacheConfiguration().TTL)
+}
+
+func (suite *ConfigReloaderTestSuite) Test_NewConfigReloader_InvalidConfiguration_Error() {
+	suite.writeConfig(_invalidConfig)
+
+	reloader, err := NewConfigReloader(instrumentation.NewNoOpInstrumentationProvider(), suite.recorder, suite.configFilePath, &ConfigReloaderConfiguration{}, "kube-system", []*ReloadableConfiguration{
+		{
+			Key:              "cache",
+			NewConfiguration: func() interface{} { return new(testCacheConfiguration) },
+			Validate:         func(configuration interface{}) error { return errors.New("invalid") },
+			Bind:             func(getConfiguration func() interface{}) {},
+		},
+	})
+
+	suite.Nil(reloader)
+	suite.NotNil(err)
+}
+
+func (suite *ConfigReloaderTestSuite) Test_NewConfigReloader_MissingFile_Error() {
+	reloader, err := NewConfigReloader(instrumentation.NewNoOpInstrumentationProvider(), suite.recorder, filepath.Join(suite.T().TempDir(), "missing.yaml"), &ConfigReloaderConfiguration{}, "kube-system", nil)
+
+	suite.Nil(reloader)
+	suite.NotNil(err)
+}
+
+func (suite *ConfigReloaderTestSuite) Test_NewConfigReloader_ConfigMapReference() {
+	suite.Equal(&corev1.ObjectReference{APIVersion: "v1", Kind: "ConfigMap", Namespace: "kube-system", Name: "azdproxy-config"}, suite.reloader.configMapReference)
+}
+
+func (suite *ConfigReloaderTestSuite) handlerConfiguration() *testHandlerConfiguration {
+	return suite.getHandlerConfiguration().(*testHandlerConfiguration)
+}
+
+func (suite *ConfigReloaderTestSuite) cacheConfiguration() *testCacheConfiguration {
+	return suite.getCacheConfiguration().(*testCacheConfiguration)
+}
+
+func (suite *ConfigReloaderTestSuite) createReloader(configMapName string) *ConfigReloader {
+	reloader, err := NewConfigReloader(instrumentation.NewNoOpInstrumentationProvider(), suite.recorder, suite.configFilePath, &ConfigReloaderConfiguration{Enabled: true, ConfigMapName: configMapName}, "kube-system", []*ReloadableConfiguration{
+		{
+			Key:              "handler",
+			NewConfiguration: func() interface{} { return new(testHandlerConfiguration) },
+			Bind: func(getConfiguration func() interface{}) {
+				suite.getHandlerConfiguration = getConfiguration
+			},
+		},
+		{
+			Key:              "cache",
+			NewConfiguration: func() interface{} { return new(testCacheConfiguration) },
+			Validate: func(configuration interface{}) error {
+				if configuration.(*testCacheConfiguration).TTL <= 0 {
+					return errors.New("non-positive TTL")
+				}
+				return nil
+			},
+			Bind: func(getConfiguration func() interface{}) {
+				suite.getCacheConfiguration = getConfiguration
+			},
+		},
+	})
+	suite.Require().Nil(err)
+	return reloader
+}
+
+func (suite *ConfigReloaderTestSuite) writeConfig(content string) {
+	suite.Require().Nil(ioutil.WriteFile(suite.configFilePath, []byte(content), os.ModePerm))
+}
+
+func TestConfigReloaderTestSuite(t *testing.T) {
+	suite.Run(t, new(ConfigReloaderTestSuite))
+}
@@ -0,0 +1,41 @@
+package metric
+
+import (
+	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/instrumentation/metric"
+)
+
+// ConfigReloadResult is enum of the results of a reload of the configuration
+type ConfigReloadResult string
+
+const (
+	// SucceededConfigReloadResult is when the changed configuration was applied
+	SucceededConfigReloadResult ConfigReloadResult = "Succeeded"
+	// FailedConfigReloadResult is when the changed configuration couldn't be loaded or is invalid - nothing was applied
+	FailedConfigReloadResult ConfigReloadResult = "Failed"
+)
+
+// ConfigReloadMetric implements metric.IMetric interface
+var _ metric.IMetric = (*ConfigReloadMetric)(nil)
+
+// ConfigReloadMetric is metric of ConfigReloader to report each reload of the changed configuration file and its result
+type ConfigReloadMetric struct {
+	// result is whether the changed configuration was applied
+	result ConfigReloadResult
+}
+
+// NewConfigReloadMetric Ctor for ConfigReloadMetric
+func NewConfigReloadMetric(result ConfigReloadResult) *ConfigReloadMetric {
+	return &ConfigReloadMetric{
+		result: result,
+	}
+}
+
+func (m *ConfigReloadMetric) MetricName() string {
+	return "ConfigReload"
+}
+
+func (m *ConfigReloadMetric) MetricDimension() []metric.Dimension {
+	return []metric.Dimension{
+		{Key: "Result", Value: string(m.result)},
+	}
+}
@@ -0,0 +1,15 @@
+package health
+
+// HealthChecksConfiguration is configuration data for the health checks of the service's dependencies
+type HealthChecksConfiguration struct {
+	// RedisPingFrequencyInSeconds is the frequency **IN SECONDS** of pinging redis
+	RedisPingFrequencyInSeconds int
+	// RedisPingMaxAgeInSeconds is the maximal age **IN SECONDS** of the last successful redis ping for the service to be ready.
+	// Should be a few times longer than RedisPingFrequencyInSeconds, so a single failed ping doesn't make the service unready.
+	RedisPingMaxAgeInSeconds int
+	// TokenAcquisitionFrequencyInSeconds is the frequency **IN SECONDS** of checking that the tokens of the identities can be acquired
+	TokenAcquisitionFrequencyInSeconds int
+	// TokenAcquisitionMaxAgeInSeconds is the maximal age **IN SECONDS** of the last successful token acquisition of each identity for the service to be ready.
+	// Should be a few times longer than TokenAcquisitionFrequencyInSeconds, so a single failed acquisition doesn't make the service unready.
+	TokenAcquisitionMaxAgeInSeconds int
+}
@@ -0,0 +1,36 @@
+package metric
+
+import (
+	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/instrumentation/metric"
+	"strconv"
+)
+
+// HealthCheckStatusMetric implements metric.IMetric interface
+var _ metric.IMetric = (*HealthCheckStatusMetric)(nil)
+
+// HealthCheckStatusMetric is metric of the status of a health check each time that it is checked by the readiness probe
+type HealthCheckStatusMetric struct {
+	// checkName is the name of the health check
+	checkName string
+	// isHealthy is whether the health check passed
+	isHealthy bool
+}
+
+// NewHealthCheckStatusMetric Ctor for HealthCheckStatusMetric
+func NewHealthCheckStatusMetric(checkName string, isHealthy bool) *HealthCheckStatusMetric {
+	return &HealthCheckStatusMetric{
+		checkName: checkName,
+		isHealthy: isHealthy,
+	}
+}
+
+func (m *HealthCheckStatusMetric) MetricName() string {
+	return "HealthCheckStatus"
+}
+
+func (m *HealthCheckStatusMetric) MetricDimension() []metric.Dimension {
+	return []metric.Dimension{
+		{Key: "CheckName", Value: m.checkName},
+		{Key: "IsHealthy", Value: strconv.FormatBool(m.isHealthy)},
+	}
+}
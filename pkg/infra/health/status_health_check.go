@@ -0,0 +1,97 @@
+// Package health contains the health checks of the service and its dependencies that are served by the readiness probe.
+package health
+
+import (
+	"fmt"
+	"github.com/pkg/errors"
+	"net/http"
+	"sync"
+	"time"
+)
+
+var (
+	// _noStatusReportedErr is the status of a health check that no status was reported to yet
+	_noStatusReportedErr = errors.New("no status was reported yet")
+)
+
+// IHealthCheck is a health check of the service or one of its dependencies
+type IHealthCheck interface {
+	// Name returns the name of the health check
+	Name() string
+	// Check returns nil if the checked dependency is healthy, otherwise returns the reason that it isn't healthy.
+	// The signature matches controller-runtime's healthz.Checker.
+	Check(req *http.Request) error
+}
+
+// StatusHealthCheck implements IHealthCheck interface
+var _ IHealthCheck = (*StatusHealthCheck)(nil)
+
+// StatusHealthCheck is a health check that its status is reported by the checked dependency (e.g. after each redis ping).
+// The check is healthy if a successful status was reported in the last maxAge - failures that are reported after it are tolerated
+// until it expires, so a single transient failure doesn't fail the check.
+type StatusHealthCheck struct {
+	// name is the name of the health check
+	name string
+	// maxAge is the maximal age of the last successful status. If zero - the last successful status never expires, and the
+	// check fails as soon as a failure is reported.
+	maxAge time.Duration
+	// lock protects lastErr and lastSuccessTime
+	lock sync.RWMutex
+	// lastErr is the error of the last reported status (nil if the last reported status is successful)
+	lastErr error
+	// lastSuccessTime is the time of the last successful status
+	lastSuccessTime time.Time
+}
+
+// NewStatusHealthCheck Constructor for StatusHealthCheck
+func NewStatusHealthCheck(name string, maxAge time.Duration) *StatusHealthCheck {
+	return &StatusHealthCheck{
+		name:    name,
+		maxAge:  maxAge,
+		lastErr: _noStatusReportedErr,
+	}
+}
+
+// Name returns the name of the health check
+func (check *StatusHealthCheck) Name() string {
+	return check.name
+}
+
+// Check returns nil if a successful status was reported in the last maxAge (or, if maxAge is zero, if the last reported
+// status is successful), otherwise returns error.
+func (check *StatusHealthCheck) Check(_ *http.Request) error {
+	check.lock.RLock()
+	defer check.lock.RUnlock()
+
+	if check.lastSuccessTime.IsZero() || (check.maxAge == 0 && check.lastErr != nil) {
+		return errors.Wrapf(check.lastErr, "health check <%s> failed", check.name)
+	}
+	if check.maxAge > 0 {
+		if age := time.Since(check.lastSuccessTime); age > check.maxAge {
+			return fmt.Errorf("health check <%s> failed: last successful status is %v old (max age is %v), last error: %v", check.name, age, check.maxAge, check.lastErr)
+		}
+	}
+	return nil
+}
+
+// Report reports the status of the checked dependency - nil for success or the error of the failure.
+func (check *StatusHealthCheck) Report(err error) {
+	check.lock.Lock()
+	defer check.lock.Unlock()
+
+	check.lastErr = err
+	if err == nil {
+		check.lastSuccessTime = time.Now()
+	}
+}
+
+// RunEveryTick runs action immediately and then every tick (in the background), and reports its result to the health check.
+func (check *StatusHealthCheck) RunEveryTick(duration time.Duration, action func() error) {
+	go func() {
+		check.Report(action())
+		ticker := time.NewTicker(duration)
+		for range ticker.C {
+			check.Report(action())
+		}
+	}()
+}
@@ -0,0 +1,86 @@
+package health
+
+import (
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/suite"
+	"testing"
+	"time"
+)
+
+const _checkName = "redis"
+
+type TestSuiteStatusHealthCheck struct {
+	suite.Suite
+}
+
+func (suite *TestSuiteStatusHealthCheck) Test_Check_NoStatusReported_Error() {
+	check := NewStatusHealthCheck(_checkName, 0)
+
+	err := check.Check(nil)
+
+	suite.ErrorIs(err, _noStatusReportedErr)
+}
+
+func (suite *TestSuiteStatusHealthCheck) Test_Check_SuccessReported_Nil() {
+	check := NewStatusHealthCheck(_checkName, time.Minute)
+	check.Report(nil)
+
+	suite.Nil(check.Check(nil))
+	suite.Equal(_checkName, check.Name())
+}
+
+func (suite *TestSuiteStatusHealthCheck) Test_Check_FailureReportedAfterSuccess_Error() {
+	expectedErr := errors.New("ping failed")
+	check := NewStatusHealthCheck(_checkName, 0)
+	check.Report(nil)
+	check.Report(expectedErr)
+
+	err := check.Check(nil)
+
+	suite.ErrorIs(err, expectedErr)
+}
+
+func (suite *TestSuiteStatusHealthCheck) Test_Check_FailureReportedAfterSuccessInMaxAge_Nil() {
+	check := NewStatusHealthCheck(_checkName, time.Minute)
+	check.Report(nil)
+	check.Report(errors.New("ping failed"))
+
+	suite.Nil(check.Check(nil))
+}
+
+func (suite *TestSuiteStatusHealthCheck) Test_Check_OnlyFailuresReported_Error() {
+	expectedErr := errors.New("ping failed")
+	check := NewStatusHealthCheck(_checkName, time.Minute)
+	check.Report(expectedErr)
+
+	err := check.Check(nil)
+
+	suite.ErrorIs(err, expectedErr)
+}
+
+func (suite *TestSuiteStatusHealthCheck) Test_Check_SuccessOlderThanMaxAge_Error() {
+	check := NewStatusHealthCheck(_checkName, time.Millisecond)
+	check.Report(nil)
+	time.Sleep(5 * time.Millisecond)
+
+	err := check.Check(nil)
+
+	suite.NotNil(err)
+}
+
+func (suite *TestSuiteStatusHealthCheck) Test_RunEveryTick_ActionRunsImmediately_ResultReported() {
+	check := NewStatusHealthCheck(_checkName, 0)
+	called := make(chan struct{})
+
+	check.RunEveryTick(time.Hour, func() error {
+		defer close(called)
+		return nil
+	})
+	<-called
+
+	suite.Eventually(func() bool { return check.Check(nil) == nil }, time.Second, time.Millisecond)
+}
+
+func TestSuiteStatusHealthCheck_Run(t *testing.T) {
+	suite.Run(t, new(TestSuiteStatusHealthCheck))
+}
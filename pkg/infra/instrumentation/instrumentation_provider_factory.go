@@ -3,6 +3,14 @@ package instrumentation
 import (
 	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/instrumentation/metric"
 	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/instrumentation/trace"
+	"github.com/pkg/errors"
+)
+
+const (
+	// TivanMetricSubmitterBackend is the backend of metrics that are submitted to Geneva/MDM using Tivan
+	TivanMetricSubmitterBackend = "tivan"
+	// PrometheusMetricSubmitterBackend is the backend of metrics that are exposed on the prometheus metrics endpoint
+	PrometheusMetricSubmitterBackend = "prometheus"
 )
 
 // IInstrumentationProviderFactory InstrumentationFactory - Instrumentation factory interface
@@ -20,29 +28,59 @@ type InstrumentationProviderFactory struct {
 	configuration *InstrumentationProviderConfiguration
 	//tracerFactory is the factory that creates the tracer.
 	tracerFactory trace.ITracerFactory
-	// metricSubmitterFactory is the factory that creates the metric submitter
-	metricSubmitterFactory metric.IMetricSubmitterFactory
+	// metricSubmitterFactories is mapping between metric submitter backend and the factory that creates its metric submitter
+	metricSubmitterFactories map[string]metric.IMetricSubmitterFactory
 }
 
 // InstrumentationProviderConfiguration is the configuration of the instrumentation.
 type InstrumentationProviderConfiguration struct {
+	// MetricSubmitterBackends are the backends that the metrics are submitted to (TivanMetricSubmitterBackend, PrometheusMetricSubmitterBackend).
+	// If empty - the metrics are submitted to Tivan.
+	MetricSubmitterBackends []string
 }
 
 // NewInstrumentationProviderFactory returns new InstrumentationFactory
 func NewInstrumentationProviderFactory(
 	configuration *InstrumentationProviderConfiguration,
 	tracerFactory trace.ITracerFactory,
-	metricSubmitterFactory metric.IMetricSubmitterFactory) (factory *InstrumentationProviderFactory) {
+	metricSubmitterFactories map[string]metric.IMetricSubmitterFactory) (factory *InstrumentationProviderFactory) {
 	return &InstrumentationProviderFactory{
-		configuration:          configuration,
-		tracerFactory:          tracerFactory,
-		metricSubmitterFactory: metricSubmitterFactory,
+		configuration:            configuration,
+		tracerFactory:            tracerFactory,
+		metricSubmitterFactories: metricSubmitterFactories,
 	}
 }
 
 // CreateInstrumentationProvider creates instrumentation using Tivan infra.
+// The metrics are submitted to the configured backends - in case that more than one backend is configured, each metric is submitted to all of them.
 func (factory *InstrumentationProviderFactory) CreateInstrumentationProvider() (instrumentationProvider IInstrumentationProvider, err error) {
-	metricSubmitter := factory.metricSubmitterFactory.CreateMetricSubmitter()
+	metricSubmitter, err := factory.createMetricSubmitter()
+	if err != nil {
+		return nil, errors.Wrap(err, "InstrumentationProviderFactory.CreateInstrumentationProvider failed to create metric submitter")
+	}
 	tracer := factory.tracerFactory.CreateTracer()
 	return NewInstrumentationProvider(tracer, metricSubmitter), nil
 }
+
+// createMetricSubmitter creates the metric submitter of the configured backends.
+// Returns error in case that one of the backends has no metric submitter factory.
+func (factory *InstrumentationProviderFactory) createMetricSubmitter() (metric.IMetricSubmitter, error) {
+	backends := factory.configuration.MetricSubmitterBackends
+	if len(backends) == 0 {
+		backends = []string{TivanMetricSubmitterBackend}
+	}
+
+	metricSubmitters := make([]metric.IMetricSubmitter, 0, len(backends))
+	for _, backend := range backends {
+		metricSubmitterFactory, exists := factory.metricSubmitterFactories[backend]
+		if !exists {
+			return nil, errors.Errorf("unsupported metric submitter backend <%s>", backend)
+		}
+		metricSubmitters = append(metricSubmitters, metricSubmitterFactory.CreateMetricSubmitter())
+	}
+
+	if len(metricSubmitters) == 1 {
+		return metricSubmitters[0], nil
+	}
+	return metric.NewFanOutMetricSubmitter(metricSubmitters...), nil
+}
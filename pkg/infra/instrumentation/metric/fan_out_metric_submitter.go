@@ -0,0 +1,25 @@
+package metric
+
+// FanOutMetricSubmitter implements IMetricSubmitter interface
+var _ IMetricSubmitter = (*FanOutMetricSubmitter)(nil)
+
+// FanOutMetricSubmitter is implementation of IMetricSubmitter that submits each metric to all of its metric submitters
+// (e.g. Tivan and Prometheus)
+type FanOutMetricSubmitter struct {
+	// metricSubmitters are the metric submitters that each metric is submitted to
+	metricSubmitters []IMetricSubmitter
+}
+
+// NewFanOutMetricSubmitter Ctor for FanOutMetricSubmitter
+func NewFanOutMetricSubmitter(metricSubmitters ...IMetricSubmitter) *FanOutMetricSubmitter {
+	return &FanOutMetricSubmitter{
+		metricSubmitters: metricSubmitters,
+	}
+}
+
+// SendMetric sends the metric to all of the metric submitters
+func (fanOut *FanOutMetricSubmitter) SendMetric(value int, metric IMetric) {
+	for _, metricSubmitter := range fanOut.metricSubmitters {
+		metricSubmitter.SendMetric(value, metric)
+	}
+}
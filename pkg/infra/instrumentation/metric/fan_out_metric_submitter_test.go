@@ -0,0 +1,37 @@
+package metric_test
+
+import (
+	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/instrumentation/metric"
+	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/instrumentation/metric/mocks"
+	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/instrumentation/metric/util"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/suite"
+	"testing"
+)
+
+type TestSuiteFanOutMetricSubmitter struct {
+	suite.Suite
+}
+
+func (suite *TestSuiteFanOutMetricSubmitter) Test_SendMetric_MultipleSubmitters_MetricSentToAll() {
+	firstSubmitterMock := new(mocks.IMetricSubmitter)
+	secondSubmitterMock := new(mocks.IMetricSubmitter)
+	errorMetric := util.NewErrorEncounteredMetric(errors.New("error"), "context")
+	firstSubmitterMock.On("SendMetric", 3, errorMetric).Once()
+	secondSubmitterMock.On("SendMetric", 3, errorMetric).Once()
+
+	metric.NewFanOutMetricSubmitter(firstSubmitterMock, secondSubmitterMock).SendMetric(3, errorMetric)
+
+	firstSubmitterMock.AssertExpectations(suite.T())
+	secondSubmitterMock.AssertExpectations(suite.T())
+}
+
+func (suite *TestSuiteFanOutMetricSubmitter) Test_SendMetric_NoSubmitters_NoPanic() {
+	suite.NotPanics(func() {
+		metric.NewFanOutMetricSubmitter().SendMetric(1, util.NewErrorEncounteredMetric(errors.New("error"), "context"))
+	})
+}
+
+func TestSuiteFanOutMetricSubmitter_Run(t *testing.T) {
+	suite.Run(t, new(TestSuiteFanOutMetricSubmitter))
+}
@@ -0,0 +1,61 @@
+// Package opentelemetry contains the setup of the OpenTelemetry SDK that exports the spans of trace.ITracerProvider over OTLP.
+package opentelemetry
+
+import (
+	"context"
+	"github.com/pkg/errors"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.7.0"
+)
+
+// ShutdownFunc flushes the spans that weren't exported yet and stops the export
+type ShutdownFunc func(ctx context.Context) error
+
+// OTelTracerProviderFactory creates and registers the global OpenTelemetry tracer provider
+type OTelTracerProviderFactory struct {
+	// configuration is the configuration of the OpenTelemetry tracing
+	configuration *OTelTracingConfiguration
+}
+
+// NewOTelTracerProviderFactory Ctor for OTelTracerProviderFactory
+func NewOTelTracerProviderFactory(configuration *OTelTracingConfiguration) *OTelTracerProviderFactory {
+	return &OTelTracerProviderFactory{
+		configuration: configuration,
+	}
+}
+
+// RegisterGlobalTracerProvider creates OpenTelemetry tracer provider that exports the spans over OTLP/HTTP to the configured
+// collector and registers it (and the W3C trace context propagator) as the global one - used by trace.TracerProvider.StartSpan.
+// If tracing isn't enabled, nothing is registered (spans are no-op) and the returned ShutdownFunc does nothing.
+func (factory *OTelTracerProviderFactory) RegisterGlobalTracerProvider(ctx context.Context) (ShutdownFunc, error) {
+	if !factory.configuration.Enabled {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	exporterOptions := []otlptracehttp.Option{otlptracehttp.WithEndpoint(factory.configuration.CollectorEndpoint)}
+	if factory.configuration.Insecure {
+		exporterOptions = append(exporterOptions, otlptracehttp.WithInsecure())
+	}
+	exporter, err := otlptracehttp.New(ctx, exporterOptions...)
+	if err != nil {
+		return nil, errors.Wrap(err, "OTelTracerProviderFactory.RegisterGlobalTracerProvider failed to create OTLP exporter")
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(semconv.SchemaURL, semconv.ServiceNameKey.String(factory.configuration.ServiceName)))
+	if err != nil {
+		return nil, errors.Wrap(err, "OTelTracerProviderFactory.RegisterGlobalTracerProvider failed to create resource")
+	}
+
+	tracerProvider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(factory.configuration.SamplingRatio))),
+	)
+	otel.SetTracerProvider(tracerProvider)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	return tracerProvider.Shutdown, nil
+}
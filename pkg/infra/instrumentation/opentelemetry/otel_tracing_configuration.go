@@ -0,0 +1,15 @@
+package opentelemetry
+
+// OTelTracingConfiguration is the configuration of the OpenTelemetry tracing (spans export)
+type OTelTracingConfiguration struct {
+	// Enabled is whether spans are exported. If false - spans are no-op.
+	Enabled bool
+	// CollectorEndpoint is the host and port of the OTLP/HTTP collector that the spans are exported to (e.g. "localhost:4318")
+	CollectorEndpoint string
+	// Insecure is whether the spans are exported to the collector over HTTP instead of HTTPS (e.g. local collector sidecar)
+	Insecure bool
+	// SamplingRatio is the ratio (between 0 and 1) of the admission requests that their spans are sampled
+	SamplingRatio float64
+	// ServiceName is the name of the service that is attached to the exported spans
+	ServiceName string
+}
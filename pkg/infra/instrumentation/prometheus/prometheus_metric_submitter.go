@@ -0,0 +1,259 @@
+// Package prometheus contains the Prometheus implementation of metric.IMetricSubmitter
+package prometheus
+
+import (
+	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/instrumentation/metric"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+const (
+	// _droppedMetricsMetricName is the name of the counter of metrics that couldn't be submitted to prometheus
+	_droppedMetricsMetricName = "prometheus_dropped_metrics_total"
+	// _metricNameLabel is the label of the metric's name in the counter of dropped metrics
+	_metricNameLabel = "metric_name"
+)
+
+var (
+	// _defaultHistogramBuckets are the upper bounds of the histograms' buckets in case that no buckets are configured - 1 to 16384
+	_defaultHistogramBuckets = prometheus.ExponentialBuckets(1, 2, 15)
+)
+
+// PrometheusMetricSubmitter implements metric.IMetricSubmitter interface
+var _ metric.IMetricSubmitter = (*PrometheusMetricSubmitter)(nil)
+
+// PrometheusMetricSubmitter submits metrics to prometheus.
+// Each metric (by its name) is submitted as a labeled counter, as a labeled histogram (configured by PrometheusMetricSubmitterConfiguration.HistogramMetrics)
+// or as a labeled gauge (configured by PrometheusMetricSubmitterConfiguration.GaugeMetrics)
+// that its labels are the metric's dimensions. The collectors are created and registered on the first submission of each metric.
+// Metrics that their dimensions keys are different from the dimensions keys of their first submission are dropped (prometheus
+// requires a constant set of labels per metric) and counted by the dropped metrics counter.
+type PrometheusMetricSubmitter struct {
+	// configuration is the configuration of the metric submitter
+	configuration *PrometheusMetricSubmitterConfiguration
+	// registerer is the prometheus registerer that the collectors are registered on (e.g. controller-runtime's metrics registry)
+	registerer prometheus.Registerer
+	// histogramMetrics is set of the names of the metrics that are submitted as histograms
+	histogramMetrics map[string]bool
+	// gaugeMetrics is set of the names of the metrics that are submitted as gauges
+	gaugeMetrics map[string]bool
+	// lock protects counters, histograms and gauges
+	lock sync.Mutex
+	// counters is mapping between metric's name and its counter
+	counters map[string]*prometheus.CounterVec
+	// histograms is mapping between metric's name and its histogram
+	histograms map[string]*prometheus.HistogramVec
+	// gauges is mapping between metric's name and its gauge
+	gauges map[string]*prometheus.GaugeVec
+	// droppedMetricsCounter counts the metrics that couldn't be submitted - nil if it couldn't be registered.
+	droppedMetricsCounter *prometheus.CounterVec
+}
+
+// PrometheusMetricSubmitterConfiguration is the configuration of PrometheusMetricSubmitter
+type PrometheusMetricSubmitterConfiguration struct {
+	// Namespace is the prefix of the names of the prometheus metrics (e.g. "azdproxy")
+	Namespace string
+	// HistogramMetrics are the names of the metrics (metric.IMetric.MetricName) that are submitted as histograms (e.g. latencies).
+	// The rest of the metrics are submitted as counters.
+	HistogramMetrics []string
+	// GaugeMetrics are the names of the metrics (metric.IMetric.MetricName) that are submitted as gauges - the submitted value
+	// replaces the previous value (e.g. current state).
+	GaugeMetrics []string
+	// HistogramBuckets are the upper bounds of the histograms' buckets. If empty - exponential buckets from 1 to 16384 are used.
+	HistogramBuckets []float64
+}
+
+// NewPrometheusMetricSubmitter Ctor for PrometheusMetricSubmitter
+func NewPrometheusMetricSubmitter(configuration *PrometheusMetricSubmitterConfiguration, registerer prometheus.Registerer) *PrometheusMetricSubmitter {
+	histogramMetrics := make(map[string]bool, len(configuration.HistogramMetrics))
+	for _, metricName := range configuration.HistogramMetrics {
+		histogramMetrics[metricName] = true
+	}
+	gaugeMetrics := make(map[string]bool, len(configuration.GaugeMetrics))
+	for _, metricName := range configuration.GaugeMetrics {
+		gaugeMetrics[metricName] = true
+	}
+
+	submitter := &PrometheusMetricSubmitter{
+		configuration:    configuration,
+		registerer:       registerer,
+		histogramMetrics: histogramMetrics,
+		gaugeMetrics:     gaugeMetrics,
+		counters:         map[string]*prometheus.CounterVec{},
+		histograms:       map[string]*prometheus.HistogramVec{},
+		gauges:           map[string]*prometheus.GaugeVec{},
+	}
+
+	droppedMetricsCounter := prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: configuration.Namespace,
+		Name:      _droppedMetricsMetricName,
+		Help:      "Number of metrics that couldn't be submitted to prometheus",
+	}, []string{_metricNameLabel})
+	if collector, err := submitter.register(droppedMetricsCounter); err == nil {
+		submitter.droppedMetricsCounter = collector.(*prometheus.CounterVec)
+	}
+	return submitter
+}
+
+// SendMetric submits the metric to prometheus - adds the value to the metric's counter, observes the value in the metric's histogram
+// or sets the value of the metric's gauge.
+func (submitter *PrometheusMetricSubmitter) SendMetric(value int, metric metric.IMetric) {
+	labels := getLabels(metric.MetricDimension())
+
+	var err error
+	if submitter.histogramMetrics[metric.MetricName()] {
+		err = submitter.observeHistogram(metric.MetricName(), labels, float64(value))
+	} else if submitter.gaugeMetrics[metric.MetricName()] {
+		err = submitter.setGauge(metric.MetricName(), labels, float64(value))
+	} else {
+		err = submitter.addToCounter(metric.MetricName(), labels, float64(value))
+	}
+
+	if err != nil && submitter.droppedMetricsCounter != nil {
+		submitter.droppedMetricsCounter.WithLabelValues(metric.MetricName()).Inc()
+	}
+}
+
+// addToCounter adds the value to the counter of the metric with the labels. Creates and registers the counter in case that it doesn't exist.
+func (submitter *PrometheusMetricSubmitter) addToCounter(metricName string, labels prometheus.Labels, value float64) error {
+	submitter.lock.Lock()
+	counter, exists := submitter.counters[metricName]
+	if !exists {
+		collector, err := submitter.register(prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: submitter.configuration.Namespace,
+			Name:      toSnakeCase(metricName) + "_total",
+			Help:      metricName,
+		}, getLabelNames(labels)))
+		if err != nil {
+			submitter.lock.Unlock()
+			return err
+		}
+		counter = collector.(*prometheus.CounterVec)
+		submitter.counters[metricName] = counter
+	}
+	submitter.lock.Unlock()
+
+	labeledCounter, err := counter.GetMetricWith(labels)
+	if err != nil {
+		return errors.Wrapf(err, "metric <%s> has different dimensions than its first submission", metricName)
+	}
+	labeledCounter.Add(value)
+	return nil
+}
+
+// observeHistogram observes the value in the histogram of the metric with the labels. Creates and registers the histogram in case that it doesn't exist.
+func (submitter *PrometheusMetricSubmitter) observeHistogram(metricName string, labels prometheus.Labels, value float64) error {
+	submitter.lock.Lock()
+	histogram, exists := submitter.histograms[metricName]
+	if !exists {
+		buckets := submitter.configuration.HistogramBuckets
+		if len(buckets) == 0 {
+			buckets = _defaultHistogramBuckets
+		}
+		collector, err := submitter.register(prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: submitter.configuration.Namespace,
+			Name:      toSnakeCase(metricName),
+			Help:      metricName,
+			Buckets:   buckets,
+		}, getLabelNames(labels)))
+		if err != nil {
+			submitter.lock.Unlock()
+			return err
+		}
+		histogram = collector.(*prometheus.HistogramVec)
+		submitter.histograms[metricName] = histogram
+	}
+	submitter.lock.Unlock()
+
+	labeledHistogram, err := histogram.GetMetricWith(labels)
+	if err != nil {
+		return errors.Wrapf(err, "metric <%s> has different dimensions than its first submission", metricName)
+	}
+	labeledHistogram.Observe(value)
+	return nil
+}
+
+// setGauge sets the value of the gauge of the metric with the labels. Creates and registers the gauge in case that it doesn't exist.
+func (submitter *PrometheusMetricSubmitter) setGauge(metricName string, labels prometheus.Labels, value float64) error {
+	submitter.lock.Lock()
+	gauge, exists := submitter.gauges[metricName]
+	if !exists {
+		collector, err := submitter.register(prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: submitter.configuration.Namespace,
+			Name:      toSnakeCase(metricName),
+			Help:      metricName,
+		}, getLabelNames(labels)))
+		if err != nil {
+			submitter.lock.Unlock()
+			return err
+		}
+		gauge = collector.(*prometheus.GaugeVec)
+		submitter.gauges[metricName] = gauge
+	}
+	submitter.lock.Unlock()
+
+	labeledGauge, err := gauge.GetMetricWith(labels)
+	if err != nil {
+		return errors.Wrapf(err, "metric <%s> has different dimensions than its first submission", metricName)
+	}
+	labeledGauge.Set(value)
+	return nil
+}
+
+// register registers the collector on the registerer. In case that an equal collector is already registered (e.g. by another
+// submitter that uses the same registerer) - returns the registered collector.
+func (submitter *PrometheusMetricSubmitter) register(collector prometheus.Collector) (prometheus.Collector, error) {
+	if err := submitter.registerer.Register(collector); err != nil {
+		if alreadyRegisteredErr, ok := err.(prometheus.AlreadyRegisteredError); ok {
+			return alreadyRegisteredErr.ExistingCollector, nil
+		}
+		return nil, errors.Wrap(err, "failed to register prometheus collector")
+	}
+	return collector, nil
+}
+
+// getLabels converts the metric's dimensions to prometheus labels
+func getLabels(dimensions []metric.Dimension) prometheus.Labels {
+	labels := make(prometheus.Labels, len(dimensions))
+	for _, dimension := range dimensions {
+		labels[toSnakeCase(dimension.Key)] = dimension.Value
+	}
+	return labels
+}
+
+// getLabelNames returns the sorted names of the labels
+func getLabelNames(labels prometheus.Labels) []string {
+	labelNames := make([]string, 0, len(labels))
+	for labelName := range labels {
+		labelNames = append(labelNames, labelName)
+	}
+	sort.Strings(labelNames)
+	return labelNames
+}
+
+// toSnakeCase converts CamelCase name (e.g. "HandlerHandleLatency", "HasSBOM") to valid prometheus snake case name
+// (e.g. "handler_handle_latency", "has_sbom")
+func toSnakeCase(name string) string {
+	runes := []rune(name)
+	var builder strings.Builder
+	for i, r := range runes {
+		switch {
+		case unicode.IsUpper(r):
+			// Start a new word on lower-to-upper transition or at the last upper letter of an acronym that is followed by a lower letter
+			if i > 0 && (unicode.IsLower(runes[i-1]) || unicode.IsDigit(runes[i-1]) ||
+				(unicode.IsUpper(runes[i-1]) && i+1 < len(runes) && unicode.IsLower(runes[i+1]))) {
+				builder.WriteRune('_')
+			}
+			builder.WriteRune(unicode.ToLower(r))
+		case r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)):
+			builder.WriteRune(r)
+		default:
+			builder.WriteRune('_')
+		}
+	}
+	return builder.String()
+}
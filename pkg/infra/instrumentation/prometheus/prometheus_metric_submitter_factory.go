@@ -0,0 +1,30 @@
+package prometheus
+
+import (
+	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/instrumentation/metric"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// PrometheusMetricSubmitterFactory implements metric.IMetricSubmitterFactory interface
+var _ metric.IMetricSubmitterFactory = (*PrometheusMetricSubmitterFactory)(nil)
+
+// PrometheusMetricSubmitterFactory creates metric submitter that submits metrics to prometheus
+type PrometheusMetricSubmitterFactory struct {
+	// configuration is the configuration of the metric submitter
+	configuration *PrometheusMetricSubmitterConfiguration
+	// registerer is the prometheus registerer that the metrics are registered on (e.g. controller-runtime's metrics registry)
+	registerer prometheus.Registerer
+}
+
+// NewPrometheusMetricSubmitterFactory creates PrometheusMetricSubmitterFactory that creates metric submitters that register their metrics on the registerer
+func NewPrometheusMetricSubmitterFactory(configuration *PrometheusMetricSubmitterConfiguration, registerer prometheus.Registerer) (factory *PrometheusMetricSubmitterFactory) {
+	return &PrometheusMetricSubmitterFactory{
+		configuration: configuration,
+		registerer:    registerer,
+	}
+}
+
+// CreateMetricSubmitter creates new IMetricSubmitter that submits metrics to prometheus
+func (factory *PrometheusMetricSubmitterFactory) CreateMetricSubmitter() (metricSubmitter metric.IMetricSubmitter) {
+	return NewPrometheusMetricSubmitter(factory.configuration, factory.registerer)
+}
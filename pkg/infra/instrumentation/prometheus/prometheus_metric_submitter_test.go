@@ -0,0 +1,120 @@
+package prometheus
+
+import (
+	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/instrumentation/metric"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/suite"
+	"testing"
+)
+
+const (
+	_namespace         = "azdproxy"
+	_latencyMetricName = "HandlerHandleLatency"
+	_counterMetricName = "SupplyChainArtifacts"
+	_gaugeMetricName   = "CircuitBreakerCurrentState"
+)
+
+// testMetric is metric.IMetric with the given name and dimensions
+type testMetric struct {
+	name       string
+	dimensions []metric.Dimension
+}
+
+func (m *testMetric) MetricName() string {
+	return m.name
+}
+
+func (m *testMetric) MetricDimension() []metric.Dimension {
+	return m.dimensions
+}
+
+type TestSuitePrometheusMetricSubmitter struct {
+	suite.Suite
+	registry  *prometheus.Registry
+	submitter *PrometheusMetricSubmitter
+}
+
+func (suite *TestSuitePrometheusMetricSubmitter) SetupTest() {
+	suite.registry = prometheus.NewRegistry()
+	suite.submitter = NewPrometheusMetricSubmitter(&PrometheusMetricSubmitterConfiguration{
+		Namespace:        _namespace,
+		HistogramMetrics: []string{_latencyMetricName},
+		GaugeMetrics:     []string{_gaugeMetricName},
+	}, suite.registry)
+}
+
+func (suite *TestSuitePrometheusMetricSubmitter) Test_SendMetric_CounterMetric_AddedToLabeledCounter() {
+	hasSBOM := &testMetric{name: _counterMetricName, dimensions: []metric.Dimension{{Key: "HasSBOM", Value: "true"}, {Key: "HasAttestation", Value: "false"}}}
+	noSBOM := &testMetric{name: _counterMetricName, dimensions: []metric.Dimension{{Key: "HasSBOM", Value: "false"}, {Key: "HasAttestation", Value: "false"}}}
+
+	suite.submitter.SendMetric(1, hasSBOM)
+	suite.submitter.SendMetric(2, hasSBOM)
+	suite.submitter.SendMetric(1, noSBOM)
+
+	counter := suite.submitter.counters[_counterMetricName]
+	suite.Equal(float64(3), testutil.ToFloat64(counter.WithLabelValues("false", "true")))
+	suite.Equal(float64(1), testutil.ToFloat64(counter.WithLabelValues("false", "false")))
+	suite.Equal(2, suite.gatherAndCount("azdproxy_supply_chain_artifacts_total"))
+}
+
+func (suite *TestSuitePrometheusMetricSubmitter) Test_SendMetric_HistogramMetric_ObservedInHistogram() {
+	latency := &testMetric{name: _latencyMetricName, dimensions: []metric.Dimension{{Key: "Context", Value: "Handle"}}}
+
+	suite.submitter.SendMetric(10, latency)
+	suite.submitter.SendMetric(300, latency)
+
+	suite.Equal(1, suite.gatherAndCount("azdproxy_handler_handle_latency"))
+	suite.Equal(0, suite.gatherAndCount("azdproxy_handler_handle_latency_total"))
+	suite.Empty(suite.submitter.counters)
+}
+
+func (suite *TestSuitePrometheusMetricSubmitter) Test_SendMetric_GaugeMetric_ValueReplaced() {
+	state := &testMetric{name: _gaugeMetricName, dimensions: []metric.Dimension{{Key: "Name", Value: "ARGClient"}}}
+
+	suite.submitter.SendMetric(2, state)
+	suite.submitter.SendMetric(1, state)
+
+	suite.Equal(float64(1), testutil.ToFloat64(suite.submitter.gauges[_gaugeMetricName].WithLabelValues("ARGClient")))
+	suite.Equal(1, suite.gatherAndCount("azdproxy_circuit_breaker_current_state"))
+	suite.Empty(suite.submitter.counters)
+}
+
+func (suite *TestSuitePrometheusMetricSubmitter) Test_SendMetric_DifferentDimensionsKeys_MetricDropped() {
+	suite.submitter.SendMetric(1, &testMetric{name: _counterMetricName, dimensions: []metric.Dimension{{Key: "HasSBOM", Value: "true"}}})
+	suite.submitter.SendMetric(1, &testMetric{name: _counterMetricName, dimensions: []metric.Dimension{{Key: "OtherKey", Value: "true"}}})
+
+	suite.Equal(float64(1), testutil.ToFloat64(suite.submitter.counters[_counterMetricName].WithLabelValues("true")))
+	suite.Equal(float64(1), testutil.ToFloat64(suite.submitter.droppedMetricsCounter.WithLabelValues(_counterMetricName)))
+}
+
+func (suite *TestSuitePrometheusMetricSubmitter) Test_NewPrometheusMetricSubmitter_SharedRegistry_CollectorsReused() {
+	otherSubmitter := NewPrometheusMetricSubmitter(suite.submitter.configuration, suite.registry)
+	dimensions := []metric.Dimension{{Key: "HasSBOM", Value: "true"}}
+
+	suite.submitter.SendMetric(1, &testMetric{name: _counterMetricName, dimensions: dimensions})
+	otherSubmitter.SendMetric(1, &testMetric{name: _counterMetricName, dimensions: dimensions})
+
+	suite.Equal(float64(2), testutil.ToFloat64(otherSubmitter.counters[_counterMetricName].WithLabelValues("true")))
+	suite.Equal(suite.submitter.droppedMetricsCounter, otherSubmitter.droppedMetricsCounter)
+}
+
+func (suite *TestSuitePrometheusMetricSubmitter) Test_toSnakeCase() {
+	suite.Equal("handler_handle_latency", toSnakeCase("HandlerHandleLatency"))
+	suite.Equal("has_sbom", toSnakeCase("HasSBOM"))
+	suite.Equal("sbom_format", toSnakeCase("SBOMFormat"))
+	suite.Equal("error_type", toSnakeCase("errorType"))
+	suite.Equal("cache_client_get", toSnakeCase("CacheClientGet"))
+	suite.Equal("dimension_key", toSnakeCase("Dimension-Key"))
+}
+
+// gatherAndCount returns the number of the series of the metric in the registry
+func (suite *TestSuitePrometheusMetricSubmitter) gatherAndCount(metricName string) int {
+	count, err := testutil.GatherAndCount(suite.registry, metricName)
+	suite.Nil(err)
+	return count
+}
+
+func TestSuitePrometheusMetricSubmitter_Run(t *testing.T) {
+	suite.Run(t, new(TestSuitePrometheusMetricSubmitter))
+}
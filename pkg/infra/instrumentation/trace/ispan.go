@@ -0,0 +1,14 @@
+package trace
+
+// ISpan is a span of a single operation (e.g. handling an admission request, resolving a digest).
+// Spans that are started from the context of another span are its children, so all the spans of an admission request are correlated.
+type ISpan interface {
+	// End ends the span. Must be called exactly once for each started span.
+	End()
+	// RecordError records the error on the span and marks the span as failed.
+	RecordError(err error)
+	// SetAttributes sets attributes on the span. keysAndValues are pairs of string key and value (like ITracer.Info).
+	SetAttributes(keysAndValues ...interface{})
+	// AddEvent adds an event (e.g. timeout encountered) to the span with attributes in the same format as SetAttributes.
+	AddEvent(name string, keysAndValues ...interface{})
+}
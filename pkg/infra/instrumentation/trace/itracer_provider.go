@@ -1,9 +1,16 @@
 package trace
 
+import "context"
+
 // ITracerProvider provides tracer. the difference between ITracerProvider and ITracerFactory is that ITracerFactory
 // creates ITracer , and the ITracerProvider doesn't create tracer,it provides exists tracer in specific context.
 type ITracerProvider interface {
 	// GetTracer Gets a tracer with specific context. the context is according to specific method
 	//(when you create the ITraceProvider you choose the struct context)
 	GetTracer(context string) (tracer ITracer)
+
+	// StartSpan starts a span of the operation name (e.g. "Resolve") as a child of the span of ctx (if exists), with keysAndValues
+	// as its attributes. It returns the span and ctx that contains it - ctx should be passed to the inner operations
+	// so their spans are correlated to the span. The span should be ended (ISpan.End) when the operation ends.
+	StartSpan(ctx context.Context, name string, keysAndValues ...interface{}) (context.Context, ISpan)
 }
@@ -0,0 +1,25 @@
+package trace
+
+// NoOpSpan implements ISpan interface
+var _ ISpan = (*NoOpSpan)(nil)
+
+// NoOpSpan is implementation that does nothing of ISpan
+// NoOp is used for testing/debugging.
+type NoOpSpan struct{}
+
+// NewNoOpSpan Ctor for NoOpSpan
+func NewNoOpSpan() *NoOpSpan {
+	return &NoOpSpan{}
+}
+
+func (span *NoOpSpan) End() {
+}
+
+func (span *NoOpSpan) RecordError(err error) {
+}
+
+func (span *NoOpSpan) SetAttributes(keysAndValues ...interface{}) {
+}
+
+func (span *NoOpSpan) AddEvent(name string, keysAndValues ...interface{}) {
+}
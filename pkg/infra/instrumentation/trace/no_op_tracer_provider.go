@@ -1,5 +1,7 @@
 package trace
 
+import "context"
+
 // NoOpTracerProvider implements ITracerProvider interface
 var _ ITracerProvider = (*NoOpTracerProvider)(nil)
 
@@ -18,3 +20,8 @@ func NewNoOpTracerProvider() *NoOpTracerProvider {
 func (provider *NoOpTracerProvider) GetTracer(context string) (tracer ITracer) {
 	return NewNoOpTracer()
 }
+
+// StartSpan returns ctx as is and no-op span
+func (provider *NoOpTracerProvider) StartSpan(ctx context.Context, name string, keysAndValues ...interface{}) (context.Context, ISpan) {
+	return ctx, NewNoOpSpan()
+}
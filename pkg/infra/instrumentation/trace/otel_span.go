@@ -0,0 +1,76 @@
+package trace
+
+import (
+	"fmt"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	oteltrace "go.opentelemetry.io/otel/trace"
+)
+
+// OTelSpan implements ISpan interface
+var _ ISpan = (*OTelSpan)(nil)
+
+// OTelSpan is implementation of ISpan that wraps OpenTelemetry span.
+type OTelSpan struct {
+	// span is the wrapped OpenTelemetry span
+	span oteltrace.Span
+}
+
+// NewOTelSpan Ctor for OTelSpan
+func NewOTelSpan(span oteltrace.Span) *OTelSpan {
+	return &OTelSpan{
+		span: span,
+	}
+}
+
+// End ends the wrapped span
+func (span *OTelSpan) End() {
+	span.span.End()
+}
+
+// RecordError records the error on the wrapped span and sets its status to error
+func (span *OTelSpan) RecordError(err error) {
+	if err == nil {
+		return
+	}
+	span.span.RecordError(err)
+	span.span.SetStatus(codes.Error, err.Error())
+}
+
+// SetAttributes sets the keys and values as attributes of the wrapped span
+func (span *OTelSpan) SetAttributes(keysAndValues ...interface{}) {
+	span.span.SetAttributes(toAttributes(keysAndValues)...)
+}
+
+// AddEvent adds event with the keys and values as attributes to the wrapped span
+func (span *OTelSpan) AddEvent(name string, keysAndValues ...interface{}) {
+	span.span.AddEvent(name, oteltrace.WithAttributes(toAttributes(keysAndValues)...))
+}
+
+// toAttributes converts pairs of key and value to OpenTelemetry attributes.
+// Keys that aren't strings are formatted, and a key without value gets an empty value.
+func toAttributes(keysAndValues []interface{}) []attribute.KeyValue {
+	attributes := make([]attribute.KeyValue, 0, (len(keysAndValues)+1)/2)
+	for i := 0; i < len(keysAndValues); i += 2 {
+		key := fmt.Sprint(keysAndValues[i])
+		if i+1 >= len(keysAndValues) {
+			attributes = append(attributes, attribute.String(key, ""))
+			break
+		}
+		switch value := keysAndValues[i+1].(type) {
+		case string:
+			attributes = append(attributes, attribute.String(key, value))
+		case bool:
+			attributes = append(attributes, attribute.Bool(key, value))
+		case int:
+			attributes = append(attributes, attribute.Int(key, value))
+		case int64:
+			attributes = append(attributes, attribute.Int64(key, value))
+		case float64:
+			attributes = append(attributes, attribute.Float64(key, value))
+		default:
+			attributes = append(attributes, attribute.String(key, fmt.Sprint(value)))
+		}
+	}
+	return attributes
+}
@@ -0,0 +1,22 @@
+package trace
+
+import "context"
+
+// spanAttributesContextKey is the key of the span attributes in the context
+type spanAttributesContextKey struct{}
+
+// ContextWithSpanAttributes returns copy of ctx with keysAndValues (in the same format as ISpan.SetAttributes) that are set as attributes
+// of all the spans that are started from the returned context or from its children (e.g. the admission request UID).
+func ContextWithSpanAttributes(ctx context.Context, keysAndValues ...interface{}) context.Context {
+	inheritedAttributes := spanAttributesFromContext(ctx)
+	attributes := make([]interface{}, 0, len(inheritedAttributes)+len(keysAndValues))
+	attributes = append(attributes, inheritedAttributes...)
+	attributes = append(attributes, keysAndValues...)
+	return context.WithValue(ctx, spanAttributesContextKey{}, attributes)
+}
+
+// spanAttributesFromContext returns the span attributes that were set on ctx by ContextWithSpanAttributes
+func spanAttributesFromContext(ctx context.Context) []interface{} {
+	attributes, _ := ctx.Value(spanAttributesContextKey{}).([]interface{})
+	return attributes
+}
@@ -1,5 +1,15 @@
 package trace
 
+import (
+	"context"
+	"go.opentelemetry.io/otel"
+)
+
+const (
+	// _otelTracerName is the name of the OpenTelemetry tracer that the spans are started by (the instrumentation library name)
+	_otelTracerName = "github.com/Azure/AzureDefender-K8S-InClusterDefense"
+)
+
 // TracerProvider implements ITracerProvider interface
 var _ ITracerProvider = (*TracerProvider)(nil)
 
@@ -8,12 +18,15 @@ var _ ITracerProvider = (*TracerProvider)(nil)
 type TracerProvider struct {
 	// tracer of the TracerProvider.
 	tracer ITracer
+	// context is the struct level context of the TracerProvider - used as the prefix of the spans' names.
+	context string
 }
 
 // NewTracerProvider  gets an exists ITracer and context, and it wraps the tracer with the new context
 func NewTracerProvider(tracer ITracer, context string) (provider *TracerProvider) {
 	return &TracerProvider{
-		tracer: tracer.WithName(context),
+		tracer:  tracer.WithName(context),
+		context: context,
 	}
 }
 
@@ -21,3 +34,14 @@ func NewTracerProvider(tracer ITracer, context string) (provider *TracerProvider
 func (provider *TracerProvider) GetTracer(context string) (tracer ITracer) {
 	return provider.tracer.WithName(context)
 }
+
+// StartSpan starts OpenTelemetry span named "<struct context>.<name>" (e.g. "Tag2DigestResolver.Resolve") using the global
+// OpenTelemetry tracer provider. The attributes of ctx (see ContextWithSpanAttributes) are set on the span as well.
+// If OpenTelemetry tracing isn't set up, the global tracer provider creates no-op spans.
+func (provider *TracerProvider) StartSpan(ctx context.Context, name string, keysAndValues ...interface{}) (context.Context, ISpan) {
+	ctx, span := otel.Tracer(_otelTracerName).Start(ctx, provider.context+"."+name)
+	otelSpan := NewOTelSpan(span)
+	otelSpan.SetAttributes(spanAttributesFromContext(ctx)...)
+	otelSpan.SetAttributes(keysAndValues...)
+	return ctx, otelSpan
+}
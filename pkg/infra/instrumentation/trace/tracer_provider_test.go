@@ -0,0 +1,77 @@
+package trace
+
+import (
+	"context"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/suite"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	oteltrace "go.opentelemetry.io/otel/trace"
+	"testing"
+)
+
+type TestSuiteTracerProvider struct {
+	suite.Suite
+	spanRecorder           *tracetest.SpanRecorder
+	previousTracerProvider oteltrace.TracerProvider
+	provider               *TracerProvider
+}
+
+func (suite *TestSuiteTracerProvider) SetupTest() {
+	suite.spanRecorder = tracetest.NewSpanRecorder()
+	suite.previousTracerProvider = otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(suite.spanRecorder)))
+	suite.provider = NewTracerProvider(NewNoOpTracer(), "Handler")
+}
+
+func (suite *TestSuiteTracerProvider) TearDownTest() {
+	otel.SetTracerProvider(suite.previousTracerProvider)
+}
+
+func (suite *TestSuiteTracerProvider) Test_StartSpan_ChildOfContextAttributes_InheritsParentAndAttributes() {
+	ctx := ContextWithSpanAttributes(context.Background(), "admissionUID", "uid")
+	ctx, parent := suite.provider.StartSpan(ctx, "Handle", "kind", "Pod")
+	_, child := suite.provider.StartSpan(ctx, "getSingleContainerVulnerabilityScanInfo", "container", "nginx")
+	child.End()
+	parent.End()
+
+	ended := suite.spanRecorder.Ended()
+	suite.Len(ended, 2)
+	childSpan, parentSpan := ended[0], ended[1]
+	suite.Equal("Handler.getSingleContainerVulnerabilityScanInfo", childSpan.Name())
+	suite.Equal("Handler.Handle", parentSpan.Name())
+	suite.Equal(parentSpan.SpanContext().SpanID(), childSpan.Parent().SpanID())
+	suite.Equal(parentSpan.SpanContext().TraceID(), childSpan.SpanContext().TraceID())
+	suite.ElementsMatch([]attribute.KeyValue{attribute.String("admissionUID", "uid"), attribute.String("kind", "Pod")}, parentSpan.Attributes())
+	suite.ElementsMatch([]attribute.KeyValue{attribute.String("admissionUID", "uid"), attribute.String("container", "nginx")}, childSpan.Attributes())
+}
+
+func (suite *TestSuiteTracerProvider) Test_StartSpan_RecordError_StatusError() {
+	_, span := suite.provider.StartSpan(context.Background(), "Handle")
+	span.RecordError(errors.New("timeout"))
+	span.End()
+
+	ended := suite.spanRecorder.Ended()
+	suite.Len(ended, 1)
+	suite.Equal(codes.Error, ended[0].Status().Code)
+	suite.Len(ended[0].Events(), 1)
+}
+
+func (suite *TestSuiteTracerProvider) Test_StartSpan_NonStringValues_ConvertedToAttributes() {
+	_, span := suite.provider.StartSpan(context.Background(), "Handle", "allowed", true, "patchCount", 2, "timeoutDuration", 1.5, "missingValue")
+	span.End()
+
+	suite.ElementsMatch([]attribute.KeyValue{
+		attribute.Bool("allowed", true),
+		attribute.Int("patchCount", 2),
+		attribute.Float64("timeoutDuration", 1.5),
+		attribute.String("missingValue", ""),
+	}, suite.spanRecorder.Ended()[0].Attributes())
+}
+
+func TestSuiteTracerProvider_Run(t *testing.T) {
+	suite.Run(t, new(TestSuiteTracerProvider))
+}
@@ -1,8 +1,10 @@
 package acrauth
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
+	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/circuitbreaker"
 	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/httpclient"
 	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/instrumentation"
 	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/instrumentation/trace"
@@ -47,7 +49,7 @@ var (
 type IACRTokenExchanger interface {
 	// ExchangeACRAccessToken receives registry endpoint and an armToken (token to azure mgmt.) and
 	// exchanges it to an ACR refresh token and returns it
-	ExchangeACRAccessToken(registry string, armToken string) (string, error)
+	ExchangeACRAccessToken(ctx context.Context, registry string, armToken string) (string, error)
 }
 
 // ACRTokenExchanger implements IACRTokenExchanger interface
@@ -61,6 +63,8 @@ type ACRTokenExchanger struct {
 	httpClient httpclient.IHttpClient
 	// retry policy
 	retryPolicy retrypolicy.IRetryPolicy
+	// circuitBreaker rejects the token exchanges of each registry while the registry is failing
+	circuitBreaker circuitbreaker.ICircuitBreaker
 }
 
 // tokenResponse represents the response object from exchange token rest api of the registry
@@ -76,32 +80,37 @@ type tokenResponse struct {
 }
 
 // NewACRTokenExchanger Ctor
-func NewACRTokenExchanger(instrumentationProvider instrumentation.IInstrumentationProvider, httpClient httpclient.IHttpClient, retryPolicy retrypolicy.IRetryPolicy) *ACRTokenExchanger {
+func NewACRTokenExchanger(instrumentationProvider instrumentation.IInstrumentationProvider, httpClient httpclient.IHttpClient, retryPolicy retrypolicy.IRetryPolicy, circuitBreaker circuitbreaker.ICircuitBreaker) *ACRTokenExchanger {
 	return &ACRTokenExchanger{
-		tracerProvider: instrumentationProvider.GetTracerProvider("ACRTokenProvider"),
+		tracerProvider: instrumentationProvider.GetTracerProvider("ACRTokenExchanger"),
 		httpClient:     httpClient,
 		retryPolicy:    retryPolicy,
+		circuitBreaker: circuitBreaker,
 	}
 }
 
 // ExchangeACRAccessToken receives registry endpoint and an armToken (token to azure mgmt.) and
 // exchanges it to an ACR refresh token and returns it
 // Generates an HTTP call to registry/oauth2/exchange rest api to exchange the token
-func (tokenExchanger *ACRTokenExchanger) ExchangeACRAccessToken(registry string, armToken string) (string, error) {
+func (tokenExchanger *ACRTokenExchanger) ExchangeACRAccessToken(ctx context.Context, registry string, armToken string) (string, error) {
 	tracer := tokenExchanger.tracerProvider.GetTracer("ExchangeACRAccessToken")
+	ctx, span := tokenExchanger.tracerProvider.StartSpan(ctx, "ExchangeACRAccessToken", "registry", registry)
+	defer span.End()
 	tracer.Info("Received:", "registry", registry)
 
 	// Argument validation
 	if registry == "" || armToken == "" {
 		err := errors.Wrap(utils.NilArgumentError, "ACRTokenExchanger")
+		span.RecordError(err)
 		tracer.Error(err, "")
 		return "", err
 	}
 
 	// Build HTTP request
-	req, err := generateExchangeTokenHTTPRequest(registry, armToken)
+	req, err := generateExchangeTokenHTTPRequest(ctx, registry, armToken)
 	if err != nil {
 		err = errors.Wrap(fmt.Errorf("failed to generate token exchange request: %w", err), "ACRTokenExchanger")
+		span.RecordError(err)
 		tracer.Error(err, "")
 		return "", err
 	}
@@ -111,17 +120,40 @@ func (tokenExchanger *ACRTokenExchanger) ExchangeACRAccessToken(registry string,
 	defer closeResponse(resp)
 
 	// Invokes call to registry
-	err = tokenExchanger.retryPolicy.RetryAction(
-		func() error {
-			resp, err = tokenExchanger.httpClient.Do(req)
-			if err != nil {
-				// err != nil so set response to nil and return error
-				resp = nil
-				return err
-			}
-			return nil
+	_, err = tokenExchanger.circuitBreaker.Execute(
+		registry,
+		func() (interface{}, error) {
+			return nil, tokenExchanger.retryPolicy.RetryAction(
+				ctx,
+				func() error {
+					// The body of the request is consumed by the previous attempt - reset it before each attempt
+					if req.GetBody != nil {
+						if req.Body, err = req.GetBody(); err != nil {
+							return err
+						}
+					}
+					resp, err = tokenExchanger.httpClient.Do(req)
+					if err != nil {
+						// err != nil so set response to nil and return error
+						resp = nil
+						return err
+					}
+					// In case that the registry throttles the request or is unavailable - retry after its hint
+					if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusServiceUnavailable {
+						if retryAfter, ok := retrypolicy.GetRetryAfter(resp); ok {
+							err = retrypolicy.NewRetryAfterErr(fmt.Errorf("ACR token exchange endpoint returned error status: %d", resp.StatusCode), retryAfter)
+							closeResponse(resp)
+							resp = nil
+							return err
+						}
+					}
+					return nil
+				},
+				// Retry on all errors except not NoSuchError
+				func(err error) bool { return !tokenExchanger.isNoSuchHostErr(err) },
+			)
 		},
-		// Retry on all errors except not NoSuchError
+		// IsFailure - registry that doesn't exist isn't failure of the registry
 		func(err error) bool { return !tokenExchanger.isNoSuchHostErr(err) },
 	)
 
@@ -130,13 +162,18 @@ func (tokenExchanger *ACRTokenExchanger) ExchangeACRAccessToken(registry string,
 		if tokenExchanger.isNoSuchHostErr(err) {
 			// If its this error - convert the error to known error and continue
 			err = registryerrors.NewRegistryIsNotFoundErr(registry, err)
+		} else if !tokenExchanger.isCircuitIsOpenErr(err) && ctx.Err() == nil {
+			// The token couldn't be exchanged to the credentials of the registry (unless the caller stopped waiting - ctx is done)
+			err = registryerrors.NewIdentityUnavailableErr(registry, err)
 		}
 
 		err = errors.Wrap(err, "failed to send token exchange request")
+		span.RecordError(err)
 		tracer.Error(err, "")
 		return "", err
 	} else if resp == nil {
 		err = errors.New("unexpected behavior - response is nil while err is also nil")
+		span.RecordError(err)
 		tracer.Error(err, "")
 		return "", err
 	}
@@ -149,6 +186,13 @@ func (tokenExchanger *ACRTokenExchanger) ExchangeACRAccessToken(registry string,
 		} else {
 			err = errors.Wrap(fmt.Errorf("ACR token exchange endpoint returned error status: %d", resp.StatusCode), "ACRTokenExchanger")
 		}
+		// The identity isn't authorized to the registry (e.g. missing AcrPull role) - otherwise, the exchange failed
+		if resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden {
+			err = registryerrors.NewUnauthorizedErr(registry, err)
+		} else {
+			err = registryerrors.NewIdentityUnavailableErr(registry, err)
+		}
+		span.RecordError(err)
 		tracer.Error(err, "")
 		return "", err
 	}
@@ -156,6 +200,7 @@ func (tokenExchanger *ACRTokenExchanger) ExchangeACRAccessToken(registry string,
 	refreshToken, err := extractRefreshTokenFromExchangeTokenHTTPResponse(resp)
 	if err != nil {
 		err = errors.Wrap(fmt.Errorf("failed to extract refresh token from response: %w", err), "ACRTokenExchanger")
+		span.RecordError(err)
 		tracer.Error(err, "")
 		return "", err
 	}
@@ -163,7 +208,7 @@ func (tokenExchanger *ACRTokenExchanger) ExchangeACRAccessToken(registry string,
 	return refreshToken, nil
 }
 
-func generateExchangeTokenHTTPRequest(registry string, armToken string) (*http.Request, error) {
+func generateExchangeTokenHTTPRequest(ctx context.Context, registry string, armToken string) (*http.Request, error) {
 	exchangeURL := fmt.Sprintf("%s://%s/oauth2/exchange", _scheme, registry)
 	exchangeUrl, err := url.Parse(exchangeURL)
 	if err != nil {
@@ -176,7 +221,7 @@ func generateExchangeTokenHTTPRequest(registry string, armToken string) (*http.R
 	// Seems like tenantId is not required - if ever needed it should be added via:	//parameters.Add("tenant", tenantID) - maybe it is needed on cross tenant
 	// Not adding it for now...
 
-	req, err := http.NewRequest(_postHTTPRequestType, exchangeURL, strings.NewReader(parameters.Encode()))
+	req, err := http.NewRequestWithContext(ctx, _postHTTPRequestType, exchangeURL, strings.NewReader(parameters.Encode()))
 	if err != nil {
 		return nil, fmt.Errorf("failed to construct token exchange reqeust: %w", err)
 	}
@@ -235,3 +280,9 @@ func (tokenExchanger *ACRTokenExchanger) isNoSuchHostErr(err error) bool {
 	}
 	return false
 }
+
+// isCircuitIsOpenErr gets an error and returns true if the exchange was rejected because the circuit of the registry is open
+func (tokenExchanger *ACRTokenExchanger) isCircuitIsOpenErr(err error) bool {
+	var circuitIsOpenErr *circuitbreaker.CircuitIsOpenErr
+	return errors.As(err, &circuitIsOpenErr)
+}
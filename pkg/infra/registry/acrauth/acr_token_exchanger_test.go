@@ -2,9 +2,12 @@ package acrauth
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/circuitbreaker"
 	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/httpclient/mocks"
 	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/instrumentation"
+	registryerrors "github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/registry/errors"
 	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/retrypolicy"
 	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/utils"
 	"github.com/pkg/errors"
@@ -56,14 +59,14 @@ func (suite *TestSuiteTokenExchanger) SetupTest() {
 	_httpClientMock = &mocks.IHttpClient{}
 
 	// TODO Add tests that use retrypolicy mock!
-	retryPolicy := retrypolicy.NewRetryPolicy(_instrumentationP, &retrypolicy.RetryPolicyConfiguration{RetryAttempts: 1, RetryDurationInMS: 3})
-	_exchanger = NewACRTokenExchanger(_instrumentationP, _httpClientMock, retryPolicy)
+	retryPolicy := retrypolicy.NewRetryPolicy(_instrumentationP, &retrypolicy.RetryPolicyConfiguration{RetryAttempts: 1, RetryDurationInMS: 3}, "ACRTokenExchanger")
+	_exchanger = NewACRTokenExchanger(_instrumentationP, _httpClientMock, retryPolicy, circuitbreaker.NewNoOpCircuitBreaker())
 }
 
 func (suite *TestSuiteTokenExchanger) Test_ExchangeACRAccessToken_Success() {
 	expectedResponse := suite.generateTokenResponse(http.StatusOK, suite.generateTokenResponseBody(_exchanger_refreshTokenMock))
 	_httpClientMock.On("Do", mock.MatchedBy(suite.isRequestExpected)).Return(expectedResponse, nil).Once()
-	refresh_token, err := _exchanger.ExchangeACRAccessToken(_excahnger_registryMock, _exchanger_armTokenMock)
+	refresh_token, err := _exchanger.ExchangeACRAccessToken(context.Background(), _excahnger_registryMock, _exchanger_armTokenMock)
 
 	suite.Nil(err)
 	suite.Equal(_exchanger_refreshTokenMock, refresh_token)
@@ -73,17 +76,45 @@ func (suite *TestSuiteTokenExchanger) Test_ExchangeACRAccessToken_Success() {
 func (suite *TestSuiteTokenExchanger) Test_ExchangeACRAccessToken_ErrorInHttp_ErrorPropagated() {
 	expectedErr := errors.New("HttpErrorMock")
 	_httpClientMock.On("Do", mock.MatchedBy(suite.isRequestExpected)).Return(nil, expectedErr).Once()
-	refresh_token, err := _exchanger.ExchangeACRAccessToken(_excahnger_registryMock, _exchanger_armTokenMock)
+	refresh_token, err := _exchanger.ExchangeACRAccessToken(context.Background(), _excahnger_registryMock, _exchanger_armTokenMock)
 
 	suite.ErrorIs(err, expectedErr)
+	suite.IsType(&registryerrors.IdentityUnavailableErr{}, errors.Cause(err))
 	suite.Equal("", refresh_token)
 	suite.AssertExpectations()
 }
 
+func (suite *TestSuiteTokenExchanger) Test_ExchangeACRAccessToken_ErrorCodeInHttp_KnownErr() {
+	tests := []struct {
+		name          string
+		statusCode    int
+		expectedCause error
+	}{
+		{name: "unauthorized", statusCode: http.StatusUnauthorized, expectedCause: &registryerrors.UnauthorizedErr{}},
+		{name: "forbidden", statusCode: http.StatusForbidden, expectedCause: &registryerrors.UnauthorizedErr{}},
+		{name: "bad request", statusCode: http.StatusBadRequest, expectedCause: &registryerrors.IdentityUnavailableErr{}},
+		{name: "server error", statusCode: http.StatusInternalServerError, expectedCause: &registryerrors.IdentityUnavailableErr{}},
+	}
+	for _, tt := range tests {
+		suite.Run(tt.name, func() {
+			suite.SetupTest()
+			expectedResponse := suite.generateTokenResponse(tt.statusCode, "MockError")
+			_httpClientMock.On("Do", mock.MatchedBy(suite.isRequestExpected)).Return(expectedResponse, nil).Once()
+
+			refresh_token, err := _exchanger.ExchangeACRAccessToken(context.Background(), _excahnger_registryMock, _exchanger_armTokenMock)
+
+			suite.IsType(tt.expectedCause, errors.Cause(err))
+			suite.True(strings.Contains(err.Error(), strconv.Itoa(tt.statusCode)))
+			suite.Equal("", refresh_token)
+			suite.AssertExpectations()
+		})
+	}
+}
+
 func (suite *TestSuiteTokenExchanger) Test_ExchangeACRAccessToken_ErrorCodeInHttpWithBody_ErrorPropagated() {
 	expectedResponse := suite.generateTokenResponse(http.StatusUnauthorized, "MockError")
 	_httpClientMock.On("Do", mock.MatchedBy(suite.isRequestExpected)).Return(expectedResponse, nil).Once()
-	refresh_token, err := _exchanger.ExchangeACRAccessToken(_excahnger_registryMock, _exchanger_armTokenMock)
+	refresh_token, err := _exchanger.ExchangeACRAccessToken(context.Background(), _excahnger_registryMock, _exchanger_armTokenMock)
 
 	suite.Error(err)
 	suite.True(strings.Contains(err.Error(), "401") && strings.Contains(err.Error(), "MockError"))
@@ -94,7 +125,7 @@ func (suite *TestSuiteTokenExchanger) Test_ExchangeACRAccessToken_ErrorCodeInHtt
 func (suite *TestSuiteTokenExchanger) Test_ExchangeACRAccessToken_ErrorCodeInHttpWithNilBody_ErrorPropagated() {
 	expectedResponse := &http.Response{StatusCode: http.StatusUnauthorized}
 	_httpClientMock.On("Do", mock.MatchedBy(suite.isRequestExpected)).Return(expectedResponse, nil).Once()
-	refresh_token, err := _exchanger.ExchangeACRAccessToken(_excahnger_registryMock, _exchanger_armTokenMock)
+	refresh_token, err := _exchanger.ExchangeACRAccessToken(context.Background(), _excahnger_registryMock, _exchanger_armTokenMock)
 
 	suite.Error(err)
 	suite.True(strings.Contains(err.Error(), "401"))
@@ -105,7 +136,7 @@ func (suite *TestSuiteTokenExchanger) Test_ExchangeACRAccessToken_ErrorCodeInHtt
 func (suite *TestSuiteTokenExchanger) Test_ExchangeACRAccessToken_OkCodeNilBody_ErrorPropagated() {
 	expectedResponse := &http.Response{StatusCode: http.StatusOK}
 	_httpClientMock.On("Do", mock.MatchedBy(suite.isRequestExpected)).Return(expectedResponse, nil).Once()
-	refresh_token, err := _exchanger.ExchangeACRAccessToken(_excahnger_registryMock, _exchanger_armTokenMock)
+	refresh_token, err := _exchanger.ExchangeACRAccessToken(context.Background(), _excahnger_registryMock, _exchanger_armTokenMock)
 
 	suite.Error(err)
 	suite.Equal("", refresh_token)
@@ -115,7 +146,7 @@ func (suite *TestSuiteTokenExchanger) Test_ExchangeACRAccessToken_OkCodeNilBody_
 func (suite *TestSuiteTokenExchanger) Test_ExchangeACRAccessToken_OkCodeBadFormatBody_ErrorPropagated() {
 	expectedResponse := suite.generateTokenResponse(http.StatusOK, "MockBadBody!")
 	_httpClientMock.On("Do", mock.MatchedBy(suite.isRequestExpected)).Return(expectedResponse, nil).Once()
-	refresh_token, err := _exchanger.ExchangeACRAccessToken(_excahnger_registryMock, _exchanger_armTokenMock)
+	refresh_token, err := _exchanger.ExchangeACRAccessToken(context.Background(), _excahnger_registryMock, _exchanger_armTokenMock)
 
 	suite.Error(err)
 	cause := errors.Cause(err)
@@ -128,7 +159,7 @@ func (suite *TestSuiteTokenExchanger) Test_ExchangeACRAccessToken_OkCodeBadForma
 func (suite *TestSuiteTokenExchanger) Test_ExchangeACRAccessToken_OkCodeBadEmptyRefreshCode_ErrorPropagated() {
 	expectedResponse := suite.generateTokenResponse(http.StatusOK, "{}")
 	_httpClientMock.On("Do", mock.MatchedBy(suite.isRequestExpected)).Return(expectedResponse, nil).Once()
-	refresh_token, err := _exchanger.ExchangeACRAccessToken(_excahnger_registryMock, _exchanger_armTokenMock)
+	refresh_token, err := _exchanger.ExchangeACRAccessToken(context.Background(), _excahnger_registryMock, _exchanger_armTokenMock)
 
 	suite.Error(err)
 	suite.ErrorIs(err, _refreshTokenEmptyError)
@@ -136,9 +167,36 @@ func (suite *TestSuiteTokenExchanger) Test_ExchangeACRAccessToken_OkCodeBadEmpty
 	suite.AssertExpectations()
 }
 
+func (suite *TestSuiteTokenExchanger) Test_ExchangeACRAccessToken_TooManyRequestsWithRetryAfter_Retried() {
+	retryPolicy := retrypolicy.NewRetryPolicy(_instrumentationP, &retrypolicy.RetryPolicyConfiguration{RetryAttempts: 2, RetryDurationInMS: 3}, "ACRTokenExchanger")
+	exchanger := NewACRTokenExchanger(_instrumentationP, _httpClientMock, retryPolicy, circuitbreaker.NewNoOpCircuitBreaker())
+	throttledResponse := suite.generateTokenResponse(http.StatusTooManyRequests, "")
+	throttledResponse.Header = http.Header{"Retry-After": []string{"0"}}
+	expectedResponse := suite.generateTokenResponse(http.StatusOK, suite.generateTokenResponseBody(_exchanger_refreshTokenMock))
+	// isRequestExpected consumes the body of the request, so it can't be matched by both of the expected calls
+	_httpClientMock.On("Do", mock.Anything).Return(throttledResponse, nil).Once()
+	_httpClientMock.On("Do", mock.Anything).Return(expectedResponse, nil).Once()
+	refresh_token, err := exchanger.ExchangeACRAccessToken(context.Background(), _excahnger_registryMock, _exchanger_armTokenMock)
+
+	suite.Nil(err)
+	suite.Equal(_exchanger_refreshTokenMock, refresh_token)
+	suite.AssertExpectations()
+}
+
+func (suite *TestSuiteTokenExchanger) Test_ExchangeACRAccessToken_TooManyRequestsWithoutRetryAfter_ErrorPropagated() {
+	expectedResponse := suite.generateTokenResponse(http.StatusTooManyRequests, "MockError")
+	_httpClientMock.On("Do", mock.MatchedBy(suite.isRequestExpected)).Return(expectedResponse, nil).Once()
+	refresh_token, err := _exchanger.ExchangeACRAccessToken(context.Background(), _excahnger_registryMock, _exchanger_armTokenMock)
+
+	suite.Error(err)
+	suite.True(strings.Contains(err.Error(), "429"))
+	suite.Equal("", refresh_token)
+	suite.AssertExpectations()
+}
+
 func (suite *TestSuiteTokenExchanger) Test_ExchangeACRAccessToken_EmptyRegistry_Error() {
 
-	refresh_token, err := _exchanger.ExchangeACRAccessToken("", _exchanger_armTokenMock)
+	refresh_token, err := _exchanger.ExchangeACRAccessToken(context.Background(), "", _exchanger_armTokenMock)
 
 	suite.Error(err)
 	suite.ErrorIs(err, utils.NilArgumentError)
@@ -148,7 +206,7 @@ func (suite *TestSuiteTokenExchanger) Test_ExchangeACRAccessToken_EmptyRegistry_
 
 func (suite *TestSuiteTokenExchanger) Test_ExchangeACRAccessToken_EmptyARMToken_Error() {
 
-	refresh_token, err := _exchanger.ExchangeACRAccessToken(_excahnger_registryMock, "")
+	refresh_token, err := _exchanger.ExchangeACRAccessToken(context.Background(), _excahnger_registryMock, "")
 
 	suite.Error(err)
 	suite.ErrorIs(err, utils.NilArgumentError)
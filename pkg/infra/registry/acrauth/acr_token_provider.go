@@ -8,6 +8,7 @@ import (
 	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/instrumentation/metric"
 	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/instrumentation/metric/util"
 	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/instrumentation/trace"
+	registryerrors "github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/registry/errors"
 	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/utils"
 	"github.com/pkg/errors"
 )
@@ -16,7 +17,7 @@ import (
 type IACRTokenProvider interface {
 	// GetACRRefreshToken provide a refresh token (used for generating access-token to registry data plane)
 	// for registry provided
-	GetACRRefreshToken(registry string) (string, error)
+	GetACRRefreshToken(ctx context.Context, registry string) (string, error)
 }
 
 // ACRTokenProvider implements IACRTokenProvider interface
@@ -34,8 +35,9 @@ type ACRTokenProvider struct {
 	tokenExchanger IACRTokenExchanger
 	// cacheClient is cache for mapping acr registry to token
 	cacheClient cache.ICacheClient
-	// acrTokenProviderConfiguration is configuration data for ACRTokenProvider
-	acrTokenProviderConfiguration *ACRTokenProviderConfiguration
+	// getConfiguration returns the current configuration of the token provider - the configuration that it was created with,
+	// or the reloaded configuration in case that a configuration provider is set.
+	getConfiguration func() *ACRTokenProviderConfiguration
 }
 
 // ACRTokenProviderConfiguration is configuration data for ACRTokenProvider
@@ -52,18 +54,24 @@ func NewACRTokenProvider(instrumentationProvider instrumentation.IInstrumentatio
 		azureBearerAuthorizerTokenProvider: azureBearerAuthorizerTokenProvider,
 		tokenExchanger:                     tokenExchanger,
 		cacheClient:                        cacheClient,
-		acrTokenProviderConfiguration:      acrTokenProviderConfiguration,
+		getConfiguration:                   func() *ACRTokenProviderConfiguration { return acrTokenProviderConfiguration },
 	}
 }
 
+// SetConfigurationProvider makes the token provider read its configuration from the provider on each use (e.g. from the
+// reloaded configuration), so the token requests after a reload use the new configuration. Should be called before the token provider is used.
+func (tokenProvider *ACRTokenProvider) SetConfigurationProvider(getConfiguration func() *ACRTokenProviderConfiguration) {
+	tokenProvider.getConfiguration = getConfiguration
+}
+
 // GetACRRefreshToken provides a refresh token (used for generating access-token to registry data plane)
 //  for registry provided.
 // Refersh and extract ARM token from azure authorizer, then exchange it to refersh token using token exchanger
-func (tokenProvider *ACRTokenProvider) GetACRRefreshToken(registry string) (string, error) {
+func (tokenProvider *ACRTokenProvider) GetACRRefreshToken(ctx context.Context, registry string) (string, error) {
 	tracer := tokenProvider.tracerProvider.GetTracer("GetACRRefreshToken")
 	tracer.Info("Received", "registry", registry)
 
-	registryRefreshToken, err := tokenProvider.cacheClient.Get(registry)
+	registryRefreshToken, err := tokenProvider.cacheClient.Get(ctx, registry)
 	// Error as a result of key doesn't exist and error from the cache are treated the same (skip cache)
 	if err != nil { // Couldn't get token from cache - skip and get results from provider
 		if cache.IsMissingKeyCacheError(err){
@@ -79,24 +87,29 @@ func (tokenProvider *ACRTokenProvider) GetACRRefreshToken(registry string) (stri
 	}
 
 	// Otherwise, get azure token
-	armToken, err := tokenProvider.azureBearerAuthorizerTokenProvider.GetOAuthToken(context.Background())
+	armToken, err := tokenProvider.azureBearerAuthorizerTokenProvider.GetOAuthToken(ctx)
 	if err != nil {
+		// The token of the identity couldn't be acquired (e.g. MSI endpoint failure) - unless the caller stopped waiting (ctx is done)
+		if ctx.Err() == nil {
+			err = registryerrors.NewIdentityUnavailableErr(registry, err)
+		}
 		err = errors.Wrap(err, "Failed to get armToken")
 		tracer.Error(err, "")
 		return "", err
 	}
 
 	// Exchange arm token to ACR refresh token
-	registryRefreshToken, err = tokenProvider.tokenExchanger.ExchangeACRAccessToken(registry, armToken)
+	registryRefreshToken, err = tokenProvider.tokenExchanger.ExchangeACRAccessToken(ctx, registry, armToken)
 	if err != nil {
 		err = errors.Wrap(err, "Failed to exchange ACR access token")
 		tracer.Error(err, "")
 		return "", err
 	}
 
-	// Save registryRefreshToken in cache
+	// Save registryRefreshToken in cache - the token is saved even if the request is done before the set is completed
+	cacheCtx := utils.NewDetachedContext(ctx)
 	go func() {
-		err = tokenProvider.cacheClient.Set(registry, registryRefreshToken, utils.GetMinutes(tokenProvider.acrTokenProviderConfiguration.RegistryRefreshTokenCacheExpirationTime))
+		err = tokenProvider.cacheClient.Set(cacheCtx, registry, registryRefreshToken, utils.GetMinutes(tokenProvider.getConfiguration().RegistryRefreshTokenCacheExpirationTime))
 		if err != nil {
 			err = errors.Wrap(err, "Failed to set registryRefreshToken in cache")
 			tracer.Error(err, "")
@@ -6,6 +6,7 @@ import (
 	cachemock "github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/cache/mocks"
 	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/instrumentation"
 	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/registry/acrauth/mocks"
+	registryerrors "github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/registry/errors"
 	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/utils"
 	"github.com/pkg/errors"
 	"github.com/stretchr/testify/mock"
@@ -38,11 +39,11 @@ func (suite *TestSuiteTokenProvider) SetupTest() {
 
 func (suite *TestSuiteTokenProvider) Test_GetACRRefreshToken_Success() {
 	_provider_azureTokenProviderMock.On("GetOAuthToken", context.Background()).Return(_provider_armToken, nil).Once()
-	_provider_exchangerMock.On("ExchangeACRAccessToken", _provider_registry, _provider_armToken).Return(_provider_refreshToken, nil).Once()
-	_provider_cacheClientMock.On("Get", mock.Anything).Return("", utils.NilArgumentError)
-	_provider_cacheClientMock.On("Set", mock.Anything, mock.Anything, mock.Anything).Return(utils.NilArgumentError).Maybe()
+	_provider_exchangerMock.On("ExchangeACRAccessToken", mock.Anything, _provider_registry, _provider_armToken).Return(_provider_refreshToken, nil).Once()
+	_provider_cacheClientMock.On("Get", mock.Anything, mock.Anything).Return("", utils.NilArgumentError)
+	_provider_cacheClientMock.On("Set", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(utils.NilArgumentError).Maybe()
 
-	val, err := _provider.GetACRRefreshToken(_provider_registry)
+	val, err := _provider.GetACRRefreshToken(context.Background(), _provider_registry)
 
 	suite.Equal(_provider_refreshToken, val)
 	suite.Nil(err)
@@ -52,24 +53,40 @@ func (suite *TestSuiteTokenProvider) Test_GetACRRefreshToken_Success() {
 func (suite *TestSuiteTokenProvider) Test_GetACRRefreshToken_FailOnTokenGet_Error() {
 	expectedError := errors.New("azureTokenProviderMockError")
 	_provider_azureTokenProviderMock.On("GetOAuthToken", context.Background()).Return("", expectedError).Once()
-	_provider_cacheClientMock.On("Get", mock.Anything).Return("", utils.NilArgumentError)
-	_provider_cacheClientMock.On("Set", mock.Anything, mock.Anything, mock.Anything).Return(utils.NilArgumentError).Maybe()
+	_provider_cacheClientMock.On("Get", mock.Anything, mock.Anything).Return("", utils.NilArgumentError)
+	_provider_cacheClientMock.On("Set", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(utils.NilArgumentError).Maybe()
 
-	val, err := _provider.GetACRRefreshToken(_provider_registry)
+	val, err := _provider.GetACRRefreshToken(context.Background(), _provider_registry)
 
 	suite.Equal("", val)
 	suite.ErrorIs(err, expectedError)
+	suite.IsType(&registryerrors.IdentityUnavailableErr{}, errors.Cause(err))
+	suite.AssertExpectations()
+}
+
+func (suite *TestSuiteTokenProvider) Test_GetACRRefreshToken_ContextCanceledOnTokenGet_NotIdentityUnavailableErr() {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_provider_azureTokenProviderMock.On("GetOAuthToken", ctx).Return("", context.Canceled).Once()
+	_provider_cacheClientMock.On("Get", mock.Anything, mock.Anything).Return("", utils.NilArgumentError)
+	_provider_cacheClientMock.On("Set", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(utils.NilArgumentError).Maybe()
+
+	val, err := _provider.GetACRRefreshToken(ctx, _provider_registry)
+
+	// The caller stopped waiting - it isn't a failure of the identity
+	suite.Equal("", val)
+	suite.Equal(context.Canceled, errors.Cause(err))
 	suite.AssertExpectations()
 }
 
 func (suite *TestSuiteTokenProvider) Test_GetACRRefreshToken_FailToExchange_Error() {
 	expectedError := errors.New("exchangerMockError")
 	_provider_azureTokenProviderMock.On("GetOAuthToken", context.Background()).Return(_provider_armToken, nil).Once()
-	_provider_exchangerMock.On("ExchangeACRAccessToken", _provider_registry, _provider_armToken).Return("", expectedError).Once()
-	_provider_cacheClientMock.On("Get", mock.Anything).Return("", utils.NilArgumentError)
-	_provider_cacheClientMock.On("Set", mock.Anything, mock.Anything, mock.Anything).Return(utils.NilArgumentError).Maybe()
+	_provider_exchangerMock.On("ExchangeACRAccessToken", mock.Anything, _provider_registry, _provider_armToken).Return("", expectedError).Once()
+	_provider_cacheClientMock.On("Get", mock.Anything, mock.Anything).Return("", utils.NilArgumentError)
+	_provider_cacheClientMock.On("Set", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(utils.NilArgumentError).Maybe()
 
-	val, err := _provider.GetACRRefreshToken(_provider_registry)
+	val, err := _provider.GetACRRefreshToken(context.Background(), _provider_registry)
 
 	suite.Equal("", val)
 	suite.ErrorIs(err, expectedError)
@@ -77,52 +94,52 @@ func (suite *TestSuiteTokenProvider) Test_GetACRRefreshToken_FailToExchange_Erro
 }
 
 func (suite *TestSuiteTokenProvider) Test_GetRefreshToken_Success_NoKeyInCache() {
-	_provider_cacheClientMock.On("Get", _provider_registry).Return("", utils.NilArgumentError)
-	_provider_cacheClientMock.On("Set", _provider_registry, _provider_refreshToken, mock.Anything).Return(utils.NilArgumentError).Maybe()
+	_provider_cacheClientMock.On("Get", mock.Anything, _provider_registry).Return("", utils.NilArgumentError)
+	_provider_cacheClientMock.On("Set", mock.Anything, _provider_registry, _provider_refreshToken, mock.Anything).Return(utils.NilArgumentError).Maybe()
 	_provider_azureTokenProviderMock.On("GetOAuthToken", context.Background()).Return(_provider_armToken, nil).Once()
-	_provider_exchangerMock.On("ExchangeACRAccessToken", _provider_registry, _provider_armToken).Return(_provider_refreshToken, nil).Once()
+	_provider_exchangerMock.On("ExchangeACRAccessToken", mock.Anything, _provider_registry, _provider_armToken).Return(_provider_refreshToken, nil).Once()
 
-	val, err := _provider.GetACRRefreshToken(_provider_registry)
+	val, err := _provider.GetACRRefreshToken(context.Background(), _provider_registry)
 	suite.Equal(_provider_refreshToken, val)
 	suite.Nil(err)
 	suite.AssertExpectations()
 }
 
 func (suite *TestSuiteTokenProvider) Test_GetACRRefreshToken_Success_RegistryKeyInCache() {
-	_provider_cacheClientMock.On("Get", _provider_registry).Return(_provider_refreshToken, nil)
+	_provider_cacheClientMock.On("Get", mock.Anything, _provider_registry).Return(_provider_refreshToken, nil)
 
-	val, err := _provider.GetACRRefreshToken(_provider_registry)
+	val, err := _provider.GetACRRefreshToken(context.Background(), _provider_registry)
 	suite.Equal(_provider_refreshToken, val)
 	suite.Nil(err)
 	suite.AssertExpectations()
 }
 
 func (suite *TestSuiteTokenProvider) Test_GetACRRefreshToken_Success_NoKeyInCache_SetKey_GetKeySecondTryBeforeExpirationTime_RegistryKey() {
-	_provider_cacheClientMock.On("Get", _provider_registry).Return("", utils.NilArgumentError).Once()
-	_provider_cacheClientMock.On("Get", _provider_registry).Return(_provider_refreshToken, nil).Once()
-	_provider_cacheClientMock.On("Set", _provider_registry, _provider_refreshToken, mock.Anything).Return(utils.NilArgumentError).Maybe()
+	_provider_cacheClientMock.On("Get", mock.Anything, _provider_registry).Return("", utils.NilArgumentError).Once()
+	_provider_cacheClientMock.On("Get", mock.Anything, _provider_registry).Return(_provider_refreshToken, nil).Once()
+	_provider_cacheClientMock.On("Set", mock.Anything, _provider_registry, _provider_refreshToken, mock.Anything).Return(utils.NilArgumentError).Maybe()
 	_provider_azureTokenProviderMock.On("GetOAuthToken", context.Background()).Return(_provider_armToken, nil).Once()
-	_provider_exchangerMock.On("ExchangeACRAccessToken", _provider_registry, _provider_armToken).Return(_provider_refreshToken, nil).Once()
+	_provider_exchangerMock.On("ExchangeACRAccessToken", mock.Anything, _provider_registry, _provider_armToken).Return(_provider_refreshToken, nil).Once()
 
-	val, err := _provider.GetACRRefreshToken(_provider_registry)
+	val, err := _provider.GetACRRefreshToken(context.Background(), _provider_registry)
 	suite.Equal(_provider_refreshToken, val)
 	suite.Nil(err)
-	val, err = _provider.GetACRRefreshToken(_provider_registry)
+	val, err = _provider.GetACRRefreshToken(context.Background(), _provider_registry)
 	suite.Equal(_provider_refreshToken, val)
 	suite.Nil(err)
 	suite.AssertExpectations()
 }
 
 func (suite *TestSuiteTokenProvider) Test_GetACRRefreshToken_Success_NoKeyInCache_SetKey_GetKeySecondTryAfterExpirationTime_RegistryKey() {
-	_provider_cacheClientMock.On("Get", _provider_registry).Return("", utils.NilArgumentError)
-	_provider_cacheClientMock.On("Set", _provider_registry, _provider_refreshToken, mock.Anything).Return(utils.NilArgumentError).Maybe()
+	_provider_cacheClientMock.On("Get", mock.Anything, _provider_registry).Return("", utils.NilArgumentError)
+	_provider_cacheClientMock.On("Set", mock.Anything, _provider_registry, _provider_refreshToken, mock.Anything).Return(utils.NilArgumentError).Maybe()
 	_provider_azureTokenProviderMock.On("GetOAuthToken", context.Background()).Return(_provider_armToken, nil).Twice()
-	_provider_exchangerMock.On("ExchangeACRAccessToken", _provider_registry, _provider_armToken).Return(_provider_refreshToken, nil).Twice()
+	_provider_exchangerMock.On("ExchangeACRAccessToken", mock.Anything, _provider_registry, _provider_armToken).Return(_provider_refreshToken, nil).Twice()
 
-	val, err := _provider.GetACRRefreshToken(_provider_registry)
+	val, err := _provider.GetACRRefreshToken(context.Background(), _provider_registry)
 	suite.Equal(_provider_refreshToken, val)
 	suite.Nil(err)
-	val, err = _provider.GetACRRefreshToken(_provider_registry)
+	val, err = _provider.GetACRRefreshToken(context.Background(), _provider_registry)
 	suite.Equal(_provider_refreshToken, val)
 	suite.Nil(err)
 	suite.AssertExpectations()
@@ -2,27 +2,31 @@
 
 package mocks
 
-import mock "github.com/stretchr/testify/mock"
+import (
+	context "context"
+
+	mock "github.com/stretchr/testify/mock"
+)
 
 // IACRTokenExchanger is an autogenerated mock type for the IACRTokenExchanger type
 type IACRTokenExchanger struct {
 	mock.Mock
 }
 
-// ExchangeACRAccessToken provides a mock function with given fields: registry, armToken
-func (_m *IACRTokenExchanger) ExchangeACRAccessToken(registry string, armToken string) (string, error) {
-	ret := _m.Called(registry, armToken)
+// ExchangeACRAccessToken provides a mock function with given fields: ctx, registry, armToken
+func (_m *IACRTokenExchanger) ExchangeACRAccessToken(ctx context.Context, registry string, armToken string) (string, error) {
+	ret := _m.Called(ctx, registry, armToken)
 
 	var r0 string
-	if rf, ok := ret.Get(0).(func(string, string) string); ok {
-		r0 = rf(registry, armToken)
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) string); ok {
+		r0 = rf(ctx, registry, armToken)
 	} else {
 		r0 = ret.Get(0).(string)
 	}
 
 	var r1 error
-	if rf, ok := ret.Get(1).(func(string, string) error); ok {
-		r1 = rf(registry, armToken)
+	if rf, ok := ret.Get(1).(func(context.Context, string, string) error); ok {
+		r1 = rf(ctx, registry, armToken)
 	} else {
 		r1 = ret.Error(1)
 	}
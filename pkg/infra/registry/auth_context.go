@@ -0,0 +1,20 @@
+package registry
+
+// AuthContext represents the deployed resource context that is used to authenticate to the registry
+type AuthContext struct {
+	// Namespace is the namespace of the deployed resource
+	Namespace string
+	// ImagePullSecrets are the names of the image pull secrets of the deployed resource
+	ImagePullSecrets []string
+	// ServiceAccountName is the name of the service account of the deployed resource
+	ServiceAccountName string
+}
+
+// NewAuthContext AuthContext ctor
+func NewAuthContext(namespace string, imagePullSecrets []string, serviceAccountName string) *AuthContext {
+	return &AuthContext{
+		Namespace:          namespace,
+		ImagePullSecrets:   imagePullSecrets,
+		ServiceAccountName: serviceAccountName,
+	}
+}
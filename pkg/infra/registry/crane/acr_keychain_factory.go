@@ -1,6 +1,7 @@
 package crane
 
 import (
+	"context"
 	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/instrumentation"
 	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/instrumentation/metric"
 	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/instrumentation/metric/util"
@@ -14,7 +15,7 @@ import (
 // IACRKeychainFactory responsible to create an ACR auth based keychain to authenticate to registry
 type IACRKeychainFactory interface {
 	// Create is creating  an ACR auth based keychain to registry using provided registry
-	Create(registry string) (authn.Keychain, error)
+	Create(ctx context.Context, registry string) (authn.Keychain, error)
 }
 
 // ACRKeychainFactory implements IACRKeychainFactory interface
@@ -45,12 +46,12 @@ func NewACRKeychainFactory(instrumentationProvider instrumentation.IInstrumentat
 }
 
 // Create creating  an ACR auth based keychain to registry using provided registry
-func (factory *ACRKeychainFactory) Create(registry string) (authn.Keychain, error) {
+func (factory *ACRKeychainFactory) Create(ctx context.Context, registry string) (authn.Keychain, error) {
 	tracer := factory.tracerProvider.GetTracer("Create")
 	tracer.Info("Received:", "registry", registry)
 
 	// Get a refresh token for registry
-	refreshToken, err := factory.acrTokenProvider.GetACRRefreshToken(registry)
+	refreshToken, err := factory.acrTokenProvider.GetACRRefreshToken(ctx, registry)
 	if err != nil {
 		err = errors.Wrap(err, "ACRKeychainFactory.Create: failed on GetACRRefreshToken")
 		tracer.Error(err, "")
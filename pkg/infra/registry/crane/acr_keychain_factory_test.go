@@ -1,11 +1,13 @@
 package crane
 
 import (
+	"context"
 	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/instrumentation"
 	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/registry/acrauth/mocks"
 	"github.com/google/go-containerregistry/pkg/authn"
 	"github.com/google/go-containerregistry/pkg/name"
 	"github.com/pkg/errors"
+	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/suite"
 	"testing"
 )
@@ -28,8 +30,8 @@ func (suite *TestSuiteACRKCFactorySuite) SetupTest() {
 func (suite *TestSuiteACRKCFactorySuite) Test_Create_Success() {
 
 	expectedKC := &ACRKeyChain{Token: _acrKcRefreshTokenMock}
-	suite.acrTokenMock.On("GetACRRefreshToken", _acrKcRegistryMock).Return(_acrKcRefreshTokenMock, nil).Once()
-	kc, err := suite.factory.Create(_acrKcRegistryMock)
+	suite.acrTokenMock.On("GetACRRefreshToken", mock.Anything, _acrKcRegistryMock).Return(_acrKcRefreshTokenMock, nil).Once()
+	kc, err := suite.factory.Create(context.Background(), _acrKcRegistryMock)
 
 	suite.Nil(err)
 	suite.Exactly(expectedKC, kc)
@@ -39,8 +41,8 @@ func (suite *TestSuiteACRKCFactorySuite) Test_Create_Success() {
 func (suite *TestSuiteACRKCFactorySuite) Test_Create_TokenError() {
 
 	expectedError := errors.New("TokenErrorMock!")
-	suite.acrTokenMock.On("GetACRRefreshToken", _acrKcRegistryMock).Return("", expectedError).Once()
-	kc, err := suite.factory.Create(_acrKcRegistryMock)
+	suite.acrTokenMock.On("GetACRRefreshToken", mock.Anything, _acrKcRegistryMock).Return("", expectedError).Once()
+	kc, err := suite.factory.Create(context.Background(), _acrKcRegistryMock)
 
 	suite.Error(err)
 	suite.ErrorIs(err, expectedError)
@@ -1,23 +1,43 @@
 package crane
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
 	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/instrumentation"
 	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/instrumentation/metric"
 	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/instrumentation/metric/util"
 	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/instrumentation/trace"
 	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/registry"
+	registryerrors "github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/registry/errors"
+	registryutils "github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/registry/utils"
 	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/registry/wrappers"
+	craneerrors "github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/registry/wrappers/errors"
 	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/utils"
 	"github.com/google/go-containerregistry/pkg/authn"
 	"github.com/google/go-containerregistry/pkg/crane"
 	"github.com/pkg/errors"
+	"strings"
 )
 
 const (
 	_userAgent = "azdproxy"
 )
 
+// referrersTagSchemaIndex is the image index that is stored under the referrers tag schema fallback tag (sha256-<hex>).
+// See https://github.com/opencontainers/distribution-spec/blob/main/spec.md#referrers-tag-schema
+type referrersTagSchemaIndex struct {
+	Manifests []referrersTagSchemaDescriptor `json:"manifests"`
+}
+
+// referrersTagSchemaDescriptor is a single referrer descriptor in referrersTagSchemaIndex
+type referrersTagSchemaDescriptor struct {
+	MediaType    string            `json:"mediaType"`
+	Digest       string            `json:"digest"`
+	ArtifactType string            `json:"artifactType,omitempty"`
+	Annotations  map[string]string `json:"annotations,omitempty"`
+}
+
 // CraneRegistryClient implements registry.IRegistryClient interface
 var _ registry.IRegistryClient = (*CraneRegistryClient)(nil)
 
@@ -32,23 +52,26 @@ type CraneRegistryClient struct {
 
 	acrKeychainFactory IACRKeychainFactory
 	k8sKeychainFactory IK8SKeychainFactory
+	// transportProvider provides the transport options of registries that can't use crane's default transport
+	transportProvider IRegistryTransportProvider
 }
 
 // NewCraneRegistryClient Constructor for the registry client
-func NewCraneRegistryClient(instrumentationProvider instrumentation.IInstrumentationProvider, craneWrapper wrappers.ICraneWrapper, acrKeychainFactory IACRKeychainFactory, k8sKeychainFactory IK8SKeychainFactory) *CraneRegistryClient {
+func NewCraneRegistryClient(instrumentationProvider instrumentation.IInstrumentationProvider, craneWrapper wrappers.ICraneWrapper, acrKeychainFactory IACRKeychainFactory, k8sKeychainFactory IK8SKeychainFactory, transportProvider IRegistryTransportProvider) *CraneRegistryClient {
 	return &CraneRegistryClient{
 		tracerProvider:     instrumentationProvider.GetTracerProvider("CraneRegistryClient"),
 		metricSubmitter:    instrumentationProvider.GetMetricSubmitter(),
 		craneWrapper:       craneWrapper,
 		acrKeychainFactory: acrKeychainFactory,
 		k8sKeychainFactory: k8sKeychainFactory,
+		transportProvider:  transportProvider,
 	}
 }
 
 // GetDigestUsingACRAttachAuth receives image reference and get it's digest using ACR attach authntication
 // ACR attach auth is based MSI token used to access the registry
 // Authenticate with multikeychain with acrkeychain and default keychain
-func (client *CraneRegistryClient) GetDigestUsingACRAttachAuth(imageReference registry.IImageReference) (string, error) {
+func (client *CraneRegistryClient) GetDigestUsingACRAttachAuth(ctx context.Context, imageReference registry.IImageReference) (string, error) {
 	tracer := client.tracerProvider.GetTracer("GetDigestUsingACRAttachAuth")
 	tracer.Info("Received image:", "imageReference", imageReference)
 
@@ -61,7 +84,7 @@ func (client *CraneRegistryClient) GetDigestUsingACRAttachAuth(imageReference re
 	}
 
 	// Create ACR auth keychain to registry - keychain with refresh token
-	acrKeyChain, err := client.acrKeychainFactory.Create(imageReference.Registry())
+	acrKeyChain, err := client.acrKeychainFactory.Create(ctx, imageReference.Registry())
 	if err != nil {
 		err = errors.Wrap(err, "CraneRegistryClient.GetDigestUsingACRAttachAuth: could not create acrKeychain")
 		tracer.Error(err, "")
@@ -69,7 +92,7 @@ func (client *CraneRegistryClient) GetDigestUsingACRAttachAuth(imageReference re
 	}
 
 	// Get digest and passing the keychain
-	digest, err := client.getDigest(imageReference, acrKeyChain)
+	digest, err := client.getDigest(ctx, imageReference, acrKeyChain)
 	if err != nil {
 		// Report error
 		err = errors.Wrap(err, "Failed with client. getDigest:")
@@ -84,7 +107,7 @@ func (client *CraneRegistryClient) GetDigestUsingACRAttachAuth(imageReference re
 // GetDigestUsingK8SAuth receives image reference and get it's digest using K8S secerts and auth
 // K8S auth is based image pull secrets used in deployment or attached to service account to pull the image
 // Authenticate with multikeychain with k8skeychain and default keychain
-func (client *CraneRegistryClient) GetDigestUsingK8SAuth(imageReference registry.IImageReference, namespace string, imagePullSecrets []string, serviceAccountName string) (string, error) {
+func (client *CraneRegistryClient) GetDigestUsingK8SAuth(ctx context.Context, imageReference registry.IImageReference, namespace string, imagePullSecrets []string, serviceAccountName string) (string, error) {
 	tracer := client.tracerProvider.GetTracer("GetDigestUsingK8SAuth")
 	tracer.Info("Received image:", "imageReference", imageReference, "namespace", namespace, "imagePullSecrets", imagePullSecrets, "serviceAccountName", serviceAccountName)
 
@@ -105,7 +128,7 @@ func (client *CraneRegistryClient) GetDigestUsingK8SAuth(imageReference registry
 	}
 
 	// Get digest and passing keychain
-	digest, err := client.getDigest(imageReference, k8sKeychain)
+	digest, err := client.getDigest(ctx, imageReference, k8sKeychain)
 	if err != nil {
 		// Report error
 		err = errors.Wrap(err, "Failed with client.getDigest:")
@@ -118,7 +141,7 @@ func (client *CraneRegistryClient) GetDigestUsingK8SAuth(imageReference registry
 }
 
 // GetDigestUsingDefaultAuth receives image reference and get it's digest using the default docker config auth
-func (client *CraneRegistryClient) GetDigestUsingDefaultAuth(imageReference registry.IImageReference) (string, error) {
+func (client *CraneRegistryClient) GetDigestUsingDefaultAuth(ctx context.Context, imageReference registry.IImageReference) (string, error) {
 	tracer := client.tracerProvider.GetTracer("GetDigestUsingDefaultAuth")
 	tracer.Info("Received image:", "imageReference", imageReference)
 
@@ -131,7 +154,7 @@ func (client *CraneRegistryClient) GetDigestUsingDefaultAuth(imageReference regi
 	}
 
 	// Get digest
-	digest, err := client.getDigest(imageReference, authn.DefaultKeychain)
+	digest, err := client.getDigest(ctx, imageReference, authn.DefaultKeychain)
 	if err != nil {
 		// Report error
 		err = errors.Wrap(err, "Failed with client.getDigest")
@@ -145,7 +168,7 @@ func (client *CraneRegistryClient) GetDigestUsingDefaultAuth(imageReference regi
 
 // getDigest private function that receives imageReference and a keychain, it wraps keychain received with multikeychain and appends the defaultkeychain as well
 // Then calls crane Digest function using the multikeychian created and the client _userAgent
-func (client *CraneRegistryClient) getDigest(imageReference registry.IImageReference, keychain authn.Keychain) (string, error) {
+func (client *CraneRegistryClient) getDigest(ctx context.Context, imageReference registry.IImageReference, keychain authn.Keychain) (string, error) {
 	tracer := client.tracerProvider.GetTracer("getDigest")
 	receivedKeyChainType := fmt.Sprintf("%T", keychain)
 	tracer.Info("Received image:", "imageReference", imageReference.Original(), "receivedKeyChainType", receivedKeyChainType)
@@ -153,9 +176,15 @@ func (client *CraneRegistryClient) getDigest(imageReference registry.IImageRefer
 	// Resolve digest using Options:
 	//  - multikeychain of received keychain and the default keychain,
 	// - _userAgent of the client
-	digest, err := client.craneWrapper.Digest(imageReference.Original(), crane.WithAuthFromKeychain(authn.NewMultiKeychain(keychain, authn.DefaultKeychain)), crane.WithUserAgent(_userAgent))
+	// - transport options of the registry (if configured)
+	digest, err := client.craneWrapper.Digest(ctx, imageReference.Original(), client.getCraneOptions(imageReference, authn.NewMultiKeychain(keychain, authn.DefaultKeychain))...)
 
 	if err != nil {
+		// The registry didn't respond in time (after the retries) - convert to known error.
+		// In case that ctx is done, the caller stopped waiting - it isn't a timeout of the registry.
+		if craneerrors.IsTimeoutErr(err) && ctx.Err() == nil {
+			err = registryerrors.NewDigestResolutionTimeoutErr(imageReference.Original(), err)
+		}
 		// Report error
 		err = errors.Wrapf(err, "CraneRegistryClient.getDigest with receivedKeyChainType %v", receivedKeyChainType)
 		tracer.Error(err, "")
@@ -166,3 +195,134 @@ func (client *CraneRegistryClient) getDigest(imageReference registry.IImageRefer
 	tracer.Info("Image resolved successfully", "imageRef", imageReference.Original(), "digest", digest)
 	return digest, nil
 }
+
+// GetManifest receives image reference and returns its raw manifest.
+// It authenticates with a chain of ACR attach auth (ACR registries only), K8S auth and the default docker config auth
+func (client *CraneRegistryClient) GetManifest(ctx context.Context, imageReference registry.IImageReference, authContext *registry.AuthContext) ([]byte, error) {
+	tracer := client.tracerProvider.GetTracer("GetManifest")
+	tracer.Info("Received image:", "imageReference", imageReference, "authContext", authContext)
+
+	// Argument validation
+	if imageReference == nil || authContext == nil {
+		err := errors.Wrap(utils.NilArgumentError, "CraneRegistryClient.GetManifest")
+		tracer.Error(err, "")
+		client.metricSubmitter.SendMetric(1, util.NewErrorEncounteredMetric(err, "CraneRegistryClient.GetManifest"))
+		return nil, err
+	}
+
+	keychain := client.createAuthContextKeychain(ctx, imageReference, authContext)
+	manifest, err := client.craneWrapper.Manifest(ctx, imageReference.Original(), client.getCraneOptions(imageReference, keychain)...)
+	if err != nil {
+		err = errors.Wrap(err, "CraneRegistryClient.GetManifest")
+		tracer.Error(err, "")
+		return nil, err
+	}
+
+	tracer.Info("Got manifest successfully", "imageRef", imageReference)
+	return manifest, nil
+}
+
+// GetBlob receives image reference and a blob digest in the image's repository and returns the blob content.
+// It authenticates with the same chain of keychains as GetManifest
+func (client *CraneRegistryClient) GetBlob(ctx context.Context, imageReference registry.IImageReference, blobDigest string, authContext *registry.AuthContext) ([]byte, error) {
+	tracer := client.tracerProvider.GetTracer("GetBlob")
+	tracer.Info("Received image:", "imageReference", imageReference, "blobDigest", blobDigest, "authContext", authContext)
+
+	// Argument validation
+	if imageReference == nil || authContext == nil {
+		err := errors.Wrap(utils.NilArgumentError, "CraneRegistryClient.GetBlob")
+		tracer.Error(err, "")
+		client.metricSubmitter.SendMetric(1, util.NewErrorEncounteredMetric(err, "CraneRegistryClient.GetBlob"))
+		return nil, err
+	}
+
+	blobReference := registryutils.GetDigestReferenceInRepository(imageReference, blobDigest).Original()
+	keychain := client.createAuthContextKeychain(ctx, imageReference, authContext)
+	blob, err := client.craneWrapper.Blob(ctx, blobReference, client.getCraneOptions(imageReference, keychain)...)
+	if err != nil {
+		err = errors.Wrap(err, "CraneRegistryClient.GetBlob")
+		tracer.Error(err, "")
+		return nil, err
+	}
+
+	tracer.Info("Got blob successfully", "blobReference", blobReference)
+	return blob, nil
+}
+
+// ListReferrers receives image reference and a digest in the image's repository and returns the artifacts that refer to the digest.
+// The referrers are read from the OCI referrers tag schema (an index that is tagged with sha256-<hex> of the digest),
+// since the referrers API isn't supported by the crane version that is used.
+func (client *CraneRegistryClient) ListReferrers(ctx context.Context, imageReference registry.IImageReference, digest string, authContext *registry.AuthContext) ([]*registry.Referrer, error) {
+	tracer := client.tracerProvider.GetTracer("ListReferrers")
+	tracer.Info("Received image:", "imageReference", imageReference, "digest", digest, "authContext", authContext)
+
+	// Argument validation
+	if imageReference == nil || authContext == nil {
+		err := errors.Wrap(utils.NilArgumentError, "CraneRegistryClient.ListReferrers")
+		tracer.Error(err, "")
+		client.metricSubmitter.SendMetric(1, util.NewErrorEncounteredMetric(err, "CraneRegistryClient.ListReferrers"))
+		return nil, err
+	}
+
+	referrersTag := registryutils.GetTagReferenceInRepository(imageReference, strings.Replace(digest, ":", "-", 1))
+	rawIndex, err := client.GetManifest(ctx, referrersTag, authContext)
+	if err != nil {
+		// No referrers tag means that there are no referrers to the digest.
+		if _, isNotFound := errors.Cause(err).(*registryerrors.ImageIsNotFoundErr); isNotFound {
+			tracer.Info("Digest has no referrers", "imageRef", imageReference, "digest", digest)
+			return []*registry.Referrer{}, nil
+		}
+		err = errors.Wrap(err, "CraneRegistryClient.ListReferrers")
+		tracer.Error(err, "")
+		return nil, err
+	}
+
+	index := &referrersTagSchemaIndex{}
+	if err = json.Unmarshal(rawIndex, index); err != nil {
+		err = errors.Wrap(err, "CraneRegistryClient.ListReferrers failed to unmarshal referrers index")
+		tracer.Error(err, "")
+		client.metricSubmitter.SendMetric(1, util.NewErrorEncounteredMetric(err, "CraneRegistryClient.ListReferrers"))
+		return nil, err
+	}
+
+	referrers := make([]*registry.Referrer, 0, len(index.Manifests))
+	for _, descriptor := range index.Manifests {
+		referrers = append(referrers, registry.NewReferrer(descriptor.Digest, descriptor.MediaType, descriptor.ArtifactType, descriptor.Annotations))
+	}
+
+	tracer.Info("Listed referrers successfully", "imageRef", imageReference, "digest", digest, "referrersCount", len(referrers))
+	return referrers, nil
+}
+
+// getCraneOptions returns the crane options of the image reference - auth from the keychain, the client _userAgent
+// and the transport options of the image's registry (if configured)
+func (client *CraneRegistryClient) getCraneOptions(imageReference registry.IImageReference, keychain authn.Keychain) []crane.Option {
+	options := []crane.Option{crane.WithAuthFromKeychain(keychain), crane.WithUserAgent(_userAgent)}
+	return append(options, client.transportProvider.GetOptions(imageReference.Registry())...)
+}
+
+// createAuthContextKeychain creates multikeychain of ACR keychain (only for ACR registries), K8S keychain and the default keychain.
+// Keychains that failed to be created are skipped, so the default keychain is always the last fallback.
+func (client *CraneRegistryClient) createAuthContextKeychain(ctx context.Context, imageReference registry.IImageReference, authContext *registry.AuthContext) authn.Keychain {
+	tracer := client.tracerProvider.GetTracer("createAuthContextKeychain")
+	keychains := make([]authn.Keychain, 0, 3)
+
+	if registryutils.IsRegistryEndpointACR(imageReference.Registry()) {
+		acrKeychain, err := client.acrKeychainFactory.Create(ctx, imageReference.Registry())
+		if err != nil {
+			tracer.Error(errors.Wrap(err, "could not create acrKeychain - skipping it"), "")
+		} else {
+			keychains = append(keychains, acrKeychain)
+		}
+	}
+
+	k8sKeychain, err := client.k8sKeychainFactory.Create(authContext.Namespace, authContext.ImagePullSecrets, authContext.ServiceAccountName)
+	if err != nil {
+		tracer.Error(errors.Wrap(err, "could not create k8sKeychain - skipping it"), "")
+	} else {
+		keychains = append(keychains, k8sKeychain)
+	}
+
+	keychains = append(keychains, authn.DefaultKeychain)
+	return authn.NewMultiKeychain(keychains...)
+}
@@ -1,14 +1,41 @@
 package crane
 
 import (
+	"context"
 	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/instrumentation"
+	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/registry"
 	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/registry/crane/mocks"
+	registryerrors "github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/registry/errors"
 	wrappersmocks "github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/registry/wrappers/mocks"
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/suite"
 	"testing"
+	"time"
 )
 
 const _expectedDigestMock = "xxyxyyxxyxsss"
+const _referredDigestMock = "sha256:0a1b2c3d"
+const _referrersTagRefMock = "tomer.io/redis:sha256-0a1b2c3d"
+const _referrersIndexMock = `{
+	"schemaVersion": 2,
+	"mediaType": "application/vnd.oci.image.index.v1+json",
+	"manifests": [
+		{
+			"mediaType": "application/vnd.oci.image.manifest.v1+json",
+			"digest": "sha256:aaaa",
+			"size": 100,
+			"artifactType": "application/spdx+json"
+		},
+		{
+			"mediaType": "application/vnd.oci.image.manifest.v1+json",
+			"digest": "sha256:bbbb",
+			"size": 200,
+			"annotations": {"in-toto.io/predicate-type": "https://slsa.dev/provenance/v0.2"}
+		}
+	]
+}`
 var _mockACRKC_RegistryClient = &ACRKeyChain{Token: "kakaksjdjkd"}
 
 type CraneRegistryTestSuite struct {
@@ -17,6 +44,7 @@ type CraneRegistryTestSuite struct {
 	craneWrapperMock *wrappersmocks.ICraneWrapper
 	acrKCFactoryMock *mocks.IACRKeychainFactory
 	k8sKCFactoryMock *mocks.IK8SKeychainFactory
+	transportProviderMock *mocks.IRegistryTransportProvider
 }
 
 func (suite *CraneRegistryTestSuite) SetupTest() {
@@ -24,19 +52,107 @@ func (suite *CraneRegistryTestSuite) SetupTest() {
 	suite.craneWrapperMock = new(wrappersmocks.ICraneWrapper)
 	suite.acrKCFactoryMock = new(mocks.IACRKeychainFactory)
 	suite.k8sKCFactoryMock = new(mocks.IK8SKeychainFactory)
-	suite.client = NewCraneRegistryClient(instrumentationP, suite.craneWrapperMock, suite.acrKCFactoryMock, suite.k8sKCFactoryMock)
+	suite.transportProviderMock = new(mocks.IRegistryTransportProvider)
+	suite.transportProviderMock.On("GetOptions", mock.Anything).Return(nil).Maybe()
+	suite.client = NewCraneRegistryClient(instrumentationP, suite.craneWrapperMock, suite.acrKCFactoryMock, suite.k8sKCFactoryMock, suite.transportProviderMock)
 }
 
 func (suite *CraneRegistryTestSuite) Test_GetDigest() {
 //TODO
 }
 
+func (suite *CraneRegistryTestSuite) Test_GetDigestUsingDefaultAuth_Timeout_ReturnsDigestResolutionTimeoutErr() {
+	suite.craneWrapperMock.On("Digest", mock.Anything, getImageReferenceMock().Original(), mock.Anything, mock.Anything).Return("", context.DeadlineExceeded).Once()
+
+	digest, err := suite.client.GetDigestUsingDefaultAuth(context.Background(), getImageReferenceMock())
+
+	suite.Equal("", digest)
+	suite.IsType(&registryerrors.DigestResolutionTimeoutErr{}, errors.Cause(err))
+	suite.ErrorIs(err, context.DeadlineExceeded)
+	suite.AssertExpectation()
+}
+
+func (suite *CraneRegistryTestSuite) Test_GetDigestUsingDefaultAuth_ContextDeadlineExceeded_NotDigestResolutionTimeoutErr() {
+	suite.craneWrapperMock.On("Digest", mock.Anything, getImageReferenceMock().Original(), mock.Anything, mock.Anything).Return("", context.DeadlineExceeded).Once()
+	ctx, cancel := context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+	defer cancel()
+
+	digest, err := suite.client.GetDigestUsingDefaultAuth(ctx, getImageReferenceMock())
+
+	// The caller stopped waiting - it isn't a timeout of the registry
+	suite.Equal("", digest)
+	suite.Equal(context.DeadlineExceeded, errors.Cause(err))
+	suite.AssertExpectation()
+}
+
+func (suite *CraneRegistryTestSuite) Test_GetDigestUsingDefaultAuth_UnknownError_ReturnsError() {
+	expectedErr := errors.New("unknown error")
+	suite.craneWrapperMock.On("Digest", mock.Anything, getImageReferenceMock().Original(), mock.Anything, mock.Anything).Return("", expectedErr).Once()
+
+	digest, err := suite.client.GetDigestUsingDefaultAuth(context.Background(), getImageReferenceMock())
+
+	suite.Equal("", digest)
+	suite.Equal(expectedErr, errors.Cause(err))
+	suite.AssertExpectation()
+}
+
+func (suite *CraneRegistryTestSuite) Test_ListReferrers_ReferrersIndexExists_ReturnsReferrers() {
+	suite.k8sKCFactoryMock.On("Create", "default", []string{}, "").Return(authn.DefaultKeychain, nil).Once()
+	suite.craneWrapperMock.On("Manifest", mock.Anything, _referrersTagRefMock, mock.Anything, mock.Anything).Return([]byte(_referrersIndexMock), nil).Once()
+
+	referrers, err := suite.client.ListReferrers(context.Background(), getImageReferenceMock(), _referredDigestMock, registry.NewAuthContext("default", []string{}, ""))
+
+	suite.Nil(err)
+	suite.Equal([]*registry.Referrer{
+		registry.NewReferrer("sha256:aaaa", "application/vnd.oci.image.manifest.v1+json", "application/spdx+json", nil),
+		registry.NewReferrer("sha256:bbbb", "application/vnd.oci.image.manifest.v1+json", "", map[string]string{"in-toto.io/predicate-type": "https://slsa.dev/provenance/v0.2"}),
+	}, referrers)
+	suite.AssertExpectation()
+}
+
+func (suite *CraneRegistryTestSuite) Test_ListReferrers_NoReferrersTag_ReturnsEmptyList() {
+	suite.k8sKCFactoryMock.On("Create", "default", []string{}, "").Return(authn.DefaultKeychain, nil).Once()
+	suite.craneWrapperMock.On("Manifest", mock.Anything, _referrersTagRefMock, mock.Anything, mock.Anything).Return(nil, registryerrors.NewImageIsNotFoundErr(_referrersTagRefMock, errors.New("MANIFEST_UNKNOWN"))).Once()
+
+	referrers, err := suite.client.ListReferrers(context.Background(), getImageReferenceMock(), _referredDigestMock, registry.NewAuthContext("default", []string{}, ""))
+
+	suite.Nil(err)
+	suite.Empty(referrers)
+	suite.NotNil(referrers)
+	suite.AssertExpectation()
+}
+
+func (suite *CraneRegistryTestSuite) Test_ListReferrers_ManifestFailed_ReturnsError() {
+	expectedErr := errors.New("registry is down")
+	suite.k8sKCFactoryMock.On("Create", "default", []string{}, "").Return(authn.DefaultKeychain, nil).Once()
+	suite.craneWrapperMock.On("Manifest", mock.Anything, _referrersTagRefMock, mock.Anything, mock.Anything).Return(nil, expectedErr).Once()
+
+	referrers, err := suite.client.ListReferrers(context.Background(), getImageReferenceMock(), _referredDigestMock, registry.NewAuthContext("default", []string{}, ""))
+
+	suite.Nil(referrers)
+	suite.Equal(expectedErr, errors.Cause(err))
+	suite.AssertExpectation()
+}
+
+func (suite *CraneRegistryTestSuite) Test_ListReferrers_NilAuthContext_ReturnsError() {
+	referrers, err := suite.client.ListReferrers(context.Background(), getImageReferenceMock(), _referredDigestMock, nil)
+
+	suite.Nil(referrers)
+	suite.NotNil(err)
+	suite.AssertExpectation()
+}
+
 func (suite *CraneRegistryTestSuite) AssertExpectation() {
 	suite.craneWrapperMock.AssertExpectations(suite.T())
 	suite.acrKCFactoryMock.AssertExpectations(suite.T())
 	suite.k8sKCFactoryMock.AssertExpectations(suite.T())
+	suite.transportProviderMock.AssertExpectations(suite.T())
 }
 
 func Test_CraneRegistryTestSuite(t *testing.T) {
 	suite.Run(t, new(CraneRegistryTestSuite))
 }
+
+func getImageReferenceMock() registry.IImageReference {
+	return registry.NewTag("tomer.io/redis:latest", "tomer.io", "redis", "latest")
+}
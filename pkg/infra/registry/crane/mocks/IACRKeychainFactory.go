@@ -3,6 +3,8 @@
 package mocks
 
 import (
+	context "context"
+
 	authn "github.com/google/go-containerregistry/pkg/authn"
 
 	mock "github.com/stretchr/testify/mock"
@@ -13,13 +15,13 @@ type IACRKeychainFactory struct {
 	mock.Mock
 }
 
-// Create provides a mock function with given fields: registry
-func (_m *IACRKeychainFactory) Create(registry string) (authn.Keychain, error) {
-	ret := _m.Called(registry)
+// Create provides a mock function with given fields: ctx, registry
+func (_m *IACRKeychainFactory) Create(ctx context.Context, registry string) (authn.Keychain, error) {
+	ret := _m.Called(ctx, registry)
 
 	var r0 authn.Keychain
-	if rf, ok := ret.Get(0).(func(string) authn.Keychain); ok {
-		r0 = rf(registry)
+	if rf, ok := ret.Get(0).(func(context.Context, string) authn.Keychain); ok {
+		r0 = rf(ctx, registry)
 	} else {
 		if ret.Get(0) != nil {
 			r0 = ret.Get(0).(authn.Keychain)
@@ -27,8 +29,8 @@ func (_m *IACRKeychainFactory) Create(registry string) (authn.Keychain, error) {
 	}
 
 	var r1 error
-	if rf, ok := ret.Get(1).(func(string) error); ok {
-		r1 = rf(registry)
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, registry)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -0,0 +1,155 @@
+package crane
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/instrumentation"
+	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/instrumentation/trace"
+	"github.com/google/go-containerregistry/pkg/crane"
+	"github.com/pkg/errors"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// IRegistryTransportProvider provides the crane transport options of registries
+type IRegistryTransportProvider interface {
+	// GetOptions returns the crane transport options of the registry.
+	// Returns empty options in case that the registry has no transport configuration (crane's default transport is used).
+	GetOptions(registry string) []crane.Option
+}
+
+// RegistryTransportProvider implements IRegistryTransportProvider interface
+var _ IRegistryTransportProvider = (*RegistryTransportProvider)(nil)
+
+// RegistryTransportProvider creates the transports of the configured registries once and provides their crane options
+type RegistryTransportProvider struct {
+	// tracerProvider is the tracer provider for the registry transport provider
+	tracerProvider trace.ITracerProvider
+	// registriesOptions is mapping between registry (lower case) and its crane transport options
+	registriesOptions map[string][]crane.Option
+}
+
+// RegistryTransportProviderConfiguration is configuration data for RegistryTransportProvider
+type RegistryTransportProviderConfiguration struct {
+	// RegistriesTransportConfiguration is the transport configuration of each registry that can't use crane's default transport
+	RegistriesTransportConfiguration []*RegistryTransportConfiguration
+}
+
+// RegistryTransportConfiguration is the transport configuration of a single registry
+type RegistryTransportConfiguration struct {
+	// Registry is the registry host (e.g. "registry.contoso.com" or "registry.contoso.com:5000")
+	Registry string
+	// CABundleFilePath is path of PEM encoded CA certificates file that are trusted in addition to the system's CA certificates
+	CABundleFilePath string
+	// Insecure is whether the registry's TLS certificate shouldn't be verified - should be used only for dev registries
+	Insecure bool
+	// PlainHTTP is whether the registry should be accessed using plain HTTP instead of HTTPS - should be used only for dev registries
+	PlainHTTP bool
+	// ProxyURL is the URL of HTTP(S) proxy that is used to access the registry. If empty, the proxy is taken from the environment (HTTPS_PROXY, NO_PROXY...)
+	ProxyURL string
+	// DialTimeoutInMS is the timeout **IN MILLISECONDS** of establishing a connection to the registry. If zero - default is used
+	DialTimeoutInMS int
+	// TLSHandshakeTimeoutInMS is the timeout **IN MILLISECONDS** of the TLS handshake with the registry. If zero - default is used
+	TLSHandshakeTimeoutInMS int
+	// ResponseHeaderTimeoutInMS is the timeout **IN MILLISECONDS** of waiting to the registry's response headers. If zero - no timeout
+	ResponseHeaderTimeoutInMS int
+}
+
+// NewRegistryTransportProvider Constructor for RegistryTransportProvider.
+// Returns error in case that the transport of one of the registries couldn't be created (e.g. invalid CA bundle or proxy URL)
+func NewRegistryTransportProvider(instrumentationProvider instrumentation.IInstrumentationProvider, configuration *RegistryTransportProviderConfiguration) (*RegistryTransportProvider, error) {
+	provider := &RegistryTransportProvider{
+		tracerProvider:    instrumentationProvider.GetTracerProvider("RegistryTransportProvider"),
+		registriesOptions: make(map[string][]crane.Option, len(configuration.RegistriesTransportConfiguration)),
+	}
+	tracer := provider.tracerProvider.GetTracer("NewRegistryTransportProvider")
+
+	for _, registryConfiguration := range configuration.RegistriesTransportConfiguration {
+		if registryConfiguration == nil || registryConfiguration.Registry == "" {
+			return nil, errors.New("RegistryTransportProvider: registry transport configuration must have a registry")
+		}
+		options, err := createRegistryOptions(registryConfiguration)
+		if err != nil {
+			return nil, errors.Wrapf(err, "RegistryTransportProvider: failed to create transport of registry <%s>", registryConfiguration.Registry)
+		}
+		provider.registriesOptions[strings.ToLower(registryConfiguration.Registry)] = options
+		tracer.Info("Registry transport created", "registry", registryConfiguration.Registry, "caBundleFilePath", registryConfiguration.CABundleFilePath, "insecure", registryConfiguration.Insecure, "plainHTTP", registryConfiguration.PlainHTTP, "proxyURL", registryConfiguration.ProxyURL)
+	}
+	return provider, nil
+}
+
+// GetOptions returns the crane transport options of the registry.
+// Returns empty options in case that the registry has no transport configuration (crane's default transport is used).
+func (provider *RegistryTransportProvider) GetOptions(registry string) []crane.Option {
+	return provider.registriesOptions[strings.ToLower(registry)]
+}
+
+// createRegistryOptions creates the crane options of the registry transport configuration
+func createRegistryOptions(configuration *RegistryTransportConfiguration) ([]crane.Option, error) {
+	transport, err := createRegistryTransport(configuration)
+	if err != nil {
+		return nil, err
+	}
+
+	options := []crane.Option{crane.WithTransport(transport)}
+	if configuration.PlainHTTP {
+		options = append(options, crane.Insecure)
+	}
+	return options, nil
+}
+
+// createRegistryTransport creates the http transport of the registry transport configuration based on the default http transport
+func createRegistryTransport(configuration *RegistryTransportConfiguration) (*http.Transport, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+
+	tlsConfig := &tls.Config{InsecureSkipVerify: configuration.Insecure}
+	if configuration.CABundleFilePath != "" {
+		rootCAs, err := loadCABundle(configuration.CABundleFilePath)
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig.RootCAs = rootCAs
+	}
+	transport.TLSClientConfig = tlsConfig
+
+	if configuration.ProxyURL != "" {
+		proxyURL, err := url.Parse(configuration.ProxyURL)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid proxy URL <%s>", configuration.ProxyURL)
+		}
+		transport.Proxy = http.ProxyURL(proxyURL)
+	}
+
+	if configuration.DialTimeoutInMS > 0 {
+		dialer := &net.Dialer{Timeout: time.Duration(configuration.DialTimeoutInMS) * time.Millisecond, KeepAlive: 30 * time.Second}
+		transport.DialContext = dialer.DialContext
+	}
+	if configuration.TLSHandshakeTimeoutInMS > 0 {
+		transport.TLSHandshakeTimeout = time.Duration(configuration.TLSHandshakeTimeoutInMS) * time.Millisecond
+	}
+	if configuration.ResponseHeaderTimeoutInMS > 0 {
+		transport.ResponseHeaderTimeout = time.Duration(configuration.ResponseHeaderTimeoutInMS) * time.Millisecond
+	}
+	return transport, nil
+}
+
+// loadCABundle returns the system's cert pool with the PEM encoded certificates of the CA bundle file appended
+func loadCABundle(caBundleFilePath string) (*x509.CertPool, error) {
+	caBundle, err := ioutil.ReadFile(caBundleFilePath)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read CA bundle file <%s>", caBundleFilePath)
+	}
+
+	rootCAs, err := x509.SystemCertPool()
+	if err != nil || rootCAs == nil {
+		rootCAs = x509.NewCertPool()
+	}
+	if !rootCAs.AppendCertsFromPEM(caBundle) {
+		return nil, errors.Errorf("CA bundle file <%s> doesn't contain PEM encoded certificates", caBundleFilePath)
+	}
+	return rootCAs, nil
+}
@@ -0,0 +1,103 @@
+package crane
+
+import (
+	"encoding/pem"
+	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/instrumentation"
+	registryerrors "github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/registry/errors"
+	craneerrors "github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/registry/wrappers/errors"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/suite"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+type RegistryTransportProviderTestSuite struct {
+	suite.Suite
+	server           *httptest.Server
+	caBundleFilePath string
+}
+
+// This will run before each test in the suite - creates a TLS server and writes its certificate to a CA bundle file.
+func (suite *RegistryTransportProviderTestSuite) SetupTest() {
+	suite.server = httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	caBundle := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: suite.server.Certificate().Raw})
+	suite.caBundleFilePath = filepath.Join(suite.T().TempDir(), "ca.pem")
+	suite.Nil(ioutil.WriteFile(suite.caBundleFilePath, caBundle, os.ModePerm))
+}
+
+func (suite *RegistryTransportProviderTestSuite) TearDownTest() {
+	suite.server.Close()
+}
+
+func (suite *RegistryTransportProviderTestSuite) Test_createRegistryTransport_CABundle_TLSVerified() {
+	transport, err := createRegistryTransport(&RegistryTransportConfiguration{Registry: "registry.contoso.com", CABundleFilePath: suite.caBundleFilePath})
+	suite.Nil(err)
+
+	resp, err := (&http.Client{Transport: transport}).Get(suite.server.URL)
+
+	suite.Nil(err)
+	suite.Equal(http.StatusOK, resp.StatusCode)
+}
+
+func (suite *RegistryTransportProviderTestSuite) Test_createRegistryTransport_Insecure_TLSNotVerified() {
+	transport, err := createRegistryTransport(&RegistryTransportConfiguration{Registry: "registry.contoso.com", Insecure: true})
+	suite.Nil(err)
+
+	resp, err := (&http.Client{Transport: transport}).Get(suite.server.URL)
+
+	suite.Nil(err)
+	suite.Equal(http.StatusOK, resp.StatusCode)
+}
+
+func (suite *RegistryTransportProviderTestSuite) Test_createRegistryTransport_NoCABundle_RegistryTLSErr() {
+	transport, err := createRegistryTransport(&RegistryTransportConfiguration{Registry: "registry.contoso.com"})
+	suite.Nil(err)
+
+	_, err = (&http.Client{Transport: transport}).Get(suite.server.URL)
+	suite.NotNil(err)
+
+	// crane flattens the ping errors to string error
+	knownErr, ok := craneerrors.TryParseCraneErrToRegistryKnownErr("registry.contoso.com/app:v1", errors.New(err.Error()))
+	suite.True(ok)
+	suite.IsType(&registryerrors.RegistryTLSErr{}, knownErr)
+}
+
+func (suite *RegistryTransportProviderTestSuite) Test_NewRegistryTransportProvider_ConfiguredRegistry_Options() {
+	provider, err := NewRegistryTransportProvider(instrumentation.NewNoOpInstrumentationProvider(), &RegistryTransportProviderConfiguration{
+		RegistriesTransportConfiguration: []*RegistryTransportConfiguration{
+			{Registry: "Registry.Contoso.com", CABundleFilePath: suite.caBundleFilePath},
+			{Registry: "dev.contoso.com:5000", PlainHTTP: true, ProxyURL: "http://proxy.contoso.com:3128", DialTimeoutInMS: 100, TLSHandshakeTimeoutInMS: 100, ResponseHeaderTimeoutInMS: 100},
+		},
+	})
+
+	suite.Nil(err)
+	suite.Len(provider.GetOptions("registry.contoso.com"), 1)
+	suite.Len(provider.GetOptions("dev.contoso.com:5000"), 2)
+	suite.Empty(provider.GetOptions("other.azurecr.io"))
+}
+
+func (suite *RegistryTransportProviderTestSuite) Test_NewRegistryTransportProvider_MissingCABundle_Error() {
+	provider, err := NewRegistryTransportProvider(instrumentation.NewNoOpInstrumentationProvider(), &RegistryTransportProviderConfiguration{
+		RegistriesTransportConfiguration: []*RegistryTransportConfiguration{{Registry: "registry.contoso.com", CABundleFilePath: "/not/exists.pem"}},
+	})
+
+	suite.NotNil(err)
+	suite.Nil(provider)
+}
+
+func (suite *RegistryTransportProviderTestSuite) Test_NewRegistryTransportProvider_MissingRegistry_Error() {
+	provider, err := NewRegistryTransportProvider(instrumentation.NewNoOpInstrumentationProvider(), &RegistryTransportProviderConfiguration{
+		RegistriesTransportConfiguration: []*RegistryTransportConfiguration{{Insecure: true}},
+	})
+
+	suite.NotNil(err)
+	suite.Nil(provider)
+}
+
+func Test_RegistryTransportProviderTestSuite(t *testing.T) {
+	suite.Run(t, new(RegistryTransportProviderTestSuite))
+}
@@ -0,0 +1,124 @@
+package errors
+
+import (
+	"fmt"
+)
+
+// The errors of this file are failures of the dependencies of the scan (data provider, identity) rather than of the image.
+// They're transient, so they aren't stored in cache. The original error is kept in the chain of errors.Is/errors.As (unlike errors.Cause).
+
+// DataProviderUnauthorizedErr implements errors.error interface
+var _ error = (*DataProviderUnauthorizedErr)(nil)
+
+// DataProviderUnauthorizedErr is error that returns when the identity isn't authorized to query the data provider (e.g. ARG returned 401/403).
+type DataProviderUnauthorizedErr struct {
+	dataProvider string
+	err          error
+}
+
+// NewDataProviderUnauthorizedErr Constructor for DataProviderUnauthorizedErr
+func NewDataProviderUnauthorizedErr(dataProvider string, err error) *DataProviderUnauthorizedErr {
+	return &DataProviderUnauthorizedErr{dataProvider: dataProvider, err: err}
+}
+
+func (err *DataProviderUnauthorizedErr) Error() string {
+	return fmt.Sprintf("Unauthorized when trying to query data provider <%s>.\n error: <%s>", err.dataProvider, err.err)
+}
+
+// Unwrap returns the original error
+func (err *DataProviderUnauthorizedErr) Unwrap() error {
+	return err.err
+}
+
+// DataProviderThrottledErr implements errors.error interface
+var _ error = (*DataProviderThrottledErr)(nil)
+
+// DataProviderThrottledErr is error that returns when the data provider throttled the query (e.g. ARG returned 429).
+type DataProviderThrottledErr struct {
+	dataProvider string
+	err          error
+}
+
+// NewDataProviderThrottledErr Constructor for DataProviderThrottledErr
+func NewDataProviderThrottledErr(dataProvider string, err error) *DataProviderThrottledErr {
+	return &DataProviderThrottledErr{dataProvider: dataProvider, err: err}
+}
+
+func (err *DataProviderThrottledErr) Error() string {
+	return fmt.Sprintf("Throttled when trying to query data provider <%s>.\n error: <%s>", err.dataProvider, err.err)
+}
+
+// Unwrap returns the original error
+func (err *DataProviderThrottledErr) Unwrap() error {
+	return err.err
+}
+
+// DataProviderUnavailableErr implements errors.error interface
+var _ error = (*DataProviderUnavailableErr)(nil)
+
+// DataProviderUnavailableErr is error that returns when the data provider can't be reached or fails (e.g. ARG returned 5xx or network failure).
+type DataProviderUnavailableErr struct {
+	dataProvider string
+	err          error
+}
+
+// NewDataProviderUnavailableErr Constructor for DataProviderUnavailableErr
+func NewDataProviderUnavailableErr(dataProvider string, err error) *DataProviderUnavailableErr {
+	return &DataProviderUnavailableErr{dataProvider: dataProvider, err: err}
+}
+
+func (err *DataProviderUnavailableErr) Error() string {
+	return fmt.Sprintf("Data provider <%s> is unavailable.\n error: <%s>", err.dataProvider, err.err)
+}
+
+// Unwrap returns the original error
+func (err *DataProviderUnavailableErr) Unwrap() error {
+	return err.err
+}
+
+// IdentityUnavailableErr implements errors.error interface
+var _ error = (*IdentityUnavailableErr)(nil)
+
+// IdentityUnavailableErr is error that returns when the token of the managed identity couldn't be acquired
+// (e.g. MSI endpoint failure) or couldn't be exchanged to the credentials of the target (e.g. ACR token exchange failure).
+type IdentityUnavailableErr struct {
+	target string
+	err    error
+}
+
+// NewIdentityUnavailableErr Constructor for IdentityUnavailableErr
+func NewIdentityUnavailableErr(target string, err error) *IdentityUnavailableErr {
+	return &IdentityUnavailableErr{target: target, err: err}
+}
+
+func (err *IdentityUnavailableErr) Error() string {
+	return fmt.Sprintf("Identity is unavailable when trying to get credentials for <%s>.\n error: <%s>", err.target, err.err)
+}
+
+// Unwrap returns the original error
+func (err *IdentityUnavailableErr) Unwrap() error {
+	return err.err
+}
+
+// DigestResolutionTimeoutErr implements errors.error interface
+var _ error = (*DigestResolutionTimeoutErr)(nil)
+
+// DigestResolutionTimeoutErr is error that returns when the registry didn't respond in time while trying to resolve the digest of an image.
+type DigestResolutionTimeoutErr struct {
+	imageRef string
+	err      error
+}
+
+// NewDigestResolutionTimeoutErr Constructor for DigestResolutionTimeoutErr
+func NewDigestResolutionTimeoutErr(imageRef string, err error) *DigestResolutionTimeoutErr {
+	return &DigestResolutionTimeoutErr{imageRef: imageRef, err: err}
+}
+
+func (err *DigestResolutionTimeoutErr) Error() string {
+	return fmt.Sprintf("Timeout when trying to resolve image <%s>.\n error: <%s>", err.imageRef, err.err)
+}
+
+// Unwrap returns the original error
+func (err *DigestResolutionTimeoutErr) Unwrap() error {
+	return err.err
+}
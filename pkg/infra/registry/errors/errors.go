@@ -3,6 +3,7 @@ package errors
 import (
 	"fmt"
 	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/azdsecinfo/contracts"
+	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/circuitbreaker"
 	"github.com/pkg/errors"
 )
 
@@ -62,6 +63,25 @@ func (err *UnauthorizedErr) Error() string {
 	return msg
 }
 
+// RegistryTLSErr implements errors.error interface
+var _ error = (*RegistryTLSErr)(nil)
+
+// RegistryTLSErr error that returns due to TLS failure while connecting the registry (e.g. unknown certificate authority).
+type RegistryTLSErr struct {
+	imageRef string
+	err      error
+}
+
+// NewRegistryTLSErr  Constructor for RegistryTLSErr
+func NewRegistryTLSErr(imageRef string, err error) *RegistryTLSErr {
+	return &RegistryTLSErr{imageRef: imageRef, err: err}
+}
+
+func (err *RegistryTLSErr) Error() string {
+	msg := fmt.Sprintf("TLS failure when trying to resolve image <%s>.\n error: <%s>", err.imageRef, err.err)
+	return msg
+}
+
 // TryParseErrToUnscannedWithReason gets an error the container that the error encountered and returns the info and error according to the type of the error.
 // If the error is expected error (e.g. image is not exists while trying to resolve the digest, unauthorized to arg) then
 // this function create new contracts.ContainerVulnerabilityScanInfo that that status is unscanned and add in the additional metadata field
@@ -84,6 +104,27 @@ func TryParseErrToUnscannedWithReason(err error) (*contracts.UnscannedReason, bo
 	case *RegistryIsNotFoundErr: // Checks if the error  NoSuchHost - it means that the registry  not found.
 		unscannedReason := contracts.RegistryDoesNotExistUnscannedReason
 		return &unscannedReason, true
+	case *RegistryTLSErr: // Checks if the error  TLS failure - e.g. registry's certificate is signed by unknown authority.
+		unscannedReason := contracts.RegistryTLSErrorUnscannedReason
+		return &unscannedReason, true
+	case *circuitbreaker.CircuitIsOpenErr: // Checks if the error  open circuit - the dependency (registry, ARG) is failing, so the call was rejected.
+		unscannedReason := contracts.CircuitBreakerIsOpenUnscannedReason
+		return &unscannedReason, true
+	case *DataProviderUnauthorizedErr: // Checks if the error  unauthorized to the data provider (e.g. ARG).
+		unscannedReason := contracts.DataProviderUnauthorizedUnscannedReason
+		return &unscannedReason, true
+	case *DataProviderThrottledErr: // Checks if the error  throttling of the data provider.
+		unscannedReason := contracts.DataProviderThrottledUnscannedReason
+		return &unscannedReason, true
+	case *DataProviderUnavailableErr: // Checks if the error  failure of the data provider (5xx or network failure).
+		unscannedReason := contracts.DataProviderUnavailableUnscannedReason
+		return &unscannedReason, true
+	case *IdentityUnavailableErr: // Checks if the error  failure to acquire the token of the identity.
+		unscannedReason := contracts.IdentityUnavailableUnscannedReason
+		return &unscannedReason, true
+	case *DigestResolutionTimeoutErr: // Checks if the error  timeout of the registry while resolving the digest.
+		unscannedReason := contracts.DigestResolutionTimeoutUnscannedReason
+		return &unscannedReason, true
 	default: // Unexpected error
 		return nil, false
 	}
@@ -98,6 +139,8 @@ func TryParseStringToUnscannedWithReasonErr(errAsString string) (error, bool){
 		return new(UnauthorizedErr), true
 	case string(contracts.RegistryDoesNotExistUnscannedReason):
 		return new(RegistryIsNotFoundErr), true
+	case string(contracts.RegistryTLSErrorUnscannedReason):
+		return new(RegistryTLSErr), true
 	default: // Unknown error or not an error
 		return nil, false
 	}
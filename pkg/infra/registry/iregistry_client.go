@@ -1,15 +1,30 @@
 package registry
 
+import "context"
+
 // IRegistryClient container registry based client
 type IRegistryClient interface {
 	// GetDigestUsingACRAttachAuth receives image reference and get it's digest using ACR attach authntication
 	// ACR attach auth is based MSI token used to access the registry
-	GetDigestUsingACRAttachAuth(imageReference IImageReference) (string, error)
+	GetDigestUsingACRAttachAuth(ctx context.Context, imageReference IImageReference) (string, error)
 
 	// GetDigestUsingK8SAuth receives image reference and get it's digest using K8S secerts and auth
 	// K8S auth is based image pull secrets used in deployment or attached to service account to pull the image
-	GetDigestUsingK8SAuth(imageReference IImageReference, namespace string, imagePullSecrets []string, serviceAccountName string) (string, error)
+	GetDigestUsingK8SAuth(ctx context.Context, imageReference IImageReference, namespace string, imagePullSecrets []string, serviceAccountName string) (string, error)
 
 	// GetDigestUsingDefaultAuth receives image reference and get it's digest using the default docker config auth
-	GetDigestUsingDefaultAuth(imageReference IImageReference) (string, error)
+	GetDigestUsingDefaultAuth(ctx context.Context, imageReference IImageReference) (string, error)
+
+	// GetManifest receives image reference and returns its raw manifest.
+	// It authenticates with a chain of ACR attach auth (ACR registries only), K8S auth and the default docker config auth
+	GetManifest(ctx context.Context, imageReference IImageReference, authContext *AuthContext) ([]byte, error)
+
+	// GetBlob receives image reference and a blob digest in the image's repository and returns the blob content.
+	// It authenticates with the same chain of keychains as GetManifest
+	GetBlob(ctx context.Context, imageReference IImageReference, blobDigest string, authContext *AuthContext) ([]byte, error)
+
+	// ListReferrers receives image reference and a digest in the image's repository and returns the artifacts that refer to the digest
+	// (e.g. SBOMs and attestations). Returns empty list in case that the digest has no referrers.
+	// It authenticates with the same chain of keychains as GetManifest
+	ListReferrers(ctx context.Context, imageReference IImageReference, digest string, authContext *AuthContext) ([]*Referrer, error)
 }
@@ -3,6 +3,8 @@
 package mocks
 
 import (
+	context "context"
+
 	registry "github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/registry"
 	mock "github.com/stretchr/testify/mock"
 )
@@ -12,20 +14,20 @@ type IRegistryClient struct {
 	mock.Mock
 }
 
-// GetDigestUsingACRAttachAuth provides a mock function with given fields: imageReference
-func (_m *IRegistryClient) GetDigestUsingACRAttachAuth(imageReference registry.IImageReference) (string, error) {
-	ret := _m.Called(imageReference)
+// GetDigestUsingACRAttachAuth provides a mock function with given fields: ctx, imageReference
+func (_m *IRegistryClient) GetDigestUsingACRAttachAuth(ctx context.Context, imageReference registry.IImageReference) (string, error) {
+	ret := _m.Called(ctx, imageReference)
 
 	var r0 string
-	if rf, ok := ret.Get(0).(func(registry.IImageReference) string); ok {
-		r0 = rf(imageReference)
+	if rf, ok := ret.Get(0).(func(context.Context, registry.IImageReference) string); ok {
+		r0 = rf(ctx, imageReference)
 	} else {
 		r0 = ret.Get(0).(string)
 	}
 
 	var r1 error
-	if rf, ok := ret.Get(1).(func(registry.IImageReference) error); ok {
-		r1 = rf(imageReference)
+	if rf, ok := ret.Get(1).(func(context.Context, registry.IImageReference) error); ok {
+		r1 = rf(ctx, imageReference)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -33,20 +35,20 @@ func (_m *IRegistryClient) GetDigestUsingACRAttachAuth(imageReference registry.I
 	return r0, r1
 }
 
-// GetDigestUsingDefaultAuth provides a mock function with given fields: imageReference
-func (_m *IRegistryClient) GetDigestUsingDefaultAuth(imageReference registry.IImageReference) (string, error) {
-	ret := _m.Called(imageReference)
+// GetDigestUsingDefaultAuth provides a mock function with given fields: ctx, imageReference
+func (_m *IRegistryClient) GetDigestUsingDefaultAuth(ctx context.Context, imageReference registry.IImageReference) (string, error) {
+	ret := _m.Called(ctx, imageReference)
 
 	var r0 string
-	if rf, ok := ret.Get(0).(func(registry.IImageReference) string); ok {
-		r0 = rf(imageReference)
+	if rf, ok := ret.Get(0).(func(context.Context, registry.IImageReference) string); ok {
+		r0 = rf(ctx, imageReference)
 	} else {
 		r0 = ret.Get(0).(string)
 	}
 
 	var r1 error
-	if rf, ok := ret.Get(1).(func(registry.IImageReference) error); ok {
-		r1 = rf(imageReference)
+	if rf, ok := ret.Get(1).(func(context.Context, registry.IImageReference) error); ok {
+		r1 = rf(ctx, imageReference)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -54,20 +56,89 @@ func (_m *IRegistryClient) GetDigestUsingDefaultAuth(imageReference registry.IIm
 	return r0, r1
 }
 
-// GetDigestUsingK8SAuth provides a mock function with given fields: imageReference, namespace, imagePullSecrets, serviceAccountName
-func (_m *IRegistryClient) GetDigestUsingK8SAuth(imageReference registry.IImageReference, namespace string, imagePullSecrets []string, serviceAccountName string) (string, error) {
-	ret := _m.Called(imageReference, namespace, imagePullSecrets, serviceAccountName)
+// GetDigestUsingK8SAuth provides a mock function with given fields: ctx, imageReference, namespace, imagePullSecrets, serviceAccountName
+func (_m *IRegistryClient) GetDigestUsingK8SAuth(ctx context.Context, imageReference registry.IImageReference, namespace string, imagePullSecrets []string, serviceAccountName string) (string, error) {
+	ret := _m.Called(ctx, imageReference, namespace, imagePullSecrets, serviceAccountName)
 
 	var r0 string
-	if rf, ok := ret.Get(0).(func(registry.IImageReference, string, []string, string) string); ok {
-		r0 = rf(imageReference, namespace, imagePullSecrets, serviceAccountName)
+	if rf, ok := ret.Get(0).(func(context.Context, registry.IImageReference, string, []string, string) string); ok {
+		r0 = rf(ctx, imageReference, namespace, imagePullSecrets, serviceAccountName)
 	} else {
 		r0 = ret.Get(0).(string)
 	}
 
 	var r1 error
-	if rf, ok := ret.Get(1).(func(registry.IImageReference, string, []string, string) error); ok {
-		r1 = rf(imageReference, namespace, imagePullSecrets, serviceAccountName)
+	if rf, ok := ret.Get(1).(func(context.Context, registry.IImageReference, string, []string, string) error); ok {
+		r1 = rf(ctx, imageReference, namespace, imagePullSecrets, serviceAccountName)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetManifest provides a mock function with given fields: ctx, imageReference, authContext
+func (_m *IRegistryClient) GetManifest(ctx context.Context, imageReference registry.IImageReference, authContext *registry.AuthContext) ([]byte, error) {
+	ret := _m.Called(ctx, imageReference, authContext)
+
+	var r0 []byte
+	if rf, ok := ret.Get(0).(func(context.Context, registry.IImageReference, *registry.AuthContext) []byte); ok {
+		r0 = rf(ctx, imageReference, authContext)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]byte)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, registry.IImageReference, *registry.AuthContext) error); ok {
+		r1 = rf(ctx, imageReference, authContext)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetBlob provides a mock function with given fields: ctx, imageReference, blobDigest, authContext
+func (_m *IRegistryClient) GetBlob(ctx context.Context, imageReference registry.IImageReference, blobDigest string, authContext *registry.AuthContext) ([]byte, error) {
+	ret := _m.Called(ctx, imageReference, blobDigest, authContext)
+
+	var r0 []byte
+	if rf, ok := ret.Get(0).(func(context.Context, registry.IImageReference, string, *registry.AuthContext) []byte); ok {
+		r0 = rf(ctx, imageReference, blobDigest, authContext)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]byte)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, registry.IImageReference, string, *registry.AuthContext) error); ok {
+		r1 = rf(ctx, imageReference, blobDigest, authContext)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ListReferrers provides a mock function with given fields: ctx, imageReference, digest, authContext
+func (_m *IRegistryClient) ListReferrers(ctx context.Context, imageReference registry.IImageReference, digest string, authContext *registry.AuthContext) ([]*registry.Referrer, error) {
+	ret := _m.Called(ctx, imageReference, digest, authContext)
+
+	var r0 []*registry.Referrer
+	if rf, ok := ret.Get(0).(func(context.Context, registry.IImageReference, string, *registry.AuthContext) []*registry.Referrer); ok {
+		r0 = rf(ctx, imageReference, digest, authContext)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*registry.Referrer)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, registry.IImageReference, string, *registry.AuthContext) error); ok {
+		r1 = rf(ctx, imageReference, digest, authContext)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -0,0 +1,23 @@
+package registry
+
+// Referrer represents an artifact that refers to an image digest (e.g. SBOM, attestation or signature)
+type Referrer struct {
+	// Digest is the digest of the referrer's manifest
+	Digest string
+	// MediaType is the media type of the referrer's manifest
+	MediaType string
+	// ArtifactType is the type of the artifact (e.g. "application/spdx+json"). May be empty in case that the registry doesn't report it.
+	ArtifactType string
+	// Annotations are the annotations of the referrer
+	Annotations map[string]string
+}
+
+// NewReferrer Referrer ctor
+func NewReferrer(digest string, mediaType string, artifactType string, annotations map[string]string) *Referrer {
+	return &Referrer{
+		Digest:       digest,
+		MediaType:    mediaType,
+		ArtifactType: artifactType,
+		Annotations:  annotations,
+	}
+}
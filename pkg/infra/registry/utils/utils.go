@@ -1,17 +1,14 @@
 package utils
 
 import (
+	"fmt"
+	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/cloudenvironment"
 	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/registry"
 	name "github.com/google/go-containerregistry/pkg/name"
 	"github.com/pkg/errors"
 	"strings"
 )
 
-const (
-	// _azureContainerRegistrySuffix is the suffix of ACR public (todo extract per env maybe?)
-	_azureContainerRegistrySuffix = ".azurecr.io"
-)
-
 //GetImageReference receives image reference string (e.g. tomer.azurecr.io/redis:v1)
 // Function extract and return received ref: registry and repository and identifiers like digest/tag, also saves the original ref str
 // If image reference is not in right format or unknown, returns error.
@@ -36,7 +33,19 @@ func GetImageReference(imageRef string) (registry.IImageReference, error) {
 	}
 }
 
-// IsRegistryEndpointACR return is registryEndpoing is ACR based (ACR suffix)
+// IsRegistryEndpointACR return is registryEndpoing is ACR based (ACR suffix of the cloud environment, e.g. .azurecr.us in US government cloud)
 func IsRegistryEndpointACR(registryEndpoint string) bool {
-	return strings.HasSuffix(strings.ToLower(registryEndpoint), _azureContainerRegistrySuffix)
+	return strings.HasSuffix(strings.ToLower(registryEndpoint), cloudenvironment.GetCloudEnvironmentInstance().GetContainerRegistrySuffix())
+}
+
+// GetTagReferenceInRepository returns a tag based reference of the given tag in the repository of the image reference (e.g. registry/repo:tag)
+func GetTagReferenceInRepository(imageReference registry.IImageReference, tag string) *registry.Tag {
+	original := fmt.Sprintf("%s/%s:%s", imageReference.Registry(), imageReference.Repository(), tag)
+	return registry.NewTag(original, imageReference.Registry(), imageReference.Repository(), tag)
+}
+
+// GetDigestReferenceInRepository returns a digest based reference of the given digest in the repository of the image reference (e.g. registry/repo@sha256:...)
+func GetDigestReferenceInRepository(imageReference registry.IImageReference, digest string) *registry.Digest {
+	original := fmt.Sprintf("%s/%s@%s", imageReference.Registry(), imageReference.Repository(), digest)
+	return registry.NewDigest(original, imageReference.Registry(), imageReference.Repository(), digest)
 }
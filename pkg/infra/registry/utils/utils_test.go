@@ -1,7 +1,9 @@
 package utils
 
 import (
+	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/cloudenvironment"
 	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/registry"
+	"github.com/Azure/go-autorest/autorest/azure"
 	"github.com/google/go-containerregistry/pkg/name"
 	"github.com/pkg/errors"
 	"github.com/stretchr/testify/suite"
@@ -107,6 +109,32 @@ func (suite *UtilsTestSuite) TestIsRegistryEndpointACR_GCR_Not() {
 	suite.False(res)
 }
 
+func (suite *UtilsTestSuite) TestIsRegistryEndpointACR_CloudEnvironments() {
+	tests := []struct {
+		name        string
+		environment azure.Environment
+		registry    string
+		expected    bool
+	}{
+		{name: "public - public registry", environment: azure.PublicCloud, registry: "tomerw.azurecr.io", expected: true},
+		{name: "public - usgovernment registry", environment: azure.PublicCloud, registry: "tomerw.azurecr.us", expected: false},
+		{name: "public - china registry", environment: azure.PublicCloud, registry: "tomerw.azurecr.cn", expected: false},
+		{name: "usgovernment - usgovernment registry", environment: azure.USGovernmentCloud, registry: "tomerw.azurecr.us", expected: true},
+		{name: "usgovernment - caps registry", environment: azure.USGovernmentCloud, registry: "tomerw.AzureCR.US", expected: true},
+		{name: "usgovernment - public registry", environment: azure.USGovernmentCloud, registry: "tomerw.azurecr.io", expected: false},
+		{name: "china - china registry", environment: azure.ChinaCloud, registry: "tomerw.azurecr.cn", expected: true},
+		{name: "china - public registry", environment: azure.ChinaCloud, registry: "tomerw.azurecr.io", expected: false},
+		{name: "china - gcr registry", environment: azure.ChinaCloud, registry: "gcr.io", expected: false},
+	}
+	for _, tt := range tests {
+		suite.Run(tt.name, func() {
+			cloudenvironment.UpdateCloudEnvironmentForTests(tt.environment)
+			suite.Equal(tt.expected, IsRegistryEndpointACR(tt.registry))
+		})
+	}
+	cloudenvironment.ResetCloudEnvironmentForTests()
+}
+
 func TestUtils(t *testing.T) {
 	suite.Run(t, new(UtilsTestSuite))
 }
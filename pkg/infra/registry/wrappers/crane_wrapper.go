@@ -1,6 +1,8 @@
 package wrappers
 
 import (
+	"context"
+	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/circuitbreaker"
 	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/instrumentation"
 	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/instrumentation/metric"
 	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/instrumentation/metric/util"
@@ -9,7 +11,9 @@ import (
 	craneerrors "github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/registry/wrappers/errors"
 	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/retrypolicy"
 	"github.com/google/go-containerregistry/pkg/crane"
+	"github.com/google/go-containerregistry/pkg/name"
 	"github.com/pkg/errors"
+	"io/ioutil"
 )
 
 var (
@@ -19,7 +23,16 @@ var (
 // ICraneWrapper wraps crane operations
 type ICraneWrapper interface {
 	// Digest get image digest using image ref using crane Digest call
-	Digest(ref string, opt ...crane.Option) (string, error)
+	// The registry calls are aborted once ctx is done.
+	Digest(ctx context.Context, ref string, opt ...crane.Option) (string, error)
+
+	// Manifest get image raw manifest using image ref using crane Manifest call
+	// The registry calls are aborted once ctx is done.
+	Manifest(ctx context.Context, ref string, opt ...crane.Option) ([]byte, error)
+
+	// Blob get the content of a blob using blob ref (e.g. registry/repo@sha256:...) using crane PullLayer call
+	// The registry calls are aborted once ctx is done.
+	Blob(ctx context.Context, ref string, opt ...crane.Option) ([]byte, error)
 }
 
 // CraneWrapper implements ICraneWrapper interface
@@ -33,35 +46,38 @@ type CraneWrapper struct {
 	metricSubmitter metric.IMetricSubmitter
 	// retryPolicy is the manager of the retry policy of the crane wrapper.
 	retryPolicy retrypolicy.IRetryPolicy
+	// circuitBreaker rejects the digest calls of each registry while the registry is failing.
+	circuitBreaker circuitbreaker.ICircuitBreaker
 }
 
 // NewCraneWrapper Cto'r for CraneWrapper
-func NewCraneWrapper(instrumentationProvider instrumentation.IInstrumentationProvider, retryPolicy retrypolicy.IRetryPolicy) *CraneWrapper {
+func NewCraneWrapper(instrumentationProvider instrumentation.IInstrumentationProvider, retryPolicy retrypolicy.IRetryPolicy, circuitBreaker circuitbreaker.ICircuitBreaker) *CraneWrapper {
 	return &CraneWrapper{
 		tracerProvider:  instrumentationProvider.GetTracerProvider("CraneWrapper"),
 		metricSubmitter: instrumentationProvider.GetMetricSubmitter(),
 		retryPolicy:     retryPolicy,
+		circuitBreaker:  circuitBreaker,
 	}
 }
 
 // Digest get image digest using image ref using crane Digest call
-func (craneWrapper *CraneWrapper) Digest(imageReference string, opt ...crane.Option) (string, error) {
+func (craneWrapper *CraneWrapper) Digest(ctx context.Context, imageReference string, opt ...crane.Option) (string, error) {
 	tracer := craneWrapper.tracerProvider.GetTracer("Digest")
 
-	digest, err := craneWrapper.retryPolicy.RetryActionString(
-		/*action ActionString*/
-		func() (string, error) { return craneWrapper.getDigest(imageReference, opt...) },
+	digest, err := craneWrapper.circuitBreaker.Execute(
+		getRegistry(imageReference),
+		func() (interface{}, error) {
+			return craneWrapper.retryPolicy.RetryActionString(
+				ctx,
+				/*action ActionString*/
+				func() (string, error) { return craneWrapper.getDigest(imageReference, withContext(ctx, opt)...) },
 
-		/*handle ShouldRetryOnSpecificError*/
-		func(err error) bool {
-			errCause := errors.Cause(err)
-			switch errCause.(type) {
-			case *registryerrors.ImageIsNotFoundErr, *registryerrors.UnauthorizedErr:
-				return false
-			default:
-				return true
-			}
+				/*handle ShouldRetryOnSpecificError*/
+				craneWrapper.shouldRetry,
+			)
 		},
+		/*isFailure - known registry errors (e.g. image is not found) aren't failures of the registry*/
+		craneWrapper.shouldRetry,
 	)
 
 	if err != nil {
@@ -72,7 +88,127 @@ func (craneWrapper *CraneWrapper) Digest(imageReference string, opt ...crane.Opt
 	}
 
 	tracer.Info("Managed to extract digest", "Image ref", imageReference, "digest", digest)
-	return digest, nil
+	return digest.(string), nil
+}
+
+// Manifest get image raw manifest using image ref using crane Manifest call
+func (craneWrapper *CraneWrapper) Manifest(ctx context.Context, imageReference string, opt ...crane.Option) ([]byte, error) {
+	tracer := craneWrapper.tracerProvider.GetTracer("Manifest")
+
+	manifest, err := craneWrapper.retryPolicy.RetryActionInterface(
+		ctx,
+		/*action ActionInterface*/
+		func() (interface{}, error) { return craneWrapper.getManifest(imageReference, withContext(ctx, opt)...) },
+
+		/*handle ShouldRetryOnSpecificError*/
+		craneWrapper.shouldRetry,
+	)
+
+	if err != nil {
+		err = errors.Wrapf(err, "failed to get manifest of image %v", imageReference)
+		tracer.Error(err, "")
+		craneWrapper.metricSubmitter.SendMetric(1, util.NewErrorEncounteredMetric(err, "CraneWrapper.Manifest"))
+		return nil, err
+	}
+
+	tracer.Info("Managed to get manifest", "Image ref", imageReference)
+	return manifest.([]byte), nil
+}
+
+// Blob get the content of a blob using blob ref (e.g. registry/repo@sha256:...) using crane PullLayer call
+func (craneWrapper *CraneWrapper) Blob(ctx context.Context, blobReference string, opt ...crane.Option) ([]byte, error) {
+	tracer := craneWrapper.tracerProvider.GetTracer("Blob")
+
+	blob, err := craneWrapper.retryPolicy.RetryActionInterface(
+		ctx,
+		/*action ActionInterface*/
+		func() (interface{}, error) { return craneWrapper.getBlob(blobReference, withContext(ctx, opt)...) },
+
+		/*handle ShouldRetryOnSpecificError*/
+		craneWrapper.shouldRetry,
+	)
+
+	if err != nil {
+		err = errors.Wrapf(err, "failed to get blob %v", blobReference)
+		tracer.Error(err, "")
+		craneWrapper.metricSubmitter.SendMetric(1, util.NewErrorEncounteredMetric(err, "CraneWrapper.Blob"))
+		return nil, err
+	}
+
+	tracer.Info("Managed to get blob", "Blob ref", blobReference)
+	return blob.([]byte), nil
+}
+
+// withContext returns the crane options with an option that binds the registry calls to ctx
+func withContext(ctx context.Context, opt []crane.Option) []crane.Option {
+	options := make([]crane.Option, 0, len(opt)+1)
+	options = append(options, opt...)
+	return append(options, crane.WithContext(ctx))
+}
+
+// getRegistry returns the registry of the image reference - the key of the registry's circuit.
+// In case that the reference can't be parsed, the reference itself is returned.
+func getRegistry(imageReference string) string {
+	ref, err := name.ParseReference(imageReference)
+	if err != nil {
+		return imageReference
+	}
+	return ref.Context().RegistryStr()
+}
+
+// shouldRetry returns false on known registry errors that won't be resolved by retrying
+func (craneWrapper *CraneWrapper) shouldRetry(err error) bool {
+	errCause := errors.Cause(err)
+	switch errCause.(type) {
+	case *registryerrors.ImageIsNotFoundErr, *registryerrors.UnauthorizedErr, *registryerrors.RegistryTLSErr:
+		return false
+	default:
+		return true
+	}
+}
+
+// getManifest get image raw manifest using crane Manifest call and converts crane errors to known errors
+func (craneWrapper *CraneWrapper) getManifest(ref string, opt ...crane.Option) ([]byte, error) {
+	tracer := craneWrapper.tracerProvider.GetTracer("getManifest")
+	manifest, err := crane.Manifest(ref, opt...)
+	if err != nil {
+		return nil, craneWrapper.tryParseCraneErr(tracer, ref, err, "CraneWrapper.getManifest")
+	}
+	return manifest, nil
+}
+
+// getBlob get blob content using crane PullLayer call and converts crane errors to known errors
+func (craneWrapper *CraneWrapper) getBlob(ref string, opt ...crane.Option) ([]byte, error) {
+	tracer := craneWrapper.tracerProvider.GetTracer("getBlob")
+	layer, err := crane.PullLayer(ref, opt...)
+	if err != nil {
+		return nil, craneWrapper.tryParseCraneErr(tracer, ref, err, "CraneWrapper.getBlob")
+	}
+
+	reader, err := layer.Compressed()
+	if err != nil {
+		return nil, craneWrapper.tryParseCraneErr(tracer, ref, err, "CraneWrapper.getBlob")
+	}
+	defer reader.Close()
+
+	blob, err := ioutil.ReadAll(reader)
+	if err != nil {
+		return nil, craneWrapper.tryParseCraneErr(tracer, ref, err, "CraneWrapper.getBlob")
+	}
+	return blob, nil
+}
+
+// tryParseCraneErr tries to convert crane error to known error. Returns the known error if succeeded, otherwise the original error.
+func (craneWrapper *CraneWrapper) tryParseCraneErr(tracer trace.ITracer, ref string, err error, metricContext string) error {
+	tracer.Error(err, "error encountered while trying to call crane.")
+	knownErr, ok := craneerrors.TryParseCraneErrToRegistryKnownErr(ref, err)
+	if !ok {
+		tracer.Error(err, "failed to parse crane error to known error")
+		craneWrapper.metricSubmitter.SendMetric(1, util.NewErrorEncounteredMetric(err, metricContext))
+		return err
+	}
+	tracer.Info("Success to parse crane error to known error", "knownErr", knownErr)
+	return knownErr
 }
 
 // Digest get image digest using image ref using crane Digest call
@@ -1,10 +1,16 @@
 package wrappers
 
 import (
+	"context"
+	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/circuitbreaker"
+	circuitbreakermocks "github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/circuitbreaker/mocks"
 	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/instrumentation"
 	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/instrumentation/metric/util"
+	registryerrors "github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/registry/errors"
 	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/retrypolicy"
+	"github.com/pkg/errors"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/suite"
 	"regexp"
 	"strconv"
@@ -31,8 +37,8 @@ type TestSuite struct {
 
 // This will run before each test in the suit
 func (suite *TestSuite) SetupTest() {
-	retryPolicy := retrypolicy.NewRetryPolicy(instrumentation.NewNoOpInstrumentationProvider(), retryPolicyConfiguration)
-	suite.craneWrapper = NewCraneWrapper(instrumentation.NewNoOpInstrumentationProvider(), retryPolicy)
+	retryPolicy := retrypolicy.NewRetryPolicy(instrumentation.NewNoOpInstrumentationProvider(), retryPolicyConfiguration, "CraneWrapper")
+	suite.craneWrapper = NewCraneWrapper(instrumentation.NewNoOpInstrumentationProvider(), retryPolicy, circuitbreaker.NewNoOpCircuitBreaker())
 }
 
 // Test the amount of actual retries is equal _retryAttempts (by a linear factor)
@@ -45,7 +51,7 @@ func (suite *TestSuite) TestCraneWrapper_NumberOfAttempts() {
 	}
 	// TODO remove skip and update test according to new Digest method
 	suite.T().Skip()
-	//_, err = suite.craneWrapper.Digest("")
+	//_, err = suite.craneWrapper.Digest(context.Background(), "")
 	// number of attempts is tested only if Digest has failed
 	suite.NotNil(err, "Digest hasn't failed")
 	// Extract number of actual attempts
@@ -63,7 +69,7 @@ func (suite *TestSuite) TestCraneWrapper_RetriesBackOff() {
 	startTime := time.Now()
 	for i := 0; i < _retryAttempts; i++ {
 		// TODO change empty string to a failing image tag
-		suite.craneWrapper.Digest("")
+		suite.craneWrapper.Digest(context.Background(), "")
 		time.Sleep(_retryDuration)
 	}
 	// Calculate running time for static delay
@@ -72,13 +78,38 @@ func (suite *TestSuite) TestCraneWrapper_RetriesBackOff() {
 	// TODO change empty string to a failing image tag
 	// TODO remove skip and update test according to new Digest method
 	suite.T().Skip()
-	// suite.craneWrapper.Digest("")
+	// suite.craneWrapper.Digest(context.Background(), "")
 	// Calculate running time for increasing delay
 	increasingDurationTime := util.GetDurationMilliseconds(startTime)
 	// TODO from > to <
 	assert.True(suite.T(), constDurationTime > increasingDurationTime, "retries back off delay is not increasing")
 }
 
+func (suite *TestSuite) TestCraneWrapper_Digest_CircuitIsOpen_CircuitIsOpenErrReturned() {
+	circuitBreakerMock := new(circuitbreakermocks.ICircuitBreaker)
+	circuitBreakerMock.On("Execute", "tomer.azurecr.io", mock.Anything, mock.Anything).Return("", circuitbreaker.NewCircuitIsOpenErr("CraneWrapper", "tomer.azurecr.io")).Once()
+	retryPolicy := retrypolicy.NewRetryPolicy(instrumentation.NewNoOpInstrumentationProvider(), retryPolicyConfiguration, "CraneWrapper")
+	craneWrapper := NewCraneWrapper(instrumentation.NewNoOpInstrumentationProvider(), retryPolicy, circuitBreakerMock)
+
+	digest, err := craneWrapper.Digest(context.Background(), "tomer.azurecr.io/redis:v1")
+
+	suite.Equal("", digest)
+	suite.IsType(&circuitbreaker.CircuitIsOpenErr{}, errors.Cause(err))
+	circuitBreakerMock.AssertExpectations(suite.T())
+}
+
+func (suite *TestSuite) TestCraneWrapper_getRegistry() {
+	suite.Equal("tomer.azurecr.io", getRegistry("tomer.azurecr.io/redis:v1"))
+	suite.Equal("tomer.azurecr.io", getRegistry("tomer.azurecr.io/redis@sha256:4a1c4b21597c1b4415bdbecb28a3296c6b5e23ca4f9feeb599860a1dac6a0108"))
+	suite.Equal("index.docker.io", getRegistry("redis"))
+	suite.Equal("not a reference", getRegistry("not a reference"))
+}
+
+func (suite *TestSuite) TestCraneWrapper_shouldRetry_KnownRegistryErrors_NotFailures() {
+	suite.False(suite.craneWrapper.shouldRetry(errors.Wrap(registryerrors.NewImageIsNotFoundErr("tomer.azurecr.io/redis:v1", errors.New("not found")), "wrap")))
+	suite.True(suite.craneWrapper.shouldRetry(errors.New("connection refused")))
+}
+
 // We need this function to kick off the test suite, otherwise
 // "go test" won't know about our tests
 func TestConfigTestSuite(t *testing.T) {
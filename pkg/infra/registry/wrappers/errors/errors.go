@@ -1,8 +1,14 @@
 package errors
 
 import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	stderrors "errors"
 	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/registry/errors"
 	"github.com/google/go-containerregistry/pkg/v1/remote/transport"
+	"net"
+	"strings"
 )
 
 var (
@@ -51,6 +57,65 @@ func isErrorInUnauthorizedErrs(err *transport.Error) bool {
 	return false
 }
 
+var (
+	// TLSErrMessages are substrings of TLS failures messages.
+	// crane flattens the errors of the registry ping to a string error, so the TLS failures can't be always detected by their type.
+	TLSErrMessages = []string{
+		"x509: ", // Certificate verification failures (e.g. certificate signed by unknown authority).
+		"tls: ",  // TLS handshake failures.
+		"server gave HTTP response to HTTPS client", // Registry that serves plain HTTP.
+	}
+)
+
+// isTLSError returns true if the error (or one of the errors that it wraps) is a TLS failure
+// (e.g. certificate signed by unknown authority, invalid certificate or HTTP response to HTTPS client).
+func isTLSError(err error) bool {
+	for _, tlsErrMessage := range TLSErrMessages {
+		if strings.Contains(err.Error(), tlsErrMessage) {
+			return true
+		}
+	}
+
+	var unknownAuthorityError x509.UnknownAuthorityError
+	var certificateInvalidError x509.CertificateInvalidError
+	var hostnameError x509.HostnameError
+	var systemRootsError x509.SystemRootsError
+	var recordHeaderError tls.RecordHeaderError
+	return stderrors.As(err, &unknownAuthorityError) ||
+		stderrors.As(err, &certificateInvalidError) ||
+		stderrors.As(err, &hostnameError) ||
+		stderrors.As(err, &systemRootsError) ||
+		stderrors.As(err, &recordHeaderError)
+}
+
+var (
+	// TimeoutErrMessages are substrings of timeout failures messages.
+	// Like TLS failures, timeouts of the registry ping are flattened to a string error.
+	TimeoutErrMessages = []string{
+		"context deadline exceeded",         // Deadline of the context.
+		"Client.Timeout exceeded",           // Timeout of the http client.
+		"timeout awaiting response headers", // Response header timeout of the transport.
+		"TLS handshake timeout",             // TLS handshake timeout of the transport.
+		"i/o timeout",                       // Dial timeout.
+	}
+)
+
+// IsTimeoutErr returns true if the error (or one of the errors that it wraps) is a timeout of the registry
+// (e.g. context deadline exceeded, response header timeout or dial timeout).
+func IsTimeoutErr(err error) bool {
+	if err == nil {
+		return false
+	}
+	for _, timeoutErrMessage := range TimeoutErrMessages {
+		if strings.Contains(err.Error(), timeoutErrMessage) {
+			return true
+		}
+	}
+
+	var netError net.Error
+	return stderrors.Is(err, context.DeadlineExceeded) || (stderrors.As(err, &netError) && netError.Timeout())
+}
+
 // TryParseCraneErrToRegistryKnownErr Gets an err and ref (the reference of the image that crane tried to get the digest) and try to convert it to known err
 func TryParseCraneErrToRegistryKnownErr(ref string, err error) (error, bool) {
 	if err == nil {
@@ -65,6 +130,9 @@ func TryParseCraneErrToRegistryKnownErr(ref string, err error) (error, bool) {
 			return errors.NewImageIsNotFoundErr(ref, err), true
 		}
 	}
+	if isTLSError(err) {
+		return errors.NewRegistryTLSErr(ref, err), true
+	}
 	// Unknown error
 	return err, false
 }
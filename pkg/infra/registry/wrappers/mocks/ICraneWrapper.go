@@ -3,6 +3,8 @@
 package mocks
 
 import (
+	context "context"
+
 	crane "github.com/google/go-containerregistry/pkg/crane"
 	mock "github.com/stretchr/testify/mock"
 )
@@ -12,27 +14,87 @@ type ICraneWrapper struct {
 	mock.Mock
 }
 
-// Digest provides a mock function with given fields: ref, opt
-func (_m *ICraneWrapper) Digest(ref string, opt ...crane.Option) (string, error) {
+// Digest provides a mock function with given fields: ctx, ref, opt
+func (_m *ICraneWrapper) Digest(ctx context.Context, ref string, opt ...crane.Option) (string, error) {
 	_va := make([]interface{}, len(opt))
 	for _i := range opt {
 		_va[_i] = opt[_i]
 	}
 	var _ca []interface{}
-	_ca = append(_ca, ref)
+	_ca = append(_ca, ctx, ref)
 	_ca = append(_ca, _va...)
 	ret := _m.Called(_ca...)
 
 	var r0 string
-	if rf, ok := ret.Get(0).(func(string, ...crane.Option) string); ok {
-		r0 = rf(ref, opt...)
+	if rf, ok := ret.Get(0).(func(context.Context, string, ...crane.Option) string); ok {
+		r0 = rf(ctx, ref, opt...)
 	} else {
 		r0 = ret.Get(0).(string)
 	}
 
 	var r1 error
-	if rf, ok := ret.Get(1).(func(string, ...crane.Option) error); ok {
-		r1 = rf(ref, opt...)
+	if rf, ok := ret.Get(1).(func(context.Context, string, ...crane.Option) error); ok {
+		r1 = rf(ctx, ref, opt...)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Manifest provides a mock function with given fields: ctx, ref, opt
+func (_m *ICraneWrapper) Manifest(ctx context.Context, ref string, opt ...crane.Option) ([]byte, error) {
+	_va := make([]interface{}, len(opt))
+	for _i := range opt {
+		_va[_i] = opt[_i]
+	}
+	var _ca []interface{}
+	_ca = append(_ca, ctx, ref)
+	_ca = append(_ca, _va...)
+	ret := _m.Called(_ca...)
+
+	var r0 []byte
+	if rf, ok := ret.Get(0).(func(context.Context, string, ...crane.Option) []byte); ok {
+		r0 = rf(ctx, ref, opt...)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]byte)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string, ...crane.Option) error); ok {
+		r1 = rf(ctx, ref, opt...)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Blob provides a mock function with given fields: ctx, ref, opt
+func (_m *ICraneWrapper) Blob(ctx context.Context, ref string, opt ...crane.Option) ([]byte, error) {
+	_va := make([]interface{}, len(opt))
+	for _i := range opt {
+		_va[_i] = opt[_i]
+	}
+	var _ca []interface{}
+	_ca = append(_ca, ctx, ref)
+	_ca = append(_ca, _va...)
+	ret := _m.Called(_ca...)
+
+	var r0 []byte
+	if rf, ok := ret.Get(0).(func(context.Context, string, ...crane.Option) []byte); ok {
+		r0 = rf(ctx, ref, opt...)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]byte)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string, ...crane.Option) error); ok {
+		r1 = rf(ctx, ref, opt...)
 	} else {
 		r1 = ret.Error(1)
 	}
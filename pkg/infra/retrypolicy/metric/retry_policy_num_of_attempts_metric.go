@@ -0,0 +1,51 @@
+package metric
+
+import (
+	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/instrumentation/metric"
+)
+
+// RetryResult is the result of an action that was executed with retry policy
+type RetryResult string
+
+const (
+	// SucceededRetryResult - one of the attempts succeeded
+	SucceededRetryResult RetryResult = "Succeeded"
+	// NonRetryableErrorRetryResult - an attempt failed on error that shouldn't be retried
+	NonRetryableErrorRetryResult RetryResult = "NonRetryableError"
+	// RetryAttemptsExhaustedRetryResult - all the attempts failed
+	RetryAttemptsExhaustedRetryResult RetryResult = "RetryAttemptsExhausted"
+	// MaxElapsedTimeExceededRetryResult - the next attempt couldn't start before the max elapsed time is passed
+	MaxElapsedTimeExceededRetryResult RetryResult = "MaxElapsedTimeExceeded"
+	// ContextDoneRetryResult - the context was done before an attempt succeeded (e.g. the admission request's deadline passed)
+	ContextDoneRetryResult RetryResult = "ContextDone"
+)
+
+// RetryPolicyNumOfAttemptsMetric implements metric.IMetric interface
+var _ metric.IMetric = (*RetryPolicyNumOfAttemptsMetric)(nil)
+
+// RetryPolicyNumOfAttemptsMetric is metric of the number of attempts of each action that was executed with retry policy
+type RetryPolicyNumOfAttemptsMetric struct {
+	// callSite is the name of the component that executed the action - e.g. CraneWrapper, ARGClient
+	callSite string
+	// result is the result of the action
+	result RetryResult
+}
+
+// NewRetryPolicyNumOfAttemptsMetric Ctor for RetryPolicyNumOfAttemptsMetric
+func NewRetryPolicyNumOfAttemptsMetric(callSite string, result RetryResult) *RetryPolicyNumOfAttemptsMetric {
+	return &RetryPolicyNumOfAttemptsMetric{
+		callSite: callSite,
+		result:   result,
+	}
+}
+
+func (m *RetryPolicyNumOfAttemptsMetric) MetricName() string {
+	return "RetryPolicyNumOfAttempts"
+}
+
+func (m *RetryPolicyNumOfAttemptsMetric) MetricDimension() []metric.Dimension {
+	return []metric.Dimension{
+		{Key: "CallSite", Value: m.callSite},
+		{Key: "Result", Value: string(m.result)},
+	}
+}
@@ -3,6 +3,8 @@
 package mocks
 
 import (
+	context "context"
+
 	retrypolicy "github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/retrypolicy"
 	mock "github.com/stretchr/testify/mock"
 )
@@ -12,13 +14,13 @@ type IRetryPolicy struct {
 	mock.Mock
 }
 
-// RetryAction provides a mock function with given fields: action, handle
-func (_m *IRetryPolicy) RetryAction(action retrypolicy.Action, handle retrypolicy.ShouldRetryOnSpecificError) error {
-	ret := _m.Called(action, handle)
+// RetryAction provides a mock function with given fields: ctx, action, handle
+func (_m *IRetryPolicy) RetryAction(ctx context.Context, action retrypolicy.Action, handle retrypolicy.ShouldRetryOnSpecificError) error {
+	ret := _m.Called(ctx, action, handle)
 
 	var r0 error
-	if rf, ok := ret.Get(0).(func(retrypolicy.Action, retrypolicy.ShouldRetryOnSpecificError) error); ok {
-		r0 = rf(action, handle)
+	if rf, ok := ret.Get(0).(func(context.Context, retrypolicy.Action, retrypolicy.ShouldRetryOnSpecificError) error); ok {
+		r0 = rf(ctx, action, handle)
 	} else {
 		r0 = ret.Error(0)
 	}
@@ -26,20 +28,43 @@ func (_m *IRetryPolicy) RetryAction(action retrypolicy.Action, handle retrypolic
 	return r0
 }
 
-// RetryActionString provides a mock function with given fields: action, handle
-func (_m *IRetryPolicy) RetryActionString(action retrypolicy.ActionString, handle retrypolicy.ShouldRetryOnSpecificError) (string, error) {
-	ret := _m.Called(action, handle)
+// RetryActionInterface provides a mock function with given fields: ctx, action, handle
+func (_m *IRetryPolicy) RetryActionInterface(ctx context.Context, action retrypolicy.ActionInterface, handle retrypolicy.ShouldRetryOnSpecificError) (interface{}, error) {
+	ret := _m.Called(ctx, action, handle)
+
+	var r0 interface{}
+	if rf, ok := ret.Get(0).(func(context.Context, retrypolicy.ActionInterface, retrypolicy.ShouldRetryOnSpecificError) interface{}); ok {
+		r0 = rf(ctx, action, handle)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(interface{})
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, retrypolicy.ActionInterface, retrypolicy.ShouldRetryOnSpecificError) error); ok {
+		r1 = rf(ctx, action, handle)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// RetryActionString provides a mock function with given fields: ctx, action, handle
+func (_m *IRetryPolicy) RetryActionString(ctx context.Context, action retrypolicy.ActionString, handle retrypolicy.ShouldRetryOnSpecificError) (string, error) {
+	ret := _m.Called(ctx, action, handle)
 
 	var r0 string
-	if rf, ok := ret.Get(0).(func(retrypolicy.ActionString, retrypolicy.ShouldRetryOnSpecificError) string); ok {
-		r0 = rf(action, handle)
+	if rf, ok := ret.Get(0).(func(context.Context, retrypolicy.ActionString, retrypolicy.ShouldRetryOnSpecificError) string); ok {
+		r0 = rf(ctx, action, handle)
 	} else {
 		r0 = ret.Get(0).(string)
 	}
 
 	var r1 error
-	if rf, ok := ret.Get(1).(func(retrypolicy.ActionString, retrypolicy.ShouldRetryOnSpecificError) error); ok {
-		r1 = rf(action, handle)
+	if rf, ok := ret.Get(1).(func(context.Context, retrypolicy.ActionString, retrypolicy.ShouldRetryOnSpecificError) error); ok {
+		r1 = rf(ctx, action, handle)
 	} else {
 		r1 = ret.Error(1)
 	}
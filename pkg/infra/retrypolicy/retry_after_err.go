@@ -0,0 +1,70 @@
+package retrypolicy
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	// _retryAfterHeaderName is the name of the HTTP header that hints how long to wait before the next request
+	_retryAfterHeaderName = "Retry-After"
+)
+
+// RetryAfterErr is error of an attempt that holds a hint (e.g. HTTP Retry-After header) of how long to wait before the next attempt.
+// Actions return it in order to make the retry policy wait at least RetryAfter before the next attempt.
+type RetryAfterErr struct {
+	// err is the error of the attempt
+	err error
+	// RetryAfter is the minimum wait duration before the next attempt
+	RetryAfter time.Duration
+}
+
+// NewRetryAfterErr Ctor for RetryAfterErr
+func NewRetryAfterErr(err error, retryAfter time.Duration) *RetryAfterErr {
+	return &RetryAfterErr{
+		err:        err,
+		RetryAfter: retryAfter,
+	}
+}
+
+func (err *RetryAfterErr) Error() string {
+	return fmt.Sprintf("%v (retry after %v)", err.err, err.RetryAfter)
+}
+
+// Unwrap returns the error of the attempt
+func (err *RetryAfterErr) Unwrap() error {
+	return err.err
+}
+
+// Cause returns the error of the attempt, so errors.Cause returns the original error of the attempt
+func (err *RetryAfterErr) Cause() error {
+	return err.err
+}
+
+// GetRetryAfter returns the wait duration that is hinted by the response's Retry-After header (delay in seconds or HTTP date).
+// Returns false in case that the response has no valid Retry-After header.
+func GetRetryAfter(resp *http.Response) (time.Duration, bool) {
+	if resp == nil {
+		return 0, false
+	}
+	value := resp.Header.Get(_retryAfterHeaderName)
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+		return time.Duration(seconds) * time.Second, true
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		retryAfter := time.Until(date)
+		if retryAfter < 0 {
+			retryAfter = 0
+		}
+		return retryAfter, true
+	}
+	return 0, false
+}
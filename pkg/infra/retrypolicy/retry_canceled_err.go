@@ -0,0 +1,46 @@
+package retrypolicy
+
+import (
+	"fmt"
+	"github.com/pkg/errors"
+)
+
+// RetryCanceledErr is the error of a retry that was canceled because its context is done while waiting for the next attempt.
+// It holds both errors - errors.Is matches the context's error (e.g. context.DeadlineExceeded), and errors.As reaches the
+// error of the last attempt (e.g. throttling of the dependency), so the callers can still tell why the attempts failed.
+type RetryCanceledErr struct {
+	// ctxErr is the error of the context
+	ctxErr error
+	// lastErr is the error of the last attempt
+	lastErr error
+	// attempts is the number of attempts before the retry was canceled
+	attempts int
+}
+
+// NewRetryCanceledErr Ctor for RetryCanceledErr
+func NewRetryCanceledErr(ctxErr error, lastErr error, attempts int) *RetryCanceledErr {
+	return &RetryCanceledErr{
+		ctxErr:   ctxErr,
+		lastErr:  lastErr,
+		attempts: attempts,
+	}
+}
+
+func (err *RetryCanceledErr) Error() string {
+	return fmt.Sprintf("retry canceled after %d tries: %v (last error: %v)", err.attempts, err.ctxErr, err.lastErr)
+}
+
+// Is returns true in case that the target is the context's error
+func (err *RetryCanceledErr) Is(target error) bool {
+	return errors.Is(err.ctxErr, target)
+}
+
+// Unwrap returns the error of the last attempt
+func (err *RetryCanceledErr) Unwrap() error {
+	return err.lastErr
+}
+
+// Cause returns the context's error, so errors.Cause returns the reason that the retry was stopped
+func (err *RetryCanceledErr) Cause() error {
+	return err.ctxErr
+}
@@ -1,14 +1,32 @@
 package retrypolicy
 
 import (
+	"context"
 	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/instrumentation"
 	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/instrumentation/metric"
+	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/instrumentation/metric/util"
 	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/instrumentation/trace"
+	retrypolicymetric "github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/retrypolicy/metric"
 	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/utils"
 	"github.com/pkg/errors"
+	"math/rand"
+	"sync"
 	"time"
 )
 
+// BackoffStrategy is the strategy of calculating the wait duration between retries
+type BackoffStrategy string
+
+const (
+	// ConstantBackoffStrategy waits RetryDuration between each retry
+	ConstantBackoffStrategy BackoffStrategy = "constant"
+	// LinearBackoffStrategy waits (attempt number * RetryDuration) before each retry - the default strategy
+	LinearBackoffStrategy BackoffStrategy = "linear"
+	// ExponentialBackoffStrategy waits random duration between 0 and (RetryDuration * 2^(attempt number - 2)) before each
+	// retry (exponential backoff with full jitter), so retries of concurrent callers are spread.
+	ExponentialBackoffStrategy BackoffStrategy = "exponential"
+)
+
 // ShouldRetryOnSpecificError is function that gets an error and returns true or false if the retry should handle with this error
 // Returns true in case that retry policy doesn't know how to handle with some error, so it will retry another time (according to the retry attempts and retry count)
 // Returns false in case that the retry policy shouldn't retry another try on error specific error.
@@ -20,14 +38,21 @@ type (
 	ActionString func() (string, error)
 	// Action is action function that returns error
 	Action func() error
+	// ActionInterface is action function that returns value of any type and error - the caller asserts the value to its type
+	ActionInterface func() (interface{}, error)
 )
 
 // IRetryPolicy interface for retrypolicy
 type IRetryPolicy interface {
-	// RetryActionString try to execute action that returns string,error
-	RetryActionString(action ActionString, handle ShouldRetryOnSpecificError) (value string, err error)
-	// RetryAction try to execute action that returns only error
-	RetryAction(action Action, handle ShouldRetryOnSpecificError) (err error)
+	// RetryActionString try to execute action that returns string,error.
+	// Stops retrying (including while waiting between retries) once ctx is done.
+	RetryActionString(ctx context.Context, action ActionString, handle ShouldRetryOnSpecificError) (value string, err error)
+	// RetryAction try to execute action that returns only error.
+	// Stops retrying (including while waiting between retries) once ctx is done.
+	RetryAction(ctx context.Context, action Action, handle ShouldRetryOnSpecificError) (err error)
+	// RetryActionInterface try to execute action that returns interface{},error.
+	// Stops retrying (including while waiting between retries) once ctx is done.
+	RetryActionInterface(ctx context.Context, action ActionInterface, handle ShouldRetryOnSpecificError) (value interface{}, err error)
 }
 
 // RetryPolicy implements IRetryPolicy interface
@@ -39,10 +64,27 @@ type RetryPolicy struct {
 	tracerProvider trace.ITracerProvider
 	//metricSubmitter
 	metricSubmitter metric.IMetricSubmitter
+	// callSite is the name of the component that uses the retry policy - the dimension of the retry policy's metrics
+	callSite string
 	// duration of the retry policy.
 	duration time.Duration
 	// RetryAttempts  is the number of attempts that the request should be executed.
 	retryAttempts int
+	// backoffStrategy is the strategy of calculating the wait duration between retries
+	backoffStrategy BackoffStrategy
+	// maxRetryDuration is the maximum wait duration between two retries - zero means no maximum
+	maxRetryDuration time.Duration
+	// maxElapsedTime is the maximum time of all the attempts and the waits between them - zero means no maximum
+	maxElapsedTime time.Duration
+	// jitter returns random duration between 0 and the given duration (inclusive)
+	jitter func(time.Duration) time.Duration
+	// configuration is the configuration that the fields above were set from
+	configuration *RetryPolicyConfiguration
+	// getConfiguration returns the current configuration (e.g. the reloaded configuration) - nil in case that the retry
+	// policy keeps the configuration that it was created with.
+	getConfiguration func() *RetryPolicyConfiguration
+	// configurationLock protects the fields that are set from the configuration - they're replaced when the configuration is reloaded
+	configurationLock sync.RWMutex
 }
 
 // RetryPolicyConfiguration is the retry policy configuration that holds the relevant fields for executing retry policy.
@@ -51,21 +93,56 @@ type RetryPolicyConfiguration struct {
 	RetryAttempts int
 	// RetryDuration is the time duration between each retry - in milliseconds
 	RetryDurationInMS int
+	// BackoffStrategy is the strategy of calculating the wait duration between retries - constant, linear or exponential.
+	// If empty - linear is used.
+	BackoffStrategy string
+	// MaxRetryDurationInMS is the maximum wait duration between two retries **IN MILLISECONDS**. If zero - there is no maximum.
+	MaxRetryDurationInMS int
+	// MaxElapsedTimeInMS is the maximum time **IN MILLISECONDS** of all the attempts and the waits between them - no retry
+	// is started if it won't start before it's passed. If zero - there is no maximum (besides the context's deadline).
+	MaxElapsedTimeInMS int
 }
 
 // NewRetryPolicy Cto'r for retry policy object
-func NewRetryPolicy(instrumentationProvider instrumentation.IInstrumentationProvider, configuration *RetryPolicyConfiguration) *RetryPolicy {
-	return &RetryPolicy{
-		duration:        configuration.GetBackOffDuration(),
-		retryAttempts:   configuration.RetryAttempts,
+func NewRetryPolicy(instrumentationProvider instrumentation.IInstrumentationProvider, configuration *RetryPolicyConfiguration, callSite string) *RetryPolicy {
+	retryPolicy := &RetryPolicy{
+		callSite:        callSite,
+		jitter:          fullJitter,
 		tracerProvider:  instrumentationProvider.GetTracerProvider("RetryPolicy"),
 		metricSubmitter: instrumentationProvider.GetMetricSubmitter(),
 	}
+	retryPolicy.UpdateConfiguration(configuration)
+	return retryPolicy
+}
+
+// UpdateConfiguration sets the attempts, durations and backoff strategy of the retry policy from the configuration.
+// Retries that already started keep the attempts and max elapsed time that they started with.
+func (r *RetryPolicy) UpdateConfiguration(configuration *RetryPolicyConfiguration) {
+	backoffStrategy, err := configuration.GetBackoffStrategy()
+	if err != nil {
+		err = errors.Wrapf(err, "RetryPolicy of <%s> uses %s backoff strategy instead", r.callSite, backoffStrategy)
+		r.tracerProvider.GetTracer("UpdateConfiguration").Error(err, "")
+		r.metricSubmitter.SendMetric(1, util.NewErrorEncounteredMetric(err, "RetryPolicy.UpdateConfiguration"))
+	}
+
+	r.configurationLock.Lock()
+	defer r.configurationLock.Unlock()
+	r.configuration = configuration
+	r.duration = configuration.GetBackOffDuration()
+	r.retryAttempts = configuration.RetryAttempts
+	r.backoffStrategy = backoffStrategy
+	r.maxRetryDuration = utils.GetMilliseconds(configuration.MaxRetryDurationInMS)
+	r.maxElapsedTime = utils.GetMilliseconds(configuration.MaxElapsedTimeInMS)
+}
 
+// SetConfigurationProvider makes the retry policy read its configuration from the provider before each retry (e.g. from
+// the reloaded configuration), so the retries that start after a reload use the new configuration. Should be called before the retry policy is used.
+func (r *RetryPolicy) SetConfigurationProvider(getConfiguration func() *RetryPolicyConfiguration) {
+	r.getConfiguration = getConfiguration
 }
 
 // RetryActionString retry to run the action with retryPolicy
-func (r *RetryPolicy) RetryActionString(action ActionString, shouldRetry ShouldRetryOnSpecificError) (value string, err error) {
+func (r *RetryPolicy) RetryActionString(ctx context.Context, action ActionString, shouldRetry ShouldRetryOnSpecificError) (value string, err error) {
 	tracer := r.tracerProvider.GetTracer("RetryActionString")
 	if action == nil || shouldRetry == nil {
 		err = errors.Wrap(utils.NilArgumentError, "action and handle can't be nil")
@@ -73,65 +150,166 @@ func (r *RetryPolicy) RetryActionString(action ActionString, shouldRetry ShouldR
 		return "", err
 	}
 
-	// Update retryCount to 1
-	retryCount := 1
-	for retryCount <= r.retryAttempts {
-		// Act
-		value, err = action()
-
-		if err != nil && !shouldRetry(err) { // Check if shouldRetry knows how to handle with error.
-			tracer.Info("failed but encountered with handled err", "err", err)
-			return "", err
-
-		} else if err == nil { // Check if err is nil - returns the result.
-			tracer.Info("succeed", "retryCount", retryCount, "value", value)
-			return value, nil
-		}
-
-		// in case that err != nil and handle(err) is false, should try another execution.
-		retryCount += 1
-		tracer.Info("waiting for another retry", "sleepTime", time.Duration(retryCount)*r.duration)
-		time.Sleep(time.Duration(retryCount) * r.duration)
-
+	result, err := r.retry(ctx, tracer, func() (interface{}, error) { return action() }, shouldRetry)
+	if err != nil {
+		return "", err
 	}
-	err = errors.Wrapf(err, "failed after %d tries", retryCount)
-	tracer.Error(err, "")
-	return "", err
+	return result.(string), nil
 }
 
 // RetryAction retry to run the action with retryPolicy
-func (r *RetryPolicy) RetryAction(action Action, shouldRetry ShouldRetryOnSpecificError) (err error) {
-	tracer := r.tracerProvider.GetTracer("RetryActionString")
+func (r *RetryPolicy) RetryAction(ctx context.Context, action Action, shouldRetry ShouldRetryOnSpecificError) (err error) {
+	tracer := r.tracerProvider.GetTracer("RetryAction")
 	if action == nil || shouldRetry == nil {
 		err = errors.Wrap(utils.NilArgumentError, "action and handle can't be nil")
 		tracer.Error(err, "")
 		return err
 	}
 
-	// Update retryCount to 1
-	retryCount := 1
-	for retryCount <= r.retryAttempts {
+	_, err = r.retry(ctx, tracer, func() (interface{}, error) { return nil, action() }, shouldRetry)
+	return err
+}
+
+// RetryActionInterface retry to run the action with retryPolicy
+func (r *RetryPolicy) RetryActionInterface(ctx context.Context, action ActionInterface, shouldRetry ShouldRetryOnSpecificError) (value interface{}, err error) {
+	tracer := r.tracerProvider.GetTracer("RetryActionInterface")
+	if action == nil || shouldRetry == nil {
+		err = errors.Wrap(utils.NilArgumentError, "action and handle can't be nil")
+		tracer.Error(err, "")
+		return nil, err
+	}
+
+	return r.retry(ctx, tracer, action, shouldRetry)
+}
+
+// retry runs the action until it succeeds, it fails on error that shouldn't be retried, the retry attempts are exhausted,
+// the max elapsed time is passed or ctx is done. Submits the number of attempts and the result of the retry as metric.
+func (r *RetryPolicy) retry(ctx context.Context, tracer trace.ITracer, action ActionInterface, shouldRetry ShouldRetryOnSpecificError) (value interface{}, err error) {
+	// Don't start the action in case that ctx is already done (e.g. the admission request's deadline passed)
+	if err = ctx.Err(); err != nil {
+		err = errors.Wrap(err, "context is done before the first try")
+		tracer.Error(err, "")
+		r.sendRetryMetric(0, retrypolicymetric.ContextDoneRetryResult)
+		return nil, err
+	}
+
+	r.applyProvidedConfiguration()
+	retryAttempts, maxElapsedTime := r.getLimits()
+	start := time.Now()
+	attempt := 1
+	for {
 		// Act
-		err = action()
+		value, err = action()
+
+		if err == nil { // Check if err is nil - returns the result.
+			tracer.Info("succeed", "retryCount", attempt, "value", value)
+			r.sendRetryMetric(attempt, retrypolicymetric.SucceededRetryResult)
+			return value, nil
 
-		if err != nil && !shouldRetry(err) { // Check if handle knows how to handle with error.
+		} else if !shouldRetry(err) { // Check if shouldRetry knows how to handle with error.
 			tracer.Info("failed but encountered with handled err", "err", err)
-			return err
+			r.sendRetryMetric(attempt, retrypolicymetric.NonRetryableErrorRetryResult)
+			return nil, err
 
-		} else if err == nil { // Check if err is nil - returns the result.
-			tracer.Info("succeed", "retryCount", retryCount)
-			return nil
+		} else if attempt >= retryAttempts { // in case that err != nil and shouldRetry(err) is true, should try another execution if there are attempts left.
+			err = errors.Wrapf(err, "failed after %d tries", attempt)
+			tracer.Error(err, "")
+			r.sendRetryMetric(attempt, retrypolicymetric.RetryAttemptsExhaustedRetryResult)
+			return nil, err
 		}
 
-		// in case that err != nil and handle(err) is false, should try another execution.
-		retryCount += 1
-		tracer.Info("waiting for another retry", "sleepTime", time.Duration(retryCount)*r.duration)
-		time.Sleep(time.Duration(retryCount) * r.duration)
+		sleepTime := r.getSleepTime(attempt+1, err)
+		if maxElapsedTime > 0 && time.Since(start)+sleepTime > maxElapsedTime {
+			err = errors.Wrapf(err, "failed after %d tries - max elapsed time %v is passed before the next try", attempt, maxElapsedTime)
+			tracer.Error(err, "")
+			r.sendRetryMetric(attempt, retrypolicymetric.MaxElapsedTimeExceededRetryResult)
+			return nil, err
+		}
 
+		tracer.Info("waiting for another retry", "sleepTime", sleepTime)
+		if ctxErr := r.sleep(ctx, sleepTime); ctxErr != nil {
+			err = NewRetryCanceledErr(ctxErr, err, attempt)
+			tracer.Error(err, "")
+			r.sendRetryMetric(attempt, retrypolicymetric.ContextDoneRetryResult)
+			return nil, err
+		}
+		attempt += 1
+	}
+}
+
+// getSleepTime returns the wait duration before the attempt according to the backoff strategy.
+// In case that the error of the previous attempt holds a retry after hint (RetryAfterErr) - waits at least the hinted duration.
+func (r *RetryPolicy) getSleepTime(attempt int, err error) time.Duration {
+	r.configurationLock.RLock()
+	defer r.configurationLock.RUnlock()
+	var sleepTime time.Duration
+	switch r.backoffStrategy {
+	case ConstantBackoffStrategy:
+		sleepTime = r.duration
+	case ExponentialBackoffStrategy:
+		// Avoid overflow - from this point the backoff is bounded by the max retry duration (if configured) anyway.
+		shift := attempt - 2
+		if shift > 30 {
+			shift = 30
+		}
+		sleepTime = r.jitter(r.capRetryDuration(r.duration * time.Duration(1<<uint(shift))))
+	default:
+		sleepTime = time.Duration(attempt) * r.duration
+	}
+	sleepTime = r.capRetryDuration(sleepTime)
+
+	var retryAfterErr *RetryAfterErr
+	if errors.As(err, &retryAfterErr) && retryAfterErr.RetryAfter > sleepTime {
+		return retryAfterErr.RetryAfter
+	}
+	return sleepTime
+}
+
+// applyProvidedConfiguration sets the fields from the provided configuration in case that it was replaced since they were set
+func (r *RetryPolicy) applyProvidedConfiguration() {
+	if r.getConfiguration == nil {
+		return
+	}
+	configuration := r.getConfiguration()
+	r.configurationLock.RLock()
+	isApplied := configuration == r.configuration
+	r.configurationLock.RUnlock()
+	if !isApplied {
+		r.UpdateConfiguration(configuration)
+	}
+}
+
+// getLimits returns the number of attempts and the max elapsed time of a retry
+func (r *RetryPolicy) getLimits() (int, time.Duration) {
+	r.configurationLock.RLock()
+	defer r.configurationLock.RUnlock()
+	return r.retryAttempts, r.maxElapsedTime
+}
+
+// capRetryDuration returns the duration bounded by the max retry duration (if configured).
+// Should be called while holding the configuration lock.
+func (r *RetryPolicy) capRetryDuration(duration time.Duration) time.Duration {
+	if r.maxRetryDuration > 0 && duration > r.maxRetryDuration {
+		return r.maxRetryDuration
+	}
+	return duration
+}
+
+// sendRetryMetric submits the number of attempts of the retry and its result
+func (r *RetryPolicy) sendRetryMetric(attempts int, result retrypolicymetric.RetryResult) {
+	r.metricSubmitter.SendMetric(attempts, retrypolicymetric.NewRetryPolicyNumOfAttemptsMetric(r.callSite, result))
+}
+
+// sleep waits for the duration - returns ctx's error in case that ctx is done before the duration passed.
+func (r *RetryPolicy) sleep(ctx context.Context, duration time.Duration) error {
+	timer := time.NewTimer(duration)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
 	}
-	err = errors.Wrapf(err, "failed after %d tries", retryCount)
-	tracer.Error(err, "")
-	return err
 }
 
 // GetBackOffDuration uses the RetryPolicyConfiguration instance's RetryDuration (int) and TimeUnit(string)
@@ -140,3 +318,24 @@ func (r *RetryPolicy) RetryAction(action Action, shouldRetry ShouldRetryOnSpecif
 func (configuration *RetryPolicyConfiguration) GetBackOffDuration() time.Duration {
 	return time.Duration(configuration.RetryDurationInMS) * time.Millisecond
 }
+
+// GetBackoffStrategy returns the configured BackoffStrategy - linear in case that it's empty.
+// In case of unknown strategy, returns linear and an error.
+func (configuration *RetryPolicyConfiguration) GetBackoffStrategy() (BackoffStrategy, error) {
+	switch strategy := BackoffStrategy(configuration.BackoffStrategy); strategy {
+	case "":
+		return LinearBackoffStrategy, nil
+	case ConstantBackoffStrategy, LinearBackoffStrategy, ExponentialBackoffStrategy:
+		return strategy, nil
+	default:
+		return LinearBackoffStrategy, errors.Errorf("unknown backoff strategy <%s>", configuration.BackoffStrategy)
+	}
+}
+
+// fullJitter returns random duration between 0 and the given duration (inclusive)
+func fullJitter(duration time.Duration) time.Duration {
+	if duration <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int63n(int64(duration) + 1))
+}
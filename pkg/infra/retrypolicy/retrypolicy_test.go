@@ -1,14 +1,23 @@
 package retrypolicy
 
 import (
+	"context"
 	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/instrumentation"
+	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/instrumentation/metric/mocks"
+	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/instrumentation/trace"
+	retrypolicymetric "github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/retrypolicy/metric"
 	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/utils"
 	"github.com/pkg/errors"
 	"github.com/stretchr/testify/suite"
+	"net/http"
 	"testing"
 	"time"
 )
 
+const (
+	_callSite = "CallSiteForTests"
+)
+
 var (
 	_configuration                 = RetryPolicyConfiguration{RetryAttempts: 4, RetryDurationInMS: 3}
 	_expectedConfigurationDuration = time.Duration(_configuration.RetryDurationInMS) * time.Millisecond
@@ -40,14 +49,14 @@ func (e err2ForTests) Error() string {
 // This will run before each test in the suite
 func (suite *TestSuite) SetupTest() {
 	// Mock
-	suite.retryPolicy = NewRetryPolicy(instrumentation.NewNoOpInstrumentationProvider(), &_configuration)
+	suite.retryPolicy = NewRetryPolicy(instrumentation.NewNoOpInstrumentationProvider(), &_configuration, _callSite)
 	suite.countActions = 0
 
 }
 
 func (suite *TestSuite) Test_NewRetryPolicy_NotNilConfiguration_ShouldReturnInstance() {
 
-	r := NewRetryPolicy(instrumentation.NewNoOpInstrumentationProvider(), &_configuration)
+	r := NewRetryPolicy(instrumentation.NewNoOpInstrumentationProvider(), &_configuration, _callSite)
 
 	suite.Equal(_configuration.RetryAttempts, r.retryAttempts)
 	suite.Equal(_expectedConfigurationDuration, r.duration)
@@ -64,7 +73,7 @@ func (suite *TestSuite) Test_RetryActionString_ActionNil_ShouldReturnError() {
 	var action ActionString = nil
 	var handle ShouldRetryOnSpecificError = func(error) bool { suite.countActions += 1; return false }
 	// Act
-	actual, err := suite.retryPolicy.RetryActionString(action, handle)
+	actual, err := suite.retryPolicy.RetryActionString(context.Background(), action, handle)
 	// Test
 	suite.Equal(utils.NilArgumentError, errors.Cause(err))
 	suite.Equal("", actual)
@@ -76,7 +85,7 @@ func (suite *TestSuite) Test_RetryActionString_HandleNil_ShouldReturnError() {
 	var action ActionString = func() (string, error) { suite.countActions += 1; return "lior", nil }
 	var handle ShouldRetryOnSpecificError = nil
 	// Act
-	actual, err := suite.retryPolicy.RetryActionString(action, handle)
+	actual, err := suite.retryPolicy.RetryActionString(context.Background(), action, handle)
 	// Test
 	suite.Equal(utils.NilArgumentError, errors.Cause(err))
 	suite.Equal("", actual)
@@ -93,7 +102,7 @@ func (suite *TestSuite) Test_RetryActionString_HandledError_ShouldBeExecutedOnce
 	}
 
 	// Act
-	actual, err := suite.retryPolicy.RetryActionString(action, handle)
+	actual, err := suite.retryPolicy.RetryActionString(context.Background(), action, handle)
 	// Test
 	suite.Equal(errForTest, err)
 	suite.Equal("", actual)
@@ -113,7 +122,7 @@ func (suite *TestSuite) Test_RetryActionString_UnHandledError_ShouldBeExecutedFe
 	}
 
 	// Act
-	actual, err := suite.retryPolicy.RetryActionString(action, handle)
+	actual, err := suite.retryPolicy.RetryActionString(context.Background(), action, handle)
 	// Test
 	suite.Equal(errForTest, errors.Cause(err))
 	suite.Equal("", actual)
@@ -137,7 +146,7 @@ func (suite *TestSuite) Test_RetryActionString_HandledErrorSecondTime_ShouldBeEx
 	}
 
 	// Act
-	actual, err := suite.retryPolicy.RetryActionString(action, handle)
+	actual, err := suite.retryPolicy.RetryActionString(context.Background(), action, handle)
 	// Test
 	suite.Equal(err2ForTest, errors.Cause(err))
 	suite.Equal("", actual)
@@ -154,7 +163,7 @@ func (suite *TestSuite) Test_RetryActionString_NoError_ShouldBeExecutedOnce() {
 	}
 
 	// Act
-	actual, err := suite.retryPolicy.RetryActionString(action, handle)
+	actual, err := suite.retryPolicy.RetryActionString(context.Background(), action, handle)
 	// Test
 	suite.Nil(err)
 	suite.Equal("lior", actual)
@@ -177,7 +186,7 @@ func (suite *TestSuite) Test_RetryAction_NoErrorSecondTime_ShouldBeExecutedTwice
 	}
 
 	// Act
-	actual, err := suite.retryPolicy.RetryActionString(action, handle)
+	actual, err := suite.retryPolicy.RetryActionString(context.Background(), action, handle)
 	// Test
 	suite.Nil(err)
 	suite.Equal("lior", actual)
@@ -189,7 +198,7 @@ func (suite *TestSuite) Test_RetryAction_ActionNil_ShouldReturnError() {
 	var action Action = nil
 	var handle ShouldRetryOnSpecificError = func(error) bool { suite.countActions += 1; return false }
 	// Act
-	err := suite.retryPolicy.RetryAction(action, handle)
+	err := suite.retryPolicy.RetryAction(context.Background(), action, handle)
 	// Test
 	suite.Equal(utils.NilArgumentError, errors.Cause(err))
 	suite.Equal(0, suite.countActions)
@@ -201,7 +210,7 @@ func (suite *TestSuite) Test_RetryAction_HandleNil_ShouldReturnError() {
 	var action Action = func() error { suite.countActions += 1; return nil }
 	var handle ShouldRetryOnSpecificError = nil
 	// Act
-	err := suite.retryPolicy.RetryAction(action, handle)
+	err := suite.retryPolicy.RetryAction(context.Background(), action, handle)
 	// Test
 	suite.Equal(utils.NilArgumentError, errors.Cause(err))
 	suite.Equal(0, suite.countActions)
@@ -217,7 +226,7 @@ func (suite *TestSuite) Test_RetryAction_HandledError_ShouldBeExecutedOnce() {
 	}
 
 	// Act
-	err := suite.retryPolicy.RetryAction(action, handle)
+	err := suite.retryPolicy.RetryAction(context.Background(), action, handle)
 	// Test
 	suite.Equal(errForTest, err)
 	suite.Equal(1, suite.countActions)
@@ -236,7 +245,7 @@ func (suite *TestSuite) Test_RetryAction_UnHandledError_ShouldBeExecutedFewTimes
 	}
 
 	// Act
-	err := suite.retryPolicy.RetryAction(action, handle)
+	err := suite.retryPolicy.RetryAction(context.Background(), action, handle)
 	// Test
 	suite.Equal(errForTest, errors.Cause(err))
 	suite.Equal(_configuration.RetryAttempts, suite.countActions)
@@ -259,7 +268,7 @@ func (suite *TestSuite) Test_RetryAction_HandledErrorSecondTime_ShouldBeExecuted
 	}
 
 	// Act
-	err := suite.retryPolicy.RetryAction(action, handle)
+	err := suite.retryPolicy.RetryAction(context.Background(), action, handle)
 	// Test
 	suite.Equal(err2ForTest, errors.Cause(err))
 	suite.Equal(2, suite.countActions)
@@ -275,7 +284,7 @@ func (suite *TestSuite) Test_RetryAction_NoError_ShouldBeExecutedOnce() {
 	}
 
 	// Act
-	err := suite.retryPolicy.RetryAction(action, handle)
+	err := suite.retryPolicy.RetryAction(context.Background(), action, handle)
 	// Test
 	suite.Nil(err)
 	suite.Equal(1, suite.countActions)
@@ -297,12 +306,314 @@ func (suite *TestSuite) Test_RetryActionString_NoErrorSecondTime_ShouldBeExecute
 	}
 
 	// Act
-	err := suite.retryPolicy.RetryAction(action, handle)
+	err := suite.retryPolicy.RetryAction(context.Background(), action, handle)
 	// Test
 	suite.Nil(err)
 	suite.Equal(2, suite.countActions)
 }
 
+func (suite *TestSuite) Test_RetryActionString_ContextDone_ShouldNotExecute() {
+	// Setup
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	var action ActionString = func() (string, error) { suite.countActions += 1; return "lior", nil }
+	var handle ShouldRetryOnSpecificError = func(error) bool { return true }
+
+	// Act
+	actual, err := suite.retryPolicy.RetryActionString(ctx, action, handle)
+	// Test
+	suite.Equal(context.Canceled, errors.Cause(err))
+	suite.Equal("", actual)
+	suite.Equal(0, suite.countActions)
+}
+
+func (suite *TestSuite) Test_RetryAction_ContextCanceledWhileWaiting_ShouldStopRetrying() {
+	// Setup
+	retryPolicy := NewRetryPolicy(instrumentation.NewNoOpInstrumentationProvider(), &RetryPolicyConfiguration{RetryAttempts: 4, RetryDurationInMS: 60000}, _callSite)
+	ctx, cancel := context.WithCancel(context.Background())
+	var action Action = func() error {
+		suite.countActions += 1
+		cancel()
+		return &err1ForTests{}
+	}
+	var handle ShouldRetryOnSpecificError = func(error) bool { return true }
+
+	// Act
+	start := time.Now()
+	err := retryPolicy.RetryAction(ctx, action, handle)
+	// Test
+	suite.Equal(context.Canceled, errors.Cause(err))
+	suite.Equal(1, suite.countActions)
+	suite.Less(time.Since(start), time.Minute)
+}
+
+func (suite *TestSuite) Test_RetryAction_ContextCanceledWhileWaiting_ShouldKeepLastError() {
+	// Setup
+	retryPolicy := NewRetryPolicy(instrumentation.NewNoOpInstrumentationProvider(), &RetryPolicyConfiguration{RetryAttempts: 4, RetryDurationInMS: 60000}, _callSite)
+	ctx, cancel := context.WithCancel(context.Background())
+	var action Action = func() error {
+		cancel()
+		return NewRetryAfterErr(&err1ForTests{}, time.Minute)
+	}
+	var handle ShouldRetryOnSpecificError = func(error) bool { return true }
+
+	// Act
+	err := retryPolicy.RetryAction(ctx, action, handle)
+
+	// Test
+	suite.True(errors.Is(err, context.Canceled))
+	var retryAfterErr *RetryAfterErr
+	suite.True(errors.As(err, &retryAfterErr))
+	suite.Equal(time.Minute, retryAfterErr.RetryAfter)
+	var lastErr *err1ForTests
+	suite.True(errors.As(err, &lastErr))
+}
+
+func (suite *TestSuite) Test_RetryActionString_DeadlineExceededWhileWaiting_ShouldStopRetrying() {
+	// Setup
+	retryPolicy := NewRetryPolicy(instrumentation.NewNoOpInstrumentationProvider(), &RetryPolicyConfiguration{RetryAttempts: 4, RetryDurationInMS: 60000}, _callSite)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	var action ActionString = func() (string, error) { suite.countActions += 1; return "", &err1ForTests{} }
+	var handle ShouldRetryOnSpecificError = func(error) bool { return true }
+
+	// Act
+	actual, err := retryPolicy.RetryActionString(ctx, action, handle)
+	// Test
+	suite.Equal(context.DeadlineExceeded, errors.Cause(err))
+	suite.Equal("", actual)
+	suite.Equal(1, suite.countActions)
+}
+
+func (suite *TestSuite) Test_NewRetryPolicy_UnknownBackoffStrategy_ShouldUseLinear() {
+	r := NewRetryPolicy(instrumentation.NewNoOpInstrumentationProvider(), &RetryPolicyConfiguration{RetryAttempts: 4, RetryDurationInMS: 3, BackoffStrategy: "unknown"}, _callSite)
+
+	suite.Equal(LinearBackoffStrategy, r.backoffStrategy)
+}
+
+func (suite *TestSuite) Test_UpdateConfiguration_ShouldApplyNewConfiguration() {
+	// Setup
+	errForTest := &err1ForTests{}
+	var action Action = func() error { suite.countActions += 1; return errForTest }
+	var handle ShouldRetryOnSpecificError = func(err error) bool { return true }
+
+	// Act
+	suite.retryPolicy.UpdateConfiguration(&RetryPolicyConfiguration{RetryAttempts: 2, RetryDurationInMS: 3, BackoffStrategy: "constant"})
+	err := suite.retryPolicy.RetryAction(context.Background(), action, handle)
+
+	// Test
+	suite.Equal(errForTest, errors.Cause(err))
+	suite.Equal(2, suite.countActions)
+	suite.Equal(ConstantBackoffStrategy, suite.retryPolicy.backoffStrategy)
+}
+
+func (suite *TestSuite) Test_SetConfigurationProvider_ConfigurationReplaced_ShouldApplyNewConfigurationOnNextRetry() {
+	// Setup
+	errForTest := &err1ForTests{}
+	var action Action = func() error { suite.countActions += 1; return errForTest }
+	var handle ShouldRetryOnSpecificError = func(err error) bool { return true }
+	configuration := &RetryPolicyConfiguration{RetryAttempts: 2, RetryDurationInMS: 3, BackoffStrategy: "constant"}
+	suite.retryPolicy.SetConfigurationProvider(func() *RetryPolicyConfiguration { return configuration })
+
+	// Act
+	err := suite.retryPolicy.RetryAction(context.Background(), action, handle)
+	configuration = &RetryPolicyConfiguration{RetryAttempts: 1, RetryDurationInMS: 3, BackoffStrategy: "constant"}
+	secondErr := suite.retryPolicy.RetryAction(context.Background(), action, handle)
+
+	// Test
+	suite.Equal(errForTest, errors.Cause(err))
+	suite.Equal(errForTest, errors.Cause(secondErr))
+	suite.Equal(3, suite.countActions)
+	suite.Equal(1, suite.retryPolicy.retryAttempts)
+}
+
+func (suite *TestSuite) Test_GetBackoffStrategy_Empty_ShouldReturnLinear() {
+	strategy, err := (&RetryPolicyConfiguration{}).GetBackoffStrategy()
+
+	suite.Nil(err)
+	suite.Equal(LinearBackoffStrategy, strategy)
+}
+
+func (suite *TestSuite) Test_GetBackoffStrategy_Unknown_ShouldReturnError() {
+	strategy, err := (&RetryPolicyConfiguration{BackoffStrategy: "unknown"}).GetBackoffStrategy()
+
+	suite.NotNil(err)
+	suite.Equal(LinearBackoffStrategy, strategy)
+}
+
+func (suite *TestSuite) Test_getSleepTime_Linear_ShouldMultiplyByAttempt() {
+	suite.Equal(2*_expectedConfigurationDuration, suite.retryPolicy.getSleepTime(2, &err1ForTests{}))
+	suite.Equal(3*_expectedConfigurationDuration, suite.retryPolicy.getSleepTime(3, &err1ForTests{}))
+}
+
+func (suite *TestSuite) Test_getSleepTime_Constant_ShouldReturnDuration() {
+	r := NewRetryPolicy(instrumentation.NewNoOpInstrumentationProvider(), &RetryPolicyConfiguration{RetryAttempts: 4, RetryDurationInMS: 3, BackoffStrategy: "constant"}, _callSite)
+
+	suite.Equal(_expectedConfigurationDuration, r.getSleepTime(2, &err1ForTests{}))
+	suite.Equal(_expectedConfigurationDuration, r.getSleepTime(4, &err1ForTests{}))
+}
+
+func (suite *TestSuite) Test_getSleepTime_Exponential_ShouldJitterDoubledDurationUpToMax() {
+	r := NewRetryPolicy(instrumentation.NewNoOpInstrumentationProvider(), &RetryPolicyConfiguration{RetryAttempts: 10, RetryDurationInMS: 10, BackoffStrategy: "exponential", MaxRetryDurationInMS: 50}, _callSite)
+	var jitterArgs []time.Duration
+	r.jitter = func(d time.Duration) time.Duration { jitterArgs = append(jitterArgs, d); return d / 2 }
+
+	suite.Equal(5*time.Millisecond, r.getSleepTime(2, &err1ForTests{}))
+	suite.Equal(10*time.Millisecond, r.getSleepTime(3, &err1ForTests{}))
+	suite.Equal(20*time.Millisecond, r.getSleepTime(4, &err1ForTests{}))
+	suite.Equal(25*time.Millisecond, r.getSleepTime(5, &err1ForTests{}))
+	suite.Equal(25*time.Millisecond, r.getSleepTime(100, &err1ForTests{}))
+	suite.Equal([]time.Duration{10 * time.Millisecond, 20 * time.Millisecond, 40 * time.Millisecond, 50 * time.Millisecond, 50 * time.Millisecond}, jitterArgs)
+}
+
+func (suite *TestSuite) Test_getSleepTime_RetryAfterErr_ShouldWaitAtLeastRetryAfter() {
+	err := errors.Wrap(NewRetryAfterErr(&err1ForTests{}, time.Second), "wrapped")
+
+	suite.Equal(time.Second, suite.retryPolicy.getSleepTime(2, err))
+}
+
+func (suite *TestSuite) Test_getSleepTime_RetryAfterErrShorterThanBackoff_ShouldWaitBackoff() {
+	err := NewRetryAfterErr(&err1ForTests{}, time.Nanosecond)
+
+	suite.Equal(2*_expectedConfigurationDuration, suite.retryPolicy.getSleepTime(2, err))
+}
+
+func (suite *TestSuite) Test_fullJitter_ShouldReturnDurationInRange() {
+	for i := 0; i < 100; i++ {
+		d := fullJitter(10 * time.Millisecond)
+		suite.GreaterOrEqual(int64(d), int64(0))
+		suite.LessOrEqual(int64(d), int64(10*time.Millisecond))
+	}
+	suite.Equal(time.Duration(0), fullJitter(0))
+}
+
+func (suite *TestSuite) Test_RetryActionInterface_NoErrorSecondTime_ShouldReturnValue() {
+	// Setup
+	expected := []byte("lior")
+	var action ActionInterface = func() (interface{}, error) {
+		suite.countActions += 1
+		if suite.countActions > 1 {
+			return expected, nil
+		}
+		return nil, &err1ForTests{}
+	}
+	var handle ShouldRetryOnSpecificError = func(error) bool { return true }
+
+	// Act
+	actual, err := suite.retryPolicy.RetryActionInterface(context.Background(), action, handle)
+	// Test
+	suite.Nil(err)
+	suite.Equal(expected, actual)
+	suite.Equal(2, suite.countActions)
+}
+
+func (suite *TestSuite) Test_RetryActionInterface_ActionNil_ShouldReturnError() {
+	// Act
+	actual, err := suite.retryPolicy.RetryActionInterface(context.Background(), nil, func(error) bool { return true })
+	// Test
+	suite.Equal(utils.NilArgumentError, errors.Cause(err))
+	suite.Nil(actual)
+}
+
+func (suite *TestSuite) Test_RetryAction_MaxElapsedTimeExceeded_ShouldStopRetrying() {
+	// Setup
+	retryPolicy := NewRetryPolicy(instrumentation.NewNoOpInstrumentationProvider(), &RetryPolicyConfiguration{RetryAttempts: 10, RetryDurationInMS: 60000, MaxElapsedTimeInMS: 100}, _callSite)
+	errForTest := &err1ForTests{}
+	var action Action = func() error { suite.countActions += 1; return errForTest }
+	var handle ShouldRetryOnSpecificError = func(error) bool { return true }
+
+	// Act
+	start := time.Now()
+	err := retryPolicy.RetryAction(context.Background(), action, handle)
+	// Test
+	suite.Equal(errForTest, errors.Cause(err))
+	suite.Equal(1, suite.countActions)
+	suite.Less(time.Since(start), time.Minute)
+}
+
+func (suite *TestSuite) Test_RetryAction_RetryAfterErr_ShouldRetryAndReturnCause() {
+	// Setup
+	errForTest := &err1ForTests{}
+	var action Action = func() error {
+		suite.countActions += 1
+		return NewRetryAfterErr(errForTest, time.Millisecond)
+	}
+	var handle ShouldRetryOnSpecificError = func(error) bool { return true }
+
+	// Act
+	err := suite.retryPolicy.RetryAction(context.Background(), action, handle)
+	// Test
+	suite.Equal(errForTest, errors.Cause(err))
+	suite.Equal(_configuration.RetryAttempts, suite.countActions)
+}
+
+func (suite *TestSuite) Test_RetryAction_SendsNumOfAttemptsMetric() {
+	// Setup
+	metricSubmitterMock := new(mocks.IMetricSubmitter)
+	metricSubmitterMock.On("SendMetric", 2, retrypolicymetric.NewRetryPolicyNumOfAttemptsMetric(_callSite, retrypolicymetric.SucceededRetryResult)).Once()
+	retryPolicy := NewRetryPolicy(instrumentation.NewInstrumentationProvider(trace.NewNoOpTracer(), metricSubmitterMock), &_configuration, _callSite)
+	var action Action = func() error {
+		suite.countActions += 1
+		if suite.countActions > 1 {
+			return nil
+		}
+		return &err1ForTests{}
+	}
+
+	// Act
+	err := retryPolicy.RetryAction(context.Background(), action, func(error) bool { return true })
+	// Test
+	suite.Nil(err)
+	metricSubmitterMock.AssertExpectations(suite.T())
+}
+
+func (suite *TestSuite) Test_RetryAction_RetryAttemptsExhausted_SendsNumOfAttemptsMetric() {
+	// Setup
+	metricSubmitterMock := new(mocks.IMetricSubmitter)
+	metricSubmitterMock.On("SendMetric", _configuration.RetryAttempts, retrypolicymetric.NewRetryPolicyNumOfAttemptsMetric(_callSite, retrypolicymetric.RetryAttemptsExhaustedRetryResult)).Once()
+	retryPolicy := NewRetryPolicy(instrumentation.NewInstrumentationProvider(trace.NewNoOpTracer(), metricSubmitterMock), &_configuration, _callSite)
+
+	// Act
+	err := retryPolicy.RetryAction(context.Background(), func() error { return &err1ForTests{} }, func(error) bool { return true })
+	// Test
+	suite.NotNil(err)
+	metricSubmitterMock.AssertExpectations(suite.T())
+}
+
+func (suite *TestSuite) Test_GetRetryAfter_Seconds_ShouldReturnDuration() {
+	resp := &http.Response{Header: http.Header{}}
+	resp.Header.Set("Retry-After", "3")
+
+	retryAfter, ok := GetRetryAfter(resp)
+
+	suite.True(ok)
+	suite.Equal(3*time.Second, retryAfter)
+}
+
+func (suite *TestSuite) Test_GetRetryAfter_HTTPDate_ShouldReturnDurationUntilDate() {
+	resp := &http.Response{Header: http.Header{}}
+	resp.Header.Set("Retry-After", time.Now().Add(time.Hour).UTC().Format(http.TimeFormat))
+
+	retryAfter, ok := GetRetryAfter(resp)
+
+	suite.True(ok)
+	suite.Greater(int64(retryAfter), int64(58*time.Minute))
+	suite.LessOrEqual(int64(retryAfter), int64(time.Hour))
+}
+
+func (suite *TestSuite) Test_GetRetryAfter_NoHeaderOrInvalid_ShouldReturnFalse() {
+	resp := &http.Response{Header: http.Header{}}
+	_, ok := GetRetryAfter(resp)
+	suite.False(ok)
+
+	resp.Header.Set("Retry-After", "invalid")
+	_, ok = GetRetryAfter(resp)
+	suite.False(ok)
+
+	_, ok = GetRetryAfter(nil)
+	suite.False(ok)
+}
+
 // We need this function to kick off the test suite, otherwise
 // "go test" won't know about our tests
 func TestRetryPolicyTestSuite(t *testing.T) {
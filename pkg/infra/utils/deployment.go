@@ -1,6 +1,9 @@
 package utils
 
-import "github.com/pkg/errors"
+import (
+	"fmt"
+	"github.com/pkg/errors"
+)
 
 var (
 	// _singleton is the only instance of deployment struct.
@@ -20,6 +23,8 @@ type DeploymentConfiguration struct {
 	IsLocalDevelopment bool
 	// Namespace  is the Namespace where the server is running
 	Namespace string
+	// ServiceAccountName is the name of the service account of the server (empty if unknown)
+	ServiceAccountName string
 }
 
 // NewDeployment creates new deployment in case of singleton is not initialized yet.
@@ -50,6 +55,15 @@ func (d *deployment) GetNamespace() string {
 	return d.configuration.Namespace
 }
 
+// GetServiceAccountUsername returns the username of the service account of the server in the requests to the api server
+// (e.g. system:serviceaccount:kube-system:azure-defender-proxy-admin). Returns empty string if the service account is unknown.
+func (d *deployment) GetServiceAccountUsername() string {
+	if d.configuration.ServiceAccountName == "" {
+		return ""
+	}
+	return fmt.Sprintf("system:serviceaccount:%s:%s", d.configuration.Namespace, d.configuration.ServiceAccountName)
+}
+
 // UpdateDeploymentForTests is used for update the singleton for tests purpose.
 // ------ Shouldn't be used in production code ---------------
 func UpdateDeploymentForTests(configuration *DeploymentConfiguration) {
@@ -41,6 +41,16 @@ func (suite *TestSuite) Test_GetDeploymentInstance_FailureOnNewDeployment__Shoul
 	suite.Equal(expected, actual)
 }
 
+func (suite *TestSuite) Test_GetServiceAccountUsername_ServiceAccountName_ShouldReturnUsername() {
+	UpdateDeploymentForTests(&DeploymentConfiguration{Namespace: "kube-system", ServiceAccountName: "azure-defender-proxy-admin"})
+	suite.Equal("system:serviceaccount:kube-system:azure-defender-proxy-admin", GetDeploymentInstance().GetServiceAccountUsername())
+}
+
+func (suite *TestSuite) Test_GetServiceAccountUsername_NoServiceAccountName_ShouldReturnEmpty() {
+	UpdateDeploymentForTests(&DeploymentConfiguration{Namespace: "kube-system"})
+	suite.Equal("", GetDeploymentInstance().GetServiceAccountUsername())
+}
+
 func (suite *TestSuite) Test_GetDeploymentInstance_BeforeInitialized__ShouldReturnError() {
 	// Act
 	actual := GetDeploymentInstance()
@@ -0,0 +1,43 @@
+package utils
+
+import (
+	"context"
+	"time"
+)
+
+// detachedContext is a context that carries the values of its parent (e.g. the span of the request) but isn't
+// canceled when its parent is canceled and has no deadline.
+type detachedContext struct {
+	// parent is the context that the values are taken from
+	parent context.Context
+}
+
+// NewDetachedContext returns context with the values of ctx that isn't canceled when ctx is canceled.
+// It is used for work that should continue after the request that started it is done (e.g. fetching results that are saved in cache after timeout).
+func NewDetachedContext(ctx context.Context) context.Context {
+	return &detachedContext{parent: ctx}
+}
+
+// NewDetachedContextWithTimeout returns context with the values of ctx that isn't canceled when ctx is canceled and has its own timeout
+// instead of the deadline of ctx. It is used for work that should continue after the request that started it is done, but not forever
+// (e.g. fetching results that are saved in cache after timeout, so the next requests with the same pod spec get them).
+// The returned cancel function should be called when the work is done.
+func NewDetachedContextWithTimeout(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(NewDetachedContext(ctx), timeout)
+}
+
+func (ctx *detachedContext) Deadline() (deadline time.Time, ok bool) {
+	return time.Time{}, false
+}
+
+func (ctx *detachedContext) Done() <-chan struct{} {
+	return nil
+}
+
+func (ctx *detachedContext) Err() error {
+	return nil
+}
+
+func (ctx *detachedContext) Value(key interface{}) interface{} {
+	return ctx.parent.Value(key)
+}
@@ -0,0 +1,74 @@
+package utils
+
+import (
+	"context"
+	"github.com/stretchr/testify/suite"
+	"testing"
+	"time"
+)
+
+type contextKey string
+
+type TestSuiteDetachedContext struct {
+	suite.Suite
+}
+
+func (suite *TestSuiteDetachedContext) Test_NewDetachedContext_ParentCanceled_NotCanceledAndKeepsValues() {
+	parent, cancel := context.WithCancel(context.WithValue(context.Background(), contextKey("key"), "value"))
+	ctx := NewDetachedContext(parent)
+
+	cancel()
+
+	suite.NotNil(parent.Err())
+	suite.Nil(ctx.Err())
+	suite.Nil(ctx.Done())
+	suite.Equal("value", ctx.Value(contextKey("key")))
+}
+
+func (suite *TestSuiteDetachedContext) Test_NewDetachedContextWithTimeout_ParentCanceled_NotCanceledAndKeepsValues() {
+	parent, cancel := context.WithTimeout(context.WithValue(context.Background(), contextKey("key"), "value"), time.Millisecond)
+	ctx, cancelDetached := NewDetachedContextWithTimeout(parent, time.Hour)
+	defer cancelDetached()
+
+	cancel()
+
+	suite.NotNil(parent.Err())
+	suite.Nil(ctx.Err())
+	suite.Equal("value", ctx.Value(contextKey("key")))
+}
+
+func (suite *TestSuiteDetachedContext) Test_NewDetachedContextWithTimeout_ParentDeadlineNotInherited() {
+	parentDeadline := time.Now().Add(time.Millisecond)
+	parent, cancel := context.WithDeadline(context.Background(), parentDeadline)
+	defer cancel()
+	ctx, cancelDetached := NewDetachedContextWithTimeout(parent, time.Hour)
+	defer cancelDetached()
+
+	<-parent.Done()
+
+	suite.Nil(ctx.Err())
+	actualDeadline, ok := ctx.Deadline()
+	suite.True(ok)
+	suite.True(actualDeadline.After(parentDeadline.Add(time.Minute)))
+}
+
+func (suite *TestSuiteDetachedContext) Test_NewDetachedContextWithTimeout_TimeoutPassed_Canceled() {
+	ctx, cancelDetached := NewDetachedContextWithTimeout(context.Background(), time.Millisecond)
+	defer cancelDetached()
+
+	<-ctx.Done()
+
+	suite.Equal(context.DeadlineExceeded, ctx.Err())
+}
+
+func (suite *TestSuiteDetachedContext) Test_NewDetachedContextWithTimeout_CanceledByCancelFunc() {
+	ctx, cancelDetached := NewDetachedContextWithTimeout(context.Background(), time.Hour)
+
+	cancelDetached()
+
+	suite.Equal(context.Canceled, ctx.Err())
+}
+
+func TestSuiteDetachedContext_Run(t *testing.T) {
+	suite.Run(t, new(TestSuiteDetachedContext))
+}
@@ -0,0 +1,24 @@
+package utils
+
+import "strings"
+
+// _jsonPointerTokenEscaper escapes the reference tokens of json pointers according to RFC 6901 - '~' is escaped first,
+// so the '~' of the escaped '/' isn't escaped again.
+var _jsonPointerTokenEscaper = strings.NewReplacer("~", "~0", "/", "~1")
+
+// EscapeJSONPointerToken escapes a reference token of a json pointer (RFC 6901), e.g. the key of an annotation in a json patch path.
+// For example, azuredefender.io/containers.vulnerability.scan.info is escaped to azuredefender.io~1containers.vulnerability.scan.info
+func EscapeJSONPointerToken(token string) string {
+	return _jsonPointerTokenEscaper.Replace(token)
+}
+
+// JoinJSONPointer returns the json pointer of the given path, in which each of the reference tokens is escaped.
+// For example, ["metadata", "annotations", "azuredefender.io/scan-status"] is joined to /metadata/annotations/azuredefender.io~1scan-status
+func JoinJSONPointer(tokens ...string) string {
+	var builder strings.Builder
+	for _, token := range tokens {
+		builder.WriteString("/")
+		builder.WriteString(EscapeJSONPointerToken(token))
+	}
+	return builder.String()
+}
@@ -0,0 +1,30 @@
+package utils
+
+import (
+	"github.com/stretchr/testify/suite"
+	"testing"
+)
+
+type JSONPointerTestSuite struct {
+	suite.Suite
+}
+
+func (suite *JSONPointerTestSuite) Test_EscapeJSONPointerToken_Slash_EscapedToTildeOne() {
+	suite.Equal("azuredefender.io~1containers.vulnerability.scan.info", EscapeJSONPointerToken("azuredefender.io/containers.vulnerability.scan.info"))
+}
+
+func (suite *JSONPointerTestSuite) Test_EscapeJSONPointerToken_TildeAndSlash_TildeEscapedFirst() {
+	suite.Equal("a~01~1b", EscapeJSONPointerToken("a~1/b"))
+}
+
+func (suite *JSONPointerTestSuite) Test_JoinJSONPointer_Tokens_EscapedPointer() {
+	suite.Equal("/metadata/annotations/azuredefender.io~1scan-status", JoinJSONPointer("metadata", "annotations", "azuredefender.io/scan-status"))
+}
+
+func (suite *JSONPointerTestSuite) Test_JoinJSONPointer_NoTokens_Empty() {
+	suite.Equal("", JoinJSONPointer())
+}
+
+func Test_JSONPointerTestSuite(t *testing.T) {
+	suite.Run(t, new(JSONPointerTestSuite))
+}
@@ -0,0 +1,88 @@
+package signature
+
+// Cosign signatures are stored as an OCI image in the repository of the signed image, tagged by the digest of the signed image
+// (e.g. sha256-<hex>.sig). Each layer of the signature image is a simple signing payload, and the signature of the payload
+// is stored in the layer's annotations.
+// See https://github.com/sigstore/cosign/blob/main/specs/SIGNATURE_SPEC.md
+const (
+	// _cosignSignatureTagSuffix is the suffix of the tag of the cosign signature image
+	_cosignSignatureTagSuffix = ".sig"
+	// _cosignSignatureAnnotationKey is the layer annotation key of the base64 encoded signature
+	_cosignSignatureAnnotationKey = "dev.cosignproject.cosign/signature"
+	// _cosignCertificateAnnotationKey is the layer annotation key of the PEM encoded signing certificate (keyless signing)
+	_cosignCertificateAnnotationKey = "dev.sigstore.cosign/certificate"
+	// _cosignBundleAnnotationKey is the layer annotation key of the transparency log (Rekor) bundle of the signature
+	_cosignBundleAnnotationKey = "dev.sigstore.cosign/bundle"
+	// _rekorHashedRekordKind is the kind of the Rekor entries of cosign signatures
+	_rekorHashedRekordKind = "hashedrekord"
+	// _rekorSHA256Algorithm is the hash algorithm of the signed payload in the Rekor entries of cosign signatures
+	_rekorSHA256Algorithm = "sha256"
+)
+
+// cosignPayload represents cosign simple signing payload
+type cosignPayload struct {
+	// Critical is the critical section of the payload that contains the signed image
+	Critical cosignPayloadCritical `json:"critical"`
+}
+
+// cosignPayloadCritical represents the critical section of cosign simple signing payload
+type cosignPayloadCritical struct {
+	// Image is the signed image
+	Image cosignPayloadImage `json:"image"`
+	// Type is the type of the signature (e.g. "cosign container image signature")
+	Type string `json:"type"`
+}
+
+// cosignPayloadImage represents the signed image of cosign simple signing payload
+type cosignPayloadImage struct {
+	// DockerManifestDigest is the digest of the signed image
+	DockerManifestDigest string `json:"docker-manifest-digest"`
+}
+
+// rekorBundle represents the transparency log (Rekor) bundle of cosign signature - the proof that the signature was
+// uploaded to the transparency log at IntegratedTime.
+type rekorBundle struct {
+	// SignedEntryTimestamp is the signature of the transparency log over the canonical JSON of Payload
+	SignedEntryTimestamp []byte `json:"SignedEntryTimestamp"`
+	// Payload is the signed entry of the transparency log
+	Payload rekorBundlePayload `json:"Payload"`
+}
+
+// rekorBundlePayload represents the signed entry of the transparency log.
+// The fields are ordered by their JSON keys, so marshaling it results in its canonical JSON.
+type rekorBundlePayload struct {
+	// Body is the base64 encoded entry (rekorEntryBody)
+	Body string `json:"body"`
+	// IntegratedTime is the unix time that the entry was integrated into the transparency log
+	IntegratedTime int64 `json:"integratedTime"`
+	// LogID is the id of the transparency log
+	LogID string `json:"logID"`
+	// LogIndex is the index of the entry in the transparency log
+	LogIndex int64 `json:"logIndex"`
+}
+
+// rekorEntryBody represents hashedrekord entry of the transparency log
+type rekorEntryBody struct {
+	// Kind is the kind of the entry (e.g. "hashedrekord")
+	Kind string `json:"kind"`
+	// Spec is the signature and the signed hash of the entry
+	Spec rekorEntrySpec `json:"spec"`
+}
+
+// rekorEntrySpec represents the spec of hashedrekord entry
+type rekorEntrySpec struct {
+	// Data is the hash of the signed payload
+	Data struct {
+		Hash struct {
+			Algorithm string `json:"algorithm"`
+			Value     string `json:"value"`
+		} `json:"hash"`
+	} `json:"data"`
+	// Signature is the base64 encoded signature and the base64 encoded PEM of its certificate
+	Signature struct {
+		Content   string `json:"content"`
+		PublicKey struct {
+			Content string `json:"content"`
+		} `json:"publicKey"`
+	} `json:"signature"`
+}
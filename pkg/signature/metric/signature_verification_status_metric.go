@@ -0,0 +1,32 @@
+package metric
+
+import (
+	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/azdsecinfo/contracts"
+	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/instrumentation/metric"
+)
+
+// SignatureVerificationStatusMetric implements metric.IMetric interface
+var _ metric.IMetric = (*SignatureVerificationStatusMetric)(nil)
+
+// SignatureVerificationStatusMetric is metric of SignatureVerifier to report the signature status of each verified digest
+type SignatureVerificationStatusMetric struct {
+	// signatureStatus is the signature status of the verified digest
+	signatureStatus contracts.SignatureStatus
+}
+
+// NewSignatureVerificationStatusMetric Ctor for SignatureVerificationStatusMetric
+func NewSignatureVerificationStatusMetric(signatureStatus contracts.SignatureStatus) *SignatureVerificationStatusMetric {
+	return &SignatureVerificationStatusMetric{
+		signatureStatus: signatureStatus,
+	}
+}
+
+func (m *SignatureVerificationStatusMetric) MetricName() string {
+	return "SignatureVerificationStatus"
+}
+
+func (m *SignatureVerificationStatusMetric) MetricDimension() []metric.Dimension {
+	return []metric.Dimension{
+		{Key: "SignatureStatus", Value: string(m.signatureStatus)},
+	}
+}
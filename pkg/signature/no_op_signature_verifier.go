@@ -0,0 +1,25 @@
+package signature
+
+import (
+	"context"
+	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/azdsecinfo/contracts"
+	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/registry"
+)
+
+// NoOpSignatureVerifier implements ISignatureVerifier interface
+var _ ISignatureVerifier = (*NoOpSignatureVerifier)(nil)
+
+// NoOpSignatureVerifier is implementation that does nothing of ISignatureVerifier.
+// It is used when signature verification is disabled - the returned status is empty, so it's omitted from the annotation.
+type NoOpSignatureVerifier struct {
+}
+
+// NewNoOpSignatureVerifier Ctor for NoOpSignatureVerifier
+func NewNoOpSignatureVerifier() *NoOpSignatureVerifier {
+	return &NoOpSignatureVerifier{}
+}
+
+// Verify returns empty signature status
+func (verifier *NoOpSignatureVerifier) Verify(ctx context.Context, imageReference registry.IImageReference, digest string, authContext *registry.AuthContext) (contracts.SignatureStatus, error) {
+	return "", nil
+}
@@ -0,0 +1,172 @@
+package signature
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/azdsecinfo/contracts"
+	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/instrumentation"
+	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/instrumentation/metric"
+	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/instrumentation/metric/util"
+	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/instrumentation/trace"
+	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/registry"
+	registryerrors "github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/registry/errors"
+	registryutils "github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/registry/utils"
+	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/utils"
+	signaturemetric "github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/signature/metric"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/pkg/errors"
+	"strings"
+)
+
+// ISignatureVerifier responsible to verify the signatures of resolved image digests
+type ISignatureVerifier interface {
+	// Verify receives an image reference, its resolved digest and the deployed resource auth context and returns the signature status of the digest.
+	// In case of an error, contracts.SignatureUnverified is returned with the error.
+	Verify(ctx context.Context, imageReference registry.IImageReference, digest string, authContext *registry.AuthContext) (contracts.SignatureStatus, error)
+}
+
+// SignatureVerifier implements ISignatureVerifier interface
+var _ ISignatureVerifier = (*SignatureVerifier)(nil)
+
+// SignatureVerifier is cosign based implementation of ISignatureVerifier interface.
+// It looks up the cosign signature image of the digest using the registry client (same keychains that are used to resolve the digest)
+// and verifies the signatures against the trusted verification keys.
+type SignatureVerifier struct {
+	//tracerProvider is tracer provider of SignatureVerifier
+	tracerProvider trace.ITracerProvider
+	//metricSubmitter is metric submitter of SignatureVerifier
+	metricSubmitter metric.IMetricSubmitter
+	// registryClient is the client of the registry which is used to fetch the signatures
+	registryClient registry.IRegistryClient
+	// verificationKeys are the trusted public keys and certificates
+	verificationKeys *VerificationKeys
+}
+
+// SignatureVerifierConfiguration is configuration data for SignatureVerifier
+type SignatureVerifierConfiguration struct {
+	// Enabled is whether the signatures of the images should be verified
+	Enabled bool
+	// VerificationKeysDirPath is the path of the directory (mounted ConfigMap) that contains the PEM encoded trusted public keys and certificates
+	VerificationKeysDirPath string
+	// TransparencyLogKeysDirPath is the path of the directory (mounted ConfigMap) that contains the PEM encoded public keys of the
+	// trusted transparency logs (Rekor). Empty path means that certificates are verified at the current time only.
+	TransparencyLogKeysDirPath string
+}
+
+// NewSignatureVerifier Ctor
+func NewSignatureVerifier(instrumentationProvider instrumentation.IInstrumentationProvider, registryClient registry.IRegistryClient, verificationKeys *VerificationKeys) *SignatureVerifier {
+	return &SignatureVerifier{
+		tracerProvider:   instrumentationProvider.GetTracerProvider("SignatureVerifier"),
+		metricSubmitter:  instrumentationProvider.GetMetricSubmitter(),
+		registryClient:   registryClient,
+		verificationKeys: verificationKeys,
+	}
+}
+
+// Verify receives an image reference, its resolved digest and the deployed resource auth context and returns the signature status of the digest.
+// The signature status is:
+// contracts.Unsigned - the signature image doesn't exist or doesn't contain signatures.
+// contracts.SignatureVerified - at least one of the signatures is verified.
+// contracts.InvalidSignature - none of the signatures is verified.
+// contracts.SignatureUnverified - an error encountered while trying to verify the signatures.
+func (verifier *SignatureVerifier) Verify(ctx context.Context, imageReference registry.IImageReference, digest string, authContext *registry.AuthContext) (contracts.SignatureStatus, error) {
+	tracer := verifier.tracerProvider.GetTracer("Verify")
+	tracer.Info("Received:", "imageReference", imageReference, "digest", digest, "authContext", authContext)
+
+	// Argument validation
+	if imageReference == nil || authContext == nil || digest == "" {
+		err := errors.Wrap(utils.NilArgumentError, "SignatureVerifier.Verify")
+		tracer.Error(err, "")
+		verifier.metricSubmitter.SendMetric(1, util.NewErrorEncounteredMetric(err, "SignatureVerifier.Verify"))
+		return contracts.SignatureUnverified, err
+	}
+
+	signatureStatus, err := verifier.verify(ctx, imageReference, digest, authContext)
+	if err != nil {
+		err = errors.Wrap(err, "SignatureVerifier.Verify failed to verify signatures")
+		tracer.Error(err, "")
+		verifier.metricSubmitter.SendMetric(1, util.NewErrorEncounteredMetric(err, "SignatureVerifier.Verify"))
+	}
+
+	tracer.Info("Signature verification finished", "imageReference", imageReference, "digest", digest, "signatureStatus", signatureStatus)
+	verifier.metricSubmitter.SendMetric(1, signaturemetric.NewSignatureVerificationStatusMetric(signatureStatus))
+	return signatureStatus, err
+}
+
+// verify fetches the cosign signature image of the digest and verifies its signatures.
+func (verifier *SignatureVerifier) verify(ctx context.Context, imageReference registry.IImageReference, digest string, authContext *registry.AuthContext) (contracts.SignatureStatus, error) {
+	tracer := verifier.tracerProvider.GetTracer("verify")
+
+	signatureImageReference := getCosignSignatureImageReference(imageReference, digest)
+	manifestBytes, err := verifier.registryClient.GetManifest(ctx, signatureImageReference, authContext)
+	if err != nil {
+		if _, isNotFound := errors.Cause(err).(*registryerrors.ImageIsNotFoundErr); isNotFound {
+			tracer.Info("Signature image doesn't exist - image is unsigned", "signatureImageReference", signatureImageReference.Original())
+			return contracts.Unsigned, nil
+		}
+		return contracts.SignatureUnverified, errors.Wrap(err, "failed to get signature image manifest")
+	}
+
+	manifest, err := v1.ParseManifest(bytes.NewReader(manifestBytes))
+	if err != nil {
+		return contracts.SignatureUnverified, errors.Wrap(err, "failed to parse signature image manifest")
+	}
+
+	numOfSignatures := 0
+	for _, layer := range manifest.Layers {
+		encodedSignature, ok := layer.Annotations[_cosignSignatureAnnotationKey]
+		if !ok {
+			continue
+		}
+		numOfSignatures++
+
+		payload, err := verifier.registryClient.GetBlob(ctx, signatureImageReference, layer.Digest.String(), authContext)
+		if err != nil {
+			return contracts.SignatureUnverified, errors.Wrap(err, "failed to get signature payload")
+		}
+
+		if verifier.isSignatureVerified(payload, encodedSignature, layer.Annotations[_cosignCertificateAnnotationKey], layer.Annotations[_cosignBundleAnnotationKey], digest) {
+			tracer.Info("Signature verified", "signatureImageReference", signatureImageReference.Original(), "layer", layer.Digest.String())
+			return contracts.SignatureVerified, nil
+		}
+	}
+
+	if numOfSignatures == 0 {
+		tracer.Info("Signature image doesn't contain signatures - image is unsigned", "signatureImageReference", signatureImageReference.Original())
+		return contracts.Unsigned, nil
+	}
+	tracer.Info("None of the signatures is verified", "signatureImageReference", signatureImageReference.Original(), "numOfSignatures", numOfSignatures)
+	return contracts.InvalidSignature, nil
+}
+
+// isSignatureVerified returns true if the payload is signing the digest and its signature is verified by the verification keys.
+func (verifier *SignatureVerifier) isSignatureVerified(payload []byte, encodedSignature string, attachedCertificatePEM string, attachedBundle string, digest string) bool {
+	tracer := verifier.tracerProvider.GetTracer("isSignatureVerified")
+
+	var parsedPayload cosignPayload
+	if err := json.Unmarshal(payload, &parsedPayload); err != nil {
+		tracer.Info("Failed to parse signature payload", "err", err)
+		return false
+	}
+	// The payload must sign the digest itself and not another digest of the repository.
+	if parsedPayload.Critical.Image.DockerManifestDigest != digest {
+		tracer.Info("Signature payload is signing another digest", "signedDigest", parsedPayload.Critical.Image.DockerManifestDigest, "digest", digest)
+		return false
+	}
+
+	signature, err := base64.StdEncoding.DecodeString(encodedSignature)
+	if err != nil {
+		tracer.Info("Failed to decode signature", "err", err)
+		return false
+	}
+
+	return verifier.verificationKeys.VerifySignature(payload, signature, attachedCertificatePEM, attachedBundle)
+}
+
+// getCosignSignatureImageReference returns the reference of the cosign signature image of the digest (e.g. registry/repo:sha256-<hex>.sig)
+func getCosignSignatureImageReference(imageReference registry.IImageReference, digest string) *registry.Tag {
+	tag := strings.Replace(digest, ":", "-", 1) + _cosignSignatureTagSuffix
+	return registryutils.GetTagReferenceInRepository(imageReference, tag)
+}
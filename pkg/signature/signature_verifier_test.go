@@ -0,0 +1,166 @@
+package signature
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/azdsecinfo/contracts"
+	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/circuitbreaker"
+	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/instrumentation"
+	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/registry"
+	registrycrane "github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/registry/crane"
+	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/registry/crane/mocks"
+	registrymocks "github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/registry/mocks"
+	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/registry/wrappers"
+	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/retrypolicy"
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/crane"
+	ggcrregistry "github.com/google/go-containerregistry/pkg/registry"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/static"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+const (
+	_repository                   = "test/app"
+	_cosignSimpleSigningMediaType = "application/vnd.dev.cosign.simplesigning.v1+json"
+)
+
+type SignatureVerifierTestSuite struct {
+	suite.Suite
+	server         *httptest.Server
+	registryHost   string
+	signingKey     *ecdsa.PrivateKey
+	imageReference registry.IImageReference
+	digest         string
+	authContext    *registry.AuthContext
+	verifier       *SignatureVerifier
+}
+
+// This will run before each test in the suite - creates a local registry with a pushed image.
+func (suite *SignatureVerifierTestSuite) SetupTest() {
+	suite.server = httptest.NewServer(ggcrregistry.New())
+	suite.registryHost = strings.TrimPrefix(suite.server.URL, "http://")
+
+	var err error
+	suite.signingKey, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	suite.Nil(err)
+
+	image, err := random.Image(1024, 1)
+	suite.Nil(err)
+	original := fmt.Sprintf("%s/%s:v1", suite.registryHost, _repository)
+	suite.Nil(crane.Push(image, original))
+	imageDigest, err := image.Digest()
+	suite.Nil(err)
+	suite.digest = imageDigest.String()
+	suite.imageReference = registry.NewTag(original, suite.registryHost, _repository, "v1")
+	suite.authContext = registry.NewAuthContext("default", []string{}, "")
+
+	suite.verifier = suite.createVerifier(NewVerificationKeys([]crypto.PublicKey{&suite.signingKey.PublicKey}, nil, nil))
+}
+
+func (suite *SignatureVerifierTestSuite) TearDownTest() {
+	suite.server.Close()
+}
+
+func (suite *SignatureVerifierTestSuite) Test_Verify_SignedWithTrustedKey_Verified() {
+	suite.pushSignature(suite.createPayload(suite.digest), suite.signingKey)
+
+	status, err := suite.verifier.Verify(context.Background(), suite.imageReference, suite.digest, suite.authContext)
+
+	suite.Nil(err)
+	suite.Equal(contracts.SignatureVerified, status)
+}
+
+func (suite *SignatureVerifierTestSuite) Test_Verify_NoSignatureImage_Unsigned() {
+	status, err := suite.verifier.Verify(context.Background(), suite.imageReference, suite.digest, suite.authContext)
+
+	suite.Nil(err)
+	suite.Equal(contracts.Unsigned, status)
+}
+
+func (suite *SignatureVerifierTestSuite) Test_Verify_SignedWithUntrustedKey_InvalidSignature() {
+	untrustedKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	suite.Nil(err)
+	suite.pushSignature(suite.createPayload(suite.digest), untrustedKey)
+
+	status, err := suite.verifier.Verify(context.Background(), suite.imageReference, suite.digest, suite.authContext)
+
+	suite.Nil(err)
+	suite.Equal(contracts.InvalidSignature, status)
+}
+
+func (suite *SignatureVerifierTestSuite) Test_Verify_PayloadOfAnotherDigest_InvalidSignature() {
+	suite.pushSignature(suite.createPayload("sha256:86a80e680602c613519a5af190219346230a3b02d98606727b9c8d47d8dc88ed"), suite.signingKey)
+
+	status, err := suite.verifier.Verify(context.Background(), suite.imageReference, suite.digest, suite.authContext)
+
+	suite.Nil(err)
+	suite.Equal(contracts.InvalidSignature, status)
+}
+
+func (suite *SignatureVerifierTestSuite) Test_Verify_RegistryError_Unverified() {
+	registryClientMock := new(registrymocks.IRegistryClient)
+	registryClientMock.On("GetManifest", mock.Anything, mock.Anything, suite.authContext).Return(nil, errors.New("registry error")).Once()
+	verifier := NewSignatureVerifier(instrumentation.NewNoOpInstrumentationProvider(), registryClientMock, NewVerificationKeys(nil, nil, nil))
+
+	status, err := verifier.Verify(context.Background(), suite.imageReference, suite.digest, suite.authContext)
+
+	suite.NotNil(err)
+	suite.Equal(contracts.SignatureUnverified, status)
+	registryClientMock.AssertExpectations(suite.T())
+}
+
+func (suite *SignatureVerifierTestSuite) Test_Verify_NilArgument_Unverified() {
+	status, err := suite.verifier.Verify(context.Background(), nil, suite.digest, suite.authContext)
+
+	suite.NotNil(err)
+	suite.Equal(contracts.SignatureUnverified, status)
+}
+
+// createVerifier creates a verifier that uses crane registry client against the local registry.
+func (suite *SignatureVerifierTestSuite) createVerifier(verificationKeys *VerificationKeys) *SignatureVerifier {
+	instrumentationProvider := instrumentation.NewNoOpInstrumentationProvider()
+	retryPolicy := retrypolicy.NewRetryPolicy(instrumentationProvider, &retrypolicy.RetryPolicyConfiguration{RetryAttempts: 1, RetryDurationInMS: 1}, "CraneWrapper")
+	k8sKeychainFactoryMock := new(mocks.IK8SKeychainFactory)
+	k8sKeychainFactoryMock.On("Create", mock.Anything, mock.Anything, mock.Anything).Return(authn.DefaultKeychain, nil)
+	transportProvider, err := registrycrane.NewRegistryTransportProvider(instrumentationProvider, &registrycrane.RegistryTransportProviderConfiguration{})
+	suite.Nil(err)
+	registryClient := registrycrane.NewCraneRegistryClient(instrumentationProvider, wrappers.NewCraneWrapper(instrumentationProvider, retryPolicy, circuitbreaker.NewNoOpCircuitBreaker()), new(mocks.IACRKeychainFactory), k8sKeychainFactoryMock, transportProvider)
+	return NewSignatureVerifier(instrumentationProvider, registryClient, verificationKeys)
+}
+
+// createPayload creates cosign simple signing payload of the digest
+func (suite *SignatureVerifierTestSuite) createPayload(digest string) []byte {
+	return []byte(fmt.Sprintf(`{"critical":{"identity":{"docker-reference":"%s/%s"},"image":{"docker-manifest-digest":"%s"},"type":"cosign container image signature"},"optional":null}`, suite.registryHost, _repository, digest))
+}
+
+// pushSignature signs the payload and pushes cosign signature image of the digest to the local registry
+func (suite *SignatureVerifierTestSuite) pushSignature(payload []byte, signingKey *ecdsa.PrivateKey) {
+	hash := sha256.Sum256(payload)
+	signature, err := ecdsa.SignASN1(rand.Reader, signingKey, hash[:])
+	suite.Nil(err)
+
+	signatureImage, err := mutate.Append(empty.Image, mutate.Addendum{
+		Layer:       static.NewLayer(payload, _cosignSimpleSigningMediaType),
+		Annotations: map[string]string{_cosignSignatureAnnotationKey: base64.StdEncoding.EncodeToString(signature)},
+	})
+	suite.Nil(err)
+	suite.Nil(crane.Push(signatureImage, getCosignSignatureImageReference(suite.imageReference, suite.digest).Original()))
+}
+
+func TestSignatureVerifierTestSuite(t *testing.T) {
+	suite.Run(t, new(SignatureVerifierTestSuite))
+}
@@ -0,0 +1,288 @@
+package signature
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"github.com/pkg/errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	_pemPublicKeyType   = "PUBLIC KEY"
+	_pemCertificateType = "CERTIFICATE"
+)
+
+// VerificationKeys holds the trusted public keys and certificates that are used to verify image signatures
+type VerificationKeys struct {
+	// publicKeys are the trusted public keys (including the public keys of the trusted certificates)
+	publicKeys []crypto.PublicKey
+	// certificatesPool is the pool of the trusted certificates, used to verify certificates that are attached to signatures
+	certificatesPool *x509.CertPool
+	// hasCertificates is true if at least one certificate is trusted
+	hasCertificates bool
+	// transparencyLogPublicKeys are the trusted public keys of the transparency logs (Rekor), used to verify the time
+	// that attached certificates signed the signatures
+	transparencyLogPublicKeys []crypto.PublicKey
+}
+
+// NewVerificationKeys VerificationKeys ctor
+func NewVerificationKeys(publicKeys []crypto.PublicKey, certificates []*x509.Certificate, transparencyLogPublicKeys []crypto.PublicKey) *VerificationKeys {
+	keys := &VerificationKeys{
+		publicKeys:                append([]crypto.PublicKey{}, publicKeys...),
+		certificatesPool:          x509.NewCertPool(),
+		transparencyLogPublicKeys: append([]crypto.PublicKey{}, transparencyLogPublicKeys...),
+	}
+	for _, certificate := range certificates {
+		keys.publicKeys = append(keys.publicKeys, certificate.PublicKey)
+		keys.certificatesPool.AddCert(certificate)
+		keys.hasCertificates = true
+	}
+	return keys
+}
+
+// LoadVerificationKeysFromDir loads all PEM encoded public keys and certificates from the files of verificationKeysDirPath,
+// and the PEM encoded public keys of the transparency logs from the files of transparencyLogKeysDirPath (skipped if empty).
+// The directories are expected to be mounted ConfigMaps, so hidden files and sub directories are skipped.
+func LoadVerificationKeysFromDir(verificationKeysDirPath string, transparencyLogKeysDirPath string) (*VerificationKeys, error) {
+	publicKeys, certificates, err := loadPEMVerificationKeysFromDir(verificationKeysDirPath)
+	if err != nil {
+		return nil, err
+	}
+
+	var transparencyLogPublicKeys []crypto.PublicKey
+	if transparencyLogKeysDirPath != "" {
+		var transparencyLogCertificates []*x509.Certificate
+		transparencyLogPublicKeys, transparencyLogCertificates, err = loadPEMVerificationKeysFromDir(transparencyLogKeysDirPath)
+		if err != nil {
+			return nil, err
+		}
+		if len(transparencyLogCertificates) > 0 {
+			return nil, errors.Errorf("transparency log keys directory %s should contain public keys only", transparencyLogKeysDirPath)
+		}
+	}
+
+	return NewVerificationKeys(publicKeys, certificates, transparencyLogPublicKeys), nil
+}
+
+// loadPEMVerificationKeysFromDir loads all PEM encoded public keys and certificates from the files of the directory.
+func loadPEMVerificationKeysFromDir(dirPath string) ([]crypto.PublicKey, []*x509.Certificate, error) {
+	entries, err := ioutil.ReadDir(dirPath)
+	if err != nil {
+		return nil, nil, errors.Wrapf(err, "failed to read verification keys directory %s", dirPath)
+	}
+
+	var publicKeys []crypto.PublicKey
+	var certificates []*x509.Certificate
+	for _, entry := range entries {
+		if strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		filePath := filepath.Join(dirPath, entry.Name())
+		// ConfigMap keys are mounted as symlinks, so stat the file itself and not the entry.
+		fileInfo, err := os.Stat(filePath)
+		if err != nil {
+			return nil, nil, errors.Wrapf(err, "failed to stat verification keys file %s", filePath)
+		}
+		if fileInfo.IsDir() {
+			continue
+		}
+
+		pemBytes, err := ioutil.ReadFile(filePath)
+		if err != nil {
+			return nil, nil, errors.Wrapf(err, "failed to read verification keys file %s", filePath)
+		}
+		filePublicKeys, fileCertificates, err := ParsePEMVerificationKeys(pemBytes)
+		if err != nil {
+			return nil, nil, errors.Wrapf(err, "failed to parse verification keys file %s", filePath)
+		}
+		publicKeys = append(publicKeys, filePublicKeys...)
+		certificates = append(certificates, fileCertificates...)
+	}
+	return publicKeys, certificates, nil
+}
+
+// ParsePEMVerificationKeys parses all PEM blocks of the given bytes to public keys and certificates.
+// Returns an error on PEM blocks that are neither a public key nor a certificate.
+func ParsePEMVerificationKeys(pemBytes []byte) ([]crypto.PublicKey, []*x509.Certificate, error) {
+	var publicKeys []crypto.PublicKey
+	var certificates []*x509.Certificate
+	for {
+		var block *pem.Block
+		block, pemBytes = pem.Decode(pemBytes)
+		if block == nil {
+			break
+		}
+
+		switch block.Type {
+		case _pemPublicKeyType:
+			publicKey, err := x509.ParsePKIXPublicKey(block.Bytes)
+			if err != nil {
+				return nil, nil, errors.Wrap(err, "failed to parse public key")
+			}
+			publicKeys = append(publicKeys, publicKey)
+		case _pemCertificateType:
+			certificate, err := x509.ParseCertificate(block.Bytes)
+			if err != nil {
+				return nil, nil, errors.Wrap(err, "failed to parse certificate")
+			}
+			certificates = append(certificates, certificate)
+		default:
+			return nil, nil, errors.Errorf("unsupported PEM block type %s", block.Type)
+		}
+	}
+	return publicKeys, certificates, nil
+}
+
+// IsEmpty returns true if there are no trusted public keys or certificates
+func (keys *VerificationKeys) IsEmpty() bool {
+	return len(keys.publicKeys) == 0
+}
+
+// VerifySignature returns true if the signature of the payload is verified by one of the trusted public keys,
+// or by the attached certificate (PEM encoded, may be empty) in case that it's a code signing certificate that is issued
+// by one of the trusted certificates.
+// The certificate chain is verified at the current time, unless the signature has an attached transparency log bundle
+// (may be empty) that is signed by one of the trusted transparency logs - then it's verified at the time that the signature
+// was integrated into the log, so short-lived certificates (keyless signing) are verified as well.
+func (keys *VerificationKeys) VerifySignature(payload []byte, signature []byte, attachedCertificatePEM string, attachedBundle string) bool {
+	for _, publicKey := range keys.publicKeys {
+		if verifyWithPublicKey(publicKey, payload, signature) {
+			return true
+		}
+	}
+
+	if attachedCertificatePEM == "" || !keys.hasCertificates {
+		return false
+	}
+	block, _ := pem.Decode([]byte(attachedCertificatePEM))
+	if block == nil || block.Type != _pemCertificateType {
+		return false
+	}
+	certificate, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return false
+	}
+	// Certificates without extended key usage are valid for any usage by x509, so code signing is checked explicitly.
+	if !hasExtKeyUsage(certificate, x509.ExtKeyUsageCodeSigning) {
+		return false
+	}
+
+	verificationTime := time.Now()
+	if integratedTime, ok := keys.getTrustedIntegratedTime(attachedBundle, payload, signature, certificate); ok {
+		verificationTime = integratedTime
+	}
+	_, err = certificate.Verify(x509.VerifyOptions{
+		Roots:       keys.certificatesPool,
+		CurrentTime: verificationTime,
+		KeyUsages:   []x509.ExtKeyUsage{x509.ExtKeyUsageCodeSigning},
+	})
+	if err != nil {
+		return false
+	}
+	return verifyWithPublicKey(certificate.PublicKey, payload, signature)
+}
+
+// getTrustedIntegratedTime returns the time that the signature was integrated into the transparency log, in case that
+// the bundle is signed by one of the trusted transparency logs and its entry is of the signature, the payload and the certificate.
+func (keys *VerificationKeys) getTrustedIntegratedTime(attachedBundle string, payload []byte, signature []byte, certificate *x509.Certificate) (time.Time, bool) {
+	if attachedBundle == "" || len(keys.transparencyLogPublicKeys) == 0 {
+		return time.Time{}, false
+	}
+	var bundle rekorBundle
+	if err := json.Unmarshal([]byte(attachedBundle), &bundle); err != nil {
+		return time.Time{}, false
+	}
+
+	// The signed entry timestamp is the signature of the canonical JSON of the payload (sorted keys, no HTML escaping)
+	canonicalPayload := new(bytes.Buffer)
+	encoder := json.NewEncoder(canonicalPayload)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(bundle.Payload); err != nil {
+		return time.Time{}, false
+	}
+	signedPayload := bytes.TrimSuffix(canonicalPayload.Bytes(), []byte("\n"))
+	isSignedByTransparencyLog := false
+	for _, publicKey := range keys.transparencyLogPublicKeys {
+		if verifyWithPublicKey(publicKey, signedPayload, bundle.SignedEntryTimestamp) {
+			isSignedByTransparencyLog = true
+			break
+		}
+	}
+	if !isSignedByTransparencyLog {
+		return time.Time{}, false
+	}
+
+	// The entry must be of this signature - otherwise any entry of the log could be attached
+	if !isEntryOfSignature(bundle.Payload.Body, payload, signature, certificate) {
+		return time.Time{}, false
+	}
+	return time.Unix(bundle.Payload.IntegratedTime, 0), true
+}
+
+// isEntryOfSignature returns true if the base64 encoded hashedrekord entry is of the signature of the payload by the certificate
+func isEntryOfSignature(encodedBody string, payload []byte, signature []byte, certificate *x509.Certificate) bool {
+	bodyBytes, err := base64.StdEncoding.DecodeString(encodedBody)
+	if err != nil {
+		return false
+	}
+	var body rekorEntryBody
+	if err := json.Unmarshal(bodyBytes, &body); err != nil || body.Kind != _rekorHashedRekordKind {
+		return false
+	}
+
+	payloadHash := sha256.Sum256(payload)
+	if body.Spec.Data.Hash.Algorithm != _rekorSHA256Algorithm || body.Spec.Data.Hash.Value != hex.EncodeToString(payloadHash[:]) {
+		return false
+	}
+	entrySignature, err := base64.StdEncoding.DecodeString(body.Spec.Signature.Content)
+	if err != nil || !bytes.Equal(entrySignature, signature) {
+		return false
+	}
+	entryCertificatePEM, err := base64.StdEncoding.DecodeString(body.Spec.Signature.PublicKey.Content)
+	if err != nil {
+		return false
+	}
+	block, _ := pem.Decode(entryCertificatePEM)
+	return block != nil && bytes.Equal(block.Bytes, certificate.Raw)
+}
+
+// hasExtKeyUsage returns true if the extended key usages of the certificate contain the usage
+func hasExtKeyUsage(certificate *x509.Certificate, usage x509.ExtKeyUsage) bool {
+	for _, extKeyUsage := range certificate.ExtKeyUsage {
+		if extKeyUsage == usage {
+			return true
+		}
+	}
+	return false
+}
+
+// verifyWithPublicKey verifies the signature of the payload using the public key (ECDSA, RSA or Ed25519)
+func verifyWithPublicKey(publicKey crypto.PublicKey, payload []byte, signature []byte) bool {
+	hash := sha256.Sum256(payload)
+	switch typedPublicKey := publicKey.(type) {
+	case *ecdsa.PublicKey:
+		return ecdsa.VerifyASN1(typedPublicKey, hash[:], signature)
+	case *rsa.PublicKey:
+		if rsa.VerifyPKCS1v15(typedPublicKey, crypto.SHA256, hash[:], signature) == nil {
+			return true
+		}
+		return rsa.VerifyPSS(typedPublicKey, crypto.SHA256, hash[:], signature, nil) == nil
+	case ed25519.PublicKey:
+		return ed25519.Verify(typedPublicKey, payload, signature)
+	default:
+		return false
+	}
+}
@@ -0,0 +1,275 @@
+package signature
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"github.com/stretchr/testify/suite"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+var _payload = []byte("payload")
+
+type VerificationKeysTestSuite struct {
+	suite.Suite
+	ecdsaKey *ecdsa.PrivateKey
+	rsaKey   *rsa.PrivateKey
+}
+
+func (suite *VerificationKeysTestSuite) SetupTest() {
+	var err error
+	suite.ecdsaKey, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	suite.Nil(err)
+	suite.rsaKey, err = rsa.GenerateKey(rand.Reader, 2048)
+	suite.Nil(err)
+}
+
+func (suite *VerificationKeysTestSuite) Test_ParsePEMVerificationKeys_PublicKeysAndCertificate() {
+	pemBytes := append(suite.encodePublicKey(&suite.ecdsaKey.PublicKey), suite.encodePublicKey(&suite.rsaKey.PublicKey)...)
+	pemBytes = append(pemBytes, suite.encodeCertificate(suite.createCertificate(suite.ecdsaKey, nil, nil))...)
+
+	publicKeys, certificates, err := ParsePEMVerificationKeys(pemBytes)
+
+	suite.Nil(err)
+	suite.Len(publicKeys, 2)
+	suite.Len(certificates, 1)
+}
+
+func (suite *VerificationKeysTestSuite) Test_ParsePEMVerificationKeys_UnsupportedBlock_Error() {
+	privateKeyBytes, err := x509.MarshalECPrivateKey(suite.ecdsaKey)
+	suite.Nil(err)
+
+	_, _, err = ParsePEMVerificationKeys(pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: privateKeyBytes}))
+
+	suite.NotNil(err)
+}
+
+func (suite *VerificationKeysTestSuite) Test_LoadVerificationKeysFromDir_SkipsHiddenFilesAndDirs() {
+	dirPath, err := ioutil.TempDir("", "verification-keys")
+	suite.Nil(err)
+	defer os.RemoveAll(dirPath)
+	suite.Nil(ioutil.WriteFile(filepath.Join(dirPath, "ecdsa.pem"), suite.encodePublicKey(&suite.ecdsaKey.PublicKey), 0600))
+	suite.Nil(ioutil.WriteFile(filepath.Join(dirPath, "..hidden"), []byte("not a pem"), 0600))
+	suite.Nil(os.Mkdir(filepath.Join(dirPath, "..data"), 0700))
+
+	keys, err := LoadVerificationKeysFromDir(dirPath, "")
+
+	suite.Nil(err)
+	suite.False(keys.IsEmpty())
+	suite.True(keys.VerifySignature(_payload, suite.signECDSA(suite.ecdsaKey), "", ""))
+}
+
+func (suite *VerificationKeysTestSuite) Test_LoadVerificationKeysFromDir_DirDoesNotExist_Error() {
+	_, err := LoadVerificationKeysFromDir(filepath.Join(os.TempDir(), "does-not-exist-verification-keys"), "")
+
+	suite.NotNil(err)
+}
+
+func (suite *VerificationKeysTestSuite) Test_VerifySignature_RSAKey() {
+	hash := sha256.Sum256(_payload)
+	signature, err := rsa.SignPKCS1v15(rand.Reader, suite.rsaKey, crypto.SHA256, hash[:])
+	suite.Nil(err)
+	keys := NewVerificationKeys([]crypto.PublicKey{&suite.rsaKey.PublicKey}, nil, nil)
+
+	suite.True(keys.VerifySignature(_payload, signature, "", ""))
+	suite.False(keys.VerifySignature([]byte("another payload"), signature, "", ""))
+}
+
+func (suite *VerificationKeysTestSuite) Test_LoadVerificationKeysFromDir_TransparencyLogKeys() {
+	dirPath, err := ioutil.TempDir("", "verification-keys")
+	suite.Nil(err)
+	defer os.RemoveAll(dirPath)
+	transparencyLogDirPath, err := ioutil.TempDir("", "transparency-log-keys")
+	suite.Nil(err)
+	defer os.RemoveAll(transparencyLogDirPath)
+	suite.Nil(ioutil.WriteFile(filepath.Join(dirPath, "ca.pem"), suite.encodeCertificate(suite.createCertificate(suite.rsaKey, nil, nil)), 0600))
+	suite.Nil(ioutil.WriteFile(filepath.Join(transparencyLogDirPath, "rekor.pem"), suite.encodePublicKey(&suite.ecdsaKey.PublicKey), 0600))
+
+	keys, err := LoadVerificationKeysFromDir(dirPath, transparencyLogDirPath)
+
+	suite.Nil(err)
+	suite.Len(keys.transparencyLogPublicKeys, 1)
+	// Transparency log keys aren't trusted to sign images
+	suite.False(keys.VerifySignature(_payload, suite.signECDSA(suite.ecdsaKey), "", ""))
+}
+
+func (suite *VerificationKeysTestSuite) Test_LoadVerificationKeysFromDir_CertificateInTransparencyLogKeys_Error() {
+	dirPath, err := ioutil.TempDir("", "verification-keys")
+	suite.Nil(err)
+	defer os.RemoveAll(dirPath)
+	suite.Nil(ioutil.WriteFile(filepath.Join(dirPath, "ca.pem"), suite.encodeCertificate(suite.createCertificate(suite.rsaKey, nil, nil)), 0600))
+
+	_, err = LoadVerificationKeysFromDir(dirPath, dirPath)
+
+	suite.NotNil(err)
+}
+
+func (suite *VerificationKeysTestSuite) Test_VerifySignature_AttachedCertificateIssuedByTrustedCertificate() {
+	caCertificate := suite.createCertificate(suite.rsaKey, nil, nil)
+	signingKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	suite.Nil(err)
+	signingCertificate := suite.createSigningCertificate(signingKey, caCertificate, time.Now().Add(10*time.Minute), x509.ExtKeyUsageCodeSigning)
+	signature := suite.signECDSA(signingKey)
+
+	trustedKeys := NewVerificationKeys(nil, []*x509.Certificate{caCertificate}, nil)
+	untrustedKeys := NewVerificationKeys([]crypto.PublicKey{&suite.ecdsaKey.PublicKey}, nil, nil)
+
+	suite.True(trustedKeys.VerifySignature(_payload, signature, string(suite.encodeCertificate(signingCertificate)), ""))
+	suite.False(trustedKeys.VerifySignature(_payload, signature, "", ""))
+	suite.False(untrustedKeys.VerifySignature(_payload, signature, string(suite.encodeCertificate(signingCertificate)), ""))
+}
+
+func (suite *VerificationKeysTestSuite) Test_VerifySignature_ExpiredCertificate_Rejected() {
+	caCertificate := suite.createCertificate(suite.rsaKey, nil, nil)
+	signingKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	suite.Nil(err)
+	signingCertificate := suite.createSigningCertificate(signingKey, caCertificate, time.Now().Add(-10*time.Minute), x509.ExtKeyUsageCodeSigning)
+	keys := NewVerificationKeys(nil, []*x509.Certificate{caCertificate}, nil)
+
+	suite.False(keys.VerifySignature(_payload, suite.signECDSA(signingKey), string(suite.encodeCertificate(signingCertificate)), ""))
+}
+
+func (suite *VerificationKeysTestSuite) Test_VerifySignature_NonCodeSigningCertificate_Rejected() {
+	caCertificate := suite.createCertificate(suite.rsaKey, nil, nil)
+	signingKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	suite.Nil(err)
+	serverAuthCertificate := suite.createSigningCertificate(signingKey, caCertificate, time.Now().Add(10*time.Minute), x509.ExtKeyUsageServerAuth)
+	noExtKeyUsageCertificate := suite.createSigningCertificate(signingKey, caCertificate, time.Now().Add(10*time.Minute))
+	keys := NewVerificationKeys(nil, []*x509.Certificate{caCertificate}, nil)
+	signature := suite.signECDSA(signingKey)
+
+	suite.False(keys.VerifySignature(_payload, signature, string(suite.encodeCertificate(serverAuthCertificate)), ""))
+	suite.False(keys.VerifySignature(_payload, signature, string(suite.encodeCertificate(noExtKeyUsageCertificate)), ""))
+}
+
+func (suite *VerificationKeysTestSuite) Test_VerifySignature_ExpiredCertificateWithTrustedBundle_VerifiedAtIntegratedTime() {
+	caCertificate := suite.createCertificate(suite.rsaKey, nil, nil)
+	signingKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	suite.Nil(err)
+	signingCertificate := suite.createSigningCertificate(signingKey, caCertificate, time.Now().Add(-10*time.Minute), x509.ExtKeyUsageCodeSigning)
+	signingCertificatePEM := string(suite.encodeCertificate(signingCertificate))
+	signature := suite.signECDSA(signingKey)
+	transparencyLogKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	suite.Nil(err)
+	keys := NewVerificationKeys(nil, []*x509.Certificate{caCertificate}, []crypto.PublicKey{&transparencyLogKey.PublicKey})
+	// Integrated while the certificate was valid
+	integratedTime := time.Now().Add(-30 * time.Minute)
+
+	suite.True(keys.VerifySignature(_payload, signature, signingCertificatePEM, suite.createBundle(transparencyLogKey, integratedTime, signature, signingCertificatePEM)))
+	// Integrated after the certificate expired
+	suite.False(keys.VerifySignature(_payload, signature, signingCertificatePEM, suite.createBundle(transparencyLogKey, time.Now(), signature, signingCertificatePEM)))
+	// Bundle of another signature
+	otherSignature := suite.signECDSA(signingKey)
+	suite.False(keys.VerifySignature(_payload, signature, signingCertificatePEM, suite.createBundle(transparencyLogKey, integratedTime, otherSignature, signingCertificatePEM)))
+	// Bundle that isn't signed by a trusted transparency log
+	suite.False(keys.VerifySignature(_payload, signature, signingCertificatePEM, suite.createBundle(suite.ecdsaKey, integratedTime, signature, signingCertificatePEM)))
+	// Tampered integrated time
+	now := time.Now()
+	bundle := suite.createBundle(transparencyLogKey, now, signature, signingCertificatePEM)
+	tamperedBundle := strings.Replace(bundle, fmt.Sprintf(`"integratedTime":%d`, now.Unix()), fmt.Sprintf(`"integratedTime":%d`, integratedTime.Unix()), 1)
+	suite.NotEqual(bundle, tamperedBundle)
+	suite.False(keys.VerifySignature(_payload, signature, signingCertificatePEM, tamperedBundle))
+}
+
+// createBundle creates a transparency log bundle of the signature that is signed by the key of the transparency log
+func (suite *VerificationKeysTestSuite) createBundle(transparencyLogKey *ecdsa.PrivateKey, integratedTime time.Time, signature []byte, certificatePEM string) string {
+	body := rekorEntryBody{Kind: _rekorHashedRekordKind}
+	payloadHash := sha256.Sum256(_payload)
+	body.Spec.Data.Hash.Algorithm = _rekorSHA256Algorithm
+	body.Spec.Data.Hash.Value = hex.EncodeToString(payloadHash[:])
+	body.Spec.Signature.Content = base64.StdEncoding.EncodeToString(signature)
+	body.Spec.Signature.PublicKey.Content = base64.StdEncoding.EncodeToString([]byte(certificatePEM))
+	bodyBytes, err := json.Marshal(body)
+	suite.Nil(err)
+
+	payload := rekorBundlePayload{
+		Body:           base64.StdEncoding.EncodeToString(bodyBytes),
+		IntegratedTime: integratedTime.Unix(),
+		LogID:          "c0d23d6ad406973f9559f3ba2d1ca01f84147d8ffc5b8445c224f98b9591801d",
+		LogIndex:       1,
+	}
+	canonicalPayload, err := json.Marshal(payload)
+	suite.Nil(err)
+	hash := sha256.Sum256(canonicalPayload)
+	signedEntryTimestamp, err := ecdsa.SignASN1(rand.Reader, transparencyLogKey, hash[:])
+	suite.Nil(err)
+
+	bundle, err := json.Marshal(rekorBundle{SignedEntryTimestamp: signedEntryTimestamp, Payload: payload})
+	suite.Nil(err)
+	return string(bundle)
+}
+
+func (suite *VerificationKeysTestSuite) signECDSA(key *ecdsa.PrivateKey) []byte {
+	hash := sha256.Sum256(_payload)
+	signature, err := ecdsa.SignASN1(rand.Reader, key, hash[:])
+	suite.Nil(err)
+	return signature
+}
+
+func (suite *VerificationKeysTestSuite) encodePublicKey(publicKey crypto.PublicKey) []byte {
+	publicKeyBytes, err := x509.MarshalPKIXPublicKey(publicKey)
+	suite.Nil(err)
+	return pem.EncodeToMemory(&pem.Block{Type: _pemPublicKeyType, Bytes: publicKeyBytes})
+}
+
+func (suite *VerificationKeysTestSuite) encodeCertificate(certificate *x509.Certificate) []byte {
+	return pem.EncodeToMemory(&pem.Block{Type: _pemCertificateType, Bytes: certificate.Raw})
+}
+
+// createCertificate creates a certificate of the key. If parent is nil, the certificate is a self signed CA certificate.
+func (suite *VerificationKeysTestSuite) createCertificate(key crypto.Signer, parent *x509.Certificate, parentKey crypto.Signer) *x509.Certificate {
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(time.Now().UnixNano()),
+		Subject:               pkix.Name{CommonName: "signer"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(10 * time.Minute),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  parent == nil,
+	}
+	if parent == nil {
+		parent, parentKey = template, key
+	}
+	certificateBytes, err := x509.CreateCertificate(rand.Reader, template, parent, key.Public(), parentKey)
+	suite.Nil(err)
+	certificate, err := x509.ParseCertificate(certificateBytes)
+	suite.Nil(err)
+	return certificate
+}
+
+// createSigningCertificate creates a leaf certificate of the key that is issued by the CA certificate (of suite.rsaKey)
+func (suite *VerificationKeysTestSuite) createSigningCertificate(key crypto.Signer, caCertificate *x509.Certificate, notAfter time.Time, extKeyUsages ...x509.ExtKeyUsage) *x509.Certificate {
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: "signer"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     notAfter,
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  extKeyUsages,
+	}
+	certificateBytes, err := x509.CreateCertificate(rand.Reader, template, caCertificate, key.Public(), suite.rsaKey)
+	suite.Nil(err)
+	certificate, err := x509.ParseCertificate(certificateBytes)
+	suite.Nil(err)
+	return certificate
+}
+
+func TestVerificationKeysTestSuite(t *testing.T) {
+	suite.Run(t, new(VerificationKeysTestSuite))
+}
@@ -3,6 +3,8 @@
 package mocks
 
 import (
+	context "context"
+
 	registry "github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/registry"
 	tag2digest "github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/tag2digest"
 	mock "github.com/stretchr/testify/mock"
@@ -13,20 +15,20 @@ type ITag2DigestResolver struct {
 	mock.Mock
 }
 
-// Resolve provides a mock function with given fields: imageReference, authContext
-func (_m *ITag2DigestResolver) Resolve(imageReference registry.IImageReference, authContext *tag2digest.ResourceContext) (string, error) {
-	ret := _m.Called(imageReference, authContext)
+// Resolve provides a mock function with given fields: ctx, imageReference, authContext
+func (_m *ITag2DigestResolver) Resolve(ctx context.Context, imageReference registry.IImageReference, authContext *tag2digest.ResourceContext) (string, error) {
+	ret := _m.Called(ctx, imageReference, authContext)
 
 	var r0 string
-	if rf, ok := ret.Get(0).(func(registry.IImageReference, *tag2digest.ResourceContext) string); ok {
-		r0 = rf(imageReference, authContext)
+	if rf, ok := ret.Get(0).(func(context.Context, registry.IImageReference, *tag2digest.ResourceContext) string); ok {
+		r0 = rf(ctx, imageReference, authContext)
 	} else {
 		r0 = ret.Get(0).(string)
 	}
 
 	var r1 error
-	if rf, ok := ret.Get(1).(func(registry.IImageReference, *tag2digest.ResourceContext) error); ok {
-		r1 = rf(imageReference, authContext)
+	if rf, ok := ret.Get(1).(func(context.Context, registry.IImageReference, *tag2digest.ResourceContext) error); ok {
+		r1 = rf(ctx, imageReference, authContext)
 	} else {
 		r1 = ret.Error(1)
 	}
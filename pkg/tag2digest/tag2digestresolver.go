@@ -1,7 +1,11 @@
 package tag2digest
 
 import (
+	"context"
+	"fmt"
+	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/decisionlog"
 	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/cache"
+	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/coalescing"
 	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/instrumentation"
 	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/instrumentation/metric"
 	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/instrumentation/metric/util"
@@ -11,12 +15,20 @@ import (
 	registryutils "github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/registry/utils"
 	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/utils"
 	"github.com/pkg/errors"
+	"strings"
+	"time"
+)
+
+const (
+	// _unauthorizedPrefixForCacheKey is a prefix for the cache key of unauthorized errors. Unauthorized errors depend on the
+	// resource context (namespace, pull secrets and service account), so unlike digests and other errors they are not cached by the image only.
+	_unauthorizedPrefixForCacheKey = "tag2digestUnauthorized"
 )
 
 // ITag2DigestResolver responsible to resolve resource's image to it's digest
 type ITag2DigestResolver interface {
 	// Resolve receives an image reference and the resource deployed context and resturns image digest
-	Resolve(imageReference registry.IImageReference, authContext *ResourceContext) (string, error)
+	Resolve(ctx context.Context, imageReference registry.IImageReference, authContext *ResourceContext) (string, error)
 }
 
 // Tag2DigestResolver implements ITag2DigestResolver interface
@@ -32,43 +44,70 @@ type Tag2DigestResolver struct {
 	registryClient registry.IRegistryClient
 	// cacheClient is a cache for mapping image full name to its digest
 	cacheClient cache.ICacheClient
-	// tag2DigestResolverConfiguration is configuration data for Tag2DigestResolver
-	tag2DigestResolverConfiguration *Tag2DigestResolverConfiguration
+	// getConfiguration returns the current configuration of the resolver - the configuration that it was created with,
+	// or the reloaded configuration in case that a configuration provider is set.
+	getConfiguration func() *Tag2DigestResolverConfiguration
+	// coalescer coalesces concurrent resolutions of the same image and resource context
+	coalescer coalescing.IRequestCoalescer
 }
 
 // Tag2DigestResolverConfiguration is configuration data for Tag2DigestResolver
 type Tag2DigestResolverConfiguration struct {
 	// cacheExpirationTime is the expiration time **IN MINUTES** for digests in the cache client
 	CacheExpirationTimeForResults int
+	// CacheExpirationTimeForImageIsNotFoundErr is the expiration time **IN SECONDS** for image is not found errors in the cache client. If zero - the error isn't cached.
+	CacheExpirationTimeForImageIsNotFoundErr int
+	// CacheExpirationTimeForRegistryIsNotFoundErr is the expiration time **IN SECONDS** for registry is not found errors in the cache client. If zero - the error isn't cached.
+	CacheExpirationTimeForRegistryIsNotFoundErr int
+	// CacheExpirationTimeForUnauthorizedErr is the expiration time **IN SECONDS** for unauthorized errors in the cache client. If zero - the error isn't cached.
+	CacheExpirationTimeForUnauthorizedErr int
+	// CacheExpirationTimeForRegistryTLSErr is the expiration time **IN SECONDS** for registry TLS errors in the cache client. If zero - the error isn't cached.
+	CacheExpirationTimeForRegistryTLSErr int
 }
 
 // NewTag2DigestResolver Ctor
-func NewTag2DigestResolver(instrumentationProvider instrumentation.IInstrumentationProvider, registryClient registry.IRegistryClient, cacheClient cache.ICacheClient, tag2DigestResolverConfiguration *Tag2DigestResolverConfiguration) *Tag2DigestResolver {
+func NewTag2DigestResolver(instrumentationProvider instrumentation.IInstrumentationProvider, registryClient registry.IRegistryClient, cacheClient cache.ICacheClient, tag2DigestResolverConfiguration *Tag2DigestResolverConfiguration, coalescer coalescing.IRequestCoalescer) *Tag2DigestResolver {
 	return &Tag2DigestResolver{
-		tracerProvider:                  instrumentationProvider.GetTracerProvider("Tag2DigestResolver"),
-		metricSubmitter:                 instrumentationProvider.GetMetricSubmitter(),
-		registryClient:                  registryClient,
-		cacheClient:                     cacheClient,
-		tag2DigestResolverConfiguration: tag2DigestResolverConfiguration,
+		tracerProvider:   instrumentationProvider.GetTracerProvider("Tag2DigestResolver"),
+		metricSubmitter:  instrumentationProvider.GetMetricSubmitter(),
+		registryClient:   registryClient,
+		cacheClient:      cacheClient,
+		getConfiguration: func() *Tag2DigestResolverConfiguration { return tag2DigestResolverConfiguration },
+		coalescer:        coalescer,
 	}
 }
 
+// SetConfigurationProvider makes the resolver read its configuration from the provider on each use (e.g. from the
+// reloaded configuration), so the resolutions after a reload use the new configuration. Should be called before the resolver is used.
+func (resolver *Tag2DigestResolver) SetConfigurationProvider(getConfiguration func() *Tag2DigestResolverConfiguration) {
+	resolver.getConfiguration = getConfiguration
+}
+
 // Resolve receives an image reference and the resource deployed context and returns image digest
 // Saves digest in cache. The format is key - image original name, value - digest
-func (resolver *Tag2DigestResolver) Resolve(imageReference registry.IImageReference, resourceCtx *ResourceContext) (string, error) {
+// Known registry errors are saved in cache as well (with their own expiration time), so the auth chain isn't walked again
+// for images that failed to be resolved. The format is key - image original name (or unauthorized key of the resource context),
+// value - the unscanned reason of the error.
+func (resolver *Tag2DigestResolver) Resolve(ctx context.Context, imageReference registry.IImageReference, resourceCtx *ResourceContext) (string, error) {
 	tracer := resolver.tracerProvider.GetTracer("Resolve")
+	ctx, span := resolver.tracerProvider.StartSpan(ctx, "Resolve")
+	defer span.End()
 	tracer.Info("Received:", "imageReference", imageReference, "resourceCtx", resourceCtx)
 
 	// Argument validation
 	if imageReference == nil || resourceCtx == nil {
 		err := errors.Wrap(utils.NilArgumentError, "Tag2DigestResolver.Resolve")
+		span.RecordError(err)
 		tracer.Error(err, "")
 		resolver.metricSubmitter.SendMetric(1, util.NewErrorEncounteredMetric(err, "Tag2DigestResolver.Resolve"))
 		return "", err
 	}
 
+	span.SetAttributes("image", imageReference.Original())
+
 	// Try to get digest from cache
-	digest, err := resolver.getDigestFromCache(imageReference)
+	digest, errorStoredInCache, err := resolver.getDigestFromCache(ctx, imageReference, resourceCtx)
+	decisionlog.AddCacheLookup(ctx, decisionlog.Tag2DigestResolverCacheLayer, imageReference.Original(), err == nil)
 	if err != nil { // Couldn't get digest from cache - skip and get results from provider
 		if cache.IsMissingKeyCacheError(err){
 			tracer.Info("Missing key. Couldn't get digest from cache: Image not in cache", "digest", digest)
@@ -77,27 +116,50 @@ func (resolver *Tag2DigestResolver) Resolve(imageReference registry.IImageRefere
 			tracer.Error(err, "")
 			resolver.metricSubmitter.SendMetric(1, util.NewErrorEncounteredMetric(err, "Tag2DigestResolver.Resolve"))
 		}
+	} else if errorStoredInCache != nil { // Known error exist in cache
+		tracer.Info("got error from cache", "errorStoredInCache", errorStoredInCache)
+		span.SetAttributes("fromCache", true)
+		span.RecordError(errorStoredInCache)
+		return "", errors.Wrap(errorStoredInCache, "Tag2DigestResolver.Resolve: got error from cache")
 	} else { // Key exist in cache
 		tracer.Info("got digest from cache")
+		span.SetAttributes("fromCache", true)
 		return digest, nil
 	}
 
-	// Get digest
-	digest, err = resolver.getDigest(imageReference, resourceCtx)
+	// Get digest - concurrent resolutions of the same image and resource context are coalesced, so only one of them reaches the registry
+	span.SetAttributes("fromCache", false)
+	digestValue, err := resolver.coalescer.Do(ctx, resolver.getCoalescingKey(imageReference, resourceCtx), func() (interface{}, error) {
+		return resolver.resolveDigestAndSetInCache(ctx, imageReference, resourceCtx)
+	})
+	if err != nil {
+		span.RecordError(err)
+		return "", err
+	}
+	return digestValue.(string), nil
+}
+
+// resolveDigestAndSetInCache gets the digest from the registry and saves the digest (or the known error) in cache
+func (resolver *Tag2DigestResolver) resolveDigestAndSetInCache(ctx context.Context, imageReference registry.IImageReference, resourceCtx *ResourceContext) (string, error) {
+	tracer := resolver.tracerProvider.GetTracer("resolveDigestAndSetInCache")
+	digest, err := resolver.getDigest(ctx, imageReference, resourceCtx)
 	if err != nil {
 		err = errors.Wrap(err, "Failed to get digest")
 		tracer.Error(err, "")
-		resolver.metricSubmitter.SendMetric(1, util.NewErrorEncounteredMetric(err, "Tag2DigestResolver.Resolve"))
+		resolver.metricSubmitter.SendMetric(1, util.NewErrorEncounteredMetric(err, "Tag2DigestResolver.resolveDigestAndSetInCache"))
+		// Save known error in cache - the set isn't bound to ctx's cancellation, so it's completed even if the request is done
+		go resolver.setErrorInCache(utils.NewDetachedContext(ctx), imageReference, resourceCtx, err)
 		return "", err
 	}
 
-	// Save digest in cache
+	// Save digest in cache - the set isn't bound to ctx's cancellation, so it's completed even if the request is done
+	cacheCtx := utils.NewDetachedContext(ctx)
 	go func() {
-		err := resolver.cacheClient.Set(imageReference.Original(), digest, utils.GetMinutes(resolver.tag2DigestResolverConfiguration.CacheExpirationTimeForResults))
+		err := resolver.cacheClient.Set(cacheCtx, imageReference.Original(), digest, utils.GetMinutes(resolver.getConfiguration().CacheExpirationTimeForResults))
 		if err != nil {
-			err = errors.Wrap(err, "Tag2DigestResolver.Resolve: Failed to set digest in cache")
+			err = errors.Wrap(err, "Tag2DigestResolver.resolveDigestAndSetInCache: Failed to set digest in cache")
 			tracer.Error(err, "")
-			resolver.metricSubmitter.SendMetric(1, util.NewErrorEncounteredMetric(err, "Tag2DigestResolver.Resolve"))
+			resolver.metricSubmitter.SendMetric(1, util.NewErrorEncounteredMetric(err, "Tag2DigestResolver.resolveDigestAndSetInCache"))
 		} else {
 			tracer.Info("Set digest in cache successfully", "image", imageReference.Original(), "digest", digest)
 		}
@@ -108,35 +170,105 @@ func (resolver *Tag2DigestResolver) Resolve(imageReference registry.IImageRefere
 
 // getDigestFromCache try to get digest from cache
 // The cache mapping image to digest or to known errors.
-// If the image exist in cache - return the value (digest or error) and a flag _gotResultsFromCache
-// If the image don't exist in cache or any other unknown error occurred - return "", nil and _didntGetResultsFromCache
-func (resolver *Tag2DigestResolver) getDigestFromCache(imageReference registry.IImageReference) (string, error) {
+// Returns:
+// string - the digest if digest was STORED in cache, otherwise ""
+// error - If known error was STORED in cache (for the image or for the image and the resource context), otherwise nil
+// error - If the image isn't in cache (cache.MissingKeyCacheError) or any other error with the cache functionality
+func (resolver *Tag2DigestResolver) getDigestFromCache(ctx context.Context, imageReference registry.IImageReference, resourceCtx *ResourceContext) (string, error, error) {
 	tracer := resolver.tracerProvider.GetTracer("getDigestFromCache")
 	// First check if we can get digest from cache
 	// Error as a result of key doesn't exist and error from the cache are treated the same (skip cache)
-	digestFromCache, err := resolver.cacheClient.Get(imageReference.Original())
+	valueFromCache, err := resolver.cacheClient.Get(ctx, imageReference.Original())
 	// If key dont exist in cache
 	if err != nil {
-		if cache.IsMissingKeyCacheError(err) {
-			tracer.Info("Missing key. Image as key is not in cache", "image", imageReference.Original())
-			return "", err
+		if !cache.IsMissingKeyCacheError(err) {
+			err = errors.Wrap(err, "Digest as value don't exist in cache or there is an error in cache functionality")
+			tracer.Error(err, "")
+			resolver.metricSubmitter.SendMetric(1, util.NewErrorEncounteredMetric(err, "Tag2DigestResolver.getDigestFromCache"))
+			return "", nil, err
 		}
-		err = errors.Wrap(err, "Digest as value don't exist in cache or there is an error in cache functionality")
-		tracer.Error(err, "")
-		resolver.metricSubmitter.SendMetric(1, util.NewErrorEncounteredMetric(err, "Tag2DigestResolver.getDigestFromCache"))
-		return "", err
+		tracer.Info("Missing key. Image as key is not in cache", "image", imageReference.Original())
+
+		// Check if unauthorized error of the resource context is stored in cache
+		valueFromCache, err = resolver.cacheClient.Get(ctx, resolver.getUnauthorizedCacheKey(imageReference, resourceCtx))
+		if err != nil {
+			if cache.IsMissingKeyCacheError(err) {
+				tracer.Info("Missing key. Image and resource context as key is not in cache", "image", imageReference.Original())
+				return "", nil, err
+			}
+			err = errors.Wrap(err, "Unauthorized error as value don't exist in cache or there is an error in cache functionality")
+			tracer.Error(err, "")
+			resolver.metricSubmitter.SendMetric(1, util.NewErrorEncounteredMetric(err, "Tag2DigestResolver.getDigestFromCache"))
+			return "", nil, err
+		}
+	}
+
+	// A known error found in cache
+	if errorStoredInCache, isErrorStoredInCache := registryerrors.TryParseStringToUnscannedWithReasonErr(valueFromCache); isErrorStoredInCache {
+		tracer.Info("Error exist in cache", "image", imageReference.Original(), "error", valueFromCache)
+		return "", errorStoredInCache, nil
 	}
 
 	// A valid digest found in cache
-	tracer.Info("Digest exist in cache", "image", imageReference.Original(), "digest", digestFromCache)
-	return digestFromCache, nil
+	tracer.Info("Digest exist in cache", "image", imageReference.Original(), "digest", valueFromCache)
+	return valueFromCache, nil, nil
+}
+
+// setErrorInCache saves known registry error in cache as its unscanned reason, so it can be restored by
+// registryerrors.TryParseStringToUnscannedWithReasonErr. Unknown errors and errors that their expiration time is zero aren't saved.
+func (resolver *Tag2DigestResolver) setErrorInCache(ctx context.Context, imageReference registry.IImageReference, resourceCtx *ResourceContext, err error) {
+	tracer := resolver.tracerProvider.GetTracer("setErrorInCache")
+
+	unscannedReason, isKnownError := registryerrors.TryParseErrToUnscannedWithReason(err)
+	if !isKnownError {
+		tracer.Info("Unknown error - not saved in cache", "image", imageReference.Original())
+		return
+	}
+
+	key := imageReference.Original()
+	configuration := resolver.getConfiguration()
+	var expirationTime time.Duration
+	switch errors.Cause(err).(type) {
+	case *registryerrors.ImageIsNotFoundErr:
+		expirationTime = utils.GetSeconds(configuration.CacheExpirationTimeForImageIsNotFoundErr)
+	case *registryerrors.RegistryIsNotFoundErr:
+		expirationTime = utils.GetSeconds(configuration.CacheExpirationTimeForRegistryIsNotFoundErr)
+	case *registryerrors.RegistryTLSErr:
+		expirationTime = utils.GetSeconds(configuration.CacheExpirationTimeForRegistryTLSErr)
+	case *registryerrors.UnauthorizedErr:
+		key = resolver.getUnauthorizedCacheKey(imageReference, resourceCtx)
+		expirationTime = utils.GetSeconds(configuration.CacheExpirationTimeForUnauthorizedErr)
+	}
+	if expirationTime <= 0 {
+		tracer.Info("Caching of error is disabled - not saved in cache", "image", imageReference.Original(), "unscannedReason", *unscannedReason)
+		return
+	}
+
+	if err := resolver.cacheClient.Set(ctx, key, string(*unscannedReason), expirationTime); err != nil {
+		err = errors.Wrap(err, "Tag2DigestResolver.setErrorInCache: Failed to set error in cache")
+		tracer.Error(err, "")
+		resolver.metricSubmitter.SendMetric(1, util.NewErrorEncounteredMetric(err, "Tag2DigestResolver.setErrorInCache"))
+		return
+	}
+	tracer.Info("Set error in cache successfully", "image", imageReference.Original(), "unscannedReason", *unscannedReason)
+}
+
+// getCoalescingKey returns the key that concurrent resolutions are coalesced by. The resolution depends on the credentials
+// of the resource context, so only resolutions of the same image and resource context are coalesced.
+func (resolver *Tag2DigestResolver) getCoalescingKey(imageReference registry.IImageReference, resourceCtx *ResourceContext) string {
+	return fmt.Sprintf("%s:%s:%s:%s", imageReference.Original(), resourceCtx.namespace, strings.Join(resourceCtx.imagePullSecrets, ","), resourceCtx.serviceAccountName)
+}
+
+// getUnauthorizedCacheKey returns the cache key of unauthorized error of the image and the resource context
+func (resolver *Tag2DigestResolver) getUnauthorizedCacheKey(imageReference registry.IImageReference, resourceCtx *ResourceContext) string {
+	return fmt.Sprintf("%s:%s:%s:%s:%s", _unauthorizedPrefixForCacheKey, imageReference.Original(), resourceCtx.namespace, strings.Join(resourceCtx.imagePullSecrets, ","), resourceCtx.serviceAccountName)
 }
 
 // getDigest receives an image refernce and the resource deployed context and returns image digest
 // It tries to see if it's a digest based image reference - if so, extract its digest
 // Then if it ACR based registry - tries to get digest using registry client's ACR attach auth method
 // If above fails or not applicable - tries to get digest using registry client's k8s auth method.
-func (resolver *Tag2DigestResolver) getDigest(imageReference registry.IImageReference, resourceCtx *ResourceContext) (string, error) {
+func (resolver *Tag2DigestResolver) getDigest(ctx context.Context, imageReference registry.IImageReference, resourceCtx *ResourceContext) (string, error) {
 	tracer := resolver.tracerProvider.GetTracer("getDigest")
 	tracer.Info("Received:", "imageReference", imageReference, "resourceCtx", resourceCtx)
 
@@ -147,11 +279,14 @@ func (resolver *Tag2DigestResolver) getDigest(imageReference registry.IImageRefe
 		return digestReference.Digest(), nil
 	}
 
+	// acrAttachAuthErr is the error of the ACR attach auth - reported in case that it's the reason that the fallbacks are unauthorized
+	var acrAttachAuthErr error
+
 	// ACR auth
 	if registryutils.IsRegistryEndpointACR(imageReference.Registry()) {
 		tracer.Info("ACR suffix so tries ACR auth", "imageRef", imageReference)
 
-		digest, err := resolver.registryClient.GetDigestUsingACRAttachAuth(imageReference)
+		digest, err := resolver.registryClient.GetDigestUsingACRAttachAuth(ctx, imageReference)
 		if err != nil {
 			// TODO Add tests that checks that we don't try another auth when we should stop.
 			// 		Should be added once @tomerweinberger finished to merge his PR.
@@ -163,6 +298,7 @@ func (resolver *Tag2DigestResolver) getDigest(imageReference registry.IImageRefe
 
 			// Failed to get digest using ACR attach auth method - continue and fall back to other methods
 			tracer.Error(err, "Failed on ACR auth -> continue to other types of auth")
+			acrAttachAuthErr = err
 		} else {
 			//TODO Check if digest is not empty
 			return digest, nil
@@ -173,7 +309,7 @@ func (resolver *Tag2DigestResolver) getDigest(imageReference registry.IImageRefe
 
 	// Fallback to K8S auth
 	// TODO Add fallback on missing pull secret
-	digest, err := resolver.registryClient.GetDigestUsingK8SAuth(imageReference, resourceCtx.namespace, resourceCtx.imagePullSecrets, resourceCtx.serviceAccountName)
+	digest, err := resolver.registryClient.GetDigestUsingK8SAuth(ctx, imageReference, resourceCtx.namespace, resourceCtx.imagePullSecrets, resourceCtx.serviceAccountName)
 	if err != nil {
 		if !resolver.shouldContinueOnError(err) {
 			err = errors.Wrap(err, "Failed to get digest on K8SAuth")
@@ -191,8 +327,12 @@ func (resolver *Tag2DigestResolver) getDigest(imageReference registry.IImageRefe
 	tracer.Info("Tries DefaultAuth", "imageRef", imageReference)
 
 	// Last fallback (default)- if this fail we dont get digest
-	digest, err = resolver.registryClient.GetDigestUsingDefaultAuth(imageReference)
+	digest, err = resolver.registryClient.GetDigestUsingDefaultAuth(ctx, imageReference)
 	if err != nil {
+		// The fallbacks are unauthorized because the identity of the ACR attach auth is unavailable - report the identity failure
+		if resolver.isIdentityUnavailableReason(acrAttachAuthErr, err) {
+			err = acrAttachAuthErr
+		}
 		err = errors.Wrap(err, "Failed to get digest on DefaultAuth")
 		tracer.Error(err, "")
 		resolver.metricSubmitter.SendMetric(1, util.NewErrorEncounteredMetric(err, "Tag2DigestResolver.GetDigest.AllOptionsFailed"))
@@ -225,15 +365,33 @@ func NewResourceContext(namespace string, imagePullSecrets []string, serviceAcco
 	}
 }
 
+// AuthContext returns the registry auth context of the deployed resource
+func (resourceCtx *ResourceContext) AuthContext() *registry.AuthContext {
+	return registry.NewAuthContext(resourceCtx.namespace, resourceCtx.imagePullSecrets, resourceCtx.serviceAccountName)
+}
+
 // shouldContinueOnError is method that gets an error and returns true in case that the error is known
 // error that thr resolve method should stop and don't try more authentications to resolve the digest.
 func (resolver *Tag2DigestResolver) shouldContinueOnError(err error) bool {
 	errorCause := errors.Cause(err)
 	switch errorCause.(type) {
 	case *registryerrors.ImageIsNotFoundErr,
-		*registryerrors.RegistryIsNotFoundErr:
+		*registryerrors.RegistryIsNotFoundErr,
+		*registryerrors.RegistryTLSErr,
+		*registryerrors.DigestResolutionTimeoutErr:
 		return false
 	default:
 		return true
 	}
 }
+
+// isIdentityUnavailableReason returns true in case that the ACR attach auth failed because the identity is unavailable
+// and the fallback auth is unauthorized - the identity failure is the reason that the digest couldn't be resolved.
+func (resolver *Tag2DigestResolver) isIdentityUnavailableReason(acrAttachAuthErr error, fallbackErr error) bool {
+	if acrAttachAuthErr == nil {
+		return false
+	}
+	_, isIdentityUnavailable := errors.Cause(acrAttachAuthErr).(*registryerrors.IdentityUnavailableErr)
+	_, isUnauthorized := errors.Cause(fallbackErr).(*registryerrors.UnauthorizedErr)
+	return isIdentityUnavailable && isUnauthorized
+}
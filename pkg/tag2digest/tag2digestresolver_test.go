@@ -1,9 +1,15 @@
 package tag2digest
 
 import (
+	"context"
+	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/azdsecinfo/contracts"
+	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/cache"
 	cachemock "github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/cache/mocks"
+	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/circuitbreaker"
+	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/coalescing"
 	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/instrumentation"
 	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/registry"
+	registryerrors "github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/registry/errors"
 	registrymocks "github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/registry/mocks"
 	registryutils "github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/registry/utils"
 	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/utils"
@@ -25,6 +31,7 @@ var _nonAcrImageRefTag registry.IImageReference
 var _ctx *ResourceContext
 var _ctxPullSecrets = []string{"tomer-pull-secret"}
 var _expirationTime = 1
+var _errorExpirationTime = 30
 
 const _ctxNamsespace = "tomer-ns"
 const _ctsServiceAccount = "tomer-sa"
@@ -34,18 +41,23 @@ func (suite *TestSuiteTag2DigestResolver) SetupTest() {
 	instrumentationP := instrumentation.NewNoOpInstrumentationProvider()
 	_registryClientMock = new(registrymocks.IRegistryClient)
 	_cacheClientMock = new(cachemock.ICacheClient)
-	_tag2DigestResolverConfiguration := &Tag2DigestResolverConfiguration{CacheExpirationTimeForResults: _expirationTime}
-	_resolver = NewTag2DigestResolver(instrumentationP, _registryClientMock, _cacheClientMock, _tag2DigestResolverConfiguration)
+	_tag2DigestResolverConfiguration := &Tag2DigestResolverConfiguration{
+		CacheExpirationTimeForResults:               _expirationTime,
+		CacheExpirationTimeForImageIsNotFoundErr:    _errorExpirationTime,
+		CacheExpirationTimeForRegistryIsNotFoundErr: _errorExpirationTime,
+		CacheExpirationTimeForUnauthorizedErr:       _errorExpirationTime,
+	}
+	_resolver = NewTag2DigestResolver(instrumentationP, _registryClientMock, _cacheClientMock, _tag2DigestResolverConfiguration, coalescing.NewRequestCoalescer(instrumentationP, "Tag2DigestResolver"))
 	_acrImageRefTag, _ = registryutils.GetImageReference("tomerw.azurecr.io/redis:v0")
 	_nonAcrImageRefTag, _ = registryutils.GetImageReference("tomerw.nonacr.io/redis:v0")
 	_ctx = NewResourceContext(_ctxNamsespace, _ctxPullSecrets, _ctsServiceAccount)
 }
 
 func (suite *TestSuiteTag2DigestResolver) Test_Resolve_DigestRefernce_ReturnDigestNoRegistryCalls() {
-	_cacheClientMock.On("Get", mock.Anything).Return("", utils.NilArgumentError)
-	_cacheClientMock.On("Set", mock.Anything, mock.Anything, mock.Anything).Return(utils.NilArgumentError)
+	_cacheClientMock.On("Get", mock.Anything, mock.Anything).Return("", utils.NilArgumentError)
+	_cacheClientMock.On("Set", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(utils.NilArgumentError)
 	imageRef, _ := registryutils.GetImageReference("tomerw.xyz.io/redis@" + _expectedDigest)
-	digest, err := _resolver.Resolve(imageRef, _ctx)
+	digest, err := _resolver.Resolve(context.Background(), imageRef, _ctx)
 
 	suite.Nil(err)
 	suite.Equal(_expectedDigest, digest)
@@ -54,11 +66,11 @@ func (suite *TestSuiteTag2DigestResolver) Test_Resolve_DigestRefernce_ReturnDige
 }
 
 func (suite *TestSuiteTag2DigestResolver) Test_Resolve_ACRReference_ACRAuthSuccess() {
-	_cacheClientMock.On("Get", mock.Anything).Return("", utils.NilArgumentError)
-	_cacheClientMock.On("Set", mock.Anything, mock.Anything, mock.Anything).Return(utils.NilArgumentError)
-	_registryClientMock.On("GetDigestUsingACRAttachAuth", _acrImageRefTag).Return(_expectedDigest, nil).Once()
+	_cacheClientMock.On("Get", mock.Anything, mock.Anything).Return("", utils.NilArgumentError)
+	_cacheClientMock.On("Set", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(utils.NilArgumentError)
+	_registryClientMock.On("GetDigestUsingACRAttachAuth", mock.Anything, _acrImageRefTag).Return(_expectedDigest, nil).Once()
 
-	digest, err := _resolver.Resolve(_acrImageRefTag, _ctx)
+	digest, err := _resolver.Resolve(context.Background(), _acrImageRefTag, _ctx)
 
 	suite.Nil(err)
 	suite.Equal(_expectedDigest, digest)
@@ -67,12 +79,12 @@ func (suite *TestSuiteTag2DigestResolver) Test_Resolve_ACRReference_ACRAuthSucce
 }
 
 func (suite *TestSuiteTag2DigestResolver) Test_Resolve_ACRReference_ACRAuthFailK8SAuthSuccess() {
-	_cacheClientMock.On("Get", mock.Anything).Return("", utils.NilArgumentError)
-	_cacheClientMock.On("Set", mock.Anything, mock.Anything, mock.Anything).Return(utils.NilArgumentError)
-	_registryClientMock.On("GetDigestUsingACRAttachAuth", _acrImageRefTag).Return("", errors.New("ACRAUthError")).Once()
-	_registryClientMock.On("GetDigestUsingK8SAuth", _acrImageRefTag, _ctxNamsespace, _ctx.imagePullSecrets, _ctsServiceAccount).Return(_expectedDigest, nil).Once()
+	_cacheClientMock.On("Get", mock.Anything, mock.Anything).Return("", utils.NilArgumentError)
+	_cacheClientMock.On("Set", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(utils.NilArgumentError)
+	_registryClientMock.On("GetDigestUsingACRAttachAuth", mock.Anything, _acrImageRefTag).Return("", errors.New("ACRAUthError")).Once()
+	_registryClientMock.On("GetDigestUsingK8SAuth", mock.Anything, _acrImageRefTag, _ctxNamsespace, _ctx.imagePullSecrets, _ctsServiceAccount).Return(_expectedDigest, nil).Once()
 
-	digest, err := _resolver.Resolve(_acrImageRefTag, _ctx)
+	digest, err := _resolver.Resolve(context.Background(), _acrImageRefTag, _ctx)
 
 	suite.Nil(err)
 	suite.Equal(_expectedDigest, digest)
@@ -81,13 +93,13 @@ func (suite *TestSuiteTag2DigestResolver) Test_Resolve_ACRReference_ACRAuthFailK
 }
 
 func (suite *TestSuiteTag2DigestResolver) Test_Resolve_ACRReference_ACRAuthAndK8SAuthFailDefaultSuccess() {
-	_cacheClientMock.On("Get", mock.Anything).Return("", utils.NilArgumentError)
-	_cacheClientMock.On("Set", mock.Anything, mock.Anything, mock.Anything).Return(utils.NilArgumentError)
-	_registryClientMock.On("GetDigestUsingACRAttachAuth", _acrImageRefTag).Return("", errors.New("ACRAUthError")).Once()
-	_registryClientMock.On("GetDigestUsingK8SAuth", _acrImageRefTag, _ctxNamsespace, _ctx.imagePullSecrets, _ctsServiceAccount).Return("", errors.New("K8SAUthError")).Once()
-	_registryClientMock.On("GetDigestUsingDefaultAuth", _acrImageRefTag).Return(_expectedDigest, nil).Once()
+	_cacheClientMock.On("Get", mock.Anything, mock.Anything).Return("", utils.NilArgumentError)
+	_cacheClientMock.On("Set", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(utils.NilArgumentError)
+	_registryClientMock.On("GetDigestUsingACRAttachAuth", mock.Anything, _acrImageRefTag).Return("", errors.New("ACRAUthError")).Once()
+	_registryClientMock.On("GetDigestUsingK8SAuth", mock.Anything, _acrImageRefTag, _ctxNamsespace, _ctx.imagePullSecrets, _ctsServiceAccount).Return("", errors.New("K8SAUthError")).Once()
+	_registryClientMock.On("GetDigestUsingDefaultAuth", mock.Anything, _acrImageRefTag).Return(_expectedDigest, nil).Once()
 
-	digest, err := _resolver.Resolve(_acrImageRefTag, _ctx)
+	digest, err := _resolver.Resolve(context.Background(), _acrImageRefTag, _ctx)
 
 	suite.Nil(err)
 	suite.Equal(_expectedDigest, digest)
@@ -96,14 +108,14 @@ func (suite *TestSuiteTag2DigestResolver) Test_Resolve_ACRReference_ACRAuthAndK8
 }
 
 func (suite *TestSuiteTag2DigestResolver) Test_Resolve_ACRReference_ACRAuthAndK8SAuthFailDefaultFail_ReflectError() {
-	_cacheClientMock.On("Get", mock.Anything).Return("", utils.NilArgumentError)
-	_cacheClientMock.On("Set", mock.Anything, mock.Anything, mock.Anything).Return(utils.NilArgumentError)
+	_cacheClientMock.On("Get", mock.Anything, mock.Anything).Return("", utils.NilArgumentError)
+	_cacheClientMock.On("Set", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(utils.NilArgumentError)
 	expectedError := errors.New("DefaultAuthError")
-	_registryClientMock.On("GetDigestUsingACRAttachAuth", _acrImageRefTag).Return("", errors.New("ACRAuthError")).Once()
-	_registryClientMock.On("GetDigestUsingK8SAuth", _acrImageRefTag, _ctxNamsespace, _ctx.imagePullSecrets, _ctsServiceAccount).Return("", errors.New("K8SAuthError")).Once()
-	_registryClientMock.On("GetDigestUsingDefaultAuth", _acrImageRefTag).Return("", expectedError).Once()
+	_registryClientMock.On("GetDigestUsingACRAttachAuth", mock.Anything, _acrImageRefTag).Return("", errors.New("ACRAuthError")).Once()
+	_registryClientMock.On("GetDigestUsingK8SAuth", mock.Anything, _acrImageRefTag, _ctxNamsespace, _ctx.imagePullSecrets, _ctsServiceAccount).Return("", errors.New("K8SAuthError")).Once()
+	_registryClientMock.On("GetDigestUsingDefaultAuth", mock.Anything, _acrImageRefTag).Return("", expectedError).Once()
 
-	digest, err := _resolver.Resolve(_acrImageRefTag, _ctx)
+	digest, err := _resolver.Resolve(context.Background(), _acrImageRefTag, _ctx)
 
 	suite.ErrorIs(err, expectedError)
 	suite.Equal("", digest)
@@ -111,12 +123,60 @@ func (suite *TestSuiteTag2DigestResolver) Test_Resolve_ACRReference_ACRAuthAndK8
 	_registryClientMock.AssertExpectations(suite.T())
 }
 
+func (suite *TestSuiteTag2DigestResolver) Test_Resolve_ACRReference_ACRAuthIdentityUnavailableDefaultUnauthorized_ReflectIdentityError() {
+	_cacheClientMock.On("Get", mock.Anything, mock.Anything).Return("", utils.NilArgumentError)
+	_cacheClientMock.On("Set", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(utils.NilArgumentError)
+	identityErr := registryerrors.NewIdentityUnavailableErr(_acrImageRefTag.Registry(), errors.New("MSIError"))
+	_registryClientMock.On("GetDigestUsingACRAttachAuth", mock.Anything, _acrImageRefTag).Return("", errors.Wrap(identityErr, "ACRAuthError")).Once()
+	_registryClientMock.On("GetDigestUsingK8SAuth", mock.Anything, _acrImageRefTag, _ctxNamsespace, _ctx.imagePullSecrets, _ctsServiceAccount).Return("", errors.New("K8SAuthError")).Once()
+	_registryClientMock.On("GetDigestUsingDefaultAuth", mock.Anything, _acrImageRefTag).Return("", registryerrors.NewUnauthorizedErr(_acrImageRefTag.Original(), errors.New("UNAUTHORIZED"))).Once()
+
+	digest, err := _resolver.Resolve(context.Background(), _acrImageRefTag, _ctx)
+
+	suite.Equal(identityErr, errors.Cause(err))
+	suite.Equal("", digest)
+
+	_registryClientMock.AssertExpectations(suite.T())
+}
+
+func (suite *TestSuiteTag2DigestResolver) Test_Resolve_ACRReference_ACRAuthIdentityUnavailableDefaultFail_ReflectDefaultError() {
+	_cacheClientMock.On("Get", mock.Anything, mock.Anything).Return("", utils.NilArgumentError)
+	_cacheClientMock.On("Set", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(utils.NilArgumentError)
+	expectedError := errors.New("DefaultAuthError")
+	_registryClientMock.On("GetDigestUsingACRAttachAuth", mock.Anything, _acrImageRefTag).Return("", registryerrors.NewIdentityUnavailableErr(_acrImageRefTag.Registry(), errors.New("MSIError"))).Once()
+	_registryClientMock.On("GetDigestUsingK8SAuth", mock.Anything, _acrImageRefTag, _ctxNamsespace, _ctx.imagePullSecrets, _ctsServiceAccount).Return("", errors.New("K8SAuthError")).Once()
+	_registryClientMock.On("GetDigestUsingDefaultAuth", mock.Anything, _acrImageRefTag).Return("", expectedError).Once()
+
+	digest, err := _resolver.Resolve(context.Background(), _acrImageRefTag, _ctx)
+
+	suite.ErrorIs(err, expectedError)
+	suite.Equal("", digest)
+
+	_registryClientMock.AssertExpectations(suite.T())
+}
+
+func (suite *TestSuiteTag2DigestResolver) Test_Resolve_ACRReference_ACRAuthDigestResolutionTimeout_ReturnErrorNoFallbacks() {
+	_cacheClientMock.On("Get", mock.Anything, mock.Anything).Return("", utils.NilArgumentError)
+	_cacheClientMock.On("Set", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(utils.NilArgumentError)
+	timeoutErr := registryerrors.NewDigestResolutionTimeoutErr(_acrImageRefTag.Original(), context.DeadlineExceeded)
+	_registryClientMock.On("GetDigestUsingACRAttachAuth", mock.Anything, _acrImageRefTag).Return("", errors.Wrap(timeoutErr, "ACRAuthError")).Once()
+
+	digest, err := _resolver.Resolve(context.Background(), _acrImageRefTag, _ctx)
+
+	suite.Equal(timeoutErr, errors.Cause(err))
+	suite.Equal("", digest)
+
+	_registryClientMock.AssertExpectations(suite.T())
+	_registryClientMock.AssertNotCalled(suite.T(), "GetDigestUsingK8SAuth", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	_registryClientMock.AssertNotCalled(suite.T(), "GetDigestUsingDefaultAuth", mock.Anything, mock.Anything)
+}
+
 func (suite *TestSuiteTag2DigestResolver) Test_Resolve_NonACRReference_K8SAuthSuccess() {
-	_cacheClientMock.On("Get", mock.Anything).Return("", utils.NilArgumentError)
-	_cacheClientMock.On("Set", mock.Anything, mock.Anything, mock.Anything).Return(utils.NilArgumentError)
-	_registryClientMock.On("GetDigestUsingK8SAuth", _nonAcrImageRefTag, _ctxNamsespace, _ctx.imagePullSecrets, _ctsServiceAccount).Return(_expectedDigest, nil).Once()
+	_cacheClientMock.On("Get", mock.Anything, mock.Anything).Return("", utils.NilArgumentError)
+	_cacheClientMock.On("Set", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(utils.NilArgumentError)
+	_registryClientMock.On("GetDigestUsingK8SAuth", mock.Anything, _nonAcrImageRefTag, _ctxNamsespace, _ctx.imagePullSecrets, _ctsServiceAccount).Return(_expectedDigest, nil).Once()
 
-	digest, err := _resolver.Resolve(_nonAcrImageRefTag, _ctx)
+	digest, err := _resolver.Resolve(context.Background(), _nonAcrImageRefTag, _ctx)
 
 	suite.Nil(err)
 	suite.Equal(_expectedDigest, digest)
@@ -125,11 +185,11 @@ func (suite *TestSuiteTag2DigestResolver) Test_Resolve_NonACRReference_K8SAuthSu
 }
 
 func (suite *TestSuiteTag2DigestResolver) Test_Resolve_NonACRReference_K8SAuthFailDefaultSuccess() {
-	_cacheClientMock.On("Get", mock.Anything).Return("", utils.NilArgumentError)
-	_cacheClientMock.On("Set", mock.Anything, mock.Anything, mock.Anything).Return(utils.NilArgumentError)
-	_registryClientMock.On("GetDigestUsingK8SAuth", _nonAcrImageRefTag, _ctxNamsespace, _ctx.imagePullSecrets, _ctsServiceAccount).Return("", errors.New("K8SAUthError")).Once()
-	_registryClientMock.On("GetDigestUsingDefaultAuth", _nonAcrImageRefTag).Return(_expectedDigest, nil).Once()
-	digest, err := _resolver.Resolve(_nonAcrImageRefTag, _ctx)
+	_cacheClientMock.On("Get", mock.Anything, mock.Anything).Return("", utils.NilArgumentError)
+	_cacheClientMock.On("Set", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(utils.NilArgumentError)
+	_registryClientMock.On("GetDigestUsingK8SAuth", mock.Anything, _nonAcrImageRefTag, _ctxNamsespace, _ctx.imagePullSecrets, _ctsServiceAccount).Return("", errors.New("K8SAUthError")).Once()
+	_registryClientMock.On("GetDigestUsingDefaultAuth", mock.Anything, _nonAcrImageRefTag).Return(_expectedDigest, nil).Once()
+	digest, err := _resolver.Resolve(context.Background(), _nonAcrImageRefTag, _ctx)
 
 	suite.Nil(err)
 	suite.Equal(_expectedDigest, digest)
@@ -138,13 +198,13 @@ func (suite *TestSuiteTag2DigestResolver) Test_Resolve_NonACRReference_K8SAuthFa
 }
 
 func (suite *TestSuiteTag2DigestResolver) Test_Resolve_NonACRReference_ACRAuthAndK8SAuthFailDefaultFail_ReflectError() {
-	_cacheClientMock.On("Get", mock.Anything).Return("", utils.NilArgumentError)
-	_cacheClientMock.On("Set", mock.Anything, mock.Anything, mock.Anything).Return(utils.NilArgumentError)
+	_cacheClientMock.On("Get", mock.Anything, mock.Anything).Return("", utils.NilArgumentError)
+	_cacheClientMock.On("Set", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(utils.NilArgumentError)
 	expectedError := errors.New("DefaultAUthError")
-	_registryClientMock.On("GetDigestUsingK8SAuth", _nonAcrImageRefTag, _ctxNamsespace, _ctx.imagePullSecrets, _ctsServiceAccount).Return("", errors.New("K8SAUthError")).Once()
-	_registryClientMock.On("GetDigestUsingDefaultAuth", _nonAcrImageRefTag).Return("", expectedError).Once()
+	_registryClientMock.On("GetDigestUsingK8SAuth", mock.Anything, _nonAcrImageRefTag, _ctxNamsespace, _ctx.imagePullSecrets, _ctsServiceAccount).Return("", errors.New("K8SAUthError")).Once()
+	_registryClientMock.On("GetDigestUsingDefaultAuth", mock.Anything, _nonAcrImageRefTag).Return("", expectedError).Once()
 
-	digest, err := _resolver.Resolve(_nonAcrImageRefTag, _ctx)
+	digest, err := _resolver.Resolve(context.Background(), _nonAcrImageRefTag, _ctx)
 
 	suite.ErrorIs(err, expectedError)
 	suite.Equal("", digest)
@@ -153,11 +213,11 @@ func (suite *TestSuiteTag2DigestResolver) Test_Resolve_NonACRReference_ACRAuthAn
 }
 
 func (suite *TestSuiteTag2DigestResolver) Test_Resolve_ACRReference_ACRAuthSuccess_NoKeyInCache() {
-	_cacheClientMock.On("Get", _acrImageRefTag.Original()).Return("", utils.NilArgumentError).Once()
-	_cacheClientMock.On("Set", _acrImageRefTag.Original(), mock.Anything, mock.Anything).Return(nil).Maybe()
+	_cacheClientMock.On("Get", mock.Anything, _acrImageRefTag.Original()).Return("", utils.NilArgumentError).Once()
+	_cacheClientMock.On("Set", mock.Anything, _acrImageRefTag.Original(), mock.Anything, mock.Anything).Return(nil).Maybe()
 
-	_registryClientMock.On("GetDigestUsingACRAttachAuth", _acrImageRefTag).Return(_expectedDigest, nil).Once()
-	digest, err := _resolver.Resolve(_acrImageRefTag, _ctx)
+	_registryClientMock.On("GetDigestUsingACRAttachAuth", mock.Anything, _acrImageRefTag).Return(_expectedDigest, nil).Once()
+	digest, err := _resolver.Resolve(context.Background(), _acrImageRefTag, _ctx)
 	suite.Nil(err)
 	suite.Equal(_expectedDigest, digest)
 	_registryClientMock.AssertExpectations(suite.T())
@@ -165,9 +225,9 @@ func (suite *TestSuiteTag2DigestResolver) Test_Resolve_ACRReference_ACRAuthSucce
 }
 
 func (suite *TestSuiteTag2DigestResolver) Test_Resolve_ACRReference_ACRAuthSuccess_KeyInCache() {
-	_cacheClientMock.On("Get", _acrImageRefTag.Original()).Return(_expectedDigest, nil).Once()
+	_cacheClientMock.On("Get", mock.Anything, _acrImageRefTag.Original()).Return(_expectedDigest, nil).Once()
 
-	digest, err := _resolver.Resolve(_acrImageRefTag, _ctx)
+	digest, err := _resolver.Resolve(context.Background(), _acrImageRefTag, _ctx)
 	suite.Nil(err)
 	suite.Equal(_expectedDigest, digest)
 	_registryClientMock.AssertExpectations(suite.T())
@@ -175,15 +235,15 @@ func (suite *TestSuiteTag2DigestResolver) Test_Resolve_ACRReference_ACRAuthSucce
 }
 
 func (suite *TestSuiteTag2DigestResolver) Test_Resolve_ACRReference_ACRAuthSuccess_NoKeyInCache_SetKey_GetKeySecondTryBeforeExpirationTime() {
-	_cacheClientMock.On("Get", _acrImageRefTag.Original()).Return("", utils.NilArgumentError).Once()
-	_cacheClientMock.On("Get", _acrImageRefTag.Original()).Return(_expectedDigest, nil).Once()
-	_cacheClientMock.On("Set", _acrImageRefTag.Original(), mock.Anything, mock.Anything).Return(nil).Maybe()
+	_cacheClientMock.On("Get", mock.Anything, _acrImageRefTag.Original()).Return("", utils.NilArgumentError).Once()
+	_cacheClientMock.On("Get", mock.Anything, _acrImageRefTag.Original()).Return(_expectedDigest, nil).Once()
+	_cacheClientMock.On("Set", mock.Anything, _acrImageRefTag.Original(), mock.Anything, mock.Anything).Return(nil).Maybe()
 
-	_registryClientMock.On("GetDigestUsingACRAttachAuth", _acrImageRefTag).Return(_expectedDigest, nil).Once()
-	digest, err := _resolver.Resolve(_acrImageRefTag, _ctx)
+	_registryClientMock.On("GetDigestUsingACRAttachAuth", mock.Anything, _acrImageRefTag).Return(_expectedDigest, nil).Once()
+	digest, err := _resolver.Resolve(context.Background(), _acrImageRefTag, _ctx)
 	suite.Nil(err)
 	suite.Equal(_expectedDigest, digest)
-	digest, err = _resolver.Resolve(_acrImageRefTag, _ctx)
+	digest, err = _resolver.Resolve(context.Background(), _acrImageRefTag, _ctx)
 	suite.Nil(err)
 	suite.Equal(_expectedDigest, digest)
 	_registryClientMock.AssertExpectations(suite.T())
@@ -191,20 +251,90 @@ func (suite *TestSuiteTag2DigestResolver) Test_Resolve_ACRReference_ACRAuthSucce
 }
 
 func (suite *TestSuiteTag2DigestResolver) Test_Resolve_ACRReference_ACRAuthSuccess_NoKeyInCache_SetKey_GetKeySecondTryAfterExpirationTime() {
-	_cacheClientMock.On("Get", _acrImageRefTag.Original()).Return("", utils.NilArgumentError).Twice()
-	_cacheClientMock.On("Set", _acrImageRefTag.Original(), mock.Anything, mock.Anything).Return(nil).Maybe()
+	_cacheClientMock.On("Get", mock.Anything, _acrImageRefTag.Original()).Return("", utils.NilArgumentError).Twice()
+	_cacheClientMock.On("Set", mock.Anything, _acrImageRefTag.Original(), mock.Anything, mock.Anything).Return(nil).Maybe()
 
-	_registryClientMock.On("GetDigestUsingACRAttachAuth", _acrImageRefTag).Return(_expectedDigest, nil).Twice()
-	digest, err := _resolver.Resolve(_acrImageRefTag, _ctx)
+	_registryClientMock.On("GetDigestUsingACRAttachAuth", mock.Anything, _acrImageRefTag).Return(_expectedDigest, nil).Twice()
+	digest, err := _resolver.Resolve(context.Background(), _acrImageRefTag, _ctx)
 	suite.Nil(err)
 	suite.Equal(_expectedDigest, digest)
-	digest, err = _resolver.Resolve(_acrImageRefTag, _ctx)
+	digest, err = _resolver.Resolve(context.Background(), _acrImageRefTag, _ctx)
 	suite.Nil(err)
 	suite.Equal(_expectedDigest, digest)
 	_registryClientMock.AssertExpectations(suite.T())
 	_cacheClientMock.AssertExpectations(suite.T())
 }
 
+func (suite *TestSuiteTag2DigestResolver) Test_Resolve_ImageIsNotFoundErrInCache_ReturnErrorNoRegistryCalls() {
+	_cacheClientMock.On("Get", mock.Anything, _acrImageRefTag.Original()).Return(string(contracts.ImageDoesNotExistUnscannedReason), nil).Once()
+
+	digest, err := _resolver.Resolve(context.Background(), _acrImageRefTag, _ctx)
+
+	suite.Equal("", digest)
+	suite.IsType(&registryerrors.ImageIsNotFoundErr{}, errors.Cause(err))
+	_registryClientMock.AssertExpectations(suite.T())
+	_cacheClientMock.AssertExpectations(suite.T())
+}
+
+func (suite *TestSuiteTag2DigestResolver) Test_Resolve_UnauthorizedErrOfResourceContextInCache_ReturnErrorNoRegistryCalls() {
+	_cacheClientMock.On("Get", mock.Anything, _acrImageRefTag.Original()).Return("", cache.NewMissingKeyCacheError(_acrImageRefTag.Original())).Once()
+	_cacheClientMock.On("Get", mock.Anything, _resolver.getUnauthorizedCacheKey(_acrImageRefTag, _ctx)).Return(string(contracts.RegistryUnauthorizedUnscannedReason), nil).Once()
+
+	digest, err := _resolver.Resolve(context.Background(), _acrImageRefTag, _ctx)
+
+	suite.Equal("", digest)
+	suite.IsType(&registryerrors.UnauthorizedErr{}, errors.Cause(err))
+	_registryClientMock.AssertExpectations(suite.T())
+	_cacheClientMock.AssertExpectations(suite.T())
+}
+
+func (suite *TestSuiteTag2DigestResolver) Test_getUnauthorizedCacheKey_DifferentResourceContext_DifferentKey() {
+	otherCtx := NewResourceContext("other-ns", _ctxPullSecrets, _ctsServiceAccount)
+
+	suite.NotEqual(_resolver.getUnauthorizedCacheKey(_acrImageRefTag, _ctx), _resolver.getUnauthorizedCacheKey(_acrImageRefTag, otherCtx))
+	suite.NotEqual(_acrImageRefTag.Original(), _resolver.getUnauthorizedCacheKey(_acrImageRefTag, _ctx))
+}
+
+func (suite *TestSuiteTag2DigestResolver) Test_setErrorInCache_ImageIsNotFoundErr_SetByImage() {
+	_cacheClientMock.On("Set", mock.Anything, _acrImageRefTag.Original(), string(contracts.ImageDoesNotExistUnscannedReason), utils.GetSeconds(_errorExpirationTime)).Return(nil).Once()
+
+	_resolver.setErrorInCache(context.Background(), _acrImageRefTag, _ctx, errors.Wrap(registryerrors.NewImageIsNotFoundErr(_acrImageRefTag.Original(), errors.New("not found")), "wrap"))
+
+	_cacheClientMock.AssertExpectations(suite.T())
+}
+
+func (suite *TestSuiteTag2DigestResolver) Test_setErrorInCache_UnauthorizedErr_SetByImageAndResourceContext() {
+	_cacheClientMock.On("Set", mock.Anything, _resolver.getUnauthorizedCacheKey(_acrImageRefTag, _ctx), string(contracts.RegistryUnauthorizedUnscannedReason), utils.GetSeconds(_errorExpirationTime)).Return(nil).Once()
+
+	_resolver.setErrorInCache(context.Background(), _acrImageRefTag, _ctx, registryerrors.NewUnauthorizedErr(_acrImageRefTag.Original(), errors.New("unauthorized")))
+
+	_cacheClientMock.AssertExpectations(suite.T())
+}
+
+func (suite *TestSuiteTag2DigestResolver) Test_setErrorInCache_ExpirationTimeIsZero_NotSet() {
+	_resolver.setErrorInCache(context.Background(), _acrImageRefTag, _ctx, registryerrors.NewRegistryTLSErr(_acrImageRefTag.Original(), errors.New("x509: certificate signed by unknown authority")))
+
+	_cacheClientMock.AssertNotCalled(suite.T(), "Set", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func (suite *TestSuiteTag2DigestResolver) Test_setErrorInCache_CircuitIsOpenErr_NotSet() {
+	_resolver.setErrorInCache(context.Background(), _acrImageRefTag, _ctx, errors.Wrap(circuitbreaker.NewCircuitIsOpenErr("CraneWrapper", _acrImageRefTag.Registry()), "wrap"))
+
+	_cacheClientMock.AssertNotCalled(suite.T(), "Set", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func (suite *TestSuiteTag2DigestResolver) Test_setErrorInCache_IdentityUnavailableErr_NotSet() {
+	_resolver.setErrorInCache(context.Background(), _acrImageRefTag, _ctx, errors.Wrap(registryerrors.NewIdentityUnavailableErr(_acrImageRefTag.Registry(), errors.New("MSIError")), "wrap"))
+
+	_cacheClientMock.AssertNotCalled(suite.T(), "Set", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func (suite *TestSuiteTag2DigestResolver) Test_setErrorInCache_UnknownError_NotSet() {
+	_resolver.setErrorInCache(context.Background(), _acrImageRefTag, _ctx, errors.New("unknown error"))
+
+	_cacheClientMock.AssertNotCalled(suite.T(), "Set", mock.Anything, mock.Anything, mock.Anything)
+}
+
 func Test_Suite(t *testing.T) {
 	suite.Run(t, new(TestSuiteTag2DigestResolver))
 }
@@ -0,0 +1,43 @@
+package metric
+
+import (
+	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/instrumentation/metric"
+)
+
+// VulnerabilityReportWriteStatus is enum of the statuses of the writes of the vulnerability reports
+type VulnerabilityReportWriteStatus string
+
+const (
+	// WrittenVulnerabilityReportWriteStatus is when the report was created or updated
+	WrittenVulnerabilityReportWriteStatus VulnerabilityReportWriteStatus = "Written"
+	// OwnerNotFoundVulnerabilityReportWriteStatus is when the workload that owns the report wasn't found (e.g. its creation was rejected)
+	OwnerNotFoundVulnerabilityReportWriteStatus VulnerabilityReportWriteStatus = "OwnerNotFound"
+	// FailedVulnerabilityReportWriteStatus is when the report couldn't be written
+	FailedVulnerabilityReportWriteStatus VulnerabilityReportWriteStatus = "Failed"
+)
+
+// VulnerabilityReportWriteMetric implements metric.IMetric interface
+var _ metric.IMetric = (*VulnerabilityReportWriteMetric)(nil)
+
+// VulnerabilityReportWriteMetric is metric of VulnerabilityReportWriter to report each write of a vulnerability report and its status
+type VulnerabilityReportWriteMetric struct {
+	// status is whether the report was written
+	status VulnerabilityReportWriteStatus
+}
+
+// NewVulnerabilityReportWriteMetric Ctor for VulnerabilityReportWriteMetric
+func NewVulnerabilityReportWriteMetric(status VulnerabilityReportWriteStatus) *VulnerabilityReportWriteMetric {
+	return &VulnerabilityReportWriteMetric{
+		status: status,
+	}
+}
+
+func (m *VulnerabilityReportWriteMetric) MetricName() string {
+	return "VulnerabilityReportWrite"
+}
+
+func (m *VulnerabilityReportWriteMetric) MetricDimension() []metric.Dimension {
+	return []metric.Dimension{
+		{Key: "Status", Value: string(m.status)},
+	}
+}
@@ -0,0 +1,26 @@
+package vulnerabilityreport
+
+import (
+	"context"
+	"github.com/Azure/AzureDefender-K8S-InClusterDefense/cmd/webhook/admisionrequest"
+	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/azdsecinfo/contracts"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// NoOpVulnerabilityReportWriter implements IVulnerabilityReportWriter interface
+var _ IVulnerabilityReportWriter = (*NoOpVulnerabilityReportWriter)(nil)
+
+// NoOpVulnerabilityReportWriter is implementation that does nothing of IVulnerabilityReportWriter.
+// It is used when the vulnerability reports are disabled.
+type NoOpVulnerabilityReportWriter struct {
+}
+
+// NewNoOpVulnerabilityReportWriter Ctor for NoOpVulnerabilityReportWriter
+func NewNoOpVulnerabilityReportWriter() *NoOpVulnerabilityReportWriter {
+	return &NoOpVulnerabilityReportWriter{}
+}
+
+// WriteReport does nothing and returns empty report name
+func (writer *NoOpVulnerabilityReportWriter) WriteReport(ctx context.Context, namespace string, kind metav1.GroupVersionKind, workloadResource *admisionrequest.WorkloadResource, containersVulnerabilityScanInfo []*contracts.ContainerVulnerabilityScanInfo) string {
+	return ""
+}
@@ -0,0 +1,132 @@
+// Package vulnerabilityreport contains the ContainerVulnerabilityReport custom resource that is written for each workload,
+// so the scan results can be queried across the cluster (e.g. kubectl get vulnreports -A) and not only in the annotations.
+package vulnerabilityreport
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/azdsecinfo/contracts"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"strings"
+	"time"
+)
+
+const (
+	// _reportContentField is the field of the custom resource that holds the VulnerabilityReport
+	_reportContentField = "report"
+	// _maxReportNameLength is the maximal length of the name of the custom resource (DNS subdomain)
+	_maxReportNameLength = 253
+	// _reportNameHashLength is the length of the hash suffix of the truncated names
+	_reportNameHashLength = 8
+	// WorkloadKindLabel is the label of the report with the kind of its workload
+	WorkloadKindLabel = contracts.AzdSecInfoAnnotationPrefix + "/workload-kind"
+	// WorkloadNameLabel is the label of the report with the name of its workload
+	WorkloadNameLabel = contracts.AzdSecInfoAnnotationPrefix + "/workload-name"
+)
+
+var (
+	// ContainerVulnerabilityReportGVK is the GroupVersionKind of the ContainerVulnerabilityReport custom resource
+	ContainerVulnerabilityReportGVK = schema.GroupVersionKind{Group: contracts.AzdSecInfoAnnotationPrefix, Version: "v1alpha1", Kind: "ContainerVulnerabilityReport"}
+
+	// _summarySeverities are the severities that are always present in the counts of the summary
+	_summarySeverities = []string{"High", "Medium", "Low"}
+)
+
+// VulnerabilityReport is the content of the ContainerVulnerabilityReport custom resource of a workload
+type VulnerabilityReport struct {
+	// Workload is the workload that the report is of (and owned by)
+	Workload *WorkloadReference `json:"workload"`
+
+	// GeneratedTimestamp represents the time the report was generated
+	GeneratedTimestamp time.Time `json:"generatedTimestamp"`
+
+	// Summary represents the summary of the containers (the status columns of the report)
+	Summary *VulnerabilityReportSummary `json:"summary"`
+
+	// Containers represents the full scan info of each container of the workload
+	Containers []*contracts.ContainerVulnerabilityScanInfo `json:"containers"`
+}
+
+// WorkloadReference represents the workload of the report
+type WorkloadReference struct {
+	// APIVersion of the workload
+	APIVersion string `json:"apiVersion"`
+
+	// Kind of the workload
+	Kind string `json:"kind"`
+
+	// Name of the workload
+	Name string `json:"name"`
+
+	// UID of the workload
+	UID string `json:"uid"`
+}
+
+// VulnerabilityReportSummary represents the summary of the scan info of the containers of the workload
+type VulnerabilityReportSummary struct {
+	// ScanStatus represents the status of the workload - unhealthyScan if any container is unhealthy, otherwise unscanned
+	// if any container is unscanned, otherwise healthyScan
+	ScanStatus contracts.ScanStatus `json:"scanStatus"`
+
+	// ContainersCount represents the count of the containers
+	ContainersCount int `json:"containersCount"`
+
+	// UnhealthyCount represents the count of the containers with unhealthy scan status
+	UnhealthyCount int `json:"unhealthyCount"`
+
+	// UnscannedCount represents the count of the containers with unscanned scan status
+	UnscannedCount int `json:"unscannedCount"`
+
+	// SeverityCounts represents the count of the findings of all the containers per severity. High, Medium and Low are always present.
+	SeverityCounts map[string]int `json:"severityCounts"`
+}
+
+// newVulnerabilityReport creates the report of the workload from the scan info of its containers
+func newVulnerabilityReport(workload *WorkloadReference, generatedTimestamp time.Time, containersVulnerabilityScanInfo []*contracts.ContainerVulnerabilityScanInfo) *VulnerabilityReport {
+	summary := &VulnerabilityReportSummary{
+		ScanStatus:     contracts.HealthyScan,
+		SeverityCounts: make(map[string]int, len(_summarySeverities)),
+	}
+	for _, severity := range _summarySeverities {
+		summary.SeverityCounts[severity] = 0
+	}
+
+	for _, info := range containersVulnerabilityScanInfo {
+		if info == nil {
+			continue
+		}
+		summary.ContainersCount++
+		switch info.ScanStatus {
+		case contracts.UnhealthyScan:
+			summary.UnhealthyCount++
+		case contracts.Unscanned:
+			summary.UnscannedCount++
+		}
+		for _, scanFinding := range info.ScanFindings {
+			summary.SeverityCounts[scanFinding.Severity]++
+		}
+	}
+	if summary.UnhealthyCount > 0 {
+		summary.ScanStatus = contracts.UnhealthyScan
+	} else if summary.UnscannedCount > 0 {
+		summary.ScanStatus = contracts.Unscanned
+	}
+
+	return &VulnerabilityReport{
+		Workload:           workload,
+		GeneratedTimestamp: generatedTimestamp,
+		Summary:            summary,
+		Containers:         containersVulnerabilityScanInfo,
+	}
+}
+
+// GetReportName returns the name of the report of the workload - <kind>-<name> in lower case.
+// Names that are too long are truncated and suffixed with a hash of the full name, so they stay unique.
+func GetReportName(workloadKind string, workloadName string) string {
+	name := strings.ToLower(workloadKind) + "-" + workloadName
+	if len(name) <= _maxReportNameLength {
+		return name
+	}
+	hash := sha256.Sum256([]byte(name))
+	return name[:_maxReportNameLength-_reportNameHashLength-1] + "-" + hex.EncodeToString(hash[:])[:_reportNameHashLength]
+}
@@ -0,0 +1,218 @@
+package vulnerabilityreport
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/Azure/AzureDefender-K8S-InClusterDefense/cmd/webhook/admisionrequest"
+	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/azdsecinfo/contracts"
+	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/instrumentation"
+	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/instrumentation/metric"
+	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/instrumentation/metric/util"
+	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/instrumentation/trace"
+	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/retrypolicy"
+	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/utils"
+	vulnerabilityreportmetric "github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/vulnerabilityreport/metric"
+	"github.com/pkg/errors"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"time"
+)
+
+// IVulnerabilityReportWriter writes the ContainerVulnerabilityReport custom resource of the workloads
+type IVulnerabilityReportWriter interface {
+	// WriteReport creates or updates (in the background) the report of the workload, owner-referenced to the workload, and returns
+	// the name of the report. namespace and kind are of the admission request of the workload.
+	// Returns empty name if no report is written (e.g. a new workload without name and without owner with UID).
+	WriteReport(ctx context.Context, namespace string, kind metav1.GroupVersionKind, workloadResource *admisionrequest.WorkloadResource, containersVulnerabilityScanInfo []*contracts.ContainerVulnerabilityScanInfo) string
+}
+
+// VulnerabilityReportWriter implements IVulnerabilityReportWriter interface
+var _ IVulnerabilityReportWriter = (*VulnerabilityReportWriter)(nil)
+
+// VulnerabilityReportWriter is IVulnerabilityReportWriter that writes the reports using the client of the manager.
+// The report is of the workload if it has a name, otherwise of its first owner with UID (e.g. the replica set of a new pod).
+// The UID of a new workload is set only after its admission, so the workload is fetched (with retries) to get the UID
+// that the report is owner-referenced to - the report is garbage-collected with the workload.
+type VulnerabilityReportWriter struct {
+	//tracerProvider is tracer provider of VulnerabilityReportWriter
+	tracerProvider trace.ITracerProvider
+	//metricSubmitter is metric submitter of VulnerabilityReportWriter
+	metricSubmitter metric.IMetricSubmitter
+	// client is the kubernetes client that the reports are written with
+	client client.Client
+	// retryPolicy is the retry policy of fetching the new workloads and of conflicting writes of the reports
+	retryPolicy retrypolicy.IRetryPolicy
+}
+
+// VulnerabilityReportWriterConfiguration is configuration data for VulnerabilityReportWriter
+type VulnerabilityReportWriterConfiguration struct {
+	// Enabled is whether a ContainerVulnerabilityReport should be written for each workload
+	Enabled bool
+}
+
+// NewVulnerabilityReportWriter Ctor for VulnerabilityReportWriter
+func NewVulnerabilityReportWriter(instrumentationProvider instrumentation.IInstrumentationProvider, client client.Client, retryPolicy retrypolicy.IRetryPolicy) *VulnerabilityReportWriter {
+	return &VulnerabilityReportWriter{
+		tracerProvider:  instrumentationProvider.GetTracerProvider("VulnerabilityReportWriter"),
+		metricSubmitter: instrumentationProvider.GetMetricSubmitter(),
+		client:          client,
+		retryPolicy:     retryPolicy,
+	}
+}
+
+// WriteReport creates or updates (in the background) the report of the workload and returns the name of the report.
+// The report is written even if the request is done before the write is completed, so the write isn't bound to ctx's cancellation.
+func (writer *VulnerabilityReportWriter) WriteReport(ctx context.Context, namespace string, kind metav1.GroupVersionKind, workloadResource *admisionrequest.WorkloadResource, containersVulnerabilityScanInfo []*contracts.ContainerVulnerabilityScanInfo) string {
+	tracer := writer.tracerProvider.GetTracer("WriteReport")
+	if workloadResource == nil || workloadResource.Metadata == nil {
+		err := errors.Wrap(utils.NilArgumentError, "VulnerabilityReportWriter.WriteReport")
+		tracer.Error(err, "")
+		writer.metricSubmitter.SendMetric(1, util.NewErrorEncounteredMetric(err, "VulnerabilityReportWriter.WriteReport"))
+		return ""
+	}
+
+	if workloadResource.Metadata.Namespace != "" {
+		namespace = workloadResource.Metadata.Namespace
+	}
+	workload := getReportWorkload(kind, workloadResource.Metadata)
+	if workload == nil {
+		tracer.Info("Workload has no name and no owner with UID - no report is written")
+		return ""
+	}
+
+	reportName := GetReportName(workload.Kind, workload.Name)
+	report := newVulnerabilityReport(workload, time.Now().UTC(), containersVulnerabilityScanInfo)
+	go writer.writeReport(utils.NewDetachedContext(ctx), namespace, reportName, report)
+	return reportName
+}
+
+// writeReport resolves the UID of the workload (if it's missing) and creates or updates the report
+func (writer *VulnerabilityReportWriter) writeReport(ctx context.Context, namespace string, reportName string, report *VulnerabilityReport) {
+	tracer := writer.tracerProvider.GetTracer("writeReport")
+
+	if report.Workload.UID == "" {
+		uid, err := writer.getWorkloadUID(ctx, namespace, report.Workload)
+		if err != nil {
+			status := vulnerabilityreportmetric.FailedVulnerabilityReportWriteStatus
+			if apierrors.IsNotFound(errors.Cause(err)) {
+				status = vulnerabilityreportmetric.OwnerNotFoundVulnerabilityReportWriteStatus
+			}
+			err = errors.Wrap(err, "VulnerabilityReportWriter.writeReport failed to get the UID of the workload")
+			tracer.Error(err, "", "namespace", namespace, "reportName", reportName)
+			writer.metricSubmitter.SendMetric(1, util.NewErrorEncounteredMetric(err, "VulnerabilityReportWriter.writeReport"))
+			writer.metricSubmitter.SendMetric(1, vulnerabilityreportmetric.NewVulnerabilityReportWriteMetric(status))
+			return
+		}
+		report.Workload.UID = uid
+	}
+
+	err := writer.retryPolicy.RetryAction(ctx, func() error {
+		return writer.createOrUpdateReport(ctx, namespace, reportName, report)
+	}, func(err error) bool {
+		// Concurrent writes of the same report (e.g. pods of the same replica set) are retried
+		return apierrors.IsConflict(err) || apierrors.IsAlreadyExists(err)
+	})
+	if err != nil {
+		err = errors.Wrap(err, "VulnerabilityReportWriter.writeReport failed to write the report")
+		tracer.Error(err, "", "namespace", namespace, "reportName", reportName)
+		writer.metricSubmitter.SendMetric(1, util.NewErrorEncounteredMetric(err, "VulnerabilityReportWriter.writeReport"))
+		writer.metricSubmitter.SendMetric(1, vulnerabilityreportmetric.NewVulnerabilityReportWriteMetric(vulnerabilityreportmetric.FailedVulnerabilityReportWriteStatus))
+		return
+	}
+	tracer.Info("Report is written", "namespace", namespace, "reportName", reportName)
+	writer.metricSubmitter.SendMetric(1, vulnerabilityreportmetric.NewVulnerabilityReportWriteMetric(vulnerabilityreportmetric.WrittenVulnerabilityReportWriteStatus))
+}
+
+// getWorkloadUID gets the UID of the workload. The workload is created only after its admission, so it's fetched with retries
+// until it's found.
+func (writer *VulnerabilityReportWriter) getWorkloadUID(ctx context.Context, namespace string, workload *WorkloadReference) (string, error) {
+	return writer.retryPolicy.RetryActionString(ctx, func() (string, error) {
+		object := &unstructured.Unstructured{}
+		object.SetGroupVersionKind(schema.FromAPIVersionAndKind(workload.APIVersion, workload.Kind))
+		if err := writer.client.Get(ctx, types.NamespacedName{Namespace: namespace, Name: workload.Name}, object); err != nil {
+			return "", err
+		}
+		return string(object.GetUID()), nil
+	}, apierrors.IsNotFound)
+}
+
+// createOrUpdateReport creates the report of the workload, or updates it if it already exists
+func (writer *VulnerabilityReportWriter) createOrUpdateReport(ctx context.Context, namespace string, reportName string, report *VulnerabilityReport) error {
+	content, err := toUnstructuredContent(report)
+	if err != nil {
+		return errors.Wrap(err, "failed to convert the report")
+	}
+
+	object := &unstructured.Unstructured{}
+	object.SetGroupVersionKind(ContainerVulnerabilityReportGVK)
+	object.SetNamespace(namespace)
+	object.SetName(reportName)
+	_, err = controllerutil.CreateOrUpdate(ctx, writer.client, object, func() error {
+		object.SetLabels(map[string]string{
+			WorkloadKindLabel: report.Workload.Kind,
+			WorkloadNameLabel: getLabelValue(report.Workload.Name),
+		})
+		object.SetOwnerReferences([]metav1.OwnerReference{
+			{
+				APIVersion: report.Workload.APIVersion,
+				Kind:       report.Workload.Kind,
+				Name:       report.Workload.Name,
+				UID:        types.UID(report.Workload.UID),
+			},
+		})
+		return unstructured.SetNestedField(object.Object, content, _reportContentField)
+	})
+	return err
+}
+
+// getReportWorkload returns the workload that the report is of - the workload if it has a name, otherwise its first owner
+// with UID. Returns nil if the workload has no name and no owner with UID.
+func getReportWorkload(kind metav1.GroupVersionKind, metadata *admisionrequest.ObjectMetadata) *WorkloadReference {
+	if metadata.Name != "" {
+		return &WorkloadReference{
+			APIVersion: schema.GroupVersion{Group: kind.Group, Version: kind.Version}.String(),
+			Kind:       kind.Kind,
+			Name:       metadata.Name,
+			UID:        metadata.UID,
+		}
+	}
+
+	for _, owner := range metadata.OwnerReferences {
+		if owner != nil && owner.UID != "" && owner.Name != "" {
+			return &WorkloadReference{
+				APIVersion: owner.APIVersion,
+				Kind:       owner.Kind,
+				Name:       owner.Name,
+				UID:        owner.UID,
+			}
+		}
+	}
+	return nil
+}
+
+// toUnstructuredContent converts the report to the content of unstructured object
+func toUnstructuredContent(report *VulnerabilityReport) (map[string]interface{}, error) {
+	marshaled, err := json.Marshal(report)
+	if err != nil {
+		return nil, err
+	}
+	content := map[string]interface{}{}
+	if err := json.Unmarshal(marshaled, &content); err != nil {
+		return nil, err
+	}
+	return content, nil
+}
+
+// getLabelValue returns the value truncated to the maximal length of label values (63)
+func getLabelValue(value string) string {
+	const maxLabelValueLength = 63
+	if len(value) > maxLabelValueLength {
+		return value[:maxLabelValueLength]
+	}
+	return value
+}
@@ -0,0 +1,161 @@
+package vulnerabilityreport
+
+import (
+	"context"
+	"github.com/Azure/AzureDefender-K8S-InClusterDefense/cmd/webhook/admisionrequest"
+	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/azdsecinfo/contracts"
+	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/instrumentation"
+	"github.com/Azure/AzureDefender-K8S-InClusterDefense/pkg/infra/retrypolicy"
+	"github.com/stretchr/testify/suite"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"strings"
+	"testing"
+	"time"
+)
+
+var (
+	_podKind        = metav1.GroupVersionKind{Group: "", Version: "v1", Kind: "Pod"}
+	_deploymentKind = metav1.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"}
+)
+
+type VulnerabilityReportWriterTestSuite struct {
+	suite.Suite
+	client        client.Client
+	writer        *VulnerabilityReportWriter
+	unhealthyInfo *contracts.ContainerVulnerabilityScanInfo
+	unscannedInfo *contracts.ContainerVulnerabilityScanInfo
+}
+
+// This will run before each test in the suite
+func (suite *VulnerabilityReportWriterTestSuite) SetupTest() {
+	suite.client = fake.NewClientBuilder().WithScheme(clientgoscheme.Scheme).WithObjects(&corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "podTest", Namespace: "default", UID: "podUID"},
+	}).Build()
+	retryPolicy := retrypolicy.NewRetryPolicy(instrumentation.NewNoOpInstrumentationProvider(), &retrypolicy.RetryPolicyConfiguration{RetryAttempts: 2, RetryDurationInMS: 1}, "VulnerabilityReportWriterTest")
+	suite.writer = NewVulnerabilityReportWriter(instrumentation.NewNoOpInstrumentationProvider(), suite.client, retryPolicy)
+	suite.unhealthyInfo = &contracts.ContainerVulnerabilityScanInfo{
+		Name:       "app",
+		Image:      &contracts.Image{Name: "image.com/app:1"},
+		ScanStatus: contracts.UnhealthyScan,
+		ScanFindings: []*contracts.ScanFinding{
+			{Id: "1", Severity: "High"},
+			{Id: "2", Severity: "High"},
+			{Id: "3", Severity: "Low"},
+		},
+	}
+	suite.unscannedInfo = &contracts.ContainerVulnerabilityScanInfo{
+		Name:       "sidecar",
+		Image:      &contracts.Image{Name: "docker.io/sidecar:2"},
+		ScanStatus: contracts.Unscanned,
+	}
+}
+
+func (suite *VulnerabilityReportWriterTestSuite) Test_newVulnerabilityReport_UnhealthyAndUnscanned_UnhealthySummary() {
+	report := newVulnerabilityReport(&WorkloadReference{Kind: "Pod", Name: "podTest"}, time.Now().UTC(), []*contracts.ContainerVulnerabilityScanInfo{suite.unhealthyInfo, suite.unscannedInfo})
+
+	suite.Equal(&VulnerabilityReportSummary{
+		ScanStatus:      contracts.UnhealthyScan,
+		ContainersCount: 2,
+		UnhealthyCount:  1,
+		UnscannedCount:  1,
+		SeverityCounts:  map[string]int{"High": 2, "Medium": 0, "Low": 1},
+	}, report.Summary)
+}
+
+func (suite *VulnerabilityReportWriterTestSuite) Test_newVulnerabilityReport_OnlyUnscanned_UnscannedSummary() {
+	report := newVulnerabilityReport(&WorkloadReference{Kind: "Pod", Name: "podTest"}, time.Now().UTC(), []*contracts.ContainerVulnerabilityScanInfo{suite.unscannedInfo})
+
+	suite.Equal(contracts.Unscanned, report.Summary.ScanStatus)
+}
+
+func (suite *VulnerabilityReportWriterTestSuite) Test_GetReportName_KindAndName_LowerCaseKindDashName() {
+	suite.Equal("deployment-nginx", GetReportName("Deployment", "nginx"))
+}
+
+func (suite *VulnerabilityReportWriterTestSuite) Test_GetReportName_LongName_TruncatedWithHash() {
+	name := strings.Repeat("a", 300)
+
+	reportName := GetReportName("Deployment", name)
+	otherReportName := GetReportName("Deployment", name+"b")
+
+	suite.Equal(_maxReportNameLength, len(reportName))
+	suite.NotEqual(reportName, otherReportName)
+}
+
+func (suite *VulnerabilityReportWriterTestSuite) Test_getReportWorkload_NamedWorkload_Workload() {
+	workload := getReportWorkload(_deploymentKind, &admisionrequest.ObjectMetadata{Name: "deploymentTest", UID: "deploymentUID"})
+
+	suite.Equal(&WorkloadReference{APIVersion: "apps/v1", Kind: "Deployment", Name: "deploymentTest", UID: "deploymentUID"}, workload)
+}
+
+func (suite *VulnerabilityReportWriterTestSuite) Test_getReportWorkload_GeneratedNamePod_OwnerWithUID() {
+	workload := getReportWorkload(_podKind, &admisionrequest.ObjectMetadata{OwnerReferences: []*admisionrequest.OwnerReference{
+		{APIVersion: "apps/v1", Kind: "ReplicaSet", Name: "replicaSetWithoutUID"},
+		{APIVersion: "apps/v1", Kind: "ReplicaSet", Name: "replicaSetTest", UID: "replicaSetUID"},
+	}})
+
+	suite.Equal(&WorkloadReference{APIVersion: "apps/v1", Kind: "ReplicaSet", Name: "replicaSetTest", UID: "replicaSetUID"}, workload)
+}
+
+func (suite *VulnerabilityReportWriterTestSuite) Test_WriteReport_GeneratedNamePodWithoutOwners_NoReport() {
+	reportName := suite.writer.WriteReport(context.Background(), "default", _podKind, createWorkloadResource("", "", nil), []*contracts.ContainerVulnerabilityScanInfo{suite.unhealthyInfo})
+
+	suite.Equal("", reportName)
+}
+
+func (suite *VulnerabilityReportWriterTestSuite) Test_writeReport_NewPod_ReportOwnedByFetchedUID() {
+	report := newVulnerabilityReport(&WorkloadReference{APIVersion: "v1", Kind: "Pod", Name: "podTest"}, time.Now().UTC(), []*contracts.ContainerVulnerabilityScanInfo{suite.unhealthyInfo})
+
+	suite.writer.writeReport(context.Background(), "default", "pod-podtest", report)
+
+	object := suite.getReport("pod-podtest")
+	suite.Equal([]metav1.OwnerReference{{APIVersion: "v1", Kind: "Pod", Name: "podTest", UID: "podUID"}}, object.GetOwnerReferences())
+	suite.Equal(map[string]string{WorkloadKindLabel: "Pod", WorkloadNameLabel: "podTest"}, object.GetLabels())
+	scanStatus, _, _ := unstructured.NestedString(object.Object, _reportContentField, "summary", "scanStatus")
+	suite.Equal(string(contracts.UnhealthyScan), scanStatus)
+	containers, _, _ := unstructured.NestedSlice(object.Object, _reportContentField, "containers")
+	suite.Equal(1, len(containers))
+}
+
+func (suite *VulnerabilityReportWriterTestSuite) Test_writeReport_ExistingReport_Updated() {
+	workload := &WorkloadReference{APIVersion: "v1", Kind: "Pod", Name: "podTest", UID: "podUID"}
+	suite.writer.writeReport(context.Background(), "default", "pod-podtest", newVulnerabilityReport(workload, time.Now().UTC(), []*contracts.ContainerVulnerabilityScanInfo{suite.unhealthyInfo}))
+
+	suite.writer.writeReport(context.Background(), "default", "pod-podtest", newVulnerabilityReport(workload, time.Now().UTC(), []*contracts.ContainerVulnerabilityScanInfo{suite.unscannedInfo}))
+
+	scanStatus, _, _ := unstructured.NestedString(suite.getReport("pod-podtest").Object, _reportContentField, "summary", "scanStatus")
+	suite.Equal(string(contracts.Unscanned), scanStatus)
+}
+
+func (suite *VulnerabilityReportWriterTestSuite) Test_writeReport_WorkloadNotFound_NoReport() {
+	report := newVulnerabilityReport(&WorkloadReference{APIVersion: "v1", Kind: "Pod", Name: "rejectedPod"}, time.Now().UTC(), []*contracts.ContainerVulnerabilityScanInfo{suite.unhealthyInfo})
+
+	suite.writer.writeReport(context.Background(), "default", "pod-rejectedpod", report)
+
+	object := &unstructured.Unstructured{}
+	object.SetGroupVersionKind(ContainerVulnerabilityReportGVK)
+	suite.NotNil(suite.client.Get(context.Background(), types.NamespacedName{Namespace: "default", Name: "pod-rejectedpod"}, object))
+}
+
+func (suite *VulnerabilityReportWriterTestSuite) getReport(name string) *unstructured.Unstructured {
+	object := &unstructured.Unstructured{}
+	object.SetGroupVersionKind(ContainerVulnerabilityReportGVK)
+	suite.Require().Nil(suite.client.Get(context.Background(), types.NamespacedName{Namespace: "default", Name: name}, object))
+	return object
+}
+
+func createWorkloadResource(name string, uid string, ownerReferences []*admisionrequest.OwnerReference) *admisionrequest.WorkloadResource {
+	return &admisionrequest.WorkloadResource{
+		Metadata: &admisionrequest.ObjectMetadata{Name: name, UID: uid, Namespace: "default", OwnerReferences: ownerReferences},
+	}
+}
+
+func TestVulnerabilityReportWriterTestSuite(t *testing.T) {
+	suite.Run(t, new(VulnerabilityReportWriterTestSuite))
+}
@@ -18,6 +18,8 @@ violation[{"msg":msg}] {
     container := containers[_]
     # Explicit filter all containers that don't have unhealthy scan status.
     container["scanStatus"] == "unhealthyScan"
+    # Containers with summarized scanFindings are checked by their scanFindingsSummary.
+    not container["scanFindingsSummary"]
     # Filter scanfindings
     scanFindings := filterScanFindings(container["scanFindings"])
     isSeverityAboveThreshold(scanFindings)
@@ -25,6 +27,51 @@ violation[{"msg":msg}] {
     additionalData := getAdditionalData(container)
     msg := sprintf("Image <%v> under container <%v> with digest <%v>, has vulnerabilities that must be remediated before deployment. Refer to Defender for Cloud to view vulnerability data for your image.", [container.image.name, container.name, container.image.digest])
 }
+# This violation checks if there is some container that its scanFindings were summarized (oversized annotation) and its counts of the severities are exceed some thresholds.
+violation[{"msg":msg}] {
+    # Extract containers
+    containers := getApplicableContainersScanInfo(input.review)
+    container := containers[_]
+    # Explicit filter all containers that don't have unhealthy scan status.
+    container["scanStatus"] == "unhealthyScan"
+    # Filter counts of scanfindings (excludeFindingIDs can't be applied on counts)
+    scanFindingsSummary := filterScanFindingsNotPatchableBelowThreshold(container["scanFindingsSummary"])
+    isSummarySeverityAboveThreshold(scanFindingsSummary)
+    # Construct violation msg
+    msg := sprintf("Image <%v> under container <%v> with digest <%v>, has vulnerabilities that must be remediated before deployment. Refer to Defender for Cloud to view vulnerability data for your image.", [container.image.name, container.name, container.image.digest])
+}
+# This violation checks if there is some container that its precomputed summary (v2 schema) has counts of severities that are exceed some thresholds.
+violation[{"msg":msg}] {
+    # Extract containers
+    containers := getApplicableContainersScanInfo(input.review)
+    container := containers[_]
+    # Explicit filter all containers that don't have unhealthy scan status.
+    container["scanStatus"] == "unhealthyScan"
+    # Compare the count of each severity to its threshold (excludeFindingIDs can't be applied on counts)
+    severityType := ["High", "Medium", "Low"][_]
+    getSummarySeverityCount(container["summary"], severityType) > input.parameters.severity[severityType]
+    # Construct violation msg
+    msg := sprintf("Image <%v> under container <%v> with digest <%v>, has vulnerabilities that must be remediated before deployment. Refer to Defender for Cloud to view vulnerability data for your image.", [container.image.name, container.name, container.image.digest])
+}
+# This violation checks if the scan info is compressed (oversized annotation with gzip encoding) - it can't be evaluated by the policy.
+violation[{"msg":msg}] {
+    containerVulnerabilityScanInfoList := getContainerVulnerabilityScanInfoList(input.review)
+    containerVulnerabilityScanInfoList["encoding"] == "gzip"
+    msg := "Vulnerability scan info of the workload is compressed (gzip encoding) and can't be evaluated by the policy. Configure the summarized encoding for oversized scan info annotations."
+}
+# This violation checks if signed images are required and there is a container that its signature wasn't verified.
+violation[{"msg":msg}] {
+    # Check that signed images are required
+    input.parameters.requireSignedImages
+    # Extract containers
+    containers := getApplicableContainersSignatureInfo(input.review)
+    container := containers[_]
+    # Check if the signature of the container's image digest wasn't verified.
+    not isSignatureVerified(container)
+    # Construct violation msg
+    signatureStatus := getSignatureStatus(container)
+    msg := sprintf("Image <%v> under container <%v> with digest <%v> must have a verified signature before deployment, signatureStatus: <%v>", [container.image.name, container.name, container.image.digest, signatureStatus])
+}
 # Extract the containers from the review object.
 getApplicableContainersScanInfo(review) = containers{
   # Extract ContainerVulnerabilityScanInfoList
@@ -39,6 +86,13 @@ getContainerVulnerabilityScanInfoList(review) = containerVulnerabilityScanInfoLi
   scanResults := review.object.metadata.annotations["azuredefender.io/containers.vulnerability.scan.info"]
   containerVulnerabilityScanInfoList := json.unmarshal(scanResults)
 }
+# The v2 schema (precomputed summary of each container) is used only if there is no v1 annotation.
+# See https://github.com/Azure/AzureDefender-K8S-InClusterDefense/blob/master/pkg/azdsecinfo/contracts/containers_vulnerability_scan_info_v2.go
+getContainerVulnerabilityScanInfoList(review) = containerVulnerabilityScanInfoList{
+  not review.object.metadata.annotations["azuredefender.io/containers.vulnerability.scan.info"]
+  scanResults := review.object.metadata.annotations["azuredefender.io/containers.vulnerability.scan.info.v2"]
+  containerVulnerabilityScanInfoList := json.unmarshal(scanResults)
+}
 # Filter containers.
 filterContainers(containers) = containers{
   containers = filterContainersWithHealthyScanStatus(containers)
@@ -110,10 +164,58 @@ isSeverityTypeAboveThreshold(scanFindings, severityType){
   scanFinding["severity"] == severityType])
   c > input.parameters.severity[severityType]
 }
+# Checks if the total count of High severity is above the threshold
+isSummarySeverityAboveThreshold(scanFindingsSummary){
+  isSummarySeverityTypeAboveThreshold(scanFindingsSummary, "High")
+}
+# Checks if the total count of Medium severity is above the threshold
+isSummarySeverityAboveThreshold(scanFindingsSummary){
+  isSummarySeverityTypeAboveThreshold(scanFindingsSummary, "Medium")
+}
+# Checks if the total count of Low severity is above the threshold
+isSummarySeverityAboveThreshold(scanFindingsSummary){
+  isSummarySeverityTypeAboveThreshold(scanFindingsSummary, "Low")
+}
+# Check if the sum of the counts with severity level of severtyType (patchable and not patchable) is exceeding the threshold
+isSummarySeverityTypeAboveThreshold(scanFindingsSummary, severityType){
+  c := sum([scanFindingsCount["count"] | scanFindingsCount := scanFindingsSummary[_]
+  scanFindingsCount["severity"] == severityType])
+  c > input.parameters.severity[severityType]
+}
+# Returns the count of severityType in the summary - the not patchable findings are counted only if severityType is above severityThresholdForExcludingNotPatchableFindings.
+getSummarySeverityCount(summary, severityType) = c{
+  isNotPatchableCounted(severityType)
+  c := summary["severityCounts"][severityType]
+}
+getSummarySeverityCount(summary, severityType) = c{
+  not isNotPatchableCounted(severityType)
+  c := summary["patchableSeverityCounts"][severityType]
+}
+# Checks if the not patchable findings of severityType are counted (i.e. severityType is above severityThresholdForExcludingNotPatchableFindings)
+isNotPatchableCounted(severityType){
+  severityToLevel := {"None":0, "Low":1, "Medium":2, "High": 3}
+  severityToLevel[severityType] > severityToLevel[input.parameters.severityThresholdForExcludingNotPatchableFindings]
+}
 getAdditionalData(container) = additionalData{
  not container.additionalData
  additionalData = "None" # Default additionalData
 }
 getAdditionalData(container) = additionalData{
  additionalData = container.additionalData	# Extract additionalData from the object
-}
\ No newline at end of file
+}
+# Extract the containers that aren't excluded from the review object (regardless of their scan status).
+getApplicableContainersSignatureInfo(review) = containers{
+  containerVulnerabilityScanInfoList := getContainerVulnerabilityScanInfoList(review)
+  containers := filterContaintersWithExcludedImages(containerVulnerabilityScanInfoList["containers"])
+}
+# Checks if the signature of the container's image digest was verified.
+isSignatureVerified(container){
+  container["signatureStatus"] == "verified"
+}
+getSignatureStatus(container) = signatureStatus{
+ not container.signatureStatus
+ signatureStatus = "None" # Signature verification is disabled or the image wasn't resolved
+}
+getSignatureStatus(container) = signatureStatus{
+ signatureStatus = container.signatureStatus # Extract signatureStatus from the object
+}
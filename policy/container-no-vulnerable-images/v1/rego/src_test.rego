@@ -191,6 +191,148 @@ test_input_review_unscanned_with_additionalData {
     contains(results[_].msg, "GetContainersVulnerabilityScanInfoGotTimeout")
 }
 
+# Checks that if signed images are required and the image signature is verified, then there is no violation.
+test_input_signature_verified_require_signed_images_0_violation {
+    input := { "review": input_review_healthy_signature_verified, "parameters": input_parameters_require_signed_images}
+    results := violation with input as input
+    count(results) == 0
+}
+
+# Checks that if signed images are required and the image is unsigned, then there is 1 violation.
+test_input_unsigned_require_signed_images_1_violation {
+    input := { "review": input_review_healthy_unsigned, "parameters": input_parameters_require_signed_images}
+    results := violation with input as input
+    count(results) == 1
+}
+
+# Checks that if signed images are not required and the image is unsigned, then there is no violation.
+test_input_unsigned_signed_images_not_required_0_violation {
+    input := { "review": input_review_healthy_unsigned, "parameters": input_parameters_empty}
+    results := violation with input as input
+    count(results) == 0
+}
+
+# Checks that if signed images are required and the unsigned image is excluded, then there is no violation.
+test_input_unsigned_excluded_image_require_signed_images_0_violation {
+    input := { "review": input_review_healthy_unsigned, "parameters": input_parameters_tomerazurecr_image_excluded_require_signed_images}
+    results := violation with input as input
+    count(results) == 0
+}
+
+# Checks that if the scanFindings were summarized and the count of High severity (not patchable are filtered) exceeds the threshold, then there is 1 violation.
+test_input_summarized_above_highSeverity_1_violation {
+    input := { "review": input_review_unhealthy_summarized, "parameters": input_parameters_severityHighTreshold_1}
+    results := violation with input as input
+    count(results) == 1
+}
+
+# Checks that if the scanFindings were summarized and the counts don't exceed the thresholds, then there is no violation.
+test_input_summarized_below_highSeverity_0_violation {
+    input := { "review": input_review_unhealthy_summarized, "parameters": input_parameters_severityHighTreshold_2}
+    results := violation with input as input
+    count(results) == 0
+}
+
+# Checks that if the scan info is compressed, then there is 1 violation.
+test_input_gzip_1_violation {
+    input := { "review": input_review_gzip, "parameters": input_parameters_severityHighTreshold_2}
+    results := violation with input as input
+    count(results) == 1
+}
+
+input_review_unhealthy_summarized = {
+    "object": {
+        "metadata": {
+            "annotations": {
+                "azuredefender.io/containers.vulnerability.scan.info": "{\"generatedTimestamp\":\"2021-05-04T23:53:20Z\",\"encoding\":\"summarized\",\"containers\":[{\"name\":\"testContainer\",\"image\":{\"name\":\"tomer.azurecr.io/core/app:4.6\",\"digest\":\"sha256:4a\"},\"scanStatus\":\"unhealthyScan\",\"scanFindings\":[{\"patchable\":true,\"id\":\"123\",\"severity\":\"High\"}],\"scanFindingsSummary\":[{\"severity\":\"High\",\"patchable\":true,\"count\":2},{\"severity\":\"High\",\"patchable\":false,\"count\":1},{\"severity\":\"Low\",\"patchable\":true,\"count\":300}]}]}"
+            }
+        }
+    }
+}
+
+input_review_gzip = {
+    "object": {
+        "metadata": {
+            "annotations": {
+                "azuredefender.io/containers.vulnerability.scan.info": "{\"generatedTimestamp\":\"2021-05-04T23:53:20Z\",\"encoding\":\"gzip\",\"containers\":null,\"compressedContainers\":\"H4sIAIez1WoC/4uOBQApu0wNAgAAAA==\"}"
+            }
+        }
+    }
+}
+
+# Checks that if the v2 summary count of High severity (not patchable are filtered) exceeds the threshold, then there is 1 violation.
+test_input_v2_above_highSeverity_1_violation {
+    input := { "review": input_review_unhealthy_v2, "parameters": input_parameters_severityHighTreshold_1}
+    results := violation with input as input
+    count(results) == 1
+}
+
+# Checks that if the v2 summary counts don't exceed the thresholds, then there is no violation.
+test_input_v2_below_highSeverity_0_violation {
+    input := { "review": input_review_unhealthy_v2, "parameters": input_parameters_severityHighTreshold_2}
+    results := violation with input as input
+    count(results) == 0
+}
+
+# Checks that if the not patchable findings are above severityThresholdForExcludingNotPatchableFindings, then they are counted in the v2 summary.
+test_input_v2_not_patchable_counted_1_violation {
+    input := { "review": input_review_unhealthy_v2, "parameters": input_parameters_high_2_severityThresholdForExcludingNotPatchableFindings_Medium}
+    results := violation with input as input
+    count(results) == 1
+}
+
+# Checks that if there is unscanned image in the v2 schema, then there is 1 violation.
+test_input_v2_unscanned_1_violation {
+    input := { "review": input_review_unscanned_v2, "parameters": input_parameters_empty}
+    results := violation with input as input
+    count(results) == 1
+}
+
+# Checks that if both v1 and v2 annotations exist, then only the v1 annotation is evaluated.
+test_input_v1_and_v2_only_v1_evaluated_0_violation {
+    input := { "review": input_review_healthy_v1_unhealthy_v2, "parameters": input_parameters_severityHighTreshold_1}
+    results := violation with input as input
+    count(results) == 0
+}
+
+input_review_unhealthy_v2 = {
+    "object": {
+        "metadata": {
+            "annotations": {
+                "azuredefender.io/containers.vulnerability.scan.info.v2": "{\"schemaVersion\":\"v2\",\"generatedTimestamp\":\"2021-05-04T23:53:20Z\",\"containers\":[{\"name\":\"testContainer\",\"image\":{\"name\":\"tomer.azurecr.io/core/app:4.6\",\"digest\":\"sha256:4a\"},\"scanStatus\":\"unhealthyScan\",\"summary\":{\"severityCounts\":{\"High\":3,\"Medium\":0,\"Low\":0},\"patchableSeverityCounts\":{\"High\":2,\"Medium\":0,\"Low\":0},\"totalCount\":3,\"patchableCount\":2,\"maxSeverity\":\"High\",\"evaluatedAt\":\"2021-05-04T23:53:20Z\",\"dataSource\":\"AzureResourceGraph\"}}]}"
+            }
+        }
+    }
+}
+
+input_review_unscanned_v2 = {
+    "object": {
+        "metadata": {
+            "annotations": {
+                "azuredefender.io/containers.vulnerability.scan.info.v2": "{\"schemaVersion\":\"v2\",\"generatedTimestamp\":\"2021-05-04T23:53:20Z\",\"containers\":[{\"name\":\"testContainer\",\"image\":{\"name\":\"tomer.azurecr.io/core/app:4.6\",\"digest\":\"\"},\"scanStatus\":\"unscanned\",\"additionalData\":{\"UnscannedReason\":\"ImageIsNotInACR\"}}]}"
+            }
+        }
+    }
+}
+
+input_review_healthy_v1_unhealthy_v2 = {
+    "object": {
+        "metadata": {
+            "annotations": {
+                "azuredefender.io/containers.vulnerability.scan.info": "{\"schemaVersion\":\"v1\",\"generatedTimestamp\":\"2021-05-04T23:53:20Z\",\"containers\":[{\"name\":\"testContainer\",\"image\":{\"name\":\"tomer.azurecr.io/core/app:4.6\",\"digest\":\"sha256:4a\"},\"scanStatus\":\"healthyScan\",\"scanFindings\":[]}]}",
+                "azuredefender.io/containers.vulnerability.scan.info.v2": "{\"schemaVersion\":\"v2\",\"generatedTimestamp\":\"2021-05-04T23:53:20Z\",\"containers\":[{\"name\":\"testContainer\",\"image\":{\"name\":\"tomer.azurecr.io/core/app:4.6\",\"digest\":\"sha256:4a\"},\"scanStatus\":\"unhealthyScan\",\"summary\":{\"severityCounts\":{\"High\":3,\"Medium\":0,\"Low\":0},\"patchableSeverityCounts\":{\"High\":3,\"Medium\":0,\"Low\":0},\"totalCount\":3,\"patchableCount\":3,\"maxSeverity\":\"High\",\"evaluatedAt\":\"2021-05-04T23:53:20Z\",\"dataSource\":\"AzureResourceGraph\"}}]}"
+            }
+        }
+    }
+}
+
+input_parameters_high_2_severityThresholdForExcludingNotPatchableFindings_Medium = {
+    "severityThresholdForExcludingNotPatchableFindings": "Medium",
+    "severity" : {
+        "High": 2
+    }
+}
+
 input_review_no_annotations = {
     "object": {
         "metadata": {
@@ -417,4 +559,33 @@ input_parameters_high_0_severityThresholdForExcludingNotPatchableFindings_High =
     "severity" : {
         "High": 0
     }
-}
\ No newline at end of file
+}
+
+input_review_healthy_signature_verified = {
+    "object": {
+        "metadata": {
+            "annotations": {
+                "azuredefender.io/containers.vulnerability.scan.info": "{\"generatedTimestamp\":\"2021-05-04T23:53:20Z\",\"containers\":[{\"name\":\"testContainer\",\"image\":{\"name\":\"tomer.azurecr.io/core/app:4.6\",\"digest\":\"sha256:4a\"},\"scanStatus\":\"healthyScan\",\"scanFindings\":[],\"signatureStatus\":\"verified\"}]}"
+            }
+        }
+    }
+}
+
+input_review_healthy_unsigned = {
+    "object": {
+        "metadata": {
+            "annotations": {
+                "azuredefender.io/containers.vulnerability.scan.info": "{\"generatedTimestamp\":\"2021-05-04T23:53:20Z\",\"containers\":[{\"name\":\"testContainer\",\"image\":{\"name\":\"tomer.azurecr.io/core/app:4.6\",\"digest\":\"sha256:4a\"},\"scanStatus\":\"healthyScan\",\"scanFindings\":[],\"signatureStatus\":\"unsigned\"}]}"
+            }
+        }
+    }
+}
+
+input_parameters_require_signed_images = {
+    "requireSignedImages": true
+}
+
+input_parameters_tomerazurecr_image_excluded_require_signed_images = {
+    "excludedImages": ["(tomer.azurecr.io).*"],
+    "requireSignedImages": true
+}